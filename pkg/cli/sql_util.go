@@ -70,6 +70,9 @@ func (c *sqlConn) ensureConn() error {
 		if c.reconnecting && isInteractive {
 			fmt.Fprintf(stderr, "connection lost; opening new connection: all session settings will be lost\n")
 		}
+		// TODO: print the notices sent by the server, e.g. for DROP ... IF
+		// EXISTS. The vendored lib/pq discards the NoticeResponse messages; a
+		// version which exposes them with a notice handler is needed first.
 		conn, err := pq.Open(c.url)
 		if err != nil {
 			return err
@@ -76,6 +76,10 @@ const (
 	// KeyDistSQLNodeVersionKeyPrefix is key prefix for each node's DistSQL
 	// version.
 	KeyDistSQLNodeVersionKeyPrefix = "distsql-version"
+
+	// KeyNotificationListenersPrefix is the key prefix for gossiping the
+	// notification channels on which the SQL sessions of each node listen.
+	KeyNotificationListenersPrefix = "notification-listeners"
 )
 
 // MakeKey creates a canonical key under which to gossip a piece of
@@ -137,3 +141,23 @@ func MakeDeadReplicasKey(storeID roachpb.StoreID) string {
 func MakeDistSQLNodeVersionKey(nodeID roachpb.NodeID) string {
 	return MakeKey(KeyDistSQLNodeVersionKeyPrefix, nodeID.String())
 }
+
+// MakeNotificationListenersKey returns the gossip key for the notification
+// channels on which the SQL sessions of the given node listen.
+func MakeNotificationListenersKey(nodeID roachpb.NodeID) string {
+	return MakeKey(KeyNotificationListenersPrefix, nodeID.String())
+}
+
+// NodeIDFromNotificationListenersKey extracts the node ID from a key
+// constructed by MakeNotificationListenersKey.
+func NodeIDFromNotificationListenersKey(key string) (roachpb.NodeID, error) {
+	trimmedKey := strings.TrimPrefix(key, KeyNotificationListenersPrefix+separator)
+	if trimmedKey == key {
+		return 0, errors.Errorf("%q is not a notification listeners key", key)
+	}
+	nodeID, err := strconv.ParseInt(trimmedKey, 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed parsing NodeID from key %q", key)
+	}
+	return roachpb.NodeID(nodeID), nil
+}
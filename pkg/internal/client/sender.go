@@ -35,6 +35,19 @@ type SenderWithDistSQLBackdoor interface {
 	// GetTxnState returns the state that the TxnCoordSender has for a
 	// transaction. The bool is false is no state is found.
 	GetTxnState(txnID uuid.UUID) (roachpb.Transaction, bool)
+
+	// AugmentTxnState merges the state of a transaction which performed writes
+	// on remote nodes through leaf transactions into the state that the
+	// TxnCoordSender has for it. If the TxnCoordSender is not yet tracking the
+	// transaction, it starts doing so.
+	AugmentTxnState(ctx context.Context, txn roachpb.Transaction, intents []roachpb.Span) error
+
+	// CleanupTxnState stops tracking a transaction which has been aborted
+	// because of an error that happened on a remote node. Like for the
+	// transactions aborted through the TxnCoordSender, the intents are left
+	// to the transactions that run into them, which find the transaction
+	// record aborted and resolve them.
+	CleanupTxnState(ctx context.Context, txn roachpb.Transaction)
 }
 
 // SenderFunc is an adapter to allow the use of ordinary functions
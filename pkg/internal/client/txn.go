@@ -16,6 +16,7 @@ package client
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/pkg/errors"
 	"golang.org/x/net/context"
@@ -94,11 +95,21 @@ type Txn struct {
 		// TODO(andrei): This is broken for DistSQL, which doesn't account for the
 		// requests it uses the transaction for.
 		commandCount int
+		// intents holds the spans written by the transaction if collectIntents
+		// is set.
+		intents []roachpb.Span
+		// lockTimeout is attached to all requests sent through this
+		// transaction. See SetLockTimeout.
+		lockTimeout time.Duration
 	}
 
 	// Set for DistSQL transactions that get errors that would otherwise be
 	// handled by the TxnCoordSender.
 	acceptUnhandledRetryableErrors bool
+	// Set for DistSQL transactions that perform writes on remote nodes. These
+	// writes bypass the gateway's TxnCoordSender, so the transaction needs to
+	// remember the intents it lays down and pass them along to the gateway.
+	collectIntents bool
 }
 
 // NewTxn returns a new txn.
@@ -151,6 +162,81 @@ func (txn *Txn) AcceptUnhandledRetryableErrors() {
 	txn.acceptUnhandledRetryableErrors = true
 }
 
+// CollectIntents makes the transaction remember the spans of the intents that
+// it writes. See GetLeafState().
+func (txn *Txn) CollectIntents() {
+	txn.collectIntents = true
+}
+
+// GetLeafState returns the proto of a DistSQL leaf transaction together with
+// the intents that it has written (if CollectIntents() was called). The state
+// is meant to be merged into the root transaction on the gateway through
+// AugmentLeafState().
+func (txn *Txn) GetLeafState() (roachpb.Transaction, []roachpb.Span) {
+	txn.mu.Lock()
+	defer txn.mu.Unlock()
+	return txn.mu.Proto.Clone(), append([]roachpb.Span(nil), txn.mu.intents...)
+}
+
+// AugmentLeafState merges the state of a DistSQL leaf transaction, as returned
+// by GetLeafState(), into this (root) transaction and makes the TxnCoordSender
+// responsible for the leaf's intents. State for a different incarnation of the
+// transaction is ignored.
+func (txn *Txn) AugmentLeafState(
+	ctx context.Context, leaf roachpb.Transaction, intents []roachpb.Span,
+) error {
+	txn.mu.Lock()
+	if leaf.ID != txn.mu.Proto.ID {
+		txn.mu.Unlock()
+		return nil
+	}
+	txn.mu.Proto.Update(&leaf)
+	proto := txn.mu.Proto.Clone()
+	txn.mu.Unlock()
+	if len(intents) == 0 {
+		return nil
+	}
+	return txn.db.GetSender().(SenderWithDistSQLBackdoor).AugmentTxnState(ctx, proto, intents)
+}
+
+// AnchorForRemoteWrites writes the transaction record, unless it has already
+// been written, and makes sure that the TxnCoordSender is tracking the
+// transaction. It needs to be called before DistSQL leaf transactions perform
+// writes on the transaction's behalf: these writes bypass the TxnCoordSender,
+// so they can't start the transaction themselves.
+//
+// key is used as the anchor key if the transaction doesn't have one yet.
+func (txn *Txn) AnchorForRemoteWrites(ctx context.Context, key roachpb.Key) error {
+	txn.mu.Lock()
+	writing := txn.mu.Proto.Writing
+	if !writing && len(txn.mu.Proto.Key) == 0 {
+		if len(txn.mu.txnAnchorKey) > 0 {
+			key = txn.mu.txnAnchorKey
+		}
+		txn.mu.Proto.Key = key
+	}
+	anchorKey := txn.mu.Proto.Key
+	txn.mu.Unlock()
+	if writing {
+		return nil
+	}
+
+	var ba roachpb.BatchRequest
+	ba.Add(&roachpb.BeginTransactionRequest{Span: roachpb.Span{Key: anchorKey}})
+	if _, pErr := txn.Send(ctx, ba); pErr != nil {
+		return pErr.GoError()
+	}
+
+	txn.mu.Lock()
+	proto := txn.mu.Proto.Clone()
+	txn.mu.Unlock()
+	// The TxnCoordSender only starts tracking a transaction once it has written
+	// an intent. Until the leaves report theirs, the transaction record stands
+	// in for them.
+	return txn.db.GetSender().(SenderWithDistSQLBackdoor).AugmentTxnState(
+		ctx, proto, []roachpb.Span{{Key: anchorKey}})
+}
+
 // CommandCount returns the count of commands executed through this txn.
 // Retryable errors on the transaction will reset the count to 0.
 func (txn *Txn) CommandCount() int {
@@ -209,6 +295,24 @@ func (txn *Txn) SetUserPriority(userPriority roachpb.UserPriority) error {
 	return nil
 }
 
+// SetLockTimeout sets how long the requests of the transaction wait for the
+// other transactions whose intents they encounter. When it expires, the
+// requests fail with a WriteIntentError with LockTimeout set. 0 means that
+// they wait until the other transactions finish. Unlike the user priority,
+// the lock timeout can be changed while the transaction is running.
+func (txn *Txn) SetLockTimeout(timeout time.Duration) {
+	txn.mu.Lock()
+	txn.mu.lockTimeout = timeout
+	txn.mu.Unlock()
+}
+
+// LockTimeout returns the lock timeout of the transaction.
+func (txn *Txn) LockTimeout() time.Duration {
+	txn.mu.Lock()
+	defer txn.mu.Unlock()
+	return txn.mu.lockTimeout
+}
+
 // InternalSetPriority sets the transaction priority. It is intended for
 // internal (testing) use only.
 func (txn *Txn) InternalSetPriority(priority int32) {
@@ -897,6 +1001,7 @@ func (txn *Txn) Send(
 		if txn.mu.UserPriority != 0 {
 			ba.UserPriority = txn.mu.UserPriority
 		}
+		ba.LockTimeout = txn.mu.lockTimeout
 
 		if !txn.mu.active {
 			user := roachpb.MakePriority(ba.UserPriority)
@@ -967,6 +1072,14 @@ func (txn *Txn) Send(
 	txn.mu.Lock()
 	defer txn.mu.Unlock()
 
+	if txn.collectIntents {
+		// Like the TxnCoordSender, remember the intents even on error; the
+		// requests might have partially succeeded.
+		ba.IntentSpanIterate(br, func(key, endKey roachpb.Key) {
+			txn.mu.intents = append(txn.mu.intents, roachpb.Span{Key: key, EndKey: endKey})
+		})
+	}
+
 	// If we inserted a begin transaction request, remove it here. We also
 	// unset the flag writingTxnRecord flag in case another ever needs to
 	// be sent again (for instance, if we're aborted and need to restart).
@@ -1092,21 +1205,21 @@ func (txn *Txn) UpdateStateOnRemoteRetryableErr(ctx context.Context, pErr roachp
 		log.Fatalf(ctx, "unexpected retryable error with no txn ran through DistSQL: %s", pErr)
 	}
 
-	// Assert that the TxnCoordSender doesn't have any state for this transaction
-	// (and it shouldn't, since DistSQL isn't supposed to do any works in
-	// transaction that had performed writes and hence started being tracked). If
-	// the TxnCoordSender were to have state, it'd be a bad thing that we're not
-	// updating it.
-	txnID := pErr.GetTxn().ID
-	if _, ok := txn.db.GetSender().(SenderWithDistSQLBackdoor).GetTxnState(txnID); ok {
-		log.Fatalf(ctx, "unexpected state in TxnCoordSender for transaction in error: %s", pErr)
-	}
-
 	// Emulate the processing that the TxnCoordSender would have done on this
 	// error.
 	newTxn := roachpb.PrepareTransactionForRetry(ctx, &pErr, txn.mu.UserPriority, txn.db.clock)
 	newErr := roachpb.NewHandledRetryableTxnError(pErr.Message, pErr.GetTxn().ID, newTxn)
 
+	// The TxnCoordSender has state for this transaction if DistSQL performed
+	// writes in it (see AnchorForRemoteWrites). If the transaction is being
+	// replaced by a new one, the TxnCoordSender needs to stop tracking the old
+	// one; a restarted epoch is picked up with the next request.
+	txnID := pErr.GetTxn().ID
+	sender := txn.db.GetSender().(SenderWithDistSQLBackdoor)
+	if _, ok := sender.GetTxnState(txnID); ok && newTxn.ID != txnID {
+		sender.CleanupTxnState(ctx, *pErr.GetTxn())
+	}
+
 	txn.updateStateOnRetryableErrLocked(
 		ctx, *newErr,
 		// We're passing the current ID as the "request"'s. In doing so, we're
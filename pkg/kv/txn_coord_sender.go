@@ -936,26 +936,8 @@ func (tc *TxnCoordSender) updateState(
 			// we expect it to be committed/aborted at some point in the
 			// future.
 			if _, isEnding := ba.GetArg(roachpb.EndTransaction); pErr != nil || !isEnding {
-				log.Event(ctx, "coordinator spawns")
-				txnMeta = &txnMetadata{
-					txn:              newTxn,
-					keys:             keys,
-					firstUpdateNanos: startNS,
-					lastUpdateNanos:  tc.clock.PhysicalNow(),
-					timeoutDuration:  tc.clientTimeout,
-					txnEnd:           make(chan struct{}),
-				}
-				tc.txnMu.txns[txnID] = txnMeta
-
-				if err := tc.stopper.RunAsyncTask(
-					ctx, "kv.TxnCoordSender: heartbeat loop", func(ctx context.Context) {
-						tc.heartbeatLoop(ctx, txnID)
-					}); err != nil {
-					// The system is already draining and we can't start the
-					// heartbeat. We refuse new transactions for now because
-					// they're likely not going to have all intents committed.
-					// In principle, we can relax this as needed though.
-					tc.unregisterTxnLocked(txnID)
+				var err error
+				if txnMeta, err = tc.trackTxnLocked(ctx, newTxn, keys, startNS); err != nil {
 					return roachpb.NewError(err)
 				}
 			} else {
@@ -977,6 +959,70 @@ func (tc *TxnCoordSender) updateState(
 	return pErr
 }
 
+// trackTxnLocked starts tracking a transaction which has laid down intents:
+// the coordinator spawns and the transaction is heartbeat until it finishes.
+// It assumes the lock is held.
+func (tc *TxnCoordSender) trackTxnLocked(
+	ctx context.Context, txn roachpb.Transaction, keys []roachpb.Span, startNS int64,
+) (*txnMetadata, error) {
+	log.Event(ctx, "coordinator spawns")
+	txnID := txn.ID
+	txnMeta := &txnMetadata{
+		txn:              txn,
+		keys:             keys,
+		firstUpdateNanos: startNS,
+		lastUpdateNanos:  tc.clock.PhysicalNow(),
+		timeoutDuration:  tc.clientTimeout,
+		txnEnd:           make(chan struct{}),
+	}
+	tc.txnMu.txns[txnID] = txnMeta
+
+	if err := tc.stopper.RunAsyncTask(
+		ctx, "kv.TxnCoordSender: heartbeat loop", func(ctx context.Context) {
+			tc.heartbeatLoop(ctx, txnID)
+		}); err != nil {
+		// The system is already draining and we can't start the
+		// heartbeat. We refuse new transactions for now because
+		// they're likely not going to have all intents committed.
+		// In principle, we can relax this as needed though.
+		tc.unregisterTxnLocked(txnID)
+		return nil, err
+	}
+	return txnMeta, nil
+}
+
+// AugmentTxnState is part of the SenderWithDistSQLBackdoor interface.
+func (tc *TxnCoordSender) AugmentTxnState(
+	ctx context.Context, txn roachpb.Transaction, intents []roachpb.Span,
+) error {
+	tc.txnMu.Lock()
+	defer tc.txnMu.Unlock()
+
+	txnMeta, ok := tc.txnMu.txns[txn.ID]
+	if !ok {
+		if !txn.Writing || len(intents) == 0 {
+			return errors.Errorf("cannot track transaction without a record or intents: %s", txn)
+		}
+		_, err := tc.trackTxnLocked(ctx, txn.Clone(), intents, tc.clock.PhysicalNow())
+		return err
+	}
+	keys := append(txnMeta.keys, intents...)
+	if int64(len(keys)) > maxIntents.Get(&tc.st.SV) {
+		return errors.Errorf("transaction is too large to commit: %d intents", len(keys))
+	}
+	txnMeta.keys = keys
+	txnMeta.txn.Update(&txn)
+	txnMeta.setLastUpdate(tc.clock.PhysicalNow())
+	return nil
+}
+
+// CleanupTxnState is part of the SenderWithDistSQLBackdoor interface.
+func (tc *TxnCoordSender) CleanupTxnState(ctx context.Context, txn roachpb.Transaction) {
+	tc.txnMu.Lock()
+	defer tc.txnMu.Unlock()
+	tc.cleanupTxnLocked(ctx, txn)
+}
+
 // GetTxnState is part of the SenderWithDistSQLBackdoor interface.
 func (tc *TxnCoordSender) GetTxnState(txnID uuid.UUID) (roachpb.Transaction, bool) {
 	tc.txnMu.Lock()
@@ -1727,3 +1727,102 @@ func TestTooManyIntentsAtCommit(t *testing.T) {
 		t.Fatal("did not expect value to exist")
 	}
 }
+
+// TestTxnCoordSenderAugmentTxnState verifies that the TxnCoordSender resolves
+// the intents written on behalf of a transaction by DistSQL leaf transactions,
+// which bypass it, once they have been handed over with AugmentTxnState. This
+// includes the intents written by a previous epoch of the transaction.
+func TestTxnCoordSenderAugmentTxnState(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	ctx := context.Background()
+	s, sender := createTestDB(t)
+	defer s.Stop()
+	defer teardownHeartbeats(sender)
+
+	txn := client.NewTxn(s.DB, 0 /* gatewayNodeID */)
+	// A transaction can only be tracked once it has a record or intents.
+	if err := sender.AugmentTxnState(ctx, *txn.Proto(), nil); !testutils.IsError(err,
+		"cannot track transaction without a record or intents") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := txn.AnchorForRemoteWrites(ctx, roachpb.Key("a")); err != nil {
+		t.Fatal(err)
+	}
+	txnID := txn.Proto().ID
+	if _, ok := sender.GetTxnState(txnID); !ok {
+		t.Fatal("expected the transaction to be tracked once anchored")
+	}
+
+	// leafWrite writes key through a leaf transaction with the given proto,
+	// which doesn't go through the TxnCoordSender, and hands its intents over
+	// to the root transaction.
+	leafDB := client.NewDB(sender.wrapped, s.Clock)
+	leafWrite := func(proto roachpb.Transaction, key roachpb.Key) {
+		t.Helper()
+		leaf := client.NewTxnWithProto(leafDB, 0 /* gatewayNodeID */, proto)
+		leaf.CollectIntents()
+		if err := leaf.Put(ctx, key, []byte("value")); err != nil {
+			t.Fatal(err)
+		}
+		leafTxn, intents := leaf.GetLeafState()
+		if err := txn.AugmentLeafState(ctx, leafTxn, intents); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	leafWrite(*txn.Proto(), roachpb.Key("b"))
+	// The transaction restarts, and the new epoch doesn't write "b" again.
+	restarted := txn.Proto().Clone()
+	restarted.BumpEpoch()
+	leafWrite(restarted, roachpb.Key("c"))
+
+	sender.txnMu.Lock()
+	keys, _ := roachpb.MergeSpans(append([]roachpb.Span(nil), sender.txnMu.txns[txnID].keys...))
+	epoch := sender.txnMu.txns[txnID].txn.Epoch
+	sender.txnMu.Unlock()
+	expected := []roachpb.Span{{Key: roachpb.Key("a")}, {Key: roachpb.Key("b")}, {Key: roachpb.Key("c")}}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Fatalf("expected intents %v, got %v", expected, keys)
+	}
+	if epoch != 1 {
+		t.Fatalf("expected the tracked transaction to be at epoch 1, got %d", epoch)
+	}
+
+	if err := txn.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+	verifyCleanup(roachpb.Key("b"), sender, s.Eng, t)
+	verifyCleanup(roachpb.Key("c"), sender, s.Eng, t)
+	// The write of the previous epoch was discarded.
+	for key, exists := range map[string]bool{"b": false, "c": true} {
+		if kv, err := s.DB.Get(ctx, key); err != nil {
+			t.Fatal(err)
+		} else if kv.Exists() != exists {
+			t.Errorf("%s: expected exists=%t, got %s", key, exists, kv)
+		}
+	}
+}
+
+// TestTxnCoordSenderCleanupTxnState verifies that CleanupTxnState stops the
+// tracking of a transaction.
+func TestTxnCoordSenderCleanupTxnState(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	ctx := context.Background()
+	s, sender := createTestDB(t)
+	defer s.Stop()
+	defer teardownHeartbeats(sender)
+
+	txn := client.NewTxn(s.DB, 0 /* gatewayNodeID */)
+	if err := txn.AnchorForRemoteWrites(ctx, roachpb.Key("a")); err != nil {
+		t.Fatal(err)
+	}
+	sender.CleanupTxnState(ctx, *txn.Proto())
+	testutils.SucceedsSoon(t, func() error {
+		if _, ok := sender.GetTxnState(txn.Proto().ID); ok {
+			return errors.New("transaction still tracked")
+		}
+		return nil
+	})
+	// Cleaning up a transaction which isn't tracked is a no-op.
+	sender.CleanupTxnState(ctx, *txn.Proto())
+}
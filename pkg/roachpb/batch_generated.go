@@ -9,7 +9,7 @@ import (
 	"strconv"
 )
 
-type reqCounts [36]int32
+type reqCounts [37]int32
 
 // getReqCounts returns the number of times each
 // request type appears in the batch.
@@ -89,6 +89,8 @@ func (ba *BatchRequest) getReqCounts() reqCounts {
 			counts[34]++
 		case r.AddSstable != nil:
 			counts[35]++
+		case r.FindSplitKeys != nil:
+			counts[36]++
 		default:
 			panic(fmt.Sprintf("unsupported request: %+v", r))
 		}
@@ -133,6 +135,7 @@ var requestNames = []string{
 	"QueryTxn",
 	"AdmScatter",
 	"AddSstable",
+	"FindSplitKeys",
 }
 
 // Summary prints a short summary of the requests in a batch.
@@ -203,6 +206,7 @@ func (ba *BatchRequest) CreateReply() *BatchResponse {
 	var buf33 []QueryTxnResponse
 	var buf34 []AdminScatterResponse
 	var buf35 []AddSSTableResponse
+	var buf36 []FindSplitKeysResponse
 
 	for i, r := range ba.Requests {
 		switch {
@@ -422,6 +426,12 @@ func (ba *BatchRequest) CreateReply() *BatchResponse {
 			}
 			br.Responses[i].AddSstable = &buf35[0]
 			buf35 = buf35[1:]
+		case r.FindSplitKeys != nil:
+			if buf36 == nil {
+				buf36 = make([]FindSplitKeysResponse, counts[36])
+			}
+			br.Responses[i].FindSplitKeys = &buf36[0]
+			buf36 = buf36[1:]
 		default:
 			panic(fmt.Sprintf("unsupported request: %+v", r))
 		}
@@ -471,6 +471,9 @@ func (e *WriteIntentError) message(_ *Error) string {
 			buf.WriteString(end[i].Key.String())
 		}
 	}
+	if e.LockTimeout {
+		buf.WriteString(" (lock timeout expired)")
+	}
 	return buf.String()
 }
 
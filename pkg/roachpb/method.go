@@ -133,4 +133,7 @@ const (
 	AdminScatter
 	// AddSSTable links a file into the RocksDB log-structured merge-tree.
 	AddSSTable
+	// FindSplitKeys returns keys that split a span into pieces of roughly
+	// equal size.
+	FindSplitKeys
 )
@@ -139,7 +139,34 @@ func UserAuthPasswordHook(insecureMode bool, password string, hashedPassword []b
 
 		// If the requested user has an empty password, disallow authentication.
 		if len(password) == 0 || CompareHashAndPassword(hashedPassword, password) != nil {
-			return errors.New("invalid password")
+			return ErrInvalidPassword
+		}
+
+		return nil
+	}
+}
+
+// UserAuthMD5Hook builds an authentication hook based on the security mode,
+// the response of the client to an MD5 challenge with the given salt and the
+// MD5 hash of the password.
+func UserAuthMD5Hook(
+	insecureMode bool, response string, salt []byte, md5Password string,
+) UserAuthHook {
+	return func(requestedUser string, clientConnection bool) error {
+		if len(requestedUser) == 0 {
+			return errors.New("user is missing")
+		}
+
+		if !clientConnection {
+			return errors.New("password authentication is only available for client connections")
+		}
+
+		if insecureMode {
+			return nil
+		}
+
+		if len(md5Password) == 0 || CompareMD5Response(md5Password, salt, response) != nil {
+			return ErrInvalidPassword
 		}
 
 		return nil
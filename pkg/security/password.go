@@ -16,9 +16,13 @@ package security
 
 import (
 	"bytes"
+	"crypto/md5"
 	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/pkg/errors"
 
@@ -34,6 +38,10 @@ const bcryptCost = bcrypt.DefaultCost
 // ErrEmptyPassword indicates that an empty password was attempted to be set.
 var ErrEmptyPassword = errors.New("empty passwords are not permitted")
 
+// ErrInvalidPassword indicates that a password, or the proof that a client
+// knows it, doesn't match the credentials of the user.
+var ErrInvalidPassword = errors.New("invalid password")
+
 // CompareHashAndPassword tests that the provided bytes are equivalent to the
 // hash of the supplied password. If they are not equivalent, returns an
 // error.
@@ -48,6 +56,29 @@ func HashPassword(password string) ([]byte, error) {
 	return bcrypt.GenerateFromPassword(h.Sum([]byte(password)), bcryptCost)
 }
 
+// HashPasswordMD5 takes a raw password and returns its hash for the MD5
+// authentication method of Postgres: "md5" followed by the MD5 of the password
+// and of the name of the user.
+func HashPasswordMD5(username, password string) string {
+	return md5Hex(password + username)
+}
+
+// CompareMD5Response tests that the response of a client to an MD5
+// authentication challenge with the given salt proves that it knows the
+// password with the given MD5 hash. If it doesn't, returns an error.
+func CompareMD5Response(md5Password string, salt []byte, response string) error {
+	expected := md5Hex(strings.TrimPrefix(md5Password, "md5") + string(salt))
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(response)) != 1 {
+		return ErrInvalidPassword
+	}
+	return nil
+}
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return "md5" + hex.EncodeToString(sum[:])
+}
+
 // PromptForPassword prompts for a password.
 // This is meant to be used when using a password.
 func PromptForPassword() (string, error) {
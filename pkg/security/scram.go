@@ -0,0 +1,289 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package security
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ScramMechanism is the name of the only SASL mechanism we support.
+const ScramMechanism = "SCRAM-SHA-256"
+
+// scramIterations is the number of iterations of the hash function used to
+// derive the SCRAM keys from a password. This is the default of Postgres.
+const scramIterations = 4096
+
+// scramSaltLen and scramNonceLen are the lengths in bytes of the random salts
+// and server nonces. These are the lengths used by Postgres.
+const (
+	scramSaltLen  = 16
+	scramNonceLen = 18
+)
+
+// ScramCredentials are the SCRAM-SHA-256 credentials of a user: the keys
+// derived from their password, from which the password can't be recovered.
+// See: https://tools.ietf.org/html/rfc5802#section-3
+type ScramCredentials struct {
+	Iterations int
+	Salt       []byte
+	StoredKey  []byte
+	ServerKey  []byte
+}
+
+// MakeScramCredentials derives the SCRAM credentials of a password with the
+// given salt and number of iterations.
+//
+// Unlike Postgres, we don't normalize the password with SASLprep: the
+// credentials only match clients that send the password as is, which is the
+// case of all clients for passwords which SASLprep leaves unchanged.
+func MakeScramCredentials(password string, salt []byte, iterations int) ScramCredentials {
+	saltedPassword := scramHi([]byte(password), salt, iterations)
+	clientKey := scramHMAC(saltedPassword, "Client Key")
+	storedKey := sha256.Sum256(clientKey)
+	return ScramCredentials{
+		Iterations: iterations,
+		Salt:       salt,
+		StoredKey:  storedKey[:],
+		ServerKey:  scramHMAC(saltedPassword, "Server Key"),
+	}
+}
+
+// HashPasswordScram takes a raw password and returns its SCRAM credentials
+// with a random salt, encoded like in the pg_authid table of Postgres.
+func HashPasswordScram(password string) (string, error) {
+	salt := make([]byte, scramSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	return MakeScramCredentials(password, salt, scramIterations).String(), nil
+}
+
+// String encodes the credentials in the format of Postgres:
+// SCRAM-SHA-256$<iterations>:<salt>$<StoredKey>:<ServerKey>.
+func (c ScramCredentials) String() string {
+	enc := base64.StdEncoding
+	return fmt.Sprintf("%s$%d:%s$%s:%s", ScramMechanism, c.Iterations,
+		enc.EncodeToString(c.Salt), enc.EncodeToString(c.StoredKey), enc.EncodeToString(c.ServerKey))
+}
+
+// ParseScramCredentials decodes credentials encoded by String.
+func ParseScramCredentials(s string) (ScramCredentials, error) {
+	var c ScramCredentials
+	parts := strings.Split(s, "$")
+	if len(parts) != 3 || parts[0] != ScramMechanism {
+		return c, errors.Errorf("invalid SCRAM credentials %q", s)
+	}
+	params, keys := strings.Split(parts[1], ":"), strings.Split(parts[2], ":")
+	if len(params) != 2 || len(keys) != 2 {
+		return c, errors.Errorf("invalid SCRAM credentials %q", s)
+	}
+	var err error
+	if c.Iterations, err = strconv.Atoi(params[0]); err != nil || c.Iterations <= 0 {
+		return c, errors.Errorf("invalid SCRAM iteration count %q", params[0])
+	}
+	enc := base64.StdEncoding
+	for _, f := range []struct {
+		dst *[]byte
+		src string
+	}{
+		{&c.Salt, params[1]},
+		{&c.StoredKey, keys[0]},
+		{&c.ServerKey, keys[1]},
+	} {
+		if *f.dst, err = enc.DecodeString(f.src); err != nil {
+			return c, errors.Wrapf(err, "invalid SCRAM credentials %q", s)
+		}
+	}
+	return c, nil
+}
+
+// MakeScramNonce returns a random nonce for a ScramServer.
+func MakeScramNonce() (string, error) {
+	b := make([]byte, scramNonceLen)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// ScramServer is the server side of a SCRAM-SHA-256 exchange, in which the
+// client proves that it knows the password of the user without sending it.
+// Channel binding isn't supported.
+// See: https://tools.ietf.org/html/rfc5802#section-5
+type ScramServer struct {
+	creds       ScramCredentials
+	serverNonce string
+
+	// The state of the exchange, set by ServerFirst.
+	gs2Header       string
+	nonce           string
+	clientFirstBare string
+	serverFirst     string
+}
+
+// NewScramServer creates a ScramServer for the given credentials. The
+// server nonce should come from MakeScramNonce.
+func NewScramServer(creds ScramCredentials, serverNonce string) *ScramServer {
+	return &ScramServer{creds: creds, serverNonce: serverNonce}
+}
+
+// ServerFirst processes the client-first-message of the exchange and
+// returns the server-first-message.
+func (s *ScramServer) ServerFirst(clientFirst []byte) ([]byte, error) {
+	msg := string(clientFirst)
+	// The GS2 header is made of the channel binding flag and of an optional
+	// authorization identity.
+	var cbFlag, authzID string
+	var ok bool
+	if cbFlag, msg, ok = scramCut(msg); !ok {
+		return nil, errors.New("malformed SCRAM message")
+	}
+	switch {
+	case cbFlag == "n", cbFlag == "y":
+	case strings.HasPrefix(cbFlag, "p="):
+		return nil, errors.New("SCRAM channel binding is not supported")
+	default:
+		return nil, errors.Errorf("malformed SCRAM channel binding flag %q", cbFlag)
+	}
+	if authzID, msg, ok = scramCut(msg); !ok {
+		return nil, errors.New("malformed SCRAM message")
+	}
+	if authzID != "" {
+		return nil, errors.New("SCRAM authorization identities are not supported")
+	}
+	s.gs2Header = cbFlag + "," + authzID + ","
+	s.clientFirstBare = msg
+
+	// The user name is ignored, like in Postgres: the user is the one
+	// given in the startup message of the connection.
+	attrs, err := scramAttributes(msg)
+	if err != nil {
+		return nil, err
+	}
+	if len(attrs) < 2 || attrs[0].name != 'n' || attrs[1].name != 'r' || attrs[1].value == "" {
+		return nil, errors.New("malformed SCRAM client-first-message")
+	}
+	s.nonce = attrs[1].value + s.serverNonce
+	s.serverFirst = fmt.Sprintf("r=%s,s=%s,i=%d",
+		s.nonce, base64.StdEncoding.EncodeToString(s.creds.Salt), s.creds.Iterations)
+	return []byte(s.serverFirst), nil
+}
+
+// ServerFinal processes the client-final-message of the exchange and returns
+// the server-final-message. ErrInvalidPassword is returned if the proof
+// sent by the client doesn't match the credentials.
+func (s *ScramServer) ServerFinal(clientFinal []byte) ([]byte, error) {
+	msg := string(clientFinal)
+	attrs, err := scramAttributes(msg)
+	if err != nil {
+		return nil, err
+	}
+	if len(attrs) < 3 || attrs[0].name != 'c' || attrs[1].name != 'r' {
+		return nil, errors.New("malformed SCRAM client-final-message")
+	}
+	proofAttr := attrs[len(attrs)-1]
+	if proofAttr.name != 'p' {
+		return nil, errors.New("malformed SCRAM client-final-message")
+	}
+	if attrs[0].value != base64.StdEncoding.EncodeToString([]byte(s.gs2Header)) {
+		return nil, errors.New("SCRAM channel binding doesn't match the GS2 header")
+	}
+	if attrs[1].value != s.nonce {
+		return nil, errors.New("SCRAM nonce doesn't match")
+	}
+	proof, err := base64.StdEncoding.DecodeString(proofAttr.value)
+	if err != nil || len(proof) != sha256.Size {
+		return nil, errors.New("malformed SCRAM client proof")
+	}
+
+	withoutProof := msg[:strings.LastIndex(msg, ",p=")]
+	authMessage := s.clientFirstBare + "," + s.serverFirst + "," + withoutProof
+	clientSignature := scramHMAC(s.creds.StoredKey, authMessage)
+	clientKey := make([]byte, len(proof))
+	for i := range proof {
+		clientKey[i] = proof[i] ^ clientSignature[i]
+	}
+	storedKey := sha256.Sum256(clientKey)
+	if subtle.ConstantTimeCompare(storedKey[:], s.creds.StoredKey) != 1 {
+		return nil, ErrInvalidPassword
+	}
+
+	serverSignature := scramHMAC(s.creds.ServerKey, authMessage)
+	return []byte("v=" + base64.StdEncoding.EncodeToString(serverSignature)), nil
+}
+
+type scramAttribute struct {
+	name  byte
+	value string
+}
+
+// scramAttributes splits a SCRAM message into its attributes.
+func scramAttributes(msg string) ([]scramAttribute, error) {
+	var attrs []scramAttribute
+	for _, a := range strings.Split(msg, ",") {
+		if len(a) < 2 || a[1] != '=' {
+			return nil, errors.Errorf("malformed SCRAM attribute %q", a)
+		}
+		attrs = append(attrs, scramAttribute{name: a[0], value: a[2:]})
+	}
+	return attrs, nil
+}
+
+// scramCut returns the part of msg before its first comma, and the rest.
+func scramCut(msg string) (string, string, bool) {
+	i := strings.IndexByte(msg, ',')
+	if i < 0 {
+		return "", "", false
+	}
+	return msg[:i], msg[i+1:], true
+}
+
+func scramHMAC(key []byte, msg string) []byte {
+	h := hmac.New(sha256.New, key)
+	_, _ = h.Write([]byte(msg))
+	return h.Sum(nil)
+}
+
+// scramHi is the Hi function of SCRAM, i.e. PBKDF2 with HMAC-SHA-256 and an
+// output of a single block.
+func scramHi(password, salt []byte, iterations int) []byte {
+	h := hmac.New(sha256.New, password)
+	var buf bytes.Buffer
+	buf.Write(salt)
+	_ = binary.Write(&buf, binary.BigEndian, uint32(1))
+	_, _ = h.Write(buf.Bytes())
+	u := h.Sum(nil)
+	result := append([]byte(nil), u...)
+	for i := 1; i < iterations; i++ {
+		h.Reset()
+		_, _ = h.Write(u)
+		u = h.Sum(u[:0])
+		for j := range result {
+			result[j] ^= u[j]
+		}
+	}
+	return result
+}
@@ -0,0 +1,144 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package security_test
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/security"
+	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+// TestScramExchange runs the SCRAM-SHA-256 exchange of RFC 7677.
+// See: https://tools.ietf.org/html/rfc7677#section-3
+func TestScramExchange(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	const (
+		clientFirst = "n,,n=user,r=rOprNGfwEbeRWgbNEkqO"
+		serverNonce = "%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0"
+		serverFirst = "r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0," +
+			"s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096"
+		clientFinal = "c=biws,r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0," +
+			"p=dHzbZapWIk4jUhN+Ute9ytag9zjfMHgsqmmiz7AndVQ="
+		serverFinal = "v=6rriTRBi23WpRR/wtup+mMhUZUn/dB5nLTJRsjl95G4="
+	)
+	salt, err := base64.StdEncoding.DecodeString("W22ZaJ0SNY7soEsUEjb6gQ==")
+	if err != nil {
+		t.Fatal(err)
+	}
+	creds := security.MakeScramCredentials("pencil", salt, 4096)
+
+	s := security.NewScramServer(creds, serverNonce)
+	msg, err := s.ServerFirst([]byte(clientFirst))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(msg) != serverFirst {
+		t.Fatalf("expected %q, got %q", serverFirst, msg)
+	}
+	msg, err = s.ServerFinal([]byte(clientFinal))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(msg) != serverFinal {
+		t.Fatalf("expected %q, got %q", serverFinal, msg)
+	}
+
+	// A wrong password doesn't give the same proof.
+	s = security.NewScramServer(security.MakeScramCredentials("pen", salt, 4096), serverNonce)
+	if _, err := s.ServerFirst([]byte(clientFirst)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ServerFinal([]byte(clientFinal)); err != security.ErrInvalidPassword {
+		t.Fatalf("expected %v, got %v", security.ErrInvalidPassword, err)
+	}
+
+	// Malformed or unsupported messages.
+	for _, tc := range []struct {
+		clientFirst, clientFinal string
+		expected                 string
+	}{
+		{"n=user,r=abc", "", "malformed SCRAM"},
+		{"p=tls-unique,,n=user,r=abc", "", "channel binding is not supported"},
+		{"n,a=admin,n=user,r=abc", "", "authorization identities are not supported"},
+		{"n,,r=abc", "", "malformed SCRAM client-first-message"},
+		{clientFirst, "c=biws,r=abc,p=dHzbZapWIk4jUhN+Ute9ytag9zjfMHgsqmmiz7AndVQ=", "nonce doesn't match"},
+		{clientFirst, "c=eSws,r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,p=abc", "doesn't match the GS2 header"},
+		{clientFirst, "c=biws,r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0", "malformed SCRAM client-final-message"},
+	} {
+		s := security.NewScramServer(creds, serverNonce)
+		_, err := s.ServerFirst([]byte(tc.clientFirst))
+		if err == nil {
+			_, err = s.ServerFinal([]byte(tc.clientFinal))
+		}
+		if !testutils.IsError(err, tc.expected) {
+			t.Errorf("%q, %q: expected %q, got %v", tc.clientFirst, tc.clientFinal, tc.expected, err)
+		}
+	}
+}
+
+func TestScramCredentialsEncoding(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	encoded, err := security.HashPasswordScram("pencil")
+	if err != nil {
+		t.Fatal(err)
+	}
+	creds, err := security.ParseScramCredentials(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.Iterations != 4096 || len(creds.Salt) != 16 {
+		t.Fatalf("unexpected credentials %+v", creds)
+	}
+	if expected := security.MakeScramCredentials("pencil", creds.Salt, 4096); expected.String() != encoded {
+		t.Fatalf("expected %s, got %s", expected, encoded)
+	}
+
+	for _, s := range []string{
+		"",
+		"md5abc",
+		"SCRAM-SHA-256$4096:abc",
+		"SCRAM-SHA-256$x:c2FsdA==$a2V5:a2V5",
+		"SCRAM-SHA-256$4096:c2FsdA==$!!!:a2V5",
+	} {
+		if _, err := security.ParseScramCredentials(s); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+}
+
+func TestMD5Password(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	md5Password := security.HashPasswordMD5("foo", "pencil")
+	if expected := "md55455d83b70e74ce9d63aed9409d89b74"; md5Password != expected {
+		t.Fatalf("expected %s, got %s", expected, md5Password)
+	}
+	salt := []byte{1, 2, 3, 4}
+	if err := security.CompareMD5Response(
+		md5Password, salt, "md5348cdbdafa3856e35cbd360ffc22db22",
+	); err != nil {
+		t.Fatal(err)
+	}
+	if err := security.CompareMD5Response(
+		md5Password, []byte{1, 2, 3, 5}, "md5348cdbdafa3856e35cbd360ffc22db22",
+	); err != security.ErrInvalidPassword {
+		t.Fatalf("expected %v, got %v", security.ErrInvalidPassword, err)
+	}
+}
@@ -237,7 +237,7 @@ WHERE id = $1`
 func (s *authenticationServer) verifyPassword(
 	ctx context.Context, username string, password string,
 ) (bool, error) {
-	exists, hashedPassword, err := sql.GetUserHashedPassword(
+	exists, creds, err := sql.GetUserCredentials(
 		ctx, s.server.sqlExecutor, s.memMetrics, username,
 	)
 	if err != nil {
@@ -246,7 +246,7 @@ func (s *authenticationServer) verifyPassword(
 	if !exists {
 		return false, nil
 	}
-	return (security.CompareHashAndPassword(hashedPassword, password) == nil), nil
+	return (security.CompareHashAndPassword(creds.HashedPassword, password) == nil), nil
 }
 
 // newAuthSession attempts to create a new authentication session for the given
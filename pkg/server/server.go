@@ -130,6 +130,7 @@ type Server struct {
 	sqlExecutor        *sql.Executor
 	leaseMgr           *sql.LeaseManager
 	sessionRegistry    *sql.SessionRegistry
+	notifyRegistry     *sql.NotificationRegistry
 	jobRegistry        *jobs.Registry
 	engines            Engines
 	internalMemMetrics sql.MemoryMetrics
@@ -395,6 +396,9 @@ func NewServer(cfg Config, stopper *stop.Stopper) (*Server, error) {
 	serverpb.RegisterInitServer(s.grpc, &noopInitServer{clusterID: s.ClusterID})
 
 	s.sessionRegistry = sql.MakeSessionRegistry()
+	s.notifyRegistry = sql.NewNotificationRegistry(
+		s.cfg.AmbientCtx, s.gossip, &s.nodeIDContainer, s.rpcContext, s.stopper,
+	)
 	s.jobRegistry = jobs.MakeRegistry(
 		s.clock, s.db, sqlExecutor, s.gossip, &s.nodeIDContainer, s.ClusterID, st)
 
@@ -442,6 +446,7 @@ func NewServer(cfg Config, stopper *stop.Stopper) (*Server, error) {
 		s.node.stores,
 		s.stopper,
 		s.sessionRegistry,
+		s.notifyRegistry,
 	)
 	s.authentication = newAuthenticationServer(s)
 	for _, gw := range []grpcGatewayServer{s.admin, s.status, s.authentication, &s.tsServer} {
@@ -469,6 +474,7 @@ func NewServer(cfg Config, stopper *stop.Stopper) (*Server, error) {
 		DistSQLSrv:              s.distSQLServer,
 		StatusServer:            s.status,
 		SessionRegistry:         s.sessionRegistry,
+		NotificationRegistry:    s.notifyRegistry,
 		JobRegistry:             s.jobRegistry,
 		HistogramWindowInterval: s.cfg.HistogramWindowInterval(),
 		RangeDescriptorCache:    s.distSender.RangeDescriptorCache(),
@@ -490,7 +496,9 @@ func NewServer(cfg Config, stopper *stop.Stopper) (*Server, error) {
 	s.pgServer = pgwire.MakeServer(
 		s.cfg.AmbientCtx,
 		s.cfg.Config,
+		s.st,
 		s.sqlExecutor,
+		s.status,
 		&s.internalMemMetrics,
 		&rootSQLMemoryMonitor,
 		s.cfg.HistogramWindowInterval(),
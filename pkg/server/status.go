@@ -101,6 +101,7 @@ type statusServer struct {
 	stores          *storage.Stores
 	stopper         *stop.Stopper
 	sessionRegistry *sql.SessionRegistry
+	notifyRegistry  *sql.NotificationRegistry
 }
 
 // newStatusServer allocates and returns a statusServer.
@@ -116,6 +117,7 @@ func newStatusServer(
 	stores *storage.Stores,
 	stopper *stop.Stopper,
 	sessionRegistry *sql.SessionRegistry,
+	notifyRegistry *sql.NotificationRegistry,
 ) *statusServer {
 	ambient.AddLogTag("status", nil)
 	server := &statusServer{
@@ -130,6 +132,7 @@ func newStatusServer(
 		stores:          stores,
 		stopper:         stopper,
 		sessionRegistry: sessionRegistry,
+		notifyRegistry:  notifyRegistry,
 	}
 
 	return server
@@ -1255,6 +1258,54 @@ func (s *statusServer) CancelQuery(
 	return output, nil
 }
 
+// Notify delivers notifications committed on another node to the sessions
+// of a node that listen on their channel.
+func (s *statusServer) Notify(
+	ctx context.Context, req *serverpb.NotifyRequest,
+) (*serverpb.NotifyResponse, error) {
+	ctx = s.AnnotateCtx(ctx)
+	nodeID, local, err := s.parseNodeID(req.NodeID)
+	if err != nil {
+		return nil, grpc.Errorf(codes.InvalidArgument, err.Error())
+	}
+
+	if !local {
+		status, err := s.dialNode(nodeID)
+		if err != nil {
+			return nil, err
+		}
+		return status.Notify(ctx, req)
+	}
+
+	s.notifyRegistry.Deliver(req.Notifications)
+	return &serverpb.NotifyResponse{}, nil
+}
+
+// CancelSession cancels the queries in flight of the session with the given
+// secret key, on behalf of a pgwire CancelRequest. Unlike CancelQuery, it
+// isn't exposed over HTTP.
+func (s *statusServer) CancelSession(
+	ctx context.Context, req *serverpb.CancelSessionRequest,
+) (*serverpb.CancelSessionResponse, error) {
+	ctx = s.AnnotateCtx(ctx)
+	nodeID, local, err := s.parseNodeID(req.NodeID)
+	if err != nil {
+		return nil, grpc.Errorf(codes.InvalidArgument, err.Error())
+	}
+
+	if !local {
+		status, err := s.dialNode(nodeID)
+		if err != nil {
+			return nil, err
+		}
+		return status.CancelSession(ctx, req)
+	}
+
+	return &serverpb.CancelSessionResponse{
+		Cancelled: s.sessionRegistry.CancelSessionQueries(req.CancelKey),
+	}, nil
+}
+
 // SpanStats requests the total statistics stored on a node for a given key
 // span, which may include multiple ranges.
 func (s *statusServer) SpanStats(
@@ -48,6 +48,7 @@ func (p *planner) AlterTable(ctx context.Context, n *tree.AlterTable) (planNode,
 	}
 	if tableDesc == nil {
 		if n.IfExists {
+			p.noticeSkipping("relation", tree.ErrString(tn))
 			return &zeroNode{}, nil
 		}
 		return nil, sqlbase.NewUndefinedRelationError(tn)
@@ -141,6 +142,9 @@ func (n *alterTableNode) Start(params runParams) error {
 				if err := idx.FillColumns(d.Columns); err != nil {
 					return err
 				}
+				if err := n.tableDesc.CheckIndexCollations(d.Columns); err != nil {
+					return err
+				}
 				_, dropped, err := n.tableDesc.FindIndexByName(string(d.Name))
 				if err == nil {
 					if dropped {
@@ -189,6 +193,9 @@ func (n *alterTableNode) Start(params runParams) error {
 			if err != nil {
 				if t.IfExists {
 					// Noop.
+					params.p.noticef(pgerror.SeverityNotice,
+						"column %q of relation %q does not exist, skipping",
+						string(t.Column), n.tableDesc.Name)
 					continue
 				}
 				return err
@@ -318,6 +325,9 @@ func (n *alterTableNode) Start(params runParams) error {
 			details, ok := info[name]
 			if !ok {
 				if t.IfExists {
+					params.p.noticef(pgerror.SeverityNotice,
+						"constraint %q of relation %q does not exist, skipping",
+						name, n.tableDesc.Name)
 					continue
 				}
 				return fmt.Errorf("constraint %q does not exist", t.Constraint)
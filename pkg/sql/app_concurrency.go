@@ -0,0 +1,182 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/util/metric"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+)
+
+// appConcurrencyLimits bounds the number of statements which the sessions of
+// each application can run concurrently on a node.
+var appConcurrencyLimits = settings.RegisterValidatedStringSetting(
+	"sql.application_concurrency.limits",
+	"comma-separated list of <application_name>=<limit> entries bounding the number of "+
+		"statements which the sessions of each application can run concurrently on each node",
+	"",
+	func(s string) error {
+		_, err := parseAppConcurrencyLimits(s)
+		return err
+	},
+)
+
+// appConcurrencyQueueTimeout is the maximum duration for which statements
+// over the concurrency limit of their application are queued.
+var appConcurrencyQueueTimeout = settings.RegisterNonNegativeDurationSetting(
+	"sql.application_concurrency.queue_timeout",
+	"maximum duration for which a statement over the concurrency limit of its application "+
+		"waits for another statement to finish before it is rejected (0 = reject immediately)",
+	0,
+)
+
+// parseAppConcurrencyLimits parses the value of the
+// sql.application_concurrency.limits setting into the limit of each
+// application.
+func parseAppConcurrencyLimits(s string) (map[string]int64, error) {
+	limits := make(map[string]int64)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		i := strings.LastIndexByte(entry, '=')
+		if i < 0 {
+			return nil, errors.Errorf("invalid application concurrency limit %q: "+
+				"expected <application_name>=<limit>", entry)
+		}
+		appName := strings.TrimSpace(entry[:i])
+		limit, err := strconv.ParseInt(strings.TrimSpace(entry[i+1:]), 10, 64)
+		if err != nil || limit < 0 {
+			return nil, errors.Errorf("invalid application concurrency limit %q: "+
+				"the limit must be a non-negative integer", entry)
+		}
+		limits[appName] = limit
+	}
+	return limits, nil
+}
+
+// appConcurrencyLimiter bounds the number of statements which the sessions of
+// each application run concurrently on this node, according to the
+// sql.application_concurrency.limits setting. It hangs off Executor.
+type appConcurrencyLimiter struct {
+	st       *cluster.Settings
+	rejected *metric.Counter
+
+	mu struct {
+		syncutil.Mutex
+		// limitsSetting is the last value of the setting seen, and limits is
+		// the result of its parsing.
+		limitsSetting string
+		limits        map[string]int64
+		// running is the number of statements running for each application
+		// with a limit.
+		running map[string]int64
+		// released is closed, and replaced, when a statement finishes, to wake
+		// up the queued statements.
+		released chan struct{}
+	}
+}
+
+func makeAppConcurrencyLimiter(
+	st *cluster.Settings, rejected *metric.Counter,
+) *appConcurrencyLimiter {
+	l := &appConcurrencyLimiter{st: st, rejected: rejected}
+	l.mu.running = make(map[string]int64)
+	l.mu.released = make(chan struct{})
+	return l
+}
+
+// limitLocked returns the concurrency limit of the given application, if it
+// has one. l.mu must be held.
+func (l *appConcurrencyLimiter) limitLocked(appName string) (int64, bool) {
+	if s := appConcurrencyLimits.Get(&l.st.SV); s != l.mu.limitsSetting || l.mu.limits == nil {
+		limits, err := parseAppConcurrencyLimits(s)
+		if err != nil {
+			// The setting is validated, so this can only be a value gossiped by a
+			// node with a different validation.
+			limits = nil
+		}
+		l.mu.limitsSetting = s
+		l.mu.limits = limits
+	}
+	limit, ok := l.mu.limits[appName]
+	return limit, ok
+}
+
+// acquire waits until the given application is under its concurrency limit,
+// up to sql.application_concurrency.queue_timeout, and counts a statement
+// against the limit until the returned function is called. A statement which
+// can't run is rejected with a too_many_connections error.
+func (l *appConcurrencyLimiter) acquire(ctx context.Context, appName string) (func(), error) {
+	var timeoutC <-chan time.Time
+	for {
+		l.mu.Lock()
+		limit, ok := l.limitLocked(appName)
+		if !ok {
+			l.mu.Unlock()
+			return func() {}, nil
+		}
+		if l.mu.running[appName] < limit {
+			l.mu.running[appName]++
+			l.mu.Unlock()
+			return func() { l.release(appName) }, nil
+		}
+		released := l.mu.released
+		l.mu.Unlock()
+
+		if timeoutC == nil {
+			timeout := appConcurrencyQueueTimeout.Get(&l.st.SV)
+			if timeout == 0 {
+				return nil, l.reject(appName, limit)
+			}
+			timer := time.NewTimer(timeout)
+			defer timer.Stop()
+			timeoutC = timer.C
+		}
+		select {
+		case <-released:
+		case <-timeoutC:
+			return nil, l.reject(appName, limit)
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (l *appConcurrencyLimiter) release(appName string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.mu.running[appName]--; l.mu.running[appName] == 0 {
+		delete(l.mu.running, appName)
+	}
+	close(l.mu.released)
+	l.mu.released = make(chan struct{})
+}
+
+func (l *appConcurrencyLimiter) reject(appName string, limit int64) error {
+	l.rejected.Inc(1)
+	return pgerror.NewErrorf(pgerror.CodeTooManyConnectionsError,
+		"too many concurrent statements for application %q (limit %d)", appName, limit)
+}
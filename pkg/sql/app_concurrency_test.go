@@ -0,0 +1,125 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/metric"
+)
+
+func TestParseAppConcurrencyLimits(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	testCases := []struct {
+		s        string
+		expected map[string]int64
+		err      string
+	}{
+		{"", map[string]int64{}, ""},
+		{"a=1", map[string]int64{"a": 1}, ""},
+		{" a = 1 , b c=0,", map[string]int64{"a": 1, "b c": 0}, ""},
+		{"=5", map[string]int64{"": 5}, ""},
+		{"a", nil, `invalid application concurrency limit "a"`},
+		{"a=-1", nil, "the limit must be a non-negative integer"},
+		{"a=b", nil, "the limit must be a non-negative integer"},
+	}
+	for _, tc := range testCases {
+		limits, err := parseAppConcurrencyLimits(tc.s)
+		if !testutils.IsError(err, tc.err) {
+			t.Errorf("%q: expected error %q, got %v", tc.s, tc.err, err)
+		} else if !reflect.DeepEqual(limits, tc.expected) {
+			t.Errorf("%q: expected %v, got %v", tc.s, tc.expected, limits)
+		}
+	}
+}
+
+func TestAppConcurrencyLimiter(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	st := cluster.MakeTestingClusterSettings()
+	u := st.MakeUpdater()
+	if err := u.Set(
+		"sql.application_concurrency.limits", "a=1,b=0", appConcurrencyLimits.Typ(),
+	); err != nil {
+		t.Fatal(err)
+	}
+	l := makeAppConcurrencyLimiter(st, metric.NewCounter(MetaAppConcurrencyRejected))
+
+	expectRejected := func(appName string, expectedCount int64) {
+		t.Helper()
+		_, err := l.acquire(ctx, appName)
+		if pgErr, ok := pgerror.GetPGCause(err); !ok ||
+			pgErr.Code != pgerror.CodeTooManyConnectionsError {
+			t.Fatalf("expected a too_many_connections error, got %v", err)
+		}
+		if count := l.rejected.Count(); count != expectedCount {
+			t.Fatalf("expected %d rejected statements, got %d", expectedCount, count)
+		}
+	}
+
+	// The applications without a limit aren't limited.
+	for i := 0; i < 3; i++ {
+		if _, err := l.acquire(ctx, "c"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	release, err := l.acquire(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectRejected("a", 1)
+	expectRejected("b", 2)
+
+	// With a queue timeout, the statements over the limit wait for a slot.
+	if err := u.Set(
+		"sql.application_concurrency.queue_timeout", "1m", appConcurrencyQueueTimeout.Typ(),
+	); err != nil {
+		t.Fatal(err)
+	}
+	acquired := make(chan error)
+	go func() {
+		release, err := l.acquire(ctx, "a")
+		if err == nil {
+			release()
+		}
+		acquired <- err
+	}()
+	release()
+	if err := <-acquired; err != nil {
+		t.Fatal(err)
+	}
+
+	// The queued statements give up when their context is canceled.
+	release, err = l.acquire(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+	cancelCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := l.acquire(cancelCtx, "a"); err != context.Canceled {
+		t.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+}
@@ -189,6 +189,13 @@ func (s *Session) resetApplicationName(appName string) {
 	}
 }
 
+// applicationName returns the value of the application_name session variable.
+func (s *Session) applicationName() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.mu.ApplicationName
+}
+
 func (s *sqlStats) getStatsForApplication(appName string) *appStats {
 	s.Lock()
 	defer s.Unlock()
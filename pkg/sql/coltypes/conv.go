@@ -84,6 +84,10 @@ func DatumTypeToColumnType(t types.T) (T, error) {
 		return Interval, nil
 	case types.JSON:
 		return JSON, nil
+	case types.TSVector:
+		return TSVector, nil
+	case types.TSQuery:
+		return TSQuery, nil
 	case types.UUID:
 		return UUID, nil
 	case types.INet:
@@ -154,6 +158,10 @@ func CastTargetToDatumType(t CastTargetType) types.T {
 		return types.Interval
 	case *TJSON:
 		return types.JSON
+	case *TTSVector:
+		return types.TSVector
+	case *TTSQuery:
+		return types.TSQuery
 	case *TUUID:
 		return types.UUID
 	case *TIPAddr:
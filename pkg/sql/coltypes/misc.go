@@ -54,6 +54,22 @@ func (node *TJSON) Format(buf *bytes.Buffer, _ lex.EncodeFlags) {
 	buf.WriteString(node.Name)
 }
 
+// TTSVector represents the TSVECTOR column type.
+type TTSVector struct{}
+
+// Format implements the ColTypeFormatter interface.
+func (node *TTSVector) Format(buf *bytes.Buffer, _ lex.EncodeFlags) {
+	buf.WriteString("TSVECTOR")
+}
+
+// TTSQuery represents the TSQUERY column type.
+type TTSQuery struct{}
+
+// Format implements the ColTypeFormatter interface.
+func (node *TTSQuery) Format(buf *bytes.Buffer, _ lex.EncodeFlags) {
+	buf.WriteString("TSQUERY")
+}
+
 // TOid represents an OID type, which is the type of system object
 // identifiers. There are several different OID types: the raw OID type, which
 // can be any integer, and the reg* types, each of which corresponds to the
@@ -16,19 +16,107 @@ package sql
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
-	"io"
+	"strings"
 	"unsafe"
 
 	"golang.org/x/net/context"
 
 	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	"github.com/cockroachdb/cockroach/pkg/sql/privilege"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/types"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
 )
 
+// CopyFormat describes how the data of a COPY statement is encoded: the
+// options of the statement, with the defaults of the format filled in.
+type CopyFormat struct {
+	Format tree.CopyFormat
+	// Header is set if the first line of CSV data holds the column names.
+	Header    bool
+	Delimiter byte
+	Null      string
+	// Quote is only used by the CSV format.
+	Quote byte
+}
+
+// MakeCopyFormat checks the options of a COPY statement and fills in the
+// defaults of the format.
+// See: https://www.postgresql.org/docs/current/static/sql-copy.html
+func MakeCopyFormat(opts tree.CopyOptions) (CopyFormat, error) {
+	f := CopyFormat{Format: opts.FileFormat}
+	switch f.Format {
+	case tree.CopyFormatUnspecified, tree.CopyFormatText:
+		f.Format = tree.CopyFormatText
+		f.Delimiter, f.Null = '\t', `\N`
+	case tree.CopyFormatCSV:
+		f.Delimiter, f.Null, f.Quote = ',', "", '"'
+	case tree.CopyFormatBinary:
+		if opts.Delimiter != nil {
+			return f, pgerror.NewError(pgerror.CodeSyntaxError,
+				"cannot specify DELIMITER in BINARY mode")
+		}
+		if opts.Null != nil {
+			return f, pgerror.NewError(pgerror.CodeSyntaxError,
+				"cannot specify NULL in BINARY mode")
+		}
+	}
+
+	if opts.Header != nil {
+		if f.Format != tree.CopyFormatCSV {
+			return f, pgerror.NewError(pgerror.CodeFeatureNotSupportedError,
+				"COPY HEADER available only in CSV mode")
+		}
+		f.Header = *opts.Header
+	}
+	if opts.Quote != nil {
+		if f.Format != tree.CopyFormatCSV {
+			return f, pgerror.NewError(pgerror.CodeFeatureNotSupportedError,
+				"COPY quote available only in CSV mode")
+		}
+		if len(*opts.Quote) != 1 {
+			return f, pgerror.NewError(pgerror.CodeFeatureNotSupportedError,
+				"COPY quote must be a single one-byte character")
+		}
+		f.Quote = (*opts.Quote)[0]
+	}
+	if opts.Delimiter != nil {
+		if len(*opts.Delimiter) != 1 {
+			return f, pgerror.NewError(pgerror.CodeFeatureNotSupportedError,
+				"COPY delimiter must be a single one-byte character")
+		}
+		f.Delimiter = (*opts.Delimiter)[0]
+	}
+	if opts.Null != nil {
+		f.Null = *opts.Null
+	}
+
+	if f.Format == tree.CopyFormatBinary {
+		return f, nil
+	}
+	if f.Delimiter == '\n' || f.Delimiter == '\r' {
+		return f, pgerror.NewError(pgerror.CodeInvalidParameterValueError,
+			"COPY delimiter cannot be newline or carriage return")
+	}
+	if strings.ContainsAny(f.Null, "\r\n") {
+		return f, pgerror.NewError(pgerror.CodeInvalidParameterValueError,
+			"COPY null representation cannot use newline or carriage return")
+	}
+	if f.Format == tree.CopyFormatText &&
+		strings.IndexByte(`\.abcdefghijklmnopqrstuvwxyz0123456789`, f.Delimiter) >= 0 {
+		return f, pgerror.NewErrorf(pgerror.CodeFeatureNotSupportedError,
+			"COPY delimiter cannot be \"%c\"", f.Delimiter)
+	}
+	if f.Format == tree.CopyFormatCSV && f.Delimiter == f.Quote {
+		return f, pgerror.NewError(pgerror.CodeFeatureNotSupportedError,
+			"COPY delimiter and quote must be different")
+	}
+	return f, nil
+}
+
 // COPY FROM is not a usual planNode. After a COPY FROM is executed as a
 // planNode and until an error or it is explicitly completed, the planner
 // carries a reference to a copyNode to send the contents of copy data
@@ -47,9 +135,17 @@ type copyNode struct {
 	table         tree.TableExpr
 	columns       tree.UnresolvedNames
 	resultColumns sqlbase.ResultColumns
+	format        CopyFormat
 	buf           bytes.Buffer
 	rows          []*tree.Tuple
 	rowsMemAcc    WrappableMemoryAccount
+
+	// headerDone is set once the CSV header line or the header of the binary
+	// format has been consumed.
+	headerDone bool
+	// done is set once the end-of-data marker has been seen. Any data after
+	// it is ignored.
+	done bool
 }
 
 func (*copyNode) Values() tree.Datums          { return nil }
@@ -62,9 +158,14 @@ func (n *copyNode) Close(ctx context.Context) {
 // CopyFrom begins a COPY.
 // Privileges: INSERT on table.
 func (p *planner) CopyFrom(ctx context.Context, n *tree.CopyFrom) (planNode, error) {
+	format, err := MakeCopyFormat(n.Options)
+	if err != nil {
+		return nil, err
+	}
 	cn := &copyNode{
 		table:   &n.Table,
 		columns: n.Columns,
+		format:  format,
 	}
 
 	tn, err := n.Table.NormalizeWithDatabaseName(p.session.Database)
@@ -88,6 +189,33 @@ func (p *planner) CopyFrom(ctx context.Context, n *tree.CopyFrom) (planNode, err
 	return cn, nil
 }
 
+// CopyTo plans a COPY TO STDOUT. Its rows are returned like those of the
+// equivalent query; it is up to the client protocol to encode them in the
+// COPY format.
+// Privileges: SELECT on table.
+func (p *planner) CopyTo(ctx context.Context, n *tree.CopyTo) (planNode, error) {
+	if _, err := MakeCopyFormat(n.Options); err != nil {
+		return nil, err
+	}
+	if n.Query != nil {
+		return p.Select(ctx, n.Query, nil)
+	}
+	exprs := tree.SelectExprs{tree.StarSelectExpr()}
+	if len(n.Columns) > 0 {
+		exprs = make(tree.SelectExprs, len(n.Columns))
+		for i, c := range n.Columns {
+			exprs[i] = tree.SelectExpr{Expr: c}
+		}
+	}
+	sel := &tree.Select{
+		Select: &tree.SelectClause{
+			Exprs: exprs,
+			From:  &tree.From{Tables: tree.TableExprs{&n.Table}},
+		},
+	}
+	return p.Select(ctx, sel, nil)
+}
+
 // Start implements the planNode interface.
 func (n *copyNode) Start(runParams) error {
 	// Should never happen because the executor prevents non-COPY messages during
@@ -111,14 +239,19 @@ const (
 	copyMsgData
 	copyMsgDone
 
-	nullString = `\N`
-	lineDelim  = '\n'
-)
+	// copyEndMarker is the line that ends the data of a COPY in the text and
+	// CSV formats.
+	copyEndMarker = `\.`
 
-var (
-	fieldDelim = []byte{'\t'}
+	// CopyBinarySignature starts the data of a COPY in the binary format.
+	CopyBinarySignature = "PGCOPY\n\377\r\n\x00"
 )
 
+// DecodeCopyBinaryField decodes a field of COPY data in the binary format,
+// which uses the binary encoding of the wire protocol. It is set by the
+// pgwire package.
+var DecodeCopyBinaryField func(typ types.T, b []byte) (tree.Datum, error)
+
 // ProcessCopyData appends data to the planner's internal COPY state as
 // parsed datums. Since the COPY protocol allows any length of data to be
 // sent in a message, there's no guarantee that data contains a complete row
@@ -128,59 +261,109 @@ func (s *Session) ProcessCopyData(
 	ctx context.Context, data string, msg copyMsg,
 ) (StatementList, error) {
 	cf := s.copyFrom
-	buf := cf.buf
 
 	switch msg {
 	case copyMsgData:
-		// ignore
-	case copyMsgDone:
-		var err error
-		// If there's a line in the buffer without \n at EOL, add it here.
-		if buf.Len() > 0 {
-			err = cf.addRow(ctx, buf.Bytes())
+		cf.buf.WriteString(data)
+		if err := cf.processBuffer(ctx, false /* final */); err != nil {
+			return nil, err
 		}
+		return StatementList{{AST: CopyDataBlock{}}}, nil
+	case copyMsgDone:
+		// The buffer may still hold a row without a line delimiter at its end.
+		err := cf.processBuffer(ctx, true /* final */)
 		return StatementList{{AST: CopyDataBlock{Done: true}}}, err
 	default:
 		return nil, fmt.Errorf("expected copy command")
 	}
+}
 
-	buf.WriteString(data)
-	for buf.Len() > 0 {
-		line, err := buf.ReadBytes(lineDelim)
-		if err != nil {
-			if err != io.EOF {
-				return nil, err
-			}
-		} else {
-			// Remove lineDelim from end.
-			line = line[:len(line)-1]
-			// Remove a single '\r' at EOL, if present.
-			if len(line) > 0 && line[len(line)-1] == '\r' {
-				line = line[:len(line)-1]
-			}
+// processBuffer extracts the rows of the data in the buffer. Unless final is
+// set, a trailing incomplete row is left in the buffer until more data
+// arrives.
+func (n *copyNode) processBuffer(ctx context.Context, final bool) error {
+	for !n.done && n.buf.Len() > 0 {
+		var ok bool
+		var err error
+		switch n.format.Format {
+		case tree.CopyFormatCSV:
+			ok, err = n.readCSVRecord(ctx, final)
+		case tree.CopyFormatBinary:
+			ok, err = n.readBinaryTuple(ctx, final)
+		default:
+			ok, err = n.readTextLine(ctx, final)
 		}
-		if buf.Len() == 0 && bytes.Equal(line, []byte(`\.`)) {
-			break
+		if err != nil || !ok {
+			return err
 		}
-		if err := cf.addRow(ctx, line); err != nil {
-			return nil, err
+	}
+	if n.done {
+		// Anything after the end of the data is ignored.
+		n.buf.Reset()
+	}
+	return nil
+}
+
+// readTextLine reads a line of COPY data in the text format. It returns
+// false if the buffer doesn't hold a complete line.
+func (n *copyNode) readTextLine(ctx context.Context, final bool) (bool, error) {
+	b := n.buf.Bytes()
+	var line []byte
+	if i := bytes.IndexByte(b, '\n'); i >= 0 {
+		line = b[:i]
+		n.buf.Next(i + 1)
+	} else if final {
+		line = b
+		n.buf.Next(len(b))
+	} else {
+		return false, nil
+	}
+	// Remove a single '\r' at EOL, if present.
+	if len(line) > 0 && line[len(line)-1] == '\r' {
+		line = line[:len(line)-1]
+	}
+	if string(line) == copyEndMarker {
+		n.done = true
+		return true, nil
+	}
+	return true, n.addTextRow(ctx, line)
+}
+
+// splitCopyTextLine splits a line of COPY data in the text format into its
+// fields. A delimiter escaped with a backslash is part of its field; the
+// other escape sequences are left for decodeCopy.
+func splitCopyTextLine(line []byte, delim byte) [][]byte {
+	var parts [][]byte
+	var field []byte
+	start := 0
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			if i+1 < len(line) && line[i+1] == delim {
+				field = append(field, line[start:i]...)
+				start = i + 1
+			}
+			i++
+		case delim:
+			parts = append(parts, append(field, line[start:i]...))
+			field = nil
+			start = i + 1
 		}
 	}
-	return StatementList{{AST: CopyDataBlock{}}}, nil
+	return append(parts, append(field, line[start:]...))
 }
 
-func (n *copyNode) addRow(ctx context.Context, line []byte) error {
+func (n *copyNode) addTextRow(ctx context.Context, line []byte) error {
 	var err error
-	parts := bytes.Split(line, fieldDelim)
+	parts := splitCopyTextLine(line, n.format.Delimiter)
 	if len(parts) != len(n.resultColumns) {
 		return fmt.Errorf("expected %d values, got %d", len(n.resultColumns), len(parts))
 	}
-	exprs := make(tree.Exprs, len(parts))
-	acc := n.rowsMemAcc.Wsession(n.session)
+	row := make(tree.Datums, len(parts))
 	for i, part := range parts {
 		s := string(part)
-		if s == nullString {
-			exprs[i] = tree.DNull
+		if s == n.format.Null {
+			row[i] = tree.DNull
 			continue
 		}
 		switch t := n.resultColumns[i].Typ; t {
@@ -197,17 +380,207 @@ func (n *copyNode) addRow(ctx context.Context, line []byte) error {
 				return err
 			}
 		}
-		evalCtx := n.session.evalCtx()
-		d, err := parser.ParseStringAs(n.resultColumns[i].Typ, s, &evalCtx)
+		row[i], err = n.parseValue(i, s)
 		if err != nil {
 			return err
 		}
+	}
+	return n.addRow(ctx, row)
+}
 
-		sz := d.Size()
-		if err := acc.Grow(ctx, int64(sz)); err != nil {
-			return err
+// readCSVRecord reads a record of COPY data in the CSV format. A quoted
+// field may span several lines, so it returns false until the buffer holds
+// the line that completes the record.
+func (n *copyNode) readCSVRecord(ctx context.Context, final bool) (bool, error) {
+	b := n.buf.Bytes()
+	delim, quote := n.format.Delimiter, n.format.Quote
+
+	var fields [][]byte
+	// quoted records which fields were quoted; a quoted field is never NULL.
+	var quoted []bool
+	var field []byte
+	inQuotes, wasQuoted := false, false
+	end := -1
+	for i := 0; i < len(b) && end < 0; i++ {
+		c := b[i]
+		if inQuotes {
+			if c != quote {
+				field = append(field, c)
+				continue
+			}
+			if i+1 == len(b) && !final {
+				// The quote may be the first of a doubled quote.
+				return false, nil
+			}
+			if i+1 < len(b) && b[i+1] == quote {
+				field = append(field, quote)
+				i++
+				continue
+			}
+			inQuotes = false
+			continue
+		}
+		switch c {
+		case quote:
+			inQuotes, wasQuoted = true, true
+		case delim:
+			fields, quoted = append(fields, field), append(quoted, wasQuoted)
+			field, wasQuoted = nil, false
+		case '\r':
+			if i+1 == len(b) && !final {
+				return false, nil
+			}
+			if i+1 < len(b) && b[i+1] == '\n' {
+				i++
+			}
+			end = i + 1
+		case '\n':
+			end = i + 1
+		default:
+			field = append(field, c)
 		}
+	}
+	if end < 0 {
+		if !final {
+			return false, nil
+		}
+		if inQuotes {
+			return false, pgerror.NewError(pgerror.CodeBadCopyFileFormatError,
+				"unterminated CSV quoted field")
+		}
+		end = len(b)
+	}
+	fields, quoted = append(fields, field), append(quoted, wasQuoted)
+	n.buf.Next(end)
 
+	if len(fields) == 1 && !quoted[0] && string(fields[0]) == copyEndMarker {
+		n.done = true
+		return true, nil
+	}
+	if n.format.Header && !n.headerDone {
+		n.headerDone = true
+		return true, nil
+	}
+	if len(fields) != len(n.resultColumns) {
+		return false, fmt.Errorf("expected %d values, got %d", len(n.resultColumns), len(fields))
+	}
+	row := make(tree.Datums, len(fields))
+	for i, f := range fields {
+		s := string(f)
+		if !quoted[i] && s == n.format.Null {
+			row[i] = tree.DNull
+			continue
+		}
+		var err error
+		row[i], err = n.parseValue(i, s)
+		if err != nil {
+			return false, err
+		}
+	}
+	return true, n.addRow(ctx, row)
+}
+
+// readBinaryTuple reads the header, a tuple or the trailer of COPY data in
+// the binary format. Each tuple is a 16-bit field count followed by the
+// fields, each prefixed by its 32-bit length (-1 for NULL); the trailer is
+// a field count of -1. It returns false if the buffer doesn't hold a
+// complete tuple.
+func (n *copyNode) readBinaryTuple(ctx context.Context, final bool) (bool, error) {
+	incomplete := func() (bool, error) {
+		if final {
+			return false, pgerror.NewError(pgerror.CodeBadCopyFileFormatError,
+				"unexpected EOF in COPY data")
+		}
+		return false, nil
+	}
+	b := n.buf.Bytes()
+
+	if !n.headerDone {
+		// The signature is followed by a 32-bit flags field and the length
+		// of a header extension area, which is skipped.
+		const headerLen = len(CopyBinarySignature) + 8
+		if len(b) < headerLen {
+			return incomplete()
+		}
+		if string(b[:len(CopyBinarySignature)]) != CopyBinarySignature {
+			return false, pgerror.NewError(pgerror.CodeBadCopyFileFormatError,
+				"COPY file signature not recognized")
+		}
+		flags := binary.BigEndian.Uint32(b[len(CopyBinarySignature):])
+		if flags&(1<<16) != 0 {
+			return false, pgerror.NewError(pgerror.CodeBadCopyFileFormatError,
+				"COPY data with OIDs is not supported")
+		}
+		extLen := int(binary.BigEndian.Uint32(b[len(CopyBinarySignature)+4:]))
+		if len(b) < headerLen+extLen {
+			return incomplete()
+		}
+		n.buf.Next(headerLen + extLen)
+		n.headerDone = true
+		return true, nil
+	}
+
+	if len(b) < 2 {
+		return incomplete()
+	}
+	count := int(int16(binary.BigEndian.Uint16(b)))
+	if count == -1 {
+		n.buf.Next(2)
+		n.done = true
+		return true, nil
+	}
+	if count != len(n.resultColumns) {
+		return false, fmt.Errorf("expected %d values, got %d", len(n.resultColumns), count)
+	}
+	if DecodeCopyBinaryField == nil {
+		return false, pgerror.NewError(pgerror.CodeFeatureNotSupportedError,
+			"binary COPY is not supported")
+	}
+	row := make(tree.Datums, count)
+	off := 2
+	for i := range row {
+		if len(b) < off+4 {
+			return incomplete()
+		}
+		size := int(int32(binary.BigEndian.Uint32(b[off:])))
+		off += 4
+		if size == -1 {
+			row[i] = tree.DNull
+			continue
+		}
+		if size < 0 {
+			return false, pgerror.NewError(pgerror.CodeBadCopyFileFormatError,
+				"invalid field size")
+		}
+		if len(b) < off+size {
+			return incomplete()
+		}
+		d, err := DecodeCopyBinaryField(n.resultColumns[i].Typ, b[off:off+size])
+		if err != nil {
+			return false, err
+		}
+		row[i] = d
+		off += size
+	}
+	n.buf.Next(off)
+	return true, n.addRow(ctx, row)
+}
+
+// parseValue parses the textual representation of a value of the i-th
+// column.
+func (n *copyNode) parseValue(i int, s string) (tree.Datum, error) {
+	evalCtx := n.session.evalCtx()
+	return parser.ParseStringAs(n.resultColumns[i].Typ, s, &evalCtx)
+}
+
+// addRow queues a row for insertion by the next CopyData.
+func (n *copyNode) addRow(ctx context.Context, row tree.Datums) error {
+	acc := n.rowsMemAcc.Wsession(n.session)
+	exprs := make(tree.Exprs, len(row))
+	for i, d := range row {
+		if err := acc.Grow(ctx, int64(d.Size())); err != nil {
+			return err
+		}
 		exprs[i] = d
 	}
 	tuple := &tree.Tuple{Exprs: exprs}
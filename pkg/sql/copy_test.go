@@ -15,8 +15,11 @@
 package sql
 
 import (
+	"reflect"
 	"testing"
 
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/testutils"
 	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
 )
 
@@ -88,3 +91,113 @@ func TestDecodeCopy(t *testing.T) {
 		}
 	}
 }
+
+func TestSplitCopyTextLine(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	tests := []struct {
+		in     string
+		delim  byte
+		expect []string
+	}{
+		{in: "a\tb\tc", delim: '\t', expect: []string{"a", "b", "c"}},
+		{in: "", delim: '\t', expect: []string{""}},
+		{in: "a\t", delim: '\t', expect: []string{"a", ""}},
+		{in: `a\tb`, delim: '\t', expect: []string{`a\tb`}},
+		{in: `a|b\|c|\N`, delim: '|', expect: []string{"a", "b|c", `\N`}},
+		{in: `a\\|b`, delim: '|', expect: []string{`a\\`, "b"}},
+	}
+
+	for _, test := range tests {
+		var out []string
+		for _, part := range splitCopyTextLine([]byte(test.in), test.delim) {
+			out = append(out, string(part))
+		}
+		if !reflect.DeepEqual(out, test.expect) {
+			t.Errorf("%q: got %q, expected %q", test.in, out, test.expect)
+		}
+	}
+}
+
+func TestMakeCopyFormat(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	str := func(s string) *string { return &s }
+	yes := true
+
+	tests := []struct {
+		opts   tree.CopyOptions
+		expect CopyFormat
+		err    string
+	}{
+		{
+			opts:   tree.CopyOptions{},
+			expect: CopyFormat{Format: tree.CopyFormatText, Delimiter: '\t', Null: `\N`},
+		},
+		{
+			opts:   tree.CopyOptions{FileFormat: tree.CopyFormatCSV, Header: &yes},
+			expect: CopyFormat{Format: tree.CopyFormatCSV, Header: true, Delimiter: ',', Quote: '"'},
+		},
+		{
+			opts: tree.CopyOptions{FileFormat: tree.CopyFormatCSV, Delimiter: str(";"), Null: str("-"),
+				Quote: str("'")},
+			expect: CopyFormat{Format: tree.CopyFormatCSV, Delimiter: ';', Null: "-", Quote: '\''},
+		},
+		{
+			opts:   tree.CopyOptions{FileFormat: tree.CopyFormatBinary},
+			expect: CopyFormat{Format: tree.CopyFormatBinary},
+		},
+
+		// Error cases.
+
+		{
+			opts: tree.CopyOptions{Header: &yes},
+			err:  "COPY HEADER available only in CSV mode",
+		},
+		{
+			opts: tree.CopyOptions{Quote: str("'")},
+			err:  "COPY quote available only in CSV mode",
+		},
+		{
+			opts: tree.CopyOptions{FileFormat: tree.CopyFormatBinary, Null: str("")},
+			err:  "cannot specify NULL in BINARY mode",
+		},
+		{
+			opts: tree.CopyOptions{Delimiter: str("ab")},
+			err:  "COPY delimiter must be a single one-byte character",
+		},
+		{
+			opts: tree.CopyOptions{Delimiter: str("\n")},
+			err:  "COPY delimiter cannot be newline or carriage return",
+		},
+		{
+			opts: tree.CopyOptions{Null: str("a\rb")},
+			err:  "COPY null representation cannot use newline or carriage return",
+		},
+		{
+			opts: tree.CopyOptions{Delimiter: str(`\`)},
+			err:  `COPY delimiter cannot be "\\"`,
+		},
+		{
+			opts: tree.CopyOptions{FileFormat: tree.CopyFormatCSV, Delimiter: str(`"`)},
+			err:  "COPY delimiter and quote must be different",
+		},
+	}
+
+	for i, test := range tests {
+		f, err := MakeCopyFormat(test.opts)
+		if test.err != "" {
+			if !testutils.IsError(err, test.err) {
+				t.Errorf("%d: expected error %q, got %v", i, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d: unexpected error: %v", i, err)
+			continue
+		}
+		if f != test.expect {
+			t.Errorf("%d: got %+v, expected %+v", i, f, test.expect)
+		}
+	}
+}
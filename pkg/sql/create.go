@@ -166,9 +166,15 @@ func (n *createIndexNode) Start(params runParams) error {
 		Unique:           n.n.Unique,
 		StoreColumnNames: n.n.Storing.ToStrings(),
 	}
+	if n.n.Inverted {
+		indexDesc.Type = sqlbase.IndexDescriptor_INVERTED
+	}
 	if err := indexDesc.FillColumns(n.n.Columns); err != nil {
 		return err
 	}
+	if err := n.tableDesc.CheckIndexCollations(n.n.Columns); err != nil {
+		return err
+	}
 
 	mutationIdx := len(n.tableDesc.Mutations)
 	if err := n.tableDesc.AddIndexMutation(indexDesc, sqlbase.DescriptorMutation_ADD); err != nil {
@@ -251,37 +257,38 @@ func (p *planner) getUserAuthInfo(nameE, passwordE tree.Expr, ctx string) (userA
 	return userAuthInfo{name: name, password: password}, nil
 }
 
-// resolve returns the actual user name and (hashed) password.
-func (ua *userAuthInfo) resolve() (string, []byte, error) {
+// resolve returns the actual user name and the credentials derived from the
+// password.
+func (ua *userAuthInfo) resolve() (string, UserCredentials, error) {
 	name, err := ua.name()
 	if err != nil {
-		return "", nil, err
+		return "", UserCredentials{}, err
 	}
 	if name == "" {
-		return "", nil, errNoUserNameSpecified
+		return "", UserCredentials{}, errNoUserNameSpecified
 	}
 	normalizedUsername, err := NormalizeAndValidateUsername(name)
 	if err != nil {
-		return "", nil, err
+		return "", UserCredentials{}, err
 	}
 
-	var hashedPassword []byte
+	var creds UserCredentials
 	if ua.password != nil {
 		resolvedPassword, err := ua.password()
 		if err != nil {
-			return "", nil, err
+			return "", UserCredentials{}, err
 		}
 		if resolvedPassword == "" {
-			return "", nil, security.ErrEmptyPassword
+			return "", UserCredentials{}, security.ErrEmptyPassword
 		}
 
-		hashedPassword, err = security.HashPassword(resolvedPassword)
+		creds, err = makeUserCredentials(normalizedUsername, resolvedPassword)
 		if err != nil {
-			return "", nil, err
+			return "", UserCredentials{}, err
 		}
 	}
 
-	return normalizedUsername, hashedPassword, nil
+	return normalizedUsername, creds, nil
 }
 
 // CreateUser creates a user.
@@ -334,7 +341,7 @@ func NormalizeAndValidateUsername(username string) (string, error) {
 var errNoUserNameSpecified = errors.New("no username specified")
 
 func (n *createUserNode) Start(params runParams) error {
-	normalizedUsername, hashedPassword, err := n.userAuthInfo.resolve()
+	normalizedUsername, creds, err := n.userAuthInfo.resolve()
 	if err != nil {
 		return err
 	}
@@ -344,9 +351,11 @@ func (n *createUserNode) Start(params runParams) error {
 		params.ctx,
 		"create-user",
 		params.p.txn,
-		"INSERT INTO system.users VALUES ($1, $2);",
+		"INSERT INTO system.users VALUES ($1, $2, $3, $4);",
 		normalizedUsername,
-		hashedPassword,
+		creds.HashedPassword,
+		creds.ScramCredentials,
+		creds.MD5Password,
 	)
 	if err != nil {
 		if sqlbase.IsUniquenessConstraintViolationError(err) {
@@ -413,7 +422,7 @@ func (n *alterUserSetPasswordNode) FastPathResults() (int, bool) {
 }
 
 func (n *alterUserSetPasswordNode) Start(params runParams) error {
-	normalizedUsername, hashedPassword, err := n.userAuthInfo.resolve()
+	normalizedUsername, creds, err := n.userAuthInfo.resolve()
 	if err != nil {
 		return err
 	}
@@ -423,9 +432,12 @@ func (n *alterUserSetPasswordNode) Start(params runParams) error {
 		params.ctx,
 		"create-user",
 		params.p.txn,
-		`UPDATE system.users SET "hashedPassword" = $2 WHERE username = $1`,
+		`UPDATE system.users SET "hashedPassword" = $2, "scramCredentials" = $3, `+
+			`"md5Password" = $4 WHERE username = $1`,
 		normalizedUsername,
-		hashedPassword,
+		creds.HashedPassword,
+		creds.ScramCredentials,
+		creds.MD5Password,
 	)
 	if err != nil {
 		return err
@@ -440,6 +452,88 @@ func (*alterUserSetPasswordNode) Next(runParams) (bool, error) { return false, n
 func (*alterUserSetPasswordNode) Close(context.Context)        {}
 func (*alterUserSetPasswordNode) Values() tree.Datums          { return tree.Datums{} }
 
+// alterUserConnLimitNode represents an ALTER USER ... CONNECTION LIMIT
+// statement.
+type alterUserConnLimitNode struct {
+	name         func() (string, error)
+	limit        int64
+	ifExists     bool
+	rowsAffected int
+}
+
+// AlterUserSetConnLimit sets the maximum number of connections which a user
+// can open to each node.
+// Privileges: UPDATE on system.users.
+func (p *planner) AlterUserSetConnLimit(
+	ctx context.Context, n *tree.AlterUserSetConnLimit,
+) (planNode, error) {
+	tDesc, err := getTableDesc(ctx, p.txn, p.getVirtualTabler(), &tree.TableName{DatabaseName: "system", TableName: "users"})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.CheckPrivilege(tDesc, privilege.UPDATE); err != nil {
+		return nil, err
+	}
+
+	name, err := p.TypeAsString(n.Name, "ALTER USER")
+	if err != nil {
+		return nil, err
+	}
+
+	return &alterUserConnLimitNode{
+		name:     name,
+		limit:    n.Limit,
+		ifExists: n.IfExists,
+	}, nil
+}
+
+func (n *alterUserConnLimitNode) FastPathResults() (int, bool) {
+	return n.rowsAffected, true
+}
+
+func (n *alterUserConnLimitNode) Start(params runParams) error {
+	name, err := n.name()
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		return errNoUserNameSpecified
+	}
+	normalizedUsername, err := NormalizeAndValidateUsername(name)
+	if err != nil {
+		return err
+	}
+
+	// Like in Postgres, a negative limit means no limit, which is stored as
+	// NULL.
+	limit := tree.DNull
+	if n.limit >= 0 {
+		limit = tree.NewDInt(tree.DInt(n.limit))
+	}
+
+	internalExecutor := InternalExecutor{LeaseManager: params.p.LeaseMgr()}
+	n.rowsAffected, err = internalExecutor.ExecuteStatementInTransaction(
+		params.ctx,
+		"alter-user-conn-limit",
+		params.p.txn,
+		`UPDATE system.users SET "connectionLimit" = $2 WHERE username = $1`,
+		normalizedUsername,
+		limit,
+	)
+	if err != nil {
+		return err
+	}
+	if n.rowsAffected == 0 && !n.ifExists {
+		return errors.Errorf("user %s does not exist", normalizedUsername)
+	}
+	return nil
+}
+
+func (*alterUserConnLimitNode) Next(runParams) (bool, error) { return false, nil }
+func (*alterUserConnLimitNode) Close(context.Context)        {}
+func (*alterUserConnLimitNode) Values() tree.Datums          { return tree.Datums{} }
+
 // createViewNode represents a CREATE VIEW statement.
 type createViewNode struct {
 	p             *planner
@@ -1564,9 +1658,15 @@ func MakeTableDesc(
 				Name:             string(d.Name),
 				StoreColumnNames: d.Storing.ToStrings(),
 			}
+			if d.Inverted {
+				idx.Type = sqlbase.IndexDescriptor_INVERTED
+			}
 			if err := idx.FillColumns(d.Columns); err != nil {
 				return desc, err
 			}
+			if err := desc.CheckIndexCollations(d.Columns); err != nil {
+				return desc, err
+			}
 			if err := desc.AddIndex(idx, false); err != nil {
 				return desc, err
 			}
@@ -1582,6 +1682,9 @@ func MakeTableDesc(
 			if err := idx.FillColumns(d.Columns); err != nil {
 				return desc, err
 			}
+			if err := desc.CheckIndexCollations(d.Columns); err != nil {
+				return desc, err
+			}
 			if err := desc.AddIndex(idx, d.PrimaryKey); err != nil {
 				return desc, err
 			}
@@ -0,0 +1,307 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/internal/client"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+)
+
+// sqlCursor is a cursor declared with DECLARE.
+//
+// Like the portals suspended by pgwire when an Execute message carries a
+// row limit, a cursor is a resumablePlan: FETCH and MOVE pull the next rows
+// of the cursor's query in the declaring transaction. A cursor kept open
+// WITH HOLD is materialized when that transaction commits, and still
+// returns the results as of that transaction afterwards.
+//
+// Unlike in Postgres, the rows which weren't fetched yet are read at the
+// current state of the transaction, which includes the writes made by the
+// transaction after the cursor was declared.
+type sqlCursor struct {
+	name         string
+	statement    string
+	hold         bool
+	creationTime time.Time
+
+	plan *resumablePlan
+}
+
+func (c *sqlCursor) close(ctx context.Context) {
+	c.plan.close(ctx)
+}
+
+// getCursor looks up a cursor by name. Cursors declared by an earlier
+// attempt of the current transaction are closed and reported as missing.
+func (s *Session) getCursor(ctx context.Context, txn *client.Txn, name string) (*sqlCursor, bool) {
+	c, ok := s.cursors[name]
+	if !ok {
+		return nil, false
+	}
+	if c.plan.txn != nil && !c.plan.startedBy(txn) {
+		s.closeCursor(ctx, name)
+		return nil, false
+	}
+	return c, true
+}
+
+// closeCursor closes the named cursor. It returns false if there was no
+// such cursor.
+func (s *Session) closeCursor(ctx context.Context, name string) bool {
+	c, ok := s.cursors[name]
+	if !ok {
+		return false
+	}
+	c.close(ctx)
+	delete(s.cursors, name)
+	return true
+}
+
+// closeCursors closes all the cursors of the session.
+func (s *Session) closeCursors(ctx context.Context) {
+	for name := range s.cursors {
+		s.closeCursor(ctx, name)
+	}
+}
+
+// holdCursors materializes the holdable cursors declared by the current
+// transaction. It is called before the transaction commits.
+func (s *Session) holdCursors(ctx context.Context) error {
+	txn := s.TxnState.mu.txn
+	for _, c := range s.cursors {
+		if c.hold && c.plan.txn != nil && c.plan.startedBy(txn) {
+			if err := c.plan.hold(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// finishCursors is called when a SQL transaction finishes. Holdable
+// cursors declared by the transaction survive if it committed; all the
+// other cursors are closed.
+func (s *Session) finishCursors(ctx context.Context) {
+	for name, c := range s.cursors {
+		if c.plan.txn == nil {
+			continue
+		}
+		if c.hold && c.plan.txn.IsCommitted() && c.plan.startedBy(c.plan.txn) && c.plan.held() {
+			c.plan.txn = nil
+			continue
+		}
+		s.closeCursor(ctx, name)
+	}
+}
+
+// DeclareCursor implements the DECLARE statement.
+// See https://www.postgresql.org/docs/current/static/sql-declare.html for details.
+func (p *planner) DeclareCursor(ctx context.Context, n *tree.DeclareCursor) (planNode, error) {
+	if !n.Hold && p.session.TxnState.implicitTxn {
+		return nil, pgerror.NewError(pgerror.CodeNoActiveSQLTransactionError,
+			"DECLARE CURSOR can only be used in transaction blocks")
+	}
+	if _, ok := p.session.getCursor(ctx, p.txn, string(n.Name)); ok {
+		return nil, pgerror.NewErrorf(pgerror.CodeDuplicateCursorError,
+			"cursor %q already exists", n.Name)
+	}
+	// The query of the cursor outlives the DECLARE statement, so it gets a
+	// planner of its own.
+	cp := p.newResumablePlanner()
+	plan, err := cp.makePlan(ctx, Statement{AST: n.Select})
+	if err != nil {
+		return nil, err
+	}
+	return &declareCursorNode{n: n, p: cp, plan: plan}, nil
+}
+
+// declareCursorNode starts the query of a cursor and stores it in the
+// session.
+type declareCursorNode struct {
+	n *tree.DeclareCursor
+	// p is the planner of the query, which is already optimized. The
+	// ownership of plan passes to the cursor when the node starts.
+	p    *planner
+	plan planNode
+}
+
+func (n *declareCursorNode) Start(params runParams) error {
+	s := params.p.session
+	// The name may have been taken since the statement was planned.
+	if _, ok := s.getCursor(params.ctx, params.p.txn, string(n.n.Name)); ok {
+		return pgerror.NewErrorf(pgerror.CodeDuplicateCursorError,
+			"cursor %q already exists", n.n.Name)
+	}
+
+	plan := n.plan
+	n.plan = nil
+	r, err := startResumablePlan(params.ctx, n.p, plan)
+	if err != nil {
+		return err
+	}
+	if n.n.Hold && s.TxnState.implicitTxn {
+		// The transaction commits when the statement finishes.
+		if err := r.hold(params.ctx); err != nil {
+			r.close(params.ctx)
+			return err
+		}
+	}
+
+	if s.cursors == nil {
+		s.cursors = make(map[string]*sqlCursor)
+	}
+	s.cursors[string(n.n.Name)] = &sqlCursor{
+		name:         string(n.n.Name),
+		statement:    n.n.String(),
+		hold:         n.n.Hold,
+		creationTime: timeutil.Now(),
+		plan:         r,
+	}
+	return nil
+}
+
+func (*declareCursorNode) Next(runParams) (bool, error) { return false, nil }
+func (*declareCursorNode) Values() tree.Datums          { return tree.Datums{} }
+func (n *declareCursorNode) Close(ctx context.Context) {
+	if n.plan != nil {
+		n.plan.Close(ctx)
+		n.plan = nil
+	}
+}
+
+// lookupCursorForFetch returns the cursor targeted by a FETCH or MOVE
+// statement.
+func (p *planner) lookupCursorForFetch(
+	ctx context.Context, n *tree.CursorStmt,
+) (*sqlCursor, error) {
+	if !n.All && n.Count < 0 {
+		return nil, pgerror.NewError(pgerror.CodeObjectNotInPrerequisiteStateError,
+			"cursor can only scan forward")
+	}
+	c, ok := p.session.getCursor(ctx, p.txn, string(n.Name))
+	if !ok {
+		return nil, pgerror.NewErrorf(pgerror.CodeInvalidCursorNameError,
+			"cursor %q does not exist", n.Name)
+	}
+	return c, nil
+}
+
+// FetchCursor implements the FETCH statement.
+// See https://www.postgresql.org/docs/current/static/sql-fetch.html for details.
+func (p *planner) FetchCursor(ctx context.Context, n *tree.FetchCursor) (planNode, error) {
+	c, err := p.lookupCursorForFetch(ctx, &n.CursorStmt)
+	if err != nil {
+		return nil, err
+	}
+	return &fetchNode{n: n, columns: c.plan.columns}, nil
+}
+
+// fetchNode returns the next rows of a cursor.
+type fetchNode struct {
+	n       *tree.FetchCursor
+	columns sqlbase.ResultColumns
+
+	cursor  *sqlCursor
+	fetched int64
+}
+
+func (n *fetchNode) Start(params runParams) error {
+	c, err := params.p.lookupCursorForFetch(params.ctx, &n.n.CursorStmt)
+	if err != nil {
+		return err
+	}
+	c.plan.resumeFor(params.p)
+	n.cursor = c
+	return nil
+}
+
+func (n *fetchNode) Next(params runParams) (bool, error) {
+	if !n.n.All && n.fetched >= n.n.Count {
+		return false, nil
+	}
+	next, err := n.cursor.plan.next(params.ctx)
+	if next {
+		n.fetched++
+	}
+	return next, err
+}
+
+func (n *fetchNode) Values() tree.Datums { return n.cursor.plan.values() }
+func (*fetchNode) Close(context.Context) {}
+
+// MoveCursor implements the MOVE statement.
+// See https://www.postgresql.org/docs/current/static/sql-move.html for details.
+func (p *planner) MoveCursor(ctx context.Context, n *tree.MoveCursor) (planNode, error) {
+	if _, err := p.lookupCursorForFetch(ctx, &n.CursorStmt); err != nil {
+		return nil, err
+	}
+	return &moveNode{n: n}, nil
+}
+
+// moveNode repositions a cursor without returning any rows.
+type moveNode struct {
+	n     *tree.MoveCursor
+	count int
+}
+
+func (n *moveNode) Start(params runParams) error {
+	c, err := params.p.lookupCursorForFetch(params.ctx, &n.n.CursorStmt)
+	if err != nil {
+		return err
+	}
+	c.plan.resumeFor(params.p)
+	for n.n.All || int64(n.count) < n.n.Count {
+		next, err := c.plan.next(params.ctx)
+		if err != nil {
+			return err
+		}
+		if !next {
+			break
+		}
+		n.count++
+	}
+	return nil
+}
+
+func (*moveNode) Next(runParams) (bool, error) { return false, nil }
+func (*moveNode) Values() tree.Datums          { return tree.Datums{} }
+func (*moveNode) Close(context.Context)        {}
+
+// FastPathResults implements the planNodeFastPath interface.
+func (n *moveNode) FastPathResults() (int, bool) {
+	return n.count, true
+}
+
+// CloseCursor implements the CLOSE statement.
+// See https://www.postgresql.org/docs/current/static/sql-close.html for details.
+func (p *planner) CloseCursor(ctx context.Context, n *tree.CloseCursor) (planNode, error) {
+	if n.Name == "" {
+		p.session.closeCursors(ctx)
+	} else {
+		if _, ok := p.session.getCursor(ctx, p.txn, string(n.Name)); !ok {
+			return nil, pgerror.NewErrorf(pgerror.CodeInvalidCursorNameError,
+				"cursor %q does not exist", n.Name)
+		}
+		p.session.closeCursor(ctx, string(n.Name))
+	}
+	return &zeroNode{}, nil
+}
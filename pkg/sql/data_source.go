@@ -600,6 +600,9 @@ func (p *planner) getPlanForDesc(
 	scanVisibility scanVisibility,
 	wantedColumns []tree.ColumnID,
 ) (planDataSource, error) {
+	if p.planCache != nil {
+		p.planCache.addDependency(desc)
+	}
 	if desc.IsView() {
 		if wantedColumns != nil {
 			return planDataSource{},
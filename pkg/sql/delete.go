@@ -38,7 +38,8 @@ type deleteNode struct {
 	n *tree.Delete
 	p *planner
 
-	tw tableDeleter
+	fkTables sqlbase.TableLookupsByID
+	tw       tableDeleter
 
 	run struct {
 		// The following fields are populated during Start().
@@ -106,6 +107,7 @@ func (p *planner) Delete(
 		n:            n,
 		p:            p,
 		editNodeBase: en,
+		fkTables:     fkTables,
 		tw:           tw,
 	}
 
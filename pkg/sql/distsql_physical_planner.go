@@ -15,6 +15,7 @@
 package sql
 
 import (
+	"bytes"
 	"fmt"
 	"math"
 	"sort"
@@ -79,6 +80,10 @@ type DistSQLPlanner struct {
 	spanResolver distsqlplan.SpanResolver
 	testingKnobs DistSQLPlannerTestingKnobs
 
+	// distSender is used to find the split keys of parallel scans within a
+	// range.
+	distSender *kv.DistSender
+
 	// runnerChan is used to send out requests (for running SetupFlow RPCs) to a
 	// pool of workers.
 	runnerChan chan runnerRequest
@@ -109,6 +114,49 @@ var planMergeJoins = settings.RegisterBoolSetting(
 	true,
 )
 
+var planLookupJoins = settings.RegisterBoolSetting(
+	"sql.distsql.lookup_joins.enabled",
+	"if set, we plan lookup joins against an index of the right table when the "+
+		"left side of a join is expected to be small",
+	true,
+)
+
+// If true, the stages of a plan that receive hash-routed rows from nodes in
+// different localities are confined to the nodes of a single locality.
+var planLocalityAware = settings.RegisterBoolSetting(
+	"sql.distsql.locality_aware_planning.enabled",
+	"if set, distributed join, aggregation and window stages are placed on the "+
+		"nodes of the locality that produces most of their input",
+	true,
+)
+
+// parallelScanReadersPerNode is the maximum number of table readers planned on
+// a node for a scan (see splitSpanPartitions).
+var parallelScanReadersPerNode = settings.RegisterIntSetting(
+	"sql.distsql.parallel_scans.readers_per_node",
+	"maximum number of table readers planned on each node for a scan without a "+
+		"limit; if greater than 1, the spans scanned on a node are split at range "+
+		"boundaries and at size-based keys within ranges and read concurrently",
+	1,
+)
+
+// parallelScanTargetSize is the target size of the pieces that a range is
+// split into when a scan has fewer ranges on a node than readers (see
+// splitSpanPartitions).
+var parallelScanTargetSize = settings.RegisterByteSizeSetting(
+	"sql.distsql.parallel_scans.target_size",
+	"target size of the data read by each table reader of a parallel scan, when "+
+		"the ranges of a node are split to plan more readers",
+	16<<20,
+)
+
+var planMutations = settings.RegisterBoolSetting(
+	"sql.distsql.distribute_mutations.enabled",
+	"if set, INSERT, UPDATE and DELETE statements can be planned with table "+
+		"writers on the leaseholders of the ranges that they write to",
+	false,
+)
+
 // NewDistSQLPlanner initializes a DistSQLPlanner
 func NewDistSQLPlanner(
 	ctx context.Context,
@@ -133,6 +181,7 @@ func NewDistSQLPlanner(
 		gossip:       gossip,
 		spanResolver: distsqlplan.NewSpanResolver(distSender, gossip, nodeDesc, resolverPolicy),
 		testingKnobs: testingKnobs,
+		distSender:   distSender,
 	}
 	dsp.initRunners()
 	return dsp
@@ -188,7 +237,14 @@ func (dsp *DistSQLPlanner) checkExpr(expr tree.Expr) error {
 //    returned).
 //  - whether it is recommended that the query be run with DistSQL.
 func (dsp *DistSQLPlanner) CheckSupport(node planNode) (bool, error) {
-	rec, err := dsp.checkSupportForNode(node)
+	var rec distRecommendation
+	var err error
+	switch node.(type) {
+	case *insertNode, *updateNode, *deleteNode:
+		rec, err = dsp.checkSupportForMutation(node)
+	default:
+		rec, err = dsp.checkSupportForNode(node)
+	}
 	if err != nil {
 		return false, err
 	}
@@ -340,8 +396,9 @@ func (dsp *DistSQLPlanner) checkSupportForNode(node planNode) (distRecommendatio
 	case *groupNode:
 		for _, fholder := range n.funcs {
 			if f, ok := fholder.expr.(*tree.FuncExpr); ok {
-				if strings.ToUpper(f.Func.FunctionReference.String()) == "ARRAY_AGG" {
-					return 0, newQueryNotSupportedError("ARRAY_AGG aggregation not supported yet")
+				switch funcStr := strings.ToUpper(f.Func.FunctionReference.String()); funcStr {
+				case "ARRAY_AGG", "JSON_AGG", "JSONB_AGG", "JSON_OBJECT_AGG", "JSONB_OBJECT_AGG":
+					return 0, newQueryNotSupportedError(funcStr + " aggregation not supported yet")
 				}
 			}
 		}
@@ -352,6 +409,45 @@ func (dsp *DistSQLPlanner) checkSupportForNode(node planNode) (distRecommendatio
 		// Distribute aggregations if possible.
 		return rec.compose(shouldDistribute), nil
 
+	case *windowNode:
+		for i, e := range n.windowRender {
+			typ := n.values.columns[i].Typ
+			if leafType(typ).FamilyEqual(types.FamTuple) {
+				return 0, newQueryNotSupportedErrorf("unsupported render type %s", typ)
+			}
+			if err := dsp.checkExpr(e); err != nil {
+				return 0, err
+			}
+		}
+		sourceColumns := planColumns(n.plan)
+		partitioned := false
+		for _, f := range n.funcs {
+			argTypes := make([]sqlbase.ColumnType, f.argCount)
+			for i := range argTypes {
+				colTyp, err := sqlbase.DatumTypeToColumnType(sourceColumns[f.argIdxStart+i].Typ)
+				if err != nil {
+					return 0, newQueryNotSupportedError(err.Error())
+				}
+				argTypes[i] = colTyp
+			}
+			if _, _, err := distsqlrun.GetWindowFunctionInfo(windowFuncName(f), argTypes...); err != nil {
+				return 0, newQueryNotSupportedError(err.Error())
+			}
+			if len(f.partitionIdxs) > 0 {
+				partitioned = true
+			}
+		}
+		rec, err := dsp.checkSupportForNode(n.plan)
+		if err != nil {
+			return 0, err
+		}
+		// Distribute window functions if their partitions can be computed in
+		// parallel.
+		if partitioned {
+			rec = rec.compose(shouldDistribute)
+		}
+		return rec, nil
+
 	case *limitNode:
 		if err := dsp.checkExpr(n.countExpr); err != nil {
 			return 0, err
@@ -379,7 +475,8 @@ func (dsp *DistSQLPlanner) checkSupportForNode(node planNode) (distRecommendatio
 		return shouldDistribute, nil
 
 	case *insertNode, *updateNode, *deleteNode:
-		// This is a potential hot path.
+		// Mutations are only supported at the root of a plan (see
+		// checkSupportForMutation). This is a potential hot path.
 		return 0, mutationsNotSupportedError
 
 	case *setNode, *setClusterSettingNode:
@@ -401,6 +498,33 @@ type planningCtx struct {
 	// physicalPlan we generate with this context.
 	// Nodes that fail a health check have empty addresses.
 	nodeAddresses map[roachpb.NodeID]string
+	// nodeLocalities caches the localities of the nodes looked up during
+	// planning (see DistSQLPlanner.nodeLocality).
+	nodeLocalities map[roachpb.NodeID]roachpb.Locality
+
+	// processorNodes, if set, causes every processor of the plan to be assigned
+	// a ProcessorID and is populated with the planNode that each processor was
+	// planned for. It is used to relate the runtime statistics of the
+	// processors to the logical plan (see EXPLAIN ANALYZE).
+	processorNodes map[int32]planNode
+	// lastProcessorID is the last ProcessorID assigned.
+	lastProcessorID int32
+}
+
+// assignProcessorIDs assigns ProcessorIDs to the processors of the plan which
+// don't have one yet and associates them with the given planNode.
+func (p *planningCtx) assignProcessorIDs(plan *physicalPlan, node planNode) {
+	for i := range plan.Processors {
+		spec := &plan.Processors[i].Spec
+		if spec.ProcessorID != 0 {
+			continue
+		}
+		p.lastProcessorID++
+		spec.ProcessorID = p.lastProcessorID
+		if node != nil {
+			p.processorNodes[spec.ProcessorID] = node
+		}
+	}
 }
 
 // sanityCheckAddresses returns an error if the same address is used by two
@@ -439,6 +563,16 @@ type physicalPlan struct {
 	// and indexJoinNode where not all columns in the table are actually used in
 	// the plan.
 	planToStreamColMap []int
+
+	// writeAnchorKey is set if the plan writes through DistSQL leaf
+	// transactions; the root transaction is anchored at this key (unless it
+	// already has an anchor) before the plan is run.
+	writeAnchorKey roachpb.Key
+
+	// resultIsRowCount is set if the result streams contain a single INT
+	// column holding numbers of affected rows, rather than the affected rows
+	// themselves.
+	resultIsRowCount bool
 }
 
 // orderingTerminated is used when streams can be joined without needing to be
@@ -649,6 +783,188 @@ func (dsp *DistSQLPlanner) nodeVersionIsCompatible(
 	return distsqlrun.FlowVerIsCompatible(dsp.planVersion, v.MinAcceptedVersion, v.Version)
 }
 
+// nodeLocality returns the locality of the given node, as advertised in its
+// gossiped node descriptor. An empty locality is returned if the node is not
+// known.
+func (dsp *DistSQLPlanner) nodeLocality(
+	planCtx *planningCtx, nodeID roachpb.NodeID,
+) roachpb.Locality {
+	if nodeID == dsp.nodeDesc.NodeID {
+		return dsp.nodeDesc.Locality
+	}
+	if l, ok := planCtx.nodeLocalities[nodeID]; ok {
+		return l
+	}
+	var l roachpb.Locality
+	if dsp.gossip != nil {
+		if desc, err := dsp.gossip.GetNodeDescriptor(nodeID); err == nil {
+			l = desc.Locality
+		}
+	}
+	planCtx.nodeLocalities[nodeID] = l
+	return l
+}
+
+// flowLocalities returns the localities of the nodes of the given flows.
+func (dsp *DistSQLPlanner) flowLocalities(
+	planCtx *planningCtx, flows map[roachpb.NodeID]distsqlrun.FlowSpec,
+) map[roachpb.NodeID]roachpb.Locality {
+	localities := make(map[roachpb.NodeID]roachpb.Locality, len(flows))
+	for nodeID := range flows {
+		localities[nodeID] = dsp.nodeLocality(planCtx, nodeID)
+	}
+	return localities
+}
+
+// nodeLatency returns the measured round-trip latency between this node and
+// the given node, if any.
+func (dsp *DistSQLPlanner) nodeLatency(
+	planCtx *planningCtx, nodeID roachpb.NodeID,
+) (time.Duration, bool) {
+	if nodeID == dsp.nodeDesc.NodeID {
+		return 0, true
+	}
+	addr, ok := planCtx.nodeAddresses[nodeID]
+	if !ok || addr == "" || dsp.rpcContext == nil {
+		return 0, false
+	}
+	return dsp.rpcContext.RemoteClocks.Latency(addr)
+}
+
+// colocateStage returns the nodes on which to run the processors of a stage
+// that receives hash-routed rows from processors on the given nodes (one entry
+// per stream). The nodes are deduplicated and returned in the order in which
+// they first appear.
+//
+// If locality-aware planning is enabled and the streams originate from nodes
+// in different localities, the stage is confined to the nodes of one locality
+// (see localityConfinedNodes); this way, only the rows produced in the other
+// localities cross locality boundaries, instead of every stream sending rows
+// to every other locality.
+func (dsp *DistSQLPlanner) colocateStage(
+	planCtx *planningCtx, streamNodes []roachpb.NodeID,
+) []roachpb.NodeID {
+	if !planLocalityAware.Get(&dsp.st.SV) {
+		return dedupNodes(streamNodes)
+	}
+	localities := make(map[roachpb.NodeID]roachpb.Locality)
+	latencies := make(map[roachpb.NodeID]time.Duration)
+	for _, nodeID := range streamNodes {
+		if _, ok := localities[nodeID]; ok {
+			continue
+		}
+		localities[nodeID] = dsp.nodeLocality(planCtx, nodeID)
+		if latency, ok := dsp.nodeLatency(planCtx, nodeID); ok {
+			latencies[nodeID] = latency
+		}
+	}
+	return localityConfinedNodes(streamNodes, localities, latencies)
+}
+
+// hashStageNodes returns the nodes for the processors of a stage which
+// receives the results of p hash-routed, one for each result router. Each
+// processor is placed on the node of the corresponding result router, unless
+// the nodes are confined to a single locality by colocateStage, in which case
+// the processors are spread over the remaining nodes.
+func (dsp *DistSQLPlanner) hashStageNodes(
+	planCtx *planningCtx, p *physicalPlan,
+) []roachpb.NodeID {
+	streamNodes := make([]roachpb.NodeID, len(p.ResultRouters))
+	for i, pIdx := range p.ResultRouters {
+		streamNodes[i] = p.Processors[pIdx].Node
+	}
+	colocated := dsp.colocateStage(planCtx, streamNodes)
+	allowed := make(map[roachpb.NodeID]struct{}, len(colocated))
+	for _, n := range colocated {
+		allowed[n] = struct{}{}
+	}
+	next := 0
+	for i, n := range streamNodes {
+		if _, ok := allowed[n]; !ok {
+			streamNodes[i] = colocated[next%len(colocated)]
+			next++
+		}
+	}
+	return streamNodes
+}
+
+// dedupNodes returns the distinct nodes in the given list, in the order in
+// which they first appear.
+func dedupNodes(nodes []roachpb.NodeID) []roachpb.NodeID {
+	var res []roachpb.NodeID
+	seen := make(map[roachpb.NodeID]struct{})
+	for _, n := range nodes {
+		if _, ok := seen[n]; !ok {
+			seen[n] = struct{}{}
+			res = append(res, n)
+		}
+	}
+	return res
+}
+
+// localityRegion returns the key by which nodes are grouped for locality-aware
+// planning: the first (most global) tier of the locality, usually the region.
+func localityRegion(l roachpb.Locality) string {
+	if len(l.Tiers) == 0 {
+		return ""
+	}
+	return l.Tiers[0].String()
+}
+
+// localityConfinedNodes groups the nodes of the given streams by region (see
+// localityRegion) and returns the distinct nodes of the region which produces
+// the most streams. Ties are broken in favor of the region with the lowest
+// latency to this node (according to the given latencies; nodes without a
+// latency measurement are considered remote), and then in favor of the region
+// that appears first. The nodes are returned in the order in which they first
+// appear in streamNodes.
+func localityConfinedNodes(
+	streamNodes []roachpb.NodeID,
+	localities map[roachpb.NodeID]roachpb.Locality,
+	latencies map[roachpb.NodeID]time.Duration,
+) []roachpb.NodeID {
+	type region struct {
+		streams int
+		latency time.Duration
+		// order is the position of the region in the order of first appearance.
+		order int
+	}
+	regions := make(map[string]*region)
+	for _, n := range streamNodes {
+		key := localityRegion(localities[n])
+		r, ok := regions[key]
+		if !ok {
+			r = &region{latency: time.Duration(math.MaxInt64), order: len(regions)}
+			regions[key] = r
+		}
+		r.streams++
+		if latency, ok := latencies[n]; ok && latency < r.latency {
+			r.latency = latency
+		}
+	}
+	if len(regions) <= 1 {
+		return dedupNodes(streamNodes)
+	}
+
+	var bestKey string
+	var best *region
+	for key, r := range regions {
+		if best == nil || r.streams > best.streams ||
+			(r.streams == best.streams && r.latency < best.latency) ||
+			(r.streams == best.streams && r.latency == best.latency && r.order < best.order) {
+			bestKey, best = key, r
+		}
+	}
+
+	var res []roachpb.NodeID
+	for _, n := range dedupNodes(streamNodes) {
+		if localityRegion(localities[n]) == bestKey {
+			res = append(res, n)
+		}
+	}
+	return res
+}
+
 // initTableReaderSpec initializes a TableReaderSpec/PostProcessSpec that
 // corresponds to a scanNode, except for the Spans and OutputColumns.
 func initTableReaderSpec(
@@ -749,6 +1065,12 @@ func (dsp *DistSQLPlanner) createTableReaders(
 	if err != nil {
 		return physicalPlan{}, err
 	}
+	if fanout := int(parallelScanReadersPerNode.Get(&dsp.st.SV)); fanout > 1 && canSplitScanSpans(n) {
+		spanPartitions, err = dsp.splitSpanPartitions(planCtx, spanPartitions, fanout)
+		if err != nil {
+			return physicalPlan{}, err
+		}
+	}
 
 	var p physicalPlan
 	stageID := p.NewStageID()
@@ -805,6 +1127,137 @@ func (dsp *DistSQLPlanner) createTableReaders(
 	return p, nil
 }
 
+// canSplitScanSpans returns whether the spans of the given scan can be split
+// into sub-spans by splitSpanPartitions: the scan must read its spans to the
+// end, as a limit is only enforced within each table reader.
+func canSplitScanSpans(n *scanNode) bool {
+	return n.hardLimit == 0 && n.softLimit == 0
+}
+
+// splitSpanPartitions divides the spans of each partition among up to fanout
+// partitions on the same node, so that several table readers scan the data of
+// the node concurrently.
+//
+// The spans are first split at the boundaries of the ranges that they cover,
+// which are known from the range descriptor cache. If that yields fewer pieces
+// than fanout, as is the case for a scan within a single large range, the
+// pieces are further split at keys picked by the leaseholders of their ranges
+// from the size of their data (see FindSplitKeysRequest), so that each piece
+// holds about sql.distsql.parallel_scans.target_size bytes. Neither range
+// boundaries nor these keys ever fall between the keys of a row.
+func (dsp *DistSQLPlanner) splitSpanPartitions(
+	planCtx *planningCtx, partitions []spanPartition, fanout int,
+) ([]spanPartition, error) {
+	res := make([]spanPartition, 0, len(partitions))
+	for _, p := range partitions {
+		pieces, err := dsp.splitSpansAtRanges(planCtx, p.spans)
+		if err != nil {
+			return nil, err
+		}
+		if len(pieces) < fanout {
+			pieces = dsp.splitSpansAtSizes(planCtx, pieces, fanout-1)
+		}
+		if len(pieces) <= 1 {
+			res = append(res, p)
+			continue
+		}
+		numReaders := fanout
+		if len(pieces) < numReaders {
+			numReaders = len(pieces)
+		}
+		for i := 0; i < numReaders; i++ {
+			var spans roachpb.Spans
+			for _, sp := range pieces[i*len(pieces)/numReaders : (i+1)*len(pieces)/numReaders] {
+				if last := len(spans) - 1; last >= 0 && spans[last].EndKey.Equal(sp.Key) {
+					// Two consecutive ranges read by the same reader, merge the spans.
+					spans[last].EndKey = sp.EndKey
+					continue
+				}
+				spans = append(spans, sp)
+			}
+			res = append(res, spanPartition{node: p.node, spans: spans})
+		}
+	}
+	return res, nil
+}
+
+// splitSpansAtRanges splits the given spans at the boundaries of the ranges
+// that they cover, according to the range descriptor cache.
+func (dsp *DistSQLPlanner) splitSpansAtRanges(
+	planCtx *planningCtx, spans roachpb.Spans,
+) (roachpb.Spans, error) {
+	ctx := planCtx.ctx
+	it := planCtx.spanIter
+	var res roachpb.Spans
+	for _, span := range spans {
+		var rspan roachpb.RSpan
+		var err error
+		if rspan.Key, err = keys.Addr(span.Key); err != nil {
+			return nil, err
+		}
+		if rspan.EndKey, err = keys.Addr(span.EndKey); err != nil {
+			return nil, err
+		}
+		lastKey := rspan.Key
+		for it.Seek(ctx, span, kv.Ascending); ; it.Next(ctx) {
+			if !it.Valid() {
+				return nil, it.Error()
+			}
+			endKey := it.Desc().EndKey
+			if rspan.EndKey.Less(endKey) {
+				endKey = rspan.EndKey
+			}
+			res = append(res, roachpb.Span{Key: lastKey.AsRawKey(), EndKey: endKey.AsRawKey()})
+			if !endKey.Less(rspan.EndKey) {
+				break
+			}
+			lastKey = endKey
+		}
+	}
+	return res, nil
+}
+
+// splitSpansAtSizes splits each of the given spans, which must each be within a
+// single range, into pieces of about sql.distsql.parallel_scans.target_size
+// bytes, at most maxKeys+1 pieces per span. The split keys are found by the
+// leaseholders of the ranges. The split is only an optimization: if the keys
+// can't be found, the spans are returned as they are.
+func (dsp *DistSQLPlanner) splitSpansAtSizes(
+	planCtx *planningCtx, spans roachpb.Spans, maxKeys int,
+) roachpb.Spans {
+	targetSize := parallelScanTargetSize.Get(&dsp.st.SV)
+	if dsp.distSender == nil || targetSize <= 0 || maxKeys <= 0 {
+		return spans
+	}
+	ctx := planCtx.ctx
+	var ba roachpb.BatchRequest
+	for _, span := range spans {
+		ba.Add(&roachpb.FindSplitKeysRequest{
+			Span:       span,
+			TargetSize: targetSize,
+			MaxKeys:    int32(maxKeys),
+		})
+	}
+	br, pErr := dsp.distSender.Send(ctx, ba)
+	if pErr != nil {
+		log.VEventf(ctx, 1, "unable to find split keys for parallel scan: %s", pErr)
+		return spans
+	}
+	var res roachpb.Spans
+	for i, span := range spans {
+		lastKey := span.Key
+		for _, key := range br.Responses[i].GetInner().(*roachpb.FindSplitKeysResponse).SplitKeys {
+			if key.Compare(lastKey) <= 0 || key.Compare(span.EndKey) >= 0 {
+				continue
+			}
+			res = append(res, roachpb.Span{Key: lastKey, EndKey: key})
+			lastKey = key
+		}
+		res = append(res, roachpb.Span{Key: lastKey, EndKey: span.EndKey})
+	}
+	return res
+}
+
 func initBackfillerSpec(
 	backfillType backfillType,
 	desc sqlbase.TableDescriptor,
@@ -1307,6 +1760,9 @@ func (dsp *DistSQLPlanner) addAggregators(
 		}
 		if fholder.argRenderIdx != noRenderIdx {
 			aggregations[i].ColIdx = []uint32{uint32(p.planToStreamColMap[fholder.argRenderIdx])}
+			for _, idx := range fholder.otherArgRenderIdxs {
+				aggregations[i].ColIdx = append(aggregations[i].ColIdx, uint32(p.planToStreamColMap[idx]))
+			}
 		}
 		if fholder.hasFilter {
 			col := uint32(p.planToStreamColMap[fholder.filterRenderIdx])
@@ -1723,10 +2179,11 @@ func (dsp *DistSQLPlanner) addAggregators(
 		// We have one final stage processor for each result router. This is a
 		// somewhat arbitrary decision; we could have a different number of nodes
 		// working on the final stage.
+		nodes := dsp.hashStageNodes(planCtx, p)
 		pIdxStart := distsqlplan.ProcessorIdx(len(p.Processors))
-		for _, resultProc := range p.ResultRouters {
+		for _, node := range nodes {
 			proc := distsqlplan.Processor{
-				Node: p.Processors[resultProc].Node,
+				Node: node,
 				Spec: distsqlrun.ProcessorSpec{
 					Input: []distsqlrun.InputSyncSpec{{
 						// The other fields will be filled in by mergeResultStreams.
@@ -1766,6 +2223,240 @@ func (dsp *DistSQLPlanner) addAggregators(
 	return nil
 }
 
+// windowFuncName returns the name of the builtin computed by a window
+// function, as expected by WindowerSpec.
+func windowFuncName(f *windowFuncHolder) string {
+	return strings.ToLower(f.expr.Func.FunctionReference.String())
+}
+
+// sameWindow returns true if the two window functions are computed over the
+// same partitions and ordering, and can therefore be computed by the same
+// windower.
+func sameWindow(a, b *windowFuncHolder) bool {
+	if len(a.partitionIdxs) != len(b.partitionIdxs) ||
+		len(a.columnOrdering) != len(b.columnOrdering) {
+		return false
+	}
+	for i := range a.partitionIdxs {
+		if a.partitionIdxs[i] != b.partitionIdxs[i] {
+			return false
+		}
+	}
+	for i := range a.columnOrdering {
+		if a.columnOrdering[i] != b.columnOrdering[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// addWindowers adds windowers corresponding to a windowNode and updates the
+// plan to reflect the windowNode. The window functions are grouped by their
+// window definition; each group is computed by a stage of windowers, which
+// append the results of the window functions to the stream columns. A final
+// rendering computes the windowNode's renders from these results.
+func (dsp *DistSQLPlanner) addWindowers(
+	planCtx *planningCtx, p *physicalPlan, n *windowNode,
+) error {
+	// windowFnCols[i] is the stream column containing the results of
+	// n.funcs[i].
+	windowFnCols := make([]int, len(n.funcs))
+	done := make([]bool, len(n.funcs))
+	// hashColumns are the columns the streams are currently distributed by, if
+	// any.
+	var hashColumns []uint32
+	for i, f := range n.funcs {
+		if done[i] {
+			continue
+		}
+		var spec distsqlrun.WindowerSpec
+		spec.PartitionBy = make([]uint32, len(f.partitionIdxs))
+		for j, idx := range f.partitionIdxs {
+			spec.PartitionBy[j] = uint32(p.planToStreamColMap[idx])
+		}
+		if len(f.columnOrdering) > 0 {
+			spec.Ordering.Columns = make([]distsqlrun.Ordering_Column, len(f.columnOrdering))
+			for j, o := range f.columnOrdering {
+				streamColIdx := p.planToStreamColMap[o.ColIdx]
+				if streamColIdx == -1 {
+					panic(fmt.Sprintf("column %d in window ordering not available", o.ColIdx))
+				}
+				spec.Ordering.Columns[j].ColIdx = uint32(streamColIdx)
+				spec.Ordering.Columns[j].Direction = distsqlrun.Ordering_Column_ASC
+				if o.Direction == encoding.Descending {
+					spec.Ordering.Columns[j].Direction = distsqlrun.Ordering_Column_DESC
+				}
+			}
+		}
+
+		outTypes := append([]sqlbase.ColumnType(nil), p.ResultTypes...)
+		for j := i; j < len(n.funcs); j++ {
+			g := n.funcs[j]
+			if done[j] || !sameWindow(f, g) {
+				continue
+			}
+			done[j] = true
+			fn := distsqlrun.WindowerSpec_WindowFn{
+				Func:    windowFuncName(g),
+				ArgIdxs: make([]uint32, g.argCount),
+			}
+			argTypes := make([]sqlbase.ColumnType, g.argCount)
+			for k := range fn.ArgIdxs {
+				streamColIdx := p.planToStreamColMap[g.argIdxStart+k]
+				fn.ArgIdxs[k] = uint32(streamColIdx)
+				argTypes[k] = p.ResultTypes[streamColIdx]
+			}
+			_, retType, err := distsqlrun.GetWindowFunctionInfo(fn.Func, argTypes...)
+			if err != nil {
+				return err
+			}
+			windowFnCols[j] = len(outTypes)
+			outTypes = append(outTypes, retType)
+			spec.WindowFns = append(spec.WindowFns, fn)
+		}
+		core := distsqlrun.ProcessorCoreUnion{Windower: &spec}
+
+		// Check if the previous stage is all on one node.
+		prevStageNode := p.Processors[p.ResultRouters[0]].Node
+		for j := 1; j < len(p.ResultRouters); j++ {
+			if n := p.Processors[p.ResultRouters[j]].Node; n != prevStageNode {
+				prevStageNode = 0
+				break
+			}
+		}
+
+		if len(spec.PartitionBy) == 0 || len(p.ResultRouters) == 1 {
+			// No PARTITION BY, or we have a single stream. Use a single windower.
+			// If the previous stage was all on a single node, put the windower
+			// there. Otherwise, bring the results back on this node.
+			node := dsp.nodeDesc.NodeID
+			if prevStageNode != 0 {
+				node = prevStageNode
+			}
+			p.AddSingleGroupStage(node, core, distsqlrun.PostProcessSpec{}, outTypes)
+			hashColumns = nil
+		} else if sameColumns(hashColumns, spec.PartitionBy) {
+			// The streams are already distributed by the partition columns (the
+			// previous windowers don't change the columns of their input).
+			p.AddNoGroupingStage(core, distsqlrun.PostProcessSpec{}, outTypes, orderingTerminated)
+		} else {
+			// We distribute (by partition columns) to multiple processors.
+
+			// Set up the output routers from the previous stage.
+			for _, resultProc := range p.ResultRouters {
+				p.Processors[resultProc].Spec.Output[0] = distsqlrun.OutputRouterSpec{
+					Type:        distsqlrun.OutputRouterSpec_BY_HASH,
+					HashColumns: spec.PartitionBy,
+				}
+			}
+
+			stageID := p.NewStageID()
+
+			// We have one windower for each result router, usually on the same
+			// node (see hashStageNodes).
+			nodes := dsp.hashStageNodes(planCtx, p)
+			pIdxStart := distsqlplan.ProcessorIdx(len(p.Processors))
+			for _, node := range nodes {
+				proc := distsqlplan.Processor{
+					Node: node,
+					Spec: distsqlrun.ProcessorSpec{
+						Input: []distsqlrun.InputSyncSpec{{
+							// The other fields will be filled in by mergeResultStreams.
+							ColumnTypes: p.ResultTypes,
+						}},
+						Core: core,
+						Output: []distsqlrun.OutputRouterSpec{{
+							Type: distsqlrun.OutputRouterSpec_PASS_THROUGH,
+						}},
+						StageID: stageID,
+					},
+				}
+				p.AddProcessor(proc)
+			}
+
+			// Connect the streams.
+			for bucket := 0; bucket < len(p.ResultRouters); bucket++ {
+				pIdx := pIdxStart + distsqlplan.ProcessorIdx(bucket)
+				p.MergeResultStreams(p.ResultRouters, bucket, distsqlrun.Ordering{}, pIdx, 0)
+			}
+
+			// Set the new result routers.
+			for j := 0; j < len(p.ResultRouters); j++ {
+				p.ResultRouters[j] = pIdxStart + distsqlplan.ProcessorIdx(j)
+			}
+			p.ResultTypes = outTypes
+			p.SetMergeOrdering(orderingTerminated)
+			hashColumns = spec.PartitionBy
+		}
+	}
+
+	// Compute the windowNode's renders. The expressions refer to the source
+	// columns and to the window function results through IndexedVars; the
+	// window function results are numbered after the source columns.
+	sourceCols := len(p.planToStreamColMap)
+	indexVarMap := make([]int, sourceCols+len(n.funcs))
+	copy(indexVarMap, p.planToStreamColMap)
+	copy(indexVarMap[sourceCols:], windowFnCols)
+	ivarHelper := tree.MakeIndexedVarHelper(nil, len(indexVarMap))
+
+	renders := make([]tree.TypedExpr, len(n.windowRender))
+	curColIdx := 0
+	curFnIdx := 0
+	for i, render := range n.windowRender {
+		if render == nil {
+			// The column is passed through from the source (see
+			// windowNode.populateValues).
+			renders[i] = ivarHelper.IndexedVarWithType(curColIdx, n.values.columns[i].Typ)
+			curColIdx++
+			continue
+		}
+		// Skip the source columns that were used as arguments to the window
+		// functions of this render.
+		for ; curFnIdx < len(n.funcs); curFnIdx++ {
+			windowFn := n.funcs[curFnIdx]
+			if windowFn.argIdxStart != curColIdx {
+				break
+			}
+			curColIdx += windowFn.argCount
+		}
+		expr, err := tree.SimpleVisit(render, func(expr tree.Expr) (error, bool, tree.Expr) {
+			switch t := expr.(type) {
+			case *windowFuncHolder:
+				return nil, false, ivarHelper.IndexedVarWithType(sourceCols+t.funcIdx, t.ResolvedType())
+			case *tree.IndexedVar:
+				idx := n.colContainer.idxMap[t.Idx]
+				if _, ok := n.aggContainer.aggIVars[t]; ok {
+					idx = n.aggContainer.idxMap[t.Idx]
+				}
+				return nil, false, ivarHelper.IndexedVarWithType(idx, t.ResolvedType())
+			}
+			return nil, true, expr
+		})
+		if err != nil {
+			return err
+		}
+		renders[i] = expr.(tree.TypedExpr)
+	}
+
+	p.AddRendering(renders, planCtx.evalCtx, indexVarMap, getTypesForPlanResult(n, nil))
+	p.planToStreamColMap = identityMap(p.planToStreamColMap, len(renders))
+	return nil
+}
+
+// sameColumns returns true if the two slices contain the same columns, in the
+// same order. It returns false if either slice is nil.
+func sameColumns(a, b []uint32) bool {
+	if a == nil || b == nil || len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
 func (dsp *DistSQLPlanner) createPlanForIndexJoin(
 	planCtx *planningCtx, n *indexJoinNode,
 ) (physicalPlan, error) {
@@ -1892,6 +2583,15 @@ func (dsp *DistSQLPlanner) createPlanForJoin(
 	//    joiner.
 	//
 	//  - The routers of the joiner processors are the result routers of the plan.
+	//
+	// If the left side is small and the right side is a table that has an index
+	// on (a prefix of) the equality columns, we instead plan a lookup join: the
+	// rows of the right side are looked up in the index by join readers fed by
+	// the left side (see createPlanForLookupJoin).
+
+	if rightScan, indexIdx, numLookupCols := dsp.findLookupJoinIndex(n); rightScan != nil {
+		return dsp.createPlanForLookupJoin(planCtx, n, rightScan, indexIdx, numLookupCols)
+	}
 
 	leftPlan, err := dsp.createPlanForNode(planCtx, n.left.plan)
 	if err != nil {
@@ -1929,6 +2629,12 @@ func (dsp *DistSQLPlanner) createPlanForJoin(
 		joinType = distsqlrun.JoinType_RIGHT_OUTER
 	case joinTypeLeftOuter:
 		joinType = distsqlrun.JoinType_LEFT_OUTER
+	case joinTypeLeftSemi:
+		joinType = distsqlrun.JoinType_LEFT_SEMI
+	case joinTypeLeftAnti:
+		joinType = distsqlrun.JoinType_LEFT_ANTI
+	case joinTypeLeftAntiNullAware:
+		joinType = distsqlrun.JoinType_LEFT_ANTI_NULL_AWARE
 	default:
 		panic(fmt.Sprintf("invalid join type %d", n.joinType))
 	}
@@ -1937,27 +2643,8 @@ func (dsp *DistSQLPlanner) createPlanForJoin(
 	leftTypes := leftPlan.ResultTypes
 	rightTypes := rightPlan.ResultTypes
 
-	// Set up the output columns.
+	// Set up the equality columns.
 	if numEq := len(n.pred.leftEqualityIndices); numEq != 0 {
-		// TODO(radu): for now we run a join processor on every node that produces
-		// data for either source. In the future we should be smarter here.
-		seen := make(map[roachpb.NodeID]struct{})
-		for _, pIdx := range leftRouters {
-			n := p.Processors[pIdx].Node
-			if _, ok := seen[n]; !ok {
-				seen[n] = struct{}{}
-				nodes = append(nodes, n)
-			}
-		}
-		for _, pIdx := range rightRouters {
-			n := p.Processors[pIdx].Node
-			if _, ok := seen[n]; !ok {
-				seen[n] = struct{}{}
-				nodes = append(nodes, n)
-			}
-		}
-
-		// Set up the equality columns.
 		leftEqCols = make([]uint32, numEq)
 		for i, leftPlanCol := range n.pred.leftEqualityIndices {
 			leftEqCols[i] = uint32(leftPlan.planToStreamColMap[leftPlanCol])
@@ -1966,8 +2653,23 @@ func (dsp *DistSQLPlanner) createPlanForJoin(
 		for i, rightPlanCol := range n.pred.rightEqualityIndices {
 			rightEqCols[i] = uint32(rightPlan.planToStreamColMap[rightPlanCol])
 		}
-		if planMergeJoins.Get(&dsp.st.SV) && len(n.mergeJoinOrdering) > 0 &&
-			joinType == distsqlrun.JoinType_INNER {
+	}
+
+	// Set up the output columns.
+	if len(leftEqCols) != 0 && joinType != distsqlrun.JoinType_LEFT_ANTI_NULL_AWARE {
+		// We run a join processor on every node that produces data for either
+		// source, restricted to a single locality if the sources span multiple
+		// localities (see colocateStage).
+		streamNodes := make([]roachpb.NodeID, 0, len(leftRouters)+len(rightRouters))
+		for _, pIdx := range leftRouters {
+			streamNodes = append(streamNodes, p.Processors[pIdx].Node)
+		}
+		for _, pIdx := range rightRouters {
+			streamNodes = append(streamNodes, p.Processors[pIdx].Node)
+		}
+		nodes = dsp.colocateStage(planCtx, streamNodes)
+
+		if planMergeJoins.Get(&dsp.st.SV) && len(n.mergeJoinOrdering) > 0 {
 			// TODO(radu): we currently only use merge joins when we have an ordering on
 			// all equality columns. We should relax this by either:
 			//  - implementing a hybrid hash/merge processor which implements merge
@@ -1992,7 +2694,8 @@ func (dsp *DistSQLPlanner) createPlanForJoin(
 		}
 	} else {
 		// Without column equality, we cannot distribute the join. Run a
-		// single processor.
+		// single processor. The same goes for null-aware anti joins, for which
+		// a NULL on the right side affects the results for all the left rows.
 		nodes = []roachpb.NodeID{dsp.nodeDesc.NodeID}
 
 		// If either side has a single stream, put the processor on that node. We
@@ -2018,6 +2721,7 @@ func (dsp *DistSQLPlanner) createPlanForJoin(
 	// The join columns are in two groups:
 	//  - the columns on the left side (numLeftCols)
 	//  - the columns on the right side (numRightCols)
+	// Semi and anti joins only have the columns on the left side.
 	joinCol := 0
 
 	for i := 0; i < n.pred.numLeftCols; i++ {
@@ -2026,7 +2730,7 @@ func (dsp *DistSQLPlanner) createPlanForJoin(
 		}
 		joinCol++
 	}
-	for i := 0; i < n.pred.numRightCols; i++ {
+	for i := 0; i < len(n.columns)-n.pred.numLeftCols; i++ {
 		if !n.columns[joinCol].Omitted {
 			joinToStreamColMap[joinCol] = addOutCol(
 				uint32(rightPlan.planToStreamColMap[i] + len(leftTypes)),
@@ -2040,7 +2744,7 @@ func (dsp *DistSQLPlanner) createPlanForJoin(
 		// the join columns as described above) to values that make sense in the
 		// joiner (0 to N-1 for the left input columns, N to N+M-1 for the right
 		// input columns).
-		joinColMap := make([]int, len(n.columns))
+		joinColMap := make([]int, n.pred.numLeftCols+n.pred.numRightCols)
 		idx := 0
 		for i := 0; i < n.pred.numLeftCols; i++ {
 			joinColMap[idx] = leftPlan.planToStreamColMap[i]
@@ -2128,14 +2832,23 @@ func (dsp *DistSQLPlanner) createPlanForJoin(
 	}
 	p.ResultRouters = p.ResultRouters[:0]
 
+	// Semi and anti joins preserve the ordering of the left side (see
+	// joinNode.joinOrdering), so the left streams are merged according to it.
+	// It implies the merge join ordering, if there is one.
+	leftInputOrd := leftMergeOrd
+	if n.joinType.isSemiOrAnti() {
+		leftInputOrd = dsp.convertOrdering(planPhysicalProps(n.left.plan), leftPlan.planToStreamColMap)
+	}
+
 	// Connect the left and right routers to the output joiners. Each joiner
 	// corresponds to a hash bucket.
 	for bucket := 0; bucket < len(nodes); bucket++ {
 		pIdx := pIdxStart + distsqlplan.ProcessorIdx(bucket)
 
-		// Connect left routers to the processor's first input. Currently the join
-		// node doesn't care about the orderings of the left and right results.
-		p.MergeResultStreams(leftRouters, bucket, leftMergeOrd, pIdx, 0)
+		// Connect left routers to the processor's first input. Otherwise, the
+		// join node doesn't care about the orderings of the left and right
+		// results.
+		p.MergeResultStreams(leftRouters, bucket, leftInputOrd, pIdx, 0)
 		// Connect right routers to the processor's second input.
 		p.MergeResultStreams(rightRouters, bucket, rightMergeOrd, pIdx, 1)
 
@@ -2152,8 +2865,654 @@ func (dsp *DistSQLPlanner) createPlanForJoin(
 	return p, nil
 }
 
+// isSmallPlan returns true if the given plan is expected to produce few rows:
+// it reads a constrained part of a table or has a limit. Without table
+// statistics, this is a heuristic used to decide whether to plan lookup joins.
+func isSmallPlan(plan planNode) bool {
+	switch n := plan.(type) {
+	case *scanNode:
+		if n.hardLimit != 0 || n.softLimit != 0 {
+			return true
+		}
+		return !(len(n.spans) == 1 && n.spans[0].EqualValue(n.desc.IndexSpan(n.index.ID)))
+	case *indexJoinNode:
+		return isSmallPlan(n.index)
+	case *renderNode:
+		return isSmallPlan(n.source.plan)
+	case *filterNode:
+		return isSmallPlan(n.source.plan)
+	case *sortNode:
+		return isSmallPlan(n.plan)
+	case *limitNode:
+		return n.countExpr != nil
+	case *valuesNode:
+		return true
+	}
+	return false
+}
+
+// findLookupJoinIndex determines if a join can be planned as a lookup join.
+// This is the case when the left side is small (see isSmallPlan) and the right
+// side is a full scan of a table with an index whose first columns are all
+// equality columns of the join and which contains all the columns needed from
+// the right side. If so, it returns the right side's scanNode, the index to use
+// (see JoinReaderSpec.IndexIdx) and the number of index columns that are
+// matched with equality columns. Otherwise, it returns a nil scanNode.
+//
+// The index that matches the largest number of equality columns is chosen,
+// with ties going to the primary index.
+func (dsp *DistSQLPlanner) findLookupJoinIndex(
+	n *joinNode,
+) (rightScan *scanNode, indexIdx uint32, numLookupCols int) {
+	if !planLookupJoins.Get(&dsp.st.SV) || len(n.pred.leftEqualityIndices) == 0 {
+		return nil, 0, 0
+	}
+	if n.joinType != joinTypeInner && n.joinType != joinTypeLeftOuter &&
+		n.joinType != joinTypeLeftSemi {
+		return nil, 0, 0
+	}
+	scan, ok := n.right.plan.(*scanNode)
+	if !ok || scan.hardLimit != 0 || scan.softLimit != 0 || !isSmallPlan(n.left.plan) {
+		return nil, 0, 0
+	}
+	if len(scan.cols) != len(scan.desc.Columns) || isSmallPlan(scan) {
+		// The right side doesn't read the whole table (or not all the columns);
+		// a hash join is good enough.
+		return nil, 0, 0
+	}
+
+	// Build the list of the right table columns that are needed by the join.
+	var neededIDs []sqlbase.ColumnID
+	scan.valNeededForCol.ForEach(func(i int) {
+		neededIDs = append(neededIDs, scan.desc.Columns[i].ID)
+	})
+	for _, i := range n.pred.rightEqualityIndices {
+		neededIDs = append(neededIDs, scan.desc.Columns[i].ID)
+	}
+	for i := n.pred.numLeftCols; i < len(n.columns); i++ {
+		if !n.columns[i].Omitted {
+			neededIDs = append(neededIDs, scan.desc.Columns[i-n.pred.numLeftCols].ID)
+		}
+	}
+
+	// tryIndex returns the number of leading index columns that are right
+	// equality columns, or 0 if the index doesn't contain all the needed
+	// columns.
+	tryIndex := func(index *sqlbase.IndexDescriptor, primary bool) int {
+		if !primary {
+			for _, id := range neededIDs {
+				if !index.ContainsColumnID(id) {
+					return 0
+				}
+			}
+		}
+		numCols := 0
+	IndexColLoop:
+		for _, id := range index.ColumnIDs {
+			for _, i := range n.pred.rightEqualityIndices {
+				if scan.desc.Columns[i].ID == id {
+					numCols++
+					continue IndexColLoop
+				}
+			}
+			break
+		}
+		return numCols
+	}
+
+	numLookupCols = tryIndex(&scan.desc.PrimaryIndex, true /* primary */)
+	for i := range scan.desc.Indexes {
+		if numCols := tryIndex(&scan.desc.Indexes[i], false /* primary */); numCols > numLookupCols {
+			indexIdx = uint32(i + 1)
+			numLookupCols = numCols
+		}
+	}
+	if numLookupCols == 0 {
+		return nil, 0, 0
+	}
+	return scan, indexIdx, numLookupCols
+}
+
+// createPlanForLookupJoin plans a join as a lookup join (see
+// findLookupJoinIndex): a join reader is added for each stream of the left
+// side; it looks up the rows of the right table that match each left row.
+func (dsp *DistSQLPlanner) createPlanForLookupJoin(
+	planCtx *planningCtx, n *joinNode, rightScan *scanNode, indexIdx uint32, numLookupCols int,
+) (physicalPlan, error) {
+	plan, err := dsp.createPlanForNode(planCtx, n.left.plan)
+	if err != nil {
+		return physicalPlan{}, err
+	}
+	leftTypes := plan.ResultTypes
+
+	// The internal columns of the join reader are the left stream columns
+	// followed by all the columns of the table. joinColMap maps the join columns
+	// (the left columns followed by the right columns) to these columns.
+	joinColMap := make([]int, n.pred.numLeftCols+n.pred.numRightCols)
+	for i := 0; i < n.pred.numLeftCols; i++ {
+		joinColMap[i] = plan.planToStreamColMap[i]
+	}
+	for i := 0; i < n.pred.numRightCols; i++ {
+		joinColMap[n.pred.numLeftCols+i] = len(leftTypes) + i
+	}
+
+	spec := distsqlrun.JoinReaderSpec{
+		Table:         *rightScan.desc,
+		IndexIdx:      indexIdx,
+		LookupColumns: make([]uint32, numLookupCols),
+		Type:          distsqlrun.JoinType_INNER,
+	}
+	switch n.joinType {
+	case joinTypeLeftOuter:
+		spec.Type = distsqlrun.JoinType_LEFT_OUTER
+	case joinTypeLeftSemi:
+		spec.Type = distsqlrun.JoinType_LEFT_SEMI
+	}
+	index := &rightScan.desc.PrimaryIndex
+	if indexIdx > 0 {
+		index = &rightScan.desc.Indexes[indexIdx-1]
+	}
+
+	// The lookup columns are the left equality columns that match the first
+	// index columns; the remaining equality columns are checked by the ON
+	// expression.
+	var onConds []string
+	lookupEqIdx := make(map[int]struct{}, numLookupCols)
+	for i := 0; i < numLookupCols; i++ {
+		for j, rightCol := range n.pred.rightEqualityIndices {
+			if rightScan.desc.Columns[rightCol].ID == index.ColumnIDs[i] {
+				spec.LookupColumns[i] = uint32(joinColMap[n.pred.leftEqualityIndices[j]])
+				lookupEqIdx[j] = struct{}{}
+				break
+			}
+		}
+	}
+	for j := range n.pred.leftEqualityIndices {
+		if _, ok := lookupEqIdx[j]; ok {
+			continue
+		}
+		onConds = append(onConds, fmt.Sprintf(
+			"@%d = @%d",
+			joinColMap[n.pred.leftEqualityIndices[j]]+1,
+			joinColMap[n.pred.numLeftCols+n.pred.rightEqualityIndices[j]]+1,
+		))
+	}
+	if n.pred.onCond != nil {
+		onConds = append(onConds,
+			distsqlplan.MakeExpression(n.pred.onCond, planCtx.evalCtx, joinColMap).Expr)
+	}
+	if rightScan.filter != nil {
+		// The filter refers to the columns of the right table.
+		onConds = append(onConds,
+			distsqlplan.MakeExpression(rightScan.filter, planCtx.evalCtx, joinColMap[n.pred.numLeftCols:]).Expr)
+	}
+	if len(onConds) > 0 {
+		spec.OnExpr.Expr = "(" + strings.Join(onConds, ") AND (") + ")"
+	}
+
+	post := distsqlrun.PostProcessSpec{
+		Projection: true,
+	}
+	joinToStreamColMap := makePlanToStreamColMap(len(n.columns))
+	for i := range n.columns {
+		if !n.columns[i].Omitted {
+			joinToStreamColMap[i] = len(post.OutputColumns)
+			post.OutputColumns = append(post.OutputColumns, uint32(joinColMap[i]))
+		}
+	}
+
+	// The join readers don't guarantee any output ordering, except for semi
+	// joins which preserve the ordering of the left side.
+	outputOrd := orderingTerminated
+	if n.joinType == joinTypeLeftSemi {
+		outputOrd = dsp.convertOrdering(n.props, joinToStreamColMap)
+	}
+	plan.AddNoGroupingStage(
+		distsqlrun.ProcessorCoreUnion{JoinReader: &spec},
+		post,
+		getTypesForPlanResult(n, joinToStreamColMap),
+		outputOrd,
+	)
+	plan.planToStreamColMap = joinToStreamColMap
+	return plan, nil
+}
+
+// checkSupportForMutation is the counterpart of checkSupportForNode for the
+// INSERT, UPDATE and DELETE statements, which DistSQL only supports at the
+// root of a plan. The writes are performed by table writers (see
+// addTableWriters) that use leaf transactions and stream their intents back
+// to the gateway.
+func (dsp *DistSQLPlanner) checkSupportForMutation(node planNode) (distRecommendation, error) {
+	if !planMutations.Get(&dsp.st.SV) {
+		return 0, mutationsNotSupportedError
+	}
+
+	var en *editNodeBase
+	var source planNode
+	var exprs []tree.TypedExpr
+	switch n := node.(type) {
+	case *insertNode:
+		if n.n.OnConflict != nil {
+			return 0, newQueryNotSupportedError("ON CONFLICT not supported")
+		}
+		en, source = &n.editNodeBase, n.run.rows
+		// The table writers flush their writes while the source is still being
+		// read, so the source must not read the table that is written to.
+		if planReadsTable(source, en.tableDesc.ID, false /* secondaryOnly */) {
+			return 0, newQueryNotSupportedError("INSERT reading from the target table not supported")
+		}
+		exprs = append(exprs, n.defaultExprs...)
+		exprs = append(exprs, n.checkHelper.exprs...)
+
+	case *updateNode:
+		en, source = &n.editNodeBase, n.run.rows
+		for _, slot := range n.sourceSlots {
+			if _, ok := slot.(scalarSlot); !ok {
+				return 0, newQueryNotSupportedError("tuple assignments not supported")
+			}
+		}
+		// The rows written by the table writers must not be read again by the
+		// source; this could happen if they were moved ahead of the scan.
+		for _, col := range n.tw.ru.UpdateCols {
+			for _, id := range en.tableDesc.PrimaryIndex.ColumnIDs {
+				if col.ID == id {
+					return 0, newQueryNotSupportedError("UPDATE of primary key columns not supported")
+				}
+			}
+		}
+		if planReadsTable(source, en.tableDesc.ID, true /* secondaryOnly */) {
+			return 0, newQueryNotSupportedError("UPDATE through a secondary index not supported")
+		}
+		exprs = append(exprs, n.checkHelper.exprs...)
+
+	case *deleteNode:
+		en, source = &n.editNodeBase, n.run.rows
+		maybeScan := source
+		if sel, ok := maybeScan.(*renderNode); ok {
+			maybeScan = sel.source.plan
+		}
+		if scan, ok := maybeScan.(*scanNode); ok && canDeleteWithoutScan(context.TODO(), n.n, scan, &n.tw) {
+			// The fast path deletes whole key ranges without reading them.
+			return 0, newQueryNotSupportedError("DELETE can be performed without a scan")
+		}
+	}
+
+	if sqlbase.IsSystemConfigID(en.tableDesc.GetID()) {
+		// The writes need to trigger a gossip update of the system config,
+		// which leaf transactions can't do.
+		return 0, newQueryNotSupportedError("mutations of system config tables not supported")
+	}
+	if _, ok := node.(*insertNode); !ok {
+		// The table readers can't read the columns that are being added or
+		// dropped.
+		for _, m := range en.tableDesc.Mutations {
+			if m.GetColumn() != nil {
+				return 0, newQueryNotSupportedError("tables with column mutations not supported")
+			}
+		}
+	}
+
+	exprs = append(exprs, en.rh.exprs...)
+	for _, e := range exprs {
+		if err := dsp.checkExpr(e); err != nil {
+			return 0, err
+		}
+	}
+
+	rec, err := dsp.checkSupportForNode(source)
+	if err != nil {
+		return 0, err
+	}
+	// A VALUES clause on its own doesn't benefit from distribution.
+	if _, ok := source.(*valuesNode); ok {
+		rec = canDistribute
+	}
+	return rec, nil
+}
+
+// planReadsTable returns whether the given plan scans the table with the
+// given ID. If secondaryOnly is set, only scans of secondary indexes count.
+func planReadsTable(plan planNode, tableID sqlbase.ID, secondaryOnly bool) bool {
+	found := false
+	_ = walkPlan(context.TODO(), plan, planObserver{
+		enterNode: func(_ context.Context, _ string, p planNode) bool {
+			if s, ok := p.(*scanNode); ok && s.desc.ID == tableID &&
+				(!secondaryOnly || s.index.ID != s.desc.PrimaryIndex.ID) {
+				found = true
+			}
+			return !found
+		},
+	})
+	return found
+}
+
+func (dsp *DistSQLPlanner) createPlanForInsert(
+	planCtx *planningCtx, n *insertNode,
+) (physicalPlan, error) {
+	plan, err := dsp.createPlanForNode(planCtx, n.run.rows)
+	if err != nil {
+		return physicalPlan{}, err
+	}
+
+	// The source produces values for a prefix of the insert columns; the
+	// values for the rest of them are generated from the default expressions.
+	sourceCols := planColumns(n.run.rows)
+	ivarHelper := tree.MakeIndexedVarHelper(nil, len(sourceCols))
+	renders := make([]tree.TypedExpr, len(n.insertCols))
+	outTypes := make([]sqlbase.ColumnType, len(n.insertCols))
+	for i := range n.insertCols {
+		if i < len(sourceCols) {
+			renders[i] = ivarHelper.IndexedVarWithType(i, sourceCols[i].Typ)
+			outTypes[i] = plan.ResultTypes[plan.planToStreamColMap[i]]
+			continue
+		}
+		if n.defaultExprs == nil {
+			renders[i] = tree.DNull
+		} else {
+			renders[i] = n.defaultExprs[i]
+		}
+		outTypes[i] = n.insertCols[i].Type
+	}
+	// The order in which the rows are written doesn't matter.
+	plan.SetMergeOrdering(orderingTerminated)
+	plan.AddRendering(renders, planCtx.evalCtx, plan.planToStreamColMap, outTypes)
+	plan.planToStreamColMap = identityMap(nil, len(renders))
+
+	spec := distsqlrun.TableWriterSpec{
+		Type:       distsqlrun.TableWriterSpec_INSERT,
+		InsertCols: columnIDs(n.insertCols),
+	}
+	if err := dsp.addTableWriters(
+		planCtx, &plan, n, &n.editNodeBase, spec, n.fkTables, n.checkHelper.exprs,
+		n.insertColIDtoRowIndex,
+	); err != nil {
+		return physicalPlan{}, err
+	}
+	return plan, nil
+}
+
+func (dsp *DistSQLPlanner) createPlanForUpdate(
+	planCtx *planningCtx, n *updateNode,
+) (physicalPlan, error) {
+	plan, err := dsp.createPlanForNode(planCtx, n.run.rows)
+	if err != nil {
+		return physicalPlan{}, err
+	}
+
+	// The source renders the fetched columns, followed by the new values; the
+	// latter can reuse the renders of the former.
+	ru := &n.tw.ru
+	cols := make([]uint32, 0, len(ru.FetchCols)+len(ru.UpdateCols))
+	for i := range ru.FetchCols {
+		cols = append(cols, uint32(plan.planToStreamColMap[i]))
+	}
+	for _, slot := range n.sourceSlots {
+		cols = append(cols, uint32(plan.planToStreamColMap[slot.(scalarSlot).sourceIndex]))
+	}
+	// The order in which the rows are written doesn't matter.
+	plan.SetMergeOrdering(orderingTerminated)
+	plan.AddProjection(cols)
+	plan.planToStreamColMap = identityMap(nil, len(cols))
+
+	spec := distsqlrun.TableWriterSpec{
+		Type:          distsqlrun.TableWriterSpec_UPDATE,
+		UpdateCols:    columnIDs(ru.UpdateCols),
+		RequestedCols: columnIDs(ru.FetchCols),
+	}
+	if err := dsp.addTableWriters(
+		planCtx, &plan, n, &n.editNodeBase, spec, n.fkTables, n.checkHelper.exprs,
+		ru.FetchColIDtoRowIndex,
+	); err != nil {
+		return physicalPlan{}, err
+	}
+	return plan, nil
+}
+
+func (dsp *DistSQLPlanner) createPlanForDelete(
+	planCtx *planningCtx, n *deleteNode,
+) (physicalPlan, error) {
+	plan, err := dsp.createPlanForNode(planCtx, n.run.rows)
+	if err != nil {
+		return physicalPlan{}, err
+	}
+
+	rd := &n.tw.rd
+	cols := make([]uint32, len(rd.FetchCols))
+	for i := range rd.FetchCols {
+		cols[i] = uint32(plan.planToStreamColMap[i])
+	}
+	// The order in which the rows are written doesn't matter.
+	plan.SetMergeOrdering(orderingTerminated)
+	plan.AddProjection(cols)
+	plan.planToStreamColMap = identityMap(nil, len(cols))
+
+	spec := distsqlrun.TableWriterSpec{
+		Type:          distsqlrun.TableWriterSpec_DELETE,
+		RequestedCols: columnIDs(rd.FetchCols),
+	}
+	if err := dsp.addTableWriters(
+		planCtx, &plan, n, &n.editNodeBase, spec, n.fkTables, nil, /* checks */
+		rd.FetchColIDtoRowIndex,
+	); err != nil {
+		return physicalPlan{}, err
+	}
+	return plan, nil
+}
+
+// columnIDs returns the IDs of the given columns.
+func columnIDs(cols []sqlbase.ColumnDescriptor) []sqlbase.ColumnID {
+	ids := make([]sqlbase.ColumnID, len(cols))
+	for i := range cols {
+		ids[i] = cols[i].ID
+	}
+	return ids
+}
+
+// addTableWriters adds a stage of table writers to a plan whose results are
+// the input rows for the writers, as described by TableWriterSpec. The rows
+// are routed to writers on the leaseholders of the ranges of the primary
+// index, using the primary key columns found in the rows through
+// colIDtoRowIndex. The writers' output is then rendered according to the
+// RETURNING clause of the statement; without one, the result streams contain
+// the numbers of rows written.
+//
+// spec only needs to have the fields that are specific to the type of the
+// mutation set.
+func (dsp *DistSQLPlanner) addTableWriters(
+	planCtx *planningCtx,
+	p *physicalPlan,
+	n planNode,
+	en *editNodeBase,
+	spec distsqlrun.TableWriterSpec,
+	fkTables sqlbase.TableLookupsByID,
+	checks []tree.TypedExpr,
+	colIDtoRowIndex map[sqlbase.ColumnID]int,
+) error {
+	desc := en.tableDesc
+	spec.Table = *desc
+	spec.Returning = en.rh.exprs != nil
+
+	fkIDs := make([]int, 0, len(fkTables))
+	for id := range fkTables {
+		fkIDs = append(fkIDs, int(id))
+	}
+	sort.Ints(fkIDs)
+	for _, id := range fkIDs {
+		lookup := fkTables[sqlbase.ID(id)]
+		if lookup.IsAdding {
+			spec.AddingFkTables = append(spec.AddingFkTables, sqlbase.ID(id))
+		} else if lookup.Table != nil {
+			spec.FkTables = append(spec.FkTables, *lookup.Table)
+		}
+	}
+
+	// The CHECK expressions refer to the columns of the table.
+	tableColsMap := identityMap(nil, len(desc.Columns))
+	spec.Checks = make([]distsqlrun.Expression, len(checks))
+	for i, e := range checks {
+		spec.Checks[i] = distsqlplan.MakeExpression(e, planCtx.evalCtx, tableColsMap)
+	}
+
+	tableTypes := make([]sqlbase.ColumnType, len(desc.Columns))
+	for i := range desc.Columns {
+		tableTypes[i] = desc.Columns[i].Type
+	}
+	outTypes := []sqlbase.ColumnType{{SemanticType: sqlbase.ColumnType_INT}}
+	if spec.Returning {
+		outTypes = tableTypes
+	}
+
+	nodes, routerSpec, err := dsp.partitionMutation(planCtx, desc, colIDtoRowIndex)
+	if err != nil {
+		return err
+	}
+	core := distsqlrun.ProcessorCoreUnion{TableWriter: &spec}
+	if len(nodes) == 0 {
+		// The writers can't be placed with the ranges they write to; they are
+		// placed with the sources of their rows instead.
+		p.AddNoGroupingStage(core, distsqlrun.PostProcessSpec{}, outTypes, orderingTerminated)
+	} else if len(nodes) == 1 {
+		p.AddSingleGroupStage(nodes[0], core, distsqlrun.PostProcessSpec{}, outTypes)
+	} else {
+		// Route each row to the writer on the node that has the range it
+		// belongs to.
+		stageID := p.NewStageID()
+		writers := make(map[roachpb.NodeID]distsqlplan.ProcessorIdx)
+		var writerIdxs []distsqlplan.ProcessorIdx
+		for _, nodeID := range nodes {
+			if _, ok := writers[nodeID]; ok {
+				continue
+			}
+			proc := distsqlplan.Processor{
+				Node: nodeID,
+				Spec: distsqlrun.ProcessorSpec{
+					Input: []distsqlrun.InputSyncSpec{{
+						Type:        distsqlrun.InputSyncSpec_UNORDERED,
+						ColumnTypes: p.ResultTypes,
+					}},
+					Core: core,
+					Output: []distsqlrun.OutputRouterSpec{{
+						Type: distsqlrun.OutputRouterSpec_PASS_THROUGH,
+					}},
+					StageID: stageID,
+				},
+			}
+			pIdx := p.AddProcessor(proc)
+			writers[nodeID] = pIdx
+			writerIdxs = append(writerIdxs, pIdx)
+		}
+		for _, resultProc := range p.ResultRouters {
+			p.Processors[resultProc].Spec.Output[0] = distsqlrun.OutputRouterSpec{
+				Type:            distsqlrun.OutputRouterSpec_BY_RANGE,
+				RangeRouterSpec: routerSpec,
+			}
+			for slot, nodeID := range nodes {
+				p.Streams = append(p.Streams, distsqlplan.Stream{
+					SourceProcessor:  resultProc,
+					SourceRouterSlot: slot,
+					DestProcessor:    writers[nodeID],
+					DestInput:        0,
+				})
+			}
+		}
+		p.ResultRouters = writerIdxs
+		p.ResultTypes = outTypes
+		p.MergeOrdering = orderingTerminated
+	}
+
+	p.writeAnchorKey = sqlbase.MakeIndexKeyPrefix(desc, desc.PrimaryIndex.ID)
+	if !spec.Returning {
+		p.planToStreamColMap = nil
+		p.resultIsRowCount = true
+		return nil
+	}
+	p.AddRendering(en.rh.exprs, planCtx.evalCtx, tableColsMap, getTypesForPlanResult(n, nil))
+	p.planToStreamColMap = identityMap(nil, len(en.rh.exprs))
+	return nil
+}
+
+// partitionMutation determines where the table writers for a mutation of the
+// given table are placed. It returns the node for each of the spans of the
+// primary index of the returned router spec, which routes rows to the spans
+// based on their primary key columns (found through colIDtoRowIndex).
+//
+// No nodes are returned if the rows can't be routed; this is the case for
+// interleaved tables, whose primary keys are not a prefix of the keys of
+// their rows.
+func (dsp *DistSQLPlanner) partitionMutation(
+	planCtx *planningCtx, desc *sqlbase.TableDescriptor, colIDtoRowIndex map[sqlbase.ColumnID]int,
+) ([]roachpb.NodeID, distsqlrun.OutputRouterSpec_RangeRouterSpec, error) {
+	index := &desc.PrimaryIndex
+	if len(index.Interleave.Ancestors) > 0 {
+		return nil, distsqlrun.OutputRouterSpec_RangeRouterSpec{}, nil
+	}
+	partitions, err := dsp.partitionSpans(planCtx, roachpb.Spans{desc.PrimaryIndexSpan()})
+	if err != nil {
+		return nil, distsqlrun.OutputRouterSpec_RangeRouterSpec{}, err
+	}
+	if len(partitions) == 1 {
+		return []roachpb.NodeID{partitions[0].node}, distsqlrun.OutputRouterSpec_RangeRouterSpec{}, nil
+	}
+
+	type nodeSpan struct {
+		node roachpb.NodeID
+		span roachpb.Span
+	}
+	var nodeSpans []nodeSpan
+	for _, p := range partitions {
+		for _, sp := range p.spans {
+			nodeSpans = append(nodeSpans, nodeSpan{node: p.node, span: sp})
+		}
+	}
+	sort.Slice(nodeSpans, func(i, j int) bool {
+		return nodeSpans[i].span.Key.Compare(nodeSpans[j].span.Key) < 0
+	})
+
+	// The router encodes the primary key columns of a row the same way as
+	// they are encoded in the keys of the index, minus the index prefix.
+	var spec distsqlrun.OutputRouterSpec_RangeRouterSpec
+	spec.Encodings = make([]distsqlrun.OutputRouterSpec_RangeRouterSpec_ColumnEncoding, len(index.ColumnIDs))
+	for i, id := range index.ColumnIDs {
+		spec.Encodings[i].Column = uint32(colIDtoRowIndex[id])
+		spec.Encodings[i].Encoding = sqlbase.DatumEncoding_ASCENDING_KEY
+		if index.ColumnDirections[i] == sqlbase.IndexDescriptor_DESC {
+			spec.Encodings[i].Encoding = sqlbase.DatumEncoding_DESCENDING_KEY
+		}
+	}
+	prefix := roachpb.Key(sqlbase.MakeIndexKeyPrefix(desc, index.ID))
+	nodes := make([]roachpb.NodeID, len(nodeSpans))
+	spec.Spans = make([]distsqlrun.OutputRouterSpec_RangeRouterSpec_Span, len(nodeSpans))
+	for i, ns := range nodeSpans {
+		nodes[i] = ns.node
+		if bytes.HasPrefix(ns.span.Key, prefix) {
+			spec.Spans[i].Start = ns.span.Key[len(prefix):]
+		}
+		// The last span is left open-ended; the rows that are not in any of the
+		// spans are sent to it.
+		if i < len(nodeSpans)-1 && bytes.HasPrefix(ns.span.EndKey, prefix) {
+			spec.Spans[i].End = ns.span.EndKey[len(prefix):]
+		}
+	}
+	defaultDest := int32(len(nodeSpans) - 1)
+	spec.DefaultDest = &defaultDest
+	return nodes, spec, nil
+}
+
 func (dsp *DistSQLPlanner) createPlanForNode(
 	planCtx *planningCtx, node planNode,
+) (physicalPlan, error) {
+	plan, err := dsp.createPhysPlanForNode(planCtx, node)
+	if err != nil || planCtx.processorNodes == nil {
+		return plan, err
+	}
+	// The processors planned for the children of this node have already been
+	// claimed by the recursive calls.
+	planCtx.assignProcessorIDs(&plan, node)
+	return plan, nil
+}
+
+func (dsp *DistSQLPlanner) createPhysPlanForNode(
+	planCtx *planningCtx, node planNode,
 ) (physicalPlan, error) {
 	switch n := node.(type) {
 	case *scanNode:
@@ -2195,6 +3554,18 @@ func (dsp *DistSQLPlanner) createPlanForNode(
 
 		return plan, nil
 
+	case *windowNode:
+		plan, err := dsp.createPlanForNode(planCtx, n.plan)
+		if err != nil {
+			return physicalPlan{}, err
+		}
+
+		if err := dsp.addWindowers(planCtx, &plan, n); err != nil {
+			return physicalPlan{}, err
+		}
+
+		return plan, nil
+
 	case *filterNode:
 		plan, err := dsp.createPlanForNode(planCtx, n.source.plan)
 		if err != nil {
@@ -2224,6 +3595,15 @@ func (dsp *DistSQLPlanner) createPlanForNode(
 	case *valuesNode:
 		return dsp.createPlanForValues(planCtx, n)
 
+	case *insertNode:
+		return dsp.createPlanForInsert(planCtx, n)
+
+	case *updateNode:
+		return dsp.createPlanForUpdate(planCtx, n)
+
+	case *deleteNode:
+		return dsp.createPlanForDelete(planCtx, n)
+
 	default:
 		panic(fmt.Sprintf("unsupported node type %T", n))
 	}
@@ -2345,10 +3725,11 @@ func (dsp *DistSQLPlanner) newPlanningCtx(
 	ctx context.Context, evalCtx *tree.EvalContext, txn *client.Txn,
 ) planningCtx {
 	planCtx := planningCtx{
-		ctx:           ctx,
-		evalCtx:       evalCtx,
-		spanIter:      dsp.spanResolver.NewSpanResolverIterator(txn),
-		nodeAddresses: make(map[roachpb.NodeID]string),
+		ctx:            ctx,
+		evalCtx:        evalCtx,
+		spanIter:       dsp.spanResolver.NewSpanResolverIterator(txn),
+		nodeAddresses:  make(map[roachpb.NodeID]string),
+		nodeLocalities: make(map[roachpb.NodeID]roachpb.Locality),
 	}
 	planCtx.nodeAddresses[dsp.nodeDesc.NodeID] = dsp.nodeDesc.Address.String()
 	return planCtx
@@ -2373,6 +3754,11 @@ func (dsp *DistSQLPlanner) FinalizePlan(planCtx *planningCtx, plan *physicalPlan
 		}
 	}
 
+	if planCtx.processorNodes != nil {
+		// The final stage (if any) doesn't correspond to a planNode.
+		planCtx.assignProcessorIDs(plan, nil /* node */)
+	}
+
 	// Set up the endpoints for p.streams.
 	plan.PopulateEndpoints(planCtx.nodeAddresses)
 
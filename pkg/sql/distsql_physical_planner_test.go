@@ -1072,3 +1072,64 @@ func TestPartitionSpansSkipsNodesNotInGossip(t *testing.T) {
 		t.Errorf("expected partitions:\n  %v\ngot:\n  %v", expectedPartitions, resMap)
 	}
 }
+
+func TestLocalityConfinedNodes(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	locality := func(region, zone string) roachpb.Locality {
+		return roachpb.Locality{Tiers: []roachpb.Tier{
+			{Key: "region", Value: region}, {Key: "zone", Value: zone},
+		}}
+	}
+	localities := map[roachpb.NodeID]roachpb.Locality{
+		1: locality("us-east", "a"),
+		2: locality("us-east", "b"),
+		3: locality("eu-west", "a"),
+		4: locality("eu-west", "b"),
+		5: locality("ap-south", "a"),
+	}
+
+	testCases := []struct {
+		streamNodes []roachpb.NodeID
+		latencies   map[roachpb.NodeID]time.Duration
+		expected    []roachpb.NodeID
+	}{
+		// A single region: all the nodes are used.
+		{
+			streamNodes: []roachpb.NodeID{2, 1, 2},
+			expected:    []roachpb.NodeID{2, 1},
+		},
+		// The region with the most streams wins.
+		{
+			streamNodes: []roachpb.NodeID{1, 3, 4, 3},
+			expected:    []roachpb.NodeID{3, 4},
+		},
+		// On a tie, the region closest to the gateway wins.
+		{
+			streamNodes: []roachpb.NodeID{1, 3, 2, 4},
+			latencies: map[roachpb.NodeID]time.Duration{
+				1: 80 * time.Millisecond, 3: time.Millisecond,
+			},
+			expected: []roachpb.NodeID{3, 4},
+		},
+		// Without latencies, the region that appears first wins.
+		{
+			streamNodes: []roachpb.NodeID{5, 1, 3},
+			expected:    []roachpb.NodeID{5},
+		},
+		// Nodes without a locality form their own group.
+		{
+			streamNodes: []roachpb.NodeID{6, 7, 1},
+			expected:    []roachpb.NodeID{6, 7},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			res := localityConfinedNodes(tc.streamNodes, localities, tc.latencies)
+			if !reflect.DeepEqual(res, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, res)
+			}
+		})
+	}
+}
@@ -117,6 +117,15 @@ func (dsp *DistSQLPlanner) Run(
 		return err
 	}
 
+	if plan.writeAnchorKey != nil {
+		// The plan writes through leaf transactions, which can't start the
+		// transaction themselves. This needs to happen before the transaction
+		// proto is sent out to the flows.
+		if err := txn.AnchorForRemoteWrites(ctx, plan.writeAnchorKey); err != nil {
+			return err
+		}
+	}
+
 	flows := plan.GenerateFlowSpecs(dsp.nodeDesc.NodeID /* gateway */)
 
 	if logPlanDiagram {
@@ -137,6 +146,7 @@ func (dsp *DistSQLPlanner) Run(
 
 	recv.outputTypes = plan.ResultTypes
 	recv.resultToStreamColMap = plan.planToStreamColMap
+	recv.resultIsRowCount = plan.resultIsRowCount
 	thisNodeID := dsp.nodeDesc.NodeID
 
 	evalCtxProto := distsqlrun.MakeEvalContext(evalCtx)
@@ -220,6 +230,15 @@ func (dsp *DistSQLPlanner) Run(
 // This is where the DistSQL execution meets the SQL Session - the RowContainer
 // comes from a client Session.
 //
+// For statements run on behalf of a client, the rowResultWriter is the pgwire
+// connection: rows are streamed to the client as they are pushed, after the
+// first few are buffered (see sql.defaults.results_buffer.size in pgwire) so
+// that the statement can still be retried automatically if no results have
+// been sent. Push blocks while the connection writes the rows, which in turn
+// blocks the flow on the gateway and, through the flow control of the
+// inbound streams, the remote producers. If the rows can't be written (e.g.
+// the client went away), Push returns ConsumerClosed and the producers stop.
+//
 // distSQLReceiver also update the RangeDescriptorCache and the LeaseholderCache
 // in response to DistSQL metadata about misplanned ranges.
 type distSQLReceiver struct {
@@ -235,6 +254,10 @@ type distSQLReceiver struct {
 	// stream.
 	resultToStreamColMap []int
 
+	// resultIsRowCount is set if each row of the results stream holds a number
+	// of affected rows instead of being an affected row itself.
+	resultIsRowCount bool
+
 	// err represents the error that we received either from a producer or
 	// internally in the operation of the distSQLReceiver. If set, this will
 	// ultimately be returned as the error for the SQL query.
@@ -313,6 +336,16 @@ func (r *distSQLReceiver) Push(
 	row sqlbase.EncDatumRow, meta distsqlrun.ProducerMetadata,
 ) distsqlrun.ConsumerStatus {
 	if !meta.Empty() {
+		if meta.TxnMeta != nil && r.txn != nil {
+			// A leaf transaction performed writes; the root transaction becomes
+			// responsible for its intents. This needs to happen even if the query
+			// failed, so that the intents are cleaned up.
+			if err := r.txn.AugmentLeafState(
+				r.ctx, meta.TxnMeta.Txn, meta.TxnMeta.Intents,
+			); err != nil && r.err == nil {
+				r.err = err
+			}
+		}
 		if meta.Err != nil && r.err == nil {
 			if r.txn != nil {
 				if retryErr, ok := meta.Err.(*roachpb.UnhandledRetryableError); ok {
@@ -363,7 +396,16 @@ func (r *distSQLReceiver) Push(
 
 	if r.resultWriter.StatementType() != tree.Rows {
 		// We only need the row count.
-		r.resultWriter.IncrementRowsAffected(1)
+		n := 1
+		if r.resultIsRowCount {
+			if err := row[0].EnsureDecoded(&r.outputTypes[0], &r.alloc); err != nil {
+				r.err = err
+				r.status = distsqlrun.ConsumerClosed
+				return r.status
+			}
+			n = int(tree.MustBeDInt(row[0].Datum))
+		}
+		r.resultWriter.IncrementRowsAffected(n)
 		return r.status
 	}
 	if r.row == nil {
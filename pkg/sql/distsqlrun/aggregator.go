@@ -15,14 +15,17 @@
 package distsqlrun
 
 import (
+	"bytes"
 	"strings"
 	"sync"
 	"unsafe"
 
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/builtins"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/types"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/util/encoding"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/cockroach/pkg/util/mon"
 	"github.com/cockroachdb/cockroach/pkg/util/tracing"
@@ -89,6 +92,12 @@ func GetAggregateInfo(
 //
 // aggregator's output schema is comprised of what is specified by the
 // accompanying SELECT expressions.
+//
+// The groups are kept in memory until the memory budget of the aggregator is
+// exhausted. From then on, the input rows that belong to groups that are not
+// already in memory are stored on disk, sorted by the grouping columns. Once
+// the input is exhausted and the in-memory groups have been emitted, the
+// groups stored on disk are aggregated one at a time.
 type aggregator struct {
 	processorBase
 
@@ -99,12 +108,26 @@ type aggregator struct {
 	outputTypes []sqlbase.ColumnType
 	datumAlloc  sqlbase.DatumAlloc
 
+	// bucketsAcc accounts for the groups. Its monitor is limited when the
+	// aggregator can fall back to disk.
 	bucketsAcc mon.BoundAccount
+	// seenAcc accounts for the values seen by the DISTINCT aggregations. These
+	// can't be stored on disk, so they are not subject to the limit above.
+	seenAcc mon.BoundAccount
 
 	groupCols    columns
 	aggregations []AggregatorSpec_Aggregation
 
 	buckets map[string]struct{} // The set of bucket keys.
+
+	// useTempStorage is set if the aggregator can store the rows of the groups
+	// that don't fit in memory on disk.
+	useTempStorage bool
+	// spilledRows holds the input rows of the groups that didn't fit in memory.
+	// It is nil until the aggregator runs out of memory.
+	spilledRows *diskRowContainer
+	// outputRow is the scratch row used to render the results of a group.
+	outputRow sqlbase.EncDatumRow
 }
 
 var _ Processor = &aggregator{}
@@ -124,7 +147,7 @@ func newAggregator(
 		buckets:      make(map[string]struct{}),
 		funcs:        make([]*aggregateFuncHolder, len(spec.Aggregations)),
 		outputTypes:  make([]sqlbase.ColumnType, len(spec.Aggregations)),
-		bucketsAcc:   flowCtx.EvalCtx.Mon.MakeBoundAccount(),
+		outputRow:    make(sqlbase.EncDatumRow, len(spec.Aggregations)),
 	}
 
 	// Loop over the select expressions and extract any aggregate functions --
@@ -177,14 +200,6 @@ func (ag *aggregator) Run(ctx context.Context, wg *sync.WaitGroup) {
 	if wg != nil {
 		defer wg.Done()
 	}
-	defer ag.bucketsAcc.Close(ctx)
-	defer func() {
-		for _, f := range ag.funcs {
-			for _, aggFunc := range f.buckets {
-				aggFunc.Close(ctx)
-			}
-		}
-	}()
 
 	ctx = log.WithLogTag(ctx, "Agg", nil)
 	ctx, span := processorSpan(ctx, "aggregator")
@@ -195,6 +210,38 @@ func (ag *aggregator) Run(ctx context.Context, wg *sync.WaitGroup) {
 		defer log.Infof(ctx, "exiting aggregator")
 	}
 
+	// Enable fall back to disk if the cluster setting is set or a memory limit
+	// has been set through testing.
+	st := ag.flowCtx.Settings
+	ag.useTempStorage = settingUseTempStorageAggregations.Get(&st.SV) ||
+		ag.flowCtx.testingKnobs.MemoryLimitBytes > 0
+	bucketsMon := ag.flowCtx.EvalCtx.Mon
+	if ag.useTempStorage {
+		// Limit the memory use by creating a child monitor with a hard limit.
+		// The groups that don't fit within this limit are aggregated from disk.
+		limit := ag.flowCtx.testingKnobs.MemoryLimitBytes
+		if limit <= 0 {
+			limit = settingWorkMemBytes.Get(&st.SV)
+		}
+		limitedMon := mon.MakeMonitorInheritWithLimit(
+			"aggregator-limited", limit, ag.flowCtx.EvalCtx.Mon,
+		)
+		limitedMon.Start(ctx, ag.flowCtx.EvalCtx.Mon, mon.BoundAccount{})
+		defer limitedMon.Stop(ctx)
+
+		bucketsMon = &limitedMon
+	}
+	ag.bucketsAcc = bucketsMon.MakeBoundAccount()
+	defer ag.bucketsAcc.Close(ctx)
+	ag.seenAcc = ag.flowCtx.EvalCtx.Mon.MakeBoundAccount()
+	defer ag.seenAcc.Close(ctx)
+	defer ag.closeBuckets(ctx)
+	defer func() {
+		if ag.spilledRows != nil {
+			ag.spilledRows.Close(ctx)
+		}
+	}()
+
 	if err := ag.accumulateRows(ctx); err != nil {
 		// We swallow the error here, it has already been forwarded to the output.
 		return
@@ -204,32 +251,18 @@ func (ag *aggregator) Run(ctx context.Context, wg *sync.WaitGroup) {
 
 	// Queries like `SELECT MAX(n) FROM t` expect a row of NULLs if nothing was
 	// aggregated.
-	if len(ag.buckets) < 1 && len(ag.groupCols) == 0 {
+	if len(ag.buckets) < 1 && len(ag.groupCols) == 0 && ag.spilledRows == nil {
 		ag.buckets[""] = struct{}{}
 	}
 
 	// Render the results.
-	var consumerDone bool
-	row := make(sqlbase.EncDatumRow, len(ag.funcs))
-	for bucket := range ag.buckets {
-		for i, f := range ag.funcs {
-			result, err := f.get(bucket)
-			if err != nil {
-				DrainAndClose(ctx, ag.out.output, err, ag.input)
-				return
-			}
-			if result == nil {
-				// Special case useful when this is a local stage of a distributed
-				// aggregation.
-				result = tree.DNull
-			}
-			row[i] = sqlbase.DatumToEncDatum(ag.outputTypes[i], result)
-		}
-
-		consumerDone = !emitHelper(ctx, &ag.out, row, ProducerMetadata{})
-		if consumerDone {
-			break
-		}
+	consumerDone, err := ag.emitBuckets(ctx)
+	if err == nil && !consumerDone && ag.spilledRows != nil {
+		consumerDone, err = ag.aggregateSpilledRows(ctx)
+	}
+	if err != nil {
+		DrainAndClose(ctx, ag.out.output, err, ag.input)
+		return
 	}
 	// If the consumer has been found to be done, emitHelper() already closed the
 	// output.
@@ -284,61 +317,226 @@ func (ag *aggregator) accumulateRows(ctx context.Context) (err error) {
 		if err != nil {
 			return err
 		}
+		scratch = encoded[:0]
 
-		if err := ag.bucketsAcc.Grow(ctx, int64(len(encoded))); err != nil {
-			return err
-		}
-
-		ag.buckets[string(encoded)] = struct{}{}
-		// Feed the func holders for this bucket the non-grouping datums.
-		for i, a := range ag.aggregations {
-			if a.FilterColIdx != nil {
-				col := *a.FilterColIdx
-				if err := row[col].EnsureDecoded(&ag.inputTypes[col], &ag.datumAlloc); err != nil {
-					return err
-				}
-				if row[*a.FilterColIdx].Datum != tree.DBoolTrue {
-					// This row doesn't contribute to this aggregation.
-					continue
+		if _, ok := ag.buckets[string(encoded)]; !ok {
+			if ag.spilledRows == nil {
+				if err := ag.bucketsAcc.Grow(ctx, ag.bucketUsage(encoded)); err != nil {
+					if err := ag.spill(ctx, err); err != nil {
+						return err
+					}
+				} else {
+					ag.buckets[string(encoded)] = struct{}{}
 				}
 			}
-			// Extract the corresponding arguments from the row to feed into the
-			// aggregate function.
-			// Most functions require at most one argument thus we separate
-			// the first argument and allocation of (if applicable) a variadic
-			// collection of arguments thereafter.
-			var firstArg tree.Datum
-			var otherArgs tree.Datums
-			if len(a.ColIdx) > 1 {
-				otherArgs = make(tree.Datums, len(a.ColIdx)-1)
-			}
-			isFirstArg := true
-			for j, c := range a.ColIdx {
-				if err := row[c].EnsureDecoded(&ag.inputTypes[c], &ag.datumAlloc); err != nil {
+			if ag.spilledRows != nil {
+				// The rows of the groups that are not in memory by the time we run
+				// out of memory are aggregated once the input is exhausted.
+				if err := ag.spilledRows.AddRow(ctx, row); err != nil {
 					return err
 				}
-				if isFirstArg {
-					firstArg = row[c].Datum
-					isFirstArg = false
-					continue
-				}
-				otherArgs[j-1] = row[c].Datum
+				continue
 			}
+		}
 
-			if err := ag.funcs[i].add(ctx, encoded, firstArg, otherArgs); err != nil {
+		if err := ag.accumulateRow(ctx, encoded, row); err != nil {
+			return err
+		}
+	}
+}
+
+// spill sets up the disk container for the rows of the groups that don't fit
+// in memory, given the error returned when trying to account for a new group.
+// Only memory errors can be recovered from; other errors are returned.
+//
+// Note that only the creation of new groups falls back to disk: the groups that
+// are already in memory keep being accumulated in memory.
+func (ag *aggregator) spill(ctx context.Context, err error) error {
+	if pgErr, ok := pgerror.GetPGCause(err); !(ok && pgErr.Code == pgerror.CodeOutOfMemoryError) {
+		return err
+	}
+	if !ag.useTempStorage {
+		return errors.Wrap(err, "external storage for large queries disabled")
+	}
+	log.VEventf(ctx, 2, "falling back to disk")
+	ordering := make(sqlbase.ColumnOrdering, len(ag.groupCols))
+	for i, c := range ag.groupCols {
+		ordering[i] = sqlbase.ColumnOrderInfo{ColIdx: int(c), Direction: encoding.Ascending}
+	}
+	diskRows := makeDiskRowContainer(
+		ctx, ag.flowCtx.diskMonitor, ag.inputTypes, ordering, ag.flowCtx.TempStorage,
+	)
+	ag.spilledRows = &diskRows
+	return nil
+}
+
+// aggregateSpilledRows aggregates the groups whose rows were stored on disk and
+// emits their results. The rows are sorted by the grouping columns, so the
+// groups are aggregated one at a time. It must be called once the in-memory
+// groups have been emitted.
+//
+// It returns true if the consumer doesn't need any more rows, in which case
+// emitHelper() has already closed the output.
+func (ag *aggregator) aggregateSpilledRows(ctx context.Context) (consumerDone bool, _ error) {
+	// Release the memory used by the in-memory groups. From now on, only a single
+	// group is held in memory at a time, which is accounted for against the
+	// flow's monitor rather than the limited one that we ran out of.
+	ag.closeBuckets(ctx)
+	ag.bucketsAcc.Close(ctx)
+	ag.bucketsAcc = ag.flowCtx.EvalCtx.Mon.MakeBoundAccount()
+
+	var scratch, group []byte
+	inGroup := false
+	i := ag.spilledRows.NewIterator(ctx)
+	defer i.Close()
+	for i.Rewind(); ; i.Next() {
+		if ok, err := i.Valid(); err != nil {
+			return false, err
+		} else if !ok {
+			break
+		}
+		row, err := i.Row()
+		if err != nil {
+			return false, err
+		}
+		encoded, err := ag.encode(scratch, row)
+		if err != nil {
+			return false, err
+		}
+		scratch = encoded[:0]
+
+		if inGroup && !bytes.Equal(encoded, group) {
+			if consumerDone, err := ag.emitBucket(ctx, string(group)); consumerDone || err != nil {
+				return consumerDone, err
+			}
+			ag.closeBuckets(ctx)
+			ag.bucketsAcc.Clear(ctx)
+			ag.seenAcc.Clear(ctx)
+			inGroup = false
+		}
+		if !inGroup {
+			if err := ag.bucketsAcc.Grow(ctx, ag.bucketUsage(encoded)); err != nil {
+				return false, err
+			}
+			group = append(group[:0], encoded...)
+			inGroup = true
+		}
+		if err := ag.accumulateRow(ctx, group, row); err != nil {
+			return false, err
+		}
+	}
+	if inGroup {
+		return ag.emitBucket(ctx, string(group))
+	}
+	return false, nil
+}
+
+// accumulateRow feeds the aggregate functions of the given bucket with the
+// values of the row.
+func (ag *aggregator) accumulateRow(
+	ctx context.Context, bucket []byte, row sqlbase.EncDatumRow,
+) error {
+	for i, a := range ag.aggregations {
+		if a.FilterColIdx != nil {
+			col := *a.FilterColIdx
+			if err := row[col].EnsureDecoded(&ag.inputTypes[col], &ag.datumAlloc); err != nil {
 				return err
 			}
+			if row[*a.FilterColIdx].Datum != tree.DBoolTrue {
+				// This row doesn't contribute to this aggregation.
+				continue
+			}
+		}
+		// Extract the corresponding arguments from the row to feed into the
+		// aggregate function.
+		// Most functions require at most one argument thus we separate
+		// the first argument and allocation of (if applicable) a variadic
+		// collection of arguments thereafter.
+		var firstArg tree.Datum
+		var otherArgs tree.Datums
+		if len(a.ColIdx) > 1 {
+			otherArgs = make(tree.Datums, len(a.ColIdx)-1)
+		}
+		isFirstArg := true
+		for j, c := range a.ColIdx {
+			if err := row[c].EnsureDecoded(&ag.inputTypes[c], &ag.datumAlloc); err != nil {
+				return err
+			}
+			if isFirstArg {
+				firstArg = row[c].Datum
+				isFirstArg = false
+				continue
+			}
+			otherArgs[j-1] = row[c].Datum
+		}
+
+		if err := ag.funcs[i].add(ctx, bucket, firstArg, otherArgs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// emitBuckets renders and emits the results of the groups held in memory. It
+// returns true if the consumer doesn't need any more rows, in which case
+// emitHelper() has already closed the output.
+func (ag *aggregator) emitBuckets(ctx context.Context) (consumerDone bool, _ error) {
+	for bucket := range ag.buckets {
+		if consumerDone, err := ag.emitBucket(ctx, bucket); consumerDone || err != nil {
+			return consumerDone, err
+		}
+	}
+	return false, nil
+}
+
+// emitBucket renders and emits the results of a single group; see
+// emitBuckets.
+func (ag *aggregator) emitBucket(ctx context.Context, bucket string) (consumerDone bool, _ error) {
+	for i, f := range ag.funcs {
+		result, err := f.get(bucket)
+		if err != nil {
+			return false, err
+		}
+		if result == nil {
+			// Special case useful when this is a local stage of a distributed
+			// aggregation.
+			result = tree.DNull
+		}
+		ag.outputRow[i] = sqlbase.DatumToEncDatum(ag.outputTypes[i], result)
+	}
+	return !emitHelper(ctx, &ag.out, ag.outputRow, ProducerMetadata{}), nil
+}
+
+// bucketUsage returns the memory accounted for when a bucket with the given
+// key is created: the key is stored in the set of buckets and in the map of
+// each aggregate function holder, along with the aggregate function itself.
+func (ag *aggregator) bucketUsage(bucket []byte) int64 {
+	// TODO(radu): we should account for the size of the aggregate functions
+	// (this needs to be done in each aggregate constructor).
+	return int64(len(bucket)) + int64(len(ag.funcs))*(int64(len(bucket))+sizeOfAggregateFunc)
+}
+
+// closeBuckets closes the aggregate functions of all the buckets and forgets
+// about the buckets. The memory accounted for them is not released.
+func (ag *aggregator) closeBuckets(ctx context.Context) {
+	for _, f := range ag.funcs {
+		for _, aggFunc := range f.buckets {
+			aggFunc.Close(ctx)
+		}
+		f.buckets = make(map[string]tree.AggregateFunc)
+		if f.seen != nil {
+			f.seen = make(map[string]struct{})
 		}
-		scratch = encoded[:0]
 	}
+	ag.buckets = make(map[string]struct{})
 }
 
 type aggregateFuncHolder struct {
-	create        func(*tree.EvalContext) tree.AggregateFunc
-	group         *aggregator
-	buckets       map[string]tree.AggregateFunc
-	seen          map[string]struct{}
-	bucketsMemAcc *mon.BoundAccount
+	create     func(*tree.EvalContext) tree.AggregateFunc
+	group      *aggregator
+	buckets    map[string]tree.AggregateFunc
+	seen       map[string]struct{}
+	seenMemAcc *mon.BoundAccount
 }
 
 const sizeOfAggregateFunc = int64(unsafe.Sizeof(tree.AggregateFunc(nil)))
@@ -347,10 +545,10 @@ func (ag *aggregator) newAggregateFuncHolder(
 	create func(*tree.EvalContext) tree.AggregateFunc,
 ) *aggregateFuncHolder {
 	return &aggregateFuncHolder{
-		create:        create,
-		group:         ag,
-		buckets:       make(map[string]tree.AggregateFunc),
-		bucketsMemAcc: &ag.bucketsAcc,
+		create:     create,
+		group:      ag,
+		buckets:    make(map[string]tree.AggregateFunc),
+		seenMemAcc: &ag.seenAcc,
 	}
 }
 
@@ -373,7 +571,7 @@ func (a *aggregateFuncHolder) add(
 			// skip
 			return nil
 		}
-		if err := a.bucketsMemAcc.Grow(ctx, int64(len(encoded))); err != nil {
+		if err := a.seenMemAcc.Grow(ctx, int64(len(encoded))); err != nil {
 			return err
 		}
 		a.seen[string(encoded)] = struct{}{}
@@ -381,17 +579,12 @@ func (a *aggregateFuncHolder) add(
 
 	impl, ok := a.buckets[string(bucket)]
 	if !ok {
-		// TODO(radu): we should account for the size of impl (this needs to be done
-		// in each aggregate constructor).
-		impl = a.create(&a.group.flowCtx.EvalCtx)
-		usage := int64(len(bucket))
-		usage += sizeOfAggregateFunc
+		// The memory used by impl has been accounted for when the bucket was
+		// created (see aggregator.bucketUsage).
 		// TODO(radu): this model of each func having a map of buckets (one per
 		// group) for each func plus a global map is very wasteful. We should have a
 		// single map that stores all the AggregateFuncs.
-		if err := a.bucketsMemAcc.Grow(ctx, usage); err != nil {
-			return err
-		}
+		impl = a.create(&a.group.flowCtx.EvalCtx)
 		a.buckets[string(bucket)] = impl
 	}
 
@@ -15,16 +15,21 @@
 package distsqlrun
 
 import (
+	"fmt"
+	"math"
 	"sort"
 	"strings"
 	"testing"
 
 	"golang.org/x/net/context"
 
+	"github.com/cockroachdb/cockroach/pkg/base"
 	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine"
 	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/mon"
 )
 
 // TODO(irfansharif): Add tests to verify the following aggregation functions:
@@ -343,48 +348,75 @@ func TestAggregator(t *testing.T) {
 		},
 	}
 
-	for _, c := range testCases {
-		t.Run("", func(t *testing.T) {
-			ags := c.spec
+	ctx := context.Background()
+	tempEngine, err := engine.NewTempEngine(base.DefaultTestTempStorageConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tempEngine.Close()
+
+	diskMonitor := mon.MakeMonitor(
+		"test-disk",
+		mon.DiskResource,
+		nil, /* curCount */
+		nil, /* maxHist */
+		-1,  /* increment: use default block size */
+		math.MaxInt64,
+	)
+	diskMonitor.Start(ctx, nil /* pool */, mon.MakeStandaloneBudget(math.MaxInt64))
+	defer diskMonitor.Stop(ctx)
 
-			in := NewRowBuffer(c.inputTypes, c.input, RowBufferArgs{})
-			out := NewRowBuffer(c.outputTypes, nil /* rows */, RowBufferArgs{})
-			evalCtx := tree.MakeTestingEvalContext()
-			defer evalCtx.Stop(context.Background())
-			flowCtx := FlowCtx{
-				Settings: cluster.MakeTestingClusterSettings(),
-				EvalCtx:  evalCtx,
-			}
+	for i, c := range testCases {
+		// Test with several memory limits:
+		// 0: Use the default limit.
+		// 1: Immediately store all the groups on disk.
+		// 200: Keep the first few groups in memory and store the others on disk.
+		for _, memLimit := range []int64{0, 1, 200} {
+			t.Run(fmt.Sprintf("%d/MemLimit=%d", i, memLimit), func(t *testing.T) {
+				ags := c.spec
 
-			ag, err := newAggregator(&flowCtx, &ags, in, &PostProcessSpec{}, out)
-			if err != nil {
-				t.Fatal(err)
-			}
+				in := NewRowBuffer(c.inputTypes, c.input, RowBufferArgs{})
+				out := NewRowBuffer(c.outputTypes, nil /* rows */, RowBufferArgs{})
+				evalCtx := tree.MakeTestingEvalContext()
+				defer evalCtx.Stop(ctx)
+				flowCtx := FlowCtx{
+					Settings:    cluster.MakeTestingClusterSettings(),
+					EvalCtx:     evalCtx,
+					TempStorage: tempEngine,
+					diskMonitor: &diskMonitor,
+				}
+				flowCtx.testingKnobs.MemoryLimitBytes = memLimit
 
-			ag.Run(context.Background(), nil)
+				ag, err := newAggregator(&flowCtx, &ags, in, &PostProcessSpec{}, out)
+				if err != nil {
+					t.Fatal(err)
+				}
 
-			var expected []string
-			for _, row := range c.expected {
-				expected = append(expected, row.String(c.outputTypes))
-			}
-			sort.Strings(expected)
-			expStr := strings.Join(expected, "")
+				ag.Run(ctx, nil)
 
-			var rets []string
-			for {
-				row := out.NextNoMeta(t)
-				if row == nil {
-					break
+				var expected []string
+				for _, row := range c.expected {
+					expected = append(expected, row.String(c.outputTypes))
 				}
-				rets = append(rets, row.String(c.outputTypes))
-			}
-			sort.Strings(rets)
-			retStr := strings.Join(rets, "")
+				sort.Strings(expected)
+				expStr := strings.Join(expected, "")
 
-			if expStr != retStr {
-				t.Errorf("invalid results; expected:\n   %s\ngot:\n   %s",
-					expStr, retStr)
-			}
-		})
+				var rets []string
+				for {
+					row := out.NextNoMeta(t)
+					if row == nil {
+						break
+					}
+					rets = append(rets, row.String(c.outputTypes))
+				}
+				sort.Strings(rets)
+				retStr := strings.Join(rets, "")
+
+				if expStr != retStr {
+					t.Errorf("invalid results; expected:\n   %s\ngot:\n   %s",
+						expStr, retStr)
+				}
+			})
+		}
 	}
 }
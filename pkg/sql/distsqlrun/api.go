@@ -19,7 +19,7 @@ import "github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 // MakeEvalContext serializes some of the fields of a tree.EvalContext into a
 // distsqlrun.EvalContext proto.
 func MakeEvalContext(evalCtx tree.EvalContext) EvalContext {
-	return EvalContext{
+	ec := EvalContext{
 		StmtTimestampNanos: evalCtx.GetStmtTimestamp().UnixNano(),
 		TxnTimestampNanos:  evalCtx.GetTxnTimestampRaw().UnixNano(),
 		ClusterTimestamp:   evalCtx.GetClusterTimestampRaw(),
@@ -27,4 +27,8 @@ func MakeEvalContext(evalCtx tree.EvalContext) EvalContext {
 		Database:           evalCtx.Database,
 		User:               evalCtx.User,
 	}
+	if evalCtx.Txn != nil {
+		ec.LockTimeoutNanos = evalCtx.Txn.LockTimeout().Nanoseconds()
+	}
+	return ec
 }
@@ -37,6 +37,9 @@ const (
 	leftOuter
 	rightOuter
 	fullOuter
+	leftSemi
+	leftAnti
+	leftAntiNullAware
 )
 
 const rowChannelBufSize = 16
@@ -290,11 +293,15 @@ type ProducerMetadata struct {
 	Err error
 	// TraceData is sent if snowball tracing is enabled.
 	TraceData []tracing.RecordedSpan
+	// TxnMeta is sent by processors that wrote through a leaf transaction. It
+	// needs to make its way to the gateway, where it's merged into the root
+	// transaction.
+	TxnMeta *RemoteProducerMetadata_TxnMeta
 }
 
 // Empty returns true if none of the fields in metadata are populated.
 func (meta ProducerMetadata) Empty() bool {
-	return meta.Ranges == nil && meta.Err == nil && meta.TraceData == nil
+	return meta.Ranges == nil && meta.Err == nil && meta.TraceData == nil && meta.TxnMeta == nil
 }
 
 // RowChannel is a thin layer over a RowChannelMsg channel, which can be used to
@@ -639,6 +646,8 @@ func NewError(err error) *Error {
 			Detail: &Error_RetryableTxnError{
 				RetryableTxnError: retryErr,
 			}}
+	} else if wiErr, ok := err.(*roachpb.WriteIntentError); ok && wiErr.LockTimeout {
+		return &Error{Detail: &Error_PGError{PGError: sqlbase.NewLockNotAvailableError(wiErr)}}
 	} else {
 		// Anything unrecognized is an "internal error".
 		return &Error{
@@ -15,15 +15,28 @@
 package distsqlrun
 
 import (
+	"bytes"
+	"sort"
 	"sync"
 
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/util/encoding"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/cockroach/pkg/util/mon"
 	"github.com/cockroachdb/cockroach/pkg/util/tracing"
+	"github.com/pkg/errors"
 	"golang.org/x/net/context"
 )
 
+// distinct is the processor core type that removes duplicate rows, comparing
+// the distinct columns.
+//
+// The encodings of the rows seen so far are kept in memory until the memory
+// budget of the processor is exhausted. From then on, the rows that haven't
+// been seen are stored on disk, sorted by the distinct columns, and the
+// distinct ones among them are emitted at the end of the current group (i.e.
+// when the ordered columns change, or when the input is exhausted).
 type distinct struct {
 	processorBase
 
@@ -36,6 +49,14 @@ type distinct struct {
 	distinctCols map[uint32]struct{}
 	memAcc       mon.BoundAccount
 	datumAlloc   sqlbase.DatumAlloc
+
+	// useTempStorage is set if the distinct can store rows on disk when it runs
+	// out of memory.
+	useTempStorage bool
+	// spilledRows holds the rows of the current group that haven't been seen
+	// before and didn't fit in memory. It is nil until the distinct runs out of
+	// memory.
+	spilledRows *diskRowContainer
 }
 
 var _ Processor = &distinct{}
@@ -48,7 +69,6 @@ func newDistinct(
 		input:        input,
 		orderedCols:  make(map[uint32]struct{}),
 		distinctCols: make(map[uint32]struct{}),
-		memAcc:       flowCtx.EvalCtx.Mon.MakeBoundAccount(),
 	}
 	for _, col := range spec.OrderedColumns {
 		d.orderedCols[col] = struct{}{}
@@ -70,7 +90,6 @@ func (d *distinct) Run(ctx context.Context, wg *sync.WaitGroup) {
 	if wg != nil {
 		defer wg.Done()
 	}
-	defer d.memAcc.Close(ctx)
 
 	ctx = log.WithLogTag(ctx, "Evaluator", nil)
 	ctx, span := processorSpan(ctx, "distinct")
@@ -81,6 +100,35 @@ func (d *distinct) Run(ctx context.Context, wg *sync.WaitGroup) {
 		defer log.Infof(ctx, "exiting distinct")
 	}
 
+	// Enable fall back to disk if the cluster setting is set or a memory limit
+	// has been set through testing.
+	st := d.flowCtx.Settings
+	d.useTempStorage = settingUseTempStorageDistincts.Get(&st.SV) ||
+		d.flowCtx.testingKnobs.MemoryLimitBytes > 0
+	seenMon := d.flowCtx.EvalCtx.Mon
+	if d.useTempStorage {
+		// Limit the memory use by creating a child monitor with a hard limit.
+		// The rows that don't fit within this limit are deduplicated on disk.
+		limit := d.flowCtx.testingKnobs.MemoryLimitBytes
+		if limit <= 0 {
+			limit = settingWorkMemBytes.Get(&st.SV)
+		}
+		limitedMon := mon.MakeMonitorInheritWithLimit(
+			"distinct-limited", limit, d.flowCtx.EvalCtx.Mon,
+		)
+		limitedMon.Start(ctx, d.flowCtx.EvalCtx.Mon, mon.BoundAccount{})
+		defer limitedMon.Stop(ctx)
+
+		seenMon = &limitedMon
+	}
+	d.memAcc = seenMon.MakeBoundAccount()
+	defer d.memAcc.Close(ctx)
+	defer func() {
+		if d.spilledRows != nil {
+			d.spilledRows.Close(ctx)
+		}
+	}()
+
 	earlyExit, err := d.mainLoop(ctx)
 	if err != nil {
 		DrainAndClose(ctx, d.out.output, err, d.input)
@@ -106,7 +154,8 @@ func (d *distinct) mainLoop(ctx context.Context) (earlyExit bool, _ error) {
 			continue
 		}
 		if row == nil {
-			return false, nil
+			// Emit the rows of the last group that were stored on disk, if any.
+			return d.emitSpilledRows(ctx)
 		}
 
 		encoding := scratch
@@ -127,6 +176,11 @@ func (d *distinct) mainLoop(ctx context.Context) (earlyExit bool, _ error) {
 		}
 
 		if !matched {
+			// The rows of the previous group that were stored on disk need to be
+			// emitted before any row of the new group.
+			if earlyExit, err := d.emitSpilledRows(ctx); earlyExit || err != nil {
+				return earlyExit, err
+			}
 			d.lastGroupKey = row
 			d.seen = make(map[string]struct{})
 			d.memAcc.Clear(ctx)
@@ -134,10 +188,24 @@ func (d *distinct) mainLoop(ctx context.Context) (earlyExit bool, _ error) {
 
 		if _, ok := d.seen[string(encoding)]; !ok {
 			if len(encoding) > 0 {
-				if err := d.memAcc.Grow(ctx, int64(len(encoding))); err != nil {
-					return false, err
+				if d.spilledRows == nil {
+					if err := d.memAcc.Grow(ctx, int64(len(encoding))); err != nil {
+						if err := d.spill(ctx, err); err != nil {
+							return false, err
+						}
+					} else {
+						d.seen[string(encoding)] = struct{}{}
+					}
+				}
+				if d.spilledRows != nil {
+					// The rows that haven't been seen by the time we run out of memory
+					// are deduplicated on disk.
+					if err := d.spilledRows.AddRow(ctx, row); err != nil {
+						return false, err
+					}
+					scratch = encoding[:0]
+					continue
 				}
-				d.seen[string(encoding)] = struct{}{}
 			}
 			if !emitHelper(ctx, &d.out, row, ProducerMetadata{}, d.input) {
 				// No cleanup required; emitHelper() took care of it.
@@ -148,6 +216,74 @@ func (d *distinct) mainLoop(ctx context.Context) (earlyExit bool, _ error) {
 	}
 }
 
+// spill sets up the disk container for the rows that don't fit in memory, given
+// the error returned when trying to account for the encoding of a new row. Only
+// memory errors can be recovered from; other errors are returned.
+func (d *distinct) spill(ctx context.Context, err error) error {
+	if pgErr, ok := pgerror.GetPGCause(err); !(ok && pgErr.Code == pgerror.CodeOutOfMemoryError) {
+		return err
+	}
+	if !d.useTempStorage {
+		return errors.Wrap(err, "external storage for large queries disabled")
+	}
+	log.VEventf(ctx, 2, "falling back to disk")
+	ordering := make(sqlbase.ColumnOrdering, 0, len(d.distinctCols))
+	for col := range d.distinctCols {
+		ordering = append(ordering, sqlbase.ColumnOrderInfo{ColIdx: int(col), Direction: encoding.Ascending})
+	}
+	sort.Slice(ordering, func(i, j int) bool { return ordering[i].ColIdx < ordering[j].ColIdx })
+	diskRows := makeDiskRowContainer(
+		ctx, d.flowCtx.diskMonitor, d.types, ordering, d.flowCtx.TempStorage,
+	)
+	d.spilledRows = &diskRows
+	return nil
+}
+
+// emitSpilledRows emits the distinct rows among those stored on disk and
+// discards the disk container. The rows are sorted by the distinct columns, so
+// duplicates are adjacent. The rows on disk are all distinct from the ones
+// that were seen in memory.
+func (d *distinct) emitSpilledRows(ctx context.Context) (earlyExit bool, _ error) {
+	if d.spilledRows == nil {
+		return false, nil
+	}
+	defer func() {
+		d.spilledRows.Close(ctx)
+		d.spilledRows = nil
+	}()
+
+	var scratch, last []byte
+	first := true
+	i := d.spilledRows.NewIterator(ctx)
+	defer i.Close()
+	for i.Rewind(); ; i.Next() {
+		if ok, err := i.Valid(); err != nil {
+			return false, err
+		} else if !ok {
+			break
+		}
+		row, err := i.Row()
+		if err != nil {
+			return false, err
+		}
+		encoded, err := d.encode(scratch, row)
+		if err != nil {
+			return false, err
+		}
+		scratch = encoded[:0]
+		if !first && bytes.Equal(encoded, last) {
+			continue
+		}
+		first = false
+		last = append(last[:0], encoded...)
+		if !emitHelper(ctx, &d.out, row, ProducerMetadata{}, d.input) {
+			// No cleanup required; emitHelper() took care of it.
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 func (d *distinct) matchLastGroupKey(row sqlbase.EncDatumRow) (bool, error) {
 	if d.lastGroupKey == nil {
 		return false, nil
@@ -15,12 +15,18 @@
 package distsqlrun
 
 import (
+	"fmt"
+	"math"
+	"sort"
 	"testing"
 
+	"github.com/cockroachdb/cockroach/pkg/base"
 	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine"
 	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/mon"
 
 	"golang.org/x/net/context"
 )
@@ -131,41 +137,86 @@ func TestDistinct(t *testing.T) {
 		},
 	}
 
-	for _, c := range testCases {
-		t.Run("", func(t *testing.T) {
-			ds := c.spec
-
-			in := NewRowBuffer(twoIntCols, c.input, RowBufferArgs{})
-			out := &RowBuffer{}
-
-			evalCtx := tree.MakeTestingEvalContext()
-			defer evalCtx.Stop(context.Background())
-			flowCtx := FlowCtx{
-				Settings: cluster.MakeTestingClusterSettings(),
-				EvalCtx:  evalCtx,
-			}
-
-			d, err := newDistinct(&flowCtx, &ds, in, &PostProcessSpec{}, out)
-			if err != nil {
-				t.Fatal(err)
-			}
-
-			d.Run(context.Background(), nil)
-			if !out.ProducerClosed {
-				t.Fatalf("output RowReceiver not closed")
-			}
-			var res sqlbase.EncDatumRows
-			for {
-				row := out.NextNoMeta(t)
-				if row == nil {
-					break
+	ctx := context.Background()
+	tempEngine, err := engine.NewTempEngine(base.DefaultTestTempStorageConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tempEngine.Close()
+
+	diskMonitor := mon.MakeMonitor(
+		"test-disk",
+		mon.DiskResource,
+		nil, /* curCount */
+		nil, /* maxHist */
+		-1,  /* increment: use default block size */
+		math.MaxInt64,
+	)
+	diskMonitor.Start(ctx, nil /* pool */, mon.MakeStandaloneBudget(math.MaxInt64))
+	defer diskMonitor.Stop(ctx)
+
+	for i, c := range testCases {
+		// Test with several memory limits:
+		// 0: Use the default limit.
+		// 1: Immediately store all the rows that aren't duplicates on disk.
+		// 32: Keep the encodings of the first few rows in memory and store the
+		// others on disk.
+		for _, memLimit := range []int64{0, 1, 32} {
+			t.Run(fmt.Sprintf("%d/MemLimit=%d", i, memLimit), func(t *testing.T) {
+				ds := c.spec
+
+				in := NewRowBuffer(twoIntCols, c.input, RowBufferArgs{})
+				out := &RowBuffer{}
+
+				evalCtx := tree.MakeTestingEvalContext()
+				defer evalCtx.Stop(ctx)
+				flowCtx := FlowCtx{
+					Settings:    cluster.MakeTestingClusterSettings(),
+					EvalCtx:     evalCtx,
+					TempStorage: tempEngine,
+					diskMonitor: &diskMonitor,
+				}
+				flowCtx.testingKnobs.MemoryLimitBytes = memLimit
+
+				d, err := newDistinct(&flowCtx, &ds, in, &PostProcessSpec{}, out)
+				if err != nil {
+					t.Fatal(err)
 				}
-				res = append(res, row)
-			}
 
-			if result := res.String(twoIntCols); result != c.expected.String(twoIntCols) {
-				t.Errorf("invalid results: %s, expected %s'", result, c.expected.String(twoIntCols))
-			}
-		})
+				d.Run(ctx, nil)
+				if !out.ProducerClosed {
+					t.Fatalf("output RowReceiver not closed")
+				}
+				var res sqlbase.EncDatumRows
+				for {
+					row := out.NextNoMeta(t)
+					if row == nil {
+						break
+					}
+					res = append(res, row)
+				}
+
+				expected := c.expected
+				if memLimit != 0 {
+					// The rows stored on disk are emitted sorted by the distinct
+					// columns, so the order of the results can differ from the order
+					// of the input.
+					res, expected = sortedRows(res), sortedRows(expected)
+				}
+				if result := res.String(twoIntCols); result != expected.String(twoIntCols) {
+					t.Errorf("invalid results: %s, expected %s'", result, expected.String(twoIntCols))
+				}
+			})
+		}
 	}
 }
+
+// sortedRows returns a copy of the given rows of two integers, sorted by their
+// string representation.
+func sortedRows(rows sqlbase.EncDatumRows) sqlbase.EncDatumRows {
+	res := append(sqlbase.EncDatumRows(nil), rows...)
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].String(twoIntCols) < res[j].String(twoIntCols)
+	})
+	return res
+}
@@ -33,6 +33,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/cockroach/pkg/util/mon"
 	"github.com/cockroachdb/cockroach/pkg/util/stop"
+	"github.com/cockroachdb/cockroach/pkg/util/tracing"
 	"github.com/cockroachdb/cockroach/pkg/util/uuid"
 )
 
@@ -133,6 +134,10 @@ type Flow struct {
 	// is to be passed to flowRegistry.RegisterFlow.
 	inboundStreams map[StreamID]*inboundStreamInfo
 
+	// collectStats is set if the flow is being traced, in which case
+	// ProcessorStats are collected for all processors (see statsProcessor).
+	collectStats bool
+
 	// waitGroup is used to wait for async components of the flow:
 	//  - processors
 	//  - inbound streams
@@ -261,7 +266,10 @@ func checkNumInOut(inputs []RowSource, outputs []RowReceiver, numIn, numOut int)
 	return nil
 }
 
-func (f *Flow) makeProcessor(ps *ProcessorSpec, inputs []RowSource) (Processor, error) {
+// setupOutputs sets up the outputs of a processor. The routers and outboxes
+// among them must be initialized with initOutputs once the output types of the
+// processor are known.
+func (f *Flow) setupOutputs(ps *ProcessorSpec) ([]RowReceiver, error) {
 	if len(ps.Output) != 1 {
 		return nil, errors.Errorf("only single-output processors supported")
 	}
@@ -289,12 +297,12 @@ func (f *Flow) makeProcessor(ps *ProcessorSpec, inputs []RowSource) (Processor,
 		outputs[i] = r
 		f.startables = append(f.startables, r)
 	}
-	proc, err := newProcessor(&f.FlowCtx, &ps.Core, &ps.Post, inputs, outputs)
-	if err != nil {
-		return nil, err
-	}
-	// Initialize any routers (the setupRouter case above) and outboxes.
-	types := proc.OutputTypes()
+	return outputs, nil
+}
+
+// initOutputs initializes any routers (the setupRouter case in setupOutputs)
+// and outboxes among the outputs of a processor.
+func (f *Flow) initOutputs(outputs []RowReceiver, types []sqlbase.ColumnType) {
 	for _, o := range outputs {
 		switch o := o.(type) {
 		case router:
@@ -303,58 +311,100 @@ func (f *Flow) makeProcessor(ps *ProcessorSpec, inputs []RowSource) (Processor,
 			o.init(types)
 		}
 	}
+}
+
+func (f *Flow) makeProcessor(ps *ProcessorSpec, inputs []RowSource) (Processor, error) {
+	outputs, err := f.setupOutputs(ps)
+	if err != nil {
+		return nil, err
+	}
+	flowCtx := &f.FlowCtx
+	procOutputs := outputs
+	var sp *statsProcessor
+	if f.collectStats {
+		sp = newStatsProcessor(&f.FlowCtx, ps.ProcessorID)
+		flowCtx = &sp.flowCtx
+		inputs = sp.wrapInputs(inputs)
+		procOutputs = sp.wrapOutputs(outputs)
+	}
+	proc, err := newProcessor(flowCtx, &ps.Core, &ps.Post, inputs, procOutputs)
+	if err != nil {
+		return nil, err
+	}
+	if sp != nil {
+		sp.Processor = proc
+		proc = sp
+	}
+	f.initOutputs(outputs, proc.OutputTypes())
 	return proc, nil
 }
 
+// setupInputSync sets up the synchronizer of an input of a processor, and the
+// inbound streams that feed it.
+func (f *Flow) setupInputSync(ctx context.Context, is *InputSyncSpec) (RowSource, error) {
+	if len(is.Streams) == 0 {
+		return nil, errors.Errorf("input sync with no streams")
+	}
+	switch is.Type {
+	case InputSyncSpec_UNORDERED:
+		if len(is.Streams) == 1 {
+			rowChan := &RowChannel{}
+			rowChan.Init(is.ColumnTypes)
+			if err := f.setupInboundStream(ctx, is.Streams[0], rowChan); err != nil {
+				return nil, err
+			}
+			return rowChan, nil
+		}
+		mrc := &MultiplexedRowChannel{}
+		mrc.Init(len(is.Streams), is.ColumnTypes)
+		for _, s := range is.Streams {
+			if err := f.setupInboundStream(ctx, s, mrc); err != nil {
+				return nil, err
+			}
+		}
+		return mrc, nil
+
+	case InputSyncSpec_ORDERED:
+		// Ordered synchronizer: create a RowChannel for each input.
+		streams := make([]RowSource, len(is.Streams))
+		for i, s := range is.Streams {
+			rowChan := &RowChannel{}
+			rowChan.Init(is.ColumnTypes)
+			if err := f.setupInboundStream(ctx, s, rowChan); err != nil {
+				return nil, err
+			}
+			streams[i] = rowChan
+		}
+		return makeOrderedSync(convertToColumnOrdering(is.Ordering), &f.EvalCtx, streams)
+
+	default:
+		return nil, errors.Errorf("unsupported input sync type %s", is.Type)
+	}
+}
+
 func (f *Flow) setup(ctx context.Context, spec *FlowSpec) error {
 	f.spec = spec
+	if sp := opentracing.SpanFromContext(ctx); sp != nil && tracing.IsRecordable(sp) {
+		f.collectStats = tracing.IsRecording(sp)
+	}
+
+	if !f.collectStats && settingVectorize.Get(&f.Settings.SV) {
+		roots, err := planVectorizedFlow(&f.FlowCtx, spec)
+		if err == nil {
+			log.VEventf(ctx, 1, "running flow with the vectorized engine")
+			return f.setupVectorizedFlow(ctx, roots)
+		}
+		log.VEventf(ctx, 1, "cannot vectorize flow, falling back to row execution: %s", err)
+	}
 
 	// First step: setup the input synchronizers for all processors.
 	inputSyncs := make([][]RowSource, len(spec.Processors))
-	for pIdx, ps := range spec.Processors {
-		for _, is := range ps.Input {
-			if len(is.Streams) == 0 {
-				return errors.Errorf("input sync with no streams")
-			}
-			var sync RowSource
-			switch is.Type {
-			case InputSyncSpec_UNORDERED:
-				if len(is.Streams) == 1 {
-					rowChan := &RowChannel{}
-					rowChan.Init(is.ColumnTypes)
-					if err := f.setupInboundStream(ctx, is.Streams[0], rowChan); err != nil {
-						return err
-					}
-					sync = rowChan
-				} else {
-					mrc := &MultiplexedRowChannel{}
-					mrc.Init(len(is.Streams), is.ColumnTypes)
-					for _, s := range is.Streams {
-						if err := f.setupInboundStream(ctx, s, mrc); err != nil {
-							return err
-						}
-					}
-					sync = mrc
-				}
-			case InputSyncSpec_ORDERED:
-				// Ordered synchronizer: create a RowChannel for each input.
-				streams := make([]RowSource, len(is.Streams))
-				for i, s := range is.Streams {
-					rowChan := &RowChannel{}
-					rowChan.Init(is.ColumnTypes)
-					if err := f.setupInboundStream(ctx, s, rowChan); err != nil {
-						return err
-					}
-					streams[i] = rowChan
-				}
-				var err error
-				sync, err = makeOrderedSync(convertToColumnOrdering(is.Ordering), &f.EvalCtx, streams)
-				if err != nil {
-					return err
-				}
-
-			default:
-				return errors.Errorf("unsupported input sync type %s", is.Type)
+	for pIdx := range spec.Processors {
+		ps := &spec.Processors[pIdx]
+		for i := range ps.Input {
+			sync, err := f.setupInputSync(ctx, &ps.Input[i])
+			if err != nil {
+				return err
 			}
 			inputSyncs[pIdx] = append(inputSyncs[pIdx], sync)
 		}
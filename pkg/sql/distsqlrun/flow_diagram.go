@@ -101,6 +101,28 @@ func (a *AggregatorSpec) summary() (string, []string) {
 	return "Aggregator", details
 }
 
+func (w *WindowerSpec) summary() (string, []string) {
+	details := make([]string, 0, len(w.WindowFns)+2)
+	if len(w.PartitionBy) > 0 {
+		details = append(details, fmt.Sprintf("PARTITION BY %s", colListStr(w.PartitionBy)))
+	}
+	if len(w.Ordering.Columns) > 0 {
+		details = append(details, fmt.Sprintf("ORDER BY %s", w.Ordering.diagramString()))
+	}
+	for _, fn := range w.WindowFns {
+		details = append(details, fmt.Sprintf("%s(%s)", strings.ToUpper(fn.Func), colListStr(fn.ArgIdxs)))
+	}
+	return "Windower", details
+}
+
+func (tw *TableWriterSpec) summary() (string, []string) {
+	details := []string{fmt.Sprintf("%s %s", tw.Type, tw.Table.Name)}
+	if tw.Returning {
+		details = append(details, "RETURNING")
+	}
+	return "TableWriter", details
+}
+
 func (tr *TableReaderSpec) summary() (string, []string) {
 	index := "primary"
 	if tr.IndexIdx > 0 {
@@ -121,6 +143,15 @@ func (jr *JoinReaderSpec) summary() (string, []string) {
 	details := []string{
 		fmt.Sprintf("%s@%s", index, jr.Table.Name),
 	}
+	if len(jr.LookupColumns) > 0 {
+		details = append(details, fmt.Sprintf("Lookup join on: %s", colListStr(jr.LookupColumns)))
+		if jr.Type != JoinType_INNER {
+			details = append(details, jr.Type.String())
+		}
+	}
+	if jr.OnExpr.Expr != "" {
+		details = append(details, fmt.Sprintf("ON %s", jr.OnExpr.Expr))
+	}
 	return "JoinReader", details
 }
 
@@ -278,7 +309,11 @@ type diagramData struct {
 	Edges      []diagramEdge      `json:"edges"`
 }
 
-func generateDiagramData(flows []FlowSpec, nodeNames []string) (diagramData, error) {
+// generateDiagramData generates the diagram data for the given flows. If stats
+// is set, the processors are annotated with their runtime statistics.
+func generateDiagramData(
+	flows []FlowSpec, nodeNames []string, stats map[int32]ProcessorStats,
+) (diagramData, error) {
 	d := diagramData{NodeNames: nodeNames}
 
 	// inPorts maps streams to their "destination" attachment point. Only DestProc
@@ -292,6 +327,9 @@ func generateDiagramData(flows []FlowSpec, nodeNames []string) (diagramData, err
 			proc := diagramProcessor{NodeIdx: n}
 			proc.Core.Title, proc.Core.Details = p.Core.GetValue().(diagramCellType).summary()
 			proc.Core.Details = append(proc.Core.Details, p.Post.summary()...)
+			if s, ok := stats[p.ProcessorID]; ok && p.ProcessorID != 0 {
+				proc.Core.Details = append(proc.Core.Details, s.Details()...)
+			}
 
 			// We need explicit synchronizers if we have multiple inputs, or if the
 			// one input has multiple input streams.
@@ -390,6 +428,15 @@ func generateDiagramData(flows []FlowSpec, nodeNames []string) (diagramData, err
 // be one FlowSpec per node. The function assumes that StreamIDs are unique
 // across all flows.
 func GeneratePlanDiagram(flows map[roachpb.NodeID]FlowSpec, w io.Writer) error {
+	return generatePlanDiagram(flows, nil /* stats */, nil /* localities */, w)
+}
+
+func generatePlanDiagram(
+	flows map[roachpb.NodeID]FlowSpec,
+	stats map[int32]ProcessorStats,
+	localities map[roachpb.NodeID]roachpb.Locality,
+	w io.Writer,
+) error {
 	// We sort the flows by node because we want the diagram data to be
 	// deterministic.
 	nodeIDs := make([]int, 0, len(flows))
@@ -405,9 +452,12 @@ func GeneratePlanDiagram(flows map[roachpb.NodeID]FlowSpec, w io.Writer) error {
 
 		flowSlice[i] = flows[n]
 		nodeNames[i] = n.String()
+		if l := localities[n]; len(l.Tiers) > 0 {
+			nodeNames[i] = fmt.Sprintf("%s (%s)", n, l)
+		}
 	}
 
-	d, err := generateDiagramData(flowSlice, nodeNames)
+	d, err := generateDiagramData(flowSlice, nodeNames, stats)
 	if err != nil {
 		return err
 	}
@@ -418,8 +468,28 @@ func GeneratePlanDiagram(flows map[roachpb.NodeID]FlowSpec, w io.Writer) error {
 // URL which encodes the diagram. There should be one FlowSpec per node. The
 // function assumes that StreamIDs are unique across all flows.
 func GeneratePlanDiagramWithURL(flows map[roachpb.NodeID]FlowSpec) (string, url.URL, error) {
+	return GeneratePlanDiagramWithStatsURL(flows, nil /* stats */)
+}
+
+// GeneratePlanDiagramWithStatsURL is like GeneratePlanDiagramWithURL, but it
+// also annotates the processors with the given runtime statistics, indexed by
+// ProcessorSpec.ProcessorID (see ExtractProcessorStats).
+func GeneratePlanDiagramWithStatsURL(
+	flows map[roachpb.NodeID]FlowSpec, stats map[int32]ProcessorStats,
+) (string, url.URL, error) {
+	return GeneratePlanDiagramWithPlacementURL(flows, stats, nil /* localities */)
+}
+
+// GeneratePlanDiagramWithPlacementURL is like GeneratePlanDiagramWithStatsURL,
+// but it also labels the nodes with their localities (if they have any), which
+// shows where the stages of the plan were placed.
+func GeneratePlanDiagramWithPlacementURL(
+	flows map[roachpb.NodeID]FlowSpec,
+	stats map[int32]ProcessorStats,
+	localities map[roachpb.NodeID]roachpb.Locality,
+) (string, url.URL, error) {
 	var json, compressed bytes.Buffer
-	if err := GeneratePlanDiagram(flows, &json); err != nil {
+	if err := generatePlanDiagram(flows, stats, localities, &json); err != nil {
 		return "", url.URL{}, err
 	}
 	jsonStr := json.String()
@@ -21,6 +21,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
@@ -387,3 +388,64 @@ func TestPlanDiagramJoin(t *testing.T) {
 
 	compareDiagrams(t, buf.String(), expected)
 }
+
+func TestPlanDiagramWithStats(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	flows := make(map[roachpb.NodeID]FlowSpec)
+
+	tr := TableReaderSpec{Table: sqlbase.TableDescriptor{Name: "Table"}}
+
+	flows[1] = FlowSpec{
+		Processors: []ProcessorSpec{{
+			Core: ProcessorCoreUnion{TableReader: &tr},
+			Output: []OutputRouterSpec{{
+				Type:    OutputRouterSpec_PASS_THROUGH,
+				Streams: []StreamEndpointSpec{{StreamID: 0}},
+			}},
+			ProcessorID: 1,
+		}},
+	}
+
+	flows[2] = FlowSpec{
+		Processors: []ProcessorSpec{{
+			Input: []InputSyncSpec{{
+				Type:    InputSyncSpec_UNORDERED,
+				Streams: []StreamEndpointSpec{{StreamID: 0}},
+			}},
+			Core: ProcessorCoreUnion{Noop: &NoopCoreSpec{}},
+			Output: []OutputRouterSpec{{
+				Type:    OutputRouterSpec_PASS_THROUGH,
+				Streams: []StreamEndpointSpec{{Type: StreamEndpointSpec_SYNC_RESPONSE}},
+			}},
+			ProcessorID: 2,
+		}},
+	}
+
+	stats := map[int32]ProcessorStats{
+		1: {OutputRows: 10, KVBytesRead: 2048, Time: 3 * time.Millisecond},
+		2: {InputRows: 10, OutputRows: 10, Time: time.Millisecond, MaxMemory: 10240},
+	}
+
+	json, _, err := GeneratePlanDiagramWithStatsURL(flows, stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `
+		{
+			"nodeNames":["1","2"],
+			"processors":[
+				{"nodeIdx":0,"inputs":[],"core":{"title":"TableReader","details":["primary@Table","rows in: 0","rows out: 10","KV bytes read: 2.0 KiB","time: 3ms"]},"outputs":[]},
+				{"nodeIdx":1,"inputs":[],"core":{"title":"No-op","details":["rows in: 10","rows out: 10","time: 1ms","max memory: 10 KiB"]},"outputs":[]},
+				{"nodeIdx":1,"inputs":[],"core":{"title":"Response","details":[]},"outputs":[]}
+			],
+			"edges":[
+				{"sourceProc":0,"sourceOutput":0,"destProc":1,"destInput":0},
+				{"sourceProc":1,"sourceOutput":0,"destProc":2,"destInput":0}
+			]
+		}
+	`
+
+	compareDiagrams(t, json, expected)
+}
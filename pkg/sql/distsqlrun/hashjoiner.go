@@ -75,6 +75,10 @@ func (p hashJoinPhase) String() string {
 //  3. Probe phase: in this phase we process all the rows from the other stream
 //     and look for matching rows from the stored stream using the map.
 //
+// Semi and anti joins always store the right stream and skip the buffering of
+// the left stream: each left row is then output (or not) as soon as it is
+// probed.
+//
 // There is no guarantee on the output ordering.
 type hashJoiner struct {
 	joinerBase
@@ -95,6 +99,12 @@ type hashJoiner struct {
 	// stream we store fully and build the hashRowContainer from.
 	storedSide joinSide
 
+	// rightNonEmpty and rightHasNull are only used for LEFT_ANTI_NULL_AWARE
+	// joins. They record whether the right stream had any rows, and any rows
+	// with a NULL on the equality column, respectively.
+	rightNonEmpty bool
+	rightHasNull  bool
+
 	// testingKnobMemFailPoint specifies a phase in which the hashJoiner will
 	// fail at a random point during this phase.
 	testingKnobMemFailPoint hashJoinPhase
@@ -123,6 +133,14 @@ func newHashJoiner(
 		initialBufferSize: hashJoinerInitialBufferSize,
 	}
 
+	if spec.Type == JoinType_LEFT_ANTI_NULL_AWARE {
+		if len(spec.LeftEqColumns) != 1 || spec.OnExpr.Expr != "" {
+			return nil, errors.Errorf(
+				"join type %s requires a single equality column and no ON expression", spec.Type,
+			)
+		}
+	}
+
 	numMergedColumns := 0
 	if spec.MergedColumns {
 		numMergedColumns = len(spec.LeftEqColumns)
@@ -294,10 +312,21 @@ func (h *hashJoiner) receiveRow(
 				break
 			}
 		}
+		if h.joinType == leftAntiNullAware && side == rightSide {
+			h.rightNonEmpty = true
+			if hasNull {
+				h.rightHasNull = true
+			}
+		}
 		if !hasNull {
 			// Normal path.
 			return row, false, nil
 		}
+		if h.joinType == leftAntiNullAware && side == leftSide && h.rightNonEmpty {
+			// NULL NOT IN (...) is only true if the right stream is empty. The
+			// right stream has been fully consumed by the time we see left rows.
+			continue
+		}
 
 		needMoreRows, err := h.maybeEmitUnmatchedRow(ctx, row, side)
 		if !needMoreRows || err != nil {
@@ -441,6 +470,10 @@ func (h *hashJoiner) bufferPhase(
 		if leftUsage >= h.initialBufferSize && rightUsage >= h.initialBufferSize {
 			break
 		}
+		if isSemiOrAntiJoin(h.joinType) {
+			// Semi and anti joins store the right stream.
+			break
+		}
 		side := rightSide
 		if leftUsage < rightUsage {
 			side = leftSide
@@ -483,8 +516,8 @@ func (h *hashJoiner) bufferPhase(
 		}
 	}
 
-	// We did not find a short stream. Stop reading for both streams, just
-	// choose the right stream and consume it.
+	// We did not find a short stream (or this is a semi or anti join). Stop
+	// reading for both streams, just choose the right stream and consume it.
 	h.storedSide = rightSide
 
 	for {
@@ -542,6 +575,11 @@ func (h *hashJoiner) probeRow(
 		// If the ON condition failed, renderedRow is nil.
 		if renderedRow != nil {
 			probeMatched = true
+			if isSemiOrAntiJoin(h.joinType) {
+				// The row is output at most once; we only need to know that it
+				// has a match.
+				break
+			}
 			if shouldEmitUnmatchedRow(h.storedSide, h.joinType) {
 				// Mark the row on the stored side. The unmarked rows can then
 				// be iterated over for {right, left} outer joins (depending on
@@ -557,7 +595,14 @@ func (h *hashJoiner) probeRow(
 		}
 	}
 
-	if !probeMatched {
+	if probeMatched && h.joinType == leftSemi {
+		consumerStatus, err := h.out.EmitRow(ctx, row)
+		if err != nil || consumerStatus != NeedMoreRows {
+			return true, err
+		}
+	}
+	if !probeMatched && !(h.joinType == leftAntiNullAware && h.rightHasNull) {
+		// Note that x NOT IN (..., NULL, ...) is never true.
 		needMoreRows, err := h.maybeEmitUnmatchedRow(ctx, row, otherSide(h.storedSide))
 		if !needMoreRows || err != nil {
 			return true, err
@@ -507,6 +507,229 @@ func TestHashJoiner(t *testing.T) {
 				{null, null, null, null, null},
 			},
 		},
+
+		// Test that full outer joins work with ON conditions that aren't
+		// equalities.
+		{
+			spec: HashJoinerSpec{
+				Type:   JoinType_FULL_OUTER,
+				OnExpr: Expression{Expr: "@1 < @2"},
+			},
+			outCols:   []uint32{0, 1},
+			leftTypes: oneIntCol,
+			leftInput: sqlbase.EncDatumRows{
+				{v[0]},
+				{v[1]},
+				{v[2]},
+			},
+			rightTypes: oneIntCol,
+			rightInput: sqlbase.EncDatumRows{
+				{v[1]},
+				{v[2]},
+				{v[3]},
+			},
+			expected: sqlbase.EncDatumRows{
+				{v[0], v[1]},
+				{v[0], v[2]},
+				{v[0], v[3]},
+				{v[1], v[2]},
+				{v[1], v[3]},
+				{v[2], v[3]},
+			},
+		},
+		{
+			spec: HashJoinerSpec{
+				Type:   JoinType_FULL_OUTER,
+				OnExpr: Expression{Expr: "@1 > @2"},
+			},
+			outCols:   []uint32{0, 1},
+			leftTypes: oneIntCol,
+			leftInput: sqlbase.EncDatumRows{
+				{v[0]},
+				{v[2]},
+			},
+			rightTypes: oneIntCol,
+			rightInput: sqlbase.EncDatumRows{
+				{v[1]},
+				{v[3]},
+			},
+			expected: sqlbase.EncDatumRows{
+				{v[0], null},
+				{v[2], v[1]},
+				{null, v[3]},
+			},
+		},
+
+		// Tests for semi and anti joins.
+		{
+			spec: HashJoinerSpec{
+				LeftEqColumns:  []uint32{0},
+				RightEqColumns: []uint32{0},
+				Type:           JoinType_LEFT_SEMI,
+			},
+			outCols:   []uint32{0, 1},
+			leftTypes: twoIntCols,
+			leftInput: sqlbase.EncDatumRows{
+				{v[0], v[0]},
+				{v[1], v[4]},
+				{v[2], v[4]},
+				{v[3], v[1]},
+				{null, v[5]},
+			},
+			rightTypes: oneIntCol,
+			rightInput: sqlbase.EncDatumRows{
+				{v[0]},
+				{v[3]},
+				{v[3]},
+				{null},
+			},
+			expected: sqlbase.EncDatumRows{
+				{v[0], v[0]},
+				{v[3], v[1]},
+			},
+		},
+		{
+			spec: HashJoinerSpec{
+				LeftEqColumns:  []uint32{0},
+				RightEqColumns: []uint32{0},
+				Type:           JoinType_LEFT_SEMI,
+				OnExpr:         Expression{Expr: "@2 >= 1"},
+			},
+			outCols:   []uint32{0, 1},
+			leftTypes: twoIntCols,
+			leftInput: sqlbase.EncDatumRows{
+				{v[0], v[0]},
+				{v[1], v[4]},
+				{v[3], v[1]},
+			},
+			rightTypes: oneIntCol,
+			rightInput: sqlbase.EncDatumRows{
+				{v[0]},
+				{v[3]},
+				{v[3]},
+			},
+			expected: sqlbase.EncDatumRows{
+				{v[3], v[1]},
+			},
+		},
+		{
+			spec: HashJoinerSpec{
+				LeftEqColumns:  []uint32{0},
+				RightEqColumns: []uint32{0},
+				Type:           JoinType_LEFT_ANTI,
+			},
+			outCols:   []uint32{0, 1},
+			leftTypes: twoIntCols,
+			leftInput: sqlbase.EncDatumRows{
+				{v[0], v[0]},
+				{v[1], v[4]},
+				{v[2], v[4]},
+				{v[3], v[1]},
+				{null, v[5]},
+			},
+			rightTypes: oneIntCol,
+			rightInput: sqlbase.EncDatumRows{
+				{v[0]},
+				{v[3]},
+				{v[3]},
+				{null},
+			},
+			expected: sqlbase.EncDatumRows{
+				{v[1], v[4]},
+				{v[2], v[4]},
+				{null, v[5]},
+			},
+		},
+		{
+			spec: HashJoinerSpec{
+				LeftEqColumns:  []uint32{0},
+				RightEqColumns: []uint32{0},
+				Type:           JoinType_LEFT_ANTI,
+				OnExpr:         Expression{Expr: "@2 >= 1"},
+			},
+			outCols:   []uint32{0, 1},
+			leftTypes: twoIntCols,
+			leftInput: sqlbase.EncDatumRows{
+				{v[0], v[0]},
+				{v[1], v[4]},
+				{v[3], v[1]},
+			},
+			rightTypes: oneIntCol,
+			rightInput: sqlbase.EncDatumRows{
+				{v[0]},
+				{v[3]},
+			},
+			expected: sqlbase.EncDatumRows{
+				{v[0], v[0]},
+				{v[1], v[4]},
+			},
+		},
+		{
+			spec: HashJoinerSpec{
+				LeftEqColumns:  []uint32{0},
+				RightEqColumns: []uint32{0},
+				Type:           JoinType_LEFT_ANTI_NULL_AWARE,
+			},
+			outCols:   []uint32{0, 1},
+			leftTypes: twoIntCols,
+			leftInput: sqlbase.EncDatumRows{
+				{v[0], v[0]},
+				{v[1], v[4]},
+				{v[2], v[4]},
+				{null, v[5]},
+			},
+			rightTypes: oneIntCol,
+			rightInput: sqlbase.EncDatumRows{
+				{v[0]},
+				{v[3]},
+			},
+			// The left row with a NULL is not output.
+			expected: sqlbase.EncDatumRows{
+				{v[1], v[4]},
+				{v[2], v[4]},
+			},
+		},
+		{
+			spec: HashJoinerSpec{
+				LeftEqColumns:  []uint32{0},
+				RightEqColumns: []uint32{0},
+				Type:           JoinType_LEFT_ANTI_NULL_AWARE,
+			},
+			outCols:   []uint32{0, 1},
+			leftTypes: twoIntCols,
+			leftInput: sqlbase.EncDatumRows{
+				{v[0], v[0]},
+				{v[1], v[4]},
+				{null, v[5]},
+			},
+			rightTypes: oneIntCol,
+			rightInput: sqlbase.EncDatumRows{
+				{v[0]},
+				{null},
+			},
+			// The right side has a NULL: no row is output.
+			expected: sqlbase.EncDatumRows{},
+		},
+		{
+			spec: HashJoinerSpec{
+				LeftEqColumns:  []uint32{0},
+				RightEqColumns: []uint32{0},
+				Type:           JoinType_LEFT_ANTI_NULL_AWARE,
+			},
+			outCols:   []uint32{0, 1},
+			leftTypes: twoIntCols,
+			leftInput: sqlbase.EncDatumRows{
+				{v[0], v[0]},
+				{null, v[5]},
+			},
+			rightTypes: oneIntCol,
+			rightInput: sqlbase.EncDatumRows{},
+			// The right side is empty: all rows are output.
+			expected: sqlbase.EncDatumRows{
+				{v[0], v[0]},
+				{null, v[5]},
+			},
+		},
 	}
 
 	ctx := context.Background()
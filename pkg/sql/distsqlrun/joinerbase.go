@@ -15,6 +15,7 @@
 package distsqlrun
 
 import (
+	"github.com/pkg/errors"
 	"golang.org/x/net/context"
 
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
@@ -58,6 +59,9 @@ func (jb *joinerBase) init(
 	jb.leftSource = leftSource
 	jb.rightSource = rightSource
 	jb.joinType = joinType(jType)
+	if isSemiOrAntiJoin(jb.joinType) && numMergedColumns > 0 {
+		return errors.Errorf("merged columns not supported for join type %s", jType)
+	}
 
 	leftTypes := leftSource.Types()
 	jb.emptyLeft = make(sqlbase.EncDatumRow, len(leftTypes))
@@ -95,7 +99,19 @@ func (jb *joinerBase) init(
 	if err := jb.onCond.init(onExpr, types, evalCtx); err != nil {
 		return err
 	}
-	return jb.out.Init(post, types, evalCtx, output)
+	outTypes := types
+	if isSemiOrAntiJoin(jb.joinType) {
+		// Semi and anti joins only output the columns of the left side; the ON
+		// condition still refers to the columns of both sides.
+		outTypes = leftTypes
+	}
+	return jb.out.Init(post, outTypes, evalCtx, output)
+}
+
+// isSemiOrAntiJoin returns true for the join types which output each row of
+// the left side at most once and don't output the columns of the right side.
+func isSemiOrAntiJoin(joinType joinType) bool {
+	return joinType == leftSemi || joinType == leftAnti || joinType == leftAntiNullAware
 }
 
 type joinSide uint8
@@ -110,10 +126,14 @@ func otherSide(s joinSide) joinSide {
 }
 
 // renderUnmatchedRow creates a result row given an unmatched row on either
-// side. Only used for outer joins.
+// side. Only used for outer and anti joins.
 func (jb *joinerBase) renderUnmatchedRow(
 	row sqlbase.EncDatumRow, side joinSide,
 ) sqlbase.EncDatumRow {
+	if isSemiOrAntiJoin(jb.joinType) {
+		// The row is from the left side and is output as is.
+		return row
+	}
 	lrow, rrow := jb.emptyLeft, jb.emptyRight
 	if side == leftSide {
 		lrow = row
@@ -134,11 +154,15 @@ func (jb *joinerBase) renderUnmatchedRow(
 
 // shouldEmitUnmatchedRow determines if we should emit am ummatched row (with
 // NULLs for the columns of the other stream). This happens in FULL OUTER joins
-// and LEFT or RIGHT OUTER joins (depending on which stream).
+// and LEFT or RIGHT OUTER joins (depending on which stream). Anti joins emit
+// the unmatched rows of the left stream, without the columns of the right
+// stream.
 func shouldEmitUnmatchedRow(side joinSide, joinType joinType) bool {
 	switch joinType {
-	case innerJoin:
+	case innerJoin, leftSemi:
 		return false
+	case leftAnti, leftAntiNullAware:
+		return side == leftSide
 	case rightOuter:
 		if side == leftSide {
 			return false
@@ -20,8 +20,11 @@ import (
 	"github.com/pkg/errors"
 	"golang.org/x/net/context"
 
+	"github.com/cockroachdb/cockroach/pkg/internal/client"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/util"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/cockroach/pkg/util/tracing"
 )
@@ -31,6 +34,8 @@ import (
 // nodes that "own" the respective ranges, and send out flows on those nodes.
 const joinReaderBatchSize = 100
 
+// joinReader performs either an index join or a lookup join against an index
+// of a table (see JoinReaderSpec).
 type joinReader struct {
 	processorBase
 
@@ -44,9 +49,35 @@ type joinReader struct {
 
 	input      RowSource
 	inputTypes []sqlbase.ColumnType
+
+	// The fields below are only used for lookup joins.
+
+	// lookupCols are the columns of the input stream that are matched against
+	// the first len(lookupCols) columns of the index. They are nil for index
+	// joins.
+	lookupCols columns
+	// indexCols are the ordinals of the table columns corresponding to
+	// lookupCols.
+	indexCols columns
+	// keyIndex is a copy of index that only has the directions of the columns
+	// matched by the lookup columns, so that the keys can be generated from
+	// these columns alone.
+	keyIndex sqlbase.IndexDescriptor
+	// lookupTypes and indexColTypes are the types of the lookup columns and of
+	// the corresponding table columns.
+	lookupTypes   []sqlbase.ColumnType
+	indexColTypes []sqlbase.ColumnType
+	keyRow        sqlbase.EncDatumRow
+
+	joinType    joinType
+	onCond      exprHelper
+	emptyRight  sqlbase.EncDatumRow
+	combinedRow sqlbase.EncDatumRow
+	rowAlloc    sqlbase.EncDatumRowAlloc
 }
 
 var _ Processor = &joinReader{}
+var _ kvReader = &joinReader{}
 
 func newJoinReader(
 	flowCtx *FlowCtx,
@@ -55,9 +86,15 @@ func newJoinReader(
 	post *PostProcessSpec,
 	output RowReceiver,
 ) (*joinReader, error) {
-	if spec.IndexIdx != 0 {
-		// TODO(radu): for now we only support joining with the primary index
-		return nil, errors.Errorf("join with index not implemented")
+	lookupJoin := len(spec.LookupColumns) > 0
+	if !lookupJoin {
+		if spec.IndexIdx != 0 {
+			// Index joins are performed against the primary index.
+			return nil, errors.Errorf("index join with a secondary index not supported")
+		}
+		if spec.Type != JoinType_INNER || spec.OnExpr.Expr != "" {
+			return nil, errors.Errorf("join type and ON expression are only supported for lookup joins")
+		}
 	}
 
 	jr := &joinReader{
@@ -65,21 +102,58 @@ func newJoinReader(
 		desc:       spec.Table,
 		input:      input,
 		inputTypes: input.Types(),
+		lookupCols: columns(spec.LookupColumns),
+		joinType:   joinType(spec.Type),
 	}
 
-	types := make([]sqlbase.ColumnType, len(spec.Table.Columns))
-	for i := range types {
-		types[i] = spec.Table.Columns[i].Type
+	tableTypes := make([]sqlbase.ColumnType, len(spec.Table.Columns))
+	for i := range tableTypes {
+		tableTypes[i] = spec.Table.Columns[i].Type
+	}
+
+	types := tableTypes
+	if lookupJoin {
+		switch spec.Type {
+		case JoinType_INNER, JoinType_LEFT_OUTER:
+			types = make([]sqlbase.ColumnType, 0, len(jr.inputTypes)+len(tableTypes))
+			types = append(types, jr.inputTypes...)
+			types = append(types, tableTypes...)
+		case JoinType_LEFT_SEMI:
+			types = jr.inputTypes
+		default:
+			return nil, errors.Errorf("lookup join of type %s not supported", spec.Type)
+		}
+
+		onTypes := make([]sqlbase.ColumnType, 0, len(jr.inputTypes)+len(tableTypes))
+		onTypes = append(onTypes, jr.inputTypes...)
+		onTypes = append(onTypes, tableTypes...)
+		if err := jr.onCond.init(spec.OnExpr, onTypes, flowCtx.NewEvalCtx()); err != nil {
+			return nil, err
+		}
+
+		jr.emptyRight = make(sqlbase.EncDatumRow, len(tableTypes))
+		for i := range jr.emptyRight {
+			jr.emptyRight[i] = sqlbase.DatumToEncDatum(tableTypes[i], tree.DNull)
+		}
+		jr.combinedRow = make(sqlbase.EncDatumRow, 0, len(onTypes))
 	}
 
 	if err := jr.init(post, types, flowCtx, output); err != nil {
 		return nil, err
 	}
 
+	neededCols := jr.out.neededColumns()
+	if lookupJoin {
+		var err error
+		if neededCols, err = jr.initLookupColumns(int(spec.IndexIdx), tableTypes); err != nil {
+			return nil, err
+		}
+	}
+
 	var err error
 	jr.index, _, err = initRowFetcher(
 		&jr.fetcher, &jr.desc, int(spec.IndexIdx), false, /* reverse */
-		jr.out.neededColumns(), &jr.alloc,
+		neededCols, &jr.alloc,
 	)
 	if err != nil {
 		return nil, err
@@ -90,6 +164,81 @@ func newJoinReader(
 	return jr, nil
 }
 
+// initLookupColumns sets up the fields used to match the lookup columns
+// against the index columns and returns the set of table columns that need to
+// be fetched for a lookup join.
+func (jr *joinReader) initLookupColumns(
+	indexIdx int, tableTypes []sqlbase.ColumnType,
+) (util.FastIntSet, error) {
+	index, _, err := jr.desc.FindIndexByIndexIdx(indexIdx)
+	if err != nil {
+		return util.FastIntSet{}, err
+	}
+	if len(jr.lookupCols) > len(index.ColumnIDs) {
+		return util.FastIntSet{}, errors.Errorf(
+			"%d lookup columns, but index %s only has %d columns",
+			len(jr.lookupCols), index.Name, len(index.ColumnIDs),
+		)
+	}
+	sharedPrefixLen := 0
+	for _, ancestor := range index.Interleave.Ancestors {
+		sharedPrefixLen += int(ancestor.SharedPrefixLen)
+	}
+	if len(jr.lookupCols) < sharedPrefixLen {
+		return util.FastIntSet{}, errors.Errorf(
+			"lookup join on a prefix of interleaved index %s not supported", index.Name,
+		)
+	}
+
+	jr.indexCols = make(columns, len(jr.lookupCols))
+	jr.lookupTypes = make([]sqlbase.ColumnType, len(jr.lookupCols))
+	jr.indexColTypes = make([]sqlbase.ColumnType, len(jr.lookupCols))
+	jr.keyRow = make(sqlbase.EncDatumRow, len(jr.lookupCols))
+	for i, c := range jr.lookupCols {
+		if int(c) >= len(jr.inputTypes) {
+			return util.FastIntSet{}, errors.Errorf("lookup column %d out of range", c)
+		}
+		colIdx := -1
+		for j := range jr.desc.Columns {
+			if jr.desc.Columns[j].ID == index.ColumnIDs[i] {
+				colIdx = j
+				break
+			}
+		}
+		if colIdx == -1 {
+			return util.FastIntSet{}, errors.Errorf("column %d not found", index.ColumnIDs[i])
+		}
+		if jr.inputTypes[c].SemanticType != tableTypes[colIdx].SemanticType {
+			return util.FastIntSet{}, errors.Errorf(
+				"lookup column %d has type %s, but index column %s has type %s", c,
+				jr.inputTypes[c].SemanticType, index.ColumnNames[i], tableTypes[colIdx].SemanticType,
+			)
+		}
+		jr.indexCols[i] = uint32(colIdx)
+		jr.lookupTypes[i] = jr.inputTypes[c]
+		jr.indexColTypes[i] = tableTypes[colIdx]
+	}
+	jr.keyIndex = *index
+	jr.keyIndex.ColumnDirections = index.ColumnDirections[:len(jr.lookupCols)]
+
+	// The table columns are needed if they are used by the output or by the ON
+	// expression. The index columns matched by the lookup columns are always
+	// needed to match the fetched rows with the input rows.
+	outNeeded := jr.out.neededColumns()
+	numInputCols := len(jr.inputTypes)
+	var neededCols util.FastIntSet
+	for i := range tableTypes {
+		if (jr.joinType != leftSemi && outNeeded.Contains(numInputCols+i)) ||
+			(jr.onCond.expr != nil && jr.onCond.vars.IndexedVarUsed(numInputCols+i)) {
+			neededCols.Add(i)
+		}
+	}
+	for _, c := range jr.indexCols {
+		neededCols.Add(int(c))
+	}
+	return neededCols, nil
+}
+
 func (jr *joinReader) generateKey(
 	row sqlbase.EncDatumRow, alloc *sqlbase.DatumAlloc, primaryKeyPrefix []byte,
 ) (roachpb.Key, error) {
@@ -106,6 +255,43 @@ func (jr *joinReader) generateKey(
 	return sqlbase.MakeKeyFromEncDatums(types, row, &jr.desc, index, primaryKeyPrefix, alloc)
 }
 
+// generateLookupKey returns the key prefix of the index rows matching the
+// given row, using the values in the given columns of the row. Returns nil if
+// any of these values is NULL, as such a row cannot match any index row.
+func (jr *joinReader) generateLookupKey(
+	row sqlbase.EncDatumRow,
+	cols columns,
+	types []sqlbase.ColumnType,
+	alloc *sqlbase.DatumAlloc,
+	keyPrefix []byte,
+) (roachpb.Key, error) {
+	for i, c := range cols {
+		if row[c].IsNull() {
+			return nil, nil
+		}
+		jr.keyRow[i] = row[c]
+	}
+	return sqlbase.MakeKeyFromEncDatums(types, jr.keyRow, &jr.desc, &jr.keyIndex, keyPrefix, alloc)
+}
+
+// render constructs the output row for an input row and a matching index row.
+// The ON condition is evaluated; if it fails, returns nil.
+func (jr *joinReader) render(lrow, rrow sqlbase.EncDatumRow) (sqlbase.EncDatumRow, error) {
+	jr.combinedRow = jr.combinedRow[:0]
+	jr.combinedRow = append(jr.combinedRow, lrow...)
+	jr.combinedRow = append(jr.combinedRow, rrow...)
+	if jr.onCond.expr != nil {
+		res, err := jr.onCond.evalFilter(jr.combinedRow)
+		if !res || err != nil {
+			return nil, err
+		}
+	}
+	if jr.joinType == leftSemi {
+		return lrow, nil
+	}
+	return jr.combinedRow, nil
+}
+
 // mainLoop runs the mainLoop and returns any error.
 //
 // If no error is returned, the input has been drained and the output has been
@@ -128,6 +314,10 @@ func (jr *joinReader) mainLoop(ctx context.Context) error {
 		defer log.Infof(ctx, "exiting")
 	}
 
+	if len(jr.lookupCols) > 0 {
+		return jr.lookupJoinLoop(ctx, txn, primaryKeyPrefix)
+	}
+
 	for {
 		// TODO(radu): figure out how to send smaller batches if the source has
 		// a soft limit (perhaps send the batch out if we don't get a result
@@ -199,6 +389,156 @@ func (jr *joinReader) mainLoop(ctx context.Context) error {
 	}
 }
 
+// lookupJoinLoop implements the mainLoop of a lookup join. The input rows are
+// read in batches; for each batch, the index rows matching the lookup columns
+// of the input rows are fetched and joined with the input rows. keyPrefix is
+// the key prefix of the index. Semi joins emit the matching input rows of a
+// batch once it is done, so the input ordering is preserved.
+//
+// The error semantics are those of mainLoop.
+func (jr *joinReader) lookupJoinLoop(
+	ctx context.Context, txn *client.Txn, keyPrefix []byte,
+) error {
+	var alloc sqlbase.DatumAlloc
+	spans := make(roachpb.Spans, 0, joinReaderBatchSize)
+	inputRows := make(sqlbase.EncDatumRows, 0, joinReaderBatchSize)
+	// matched[i] is set if inputRows[i] matched at least one index row.
+	matched := make([]bool, joinReaderBatchSize)
+	// keyToInputRows maps the lookup key of an input row of the batch to the
+	// indices of all the input rows of the batch with the same key.
+	keyToInputRows := make(map[string][]int)
+
+	for {
+		inputRows = inputRows[:0]
+		spans = spans[:0]
+		for k := range keyToInputRows {
+			delete(keyToInputRows, k)
+		}
+
+		inputDone := false
+		for len(inputRows) < joinReaderBatchSize {
+			row, meta := jr.input.Next()
+			if !meta.Empty() {
+				if meta.Err != nil {
+					return meta.Err
+				}
+				if !emitHelper(ctx, &jr.out, nil /* row */, meta, jr.input) {
+					return nil
+				}
+				continue
+			}
+			if row == nil {
+				inputDone = true
+				break
+			}
+			inputRows = append(inputRows, jr.rowAlloc.CopyRow(row))
+		}
+
+		for i, row := range inputRows {
+			matched[i] = false
+			key, err := jr.generateLookupKey(row, jr.lookupCols, jr.lookupTypes, &alloc, keyPrefix)
+			if err != nil {
+				return err
+			}
+			if key == nil {
+				// The row has NULLs in the lookup columns; it can't match anything.
+				continue
+			}
+			if _, ok := keyToInputRows[string(key)]; !ok {
+				spans = append(spans, roachpb.Span{
+					Key:    key,
+					EndKey: key.PrefixEnd(),
+				})
+			}
+			keyToInputRows[string(key)] = append(keyToInputRows[string(key)], i)
+		}
+
+		if len(spans) > 0 {
+			// TODO(radu,andrei,knz): set the traceKV flag when requested by the session.
+			err := jr.fetcher.StartScan(ctx, txn, spans, false /* no batch limits */, 0, false /* traceKV */)
+			if err != nil {
+				log.Errorf(ctx, "scan error: %s", err)
+				return err
+			}
+
+			for {
+				row, _, _, err := jr.fetcher.NextRow(ctx)
+				if err != nil {
+					return err
+				}
+				if row == nil {
+					// Done with this batch.
+					break
+				}
+
+				// Find the input rows with the same lookup key as the index row.
+				key, err := jr.generateLookupKey(row, jr.indexCols, jr.indexColTypes, &alloc, keyPrefix)
+				if err != nil {
+					return err
+				}
+				for _, i := range keyToInputRows[string(key)] {
+					if jr.joinType == leftSemi && matched[i] {
+						// Semi joins emit each input row at most once.
+						continue
+					}
+					renderedRow, err := jr.render(inputRows[i], row)
+					if err != nil {
+						return err
+					}
+					if renderedRow == nil {
+						continue
+					}
+					matched[i] = true
+					if jr.joinType == leftSemi {
+						// The matching input rows are emitted after the batch, so
+						// that they keep the order of the input.
+						continue
+					}
+					if !emitHelper(ctx, &jr.out, renderedRow, ProducerMetadata{}, jr.input) {
+						return nil
+					}
+				}
+			}
+		}
+
+		if jr.joinType == leftSemi {
+			for i, row := range inputRows {
+				if !matched[i] {
+					continue
+				}
+				if !emitHelper(ctx, &jr.out, row, ProducerMetadata{}, jr.input) {
+					return nil
+				}
+			}
+		}
+
+		if jr.joinType == leftOuter {
+			for i, row := range inputRows {
+				if matched[i] {
+					continue
+				}
+				jr.combinedRow = jr.combinedRow[:0]
+				jr.combinedRow = append(jr.combinedRow, row...)
+				jr.combinedRow = append(jr.combinedRow, jr.emptyRight...)
+				if !emitHelper(ctx, &jr.out, jr.combinedRow, ProducerMetadata{}, jr.input) {
+					return nil
+				}
+			}
+		}
+
+		if inputDone {
+			sendTraceData(ctx, jr.out.output)
+			jr.out.Close()
+			return nil
+		}
+	}
+}
+
+// kvBytesRead is part of the kvReader interface.
+func (jr *joinReader) kvBytesRead() int64 {
+	return jr.fetcher.BytesRead()
+}
+
 // Run is part of the processor interface.
 func (jr *joinReader) Run(ctx context.Context, wg *sync.WaitGroup) {
 	if wg != nil {
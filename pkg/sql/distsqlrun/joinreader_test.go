@@ -60,6 +60,9 @@ func TestJoinReader(t *testing.T) {
 	td := sqlbase.GetTableDescriptor(kvDB, "test", "t")
 
 	testCases := []struct {
+		description string
+		// The Table field of the spec is set by the test.
+		spec        JoinReaderSpec
 		post        PostProcessSpec
 		input       [][]tree.Datum
 		outputTypes []sqlbase.ColumnType
@@ -98,9 +101,59 @@ func TestJoinReader(t *testing.T) {
 			outputTypes: []sqlbase.ColumnType{strType},
 			expected:    "[['one'] ['five'] ['two-one'] ['one-three'] ['five-zero']]",
 		},
+		{
+			description: "Lookup join on a prefix of the primary index with ON",
+			spec: JoinReaderSpec{
+				LookupColumns: []uint32{0},
+				OnExpr:        Expression{Expr: "@4 > @2"}, // b > input column 2
+			},
+			post: PostProcessSpec{
+				Projection:    true,
+				OutputColumns: []uint32{0, 3, 4},
+			},
+			input: [][]tree.Datum{
+				{tree.NewDInt(3), tree.NewDInt(7)},
+			},
+			outputTypes: threeIntCols,
+			expected:    "[[3 8 11] [3 9 12]]",
+		},
+		{
+			description: "Left outer lookup join on a secondary index",
+			spec: JoinReaderSpec{
+				IndexIdx:      1,
+				LookupColumns: []uint32{1},
+				OnExpr:        Expression{Expr: "@3 < 2"}, // a < 2
+				Type:          JoinType_LEFT_OUTER,
+			},
+			post: PostProcessSpec{
+				Projection:    true,
+				OutputColumns: []uint32{0, 2, 5},
+			},
+			input: [][]tree.Datum{
+				{tree.NewDInt(0), tree.NewDInt(5)},
+				{tree.NewDInt(1), tree.NewDInt(10)},
+				{tree.NewDInt(2), tree.DNull},
+			},
+			outputTypes: []sqlbase.ColumnType{intType, intType, strType},
+			expected:    "[[0 0 'five'] [0 1 'one-five'] [1 NULL NULL] [2 NULL NULL]]",
+		},
+		{
+			description: "Semi lookup join",
+			spec: JoinReaderSpec{
+				LookupColumns: []uint32{0},
+				Type:          JoinType_LEFT_SEMI,
+			},
+			input: [][]tree.Datum{
+				{tree.NewDInt(2), tree.NewDInt(0)},
+				{tree.NewDInt(20), tree.NewDInt(1)},
+				{tree.NewDInt(2), tree.NewDInt(2)},
+			},
+			outputTypes: twoIntCols,
+			expected:    "[[2 0] [2 2]]",
+		},
 	}
 	for _, c := range testCases {
-		t.Run("", func(t *testing.T) {
+		t.Run(c.description, func(t *testing.T) {
 			evalCtx := tree.MakeTestingEvalContext()
 			defer evalCtx.Stop(context.Background())
 			flowCtx := FlowCtx{
@@ -121,7 +174,9 @@ func TestJoinReader(t *testing.T) {
 			in := NewRowBuffer(twoIntCols, encRows, RowBufferArgs{})
 
 			out := &RowBuffer{}
-			jr, err := newJoinReader(&flowCtx, &JoinReaderSpec{Table: *td}, in, &c.post, out)
+			spec := c.spec
+			spec.Table = *td
+			jr, err := newJoinReader(&flowCtx, &spec, in, &c.post, out)
 			if err != nil {
 				t.Fatal(err)
 			}
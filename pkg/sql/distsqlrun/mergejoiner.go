@@ -45,6 +45,9 @@ func newMergeJoiner(
 	post *PostProcessSpec,
 	output RowReceiver,
 ) (*mergeJoiner, error) {
+	if spec.Type == JoinType_LEFT_ANTI_NULL_AWARE {
+		return nil, errors.New("null-aware anti joins are not supported by the merge joiner")
+	}
 	for i, c := range spec.LeftOrdering.Columns {
 		if spec.RightOrdering.Columns[i].Direction != c.Direction {
 			return nil, errors.New("Unmatched column orderings")
@@ -97,7 +100,8 @@ func (m *mergeJoiner) Run(ctx context.Context, wg *sync.WaitGroup) {
 }
 
 // outputBatch outputs all the rows corresponding to a streamMerger batch (the
-// cross-product of two groups of matching rows).
+// cross-product of two groups of matching rows, or for semi and anti joins the
+// left rows that do or don't have a match in the right group).
 //
 // Returns true if more batches are available and needed. If false is returned,
 // the caller should drain the inputs (as the termination condition might have
@@ -130,6 +134,11 @@ func (m *mergeJoiner) outputBatch(
 			}
 			if renderedRow != nil {
 				matched = true
+				if isSemiOrAntiJoin(m.joinType) {
+					// The left row is output at most once; we only need to know
+					// that it has a match.
+					break
+				}
 				if matchedRight != nil {
 					matchedRight[rIdx] = true
 				}
@@ -139,6 +148,12 @@ func (m *mergeJoiner) outputBatch(
 				}
 			}
 		}
+		if matched && m.joinType == leftSemi {
+			consumerStatus, err := m.out.EmitRow(ctx, lrow)
+			if err != nil || consumerStatus != NeedMoreRows {
+				return false, err
+			}
+		}
 		if !matched {
 			needMoreRows, err := m.maybeEmitUnmatchedRow(ctx, lrow, leftSide)
 			if !needMoreRows || err != nil {
@@ -422,6 +422,140 @@ func TestMergeJoiner(t *testing.T) {
 			expectedTypes: twoIntCols,
 			expected:      sqlbase.EncDatumRows{},
 		},
+		{
+			spec: MergeJoinerSpec{
+				LeftOrdering: convertToSpecOrdering(
+					sqlbase.ColumnOrdering{
+						{ColIdx: 0, Direction: encoding.Ascending},
+					}),
+				RightOrdering: convertToSpecOrdering(
+					sqlbase.ColumnOrdering{
+						{ColIdx: 0, Direction: encoding.Ascending},
+					}),
+				Type: JoinType_LEFT_SEMI,
+			},
+			outCols:   []uint32{0, 1},
+			leftTypes: twoIntCols,
+			leftInput: sqlbase.EncDatumRows{
+				{null, v[5]},
+				{v[0], v[0]},
+				{v[1], v[4]},
+				{v[2], v[4]},
+				{v[3], v[1]},
+				{v[3], v[2]},
+			},
+			rightTypes: oneIntCol,
+			rightInput: sqlbase.EncDatumRows{
+				{null},
+				{v[0]},
+				{v[3]},
+				{v[3]},
+			},
+			expectedTypes: twoIntCols,
+			expected: sqlbase.EncDatumRows{
+				{v[0], v[0]},
+				{v[3], v[1]},
+				{v[3], v[2]},
+			},
+		},
+		{
+			spec: MergeJoinerSpec{
+				LeftOrdering: convertToSpecOrdering(
+					sqlbase.ColumnOrdering{
+						{ColIdx: 0, Direction: encoding.Ascending},
+					}),
+				RightOrdering: convertToSpecOrdering(
+					sqlbase.ColumnOrdering{
+						{ColIdx: 0, Direction: encoding.Ascending},
+					}),
+				Type:   JoinType_LEFT_SEMI,
+				OnExpr: Expression{Expr: "@2 >= 2"},
+			},
+			outCols:   []uint32{0, 1},
+			leftTypes: twoIntCols,
+			leftInput: sqlbase.EncDatumRows{
+				{v[0], v[0]},
+				{v[3], v[1]},
+				{v[3], v[2]},
+			},
+			rightTypes: oneIntCol,
+			rightInput: sqlbase.EncDatumRows{
+				{v[0]},
+				{v[3]},
+				{v[3]},
+			},
+			expectedTypes: twoIntCols,
+			expected: sqlbase.EncDatumRows{
+				{v[3], v[2]},
+			},
+		},
+		{
+			spec: MergeJoinerSpec{
+				LeftOrdering: convertToSpecOrdering(
+					sqlbase.ColumnOrdering{
+						{ColIdx: 0, Direction: encoding.Ascending},
+					}),
+				RightOrdering: convertToSpecOrdering(
+					sqlbase.ColumnOrdering{
+						{ColIdx: 0, Direction: encoding.Ascending},
+					}),
+				Type: JoinType_LEFT_ANTI,
+			},
+			outCols:   []uint32{0, 1},
+			leftTypes: twoIntCols,
+			leftInput: sqlbase.EncDatumRows{
+				{null, v[5]},
+				{v[0], v[0]},
+				{v[1], v[4]},
+				{v[2], v[4]},
+				{v[3], v[1]},
+				{v[3], v[2]},
+			},
+			rightTypes: oneIntCol,
+			rightInput: sqlbase.EncDatumRows{
+				{null},
+				{v[0]},
+				{v[3]},
+				{v[3]},
+			},
+			expectedTypes: twoIntCols,
+			expected: sqlbase.EncDatumRows{
+				{null, v[5]},
+				{v[1], v[4]},
+				{v[2], v[4]},
+			},
+		},
+		{
+			spec: MergeJoinerSpec{
+				LeftOrdering: convertToSpecOrdering(
+					sqlbase.ColumnOrdering{
+						{ColIdx: 0, Direction: encoding.Ascending},
+					}),
+				RightOrdering: convertToSpecOrdering(
+					sqlbase.ColumnOrdering{
+						{ColIdx: 0, Direction: encoding.Ascending},
+					}),
+				Type:   JoinType_LEFT_ANTI,
+				OnExpr: Expression{Expr: "@2 >= 2"},
+			},
+			outCols:   []uint32{0, 1},
+			leftTypes: twoIntCols,
+			leftInput: sqlbase.EncDatumRows{
+				{v[0], v[0]},
+				{v[3], v[1]},
+				{v[3], v[2]},
+			},
+			rightTypes: oneIntCol,
+			rightInput: sqlbase.EncDatumRows{
+				{v[0]},
+				{v[3]},
+			},
+			expectedTypes: twoIntCols,
+			expected: sqlbase.EncDatumRows{
+				{v[0], v[0]},
+				{v[3], v[1]},
+			},
+		},
 	}
 
 	for _, c := range testCases {
@@ -16,6 +16,7 @@ package distsqlrun
 
 import (
 	"io"
+	"time"
 
 	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/pkg/errors"
@@ -70,7 +71,7 @@ type DistSQLVersion uint32
 //
 // ATTENTION: When updating these fields, add to version_history.txt explaining
 // what changed.
-const Version DistSQLVersion = 7
+const Version DistSQLVersion = 11
 
 // MinAcceptedVersion is the oldest version that the server is
 // compatible with; see above.
@@ -88,6 +89,24 @@ var settingUseTempStorageJoins = settings.RegisterBoolSetting(
 	true,
 )
 
+var settingUseTempStorageAggregations = settings.RegisterBoolSetting(
+	"sql.distsql.temp_storage.aggregations",
+	"set to true to enable use of disk for distributed sql aggregations",
+	true,
+)
+
+var settingUseTempStorageDistincts = settings.RegisterBoolSetting(
+	"sql.distsql.temp_storage.distincts",
+	"set to true to enable use of disk for distributed sql distincts",
+	true,
+)
+
+var settingVectorize = settings.RegisterBoolSetting(
+	"sql.distsql.vectorize.enabled",
+	"set to true to run flows whose processors all support it with the vectorized execution engine",
+	false,
+)
+
 var settingWorkMemBytes = settings.RegisterByteSizeSetting(
 	"sql.distsql.temp_storage.workmem",
 	"maximum amount of memory in bytes a processor can use before falling back to temp storage",
@@ -249,6 +268,7 @@ func (ds *ServerImpl) setupFlow(
 	// DistSQL transactions get retryable errors that would otherwise be handled
 	// by the TxnCoordSender.
 	txn.AcceptUnhandledRetryableErrors()
+	txn.SetLockTimeout(time.Duration(req.EvalContext.LockTimeoutNanos))
 
 	location, err := timeutil.TimeZoneStringToLocation(req.EvalContext.Location)
 	if err != nil {
@@ -0,0 +1,314 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package distsqlrun
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/util/humanizeutil"
+	"github.com/cockroachdb/cockroach/pkg/util/mon"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/cockroachdb/cockroach/pkg/util/tracing"
+)
+
+// ProcessorStats contains runtime statistics about a processor. They are
+// collected when a flow runs with a recording trace (e.g. for EXPLAIN ANALYZE)
+// and are sent back to the gateway as tags on a span that is part of the
+// trace data of the flow (see ExtractProcessorStats).
+type ProcessorStats struct {
+	// InputRows is the number of rows read by the processor from its inputs.
+	InputRows int64
+	// OutputRows is the number of rows emitted by the processor (after
+	// post-processing).
+	OutputRows int64
+	// KVBytesRead is the number of bytes of keys and values read from the KV
+	// layer.
+	KVBytesRead int64
+	// Time is the time spent running the processor, excluding the time spent
+	// waiting for input rows.
+	Time time.Duration
+	// MaxMemory is the peak memory usage of the processor.
+	MaxMemory int64
+	// MaxDisk is the peak temporary storage usage of the processor; it is
+	// non-zero only if the processor spilled to disk.
+	MaxDisk int64
+}
+
+// Add accumulates the statistics of another processor into s. Peak usages are
+// summed, since the processors can run concurrently.
+func (s *ProcessorStats) Add(other ProcessorStats) {
+	s.InputRows += other.InputRows
+	s.OutputRows += other.OutputRows
+	s.KVBytesRead += other.KVBytesRead
+	s.Time += other.Time
+	s.MaxMemory += other.MaxMemory
+	s.MaxDisk += other.MaxDisk
+}
+
+// Details returns a human-readable description of the statistics, one line
+// per statistic. Statistics that don't apply (e.g. KV bytes for processors
+// that don't read from KV) are omitted.
+func (s *ProcessorStats) Details() []string {
+	details := []string{
+		fmt.Sprintf("rows in: %d", s.InputRows),
+		fmt.Sprintf("rows out: %d", s.OutputRows),
+	}
+	if s.KVBytesRead > 0 {
+		details = append(details, fmt.Sprintf("KV bytes read: %s", humanizeutil.IBytes(s.KVBytesRead)))
+	}
+	details = append(details, fmt.Sprintf("time: %s", s.Time))
+	if s.MaxMemory > 0 {
+		details = append(details, fmt.Sprintf("max memory: %s", humanizeutil.IBytes(s.MaxMemory)))
+	}
+	if s.MaxDisk > 0 {
+		details = append(details, fmt.Sprintf("max disk: %s", humanizeutil.IBytes(s.MaxDisk)))
+	}
+	return details
+}
+
+// Tags used to record the ProcessorStats on a span.
+const (
+	processorIDTagKey = "cockroach.processor.id"
+	inputRowsTagKey   = "cockroach.processor.input_rows"
+	outputRowsTagKey  = "cockroach.processor.output_rows"
+	kvBytesReadTagKey = "cockroach.processor.kv_bytes_read"
+	timeTagKey        = "cockroach.processor.time_nanos"
+	maxMemoryTagKey   = "cockroach.processor.max_memory"
+	maxDiskTagKey     = "cockroach.processor.max_disk"
+)
+
+func (s *ProcessorStats) setSpanTags(sp opentracing.Span, processorID int32) {
+	sp.SetTag(processorIDTagKey, processorID)
+	sp.SetTag(inputRowsTagKey, s.InputRows)
+	sp.SetTag(outputRowsTagKey, s.OutputRows)
+	sp.SetTag(kvBytesReadTagKey, s.KVBytesRead)
+	sp.SetTag(timeTagKey, s.Time.Nanoseconds())
+	sp.SetTag(maxMemoryTagKey, s.MaxMemory)
+	sp.SetTag(maxDiskTagKey, s.MaxDisk)
+}
+
+// ExtractProcessorStats retrieves the statistics recorded by the processors
+// of a traced flow, indexed by ProcessorSpec.ProcessorID. Spans which don't
+// contain processor statistics are ignored.
+func ExtractProcessorStats(spans []tracing.RecordedSpan) (map[int32]ProcessorStats, error) {
+	res := make(map[int32]ProcessorStats)
+	for i := range spans {
+		tags := spans[i].Tags
+		idStr, ok := tags[processorIDTagKey]
+		if !ok {
+			continue
+		}
+		var vals [7]int64
+		for j, key := range []string{
+			processorIDTagKey, inputRowsTagKey, outputRowsTagKey, kvBytesReadTagKey,
+			timeTagKey, maxMemoryTagKey, maxDiskTagKey,
+		} {
+			v, err := strconv.ParseInt(tags[key], 10, 64)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid stats for processor %s", idStr)
+			}
+			vals[j] = v
+		}
+		res[int32(vals[0])] = ProcessorStats{
+			InputRows:   vals[1],
+			OutputRows:  vals[2],
+			KVBytesRead: vals[3],
+			Time:        time.Duration(vals[4]),
+			MaxMemory:   vals[5],
+			MaxDisk:     vals[6],
+		}
+	}
+	return res, nil
+}
+
+// kvReader is implemented by processors that read from the KV layer.
+type kvReader interface {
+	// kvBytesRead returns the number of bytes read from the KV layer so far.
+	kvBytesRead() int64
+}
+
+// statsProcessor wraps a Processor and collects ProcessorStats for it. The
+// inputs and output of the wrapped processor are wrapped as well, in order to
+// count rows, and the processor runs against memory and disk monitors of its
+// own in order to measure its peak usage.
+//
+// The statistics are recorded on a span when the wrapped processor closes its
+// output, and that span's recording is pushed to the output as trace data
+// right before the output is closed.
+type statsProcessor struct {
+	// The following are accessed atomically, since rows can be consumed from
+	// (or metadata pushed to) multiple goroutines while draining.
+	inputRows  int64
+	outputRows int64
+	inputWait  int64 // nanoseconds
+
+	Processor
+
+	processorID int32
+	// flowCtx is a copy of the flow's FlowCtx which refers to memMonitor and
+	// diskMonitor; it is to be passed to the wrapped processor.
+	flowCtx FlowCtx
+
+	// parentMemMonitor and parentDiskMonitor are the monitors of the flow;
+	// parentDiskMonitor can be nil.
+	parentMemMonitor  *mon.BytesMonitor
+	parentDiskMonitor *mon.BytesMonitor
+	memMonitor        mon.BytesMonitor
+	diskMonitor       mon.BytesMonitor
+
+	span  opentracing.Span
+	start time.Time
+}
+
+var _ Processor = &statsProcessor{}
+
+// newStatsProcessor creates a statsProcessor. The wrapped processor must be
+// created using sp.flowCtx, the inputs returned by sp.wrapInputs and the
+// outputs returned by sp.wrapOutputs, and must then be set in sp.Processor.
+func newStatsProcessor(flowCtx *FlowCtx, processorID int32) *statsProcessor {
+	sp := &statsProcessor{
+		processorID:       processorID,
+		flowCtx:           *flowCtx,
+		parentMemMonitor:  flowCtx.EvalCtx.Mon,
+		parentDiskMonitor: flowCtx.diskMonitor,
+	}
+	sp.memMonitor = mon.MakeMonitorInheritWithLimit(
+		"processor-stats", math.MaxInt64, flowCtx.EvalCtx.Mon,
+	)
+	sp.flowCtx.EvalCtx.Mon = &sp.memMonitor
+	if flowCtx.diskMonitor != nil {
+		sp.diskMonitor = mon.MakeMonitorInheritWithLimit(
+			"processor-stats-disk", math.MaxInt64, flowCtx.diskMonitor,
+		)
+		sp.flowCtx.diskMonitor = &sp.diskMonitor
+	}
+	return sp
+}
+
+func (sp *statsProcessor) wrapInputs(inputs []RowSource) []RowSource {
+	res := make([]RowSource, len(inputs))
+	for i, input := range inputs {
+		res[i] = &statsRowSource{RowSource: input, sp: sp}
+	}
+	return res
+}
+
+func (sp *statsProcessor) wrapOutputs(outputs []RowReceiver) []RowReceiver {
+	res := make([]RowReceiver, len(outputs))
+	for i, output := range outputs {
+		res[i] = &statsRowReceiver{RowReceiver: output, sp: sp}
+	}
+	return res
+}
+
+// Run is part of the Processor interface.
+func (sp *statsProcessor) Run(ctx context.Context, wg *sync.WaitGroup) {
+	if wg != nil {
+		defer wg.Done()
+	}
+	ctx, sp.span = processorSpan(ctx, "processor stats")
+	defer tracing.FinishSpan(sp.span)
+
+	sp.memMonitor.Start(ctx, sp.parentMemMonitor, mon.BoundAccount{})
+	defer sp.memMonitor.Stop(ctx)
+	if sp.parentDiskMonitor != nil {
+		sp.diskMonitor.Start(ctx, sp.parentDiskMonitor, mon.BoundAccount{})
+		defer sp.diskMonitor.Stop(ctx)
+	}
+
+	sp.start = timeutil.Now()
+	sp.Processor.Run(ctx, nil /* wg */)
+}
+
+// stats returns the statistics collected so far.
+func (sp *statsProcessor) stats() ProcessorStats {
+	s := ProcessorStats{
+		InputRows:  atomic.LoadInt64(&sp.inputRows),
+		OutputRows: atomic.LoadInt64(&sp.outputRows),
+		Time:       timeutil.Since(sp.start) - time.Duration(atomic.LoadInt64(&sp.inputWait)),
+		MaxMemory:  sp.memMonitor.MaximumBytes(),
+	}
+	if s.Time < 0 {
+		// Inputs can be drained concurrently, in which case the waits overlap.
+		s.Time = 0
+	}
+	if sp.parentDiskMonitor != nil {
+		s.MaxDisk = sp.diskMonitor.MaximumBytes()
+	}
+	if r, ok := sp.Processor.(kvReader); ok {
+		s.KVBytesRead = r.kvBytesRead()
+	}
+	return s
+}
+
+// statsRowSource is a RowSource that counts the rows read from the wrapped
+// RowSource and the time spent waiting for them.
+type statsRowSource struct {
+	RowSource
+	sp *statsProcessor
+}
+
+var _ RowSource = &statsRowSource{}
+
+// Next is part of the RowSource interface.
+func (s *statsRowSource) Next() (sqlbase.EncDatumRow, ProducerMetadata) {
+	start := timeutil.Now()
+	row, meta := s.RowSource.Next()
+	atomic.AddInt64(&s.sp.inputWait, int64(timeutil.Since(start)))
+	if row != nil {
+		atomic.AddInt64(&s.sp.inputRows, 1)
+	}
+	return row, meta
+}
+
+// statsRowReceiver is a RowReceiver that counts the rows pushed to the wrapped
+// RowReceiver, and which sends the statistics of the processor before the
+// wrapped RowReceiver is closed.
+type statsRowReceiver struct {
+	RowReceiver
+	sp *statsProcessor
+}
+
+var _ RowReceiver = &statsRowReceiver{}
+
+// Push is part of the RowReceiver interface.
+func (s *statsRowReceiver) Push(row sqlbase.EncDatumRow, meta ProducerMetadata) ConsumerStatus {
+	if row != nil {
+		atomic.AddInt64(&s.sp.outputRows, 1)
+	}
+	return s.RowReceiver.Push(row, meta)
+}
+
+// ProducerDone is part of the RowReceiver interface.
+func (s *statsRowReceiver) ProducerDone() {
+	if sp := s.sp.span; sp != nil {
+		stats := s.sp.stats()
+		stats.setSpanTags(sp, s.sp.processorID)
+		if rec := tracing.GetRecording(sp); rec != nil {
+			s.RowReceiver.Push(nil /* row */, ProducerMetadata{TraceData: rec})
+		}
+	}
+	s.RowReceiver.ProducerDone()
+}
@@ -0,0 +1,95 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package distsqlrun
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/tracing"
+)
+
+func TestStatsProcessor(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx, _, cancel := tracing.ContextWithRecordingSpan(context.Background(), "test")
+	defer cancel()
+
+	evalCtx := tree.MakeTestingEvalContext()
+	defer evalCtx.Stop(ctx)
+	flowCtx := FlowCtx{
+		Settings: cluster.MakeTestingClusterSettings(),
+		EvalCtx:  evalCtx,
+	}
+
+	in := NewRowBuffer(twoIntCols, genEncDatumRowsInt([][]int{
+		{1, 10}, {2, 20}, {3, 30}, {4, 40}, {5, 50}, {6, 60},
+	}), RowBufferArgs{})
+	out := &RowBuffer{}
+
+	const processorID = 7
+	sp := newStatsProcessor(&flowCtx, processorID)
+	post := PostProcessSpec{Filter: Expression{Expr: "@1 % 2 = 0"}}
+	noop, err := newNoopProcessor(
+		&sp.flowCtx, sp.wrapInputs([]RowSource{in})[0], &post, sp.wrapOutputs([]RowReceiver{out})[0],
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sp.Processor = noop
+	sp.Run(ctx, nil /* wg */)
+
+	if !out.ProducerClosed {
+		t.Fatalf("output RowReceiver not closed")
+	}
+
+	var spans []tracing.RecordedSpan
+	var rows int
+	for {
+		row, meta := out.Next()
+		if row == nil && meta.Empty() {
+			break
+		}
+		if row != nil {
+			rows++
+		}
+		spans = append(spans, meta.TraceData...)
+	}
+	if rows != 3 {
+		t.Fatalf("expected 3 rows, got %d", rows)
+	}
+
+	stats, err := ExtractProcessorStats(spans)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 1 {
+		t.Fatalf("expected stats for one processor, got %v", stats)
+	}
+	s, ok := stats[processorID]
+	if !ok {
+		t.Fatalf("no stats for processor %d: %v", processorID, stats)
+	}
+	if s.InputRows != 6 || s.OutputRows != 3 {
+		t.Errorf("expected 6 input rows and 3 output rows, got %+v", s)
+	}
+	if s.KVBytesRead != 0 || s.MaxDisk != 0 {
+		t.Errorf("unexpected KV or disk usage: %+v", s)
+	}
+}
@@ -46,6 +46,7 @@ type tableReader struct {
 }
 
 var _ Processor = &tableReader{}
+var _ kvReader = &tableReader{}
 
 // newTableReader creates a tableReader.
 func newTableReader(
@@ -59,13 +60,42 @@ func newTableReader(
 		tableID: spec.Table.ID,
 	}
 
+	tr.limitHint = tableReaderLimitHint(spec, post)
+
+	types := make([]sqlbase.ColumnType, len(spec.Table.Columns))
+	for i := range types {
+		types[i] = spec.Table.Columns[i].Type
+	}
+	if err := tr.init(post, types, flowCtx, output); err != nil {
+		return nil, err
+	}
+
+	desc := spec.Table
+	if _, _, err := initRowFetcher(
+		&tr.fetcher, &desc, int(spec.IndexIdx), spec.Reverse, tr.out.neededColumns(), &tr.alloc,
+	); err != nil {
+		return nil, err
+	}
+
+	tr.spans = make(roachpb.Spans, len(spec.Spans))
+	for i, s := range spec.Spans {
+		tr.spans[i] = s.Span
+	}
+
+	return tr, nil
+}
+
+// tableReaderLimitHint returns the number of rows that a table reader asks
+// for in its first KV batch, or 0 for no limit.
+func tableReaderLimitHint(spec *TableReaderSpec, post *PostProcessSpec) int64 {
+	var limitHint int64
 	// We ignore any limits that are higher than this value to avoid any
 	// overflows.
 	const overflowProtection = 1000000000
 	if post.Limit != 0 && post.Limit <= overflowProtection {
 		// In this case the ProcOutputHelper will tell us to stop once we emit
 		// enough rows.
-		tr.limitHint = int64(post.Limit)
+		limitHint = int64(post.Limit)
 	} else if spec.LimitHint != 0 && spec.LimitHint <= overflowProtection {
 		// If it turns out that limiHint rows are sufficient for our consumer, we
 		// want to avoid asking for another batch. Currently, the only way for us to
@@ -84,35 +114,15 @@ func newTableReader(
 		// reasoning goes out the door.
 		//
 		// TODO(radu, andrei): work on a real mechanism for limits.
-		tr.limitHint = spec.LimitHint + rowChannelBufSize + 1
+		limitHint = spec.LimitHint + rowChannelBufSize + 1
 	}
 
 	if post.Filter.Expr != "" {
 		// We have a filter so we will likely need to read more rows.
-		tr.limitHint *= 2
-	}
-
-	types := make([]sqlbase.ColumnType, len(spec.Table.Columns))
-	for i := range types {
-		types[i] = spec.Table.Columns[i].Type
-	}
-	if err := tr.init(post, types, flowCtx, output); err != nil {
-		return nil, err
-	}
-
-	desc := spec.Table
-	if _, _, err := initRowFetcher(
-		&tr.fetcher, &desc, int(spec.IndexIdx), spec.Reverse, tr.out.neededColumns(), &tr.alloc,
-	); err != nil {
-		return nil, err
-	}
-
-	tr.spans = make(roachpb.Spans, len(spec.Spans))
-	for i, s := range spec.Spans {
-		tr.spans[i] = s.Span
+		limitHint *= 2
 	}
 
-	return tr, nil
+	return limitHint
 }
 
 func initRowFetcher(
@@ -150,30 +160,46 @@ func initRowFetcher(
 	return index, isSecondaryIndex, nil
 }
 
-// sendMisplannedRangesMetadata sends information about the non-local ranges
-// that were read by this tableReader. This should be called after the fetcher
-// was used to read everything this tableReader was supposed to read.
-func (tr *tableReader) sendMisplannedRangesMetadata(ctx context.Context) {
-	rangeInfos := tr.fetcher.GetRangeInfo()
-	var misplannedRanges []roachpb.RangeInfo
+// misplannedRanges filters the given ranges, which were read by a processor
+// on the given node, down to the ones whose lease holder is on another node.
+// Their descriptors are sent to the gateway as metadata, so that it can update
+// its range cache.
+func misplannedRanges(
+	ctx context.Context, rangeInfos []roachpb.RangeInfo, nodeID roachpb.NodeID,
+) []roachpb.RangeInfo {
+	var misplanned []roachpb.RangeInfo
 	for _, ri := range rangeInfos {
-		if ri.Lease.Replica.NodeID != tr.flowCtx.nodeID {
-			misplannedRanges = append(misplannedRanges, ri)
+		if ri.Lease.Replica.NodeID != nodeID {
+			misplanned = append(misplanned, ri)
 		}
 	}
-	if len(misplannedRanges) != 0 {
+	if len(misplanned) != 0 {
 		var msg string
-		if len(misplannedRanges) < 3 {
-			msg = fmt.Sprintf("%+v", misplannedRanges[0].Desc)
+		if len(misplanned) < 3 {
+			msg = fmt.Sprintf("%+v", misplanned[0].Desc)
 		} else {
-			msg = fmt.Sprintf("%+v...", misplannedRanges[:3])
+			msg = fmt.Sprintf("%+v...", misplanned[:3])
 		}
-		log.VEventf(ctx, 2, "tableReader pushing metadata about misplanned ranges: %s",
-			msg)
-		tr.out.output.Push(nil /* row */, ProducerMetadata{Ranges: misplannedRanges})
+		log.VEventf(ctx, 2, "pushing metadata about misplanned ranges: %s", msg)
+	}
+	return misplanned
+}
+
+// sendMisplannedRangesMetadata sends information about the non-local ranges
+// that were read by this tableReader. This should be called after the fetcher
+// was used to read everything this tableReader was supposed to read.
+func (tr *tableReader) sendMisplannedRangesMetadata(ctx context.Context) {
+	ranges := misplannedRanges(ctx, tr.fetcher.GetRangeInfo(), tr.flowCtx.nodeID)
+	if len(ranges) != 0 {
+		tr.out.output.Push(nil /* row */, ProducerMetadata{Ranges: ranges})
 	}
 }
 
+// kvBytesRead is part of the kvReader interface.
+func (tr *tableReader) kvBytesRead() int64 {
+	return tr.fetcher.BytesRead()
+}
+
 // Run is part of the processor interface.
 func (tr *tableReader) Run(ctx context.Context, wg *sync.WaitGroup) {
 	if wg != nil {
@@ -0,0 +1,396 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package distsqlrun
+
+import (
+	"sync"
+
+	"github.com/cockroachdb/cockroach/pkg/internal/client"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/tracing"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+// tableWriterBatchSize is the number of rows that a tableWriter buffers
+// before sending their writes to KV.
+const tableWriterBatchSize = 1000
+
+// tableWriter is the processor core type that performs the writes of an
+// INSERT, UPDATE or DELETE statement (see TableWriterSpec). The writes are
+// performed through the flow's transaction, which collects the spans of the
+// intents it lays down; these are sent back to the gateway as metadata, so
+// that the root transaction can resolve them when it finishes.
+type tableWriter struct {
+	processorBase
+
+	flowCtx *FlowCtx
+	// input is a row source without metadata; the metadata is directed straight
+	// to out.output.
+	input NoMetadataRowSource
+	// rawInput is the true input, not wrapped in a NoMetadataRowSource.
+	rawInput   RowSource
+	inputTypes []sqlbase.ColumnType
+
+	typ       TableWriterSpec_Type
+	table     *sqlbase.TableDescriptor
+	returning bool
+
+	ri sqlbase.RowInserter
+	ru sqlbase.RowUpdater
+	rd sqlbase.RowDeleter
+
+	// writeColIDtoRowIndex maps the IDs of the columns whose (new) values are
+	// known for a written row to their position in writeRow.
+	writeColIDtoRowIndex map[sqlbase.ColumnID]int
+	writeRow             tree.Datums
+	// tableRow holds the values of the (public) columns of the table for the
+	// row being written; it is used to evaluate the CHECK constraints and to
+	// output the row when returning is set.
+	tableRow   sqlbase.EncDatumRow
+	tableTypes []sqlbase.ColumnType
+
+	checks []exprHelper
+
+	evalCtx    *tree.EvalContext
+	datumAlloc sqlbase.DatumAlloc
+}
+
+var _ Processor = &tableWriter{}
+
+func newTableWriter(
+	flowCtx *FlowCtx,
+	spec *TableWriterSpec,
+	input RowSource,
+	post *PostProcessSpec,
+	output RowReceiver,
+) (*tableWriter, error) {
+	tw := &tableWriter{
+		flowCtx:    flowCtx,
+		input:      MakeNoMetadataRowSource(input, output),
+		rawInput:   input,
+		inputTypes: input.Types(),
+		typ:        spec.Type,
+		table:      &spec.Table,
+		returning:  spec.Returning,
+		evalCtx:    flowCtx.NewEvalCtx(),
+	}
+
+	fkTables := make(sqlbase.TableLookupsByID, len(spec.FkTables)+len(spec.AddingFkTables))
+	for i := range spec.FkTables {
+		fkTables[spec.FkTables[i].ID] = sqlbase.TableLookup{Table: &spec.FkTables[i]}
+	}
+	for _, id := range spec.AddingFkTables {
+		fkTables[id] = sqlbase.TableLookup{IsAdding: true}
+	}
+
+	// The transaction remembers the intents it writes so that they can be
+	// reported to the gateway.
+	flowCtx.txn.CollectIntents()
+
+	var err error
+	var numInputCols int
+	switch spec.Type {
+	case TableWriterSpec_INSERT:
+		var insertCols []sqlbase.ColumnDescriptor
+		if insertCols, err = tw.columnsByID(spec.InsertCols); err != nil {
+			return nil, err
+		}
+		if tw.ri, err = sqlbase.MakeRowInserter(
+			flowCtx.txn, tw.table, fkTables, insertCols, sqlbase.CheckFKs, &tw.datumAlloc,
+		); err != nil {
+			return nil, err
+		}
+		tw.writeColIDtoRowIndex = tw.ri.InsertColIDtoRowIndex
+		numInputCols = len(insertCols)
+
+	case TableWriterSpec_UPDATE:
+		var updateCols, requestedCols []sqlbase.ColumnDescriptor
+		if updateCols, err = tw.columnsByID(spec.UpdateCols); err != nil {
+			return nil, err
+		}
+		if requestedCols, err = tw.columnsByID(spec.RequestedCols); err != nil {
+			return nil, err
+		}
+		if tw.ru, err = sqlbase.MakeRowUpdater(
+			flowCtx.txn, tw.table, fkTables, updateCols, requestedCols,
+			sqlbase.RowUpdaterDefault, &tw.datumAlloc,
+		); err != nil {
+			return nil, err
+		}
+		tw.writeColIDtoRowIndex = tw.ru.FetchColIDtoRowIndex
+		numInputCols = len(tw.ru.FetchCols) + len(tw.ru.UpdateCols)
+
+	case TableWriterSpec_DELETE:
+		var requestedCols []sqlbase.ColumnDescriptor
+		if requestedCols, err = tw.columnsByID(spec.RequestedCols); err != nil {
+			return nil, err
+		}
+		if tw.rd, err = sqlbase.MakeRowDeleter(
+			flowCtx.txn, tw.table, fkTables, requestedCols, sqlbase.CheckFKs, &tw.datumAlloc,
+		); err != nil {
+			return nil, err
+		}
+		tw.writeColIDtoRowIndex = tw.rd.FetchColIDtoRowIndex
+		numInputCols = len(tw.rd.FetchCols)
+
+	default:
+		return nil, errors.Errorf("unknown table writer type %s", spec.Type)
+	}
+	if len(tw.inputTypes) != numInputCols {
+		return nil, errors.Errorf(
+			"table writer expected %d input columns, got %d", numInputCols, len(tw.inputTypes),
+		)
+	}
+
+	tw.tableTypes = make([]sqlbase.ColumnType, len(tw.table.Columns))
+	for i := range tw.table.Columns {
+		tw.tableTypes[i] = tw.table.Columns[i].Type
+	}
+	tw.tableRow = make(sqlbase.EncDatumRow, len(tw.tableTypes))
+
+	tw.checks = make([]exprHelper, len(spec.Checks))
+	for i := range spec.Checks {
+		if err := tw.checks[i].init(spec.Checks[i], tw.tableTypes, tw.evalCtx); err != nil {
+			return nil, err
+		}
+	}
+
+	outputTypes := []sqlbase.ColumnType{{SemanticType: sqlbase.ColumnType_INT}}
+	if tw.returning {
+		outputTypes = tw.tableTypes
+	}
+	if err := tw.init(post, outputTypes, flowCtx, output); err != nil {
+		return nil, err
+	}
+	return tw, nil
+}
+
+// columnsByID returns the descriptors of the columns of the table with the
+// given IDs. Columns that are being added are included.
+func (tw *tableWriter) columnsByID(ids []sqlbase.ColumnID) ([]sqlbase.ColumnDescriptor, error) {
+	cols := make([]sqlbase.ColumnDescriptor, len(ids))
+	for i, id := range ids {
+		col, err := tw.table.FindColumnByID(id)
+		if err != nil {
+			return nil, err
+		}
+		cols[i] = *col
+	}
+	return cols, nil
+}
+
+// Run is part of the processor interface.
+func (tw *tableWriter) Run(ctx context.Context, wg *sync.WaitGroup) {
+	if wg != nil {
+		defer wg.Done()
+	}
+
+	ctx = log.WithLogTag(ctx, "TableWriter", nil)
+	ctx, span := processorSpan(ctx, "table writer")
+	defer tracing.FinishSpan(span)
+
+	if log.V(2) {
+		log.Infof(ctx, "starting table writer run")
+		defer log.Infof(ctx, "exiting table writer run")
+	}
+
+	err := tw.mainLoop(ctx)
+	// The intents need to make it to the gateway regardless of whether the
+	// writes succeeded, so that they get cleaned up.
+	txn, intents := tw.flowCtx.txn.GetLeafState()
+	tw.out.output.Push(nil /* row */, ProducerMetadata{
+		TxnMeta: &RemoteProducerMetadata_TxnMeta{Txn: txn, Intents: intents},
+	})
+	DrainAndClose(ctx, tw.out.output, err, tw.rawInput)
+}
+
+// mainLoop writes all the input rows, in batches of tableWriterBatchSize,
+// outputting the written rows if returning is set and the number of rows
+// written otherwise.
+//
+// It returns once either all the input has been exhausted or the consumer
+// indicated that no more rows are needed. In any case, the caller is
+// responsible for draining and closing the producer and the consumer.
+func (tw *tableWriter) mainLoop(ctx context.Context) error {
+	txn := tw.flowCtx.txn
+	b := txn.NewBatch()
+	batchRows := 0
+	var count int64
+	datums := make(tree.Datums, len(tw.inputTypes))
+	for {
+		row, err := tw.input.NextRow()
+		if err != nil {
+			return err
+		}
+		if row == nil {
+			break
+		}
+		for i := range row {
+			if err := row[i].EnsureDecoded(&tw.inputTypes[i], &tw.datumAlloc); err != nil {
+				return err
+			}
+			datums[i] = row[i].Datum
+		}
+		if err := tw.processRow(ctx, b, datums); err != nil {
+			return err
+		}
+		count++
+		batchRows++
+		if batchRows == tableWriterBatchSize {
+			if err := tw.flush(ctx, txn, b); err != nil {
+				return err
+			}
+			b = txn.NewBatch()
+			batchRows = 0
+		}
+
+		if tw.returning {
+			if consumerStatus, err := tw.out.EmitRow(ctx, tw.tableRow); err != nil || consumerStatus != NeedMoreRows {
+				// The rows that were already output must have been written.
+				if err == nil && batchRows > 0 {
+					err = tw.flush(ctx, txn, b)
+				}
+				return err
+			}
+		}
+	}
+	if batchRows > 0 {
+		if err := tw.flush(ctx, txn, b); err != nil {
+			return err
+		}
+	}
+
+	if !tw.returning {
+		countRow := sqlbase.EncDatumRow{sqlbase.DatumToEncDatum(
+			sqlbase.ColumnType{SemanticType: sqlbase.ColumnType_INT}, tree.NewDInt(tree.DInt(count)),
+		)}
+		if _, err := tw.out.EmitRow(ctx, countRow); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// processRow validates the given input row and adds the writes for it to the
+// batch. tableRow is populated with the values of the written row if they are
+// needed.
+func (tw *tableWriter) processRow(ctx context.Context, b *client.Batch, datums tree.Datums) error {
+	switch tw.typ {
+	case TableWriterSpec_INSERT:
+		// Check to see if NULL is being inserted into any non-nullable column.
+		for _, col := range tw.table.Columns {
+			if !col.Nullable {
+				if i, ok := tw.ri.InsertColIDtoRowIndex[col.ID]; !ok || datums[i] == tree.DNull {
+					return sqlbase.NewNonNullViolationError(col.Name)
+				}
+			}
+		}
+		// Ensure that the values honor the specified column widths.
+		for i := range datums {
+			if err := sqlbase.CheckValueWidth(tw.ri.InsertCols[i].Type, datums[i], tw.ri.InsertCols[i].Name); err != nil {
+				return err
+			}
+		}
+		tw.writeRow = datums
+		if err := tw.checkAndLoadTableRow(); err != nil {
+			return err
+		}
+		return tw.ri.InsertRow(ctx, b, datums, false /* ignoreConflicts */, false /* traceKV */)
+
+	case TableWriterSpec_UPDATE:
+		oldValues := datums[:len(tw.ru.FetchCols)]
+		updateValues := datums[len(tw.ru.FetchCols):]
+		for i, col := range tw.ru.UpdateCols {
+			if err := sqlbase.CheckValueWidth(col.Type, updateValues[i], col.Name); err != nil {
+				return err
+			}
+			if !col.Nullable && updateValues[i] == tree.DNull {
+				return sqlbase.NewNonNullViolationError(col.Name)
+			}
+		}
+		if tw.writeRow == nil {
+			tw.writeRow = make(tree.Datums, len(tw.ru.FetchCols))
+		}
+		copy(tw.writeRow, oldValues)
+		for i, col := range tw.ru.UpdateCols {
+			tw.writeRow[tw.ru.FetchColIDtoRowIndex[col.ID]] = updateValues[i]
+		}
+		if err := tw.checkAndLoadTableRow(); err != nil {
+			return err
+		}
+		_, err := tw.ru.UpdateRow(ctx, b, oldValues, updateValues, false /* traceKV */)
+		return err
+
+	case TableWriterSpec_DELETE:
+		tw.writeRow = datums
+		if tw.returning {
+			tw.loadTableRow()
+		}
+		return tw.rd.DeleteRow(ctx, b, datums, false /* traceKV */)
+
+	default:
+		return errors.Errorf("unknown table writer type %s", tw.typ)
+	}
+}
+
+// loadTableRow populates tableRow from writeRow. Columns for which writeRow
+// has no value are NULL.
+func (tw *tableWriter) loadTableRow() {
+	for i := range tw.table.Columns {
+		d := tree.Datum(tree.DNull)
+		if j, ok := tw.writeColIDtoRowIndex[tw.table.Columns[i].ID]; ok {
+			d = tw.writeRow[j]
+		}
+		tw.tableRow[i] = sqlbase.DatumToEncDatum(tw.tableTypes[i], d)
+	}
+}
+
+// checkAndLoadTableRow populates tableRow and verifies that it satisfies the
+// CHECK constraints of the table.
+func (tw *tableWriter) checkAndLoadTableRow() error {
+	if len(tw.checks) == 0 && !tw.returning {
+		return nil
+	}
+	tw.loadTableRow()
+	for i := range tw.checks {
+		d, err := tw.checks[i].eval(tw.tableRow)
+		if err != nil {
+			return err
+		}
+		if res, err := tree.GetBool(d); err != nil {
+			return err
+		} else if !res && d != tree.DNull {
+			// Failed to satisfy CHECK constraint.
+			return pgerror.NewErrorf(pgerror.CodeCheckViolationError,
+				"failed to satisfy CHECK constraint (%s)", tw.table.Checks[i].Expr)
+		}
+	}
+	return nil
+}
+
+// flush sends the writes accumulated in the batch.
+func (tw *tableWriter) flush(ctx context.Context, txn *client.Txn, b *client.Batch) error {
+	if err := txn.Run(ctx, b); err != nil {
+		if tw.typ == TableWriterSpec_DELETE {
+			return err
+		}
+		return sqlbase.ConvertBatchError(ctx, tw.table, b)
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package distsqlrun
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/internal/client"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/sqlutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func TestTableWriter(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	s, sqlDB, kvDB := serverutils.StartServer(t, base.TestServerArgs{})
+	defer s.Stopper().Stop(context.TODO())
+
+	sqlutils.CreateTable(t, sqlDB, "t",
+		"a INT PRIMARY KEY, b INT NOT NULL CHECK (b > 0)",
+		10,
+		sqlutils.ToRowFn(sqlutils.RowIdxFn, sqlutils.RowIdxFn))
+
+	td := sqlbase.GetTableDescriptor(kvDB, "test", "t")
+	cols := []sqlbase.ColumnID{td.Columns[0].ID, td.Columns[1].ID}
+	checks := []Expression{{Expr: "@2 > 0"}}
+
+	row := func(a, b tree.Datum) sqlbase.EncDatumRow {
+		return sqlbase.EncDatumRow{
+			sqlbase.DatumToEncDatum(intType, a), sqlbase.DatumToEncDatum(intType, b),
+		}
+	}
+	i := func(v int) tree.Datum { return tree.NewDInt(tree.DInt(v)) }
+
+	testCases := []struct {
+		description string
+		spec        TableWriterSpec
+		input       sqlbase.EncDatumRows
+		outputTypes []sqlbase.ColumnType
+		expected    string
+		expectedErr string
+		// numKeys is the number of keys of the table, as seen by the transaction
+		// after the writes.
+		numKeys int
+	}{
+		{
+			description: "insert",
+			spec: TableWriterSpec{
+				Type:       TableWriterSpec_INSERT,
+				InsertCols: cols,
+				Checks:     checks,
+			},
+			input:       sqlbase.EncDatumRows{row(i(11), i(1)), row(i(12), i(2))},
+			outputTypes: oneIntCol,
+			expected:    "[[2]]",
+			numKeys:     12,
+		},
+		{
+			description: "insert returning",
+			spec: TableWriterSpec{
+				Type:       TableWriterSpec_INSERT,
+				InsertCols: cols,
+				Checks:     checks,
+				Returning:  true,
+			},
+			input:       sqlbase.EncDatumRows{row(i(11), i(1)), row(i(12), i(2))},
+			outputTypes: twoIntCols,
+			expected:    "[[11 1] [12 2]]",
+			numKeys:     12,
+		},
+		{
+			description: "insert duplicate",
+			spec: TableWriterSpec{
+				Type:       TableWriterSpec_INSERT,
+				InsertCols: cols,
+			},
+			input:       sqlbase.EncDatumRows{row(i(1), i(1))},
+			expectedErr: "duplicate key value",
+		},
+		{
+			description: "insert violating CHECK",
+			spec: TableWriterSpec{
+				Type:       TableWriterSpec_INSERT,
+				InsertCols: cols,
+				Checks:     checks,
+			},
+			input:       sqlbase.EncDatumRows{row(i(11), i(0))},
+			expectedErr: `failed to satisfy CHECK constraint \(b > 0\)`,
+		},
+		{
+			description: "insert violating NOT NULL",
+			spec: TableWriterSpec{
+				Type:       TableWriterSpec_INSERT,
+				InsertCols: cols,
+				Checks:     checks,
+			},
+			input:       sqlbase.EncDatumRows{row(i(11), tree.DNull)},
+			expectedErr: `null value in column "b" violates not-null constraint`,
+		},
+		{
+			description: "update",
+			spec: TableWriterSpec{
+				Type:          TableWriterSpec_UPDATE,
+				UpdateCols:    cols[1:],
+				RequestedCols: cols,
+				Checks:        checks,
+				Returning:     true,
+			},
+			input: sqlbase.EncDatumRows{
+				append(row(i(1), i(1)), sqlbase.DatumToEncDatum(intType, i(100))),
+			},
+			outputTypes: twoIntCols,
+			expected:    "[[1 100]]",
+			numKeys:     10,
+		},
+		{
+			description: "delete",
+			spec: TableWriterSpec{
+				Type:          TableWriterSpec_DELETE,
+				RequestedCols: cols,
+			},
+			input:       sqlbase.EncDatumRows{row(i(1), i(1)), row(i(2), i(2)), row(i(3), i(3))},
+			outputTypes: oneIntCol,
+			expected:    "[[3]]",
+			numKeys:     7,
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.description, func(t *testing.T) {
+			ctx := context.Background()
+			evalCtx := tree.MakeTestingEvalContext()
+			defer evalCtx.Stop(ctx)
+			// The writes are performed by a leaf transaction, which doesn't go
+			// through a TxnCoordSender. Its intents are handed back to the root
+			// transaction, which is rolled back at the end of each test case.
+			txn := client.NewTxn(kvDB, s.NodeID())
+			defer func() { _ = txn.Rollback(ctx) }()
+			if err := txn.AnchorForRemoteWrites(ctx, td.PrimaryIndexSpan().Key); err != nil {
+				t.Fatal(err)
+			}
+			// Pass a DB without a TxnCoordSender.
+			leaf := client.NewTxnWithProto(
+				client.NewDB(s.DistSender(), s.Clock()), s.NodeID(), *txn.Proto(),
+			)
+			leaf.AcceptUnhandledRetryableErrors()
+			flowCtx := FlowCtx{
+				EvalCtx:  evalCtx,
+				Settings: s.ClusterSettings(),
+				txn:      leaf,
+			}
+
+			in := NewRowBuffer(twoIntCols, c.input, RowBufferArgs{})
+			if c.spec.Type == TableWriterSpec_UPDATE {
+				in = NewRowBuffer(threeIntCols, c.input, RowBufferArgs{})
+			}
+			out := &RowBuffer{}
+			spec := c.spec
+			spec.Table = *td
+			tw, err := newTableWriter(&flowCtx, &spec, in, &PostProcessSpec{}, out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tw.Run(ctx, nil)
+
+			if !in.Done {
+				t.Fatal("tableWriter didn't consume all the rows")
+			}
+			if !out.ProducerClosed {
+				t.Fatalf("output RowReceiver not closed")
+			}
+
+			var res sqlbase.EncDatumRows
+			var txnMeta *RemoteProducerMetadata_TxnMeta
+			var resErr error
+			for {
+				row, meta := out.Next()
+				if meta.TxnMeta != nil {
+					txnMeta = meta.TxnMeta
+				}
+				if meta.Err != nil && resErr == nil {
+					resErr = meta.Err
+				}
+				if row == nil && meta.Empty() {
+					break
+				}
+				if row != nil {
+					res = append(res, row)
+				}
+			}
+
+			if txnMeta == nil {
+				t.Fatal("tableWriter didn't report the state of its transaction")
+			}
+			if err := txn.AugmentLeafState(ctx, txnMeta.Txn, txnMeta.Intents); err != nil {
+				t.Fatal(err)
+			}
+			if c.expectedErr != "" {
+				if !testutils.IsError(resErr, c.expectedErr) {
+					t.Fatalf("expected error %q, got %v", c.expectedErr, resErr)
+				}
+				return
+			}
+			if resErr != nil {
+				t.Fatal(resErr)
+			}
+			if result := res.String(c.outputTypes); result != c.expected {
+				t.Errorf("invalid results: %s, expected %s", result, c.expected)
+			}
+			if len(txnMeta.Intents) == 0 {
+				t.Errorf("expected the intents of the writes to be reported")
+			}
+
+			// The writes are visible to the transaction.
+			span := td.PrimaryIndexSpan()
+			kvs, err := leaf.Scan(ctx, span.Key, span.EndKey, 0 /* maxRows */)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(kvs) != c.numKeys {
+				t.Errorf("expected %d keys, found %d", c.numKeys, len(kvs))
+			}
+		})
+	}
+}
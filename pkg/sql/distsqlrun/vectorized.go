@@ -0,0 +1,876 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package distsqlrun
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/exec"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/util/mon"
+	"github.com/cockroachdb/cockroach/pkg/util/tracing"
+)
+
+// This file contains the glue between flows and the vectorized execution
+// engine (see the exec package). When the sql.distsql.vectorize.enabled
+// setting is on, a flow whose processors can all be converted to exec
+// operators is run as trees of operators instead:
+//
+//  - a processor whose only output is a local stream that is the only stream
+//    of an input of its consumer is fused with the consumer: its operator
+//    feeds the consumer's operator directly.
+//  - the tree of operators of any other processor (a "root") is run by a
+//    materializer, which converts the batches of the root into rows and sends
+//    them to the outputs of the processor.
+//  - the inputs of a tree that come from other flows or from other roots are
+//    converted into batches by columnarizers.
+//
+// Flows that contain any processor, post-processing expression or column type
+// that the vectorized engine doesn't support are run by row processors.
+
+// vectorizedRoot is a tree of operators that replaces a processor, and the
+// processors fused with it.
+type vectorizedRoot struct {
+	spec  *ProcessorSpec
+	op    exec.Operator
+	types []sqlbase.ColumnType
+	// columnarizers are the leaves of the tree that read rows from the input
+	// synchronizers of the processors.
+	columnarizers []*columnarizer
+	// metadataSources are the operators of the tree that produce metadata.
+	metadataSources []metadataSource
+	// acc accounts for the memory used by the operators of the tree.
+	acc mon.BoundAccount
+}
+
+// metadataSource is implemented by the operators that produce metadata.
+type metadataSource interface {
+	// drainMeta returns the metadata produced by the operator. It is called
+	// once the output of the operator is no longer needed.
+	drainMeta(ctx context.Context) []ProducerMetadata
+}
+
+// vectorizedPlanner converts the processors of a flow into trees of
+// operators.
+type vectorizedPlanner struct {
+	flowCtx *FlowCtx
+	spec    *FlowSpec
+	// fused maps the ID of each local stream whose producer is fused with its
+	// consumer to the index of the producer.
+	fused map[StreamID]int
+	// root is the tree being planned.
+	root *vectorizedRoot
+}
+
+// planVectorizedFlow converts the processors of a flow into trees of
+// operators, or returns an error if the vectorized engine doesn't support any
+// of them. The planning has no side effects on the flow, which can be set up
+// with row processors if it fails.
+func planVectorizedFlow(flowCtx *FlowCtx, spec *FlowSpec) ([]*vectorizedRoot, error) {
+	p := vectorizedPlanner{flowCtx: flowCtx, spec: spec, fused: make(map[StreamID]int)}
+
+	producers := make(map[StreamID]int)
+	for i := range spec.Processors {
+		ps := &spec.Processors[i]
+		if len(ps.Output) != 1 {
+			return nil, errors.Errorf("only single-output processors supported")
+		}
+		o := &ps.Output[0]
+		if o.Type == OutputRouterSpec_PASS_THROUGH && len(o.Streams) == 1 &&
+			o.Streams[0].Type == StreamEndpointSpec_LOCAL {
+			producers[o.Streams[0].StreamID] = i
+		}
+	}
+	isFused := make([]bool, len(spec.Processors))
+	for i := range spec.Processors {
+		for _, is := range spec.Processors[i].Input {
+			if len(is.Streams) != 1 || is.Streams[0].Type != StreamEndpointSpec_LOCAL {
+				continue
+			}
+			if producer, ok := producers[is.Streams[0].StreamID]; ok {
+				p.fused[is.Streams[0].StreamID] = producer
+				isFused[producer] = true
+			}
+		}
+	}
+
+	var roots []*vectorizedRoot
+	for i := range spec.Processors {
+		if isFused[i] {
+			continue
+		}
+		ps := &spec.Processors[i]
+		p.root = &vectorizedRoot{spec: ps, acc: flowCtx.EvalCtx.Mon.MakeBoundAccount()}
+		op, types, err := p.planProcessor(ps)
+		if err != nil {
+			return nil, err
+		}
+		p.root.op, p.root.types = op, types
+		roots = append(roots, p.root)
+	}
+	return roots, nil
+}
+
+// planProcessor returns the operator that produces the output of a processor,
+// along with the types of its columns.
+func (p *vectorizedPlanner) planProcessor(
+	ps *ProcessorSpec,
+) (exec.Operator, []sqlbase.ColumnType, error) {
+	inputs := make([]exec.Operator, len(ps.Input))
+	inputTypes := make([][]sqlbase.ColumnType, len(ps.Input))
+	for i := range ps.Input {
+		is := &ps.Input[i]
+		if len(is.Streams) == 1 {
+			if producer, ok := p.fused[is.Streams[0].StreamID]; ok {
+				var err error
+				inputs[i], inputTypes[i], err = p.planProcessor(&p.spec.Processors[producer])
+				if err != nil {
+					return nil, nil, err
+				}
+				continue
+			}
+		}
+		c, err := newColumnarizer(is)
+		if err != nil {
+			return nil, nil, err
+		}
+		p.root.columnarizers = append(p.root.columnarizers, c)
+		p.root.metadataSources = append(p.root.metadataSources, c)
+		inputs[i], inputTypes[i] = c, is.ColumnTypes
+	}
+
+	op, types, err := p.planCore(ps, inputs, inputTypes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return p.planPostProcess(op, types, &ps.Post)
+}
+
+// planCore returns the operator that implements the core of a processor,
+// along with the types of its columns.
+func (p *vectorizedPlanner) planCore(
+	ps *ProcessorSpec, inputs []exec.Operator, inputTypes [][]sqlbase.ColumnType,
+) (exec.Operator, []sqlbase.ColumnType, error) {
+	core := &ps.Core
+	numInputs := 1
+	switch {
+	case core.TableReader != nil:
+		numInputs = 0
+	case core.HashJoiner != nil:
+		numInputs = 2
+	}
+	if len(inputs) != numInputs {
+		return nil, nil, errors.Errorf("expected %d input(s), got %d", numInputs, len(inputs))
+	}
+
+	switch {
+	case core.Noop != nil:
+		return inputs[0], inputTypes[0], nil
+
+	case core.TableReader != nil:
+		s, err := newColBatchScan(p.flowCtx, core.TableReader, &ps.Post)
+		if err != nil {
+			return nil, nil, err
+		}
+		p.root.metadataSources = append(p.root.metadataSources, s)
+		return s, s.types, nil
+
+	case core.Aggregator != nil:
+		return p.planAggregator(core.Aggregator, inputs[0], inputTypes[0])
+
+	case core.HashJoiner != nil:
+		spec := core.HashJoiner
+		if spec.Type != JoinType_INNER || spec.OnExpr.Expr != "" || spec.MergedColumns {
+			return nil, nil, errors.Errorf("unsupported hash join %s", spec)
+		}
+		leftTypes, err := exec.FromColumnTypes(inputTypes[0])
+		if err != nil {
+			return nil, nil, err
+		}
+		rightTypes, err := exec.FromColumnTypes(inputTypes[1])
+		if err != nil {
+			return nil, nil, err
+		}
+		op, err := exec.NewHashJoiner(
+			inputs[0], inputs[1], leftTypes, rightTypes,
+			uint32sToInts(spec.LeftEqColumns), uint32sToInts(spec.RightEqColumns), &p.root.acc,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		types := make([]sqlbase.ColumnType, 0, len(inputTypes[0])+len(inputTypes[1]))
+		types = append(types, inputTypes[0]...)
+		types = append(types, inputTypes[1]...)
+		return op, types, nil
+	}
+	return nil, nil, errors.Errorf("unsupported processor core %T", core.GetValue())
+}
+
+// vectorizedAggregateFuncs maps the aggregate functions supported by the
+// vectorized engine to their implementation.
+var vectorizedAggregateFuncs = map[AggregatorSpec_Func]exec.AggregateFunc{
+	AggregatorSpec_IDENT:      exec.Ident,
+	AggregatorSpec_COUNT_ROWS: exec.CountRows,
+	AggregatorSpec_COUNT:      exec.Count,
+	AggregatorSpec_SUM:        exec.Sum,
+	AggregatorSpec_SUM_INT:    exec.Sum,
+	AggregatorSpec_AVG:        exec.Avg,
+	AggregatorSpec_MIN:        exec.Min,
+	AggregatorSpec_MAX:        exec.Max,
+}
+
+func (p *vectorizedPlanner) planAggregator(
+	spec *AggregatorSpec, input exec.Operator, inputTypes []sqlbase.ColumnType,
+) (exec.Operator, []sqlbase.ColumnType, error) {
+	typs, err := exec.FromColumnTypes(inputTypes)
+	if err != nil {
+		return nil, nil, err
+	}
+	aggs := make([]exec.AggregateSpec, len(spec.Aggregations))
+	types := make([]sqlbase.ColumnType, len(spec.Aggregations))
+	for i, a := range spec.Aggregations {
+		fn, ok := vectorizedAggregateFuncs[a.Func]
+		if !ok || a.Distinct || a.FilterColIdx != nil {
+			return nil, nil, errors.Errorf("unsupported aggregation %s", a)
+		}
+		argTypes := make([]sqlbase.ColumnType, len(a.ColIdx))
+		for j, c := range a.ColIdx {
+			argTypes[j] = inputTypes[c]
+		}
+		_, types[i], err = GetAggregateInfo(a.Func, argTypes...)
+		if err != nil {
+			return nil, nil, err
+		}
+		aggs[i].Func = fn
+		var argType exec.T
+		if fn != exec.CountRows {
+			if len(a.ColIdx) != 1 {
+				return nil, nil, errors.Errorf("unsupported aggregation %s", a)
+			}
+			aggs[i].ColIdx = int(a.ColIdx[0])
+			argType = typs[aggs[i].ColIdx]
+		}
+		// The vectorized function must produce the result type of the row
+		// function; for example, SUM of integers is a DECIMAL.
+		if t, err := exec.AggregateOutputType(fn, argType); err != nil {
+			return nil, nil, err
+		} else if t != exec.FromColumnType(types[i]) {
+			return nil, nil, errors.Errorf("unsupported aggregation %s", a)
+		}
+	}
+	op, err := exec.NewHashAggregator(input, typs, uint32sToInts(spec.GroupCols), aggs, &p.root.acc)
+	if err != nil {
+		return nil, nil, err
+	}
+	return op, types, nil
+}
+
+// planPostProcess returns the operator that applies a post-processing stage
+// to the output of op, whose columns have the given types, along with the
+// types of the columns of its output.
+func (p *vectorizedPlanner) planPostProcess(
+	op exec.Operator, types []sqlbase.ColumnType, post *PostProcessSpec,
+) (exec.Operator, []sqlbase.ColumnType, error) {
+	// The ProcOutputHelper parses and type-checks the expressions.
+	var h ProcOutputHelper
+	evalCtx := p.flowCtx.NewEvalCtx()
+	if err := h.Init(post, types, evalCtx, nil /* output */); err != nil {
+		return nil, nil, err
+	}
+	var err error
+	if h.filter != nil {
+		if op, err = planFilter(op, h.filter.expr, types, evalCtx); err != nil {
+			return nil, nil, err
+		}
+	}
+	if post.Offset != 0 {
+		if post.Offset > math.MaxInt32 {
+			return nil, nil, errors.Errorf("unsupported offset %d", post.Offset)
+		}
+		op = exec.NewOffsetOp(op, int(post.Offset))
+	}
+	if post.Limit != 0 && post.Limit <= math.MaxInt32 {
+		op = exec.NewLimitOp(op, int(post.Limit))
+	} else if post.Limit != 0 {
+		return nil, nil, errors.Errorf("unsupported limit %d", post.Limit)
+	}
+
+	if h.renderExprs != nil {
+		r := renderPlanner{evalCtx: evalCtx, op: op, types: make([]exec.T, len(types))}
+		for i := range types {
+			r.types[i] = exec.FromColumnType(types[i])
+		}
+		cols := make([]int, len(h.renderExprs))
+		for i := range h.renderExprs {
+			if cols[i], err = r.planRender(h.renderExprs[i].expr); err != nil {
+				return nil, nil, err
+			}
+		}
+		op = exec.NewSimpleProjectOp(r.op, cols)
+	} else if h.outputCols != nil {
+		op = exec.NewSimpleProjectOp(op, uint32sToInts(h.outputCols))
+	}
+	return op, h.outputTypes, nil
+}
+
+// vectorizedCmpOps maps the comparison operators supported by the vectorized
+// engine to their implementation.
+var vectorizedCmpOps = map[tree.ComparisonOperator]exec.CmpOp{
+	tree.EQ: exec.EQ,
+	tree.NE: exec.NE,
+	tree.LT: exec.LT,
+	tree.LE: exec.LE,
+	tree.GT: exec.GT,
+	tree.GE: exec.GE,
+}
+
+// planFilter returns an operator that filters the output of op, whose columns
+// have the given types, with the given expression.
+func planFilter(
+	op exec.Operator, expr tree.TypedExpr, types []sqlbase.ColumnType, evalCtx *tree.EvalContext,
+) (exec.Operator, error) {
+	switch t := stripParens(expr).(type) {
+	case *tree.AndExpr:
+		op, err := planFilter(op, t.TypedLeft(), types, evalCtx)
+		if err != nil {
+			return nil, err
+		}
+		return planFilter(op, t.TypedRight(), types, evalCtx)
+
+	case *tree.IndexedVar:
+		if exec.FromColumnType(types[t.Idx]) == exec.Bool {
+			return exec.NewSelConstCmpOp(op, exec.EQ, t.Idx, exec.Bool, true), nil
+		}
+
+	case *tree.ComparisonExpr:
+		cmp, ok := vectorizedCmpOps[t.Operator]
+		if !ok {
+			break
+		}
+		left, right := stripParens(t.TypedLeft()), stripParens(t.TypedRight())
+		if _, ok := left.(*tree.IndexedVar); !ok {
+			left, right = right, left
+			cmp = cmp.Flip()
+		}
+		leftVar, ok := left.(*tree.IndexedVar)
+		if !ok {
+			break
+		}
+		typ := exec.FromColumnType(types[leftVar.Idx])
+		if typ == exec.Unhandled {
+			break
+		}
+		if rightVar, ok := right.(*tree.IndexedVar); ok {
+			if exec.FromColumnType(types[rightVar.Idx]) != typ {
+				break
+			}
+			return exec.NewSelColCmpOp(op, cmp, leftVar.Idx, rightVar.Idx), nil
+		}
+		val, err := evalConst(right, typ, evalCtx)
+		if err != nil {
+			return nil, err
+		}
+		return exec.NewSelConstCmpOp(op, cmp, leftVar.Idx, typ, val), nil
+	}
+	return nil, errors.Errorf("unsupported filter %s", expr)
+}
+
+// renderPlanner plans the operators that compute render expressions. Each
+// expression that isn't a column of the input is computed into a column
+// appended to the batches.
+type renderPlanner struct {
+	evalCtx *tree.EvalContext
+	op      exec.Operator
+	// types are the types of the columns of the batches of op.
+	types []exec.T
+}
+
+// vectorizedBinOps maps the arithmetic operators supported by the vectorized
+// engine to their implementation.
+var vectorizedBinOps = map[tree.BinaryOperator]exec.BinOp{
+	tree.Plus:  exec.Plus,
+	tree.Minus: exec.Minus,
+	tree.Mult:  exec.Mult,
+}
+
+// planRender adds the operators that compute the given expression, and
+// returns the index of the column that holds its result.
+func (r *renderPlanner) planRender(expr tree.TypedExpr) (int, error) {
+	switch t := stripParens(expr).(type) {
+	case *tree.IndexedVar:
+		if r.types[t.Idx] != exec.Unhandled {
+			return t.Idx, nil
+		}
+
+	case *tree.BinaryExpr:
+		binOp, ok := vectorizedBinOps[t.Operator]
+		if !ok {
+			break
+		}
+		colTyp, err := sqlbase.DatumTypeToColumnType(t.ResolvedType())
+		if err != nil {
+			return 0, err
+		}
+		typ := exec.FromColumnType(colTyp)
+		left, err := r.planOperand(t.TypedLeft(), typ)
+		if err != nil {
+			return 0, err
+		}
+		right, err := r.planOperand(t.TypedRight(), typ)
+		if err != nil {
+			return 0, err
+		}
+		outputIdx := len(r.types)
+		if r.op, err = exec.NewProjBinOp(r.op, binOp, typ, left, right, outputIdx); err != nil {
+			return 0, err
+		}
+		r.types = append(r.types, typ)
+		return outputIdx, nil
+	}
+	return 0, errors.Errorf("unsupported render expression %s", expr)
+}
+
+// planOperand plans an operand of type typ of an arithmetic operator.
+func (r *renderPlanner) planOperand(expr tree.TypedExpr, typ exec.T) (exec.ProjOperand, error) {
+	if !tree.ContainsVars(r.evalCtx, expr) {
+		val, err := evalConst(expr, typ, r.evalCtx)
+		return exec.ProjOperand{Const: val}, err
+	}
+	idx, err := r.planRender(expr)
+	if err != nil {
+		return exec.ProjOperand{}, err
+	}
+	if r.types[idx] != typ {
+		return exec.ProjOperand{}, errors.Errorf("unsupported operand %s", expr)
+	}
+	return exec.ProjOperand{ColIdx: idx}, nil
+}
+
+// evalConst evaluates an expression that doesn't depend on the input, and
+// returns its value as the Go type that holds values of type typ.
+func evalConst(expr tree.TypedExpr, typ exec.T, evalCtx *tree.EvalContext) (interface{}, error) {
+	if tree.ContainsVars(evalCtx, expr) {
+		return nil, errors.Errorf("unsupported expression %s", expr)
+	}
+	d, err := expr.Eval(evalCtx)
+	if err != nil {
+		return nil, err
+	}
+	switch v := d.(type) {
+	case *tree.DBool:
+		if typ == exec.Bool {
+			return bool(*v), nil
+		}
+	case *tree.DInt:
+		if typ == exec.Int64 {
+			return int64(*v), nil
+		}
+	case *tree.DFloat:
+		if typ == exec.Float64 {
+			return float64(*v), nil
+		}
+	case *tree.DString:
+		if typ == exec.Bytes {
+			return []byte(*v), nil
+		}
+	case *tree.DBytes:
+		if typ == exec.Bytes {
+			return []byte(*v), nil
+		}
+	}
+	return nil, errors.Errorf("unsupported constant %s of type %s", d, d.ResolvedType())
+}
+
+func stripParens(expr tree.TypedExpr) tree.TypedExpr {
+	for {
+		p, ok := expr.(*tree.ParenExpr)
+		if !ok {
+			return expr
+		}
+		expr = p.TypedInnerExpr()
+	}
+}
+
+func uint32sToInts(s []uint32) []int {
+	res := make([]int, len(s))
+	for i, v := range s {
+		res[i] = int(v)
+	}
+	return res
+}
+
+// setupVectorizedFlow sets up the processors of a flow planned by
+// planVectorizedFlow: a materializer for each root.
+func (f *Flow) setupVectorizedFlow(ctx context.Context, roots []*vectorizedRoot) error {
+	// The input synchronizers are set up first, so that the local streams are
+	// registered before the outputs that feed them are set up.
+	for _, r := range roots {
+		for _, c := range r.columnarizers {
+			var err error
+			if c.input, err = f.setupInputSync(ctx, c.syncSpec); err != nil {
+				return err
+			}
+		}
+	}
+	f.processors = make([]Processor, len(roots))
+	for i, r := range roots {
+		outputs, err := f.setupOutputs(r.spec)
+		if err != nil {
+			return err
+		}
+		m, err := newMaterializer(&f.FlowCtx, r, outputs[0])
+		if err != nil {
+			return err
+		}
+		f.initOutputs(outputs, m.OutputTypes())
+		f.processors[i] = m
+	}
+	return nil
+}
+
+// colBatchScan is the operator that implements table readers. It reads rows
+// with a MultiRowFetcher and copies the values of the needed columns into
+// column vectors.
+//
+// The rows still go through the fetcher's EncDatums: key columns and columns
+// of multi-column families are decoded from their encoded bytes without
+// allocating Datums, but values of single-column families are unmarshaled
+// into Datums by the fetcher first. Decoding the KV bytes straight into the
+// vectors of a batch requires a columnar fetcher, which doesn't exist yet.
+type colBatchScan struct {
+	flowCtx   *FlowCtx
+	spans     roachpb.Spans
+	limitHint int64
+	types     []sqlbase.ColumnType
+	// neededCols are the columns that are decoded; the vectors of the other
+	// columns are not used.
+	neededCols []int
+	vecTypes   []exec.T
+
+	fetcher sqlbase.MultiRowFetcher
+	alloc   sqlbase.DatumAlloc
+	batch   exec.ColBatch
+	started bool
+	done    bool
+}
+
+var _ exec.Operator = &colBatchScan{}
+var _ metadataSource = &colBatchScan{}
+
+func newColBatchScan(
+	flowCtx *FlowCtx, spec *TableReaderSpec, post *PostProcessSpec,
+) (*colBatchScan, error) {
+	if flowCtx.nodeID == 0 {
+		return nil, errors.Errorf("attempting to create a colBatchScan with uninitialized NodeID")
+	}
+	if flowCtx.txn == nil {
+		return nil, errors.Errorf("attempting to create a colBatchScan outside of a txn")
+	}
+	s := &colBatchScan{
+		flowCtx:   flowCtx,
+		limitHint: tableReaderLimitHint(spec, post),
+		types:     make([]sqlbase.ColumnType, len(spec.Table.Columns)),
+		vecTypes:  make([]exec.T, len(spec.Table.Columns)),
+	}
+	for i := range s.types {
+		s.types[i] = spec.Table.Columns[i].Type
+	}
+
+	// The post-processing stage determines the columns that are needed.
+	var h ProcOutputHelper
+	if err := h.Init(post, s.types, flowCtx.NewEvalCtx(), nil /* output */); err != nil {
+		return nil, err
+	}
+	neededCols := h.neededColumns()
+	for i := range s.types {
+		s.vecTypes[i] = exec.Unhandled
+		if !neededCols.Contains(i) {
+			continue
+		}
+		if s.vecTypes[i] = exec.FromColumnType(s.types[i]); s.vecTypes[i] == exec.Unhandled {
+			return nil, errors.Errorf("unsupported column type %s", s.types[i].SQLString())
+		}
+		s.neededCols = append(s.neededCols, i)
+	}
+
+	desc := spec.Table
+	if _, _, err := initRowFetcher(
+		&s.fetcher, &desc, int(spec.IndexIdx), spec.Reverse, neededCols, &s.alloc,
+	); err != nil {
+		return nil, err
+	}
+	s.spans = make(roachpb.Spans, len(spec.Spans))
+	for i, sp := range spec.Spans {
+		s.spans[i] = sp.Span
+	}
+	return s, nil
+}
+
+// Init is part of the exec.Operator interface.
+func (s *colBatchScan) Init() {
+	s.batch = exec.NewMemBatch(s.vecTypes)
+}
+
+// Next is part of the exec.Operator interface.
+func (s *colBatchScan) Next(ctx context.Context) (exec.ColBatch, error) {
+	if !s.started {
+		s.started = true
+		// TODO(radu,andrei,knz): set the traceKV flag when requested by the session.
+		if err := s.fetcher.StartScan(
+			ctx, s.flowCtx.txn, s.spans, true /* limit batches */, s.limitHint, false, /* traceKV */
+		); err != nil {
+			return nil, err
+		}
+	}
+	s.batch.SetSelection(false)
+	vecs := s.batch.ColVecs()
+	for _, c := range s.neededCols {
+		vecs[c].UnsetNulls()
+	}
+	n := 0
+	for ; n < exec.ColBatchSize && !s.done; n++ {
+		row, _, _, err := s.fetcher.NextRow(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if row == nil {
+			s.done = true
+			break
+		}
+		for _, c := range s.neededCols {
+			if err := setVecValue(vecs[c], n, &row[c]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	s.batch.SetLength(n)
+	return s.batch, nil
+}
+
+// drainMeta is part of the metadataSource interface. It returns information
+// about the non-local ranges that were read.
+func (s *colBatchScan) drainMeta(ctx context.Context) []ProducerMetadata {
+	if !s.started {
+		return nil
+	}
+	ranges := misplannedRanges(ctx, s.fetcher.GetRangeInfo(), s.flowCtx.nodeID)
+	if len(ranges) == 0 {
+		return nil
+	}
+	return []ProducerMetadata{{Ranges: ranges}}
+}
+
+// setVecValue sets the i-th value of vec to the value of an EncDatum.
+func setVecValue(vec exec.ColVec, i int, ed *sqlbase.EncDatum) error {
+	if ed.IsNull() {
+		vec.SetNull(i)
+		return nil
+	}
+	var err error
+	switch vec.Type() {
+	case exec.Bool:
+		vec.Bool()[i], err = ed.GetBool()
+	case exec.Bytes:
+		vec.Bytes()[i], err = ed.GetBytes()
+	case exec.Int64:
+		vec.Int64()[i], err = ed.GetInt()
+	case exec.Float64:
+		vec.Float64()[i], err = ed.GetFloat()
+	default:
+		err = errors.Errorf("unsupported vector type %s", vec.Type())
+	}
+	return err
+}
+
+// columnarizer is an operator that converts the rows of a RowSource into
+// batches. The metadata of the input is buffered until the columnarizer is
+// drained, except for errors, which are returned.
+type columnarizer struct {
+	syncSpec *InputSyncSpec
+	// input is set up when the flow is set up, after the planning.
+	input    RowSource
+	vecTypes []exec.T
+
+	batch        exec.ColBatch
+	bufferedMeta []ProducerMetadata
+	done         bool
+}
+
+var _ exec.Operator = &columnarizer{}
+var _ metadataSource = &columnarizer{}
+
+func newColumnarizer(is *InputSyncSpec) (*columnarizer, error) {
+	vecTypes, err := exec.FromColumnTypes(is.ColumnTypes)
+	if err != nil {
+		return nil, err
+	}
+	return &columnarizer{syncSpec: is, vecTypes: vecTypes}, nil
+}
+
+// Init is part of the exec.Operator interface.
+func (c *columnarizer) Init() {
+	c.batch = exec.NewMemBatch(c.vecTypes)
+}
+
+// Next is part of the exec.Operator interface.
+func (c *columnarizer) Next(context.Context) (exec.ColBatch, error) {
+	c.batch.SetSelection(false)
+	vecs := c.batch.ColVecs()
+	for _, vec := range vecs {
+		vec.UnsetNulls()
+	}
+	n := 0
+	for n < exec.ColBatchSize && !c.done {
+		row, meta := c.input.Next()
+		if !meta.Empty() {
+			if meta.Err != nil {
+				return nil, meta.Err
+			}
+			c.bufferedMeta = append(c.bufferedMeta, meta)
+			continue
+		}
+		if row == nil {
+			c.done = true
+			break
+		}
+		for i := range c.vecTypes {
+			if err := setVecValue(vecs[i], n, &row[i]); err != nil {
+				return nil, err
+			}
+		}
+		n++
+	}
+	c.batch.SetLength(n)
+	return c.batch, nil
+}
+
+// drainMeta is part of the metadataSource interface.
+func (c *columnarizer) drainMeta(context.Context) []ProducerMetadata {
+	meta := c.bufferedMeta
+	c.bufferedMeta = nil
+	return meta
+}
+
+// materializer is the processor that runs a tree of operators and sends its
+// output, converted into rows, to the output of the processor at the root of
+// the tree.
+type materializer struct {
+	processorBase
+
+	root  *vectorizedRoot
+	row   sqlbase.EncDatumRow
+	alloc sqlbase.DatumAlloc
+}
+
+var _ Processor = &materializer{}
+
+func newMaterializer(
+	flowCtx *FlowCtx, root *vectorizedRoot, output RowReceiver,
+) (*materializer, error) {
+	m := &materializer{
+		root: root,
+		row:  make(sqlbase.EncDatumRow, len(root.types)),
+	}
+	// The post-processing of the processor is performed by the operators.
+	if err := m.init(&PostProcessSpec{}, root.types, flowCtx, output); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Run is part of the processor interface.
+func (m *materializer) Run(ctx context.Context, wg *sync.WaitGroup) {
+	if wg != nil {
+		defer wg.Done()
+	}
+
+	ctx, span := processorSpan(ctx, "materializer")
+	defer tracing.FinishSpan(span)
+	defer m.root.acc.Close(ctx)
+
+	err := m.run(ctx)
+	for _, src := range m.root.metadataSources {
+		for _, meta := range src.drainMeta(ctx) {
+			m.out.output.Push(nil /* row */, meta)
+		}
+	}
+	inputs := make([]RowSource, len(m.root.columnarizers))
+	for i, c := range m.root.columnarizers {
+		inputs[i] = c.input
+	}
+	DrainAndClose(ctx, m.out.output, err, inputs...)
+}
+
+// run emits the output of the tree of operators, until it is exhausted or the
+// consumer doesn't need more rows.
+func (m *materializer) run(ctx context.Context) error {
+	m.root.op.Init()
+	for {
+		batch, err := m.root.op.Next(ctx)
+		if err != nil {
+			return err
+		}
+		n := batch.Length()
+		if n == 0 {
+			return nil
+		}
+		sel := batch.Selection()
+		for j := 0; j < n; j++ {
+			i := j
+			if sel != nil {
+				i = sel[j]
+			}
+			for c := range m.row {
+				m.row[c] = sqlbase.DatumToEncDatum(
+					m.root.types[c], m.datum(batch.ColVec(c), i, &m.root.types[c]),
+				)
+			}
+			status, err := m.out.EmitRow(ctx, m.row)
+			if err != nil {
+				return err
+			}
+			if status != NeedMoreRows {
+				return nil
+			}
+		}
+	}
+}
+
+// datum returns the i-th value of vec as a Datum of the given type.
+func (m *materializer) datum(vec exec.ColVec, i int, typ *sqlbase.ColumnType) tree.Datum {
+	if vec.NullAt(i) {
+		return tree.DNull
+	}
+	switch typ.SemanticType {
+	case sqlbase.ColumnType_BOOL:
+		return tree.MakeDBool(tree.DBool(vec.Bool()[i]))
+	case sqlbase.ColumnType_INT:
+		return m.alloc.NewDInt(tree.DInt(vec.Int64()[i]))
+	case sqlbase.ColumnType_FLOAT:
+		return m.alloc.NewDFloat(tree.DFloat(vec.Float64()[i]))
+	case sqlbase.ColumnType_STRING:
+		return m.alloc.NewDString(tree.DString(vec.Bytes()[i]))
+	case sqlbase.ColumnType_BYTES:
+		return m.alloc.NewDBytes(tree.DBytes(vec.Bytes()[i]))
+	}
+	panic(fmt.Sprintf("unsupported column type %s", typ.SQLString()))
+}
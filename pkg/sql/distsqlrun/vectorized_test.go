@@ -0,0 +1,296 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package distsqlrun
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+// vectorizedProcessorSpec returns the spec of a processor that reads each of
+// the given inputs from a remote stream and sends its output to the gateway.
+func vectorizedProcessorSpec(
+	core ProcessorCoreUnion, post PostProcessSpec, inputTypes ...[]sqlbase.ColumnType,
+) ProcessorSpec {
+	ps := ProcessorSpec{
+		Core: core,
+		Post: post,
+		Output: []OutputRouterSpec{{
+			Type:    OutputRouterSpec_PASS_THROUGH,
+			Streams: []StreamEndpointSpec{{Type: StreamEndpointSpec_SYNC_RESPONSE}},
+		}},
+	}
+	for i, types := range inputTypes {
+		ps.Input = append(ps.Input, InputSyncSpec{
+			Type:        InputSyncSpec_UNORDERED,
+			Streams:     []StreamEndpointSpec{{Type: StreamEndpointSpec_REMOTE, StreamID: StreamID(i)}},
+			ColumnTypes: types,
+		})
+	}
+	return ps
+}
+
+func TestVectorizedFlow(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	v := [10]sqlbase.EncDatum{}
+	for i := range v {
+		v[i] = sqlbase.DatumToEncDatum(intType, tree.NewDInt(tree.DInt(i)))
+	}
+	null := sqlbase.EncDatum{Datum: tree.DNull}
+	boolTrue := sqlbase.DatumToEncDatum(boolType, tree.DBoolTrue)
+	boolFalse := sqlbase.DatumToEncDatum(boolType, tree.DBoolFalse)
+	strA := sqlbase.DatumToEncDatum(strType, tree.NewDString("a"))
+	strB := sqlbase.DatumToEncDatum(strType, tree.NewDString("b"))
+
+	testCases := []struct {
+		name        string
+		spec        ProcessorSpec
+		inputs      []sqlbase.EncDatumRows
+		outputTypes []sqlbase.ColumnType
+		expected    sqlbase.EncDatumRows
+	}{
+		{
+			name: "filter",
+			spec: vectorizedProcessorSpec(
+				ProcessorCoreUnion{Noop: &NoopCoreSpec{}},
+				PostProcessSpec{
+					Filter:        Expression{Expr: "@1 > 2 AND @3 AND @2 != @1"},
+					Projection:    true,
+					OutputColumns: []uint32{0, 3},
+				},
+				[]sqlbase.ColumnType{intType, intType, boolType, strType},
+			),
+			inputs: []sqlbase.EncDatumRows{{
+				{v[1], v[2], boolTrue, strA},
+				{v[3], v[2], boolTrue, strA},
+				{v[4], v[4], boolTrue, strB},
+				{v[5], null, boolTrue, strB},
+				{v[6], v[2], boolFalse, strB},
+				{v[7], v[2], null, strB},
+				{v[8], v[1], boolTrue, null},
+			}},
+			outputTypes: []sqlbase.ColumnType{intType, strType},
+			expected: sqlbase.EncDatumRows{
+				{v[3], strA},
+				{v[8], null},
+			},
+		},
+		{
+			name: "render",
+			spec: vectorizedProcessorSpec(
+				ProcessorCoreUnion{Noop: &NoopCoreSpec{}},
+				PostProcessSpec{
+					RenderExprs: []Expression{{Expr: "@2"}, {Expr: "(@1 + 1) * @2"}},
+					Offset:      1,
+					Limit:       2,
+				},
+				[]sqlbase.ColumnType{intType, intType},
+			),
+			inputs: []sqlbase.EncDatumRows{{
+				{v[1], v[2]},
+				{v[2], v[3]},
+				{v[3], null},
+				{v[4], v[5]},
+			}},
+			outputTypes: []sqlbase.ColumnType{intType, intType},
+			expected: sqlbase.EncDatumRows{
+				{v[3], v[9]},
+				{null, null},
+			},
+		},
+		{
+			name: "aggregator",
+			spec: vectorizedProcessorSpec(
+				ProcessorCoreUnion{Aggregator: &AggregatorSpec{
+					GroupCols: []uint32{0},
+					Aggregations: []AggregatorSpec_Aggregation{
+						{Func: AggregatorSpec_IDENT, ColIdx: []uint32{0}},
+						{Func: AggregatorSpec_COUNT_ROWS},
+						{Func: AggregatorSpec_SUM_INT, ColIdx: []uint32{1}},
+						{Func: AggregatorSpec_MAX, ColIdx: []uint32{1}},
+					},
+				}},
+				PostProcessSpec{},
+				[]sqlbase.ColumnType{strType, intType},
+			),
+			inputs: []sqlbase.EncDatumRows{{
+				{strA, v[1]},
+				{strB, v[2]},
+				{strA, v[3]},
+				{null, v[4]},
+				{strB, null},
+			}},
+			outputTypes: []sqlbase.ColumnType{strType, intType, intType, intType},
+			expected: sqlbase.EncDatumRows{
+				{strA, v[2], v[4], v[3]},
+				{strB, v[2], v[2], v[2]},
+				{null, v[1], v[4], v[4]},
+			},
+		},
+		{
+			name: "hash joiner",
+			spec: vectorizedProcessorSpec(
+				ProcessorCoreUnion{HashJoiner: &HashJoinerSpec{
+					LeftEqColumns:  []uint32{0},
+					RightEqColumns: []uint32{1},
+				}},
+				PostProcessSpec{Projection: true, OutputColumns: []uint32{1, 2}},
+				[]sqlbase.ColumnType{intType, strType},
+				[]sqlbase.ColumnType{boolType, intType},
+			),
+			inputs: []sqlbase.EncDatumRows{
+				{
+					{v[1], strA},
+					{v[2], strB},
+					{null, strB},
+				},
+				{
+					{boolTrue, v[1]},
+					{boolFalse, v[1]},
+					{boolTrue, v[3]},
+					{boolFalse, null},
+				},
+			},
+			outputTypes: []sqlbase.ColumnType{strType, boolType},
+			expected: sqlbase.EncDatumRows{
+				{strA, boolTrue},
+				{strA, boolFalse},
+			},
+		},
+	}
+
+	ctx := context.Background()
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			evalCtx := tree.MakeTestingEvalContext()
+			defer evalCtx.Stop(ctx)
+			flowCtx := FlowCtx{
+				Settings: cluster.MakeTestingClusterSettings(),
+				EvalCtx:  evalCtx,
+			}
+
+			roots, err := planVectorizedFlow(&flowCtx, &FlowSpec{Processors: []ProcessorSpec{c.spec}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(roots) != 1 {
+				t.Fatalf("expected 1 root, got %d", len(roots))
+			}
+			root := roots[0]
+			for i, col := range root.columnarizers {
+				col.input = NewRowBuffer(col.syncSpec.ColumnTypes, c.inputs[i], RowBufferArgs{})
+			}
+			out := NewRowBuffer(c.outputTypes, nil /* rows */, RowBufferArgs{})
+			m, err := newMaterializer(&flowCtx, root, out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			m.Run(ctx, nil /* wg */)
+
+			if !out.ProducerClosed {
+				t.Fatalf("output RowReceiver not closed")
+			}
+			var expected, rets []string
+			for _, row := range c.expected {
+				expected = append(expected, row.String(c.outputTypes))
+			}
+			for _, row := range out.GetRowsNoMeta(t) {
+				rets = append(rets, row.String(c.outputTypes))
+			}
+			sort.Strings(expected)
+			sort.Strings(rets)
+			if expStr, retStr := strings.Join(expected, ""), strings.Join(rets, ""); expStr != retStr {
+				t.Errorf("invalid results; expected:\n   %s\ngot:\n   %s", expStr, retStr)
+			}
+		})
+	}
+}
+
+func TestVectorizedFlowUnsupported(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	intTypes := []sqlbase.ColumnType{intType, intType}
+	testCases := []ProcessorSpec{
+		// Unsupported core.
+		vectorizedProcessorSpec(
+			ProcessorCoreUnion{Sorter: &SorterSpec{}}, PostProcessSpec{}, intTypes,
+		),
+		// Unsupported filter.
+		vectorizedProcessorSpec(
+			ProcessorCoreUnion{Noop: &NoopCoreSpec{}},
+			PostProcessSpec{Filter: Expression{Expr: "@1 > 1 OR @2 > 1"}},
+			intTypes,
+		),
+		// Unsupported render.
+		vectorizedProcessorSpec(
+			ProcessorCoreUnion{Noop: &NoopCoreSpec{}},
+			PostProcessSpec{RenderExprs: []Expression{{Expr: "@1 // @2"}}},
+			intTypes,
+		),
+		// Unsupported column type.
+		vectorizedProcessorSpec(
+			ProcessorCoreUnion{Noop: &NoopCoreSpec{}},
+			PostProcessSpec{},
+			[]sqlbase.ColumnType{{SemanticType: sqlbase.ColumnType_DECIMAL}},
+		),
+		// SUM of integers is a DECIMAL.
+		vectorizedProcessorSpec(
+			ProcessorCoreUnion{Aggregator: &AggregatorSpec{
+				Aggregations: []AggregatorSpec_Aggregation{
+					{Func: AggregatorSpec_SUM, ColIdx: []uint32{0}},
+				},
+			}},
+			PostProcessSpec{},
+			intTypes,
+		),
+		// Unsupported join type.
+		vectorizedProcessorSpec(
+			ProcessorCoreUnion{HashJoiner: &HashJoinerSpec{
+				LeftEqColumns:  []uint32{0},
+				RightEqColumns: []uint32{0},
+				Type:           JoinType_LEFT_OUTER,
+			}},
+			PostProcessSpec{},
+			intTypes, intTypes,
+		),
+	}
+
+	ctx := context.Background()
+	for i, spec := range testCases {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			evalCtx := tree.MakeTestingEvalContext()
+			defer evalCtx.Stop(ctx)
+			flowCtx := FlowCtx{
+				Settings: cluster.MakeTestingClusterSettings(),
+				EvalCtx:  evalCtx,
+			}
+			if _, err := planVectorizedFlow(
+				&flowCtx, &FlowSpec{Processors: []ProcessorSpec{spec}},
+			); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
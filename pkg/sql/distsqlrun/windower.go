@@ -0,0 +1,637 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package distsqlrun
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/builtins"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/types"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/util/encoding"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/mon"
+	"github.com/cockroachdb/cockroach/pkg/util/tracing"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+// GetWindowFunctionInfo returns the window function constructor and the
+// return type for the given builtin window function or aggregate function
+// (used as a window function) applied to arguments of the given types.
+func GetWindowFunctionInfo(
+	name string, inputTypes ...sqlbase.ColumnType,
+) (
+	windowConstructor func(*tree.EvalContext) tree.WindowFunc,
+	returnType sqlbase.ColumnType,
+	err error,
+) {
+	datumTypes := make([]types.T, len(inputTypes))
+	for i := range inputTypes {
+		datumTypes[i] = inputTypes[i].ToDatumType()
+	}
+
+	for _, b := range builtins.Builtins[strings.ToLower(name)] {
+		if b.WindowFunc == nil {
+			continue
+		}
+		argTypes := b.Types.Types()
+		if len(argTypes) != len(inputTypes) {
+			continue
+		}
+		match := true
+		for i, t := range argTypes {
+			if !datumTypes[i].Equivalent(t) {
+				match = false
+				break
+			}
+		}
+		if match {
+			// Found!
+			windowFunc := b.WindowFunc
+			constructWindow := func(evalCtx *tree.EvalContext) tree.WindowFunc {
+				return windowFunc(datumTypes, evalCtx)
+			}
+
+			colTyp, err := sqlbase.DatumTypeToColumnType(b.FixedReturnType())
+			if err != nil {
+				return nil, sqlbase.ColumnType{}, err
+			}
+			return constructWindow, colTyp, nil
+		}
+	}
+	return nil, sqlbase.ColumnType{}, errors.Errorf(
+		"no builtin window function for %s on %v", name, inputTypes,
+	)
+}
+
+// windower is the processor core type that computes window functions (see
+// WindowerSpec). It sorts its input by the partition columns followed by the
+// ordering within partitions, falling back to disk if the input does not fit
+// in memory, and then evaluates the window functions over each partition in
+// turn. The rows of the current partition are buffered, in memory or, if they
+// don't fit, on disk.
+//
+// The rows are output in the order in which they were sorted.
+type windower struct {
+	processorBase
+
+	flowCtx *FlowCtx
+	// input is a row source without metadata; the metadata is directed straight
+	// to out.output.
+	input NoMetadataRowSource
+	// rawInput is the true input, not wrapped in a NoMetadataRowSource.
+	rawInput    RowSource
+	inputTypes  []sqlbase.ColumnType
+	outputTypes []sqlbase.ColumnType
+
+	partitionBy []uint32
+	// ordering is the order in which the input is sorted: by the partition
+	// columns, followed by the ordering within partitions.
+	ordering sqlbase.ColumnOrdering
+	// peerOrdering is the ordering within partitions; rows that are equal
+	// according to it are peers.
+	peerOrdering sqlbase.ColumnOrdering
+	windowFns    []windowFn
+
+	evalCtx    *tree.EvalContext
+	datumAlloc sqlbase.DatumAlloc
+}
+
+// windowFn is a window function computed by a windower.
+type windowFn struct {
+	construct func(*tree.EvalContext) tree.WindowFunc
+	argIdxs   []uint32
+}
+
+var _ Processor = &windower{}
+
+func newWindower(
+	flowCtx *FlowCtx, spec *WindowerSpec, input RowSource, post *PostProcessSpec, output RowReceiver,
+) (*windower, error) {
+	w := &windower{
+		flowCtx:      flowCtx,
+		input:        MakeNoMetadataRowSource(input, output),
+		rawInput:     input,
+		inputTypes:   input.Types(),
+		partitionBy:  spec.PartitionBy,
+		peerOrdering: convertToColumnOrdering(spec.Ordering),
+		windowFns:    make([]windowFn, len(spec.WindowFns)),
+		evalCtx:      flowCtx.NewEvalCtx(),
+	}
+
+	w.ordering = make(sqlbase.ColumnOrdering, 0, len(spec.PartitionBy)+len(w.peerOrdering))
+	for _, c := range spec.PartitionBy {
+		if c >= uint32(len(w.inputTypes)) {
+			return nil, errors.Errorf("partition column %d out of range", c)
+		}
+		w.ordering = append(w.ordering, sqlbase.ColumnOrderInfo{
+			ColIdx: int(c), Direction: encoding.Ascending,
+		})
+	}
+	w.ordering = append(w.ordering, w.peerOrdering...)
+
+	w.outputTypes = make([]sqlbase.ColumnType, len(w.inputTypes), len(w.inputTypes)+len(spec.WindowFns))
+	copy(w.outputTypes, w.inputTypes)
+	for i, fn := range spec.WindowFns {
+		argTypes := make([]sqlbase.ColumnType, len(fn.ArgIdxs))
+		for j, c := range fn.ArgIdxs {
+			if c >= uint32(len(w.inputTypes)) {
+				return nil, errors.Errorf("ArgIdxs out of range (%d)", fn.ArgIdxs)
+			}
+			argTypes[j] = w.inputTypes[c]
+		}
+		windowConstructor, retType, err := GetWindowFunctionInfo(fn.Func, argTypes...)
+		if err != nil {
+			return nil, err
+		}
+		w.windowFns[i] = windowFn{construct: windowConstructor, argIdxs: fn.ArgIdxs}
+		w.outputTypes = append(w.outputTypes, retType)
+	}
+
+	if err := w.init(post, w.outputTypes, flowCtx, output); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Run is part of the processor interface.
+func (w *windower) Run(ctx context.Context, wg *sync.WaitGroup) {
+	if wg != nil {
+		defer wg.Done()
+	}
+
+	ctx = log.WithLogTag(ctx, "Windower", nil)
+	ctx, span := processorSpan(ctx, "windower")
+	defer tracing.FinishSpan(span)
+
+	if log.V(2) {
+		log.Infof(ctx, "starting windower run")
+		defer log.Infof(ctx, "exiting windower run")
+	}
+
+	err := w.mainLoop(ctx)
+	DrainAndClose(ctx, w.out.output, err, w.rawInput)
+}
+
+// mainLoop sorts the input and computes the window functions over each
+// partition, pushing the results to the output.
+//
+// It returns once either all the input has been exhausted or the consumer
+// indicated that no more rows are needed. In any case, the caller is
+// responsible for draining and closing the producer and the consumer.
+func (w *windower) mainLoop(ctx context.Context) error {
+	// Sorting the input is the part of the windower that can need a lot of
+	// memory, so we fall back to disk under the same conditions as the sorter.
+	st := w.flowCtx.Settings
+	useTempStorage := settingUseTempStorageSorts.Get(&st.SV) ||
+		w.flowCtx.testingKnobs.MemoryLimitBytes > 0
+	rowContainerMon := w.flowCtx.EvalCtx.Mon
+	if useTempStorage {
+		limit := w.flowCtx.testingKnobs.MemoryLimitBytes
+		if limit <= 0 {
+			limit = settingWorkMemBytes.Get(&st.SV)
+		}
+		limitedMon := mon.MakeMonitorInheritWithLimit(
+			"windower-limited", limit, w.flowCtx.EvalCtx.Mon,
+		)
+		limitedMon.Start(ctx, w.flowCtx.EvalCtx.Mon, mon.BoundAccount{})
+		defer limitedMon.Stop(ctx)
+
+		rowContainerMon = &limitedMon
+	}
+
+	var memRows memRowContainer
+	memRows.initWithMon(w.ordering, w.inputTypes, w.evalCtx, rowContainerMon)
+	defer memRows.Close(ctx)
+
+	row, err := w.accumulateRows(ctx, &memRows)
+	if err == nil {
+		memRows.Sort(ctx)
+		return w.computeWindows(ctx, &memRows, rowContainerMon, useTempStorage)
+	}
+	// We only fall back to disk if we ran out of memory while adding a row
+	// (see sortAllStrategy.Execute).
+	if pgErr, ok := pgerror.GetPGCause(err); !(ok && pgErr.Code == pgerror.CodeOutOfMemoryError) || row == nil {
+		return err
+	}
+	if !useTempStorage {
+		return errors.Wrap(err, "external storage for large queries disabled")
+	}
+	log.VEventf(ctx, 2, "falling back to disk")
+	diskRows := makeDiskRowContainer(
+		ctx, w.flowCtx.diskMonitor, w.inputTypes, w.ordering, w.flowCtx.TempStorage,
+	)
+	defer diskRows.Close(ctx)
+
+	// Transfer the rows from memory to disk and release the memory they were
+	// taking up.
+	i := memRows.NewIterator(ctx)
+	for i.Rewind(); ; i.Next() {
+		if ok, err := i.Valid(); err != nil {
+			i.Close()
+			return err
+		} else if !ok {
+			break
+		}
+		memRow, err := i.Row()
+		if err != nil {
+			i.Close()
+			return err
+		}
+		if err := diskRows.AddRow(ctx, memRow); err != nil {
+			i.Close()
+			return err
+		}
+	}
+	i.Close()
+	memRows.Clear(ctx)
+
+	// Add the row that caused the memory container to run out of memory.
+	if err := diskRows.AddRow(ctx, row); err != nil {
+		return err
+	}
+	if _, err := w.accumulateRows(ctx, &diskRows); err != nil {
+		return err
+	}
+	return w.computeWindows(ctx, &diskRows, rowContainerMon, useTempStorage)
+}
+
+// accumulateRows adds all the input rows to the given container. If an error
+// occurs while adding a row, the row is returned in order to not lose it.
+func (w *windower) accumulateRows(
+	ctx context.Context, r sortableRowContainer,
+) (sqlbase.EncDatumRow, error) {
+	for {
+		row, err := w.input.NextRow()
+		if err != nil {
+			return nil, err
+		}
+		if row == nil {
+			return nil, nil
+		}
+		if err := r.AddRow(ctx, row); err != nil {
+			return row, err
+		}
+	}
+}
+
+// computeWindows iterates over the sorted rows, buffering the rows of one
+// partition at a time and computing the window functions over it. It returns
+// once all the rows have been processed or the consumer indicated that no
+// more rows are needed.
+//
+// The rows of a partition are accounted for in rowContainerMon, and moved to
+// disk if they don't fit in it (see windowPartition).
+func (w *windower) computeWindows(
+	ctx context.Context,
+	rows sortableRowContainer,
+	rowContainerMon *mon.BytesMonitor,
+	useTempStorage bool,
+) error {
+	var partition windowPartition
+	partition.init(w.flowCtx, w.inputTypes, w.evalCtx, rowContainerMon, useTempStorage)
+	defer partition.Close(ctx)
+
+	i := rows.NewIterator(ctx)
+	defer i.Close()
+
+	// firstRow is the first row of the current partition; it is compared to
+	// each row to find where the partition ends.
+	firstRow := make(tree.Datums, len(w.inputTypes))
+	datums := make(tree.Datums, len(w.inputTypes))
+	for i.Rewind(); ; i.Next() {
+		if ok, err := i.Valid(); err != nil {
+			return err
+		} else if !ok {
+			break
+		}
+		row, err := i.Row()
+		if err != nil {
+			return err
+		}
+		for c := range row {
+			if err := row[c].EnsureDecoded(&w.inputTypes[c], &w.datumAlloc); err != nil {
+				return err
+			}
+			datums[c] = row[c].Datum
+		}
+		if partition.Len() > 0 && !w.samePartition(firstRow, datums) {
+			if done, err := w.processPartition(ctx, &partition); err != nil || done {
+				return err
+			}
+			partition.Clear(ctx)
+		}
+		if partition.Len() == 0 {
+			copy(firstRow, datums)
+		}
+		if err := partition.AddRow(ctx, row); err != nil {
+			return err
+		}
+	}
+	if partition.Len() > 0 {
+		if _, err := w.processPartition(ctx, &partition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// samePartition returns whether the two rows belong to the same partition.
+// Like in GROUP BY, NULL values are considered equal to each other.
+func (w *windower) samePartition(a, b tree.Datums) bool {
+	for _, c := range w.partitionBy {
+		if a[c].Compare(w.evalCtx, b[c]) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// processPartition computes the window functions over the rows of a
+// partition and pushes the results to the output. It returns true if the
+// consumer indicated that no more rows are needed.
+//
+// The window functions are computed together, one row at a time, so that
+// only the partition's rows need to be buffered.
+//
+// Like windowNode, we only support the default framing option of RANGE
+// UNBOUNDED PRECEDING: the frame of a row consists of all rows from the
+// partition start up through the row's last peer.
+func (w *windower) processPartition(
+	ctx context.Context, partition *windowPartition,
+) (consumerDone bool, _ error) {
+	builtins := make([]tree.WindowFunc, 0, len(w.windowFns))
+	defer func() {
+		for _, builtin := range builtins {
+			builtin.Close(ctx, w.evalCtx)
+		}
+	}()
+	frames := make([]tree.WindowFrame, len(w.windowFns))
+	for i, fn := range w.windowFns {
+		builtins = append(builtins, fn.construct(w.evalCtx))
+		frames[i] = tree.WindowFrame{
+			Rows: &windowFnArgs{
+				partition: partition,
+				argIdxs:   fn.argIdxs,
+				scratch:   make(tree.Datums, len(fn.argIdxs)),
+			},
+			ArgIdxStart: 0,
+			ArgCount:    len(fn.argIdxs),
+		}
+	}
+
+	rowCount := partition.Len()
+	inputCols := len(w.inputTypes)
+	outRow := make(sqlbase.EncDatumRow, len(w.outputTypes))
+	results := make(tree.Datums, len(w.windowFns))
+	var firstPeerIdx, peerRowCount int
+	for rowIdx := 0; rowIdx < rowCount; rowIdx++ {
+		if rowIdx == firstPeerIdx+peerRowCount {
+			firstPeerIdx = rowIdx
+			var err error
+			if peerRowCount, err = w.peerGroupSize(ctx, partition, firstPeerIdx); err != nil {
+				return false, err
+			}
+		}
+		for fnIdx, builtin := range builtins {
+			frame := &frames[fnIdx]
+			frame.RowIdx = rowIdx
+			frame.FirstPeerIdx = firstPeerIdx
+			frame.PeerRowCount = peerRowCount
+			res, err := builtin.Compute(ctx, w.evalCtx, *frame)
+			if err != nil {
+				return false, err
+			}
+			results[fnIdx] = res
+		}
+
+		row, err := partition.at(ctx, rowIdx)
+		if err != nil {
+			return false, err
+		}
+		for c, d := range row {
+			outRow[c] = sqlbase.DatumToEncDatum(w.inputTypes[c], d)
+		}
+		for j, d := range results {
+			outRow[inputCols+j] = sqlbase.DatumToEncDatum(w.outputTypes[inputCols+j], d)
+		}
+		consumerStatus, err := w.out.EmitRow(ctx, outRow)
+		if err != nil || consumerStatus != NeedMoreRows {
+			return true, err
+		}
+	}
+	return false, nil
+}
+
+// peerGroupSize returns the number of rows in the peer group that starts at
+// the given row of the partition.
+func (w *windower) peerGroupSize(
+	ctx context.Context, partition *windowPartition, start int,
+) (int, error) {
+	first, err := partition.at(ctx, start)
+	if err != nil {
+		return 0, err
+	}
+	// The rows returned by the partition are only valid until the next call
+	// to at, so we hold on to a copy of the first one.
+	firstPeer := make(tree.Datums, len(first))
+	copy(firstPeer, first)
+	n := 1
+	for ; start+n < partition.Len(); n++ {
+		row, err := partition.at(ctx, start+n)
+		if err != nil {
+			return 0, err
+		}
+		if sqlbase.CompareDatums(w.peerOrdering, w.evalCtx, firstPeer, row) != 0 {
+			break
+		}
+	}
+	return n, nil
+}
+
+// windowPartition holds the rows of the partition that is being processed.
+// The rows are kept in memory until they run out of memory budget, at which
+// point they are moved to disk, the same way the sort step falls back to disk
+// (see windower.mainLoop). The rows are read by their index in the
+// partition.
+type windowPartition struct {
+	flowCtx        *FlowCtx
+	types          []sqlbase.ColumnType
+	useTempStorage bool
+
+	mem memRowContainer
+	// disk is set once the rows have been moved to disk. It is created
+	// without an ordering, so the rows are kept in the order in which they
+	// were added and keyed by their index.
+	disk *diskRowContainer
+	// diskIter is an iterator over disk, positioned on the row at index
+	// iterIdx. It is created on the first read of a row on disk.
+	diskIter *diskRowIterator
+	iterIdx  int
+	len      int
+
+	scratch    tree.Datums
+	datumAlloc sqlbase.DatumAlloc
+}
+
+func (p *windowPartition) init(
+	flowCtx *FlowCtx,
+	types []sqlbase.ColumnType,
+	evalCtx *tree.EvalContext,
+	mon *mon.BytesMonitor,
+	useTempStorage bool,
+) {
+	p.flowCtx = flowCtx
+	p.types = types
+	p.useTempStorage = useTempStorage
+	p.mem.initWithMon(nil /* ordering */, types, evalCtx, mon)
+	p.iterIdx = -1
+	p.scratch = make(tree.Datums, len(types))
+}
+
+// Len returns the number of rows in the partition.
+func (p *windowPartition) Len() int {
+	return p.len
+}
+
+// AddRow adds a row at the end of the partition. If the rows in memory run
+// out of memory budget, they are moved to disk.
+func (p *windowPartition) AddRow(ctx context.Context, row sqlbase.EncDatumRow) error {
+	if p.disk == nil {
+		err := p.mem.AddRow(ctx, row)
+		if err == nil {
+			p.len++
+			return nil
+		}
+		if pgErr, ok := pgerror.GetPGCause(err); !(ok && pgErr.Code == pgerror.CodeOutOfMemoryError) {
+			return err
+		}
+		if !p.useTempStorage {
+			return errors.Wrap(err, "external storage for large queries disabled")
+		}
+		if err := p.spillToDisk(ctx); err != nil {
+			return err
+		}
+	}
+	if err := p.disk.AddRow(ctx, row); err != nil {
+		return err
+	}
+	p.len++
+	return nil
+}
+
+// spillToDisk moves the rows of the partition from memory to disk.
+func (p *windowPartition) spillToDisk(ctx context.Context) error {
+	log.VEventf(ctx, 2, "falling back to disk for window partition")
+	disk := makeDiskRowContainer(
+		ctx, p.flowCtx.diskMonitor, p.types, nil /* ordering */, p.flowCtx.TempStorage,
+	)
+	p.disk = &disk
+	for i := 0; i < p.mem.Len(); i++ {
+		if err := p.disk.AddRow(ctx, p.mem.EncRow(i)); err != nil {
+			return err
+		}
+	}
+	p.mem.Clear(ctx)
+	return nil
+}
+
+// at returns the row at the given index in the partition. The returned row is
+// only valid until the next call to at.
+func (p *windowPartition) at(ctx context.Context, idx int) (tree.Datums, error) {
+	if p.disk == nil {
+		return p.mem.At(idx), nil
+	}
+	if p.diskIter == nil {
+		i := p.disk.NewIterator(ctx).(diskRowIterator)
+		p.diskIter = &i
+	}
+	// The rows are mostly read in order, in which case there is no need to
+	// seek.
+	if p.iterIdx >= 0 && idx == p.iterIdx+1 {
+		p.diskIter.Next()
+	} else if idx != p.iterIdx {
+		p.diskIter.Seek(encoding.EncodeUvarintAscending(nil, uint64(idx)))
+	}
+	p.iterIdx = idx
+	row, err := p.diskIter.Row()
+	if err != nil {
+		return nil, err
+	}
+	for c := range row {
+		if err := row[c].EnsureDecoded(&p.types[c], &p.datumAlloc); err != nil {
+			return nil, err
+		}
+		p.scratch[c] = row[c].Datum
+	}
+	return p.scratch, nil
+}
+
+// Clear removes all the rows from the partition. The rows of the next
+// partition are kept in memory again, until they run out of memory budget.
+func (p *windowPartition) Clear(ctx context.Context) {
+	p.closeDisk(ctx)
+	p.mem.Clear(ctx)
+	p.len = 0
+}
+
+// Close frees up the resources held by the partition.
+func (p *windowPartition) Close(ctx context.Context) {
+	p.closeDisk(ctx)
+	p.mem.Close(ctx)
+}
+
+func (p *windowPartition) closeDisk(ctx context.Context) {
+	if p.diskIter != nil {
+		p.diskIter.Close()
+		p.diskIter = nil
+	}
+	p.iterIdx = -1
+	if p.disk != nil {
+		p.disk.Close(ctx)
+		p.disk = nil
+	}
+}
+
+// windowFnArgs exposes the arguments of a window function in the rows of a
+// partition as tree.IndexedRows.
+type windowFnArgs struct {
+	partition *windowPartition
+	argIdxs   []uint32
+	scratch   tree.Datums
+}
+
+var _ tree.IndexedRows = &windowFnArgs{}
+
+// Len is part of the tree.IndexedRows interface.
+func (a *windowFnArgs) Len() int {
+	return a.partition.Len()
+}
+
+// GetRow is part of the tree.IndexedRows interface.
+func (a *windowFnArgs) GetRow(ctx context.Context, idx int) (tree.IndexedRow, error) {
+	row, err := a.partition.at(ctx, idx)
+	if err != nil {
+		return tree.IndexedRow{}, err
+	}
+	for j, c := range a.argIdxs {
+		a.scratch[j] = row[c]
+	}
+	return tree.IndexedRow{Idx: idx, Row: a.scratch}, nil
+}
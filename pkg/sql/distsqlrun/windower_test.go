@@ -0,0 +1,302 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package distsqlrun
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine"
+	"github.com/cockroachdb/cockroach/pkg/util/encoding"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/mon"
+
+	"golang.org/x/net/context"
+)
+
+func TestWindower(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	v := [6]sqlbase.EncDatum{}
+	for i := range v {
+		v[i] = sqlbase.DatumToEncDatum(intType, tree.NewDInt(tree.DInt(i)))
+	}
+
+	testCases := []struct {
+		name     string
+		spec     WindowerSpec
+		post     PostProcessSpec
+		input    sqlbase.EncDatumRows
+		outTypes []sqlbase.ColumnType
+		expected sqlbase.EncDatumRows
+	}{
+		{
+			name: "Partitioned",
+			// SELECT a, b, row_number() OVER w, rank() OVER w, max(b) OVER w
+			// WINDOW w AS (PARTITION BY a ORDER BY b)
+			spec: WindowerSpec{
+				PartitionBy: []uint32{0},
+				Ordering: convertToSpecOrdering(
+					sqlbase.ColumnOrdering{{ColIdx: 1, Direction: encoding.Ascending}},
+				),
+				WindowFns: []WindowerSpec_WindowFn{
+					{Func: "row_number"},
+					{Func: "rank"},
+					{Func: "max", ArgIdxs: []uint32{1}},
+				},
+			},
+			input: sqlbase.EncDatumRows{
+				{v[1], v[2]},
+				{v[0], v[3]},
+				{v[1], v[1]},
+				{v[0], v[3]},
+				{v[1], v[2]},
+				{v[0], v[1]},
+			},
+			outTypes: []sqlbase.ColumnType{intType, intType, intType, intType, intType},
+			expected: sqlbase.EncDatumRows{
+				{v[0], v[1], v[1], v[1], v[1]},
+				{v[0], v[3], v[2], v[2], v[3]},
+				{v[0], v[3], v[3], v[2], v[3]},
+				{v[1], v[1], v[1], v[1], v[1]},
+				{v[1], v[2], v[2], v[2], v[2]},
+				{v[1], v[2], v[3], v[2], v[2]},
+			},
+		}, {
+			name: "NoPartition",
+			// SELECT a, b, count(*) OVER w, min(a) OVER w
+			// WINDOW w AS (ORDER BY b DESC)
+			spec: WindowerSpec{
+				Ordering: convertToSpecOrdering(
+					sqlbase.ColumnOrdering{{ColIdx: 1, Direction: encoding.Descending}},
+				),
+				WindowFns: []WindowerSpec_WindowFn{
+					{Func: "count_rows"},
+					{Func: "min", ArgIdxs: []uint32{0}},
+				},
+			},
+			input: sqlbase.EncDatumRows{
+				{v[3], v[1]},
+				{v[1], v[4]},
+				{v[2], v[2]},
+				{v[0], v[3]},
+			},
+			outTypes: []sqlbase.ColumnType{intType, intType, intType, intType},
+			expected: sqlbase.EncDatumRows{
+				{v[1], v[4], v[1], v[1]},
+				{v[0], v[3], v[2], v[0]},
+				{v[2], v[2], v[3], v[0]},
+				{v[3], v[1], v[4], v[0]},
+			},
+		}, {
+			name: "Projection",
+			// SELECT b, row_number() OVER (PARTITION BY a) LIMIT 2
+			spec: WindowerSpec{
+				PartitionBy: []uint32{0},
+				WindowFns:   []WindowerSpec_WindowFn{{Func: "row_number"}},
+			},
+			post: PostProcessSpec{
+				Projection:    true,
+				OutputColumns: []uint32{1, 2},
+				Limit:         2,
+			},
+			input: sqlbase.EncDatumRows{
+				{v[2], v[5]},
+				{v[1], v[4]},
+				{v[2], v[5]},
+			},
+			outTypes: []sqlbase.ColumnType{intType, intType},
+			expected: sqlbase.EncDatumRows{
+				{v[4], v[1]},
+				{v[5], v[1]},
+			},
+		},
+	}
+
+	ctx := context.Background()
+	tempEngine, err := engine.NewTempEngine(base.DefaultTestTempStorageConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tempEngine.Close()
+
+	evalCtx := tree.MakeTestingEvalContext()
+	defer evalCtx.Stop(ctx)
+	diskMonitor := mon.MakeMonitor(
+		"test-disk",
+		mon.DiskResource,
+		nil, /* curCount */
+		nil, /* maxHist */
+		-1,  /* increment: use default block size */
+		math.MaxInt64,
+	)
+	diskMonitor.Start(ctx, nil /* pool */, mon.MakeStandaloneBudget(math.MaxInt64))
+	defer diskMonitor.Stop(ctx)
+	flowCtx := FlowCtx{
+		EvalCtx:     evalCtx,
+		Settings:    cluster.MakeTestingClusterSettings(),
+		TempStorage: tempEngine,
+		diskMonitor: &diskMonitor,
+	}
+
+	for _, c := range testCases {
+		// Test with several memory limits:
+		// 0: Use the default limit.
+		// 1: Immediately switch to disk.
+		// 1150: The rows are moved from memory to disk after a couple of rows.
+		for _, memLimit := range []int64{0, 1, 1150} {
+			t.Run(fmt.Sprintf("%sMemLimit=%d", c.name, memLimit), func(t *testing.T) {
+				in := NewRowBuffer(twoIntCols, c.input, RowBufferArgs{})
+				out := &RowBuffer{}
+
+				w, err := newWindower(&flowCtx, &c.spec, in, &c.post, out)
+				if err != nil {
+					t.Fatal(err)
+				}
+				w.flowCtx.testingKnobs.MemoryLimitBytes = memLimit
+				w.Run(ctx, nil)
+				if !out.ProducerClosed {
+					t.Fatalf("output RowReceiver not closed")
+				}
+
+				var retRows sqlbase.EncDatumRows
+				for {
+					row := out.NextNoMeta(t)
+					if row == nil {
+						break
+					}
+					retRows = append(retRows, row)
+				}
+
+				expStr := c.expected.String(c.outTypes)
+				retStr := retRows.String(c.outTypes)
+				if expStr != retStr {
+					t.Errorf("invalid results; expected:\n   %s\ngot:\n   %s",
+						expStr, retStr)
+				}
+			})
+		}
+	}
+}
+
+// TestWindowerLargePartition checks that a partition that doesn't fit in the
+// memory limit is moved to disk, and that the window functions are computed
+// correctly over its rows on disk.
+func TestWindowerLargePartition(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	const numRows = 1000
+	intDatum := func(i int) sqlbase.EncDatum {
+		return sqlbase.DatumToEncDatum(intType, tree.NewDInt(tree.DInt(i)))
+	}
+	nullDatum := sqlbase.DatumToEncDatum(intType, tree.DNull)
+
+	// The input consists of pairs of equal rows (i/2, i/2), in reverse order.
+	var input, expected sqlbase.EncDatumRows
+	for i := numRows - 1; i >= 0; i-- {
+		input = append(input, sqlbase.EncDatumRow{intDatum(i / 2), intDatum(i / 2)})
+	}
+	// SELECT a, b, row_number() OVER w, rank() OVER w, count(*) OVER w,
+	//        lag(a) OVER w, first_value(a) OVER w
+	// WINDOW w AS (ORDER BY b)
+	spec := WindowerSpec{
+		Ordering: convertToSpecOrdering(
+			sqlbase.ColumnOrdering{{ColIdx: 1, Direction: encoding.Ascending}},
+		),
+		WindowFns: []WindowerSpec_WindowFn{
+			{Func: "row_number"},
+			{Func: "rank"},
+			{Func: "count_rows"},
+			{Func: "lag", ArgIdxs: []uint32{0}},
+			{Func: "first_value", ArgIdxs: []uint32{0}},
+		},
+	}
+	for i := 0; i < numRows; i++ {
+		lag := nullDatum
+		if i > 0 {
+			lag = intDatum((i - 1) / 2)
+		}
+		expected = append(expected, sqlbase.EncDatumRow{
+			intDatum(i / 2), intDatum(i / 2),
+			intDatum(i + 1), intDatum(i/2*2 + 1), intDatum(i/2*2 + 2), lag, intDatum(0),
+		})
+	}
+	outTypes := make([]sqlbase.ColumnType, 7)
+	for i := range outTypes {
+		outTypes[i] = intType
+	}
+
+	ctx := context.Background()
+	tempEngine, err := engine.NewTempEngine(base.DefaultTestTempStorageConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tempEngine.Close()
+
+	evalCtx := tree.MakeTestingEvalContext()
+	defer evalCtx.Stop(ctx)
+	diskMonitor := mon.MakeMonitor(
+		"test-disk",
+		mon.DiskResource,
+		nil, /* curCount */
+		nil, /* maxHist */
+		-1,  /* increment: use default block size */
+		math.MaxInt64,
+	)
+	diskMonitor.Start(ctx, nil /* pool */, mon.MakeStandaloneBudget(math.MaxInt64))
+	defer diskMonitor.Stop(ctx)
+	flowCtx := FlowCtx{
+		EvalCtx:     evalCtx,
+		Settings:    cluster.MakeTestingClusterSettings(),
+		TempStorage: tempEngine,
+		diskMonitor: &diskMonitor,
+	}
+	// With a limit of 1 byte, both the sorted input and the partition are
+	// moved to disk as soon as their first row is added.
+	flowCtx.testingKnobs.MemoryLimitBytes = 1
+
+	in := NewRowBuffer(twoIntCols, input, RowBufferArgs{})
+	out := &RowBuffer{}
+	w, err := newWindower(&flowCtx, &spec, in, &PostProcessSpec{}, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Run(ctx, nil)
+	if !out.ProducerClosed {
+		t.Fatalf("output RowReceiver not closed")
+	}
+
+	var retRows sqlbase.EncDatumRows
+	for {
+		row := out.NextNoMeta(t)
+		if row == nil {
+			break
+		}
+		retRows = append(retRows, row)
+	}
+	expStr := expected.String(outTypes)
+	retStr := retRows.String(outTypes)
+	if expStr != retStr {
+		t.Errorf("invalid results; expected:\n   %s\ngot:\n   %s", expStr, retStr)
+	}
+	if diskMonitor.MaximumBytes() == 0 {
+		t.Fatal("expected the partition to be moved to disk")
+	}
+}
@@ -59,6 +59,7 @@ func (p *planner) DropDatabase(ctx context.Context, n *tree.DropDatabase) (planN
 	if dbDesc == nil {
 		if n.IfExists {
 			// Noop.
+			p.noticeSkipping("database", string(n.Name))
 			return &zeroNode{}, nil
 		}
 		return nil, sqlbase.NewUndefinedDatabaseError(string(n.Name))
@@ -86,6 +87,9 @@ func (p *planner) DropDatabase(ctx context.Context, n *tree.DropDatabase) (planN
 				return nil, pgerror.NewDangerousStatementErrorf(
 					"DROP DATABASE on non-empty database without explicit CASCADE")
 			}
+			p.noticef(pgerror.SeverityNotice,
+				"DROP DATABASE on non-empty database without explicit CASCADE drops %d tables",
+				len(tbNames))
 		}
 	}
 
@@ -257,6 +261,7 @@ func (p *planner) DropIndex(ctx context.Context, n *tree.DropIndex) (planNode, e
 			// don't exist.
 			if n.IfExists {
 				// Skip this index and don't return an error.
+				p.noticeSkipping("index", string(index.Index))
 				continue
 			}
 			// Index does not exist, but we want it to error out.
@@ -328,6 +333,7 @@ func (p *planner) dropIndexByName(
 		// don't exist.
 		if ifExists {
 			// Noop.
+			p.noticeSkipping("index", string(idxName))
 			return nil
 		}
 		// Index does not exist, but we want it to: error out.
@@ -471,6 +477,7 @@ func (p *planner) DropView(ctx context.Context, n *tree.DropView) (planNode, err
 		}
 		if droppedDesc == nil {
 			if n.IfExists {
+				p.noticeSkipping("view", tree.ErrString(tn))
 				continue
 			}
 			// View does not exist, but we want it to: error out.
@@ -572,6 +579,7 @@ func (p *planner) DropSequence(ctx context.Context, n *tree.DropSequence) (planN
 		}
 		if droppedDesc == nil {
 			if n.IfExists {
+				p.noticeSkipping("sequence", tree.ErrString(tn))
 				continue
 			}
 			// Sequence does not exist, but we want it to: error out.
@@ -655,6 +663,7 @@ func (p *planner) DropTable(ctx context.Context, n *tree.DropTable) (planNode, e
 		}
 		if droppedDesc == nil {
 			if n.IfExists {
+				p.noticeSkipping("table", tree.ErrString(tn))
 				continue
 			}
 			// Table does not exist, but we want it to: error out.
@@ -1250,8 +1259,11 @@ func (n *dropUserNode) Start(params runParams) error {
 			return err
 		}
 
-		if rowsAffected == 0 && !n.ifExists {
-			return errors.Errorf("user %s does not exist", normalizedUsername)
+		if rowsAffected == 0 {
+			if !n.ifExists {
+				return errors.Errorf("user %s does not exist", normalizedUsername)
+			}
+			params.p.noticeSkipping("user", normalizedUsername)
 		}
 
 		numDeleted += rowsAffected
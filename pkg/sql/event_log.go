@@ -84,6 +84,11 @@ const (
 
 	// EventLogSetClusterSetting is recorded when a cluster setting is changed.
 	EventLogSetClusterSetting EventLogType = "set_cluster_setting"
+
+	// EventLogConnectionDenied is recorded when a connection is rejected by
+	// the host-based authentication rules. It is throttled, so that not every
+	// rejected connection is recorded.
+	EventLogConnectionDenied EventLogType = "connection_denied"
 )
 
 // An EventLogger exposes methods used to record events to the event table.
@@ -150,3 +155,16 @@ VALUES(
 	}
 	return nil
 }
+
+// LogEvent inserts a single event reported by this node into the event log,
+// in its own transaction. It is used to record the events which don't
+// happen as part of a transaction.
+func (e *Executor) LogEvent(
+	ctx context.Context, eventType EventLogType, targetID int32, info interface{},
+) error {
+	return e.cfg.DB.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
+		return MakeEventLogger(e.cfg.LeaseManager).InsertEventRecord(
+			ctx, txn, eventType, targetID, int32(e.cfg.NodeID.Get()), info,
+		)
+	})
+}
@@ -0,0 +1,153 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package exec
+
+// ColBatchSize is the maximum number of rows of a ColBatch.
+const ColBatchSize = 1024
+
+// ColBatch is a batch of rows stored as a set of column vectors of the same
+// length.
+type ColBatch interface {
+	// Length returns the number of rows of the batch. If the batch uses a
+	// selection vector, it is the number of selected rows.
+	Length() int
+	// SetLength sets the number of rows of the batch.
+	SetLength(int)
+	// Width returns the number of columns of the batch.
+	Width() int
+	// ColVec returns the i-th column vector.
+	ColVec(i int) ColVec
+	// ColVecs returns all of the column vectors.
+	ColVecs() []ColVec
+	// Selection returns the selection vector of the batch, or nil if the batch
+	// doesn't use one. The first Length() elements of the selection vector are
+	// the indexes, in increasing order, of the rows of the column vectors that
+	// are part of the batch.
+	Selection() []int
+	// SetSelection sets whether the batch uses its selection vector. The
+	// contents of the selection vector are undefined after it is enabled.
+	SetSelection(bool)
+	// AppendCol adds a column vector of the given type to the batch.
+	AppendCol(T)
+}
+
+// memBatch is the in-memory ColBatch implementation.
+type memBatch struct {
+	n      int
+	b      []ColVec
+	sel    []int
+	useSel bool
+}
+
+var _ ColBatch = &memBatch{}
+
+// NewMemBatch returns a ColBatch with ColBatchSize rows of columns of the given
+// types.
+func NewMemBatch(types []T) ColBatch {
+	b := &memBatch{
+		b:   make([]ColVec, len(types)),
+		sel: make([]int, ColBatchSize),
+	}
+	for i, t := range types {
+		b.b[i] = newMemColumn(t, ColBatchSize)
+	}
+	return b
+}
+
+func (m *memBatch) Length() int {
+	return m.n
+}
+
+func (m *memBatch) SetLength(n int) {
+	m.n = n
+}
+
+func (m *memBatch) Width() int {
+	return len(m.b)
+}
+
+func (m *memBatch) ColVec(i int) ColVec {
+	return m.b[i]
+}
+
+func (m *memBatch) ColVecs() []ColVec {
+	return m.b
+}
+
+func (m *memBatch) Selection() []int {
+	if !m.useSel {
+		return nil
+	}
+	return m.sel
+}
+
+func (m *memBatch) SetSelection(b bool) {
+	m.useSel = b
+}
+
+func (m *memBatch) AppendCol(t T) {
+	m.b = append(m.b, newMemColumn(t, ColBatchSize))
+}
+
+// identity is a selection vector that selects all of the rows of a batch. It
+// must not be modified.
+var identity = func() []int {
+	sel := make([]int, ColBatchSize)
+	for i := range sel {
+		sel[i] = i
+	}
+	return sel
+}()
+
+// selectedRows returns the indexes of the rows of the batch. The returned
+// slice must not be modified.
+func selectedRows(batch ColBatch) []int {
+	n := batch.Length()
+	if sel := batch.Selection(); sel != nil {
+		return sel[:n]
+	}
+	return identity[:n]
+}
+
+// projectingBatch is a ColBatch that exposes a subset of the columns of
+// another batch, in a possibly different order.
+type projectingBatch struct {
+	ColBatch
+	projection []int
+	vecs       []ColVec
+}
+
+var _ ColBatch = &projectingBatch{}
+
+func (b *projectingBatch) Width() int {
+	return len(b.projection)
+}
+
+func (b *projectingBatch) ColVec(i int) ColVec {
+	return b.ColBatch.ColVec(b.projection[i])
+}
+
+func (b *projectingBatch) ColVecs() []ColVec {
+	b.vecs = b.vecs[:0]
+	for _, i := range b.projection {
+		b.vecs = append(b.vecs, b.ColBatch.ColVec(i))
+	}
+	return b.vecs
+}
+
+func (b *projectingBatch) AppendCol(t T) {
+	b.ColBatch.AppendCol(t)
+	b.projection = append(b.projection, b.ColBatch.Width()-1)
+}
@@ -0,0 +1,116 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package exec
+
+import (
+	"math"
+
+	"github.com/cockroachdb/cockroach/pkg/util/encoding"
+)
+
+// keyEncoder builds, for the rows of a batch, byte keys out of the values of a
+// set of columns. The keys of two rows are equal if and only if their values
+// are equal, with NULLs equal to each other; they are used to look rows up in
+// hash tables. The keys are built a column at a time.
+type keyEncoder struct {
+	cols []int
+	// keys[j] is the key of the j-th selected row of the last batch.
+	keys [][]byte
+	// nulls[j] is set if any of the values of the j-th selected row is NULL.
+	nulls []bool
+}
+
+func makeKeyEncoder(cols []int) keyEncoder {
+	return keyEncoder{
+		cols:  cols,
+		keys:  make([][]byte, ColBatchSize),
+		nulls: make([]bool, ColBatchSize),
+	}
+}
+
+// encode builds the keys of the given rows of the batch. Each value is
+// prefixed with a marker byte that tells NULLs apart; the key of a NULL is only
+// the marker.
+func (e *keyEncoder) encode(batch ColBatch, sel []int) {
+	for j := range sel {
+		e.keys[j] = e.keys[j][:0]
+		e.nulls[j] = false
+	}
+	for _, c := range e.cols {
+		vec := batch.ColVec(c)
+		hasNulls := vec.HasNulls()
+		switch vec.Type() {
+		case Bool:
+			col := vec.Bool()
+			for j, i := range sel {
+				if hasNulls && e.appendNull(vec, i, j) {
+					continue
+				}
+				var b byte
+				if col[i] {
+					b = 1
+				}
+				e.keys[j] = append(e.keys[j], 1, b)
+			}
+		case Bytes:
+			col := vec.Bytes()
+			for j, i := range sel {
+				if hasNulls && e.appendNull(vec, i, j) {
+					continue
+				}
+				e.keys[j] = append(e.keys[j], 1)
+				e.keys[j] = encoding.EncodeUvarintAscending(e.keys[j], uint64(len(col[i])))
+				e.keys[j] = append(e.keys[j], col[i]...)
+			}
+		case Int64:
+			col := vec.Int64()
+			for j, i := range sel {
+				if hasNulls && e.appendNull(vec, i, j) {
+					continue
+				}
+				e.keys[j] = append(e.keys[j], 1)
+				e.keys[j] = encoding.EncodeUint64Ascending(e.keys[j], uint64(col[i]))
+			}
+		case Float64:
+			col := vec.Float64()
+			for j, i := range sel {
+				if hasNulls && e.appendNull(vec, i, j) {
+					continue
+				}
+				f := col[i]
+				// Values that are equal but have different representations must
+				// have the same key.
+				if f == 0 {
+					f = 0
+				} else if math.IsNaN(f) {
+					f = math.NaN()
+				}
+				e.keys[j] = append(e.keys[j], 1)
+				e.keys[j] = encoding.EncodeUint64Ascending(e.keys[j], math.Float64bits(f))
+			}
+		}
+	}
+}
+
+// appendNull appends the key of a NULL to the key of the j-th row if the i-th
+// value of the vector is NULL, and returns whether it did.
+func (e *keyEncoder) appendNull(vec ColVec, i, j int) bool {
+	if !vec.NullAt(i) {
+		return false
+	}
+	e.keys[j] = append(e.keys[j], 0)
+	e.nulls[j] = true
+	return true
+}
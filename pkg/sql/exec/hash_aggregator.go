@@ -0,0 +1,409 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package exec
+
+import (
+	"bytes"
+
+	"github.com/cockroachdb/cockroach/pkg/util/mon"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+// AggregateFunc is an aggregate function supported by the hash aggregator.
+type AggregateFunc int
+
+const (
+	// Ident returns an arbitrary value of the group. It is used to output the
+	// grouping columns, whose values are the same for all of the rows of a
+	// group.
+	Ident AggregateFunc = iota
+	// CountRows counts the rows of the group.
+	CountRows
+	// Count counts the non-NULL values of the group.
+	Count
+	// Sum adds up the values of the group. Like SUM_INT, the sum of Int64
+	// values wraps around on overflow.
+	Sum
+	// Avg averages the values of the group.
+	Avg
+	// Min returns the smallest value of the group.
+	Min
+	// Max returns the largest value of the group.
+	Max
+)
+
+// AggregateSpec describes an aggregation performed by the hash aggregator.
+type AggregateSpec struct {
+	Func AggregateFunc
+	// ColIdx is the input column that the function is applied to. It is unused
+	// by CountRows.
+	ColIdx int
+}
+
+// AggregateOutputType returns the type of the result of the aggregate function
+// when applied on values of type t, or an error if the vectorized engine
+// doesn't support the function on that type.
+func AggregateOutputType(fn AggregateFunc, t T) (T, error) {
+	switch fn {
+	case CountRows, Count:
+		return Int64, nil
+	case Ident, Min, Max:
+		return t, nil
+	case Sum:
+		if t == Int64 || t == Float64 {
+			return t, nil
+		}
+	case Avg:
+		if t == Float64 {
+			return t, nil
+		}
+	}
+	return Unhandled, errors.Errorf("unsupported aggregation %d on %s", fn, t)
+}
+
+// groupSizeEstimate is the estimated overhead of a group of the hash
+// aggregator, excluding its key and the values of its aggregations.
+const groupSizeEstimate = 64
+
+// aggValueSizeEstimate is the estimated size of the state of an aggregation
+// for a group.
+const aggValueSizeEstimate = 24
+
+// hashAggregator is an Operator that groups the rows of its input by the
+// values of a set of columns and computes aggregations over each group. The
+// groups are kept in memory, and output once the input is exhausted, in the
+// order in which they were first seen.
+type hashAggregator struct {
+	input      Operator
+	inputTypes []T
+	groupCols  []int
+	aggs       []aggState
+	outTypes   []T
+	acc        *mon.BoundAccount
+
+	keys   keyEncoder
+	groups map[string]int
+	// numGroups is the number of groups.
+	numGroups int
+	// groupIdxs[j] is the group of the j-th selected row of the current batch.
+	groupIdxs []int
+
+	// emitted is the number of groups that have been output.
+	emitted int
+	done    bool
+	output  ColBatch
+}
+
+var _ Operator = &hashAggregator{}
+
+// NewHashAggregator returns an Operator that computes the given aggregations
+// over the groups of rows of its input that have equal values in groupCols.
+// The output has a column per aggregation. When there are no grouping
+// columns, all of the rows form a single group, which is output even if the
+// input is empty. The memory used by the groups is accounted for in acc.
+func NewHashAggregator(
+	input Operator,
+	inputTypes []T,
+	groupCols []int,
+	aggs []AggregateSpec,
+	acc *mon.BoundAccount,
+) (Operator, error) {
+	a := &hashAggregator{
+		input:      input,
+		inputTypes: inputTypes,
+		groupCols:  groupCols,
+		aggs:       make([]aggState, len(aggs)),
+		outTypes:   make([]T, len(aggs)),
+		acc:        acc,
+	}
+	for i, spec := range aggs {
+		var t T
+		if spec.Func != CountRows {
+			t = inputTypes[spec.ColIdx]
+		}
+		outType, err := AggregateOutputType(spec.Func, t)
+		if err != nil {
+			return nil, err
+		}
+		a.outTypes[i] = outType
+		a.aggs[i] = aggState{AggregateSpec: spec, t: t}
+	}
+	return a, nil
+}
+
+func (a *hashAggregator) Init() {
+	a.keys = makeKeyEncoder(a.groupCols)
+	a.groups = make(map[string]int)
+	a.groupIdxs = make([]int, ColBatchSize)
+	a.output = NewMemBatch(a.outTypes)
+	a.input.Init()
+}
+
+func (a *hashAggregator) Next(ctx context.Context) (ColBatch, error) {
+	if !a.done {
+		if err := a.aggregate(ctx); err != nil {
+			return nil, err
+		}
+		a.done = true
+	}
+	n := a.numGroups - a.emitted
+	if n > ColBatchSize {
+		n = ColBatchSize
+	}
+	for i := range a.aggs {
+		a.aggs[i].emit(a.output.ColVec(i), a.emitted, n)
+	}
+	a.emitted += n
+	a.output.SetLength(n)
+	return a.output, nil
+}
+
+// aggregate consumes the input, accumulating its rows into groups.
+func (a *hashAggregator) aggregate(ctx context.Context) error {
+	for {
+		batch, err := a.input.Next(ctx)
+		if err != nil {
+			return err
+		}
+		if batch.Length() == 0 {
+			break
+		}
+		sel := selectedRows(batch)
+		a.keys.encode(batch, sel)
+		for j := range sel {
+			g, ok := a.groups[string(a.keys.keys[j])]
+			if !ok {
+				if g, err = a.addGroup(ctx, a.keys.keys[j]); err != nil {
+					return err
+				}
+			}
+			a.groupIdxs[j] = g
+		}
+		for i := range a.aggs {
+			var vec ColVec
+			if a.aggs[i].Func != CountRows {
+				vec = batch.ColVec(a.aggs[i].ColIdx)
+			}
+			if err := a.aggs[i].update(ctx, a.acc, vec, sel, a.groupIdxs); err != nil {
+				return err
+			}
+		}
+	}
+	if len(a.groupCols) == 0 && a.numGroups == 0 {
+		if _, err := a.addGroup(ctx, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addGroup adds a group with the given key and returns its index.
+func (a *hashAggregator) addGroup(ctx context.Context, key []byte) (int, error) {
+	if err := a.acc.Grow(
+		ctx, int64(len(key)+groupSizeEstimate+len(a.aggs)*aggValueSizeEstimate),
+	); err != nil {
+		return 0, err
+	}
+	g := a.numGroups
+	a.groups[string(key)] = g
+	a.numGroups++
+	for i := range a.aggs {
+		a.aggs[i].addGroup()
+	}
+	return g, nil
+}
+
+// aggState is the state of an aggregation: for each group, the aggregated
+// value so far. Only the slices used by the aggregate function and its input
+// type are populated.
+type aggState struct {
+	AggregateSpec
+	// t is the type of the aggregated values.
+	t T
+
+	// count is the number of values, for CountRows, Count and Avg.
+	count []int64
+	// isSet is set once a non-NULL value has been aggregated. A group whose
+	// isSet is false outputs a NULL, except for CountRows and Count.
+	isSet  []bool
+	bools  []bool
+	bytes  [][]byte
+	ints   []int64
+	floats []float64
+}
+
+func (s *aggState) addGroup() {
+	s.count = append(s.count, 0)
+	s.isSet = append(s.isSet, false)
+	switch s.t {
+	case Bool:
+		s.bools = append(s.bools, false)
+	case Bytes:
+		s.bytes = append(s.bytes, nil)
+	case Int64:
+		s.ints = append(s.ints, 0)
+	case Float64:
+		s.floats = append(s.floats, 0)
+	}
+}
+
+// replaceOn returns, for each of the results -1, 0 and 1 (shifted by one) of
+// the comparison of a new value to the current value of a group, whether the
+// new value replaces it.
+func (s *aggState) replaceOn() [3]bool {
+	switch s.Func {
+	case Min:
+		return [3]bool{true, false, false}
+	case Max:
+		return [3]bool{false, false, true}
+	}
+	return [3]bool{}
+}
+
+// update aggregates the given rows of vec; groups[j] is the group of the row
+// sel[j].
+func (s *aggState) update(
+	ctx context.Context, acc *mon.BoundAccount, vec ColVec, sel []int, groups []int,
+) error {
+	if s.Func == CountRows {
+		for j := range sel {
+			s.count[groups[j]]++
+		}
+		return nil
+	}
+	hasNulls := vec.HasNulls()
+	switch s.Func {
+	case Count:
+		for j, i := range sel {
+			if !hasNulls || !vec.NullAt(i) {
+				s.count[groups[j]]++
+			}
+		}
+
+	case Sum, Avg:
+		switch s.t {
+		case Int64:
+			col := vec.Int64()
+			for j, i := range sel {
+				if !hasNulls || !vec.NullAt(i) {
+					g := groups[j]
+					s.ints[g] += col[i]
+					s.isSet[g] = true
+				}
+			}
+		case Float64:
+			col := vec.Float64()
+			for j, i := range sel {
+				if !hasNulls || !vec.NullAt(i) {
+					g := groups[j]
+					s.floats[g] += col[i]
+					s.count[g]++
+					s.isSet[g] = true
+				}
+			}
+		}
+
+	case Ident, Min, Max:
+		replaceOn := s.replaceOn()
+		switch s.t {
+		case Bool:
+			col := vec.Bool()
+			for j, i := range sel {
+				if !hasNulls || !vec.NullAt(i) {
+					g := groups[j]
+					if !s.isSet[g] || replaceOn[compareBool(col[i], s.bools[g])+1] {
+						s.bools[g] = col[i]
+						s.isSet[g] = true
+					}
+				}
+			}
+		case Bytes:
+			col := vec.Bytes()
+			for j, i := range sel {
+				if !hasNulls || !vec.NullAt(i) {
+					g := groups[j]
+					if !s.isSet[g] || replaceOn[bytes.Compare(col[i], s.bytes[g])+1] {
+						// The values of the input are only valid until its next batch.
+						if err := acc.ResizeItem(
+							ctx, int64(len(s.bytes[g])), int64(len(col[i])),
+						); err != nil {
+							return err
+						}
+						s.bytes[g] = append(s.bytes[g][:0], col[i]...)
+						s.isSet[g] = true
+					}
+				}
+			}
+		case Int64:
+			col := vec.Int64()
+			for j, i := range sel {
+				if !hasNulls || !vec.NullAt(i) {
+					g := groups[j]
+					if !s.isSet[g] || replaceOn[compareInt64(col[i], s.ints[g])+1] {
+						s.ints[g] = col[i]
+						s.isSet[g] = true
+					}
+				}
+			}
+		case Float64:
+			col := vec.Float64()
+			for j, i := range sel {
+				if !hasNulls || !vec.NullAt(i) {
+					g := groups[j]
+					if !s.isSet[g] || replaceOn[compareFloat64(col[i], s.floats[g])+1] {
+						s.floats[g] = col[i]
+						s.isSet[g] = true
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// emit writes the results of the n groups starting at the given one to the
+// first n values of out.
+func (s *aggState) emit(out ColVec, start, n int) {
+	out.UnsetNulls()
+	switch s.Func {
+	case CountRows, Count:
+		copy(out.Int64(), s.count[start:start+n])
+		return
+	case Avg:
+		o := out.Float64()
+		for j := 0; j < n; j++ {
+			if g := start + j; s.isSet[g] {
+				o[j] = s.floats[g] / float64(s.count[g])
+			}
+		}
+	default:
+		switch s.t {
+		case Bool:
+			copy(out.Bool(), s.bools[start:start+n])
+		case Bytes:
+			copy(out.Bytes(), s.bytes[start:start+n])
+		case Int64:
+			copy(out.Int64(), s.ints[start:start+n])
+		case Float64:
+			copy(out.Float64(), s.floats[start:start+n])
+		}
+	}
+	for j := 0; j < n; j++ {
+		if !s.isSet[start+j] {
+			out.SetNull(j)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package exec
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"golang.org/x/net/context"
+)
+
+func TestHashAggregator(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	testCases := []struct {
+		description string
+		types       []T
+		tuples      tuples
+		groupCols   []int
+		aggs        []AggregateSpec
+		expected    tuples
+	}{
+		{
+			description: "count and sum by group",
+			types:       []T{Int64, Int64},
+			tuples:      tuples{{1, 10}, {2, 20}, {1, nil}, {nil, 5}, {2, 30}, {nil, 6}},
+			groupCols:   []int{0},
+			aggs: []AggregateSpec{
+				{Func: Ident, ColIdx: 0},
+				{Func: CountRows},
+				{Func: Count, ColIdx: 1},
+				{Func: Sum, ColIdx: 1},
+			},
+			expected: tuples{{1, 2, 1, 10}, {2, 2, 2, 50}, {nil, 2, 2, 11}},
+		},
+		{
+			description: "min, max and avg",
+			types:       []T{Bytes, Float64, Bool},
+			tuples: tuples{
+				{"a", 1.5, true}, {"b", 2.0, false}, {"a", -1.5, false}, {"b", nil, nil}, {"c", nil, nil},
+			},
+			groupCols: []int{0},
+			aggs: []AggregateSpec{
+				{Func: Ident, ColIdx: 0},
+				{Func: Min, ColIdx: 1},
+				{Func: Max, ColIdx: 1},
+				{Func: Avg, ColIdx: 1},
+				{Func: Min, ColIdx: 2},
+				{Func: Max, ColIdx: 2},
+			},
+			expected: tuples{
+				{"a", -1.5, 1.5, 0.0, false, true},
+				{"b", 2.0, 2.0, 2.0, false, false},
+				{"c", nil, nil, nil, nil, nil},
+			},
+		},
+		{
+			description: "min and max of bytes",
+			types:       []T{Int64, Bytes},
+			tuples:      tuples{{1, "foo"}, {1, "bar"}, {1, "baz"}, {2, "qux"}},
+			groupCols:   []int{0},
+			aggs: []AggregateSpec{
+				{Func: Min, ColIdx: 1},
+				{Func: Max, ColIdx: 1},
+			},
+			expected: tuples{{"bar", "foo"}, {"qux", "qux"}},
+		},
+		{
+			description: "multiple grouping columns",
+			types:       []T{Int64, Bytes, Float64},
+			tuples: tuples{
+				{1, "a", 1.0}, {1, "b", 2.0}, {1, "a", 3.0}, {2, "a", 4.0}, {1, nil, 5.0}, {1, nil, 6.0},
+			},
+			groupCols: []int{0, 1},
+			aggs:      []AggregateSpec{{Func: Sum, ColIdx: 2}},
+			expected:  tuples{{4.0}, {2.0}, {4.0}, {11.0}},
+		},
+		{
+			description: "no grouping columns",
+			types:       []T{Int64},
+			tuples:      tuples{{1}, {2}, {nil}},
+			aggs:        []AggregateSpec{{Func: CountRows}, {Func: Sum, ColIdx: 0}},
+			expected:    tuples{{3, 3}},
+		},
+		{
+			description: "no grouping columns and no rows",
+			types:       []T{Int64},
+			aggs:        []AggregateSpec{{Func: CountRows}, {Func: Sum, ColIdx: 0}},
+			expected:    tuples{{0, nil}},
+		},
+		{
+			description: "no rows",
+			types:       []T{Int64},
+			groupCols:   []int{0},
+			aggs:        []AggregateSpec{{Func: CountRows}},
+			expected:    nil,
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.description, func(t *testing.T) {
+			runTests(t, c.types, c.tuples, func(t *testing.T, input *opTestInput) {
+				ctx := context.Background()
+				m, acc := newTestAccount(ctx)
+				defer m.Stop(ctx)
+				defer acc.Close(ctx)
+
+				op, err := NewHashAggregator(input, c.types, c.groupCols, c.aggs, acc)
+				if err != nil {
+					t.Fatal(err)
+				}
+				res, err := collect(op)
+				if err != nil {
+					t.Fatal(err)
+				}
+				// The groups are output in the order in which they are first seen.
+				assertTuplesEqual(t, c.expected, res, false /* unordered */)
+			})
+		})
+	}
+}
+
+func TestHashAggregatorManyGroups(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	const numRows = 3*ColBatchSize + 1
+	tups := make(tuples, numRows)
+	expected := make(tuples, numRows)
+	for i := range tups {
+		tups[i] = tuple{i, i}
+		expected[i] = tuple{i, 2}
+	}
+	// Each group has two rows.
+	input := newOpTestInput([]T{Int64, Int64}, ColBatchSize, append(tups, tups...))
+	ctx := context.Background()
+	m, acc := newTestAccount(ctx)
+	defer m.Stop(ctx)
+	defer acc.Close(ctx)
+
+	op, err := NewHashAggregator(
+		input, []T{Int64, Int64}, []int{0},
+		[]AggregateSpec{{Func: Ident, ColIdx: 0}, {Func: Count, ColIdx: 1}}, acc,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := collect(op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertTuplesEqual(t, expected, res, false /* unordered */)
+}
+
+func TestHashAggregatorUnsupported(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	for _, agg := range []AggregateSpec{{Func: Avg}, {Func: Sum}} {
+		if _, err := NewHashAggregator(
+			nil /* input */, []T{Bytes}, nil /* groupCols */, []AggregateSpec{agg}, nil, /* acc */
+		); err == nil {
+			t.Errorf("expected aggregation %d on bytes to be unsupported", agg.Func)
+		}
+	}
+}
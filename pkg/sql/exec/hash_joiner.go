@@ -0,0 +1,223 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package exec
+
+import (
+	"github.com/cockroachdb/cockroach/pkg/util/mon"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+// buildRowSizeEstimate is the estimated overhead of a row stored by the hash
+// joiner, excluding its key and its values.
+const buildRowSizeEstimate = 32
+
+// hashJoiner is an Operator that performs an inner equality join of its two
+// inputs. The right input is consumed first and stored in memory, in a hash
+// table indexed by the values of its equality columns; the rows of the left
+// input are then looked up in the hash table. Rows that have a NULL in any of
+// the equality columns never match.
+type hashJoiner struct {
+	left, right           Operator
+	leftTypes, rightTypes []T
+	leftEqCols            []int
+	rightEqCols           []int
+	acc                   *mon.BoundAccount
+
+	// buildCols are the columns of the stored right rows.
+	buildCols []*memColumn
+	// head maps a key to one plus the index of the last stored row with that
+	// key; next[i] is one plus the index of the previous stored row with the
+	// same key as row i, or zero.
+	head  map[string]int
+	next  []int
+	built bool
+
+	// The state of the probe of the current left batch: the row being looked
+	// up (an index into probeSel), and one plus the index of its next match,
+	// or zero if the row hasn't been looked up yet.
+	keys       keyEncoder
+	probeBatch ColBatch
+	probeSel   []int
+	probePos   int
+	match      int
+
+	leftIdxs  []int
+	rightIdxs []int
+	output    ColBatch
+}
+
+var _ Operator = &hashJoiner{}
+
+// NewHashJoiner returns an Operator that performs an inner join of its inputs
+// on the equality of leftEqCols and rightEqCols, which must be of the same
+// types. The output columns are the left columns followed by the right
+// columns. The memory used by the right rows is accounted for in acc.
+func NewHashJoiner(
+	left, right Operator,
+	leftTypes, rightTypes []T,
+	leftEqCols, rightEqCols []int,
+	acc *mon.BoundAccount,
+) (Operator, error) {
+	if len(leftEqCols) != len(rightEqCols) {
+		return nil, errors.Errorf("mismatched equality columns %v and %v", leftEqCols, rightEqCols)
+	}
+	for i := range leftEqCols {
+		if l, r := leftTypes[leftEqCols[i]], rightTypes[rightEqCols[i]]; l != r {
+			return nil, errors.Errorf("equality of columns of different types %s and %s", l, r)
+		}
+	}
+	return &hashJoiner{
+		left:        left,
+		right:       right,
+		leftTypes:   leftTypes,
+		rightTypes:  rightTypes,
+		leftEqCols:  leftEqCols,
+		rightEqCols: rightEqCols,
+		acc:         acc,
+	}, nil
+}
+
+func (h *hashJoiner) Init() {
+	h.buildCols = make([]*memColumn, len(h.rightTypes))
+	for i, t := range h.rightTypes {
+		h.buildCols[i] = newMemColumn(t, 0)
+	}
+	h.head = make(map[string]int)
+	h.leftIdxs = make([]int, ColBatchSize)
+	h.rightIdxs = make([]int, ColBatchSize)
+	h.output = NewMemBatch(append(append([]T(nil), h.leftTypes...), h.rightTypes...))
+	h.left.Init()
+	h.right.Init()
+}
+
+func (h *hashJoiner) Next(ctx context.Context) (ColBatch, error) {
+	if !h.built {
+		if err := h.build(ctx); err != nil {
+			return nil, err
+		}
+		h.built = true
+		h.keys = makeKeyEncoder(h.leftEqCols)
+	}
+	for {
+		if h.probePos >= len(h.probeSel) {
+			batch, err := h.left.Next(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if batch.Length() == 0 {
+				h.output.SetLength(0)
+				return h.output, nil
+			}
+			h.probeBatch = batch
+			h.probeSel = selectedRows(batch)
+			h.probePos = 0
+			h.match = 0
+			h.keys.encode(batch, h.probeSel)
+		}
+
+		n := 0
+		for h.probePos < len(h.probeSel) && n < ColBatchSize {
+			if h.match == 0 {
+				if !h.keys.nulls[h.probePos] {
+					h.match = h.head[string(h.keys.keys[h.probePos])]
+				}
+				if h.match == 0 {
+					h.probePos++
+					continue
+				}
+			}
+			h.leftIdxs[n] = h.probeSel[h.probePos]
+			h.rightIdxs[n] = h.match - 1
+			n++
+			if h.match = h.next[h.match-1]; h.match == 0 {
+				h.probePos++
+			}
+		}
+		if n > 0 {
+			h.emit(n)
+			return h.output, nil
+		}
+	}
+}
+
+// build consumes the right input and stores its rows in the hash table.
+func (h *hashJoiner) build(ctx context.Context) error {
+	keys := makeKeyEncoder(h.rightEqCols)
+	idxs := make([]int, 0, ColBatchSize)
+	for {
+		batch, err := h.right.Next(ctx)
+		if err != nil {
+			return err
+		}
+		if batch.Length() == 0 {
+			return nil
+		}
+		sel := selectedRows(batch)
+		keys.encode(batch, sel)
+		idxs = idxs[:0]
+		var size int64
+		for j, i := range sel {
+			if keys.nulls[j] {
+				continue
+			}
+			idxs = append(idxs, i)
+			size += int64(len(keys.keys[j]) + buildRowSizeEstimate)
+		}
+		for _, vec := range batch.ColVecs() {
+			if vec.Type() == Bytes {
+				col := vec.Bytes()
+				for _, i := range idxs {
+					size += int64(len(col[i]))
+				}
+			} else {
+				size += int64(len(idxs) * 8)
+			}
+		}
+		if err := h.acc.Grow(ctx, size); err != nil {
+			return err
+		}
+
+		row := len(h.next)
+		for c, vec := range batch.ColVecs() {
+			h.buildCols[c].appendValues(vec, idxs)
+		}
+		for j := range sel {
+			if keys.nulls[j] {
+				continue
+			}
+			key := string(keys.keys[j])
+			h.next = append(h.next, h.head[key])
+			h.head[key] = row + 1
+			row++
+		}
+	}
+}
+
+// emit writes the n joined rows whose indexes are in leftIdxs and rightIdxs to
+// the output batch.
+func (h *hashJoiner) emit(n int) {
+	for i := range h.leftTypes {
+		out := h.output.ColVec(i)
+		out.UnsetNulls()
+		gather(out, h.probeBatch.ColVec(i), h.leftIdxs[:n])
+	}
+	for i := range h.rightTypes {
+		out := h.output.ColVec(len(h.leftTypes) + i)
+		out.UnsetNulls()
+		gather(out, h.buildCols[i], h.rightIdxs[:n])
+	}
+	h.output.SetLength(n)
+}
@@ -0,0 +1,140 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package exec
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"golang.org/x/net/context"
+)
+
+func TestHashJoiner(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	testCases := []struct {
+		description           string
+		leftTypes, rightTypes []T
+		left, right           tuples
+		leftEqCols            []int
+		rightEqCols           []int
+		expected              tuples
+	}{
+		{
+			description: "one to one",
+			leftTypes:   []T{Int64, Bytes},
+			rightTypes:  []T{Int64, Float64},
+			left:        tuples{{1, "a"}, {2, "b"}, {3, "c"}, {nil, "d"}},
+			right:       tuples{{3, 3.5}, {1, 1.5}, {4, 4.5}, {nil, 0.5}},
+			leftEqCols:  []int{0},
+			rightEqCols: []int{0},
+			expected:    tuples{{1, "a", 1, 1.5}, {3, "c", 3, 3.5}},
+		},
+		{
+			description: "many to many",
+			leftTypes:   []T{Int64},
+			rightTypes:  []T{Int64, Bytes},
+			left:        tuples{{1}, {1}, {2}},
+			right:       tuples{{1, "x"}, {2, "y"}, {1, "z"}, {2, nil}},
+			leftEqCols:  []int{0},
+			rightEqCols: []int{0},
+			expected: tuples{
+				{1, 1, "x"}, {1, 1, "z"}, {1, 1, "x"}, {1, 1, "z"}, {2, 2, "y"}, {2, 2, nil},
+			},
+		},
+		{
+			description: "multiple equality columns",
+			leftTypes:   []T{Bytes, Int64},
+			rightTypes:  []T{Int64, Bytes},
+			left:        tuples{{"a", 1}, {"a", 2}, {"b", 1}, {"a", nil}},
+			right:       tuples{{1, "a"}, {1, "b"}, {2, "b"}, {nil, "a"}},
+			leftEqCols:  []int{0, 1},
+			rightEqCols: []int{1, 0},
+			expected:    tuples{{"a", 1, 1, "a"}, {"b", 1, 1, "b"}},
+		},
+		{
+			description: "empty right side",
+			leftTypes:   []T{Int64},
+			rightTypes:  []T{Int64},
+			left:        tuples{{1}, {2}},
+			leftEqCols:  []int{0},
+			rightEqCols: []int{0},
+			expected:    nil,
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.description, func(t *testing.T) {
+			runTests(t, c.leftTypes, c.left, func(t *testing.T, left *opTestInput) {
+				for _, rightBatchSize := range []int{1, 3, ColBatchSize} {
+					ctx := context.Background()
+					m, acc := newTestAccount(ctx)
+
+					// Reset the left input, which is consumed by each run.
+					l := *left
+					right := newOpTestInput(c.rightTypes, rightBatchSize, c.right)
+					op, err := NewHashJoiner(
+						&l, right, c.leftTypes, c.rightTypes, c.leftEqCols, c.rightEqCols, acc,
+					)
+					if err != nil {
+						t.Fatal(err)
+					}
+					res, err := collect(op)
+					if err != nil {
+						t.Fatal(err)
+					}
+					assertTuplesEqual(t, c.expected, res, true /* unordered */)
+					acc.Close(ctx)
+					m.Stop(ctx)
+				}
+			})
+		})
+	}
+}
+
+func TestHashJoinerManyMatches(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	// Each left row matches more rows than fit in a batch.
+	const numRight = ColBatchSize + 10
+	right := make(tuples, numRight)
+	for i := range right {
+		right[i] = tuple{7, i}
+	}
+	left := tuples{{7}, {8}, {7}}
+	ctx := context.Background()
+	m, acc := newTestAccount(ctx)
+	defer m.Stop(ctx)
+	defer acc.Close(ctx)
+
+	op, err := NewHashJoiner(
+		newOpTestInput([]T{Int64}, 2, left), newOpTestInput([]T{Int64, Int64}, 100, right),
+		[]T{Int64}, []T{Int64, Int64}, []int{0}, []int{0}, acc,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := collect(op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected tuples
+	for i := 0; i < 2; i++ {
+		for j := range right {
+			expected = append(expected, tuple{7, 7, j})
+		}
+	}
+	assertTuplesEqual(t, expected, res, true /* unordered */)
+}
@@ -0,0 +1,99 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package exec
+
+import "golang.org/x/net/context"
+
+// offsetOp is an Operator that skips the first rows of its input.
+type offsetOp struct {
+	input  Operator
+	offset int
+	seen   int
+}
+
+var _ Operator = &offsetOp{}
+
+// NewOffsetOp returns an Operator that skips the first offset rows of its
+// input.
+func NewOffsetOp(input Operator, offset int) Operator {
+	return &offsetOp{input: input, offset: offset}
+}
+
+func (o *offsetOp) Init() {
+	o.input.Init()
+}
+
+func (o *offsetOp) Next(ctx context.Context) (ColBatch, error) {
+	for {
+		batch, err := o.input.Next(ctx)
+		if err != nil {
+			return nil, err
+		}
+		n := batch.Length()
+		if n == 0 || o.seen >= o.offset {
+			return batch, nil
+		}
+		if o.seen+n <= o.offset {
+			o.seen += n
+			continue
+		}
+		skip := o.offset - o.seen
+		o.seen = o.offset
+		if sel := batch.Selection(); sel != nil {
+			copy(sel, sel[skip:n])
+		} else {
+			batch.SetSelection(true)
+			copy(batch.Selection(), identity[skip:n])
+		}
+		batch.SetLength(n - skip)
+		return batch, nil
+	}
+}
+
+// limitOp is an Operator that stops after the first rows of its input.
+type limitOp struct {
+	input Operator
+	limit int
+	seen  int
+}
+
+var _ Operator = &limitOp{}
+
+// NewLimitOp returns an Operator that returns the first limit rows of its
+// input. Its input is not read any further once the limit is reached.
+func NewLimitOp(input Operator, limit int) Operator {
+	return &limitOp{input: input, limit: limit}
+}
+
+func (l *limitOp) Init() {
+	l.input.Init()
+}
+
+func (l *limitOp) Next(ctx context.Context) (ColBatch, error) {
+	if l.seen >= l.limit {
+		return zeroBatch, nil
+	}
+	batch, err := l.input.Next(ctx)
+	if err != nil {
+		return nil, err
+	}
+	n := batch.Length()
+	if l.seen+n > l.limit {
+		n = l.limit - l.seen
+		batch.SetLength(n)
+	}
+	l.seen += n
+	return batch, nil
+}
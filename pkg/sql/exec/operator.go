@@ -0,0 +1,35 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package exec
+
+import "golang.org/x/net/context"
+
+// Operator is a vectorized operator: a node of a tree of operators that
+// produces its output a ColBatch at a time.
+type Operator interface {
+	// Init initializes the operator. It is called once, before the first call
+	// to Next.
+	Init()
+
+	// Next returns the next batch of the operator's output. A batch of length
+	// zero signals that the output is exhausted; subsequent calls return
+	// zero-length batches as well. The returned batch is owned by the operator
+	// and is only valid until the next call to Next, which can reuse it.
+	Next(ctx context.Context) (ColBatch, error)
+}
+
+// zeroBatch is a batch of length zero returned by operators that don't get a
+// batch from their input when their output is exhausted.
+var zeroBatch = NewMemBatch(nil)
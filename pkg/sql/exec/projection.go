@@ -0,0 +1,221 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package exec
+
+import (
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+// simpleProjectOp is an Operator that exposes a subset of the columns of the
+// batches of its input.
+type simpleProjectOp struct {
+	input Operator
+	batch projectingBatch
+}
+
+var _ Operator = &simpleProjectOp{}
+
+// NewSimpleProjectOp returns an Operator whose output columns are the columns
+// of its input with the given indexes.
+func NewSimpleProjectOp(input Operator, projection []int) Operator {
+	p := &simpleProjectOp{input: input}
+	p.batch.projection = append([]int(nil), projection...)
+	return p
+}
+
+func (p *simpleProjectOp) Init() {
+	p.input.Init()
+}
+
+func (p *simpleProjectOp) Next(ctx context.Context) (ColBatch, error) {
+	batch, err := p.input.Next(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Operators reuse their output batch, so columns appended to the batch by
+	// the operators above this one are appended once, and stay part of the
+	// projection.
+	p.batch.ColBatch = batch
+	return &p.batch, nil
+}
+
+// BinOp is an arithmetic operator.
+type BinOp int
+
+const (
+	// Plus is the + operator.
+	Plus BinOp = iota
+	// Minus is the - operator.
+	Minus
+	// Mult is the * operator.
+	Mult
+)
+
+var errIntOutOfRange = pgerror.NewError(pgerror.CodeNumericValueOutOfRangeError,
+	"integer out of range")
+
+// projBinOp is an Operator that adds a column to the batches of its input,
+// holding the result of an arithmetic operator applied to two columns or to a
+// column and a constant. The result is NULL if either operand is NULL.
+type projBinOp struct {
+	input     Operator
+	op        BinOp
+	t         T
+	leftIdx   int
+	rightIdx  int
+	leftVec   ColVec
+	rightVec  ColVec
+	outputIdx int
+}
+
+var _ Operator = &projBinOp{}
+
+// ProjOperand is an operand of a projection operator: either a column of the
+// input or a constant.
+type ProjOperand struct {
+	// ColIdx is the index of the column, if Const is nil.
+	ColIdx int
+	// Const is the value of the constant, of the Go type that holds values of
+	// the operator's type.
+	Const interface{}
+}
+
+// NewProjBinOp returns an Operator that appends to each batch of its input a
+// column of type t that contains the result of applying op to the operands,
+// which must be of type t as well. outputIdx is the index of the appended
+// column, which must be the width of the input batches. Int64 results that
+// overflow cause an error.
+func NewProjBinOp(
+	input Operator, op BinOp, t T, left, right ProjOperand, outputIdx int,
+) (Operator, error) {
+	if t != Int64 && t != Float64 {
+		return nil, errors.Errorf("unsupported arithmetic on %s", t)
+	}
+	if left.Const != nil && right.Const != nil {
+		return nil, errors.Errorf("arithmetic on two constants")
+	}
+	p := &projBinOp{
+		input:     input,
+		op:        op,
+		t:         t,
+		leftIdx:   left.ColIdx,
+		rightIdx:  right.ColIdx,
+		outputIdx: outputIdx,
+	}
+	if left.Const != nil {
+		p.leftVec = newConstColumn(t, left.Const)
+	}
+	if right.Const != nil {
+		p.rightVec = newConstColumn(t, right.Const)
+	}
+	return p, nil
+}
+
+func (p *projBinOp) Init() {
+	p.input.Init()
+}
+
+func (p *projBinOp) Next(ctx context.Context) (ColBatch, error) {
+	batch, err := p.input.Next(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if batch.Length() == 0 {
+		return batch, nil
+	}
+	if batch.Width() == p.outputIdx {
+		batch.AppendCol(p.t)
+	}
+	left, right := p.leftVec, p.rightVec
+	if left == nil {
+		left = batch.ColVec(p.leftIdx)
+	}
+	if right == nil {
+		right = batch.ColVec(p.rightIdx)
+	}
+	out := batch.ColVec(p.outputIdx)
+	out.UnsetNulls()
+	sel := selectedRows(batch)
+	hasNulls := left.HasNulls() || right.HasNulls()
+	if hasNulls {
+		for _, i := range sel {
+			if left.NullAt(i) || right.NullAt(i) {
+				out.SetNull(i)
+			}
+		}
+	}
+
+	switch p.t {
+	case Int64:
+		l, r, o := left.Int64(), right.Int64(), out.Int64()
+		for _, i := range sel {
+			if hasNulls && out.NullAt(i) {
+				continue
+			}
+			var ok bool
+			switch p.op {
+			case Plus:
+				o[i], ok = addInt64(l[i], r[i])
+			case Minus:
+				o[i], ok = subInt64(l[i], r[i])
+			case Mult:
+				o[i], ok = mulInt64(l[i], r[i])
+			}
+			if !ok {
+				return nil, errIntOutOfRange
+			}
+		}
+	case Float64:
+		l, r, o := left.Float64(), right.Float64(), out.Float64()
+		switch p.op {
+		case Plus:
+			for _, i := range sel {
+				o[i] = l[i] + r[i]
+			}
+		case Minus:
+			for _, i := range sel {
+				o[i] = l[i] - r[i]
+			}
+		case Mult:
+			for _, i := range sel {
+				o[i] = l[i] * r[i]
+			}
+		}
+	}
+	return batch, nil
+}
+
+// addInt64 returns a+b and whether the addition didn't overflow.
+func addInt64(a, b int64) (int64, bool) {
+	r := a + b
+	return r, (r < a) == (b < 0)
+}
+
+// subInt64 returns a-b and whether the subtraction didn't overflow.
+func subInt64(a, b int64) (int64, bool) {
+	r := a - b
+	return r, (r < a) == (b > 0)
+}
+
+// mulInt64 returns a*b and whether the multiplication didn't overflow.
+func mulInt64(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	r := a * b
+	return r, (r < 0) == ((a < 0) != (b < 0)) && r/b == a
+}
@@ -0,0 +1,194 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package exec
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func TestProjBinOp(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	col := func(i int) ProjOperand { return ProjOperand{ColIdx: i} }
+	cnst := func(v interface{}) ProjOperand { return ProjOperand{Const: v} }
+
+	testCases := []struct {
+		description string
+		types       []T
+		tuples      tuples
+		op          BinOp
+		t           T
+		left, right ProjOperand
+		expected    tuples
+		expectedErr string
+	}{
+		{
+			description: "int + int",
+			types:       []T{Int64, Int64},
+			tuples:      tuples{{1, 2}, {nil, 3}, {4, nil}, {-5, 5}},
+			op:          Plus,
+			t:           Int64,
+			left:        col(0),
+			right:       col(1),
+			expected:    tuples{{1, 2, 3}, {nil, 3, nil}, {4, nil, nil}, {-5, 5, 0}},
+		},
+		{
+			description: "const - int",
+			types:       []T{Int64},
+			tuples:      tuples{{1}, {nil}, {10}},
+			op:          Minus,
+			t:           Int64,
+			left:        cnst(int64(5)),
+			right:       col(0),
+			expected:    tuples{{1, 4}, {nil, nil}, {10, -5}},
+		},
+		{
+			description: "float * const",
+			types:       []T{Float64},
+			tuples:      tuples{{1.5}, {nil}, {-2.0}},
+			op:          Mult,
+			t:           Float64,
+			left:        col(0),
+			right:       cnst(2.0),
+			expected:    tuples{{1.5, 3.0}, {nil, nil}, {-2.0, -4.0}},
+		},
+		{
+			description: "int * int overflow",
+			types:       []T{Int64, Int64},
+			tuples:      tuples{{1, 2}, {math.MaxInt64, 2}},
+			op:          Mult,
+			t:           Int64,
+			left:        col(0),
+			right:       col(1),
+			expectedErr: "integer out of range",
+		},
+		{
+			description: "int - const overflow",
+			types:       []T{Int64},
+			tuples:      tuples{{math.MinInt64}},
+			op:          Minus,
+			t:           Int64,
+			left:        col(0),
+			right:       cnst(int64(1)),
+			expectedErr: "integer out of range",
+		},
+		{
+			description: "int + int with a NULL",
+			types:       []T{Int64, Int64},
+			tuples:      tuples{{nil, 2}},
+			op:          Plus,
+			t:           Int64,
+			left:        col(0),
+			right:       col(1),
+			expected:    tuples{{nil, 2, nil}},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.description, func(t *testing.T) {
+			runTests(t, c.types, c.tuples, func(t *testing.T, input *opTestInput) {
+				op, err := NewProjBinOp(input, c.op, c.t, c.left, c.right, len(c.types))
+				if err != nil {
+					t.Fatal(err)
+				}
+				res, err := collect(op)
+				if c.expectedErr != "" {
+					if !testutils.IsError(err, c.expectedErr) {
+						t.Fatalf("expected error %q, got %v", c.expectedErr, err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				assertTuplesEqual(t, c.expected, res, false /* unordered */)
+			})
+		})
+	}
+}
+
+func TestMulInt64(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	testCases := []struct {
+		a, b int64
+		ok   bool
+	}{
+		{0, math.MinInt64, true},
+		{-1, math.MaxInt64, true},
+		{-1, math.MinInt64, false},
+		{math.MinInt64, -1, false},
+		{math.MaxInt64 / 2, 2, true},
+		{math.MaxInt64/2 + 1, 2, false},
+		{math.MinInt64 / 2, 2, true},
+		{3037000500, 3037000500, false},
+		{-3037000499, 3037000499, true},
+	}
+	for _, c := range testCases {
+		if r, ok := mulInt64(c.a, c.b); ok != c.ok {
+			t.Errorf("%d * %d: expected ok=%t, got %t (%d)", c.a, c.b, c.ok, ok, r)
+		}
+	}
+}
+
+func TestSimpleProjectOp(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	tups := tuples{{1, "a", 1.5}, {2, nil, 2.5}}
+	runTests(t, []T{Int64, Bytes, Float64}, tups, func(t *testing.T, input *opTestInput) {
+		// The projection is followed by an operator that appends a column.
+		proj, err := NewProjBinOp(
+			NewSimpleProjectOp(input, []int{2, 0}), Plus, Int64,
+			ProjOperand{ColIdx: 1}, ProjOperand{Const: int64(1)}, 2, /* outputIdx */
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := collect(proj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertTuplesEqual(t, tuples{{1.5, 1, 2}, {2.5, 2, 3}}, res, false /* unordered */)
+	})
+}
+
+func TestLimitOffset(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	tups := tuples{{1}, {2}, {3}, {4}, {5}}
+	testCases := []struct {
+		offset, limit int
+		expected      tuples
+	}{
+		{offset: 0, limit: 2, expected: tuples{{1}, {2}}},
+		{offset: 2, limit: 10, expected: tuples{{3}, {4}, {5}}},
+		{offset: 1, limit: 3, expected: tuples{{2}, {3}, {4}}},
+		{offset: 5, limit: 1, expected: nil},
+		{offset: 0, limit: 0, expected: nil},
+	}
+	for _, c := range testCases {
+		runTests(t, []T{Int64}, tups, func(t *testing.T, input *opTestInput) {
+			res, err := collect(NewLimitOp(NewOffsetOp(input, c.offset), c.limit))
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertTuplesEqual(t, c.expected, res, false /* unordered */)
+		})
+	}
+}
@@ -0,0 +1,249 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package exec
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+
+	"golang.org/x/net/context"
+)
+
+// CmpOp is a comparison operator.
+type CmpOp int
+
+const (
+	// EQ is the = operator.
+	EQ CmpOp = iota
+	// NE is the != operator.
+	NE
+	// LT is the < operator.
+	LT
+	// LE is the <= operator.
+	LE
+	// GT is the > operator.
+	GT
+	// GE is the >= operator.
+	GE
+)
+
+// Flip returns the operator that gives the same result when the operands are
+// swapped.
+func (o CmpOp) Flip() CmpOp {
+	switch o {
+	case LT:
+		return GT
+	case LE:
+		return GE
+	case GT:
+		return LT
+	case GE:
+		return LE
+	}
+	return o
+}
+
+// matches returns, for each of the results -1, 0 and 1 of a three-way
+// comparison (shifted by one), whether the operator is satisfied.
+func (o CmpOp) matches() [3]bool {
+	switch o {
+	case EQ:
+		return [3]bool{false, true, false}
+	case NE:
+		return [3]bool{true, false, true}
+	case LT:
+		return [3]bool{true, false, false}
+	case LE:
+		return [3]bool{true, true, false}
+	case GT:
+		return [3]bool{false, false, true}
+	case GE:
+		return [3]bool{false, true, true}
+	}
+	panic(fmt.Sprintf("unknown comparison operator %d", o))
+}
+
+func compareBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	default:
+		return -1
+	}
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// compareFloat64 compares floats like tree.DFloat does: NaN is equal to itself
+// and smaller than any other value.
+func compareFloat64(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	case a == b:
+		return 0
+	}
+	aNaN, bNaN := math.IsNaN(a), math.IsNaN(b)
+	switch {
+	case aNaN && bNaN:
+		return 0
+	case aNaN:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// selCmpOp is an Operator that filters the rows of its input, keeping those
+// for which the comparison of two columns, or of a column and a constant, is
+// true. Rows for which either value is NULL are filtered out.
+type selCmpOp struct {
+	input    Operator
+	op       CmpOp
+	matches  [3]bool
+	leftIdx  int
+	rightIdx int
+	// constVec, if set, holds the constant that the left column is compared
+	// to, in place of the right column.
+	constVec ColVec
+}
+
+var _ Operator = &selCmpOp{}
+
+// NewSelColCmpOp returns an Operator that keeps the rows of its input for
+// which the comparison of the given columns, which must be of the same type,
+// is true.
+func NewSelColCmpOp(input Operator, op CmpOp, leftIdx, rightIdx int) Operator {
+	return &selCmpOp{input: input, op: op, leftIdx: leftIdx, rightIdx: rightIdx}
+}
+
+// NewSelConstCmpOp returns an Operator that keeps the rows of its input for
+// which the comparison of the given column and a constant is true. The
+// constant must be of the Go type that holds values of type t: bool, []byte,
+// int64 or float64.
+func NewSelConstCmpOp(input Operator, op CmpOp, colIdx int, t T, constVal interface{}) Operator {
+	return &selCmpOp{
+		input:    input,
+		op:       op,
+		leftIdx:  colIdx,
+		constVec: newConstColumn(t, constVal),
+	}
+}
+
+func (p *selCmpOp) Init() {
+	p.matches = p.op.matches()
+	p.input.Init()
+}
+
+func (p *selCmpOp) Next(ctx context.Context) (ColBatch, error) {
+	for {
+		batch, err := p.input.Next(ctx)
+		if err != nil {
+			return nil, err
+		}
+		n := batch.Length()
+		if n == 0 {
+			return batch, nil
+		}
+		left := batch.ColVec(p.leftIdx)
+		right := p.constVec
+		if right == nil {
+			right = batch.ColVec(p.rightIdx)
+		}
+		sel := batch.Selection()
+		if sel == nil {
+			batch.SetSelection(true)
+			sel = batch.Selection()
+			copy(sel, identity[:n])
+		}
+		sel = sel[:n]
+		if left.HasNulls() || right.HasNulls() {
+			sel = sel[:selectNotNull(sel, left, right)]
+		}
+		if n = p.selectRows(sel, left, right); n > 0 {
+			batch.SetLength(n)
+			return batch, nil
+		}
+	}
+}
+
+// selectNotNull keeps the rows of sel for which neither vector is NULL,
+// compacting them at the beginning of sel, and returns their number.
+func selectNotNull(sel []int, left, right ColVec) int {
+	idx := 0
+	for _, i := range sel {
+		if !left.NullAt(i) && !right.NullAt(i) {
+			sel[idx] = i
+			idx++
+		}
+	}
+	return idx
+}
+
+// selectRows keeps the rows of sel for which the comparison is true,
+// compacting them at the beginning of sel, and returns their number.
+func (p *selCmpOp) selectRows(sel []int, left, right ColVec) int {
+	m := p.matches
+	idx := 0
+	switch left.Type() {
+	case Bool:
+		l, r := left.Bool(), right.Bool()
+		for _, i := range sel {
+			if m[compareBool(l[i], r[i])+1] {
+				sel[idx] = i
+				idx++
+			}
+		}
+	case Bytes:
+		l, r := left.Bytes(), right.Bytes()
+		for _, i := range sel {
+			if m[bytes.Compare(l[i], r[i])+1] {
+				sel[idx] = i
+				idx++
+			}
+		}
+	case Int64:
+		l, r := left.Int64(), right.Int64()
+		for _, i := range sel {
+			if m[compareInt64(l[i], r[i])+1] {
+				sel[idx] = i
+				idx++
+			}
+		}
+	case Float64:
+		l, r := left.Float64(), right.Float64()
+		for _, i := range sel {
+			if m[compareFloat64(l[i], r[i])+1] {
+				sel[idx] = i
+				idx++
+			}
+		}
+	}
+	return idx
+}
@@ -0,0 +1,101 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package exec
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func TestSelCmpOp(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	nan := math.NaN()
+	testCases := []struct {
+		description string
+		types       []T
+		tuples      tuples
+		op          func(input Operator) Operator
+		expected    tuples
+	}{
+		{
+			description: "int < const",
+			types:       []T{Int64, Int64},
+			tuples:      tuples{{1, 10}, {5, 20}, {nil, 30}, {3, nil}, {-2, 40}},
+			op:          func(input Operator) Operator { return NewSelConstCmpOp(input, LT, 0, Int64, int64(4)) },
+			expected:    tuples{{1, 10}, {3, nil}, {-2, 40}},
+		},
+		{
+			description: "int = int",
+			types:       []T{Int64, Int64},
+			tuples:      tuples{{1, 1}, {5, 2}, {nil, nil}, {3, nil}, {4, 4}},
+			op:          func(input Operator) Operator { return NewSelColCmpOp(input, EQ, 0, 1) },
+			expected:    tuples{{1, 1}, {4, 4}},
+		},
+		{
+			description: "float >= const, with NaNs",
+			types:       []T{Float64},
+			tuples:      tuples{{1.5}, {nan}, {-0.5}, {nil}, {2.0}},
+			op:          func(input Operator) Operator { return NewSelConstCmpOp(input, GE, 0, Float64, 1.5) },
+			expected:    tuples{{1.5}, {2.0}},
+		},
+		{
+			description: "float = float, with NaNs",
+			types:       []T{Float64, Float64},
+			tuples:      tuples{{1.5, 1.5}, {nan, nan}, {nan, 0.0}, {0.0, nan}},
+			op:          func(input Operator) Operator { return NewSelColCmpOp(input, EQ, 0, 1) },
+			expected:    tuples{{1.5, 1.5}, {nan, nan}},
+		},
+		{
+			description: "bytes != const",
+			types:       []T{Bytes},
+			tuples:      tuples{{"a"}, {"b"}, {nil}, {"ab"}},
+			op:          func(input Operator) Operator { return NewSelConstCmpOp(input, NE, 0, Bytes, []byte("a")) },
+			expected:    tuples{{"b"}, {"ab"}},
+		},
+		{
+			description: "bool > bool",
+			types:       []T{Bool, Bool},
+			tuples:      tuples{{true, false}, {false, true}, {true, true}},
+			op:          func(input Operator) Operator { return NewSelColCmpOp(input, GT, 0, 1) },
+			expected:    tuples{{true, false}},
+		},
+		{
+			description: "conjunction",
+			types:       []T{Int64, Int64},
+			tuples:      tuples{{1, 10}, {2, 20}, {3, 30}, {4, 40}},
+			op: func(input Operator) Operator {
+				return NewSelConstCmpOp(
+					NewSelConstCmpOp(input, GT, 0, Int64, int64(1)), LE, 1, Int64, int64(30),
+				)
+			},
+			expected: tuples{{2, 20}, {3, 30}},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.description, func(t *testing.T) {
+			runTests(t, c.types, c.tuples, func(t *testing.T, input *opTestInput) {
+				res, err := collect(c.op(input))
+				if err != nil {
+					t.Fatal(err)
+				}
+				assertTuplesEqual(t, c.expected, res, false /* unordered */)
+			})
+		})
+	}
+}
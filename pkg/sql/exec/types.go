@@ -0,0 +1,90 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package exec implements vectorized, batch-at-a-time execution of SQL
+// operators. Data flows through a tree of Operators as ColBatches: sets of
+// typed column vectors that are processed a column at a time, without boxing
+// every value into a Datum.
+package exec
+
+import (
+	"fmt"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/pkg/errors"
+)
+
+// T is the physical type of the values of a column vector.
+type T int
+
+const (
+	// Bool is a column of bool values.
+	Bool T = iota
+	// Bytes is a column of []byte values, used for both STRING and BYTES
+	// columns.
+	Bytes
+	// Int64 is a column of int64 values.
+	Int64
+	// Float64 is a column of float64 values.
+	Float64
+	// Unhandled is the type of columns that can't be processed by the
+	// vectorized engine.
+	Unhandled
+)
+
+func (t T) String() string {
+	switch t {
+	case Bool:
+		return "Bool"
+	case Bytes:
+		return "Bytes"
+	case Int64:
+		return "Int64"
+	case Float64:
+		return "Float64"
+	case Unhandled:
+		return "Unhandled"
+	default:
+		return fmt.Sprintf("T(%d)", int(t))
+	}
+}
+
+// FromColumnType returns the physical type that holds values of the given
+// column type, or Unhandled if the vectorized engine doesn't support it.
+func FromColumnType(ct sqlbase.ColumnType) T {
+	switch ct.SemanticType {
+	case sqlbase.ColumnType_BOOL:
+		return Bool
+	case sqlbase.ColumnType_BYTES, sqlbase.ColumnType_STRING:
+		return Bytes
+	case sqlbase.ColumnType_INT:
+		return Int64
+	case sqlbase.ColumnType_FLOAT:
+		return Float64
+	}
+	return Unhandled
+}
+
+// FromColumnTypes calls FromColumnType on each of the given column types. It
+// returns an error if any of them is Unhandled.
+func FromColumnTypes(cts []sqlbase.ColumnType) ([]T, error) {
+	typs := make([]T, len(cts))
+	for i := range cts {
+		typs[i] = FromColumnType(cts[i])
+		if typs[i] == Unhandled {
+			return nil, errors.Errorf("unsupported column type %s", cts[i].SQLString())
+		}
+	}
+	return typs, nil
+}
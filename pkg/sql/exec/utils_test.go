@@ -0,0 +1,168 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package exec
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/util/mon"
+	"golang.org/x/net/context"
+)
+
+// tuple is a row of test data; nil is a NULL.
+type tuple []interface{}
+
+type tuples []tuple
+
+// opTestInput is an Operator that outputs test tuples, in batches of at most
+// batchSize rows.
+type opTestInput struct {
+	types     []T
+	tuples    tuples
+	batchSize int
+	// useSel, if set, makes the batches use a selection vector that skips
+	// every other row of their column vectors.
+	useSel bool
+
+	batch ColBatch
+}
+
+var _ Operator = &opTestInput{}
+
+func newOpTestInput(types []T, batchSize int, tups tuples) *opTestInput {
+	return &opTestInput{types: types, tuples: tups, batchSize: batchSize}
+}
+
+func (s *opTestInput) Init() {
+	s.batch = NewMemBatch(s.types)
+}
+
+func (s *opTestInput) Next(context.Context) (ColBatch, error) {
+	n := s.batchSize
+	if n > len(s.tuples) {
+		n = len(s.tuples)
+	}
+	tups := s.tuples[:n]
+	s.tuples = s.tuples[n:]
+
+	s.batch.SetSelection(s.useSel)
+	// Operators above the input can append columns to its batch.
+	for c := range s.types {
+		vec := s.batch.ColVec(c)
+		vec.UnsetNulls()
+		for j, tup := range tups {
+			i := j
+			if s.useSel {
+				i = 2*j + 1
+				s.batch.Selection()[j] = i
+			}
+			if tup[c] == nil {
+				vec.SetNull(i)
+				continue
+			}
+			switch vec.Type() {
+			case Bool:
+				vec.Bool()[i] = tup[c].(bool)
+			case Bytes:
+				vec.Bytes()[i] = []byte(tup[c].(string))
+			case Int64:
+				vec.Int64()[i] = int64(tup[c].(int))
+			case Float64:
+				vec.Float64()[i] = tup[c].(float64)
+			}
+		}
+	}
+	s.batch.SetLength(n)
+	return s.batch, nil
+}
+
+// collect runs the operator to completion and returns its output as tuples.
+func collect(op Operator) (tuples, error) {
+	ctx := context.Background()
+	op.Init()
+	var res tuples
+	for {
+		batch, err := op.Next(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if batch.Length() == 0 {
+			return res, nil
+		}
+		for _, i := range selectedRows(batch) {
+			tup := make(tuple, batch.Width())
+			for c, vec := range batch.ColVecs() {
+				if vec.NullAt(i) {
+					continue
+				}
+				switch vec.Type() {
+				case Bool:
+					tup[c] = vec.Bool()[i]
+				case Bytes:
+					tup[c] = string(vec.Bytes()[i])
+				case Int64:
+					tup[c] = int(vec.Int64()[i])
+				case Float64:
+					tup[c] = vec.Float64()[i]
+				}
+			}
+			res = append(res, tup)
+		}
+	}
+}
+
+// runTests runs the given test with inputs that deliver the tuples in batches
+// of various sizes, with and without selection vectors.
+func runTests(t *testing.T, types []T, tups tuples, test func(t *testing.T, input *opTestInput)) {
+	for _, batchSize := range []int{1, 2, 3, ColBatchSize / 2} {
+		for _, useSel := range []bool{false, true} {
+			t.Run(fmt.Sprintf("batchSize=%d/useSel=%t", batchSize, useSel), func(t *testing.T) {
+				input := newOpTestInput(types, batchSize, tups)
+				input.useSel = useSel
+				test(t, input)
+			})
+		}
+	}
+}
+
+// assertTuplesEqual checks that the given tuples are equal, ignoring their
+// order if unordered is set.
+func assertTuplesEqual(t *testing.T, expected, actual tuples, unordered bool) {
+	e, a := fmt.Sprint(expected), fmt.Sprint(actual)
+	if unordered {
+		e, a = sortedTuples(expected), sortedTuples(actual)
+	}
+	if e != a {
+		t.Errorf("expected %s, got %s", e, a)
+	}
+}
+
+func sortedTuples(tups tuples) string {
+	s := make([]string, len(tups))
+	for i := range tups {
+		s[i] = fmt.Sprint(tups[i])
+	}
+	sort.Strings(s)
+	return fmt.Sprint(s)
+}
+
+func newTestAccount(ctx context.Context) (*mon.BytesMonitor, *mon.BoundAccount) {
+	m := mon.MakeUnlimitedMonitor(ctx, "test", mon.MemoryResource, nil, nil, math.MaxInt64)
+	acc := m.MakeBoundAccount()
+	return &m, &acc
+}
@@ -0,0 +1,220 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package exec
+
+import "fmt"
+
+// ColVec is a column vector: the values of one column of a ColBatch. The
+// values are accessed through the typed slice accessor that corresponds to the
+// vector's type; calling another accessor panics.
+type ColVec interface {
+	// Type returns the type of the values of the vector.
+	Type() T
+
+	// Bool returns the values of a Bool vector.
+	Bool() []bool
+	// Bytes returns the values of a Bytes vector.
+	Bytes() [][]byte
+	// Int64 returns the values of an Int64 vector.
+	Int64() []int64
+	// Float64 returns the values of a Float64 vector.
+	Float64() []float64
+
+	// HasNulls returns true if any of the values of the vector is NULL.
+	HasNulls() bool
+	// NullAt returns true if the i-th value is NULL.
+	NullAt(i int) bool
+	// SetNull marks the i-th value as NULL.
+	SetNull(i int)
+	// UnsetNulls marks all the values as not NULL.
+	UnsetNulls()
+}
+
+// memColumn is the in-memory ColVec implementation. The values are stored in a
+// typed slice; the NULLs in a separate slice that is only consulted when
+// hasNulls is set.
+type memColumn struct {
+	t        T
+	col      interface{}
+	nulls    []bool
+	hasNulls bool
+}
+
+var _ ColVec = &memColumn{}
+
+// newMemColumn returns a memColumn of the given type that holds n values. An
+// Unhandled memColumn holds no values; it is a placeholder for a column that
+// isn't used.
+func newMemColumn(t T, n int) *memColumn {
+	m := &memColumn{t: t, nulls: make([]bool, n)}
+	switch t {
+	case Bool:
+		m.col = make([]bool, n)
+	case Bytes:
+		m.col = make([][]byte, n)
+	case Int64:
+		m.col = make([]int64, n)
+	case Float64:
+		m.col = make([]float64, n)
+	case Unhandled:
+	default:
+		panic(fmt.Sprintf("unhandled type %s", t))
+	}
+	return m
+}
+
+// newConstColumn returns a memColumn of ColBatchSize values that are all equal
+// to val, which must be of the Go type that corresponds to t. It is used to
+// apply column-to-column kernels to a column and a constant.
+func newConstColumn(t T, val interface{}) *memColumn {
+	m := newMemColumn(t, ColBatchSize)
+	switch t {
+	case Bool:
+		col, v := m.Bool(), val.(bool)
+		for i := range col {
+			col[i] = v
+		}
+	case Bytes:
+		col, v := m.Bytes(), val.([]byte)
+		for i := range col {
+			col[i] = v
+		}
+	case Int64:
+		col, v := m.Int64(), val.(int64)
+		for i := range col {
+			col[i] = v
+		}
+	case Float64:
+		col, v := m.Float64(), val.(float64)
+		for i := range col {
+			col[i] = v
+		}
+	}
+	return m
+}
+
+func (m *memColumn) Type() T {
+	return m.t
+}
+
+func (m *memColumn) Bool() []bool {
+	return m.col.([]bool)
+}
+
+func (m *memColumn) Bytes() [][]byte {
+	return m.col.([][]byte)
+}
+
+func (m *memColumn) Int64() []int64 {
+	return m.col.([]int64)
+}
+
+func (m *memColumn) Float64() []float64 {
+	return m.col.([]float64)
+}
+
+func (m *memColumn) HasNulls() bool {
+	return m.hasNulls
+}
+
+func (m *memColumn) NullAt(i int) bool {
+	return m.hasNulls && m.nulls[i]
+}
+
+func (m *memColumn) SetNull(i int) {
+	m.nulls[i] = true
+	m.hasNulls = true
+}
+
+func (m *memColumn) UnsetNulls() {
+	if !m.hasNulls {
+		return
+	}
+	for i := range m.nulls {
+		m.nulls[i] = false
+	}
+	m.hasNulls = false
+}
+
+// appendValues appends the values of src at the given indexes to the vector.
+func (m *memColumn) appendValues(src ColVec, idxs []int) {
+	switch m.t {
+	case Bool:
+		col, srcCol := m.Bool(), src.Bool()
+		for _, i := range idxs {
+			col = append(col, srcCol[i])
+		}
+		m.col = col
+	case Bytes:
+		col, srcCol := m.Bytes(), src.Bytes()
+		for _, i := range idxs {
+			// The values of the source are only valid until its next batch.
+			col = append(col, append([]byte(nil), srcCol[i]...))
+		}
+		m.col = col
+	case Int64:
+		col, srcCol := m.Int64(), src.Int64()
+		for _, i := range idxs {
+			col = append(col, srcCol[i])
+		}
+		m.col = col
+	case Float64:
+		col, srcCol := m.Float64(), src.Float64()
+		for _, i := range idxs {
+			col = append(col, srcCol[i])
+		}
+		m.col = col
+	}
+	for _, i := range idxs {
+		m.nulls = append(m.nulls, false)
+		if src.NullAt(i) {
+			m.SetNull(len(m.nulls) - 1)
+		}
+	}
+}
+
+// gather sets the first len(idxs) values of dst to the values of src at the
+// given indexes. dst and src must be of the same type.
+func gather(dst, src ColVec, idxs []int) {
+	switch dst.Type() {
+	case Bool:
+		d, s := dst.Bool(), src.Bool()
+		for j, i := range idxs {
+			d[j] = s[i]
+		}
+	case Bytes:
+		d, s := dst.Bytes(), src.Bytes()
+		for j, i := range idxs {
+			d[j] = s[i]
+		}
+	case Int64:
+		d, s := dst.Int64(), src.Int64()
+		for j, i := range idxs {
+			d[j] = s[i]
+		}
+	case Float64:
+		d, s := dst.Float64(), src.Float64()
+		for j, i := range idxs {
+			d[j] = s[i]
+		}
+	}
+	if src.HasNulls() {
+		for j, i := range idxs {
+			if src.NullAt(i) {
+				dst.SetNull(j)
+			}
+		}
+	}
+}
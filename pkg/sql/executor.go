@@ -135,6 +135,9 @@ var (
 	MetaQuery = metric.Metadata{
 		Name: "sql.query.count",
 		Help: "Number of SQL queries"}
+	MetaAppConcurrencyRejected = metric.Metadata{
+		Name: "sql.application_concurrency.rejected.count",
+		Help: "Number of SQL statements rejected because of the application concurrency limits"}
 )
 
 type traceResult struct {
@@ -191,6 +194,8 @@ type Result struct {
 	// the result set of the result.
 	// TODO(nvanbenschoten): Can this be streamed from the planNode?
 	Rows *sqlbase.RowContainer
+	// Notices are the notices raised by the statement.
+	Notices []*pgerror.Notice
 }
 
 // Close ensures that the resources claimed by the result are released.
@@ -232,6 +237,11 @@ type Executor struct {
 	MiscCount        *metric.Counter
 	QueryCount       *metric.Counter
 
+	// AppConcurrencyRejectedCount counts the statements rejected by
+	// appConcurrency.
+	AppConcurrencyRejectedCount *metric.Counter
+	appConcurrency              *appConcurrencyLimiter
+
 	// System Config and mutex.
 	systemConfig config.SystemConfig
 	// databaseCache is updated with systemConfigMu held, but read atomically in
@@ -278,6 +288,8 @@ type ExecutorConfig struct {
 	StatusServer    serverpb.StatusServer
 	SessionRegistry *SessionRegistry
 	JobRegistry     *jobs.Registry
+	// NotificationRegistry delivers the notifications of NOTIFY.
+	NotificationRegistry *NotificationRegistry
 
 	TestingKnobs              *ExecutorTestingKnobs
 	SchemaChangerTestingKnobs *SchemaChangerTestingKnobs
@@ -325,6 +337,11 @@ type ExecutorTestingKnobs struct {
 	// and normal preparation is short-circuited.
 	BeforePrepare func(ctx context.Context, stmt string, planner *planner) (*PreparedStatement, error)
 
+	// BeforePlan is called by the Executor before it plans a statement. It is
+	// not called for the executions of prepared statements that reuse a cached
+	// plan.
+	BeforePlan func(ctx context.Context, stmt string)
+
 	// BeforeExecute is called by the Executor before plan execution. It is useful
 	// for synchronizing statement execution, such as with parallel statemets.
 	BeforeExecute func(ctx context.Context, stmt string, isParallel bool)
@@ -372,7 +389,7 @@ type DistSQLPlannerTestingKnobs struct {
 // NewExecutor creates an Executor and registers a callback on the
 // system config.
 func NewExecutor(cfg ExecutorConfig, stopper *stop.Stopper) *Executor {
-	return &Executor{
+	e := &Executor{
 		cfg:     cfg,
 		stopper: stopper,
 		reCache: tree.NewRegexpCache(512),
@@ -399,7 +416,11 @@ func NewExecutor(cfg ExecutorConfig, stopper *stop.Stopper) *Executor {
 		MiscCount:   metric.NewCounter(MetaMisc),
 		QueryCount:  metric.NewCounter(MetaQuery),
 		sqlStats:    sqlStats{st: cfg.Settings, apps: make(map[string]*appStats)},
+
+		AppConcurrencyRejectedCount: metric.NewCounter(MetaAppConcurrencyRejected),
 	}
+	e.appConcurrency = makeAppConcurrencyLimiter(cfg.Settings, e.AppConcurrencyRejectedCount)
+	return e
 }
 
 // Start starts workers for the executor.
@@ -634,6 +655,29 @@ func (e *Executor) ExecuteStatements(
 // ExecutePreparedStatement executes the given statement and returns a response.
 func (e *Executor) ExecutePreparedStatement(
 	session *Session, stmt *PreparedStatement, pinfo *tree.PlaceholderInfo,
+) error {
+	return e.execPrepared(session, stmt, nil /* portal */, 0 /* limit */, pinfo)
+}
+
+// ExecutePortal executes the statement of the given portal. If limit isn't 0,
+// at most limit rows are returned: the execution of a statement returning rows
+// is suspended at the limit, and resumed by the next executions of the portal
+// in the same transaction.
+func (e *Executor) ExecutePortal(
+	session *Session, portal *PreparedPortal, pinfo *tree.PlaceholderInfo, limit int,
+) error {
+	return e.execPrepared(session, portal.Stmt, portal, limit, pinfo)
+}
+
+// execPrepared executes a prepared statement, possibly for a portal. It
+// returns an error if there is more than 1 result or the returned types differ
+// from the prepared return types.
+func (e *Executor) execPrepared(
+	session *Session,
+	stmt *PreparedStatement,
+	portal *PreparedPortal,
+	limit int,
+	pinfo *tree.PlaceholderInfo,
 ) error {
 	defer session.maybeRecover("executing", stmt.Str)
 
@@ -653,15 +697,6 @@ func (e *Executor) ExecutePreparedStatement(
 		session.phaseTimes[sessionEndParse] = now
 	}
 
-	return e.execPrepared(session, stmt, pinfo)
-}
-
-// execPrepared executes a prepared statement. It returns an error if there
-// is more than 1 result or the returned types differ from the prepared
-// return types.
-func (e *Executor) execPrepared(
-	session *Session, stmt *PreparedStatement, pinfo *tree.PlaceholderInfo,
-) error {
 	if log.V(2) || logStatementsExecuteEnabled.Get(&e.cfg.Settings.SV) {
 		log.Infof(session.Ctx(), "execPrepared: %s", stmt.Str)
 	}
@@ -672,6 +707,9 @@ func (e *Executor) execPrepared(
 			AST:           stmt.Statement,
 			ExpectedTypes: stmt.Columns,
 			AnonymizedStr: stmt.AnonymizedStr,
+			prepared:      stmt,
+			portal:        portal,
+			limit:         limit,
 		}}
 	}
 	// Send the Request for SQL execution and set the application-level error
@@ -1658,6 +1696,9 @@ func (e *Executor) execStmtInOpenTxn(
 		if err := session.synchronizeParallelStmts(session.Ctx()); err != nil {
 			return err
 		}
+		// The notices raised by the parallelized statements are sent with the
+		// results of this statement.
+		session.parallelNotices.flush(res)
 	}
 
 	if txnState.implicitTxn && !stmtAllowedInImplicitTxn(stmt) {
@@ -1673,7 +1714,7 @@ func (e *Executor) execStmtInOpenTxn(
 	case *tree.CommitTransaction:
 		// CommitTransaction is executed fully here; there's no planNode for it
 		// and a planner is not involved at all.
-		transition = commitSQLTransaction(txnState, commit, res)
+		transition = commitSQLTransaction(session, commit, res)
 		explicitStateTransition = true
 		return nil
 
@@ -1683,7 +1724,7 @@ func (e *Executor) execStmtInOpenTxn(
 		}
 		// ReleaseSavepoint is executed fully here; there's no planNode for it
 		// and a planner is not involved at all.
-		transition = commitSQLTransaction(txnState, release, res)
+		transition = commitSQLTransaction(session, release, res)
 		explicitStateTransition = true
 		return nil
 
@@ -1761,6 +1802,7 @@ func (e *Executor) execStmtInOpenTxn(
 		stmt.AST = ps.Statement
 		stmt.ExpectedTypes = ps.Columns
 		stmt.AnonymizedStr = ps.AnonymizedStr
+		stmt.prepared = ps
 	}
 
 	var p *planner
@@ -1774,6 +1816,9 @@ func (e *Executor) execStmtInOpenTxn(
 		// session.
 		p = &session.planner
 		session.resetPlanner(p, e, txnState.mu.txn)
+		if stmt.prepared != nil {
+			p.usePlanCache(stmt.prepared)
+		}
 	}
 	p.evalCtx.SetTxnTimestamp(txnState.sqlTimestamp)
 	p.evalCtx.SetStmtTimestamp(e.cfg.Clock.PhysicalTime())
@@ -1790,6 +1835,17 @@ func (e *Executor) execStmtInOpenTxn(
 	p.evalCtx.ActiveMemAcc = &constantMemAcc
 	defer constantMemAcc.Close(session.Ctx())
 
+	// The requests of the statement wait for the transactions whose intents
+	// they encounter for at most the lock timeout of the session.
+	txnState.mu.txn.SetLockTimeout(session.LockTimeout)
+
+	// A statement which runs over the statement timeout is canceled like with
+	// CANCEL QUERY.
+	var timeoutTimer *time.Timer
+	if timeout := session.StatementTimeout; timeout > 0 {
+		timeoutTimer = time.AfterFunc(timeout, stmt.queryMeta.cancel)
+	}
+
 	if runInParallel {
 		// Only run statements asynchronously through the parallelize queue if the
 		// statements are parallelized and we're in a transaction. Parallelized
@@ -1799,11 +1855,28 @@ func (e *Executor) execStmtInOpenTxn(
 		err = e.execStmtInParallel(stmt, p, res)
 	} else {
 		p.autoCommit = txnState.implicitTxn && !e.cfg.TestingKnobs.DisableAutoCommit
-		err = e.execStmt(stmt, p, automaticRetryCount, res)
+		// The statement waits until its application is under its concurrency
+		// limit, if any. Parallelized statements aren't limited.
+		var release func()
+		if release, err = e.appConcurrency.acquire(
+			stmt.queryMeta.ctx, session.applicationName(),
+		); err == nil {
+			err = e.execStmt(stmt, p, automaticRetryCount, res)
+			release()
+		}
 		// Zeroing the cached planner allows the GC to clean up any memory hanging
 		// off the planner, which we're finished using at this point.
 	}
 
+	if timeoutTimer != nil && !timeoutTimer.Stop() {
+		// The timer fired, so the context of the transaction was canceled. If
+		// the statement completed, it only matters when the transaction is still
+		// open: a statement whose implicit transaction committed succeeded.
+		if err != nil || !p.autoCommit {
+			err = sqlbase.NewStatementTimeoutError()
+		}
+	}
+
 	if err != nil {
 		if independentFromParallelStmts {
 			// If the statement run was independent from parallelized execution, it
@@ -1900,16 +1973,22 @@ const (
 // commitSQLTransaction executes a COMMIT or RELEASE SAVEPOINT statement. The
 // transaction is committed and the statement result is written to res.
 func commitSQLTransaction(
-	txnState *txnState, commitType commitType, res StatementResult,
+	session *Session, commitType commitType, res StatementResult,
 ) stateTransition {
-
+	txnState := &session.TxnState
 	if !txnState.TxnIsOpen() {
 		panic(fmt.Sprintf("commitSqlTransaction called on non-open txn: %+v", txnState.mu.txn))
 	}
 	if commitType == commit {
 		txnState.commitSeen = true
 	}
-	if err := txnState.mu.txn.Commit(txnState.Ctx); err != nil {
+	// The cursors declared WITH HOLD read their remaining rows before the
+	// transaction commits. An error fails the commit.
+	err := session.holdCursors(txnState.Ctx)
+	if err == nil {
+		err = txnState.mu.txn.Commit(txnState.Ctx)
+	}
+	if err != nil {
 		// Errors on COMMIT need special handling: if the errors is not handled by
 		// auto-retry, COMMIT needs to finalize the transaction (it can't leave it
 		// in Aborted or RestartWait). Higher layers will handle this with the help
@@ -2131,14 +2210,41 @@ func (e *Executor) execStmt(
 ) error {
 	session := planner.session
 	ctx := session.Ctx()
+	planner.noticeSender = res
+
+	if stmt.portal != nil && stmt.portal.plan != nil {
+		return e.execPortal(stmt, planner, nil /* plan */, automaticRetryCount, res)
+	}
+	if stmt.limit != 0 {
+		// The plan may be kept in the portal past this execution.
+		planner = planner.newResumablePlanner()
+	}
 
 	planner.phaseTimes[plannerStartLogicalPlan] = timeutil.Now()
+	if stmt.limit == 0 && planner.planCache != nil {
+		gp, empty, err := planner.planCache.genericPlan(ctx, planner, stmt)
+		if err != nil {
+			return err
+		}
+		if gp != nil {
+			planner.planCache = nil
+			planner.phaseTimes[plannerEndLogicalPlan] = timeutil.Now()
+			return e.execGenericPlan(stmt, planner, gp, empty, automaticRetryCount, res)
+		}
+	}
+	if e.cfg.TestingKnobs.BeforePlan != nil {
+		e.cfg.TestingKnobs.BeforePlan(ctx, stmt.String())
+	}
 	plan, err := planner.makePlan(ctx, stmt)
 	planner.phaseTimes[plannerEndLogicalPlan] = timeutil.Now()
 	if err != nil {
 		return err
 	}
 
+	if stmt.limit != 0 && stmt.AST.StatementType() == tree.Rows {
+		return e.execPortal(stmt, planner, plan, automaticRetryCount, res)
+	}
+
 	defer plan.Close(ctx)
 
 	err = initStatementResult(res, stmt, plan)
@@ -2175,6 +2281,128 @@ func (e *Executor) execStmt(
 	return res.CloseResult()
 }
 
+// execGenericPlan executes the generic plan of a prepared statement, bound to
+// the values of its placeholders by planner. The plan is executed locally, and
+// stays in the plan cache of the session for the next executions. If empty is
+// set, the plan produces no rows and isn't run.
+func (e *Executor) execGenericPlan(
+	stmt Statement,
+	planner *planner,
+	gp *genericPlan,
+	empty bool,
+	automaticRetryCount int,
+	res StatementResult,
+) error {
+	session := planner.session
+	ctx := session.Ctx()
+
+	if err := initStatementResult(res, stmt, gp.plan); err != nil {
+		return err
+	}
+
+	if e.cfg.TestingKnobs.BeforeExecute != nil {
+		e.cfg.TestingKnobs.BeforeExecute(ctx, stmt.String(), false /* isParallel */)
+	}
+
+	planner.phaseTimes[plannerStartExecStmt] = timeutil.Now()
+	session.setQueryExecutionMode(stmt.queryID, false /* isDistributed */, false /* isParallel */)
+	var err error
+	if !empty {
+		err = e.execClassic(gp.p, gp.plan, res)
+	}
+	planner.phaseTimes[plannerEndExecStmt] = timeutil.Now()
+	e.recordStatementSummary(
+		planner, stmt, false /* distSQLUsed */, automaticRetryCount, res, err,
+	)
+	if e.cfg.TestingKnobs.AfterExecute != nil {
+		e.cfg.TestingKnobs.AfterExecute(ctx, stmt.String(), res, err)
+	}
+	if err != nil {
+		return err
+	}
+	return res.CloseResult()
+}
+
+// execPortal executes a portal which returns rows with a row limit, and
+// writes up to limit rows to res. plan is set by the first execution of the
+// portal: it is started, and kept in the portal to be resumed by the next
+// executions of the portal.
+//
+// Only the SELECT statements of explicit transactions are run lazily. Like in
+// Postgres, the other statements are run to completion by their first
+// execution, and their rows are held for the portal. The plans of implicit
+// transactions are also held, since the transaction commits at the end of the
+// execution.
+func (e *Executor) execPortal(
+	stmt Statement, planner *planner, plan planNode, automaticRetryCount int, res StatementResult,
+) error {
+	session := planner.session
+	ctx := session.Ctx()
+
+	r := stmt.portal.plan
+	if r == nil {
+		if err := initStatementResult(res, stmt, plan); err != nil {
+			plan.Close(ctx)
+			return err
+		}
+		var err error
+		if r, err = startResumablePlan(ctx, planner, plan); err != nil {
+			return err
+		}
+		if _, ok := stmt.AST.(*tree.Select); !ok || session.TxnState.implicitTxn {
+			if err := r.hold(ctx); err != nil {
+				r.close(ctx)
+				return err
+			}
+		}
+		stmt.portal.plan = r
+	} else {
+		if r.txn != nil && !r.startedBy(planner.txn) {
+			return pgerror.NewError(pgerror.CodeInvalidCursorStateError,
+				"portal cannot be resumed outside of the transaction that suspended it")
+		}
+		res.BeginResult(stmt.AST)
+		res.SetColumns(r.columns)
+		r.resumeFor(planner)
+	}
+
+	if e.cfg.TestingKnobs.BeforeExecute != nil {
+		e.cfg.TestingKnobs.BeforeExecute(ctx, stmt.String(), false /* isParallel */)
+	}
+
+	planner.phaseTimes[plannerStartExecStmt] = timeutil.Now()
+	session.setQueryExecutionMode(stmt.queryID, false /* isDistributed */, false /* isParallel */)
+	err := func() error {
+		for count := 0; stmt.limit == 0 || count < stmt.limit; count++ {
+			next, err := r.next(ctx)
+			if err != nil || !next {
+				return err
+			}
+			values := r.values()
+			for _, val := range values {
+				if err := checkResultType(val.ResolvedType()); err != nil {
+					return err
+				}
+			}
+			if err := res.AddRow(ctx, values); err != nil {
+				return err
+			}
+		}
+		return nil
+	}()
+	planner.phaseTimes[plannerEndExecStmt] = timeutil.Now()
+	e.recordStatementSummary(
+		planner, stmt, false /* distSQLUsed */, automaticRetryCount, res, err,
+	)
+	if e.cfg.TestingKnobs.AfterExecute != nil {
+		e.cfg.TestingKnobs.AfterExecute(ctx, stmt.String(), res, err)
+	}
+	if err != nil {
+		return err
+	}
+	return res.CloseResult()
+}
+
 // execStmtInParallel executes the statement asynchronously and writes mocked
 // out results to res. These mocked out results will be the "zero value"
 // of the statement's result type:
@@ -2193,6 +2421,7 @@ func (e *Executor) execStmtInParallel(
 		ctx: ctx,
 		p:   planner,
 	}
+	planner.noticeSender = res
 
 	plan, err := planner.makePlan(ctx, stmt)
 	if err != nil {
@@ -2216,6 +2445,10 @@ func (e *Executor) execStmtInParallel(
 		if err != nil {
 			return err
 		}
+		// The results of the statement are sent to the client before it is
+		// executed, so the notices raised by its execution are held by the
+		// session until the next statement which waits for it.
+		planner.noticeSender = &session.parallelNotices
 
 		if e.cfg.TestingKnobs.BeforeExecute != nil {
 			e.cfg.TestingKnobs.BeforeExecute(ctx, stmt.String(), true /* isParallel */)
@@ -2358,6 +2591,8 @@ func checkResultType(typ types.T) error {
 	case types.TimestampTZ:
 	case types.Interval:
 	case types.JSON:
+	case types.TSVector:
+	case types.TSQuery:
 	case types.UUID:
 	case types.INet:
 	case types.NameArray:
@@ -2588,6 +2823,11 @@ func convertToErrWithPGCode(err error) error {
 		// different mechanism to marshal AmbiguousResultErrors from the executing
 		// nodes.
 		return sqlbase.NewStatementCompletionUnknownError(tErr)
+	case *roachpb.WriteIntentError:
+		if tErr.LockTimeout {
+			return sqlbase.NewLockNotAvailableError(tErr)
+		}
+		return err
 	default:
 		return err
 	}
@@ -112,7 +112,12 @@ func doExpandPlan(
 		n.source.plan, err = doExpandPlan(ctx, p, params, n.source.plan)
 
 	case *joinNode:
-		n.left.plan, err = doExpandPlan(ctx, p, noParams, n.left.plan)
+		leftParams := noParams
+		if n.joinType.isSemiOrAnti() {
+			// Semi and anti joins preserve the ordering of the left side.
+			leftParams.desiredOrdering = params.desiredOrdering
+		}
+		n.left.plan, err = doExpandPlan(ctx, p, leftParams, n.left.plan)
 		if err != nil {
 			return plan, err
 		}
@@ -255,6 +260,7 @@ func doExpandPlan(
 	case *alterTableNode:
 	case *alterSequenceNode:
 	case *alterUserSetPasswordNode:
+	case *alterUserConnLimitNode:
 	case *cancelQueryNode:
 	case *scrubNode:
 	case *controlJobNode:
@@ -270,6 +276,11 @@ func doExpandPlan(
 	case *dropViewNode:
 	case *dropSequenceNode:
 	case *dropUserNode:
+	case *declareCursorNode:
+	case *fetchNode:
+	case *moveNode:
+	case *listenNode:
+	case *notifyNode:
 	case *zeroNode:
 	case *unaryNode:
 	case *hookFnNode:
@@ -490,6 +501,12 @@ func (p *planner) simplifyOrderings(plan planNode, usefulOrdering sqlbase.Column
 		}
 
 		n.props.trim(usefulOrdering)
+		// Semi and anti joins get their ordering from the left side (see
+		// joinOrdering), so the left side must keep it. Both orderings are
+		// prefixes of the ordering of the left side; keep the longer one.
+		if n.joinType.isSemiOrAnti() && len(n.props.ordering) > len(usefulLeft) {
+			usefulLeft = n.props.ordering
+		}
 
 		n.left.plan = p.simplifyOrderings(n.left.plan, usefulLeft)
 		n.right.plan = p.simplifyOrderings(n.right.plan, usefulRight)
@@ -585,6 +602,7 @@ func (p *planner) simplifyOrderings(plan planNode, usefulOrdering sqlbase.Column
 	case *alterTableNode:
 	case *alterSequenceNode:
 	case *alterUserSetPasswordNode:
+	case *alterUserConnLimitNode:
 	case *cancelQueryNode:
 	case *scrubNode:
 	case *controlJobNode:
@@ -600,6 +618,11 @@ func (p *planner) simplifyOrderings(plan planNode, usefulOrdering sqlbase.Column
 	case *dropViewNode:
 	case *dropSequenceNode:
 	case *dropUserNode:
+	case *declareCursorNode:
+	case *fetchNode:
+	case *moveNode:
+	case *listenNode:
+	case *notifyNode:
 	case *zeroNode:
 	case *unaryNode:
 	case *hookFnNode:
@@ -16,15 +16,20 @@ package sql
 
 import (
 	"fmt"
+	"net/url"
 	"strings"
 
 	"golang.org/x/net/context"
 
 	"github.com/cockroachdb/cockroach/pkg/internal/client"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/sql/distsqlrun"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/types"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/tracing"
 )
 
 type explainMode int
@@ -53,6 +58,7 @@ func (p *planner) Explain(ctx context.Context, n *tree.Explain) (planNode, error
 	optimized := true
 	expanded := true
 	normalizeExprs := true
+	analyze := false
 	explainer := explainer{
 		showMetadata: false,
 		showExprs:    false,
@@ -79,6 +85,9 @@ func (p *planner) Explain(ctx context.Context, n *tree.Explain) (planNode, error
 				// TYPES implies METADATA.
 				explainer.showMetadata = true
 
+			case "analyze":
+				analyze = true
+
 			case "indent":
 				explainer.doIndent = true
 
@@ -125,6 +134,9 @@ func (p *planner) Explain(ctx context.Context, n *tree.Explain) (planNode, error
 	if mode == explainNone {
 		mode = explainPlan
 	}
+	if analyze && (!expanded || !optimized) {
+		return nil, fmt.Errorf("EXPLAIN ANALYZE cannot be used with NOEXPAND or NOOPTIMIZE")
+	}
 
 	p.evalCtx.SkipNormalize = !normalizeExprs
 
@@ -138,13 +150,14 @@ func (p *planner) Explain(ctx context.Context, n *tree.Explain) (planNode, error
 			plan:           plan,
 			distSQLPlanner: p.session.distSQLPlanner,
 			txn:            p.txn,
+			analyze:        analyze,
 		}, nil
 
 	case explainPlan:
 		// We may want to show placeholder types, so ensure no values
 		// are missing.
 		p.semaCtx.Placeholders.PermitUnassigned()
-		return p.makeExplainPlanNode(explainer, expanded, optimized, n.Statement, plan), nil
+		return p.makeExplainPlanNode(explainer, expanded, optimized, analyze, n.Statement, plan), nil
 
 	default:
 		return nil, fmt.Errorf("unsupported EXPLAIN mode: %d", mode)
@@ -162,6 +175,10 @@ type explainDistSQLNode struct {
 	// txn is the current transaction (used for the fake span resolver).
 	txn *client.Txn
 
+	// analyze is set for EXPLAIN ANALYZE; the plan is run and the diagram is
+	// annotated with the statistics collected by the processors.
+	analyze bool
+
 	// The single row returned by the node.
 	values tree.Datums
 
@@ -188,16 +205,33 @@ func (n *explainDistSQLNode) Start(params runParams) error {
 		return err
 	}
 
-	planCtx := n.distSQLPlanner.newPlanningCtx(params.ctx, &params.p.evalCtx, n.txn)
-	plan, err := n.distSQLPlanner.createPlanForNode(&planCtx, n.plan)
-	if err != nil {
-		return err
-	}
-	n.distSQLPlanner.FinalizePlan(&planCtx, &plan)
-	flows := plan.GenerateFlowSpecs(params.p.evalCtx.NodeID)
-	planJSON, planURL, err := distsqlrun.GeneratePlanDiagramWithURL(flows)
-	if err != nil {
-		return err
+	var planJSON string
+	var planURL url.URL
+	if n.analyze {
+		res, err := runExplainAnalyze(params, n.distSQLPlanner, n.plan)
+		if err != nil {
+			return err
+		}
+		planJSON, planURL, err = distsqlrun.GeneratePlanDiagramWithPlacementURL(
+			res.flows, res.stats, res.localities,
+		)
+		if err != nil {
+			return err
+		}
+	} else {
+		planCtx := n.distSQLPlanner.newPlanningCtx(params.ctx, &params.p.evalCtx, n.txn)
+		plan, err := n.distSQLPlanner.createPlanForNode(&planCtx, n.plan)
+		if err != nil {
+			return err
+		}
+		n.distSQLPlanner.FinalizePlan(&planCtx, &plan)
+		flows := plan.GenerateFlowSpecs(params.p.evalCtx.NodeID)
+		planJSON, planURL, err = distsqlrun.GeneratePlanDiagramWithPlacementURL(
+			flows, nil /* stats */, n.distSQLPlanner.flowLocalities(&planCtx, flows),
+		)
+		if err != nil {
+			return err
+		}
 	}
 
 	n.values = tree.Datums{
@@ -219,3 +253,85 @@ func (n *explainDistSQLNode) Next(runParams) (bool, error) {
 func (n *explainDistSQLNode) Values() tree.Datums {
 	return n.values
 }
+
+// explainAnalyzeResult contains the results of running a plan for EXPLAIN
+// ANALYZE.
+type explainAnalyzeResult struct {
+	// flows are the flows of the physical plan that was run.
+	flows map[roachpb.NodeID]distsqlrun.FlowSpec
+	// localities contains the localities of the nodes of the flows.
+	localities map[roachpb.NodeID]roachpb.Locality
+	// processorNodes maps each ProcessorID to the planNode that the processor
+	// was planned for. The processors of the final stage (which don't
+	// correspond to a planNode) are not present.
+	processorNodes map[int32]planNode
+	// stats contains the statistics collected by each processor, indexed by
+	// ProcessorID.
+	stats map[int32]distsqlrun.ProcessorStats
+}
+
+// runExplainAnalyze runs the given plan through DistSQL with runtime
+// statistics collection enabled, discarding the results. The plan must be
+// supported by DistSQL (see CheckSupport).
+func runExplainAnalyze(
+	params runParams, dsp *DistSQLPlanner, plan planNode,
+) (explainAnalyzeResult, error) {
+	p := params.p
+	if _, err := dsp.CheckSupport(plan); err != nil {
+		return explainAnalyzeResult{}, pgerror.NewErrorf(pgerror.CodeFeatureNotSupportedError,
+			"EXPLAIN ANALYZE is not supported for this statement: %v", err)
+	}
+	// Trigger limit propagation.
+	setUnlimited(plan)
+
+	// The statistics are collected by the processors in the trace of the flows,
+	// so we run the plan under a recording span of our own.
+	ctx, sp, err := tracing.StartSnowballTrace(
+		params.ctx, p.ExecCfg().AmbientCtx.Tracer, "explain analyze",
+	)
+	if err != nil {
+		return explainAnalyzeResult{}, err
+	}
+	defer sp.Finish()
+
+	planCtx := dsp.newPlanningCtx(ctx, &p.evalCtx, p.txn)
+	planCtx.processorNodes = make(map[int32]planNode)
+	physPlan, err := dsp.createPlanForNode(&planCtx, plan)
+	if err != nil {
+		return explainAnalyzeResult{}, err
+	}
+	dsp.FinalizePlan(&planCtx, &physPlan)
+
+	recv, err := makeDistSQLReceiver(
+		ctx,
+		NewRowResultWriter(tree.RowsAffected, nil /* rowContainer */),
+		p.ExecCfg().RangeDescriptorCache,
+		p.ExecCfg().LeaseHolderCache,
+		p.txn,
+		func(ts hlc.Timestamp) {
+			_ = p.ExecCfg().Clock.Update(ts)
+		},
+	)
+	if err != nil {
+		return explainAnalyzeResult{}, err
+	}
+	if err := dsp.Run(&planCtx, p.txn, &physPlan, &recv, p.evalCtx); err != nil {
+		return explainAnalyzeResult{}, err
+	}
+	if recv.err != nil {
+		return explainAnalyzeResult{}, recv.err
+	}
+
+	stats, err := distsqlrun.ExtractProcessorStats(tracing.GetRecording(sp))
+	tracing.StopRecording(sp)
+	if err != nil {
+		return explainAnalyzeResult{}, err
+	}
+	flows := physPlan.GenerateFlowSpecs(dsp.nodeDesc.NodeID)
+	return explainAnalyzeResult{
+		flows:          flows,
+		localities:     dsp.flowLocalities(&planCtx, flows),
+		processorNodes: planCtx.processorNodes,
+		stats:          stats,
+	}, nil
+}
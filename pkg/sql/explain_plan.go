@@ -17,12 +17,15 @@ package sql
 import (
 	"bytes"
 	"fmt"
+	"strconv"
 
 	"golang.org/x/net/context"
 
+	"github.com/cockroachdb/cockroach/pkg/sql/distsqlrun"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/types"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/util/humanizeutil"
 )
 
 // explainer represents the run-time state of the EXPLAIN logic.
@@ -58,6 +61,11 @@ type explainer struct {
 	// with leading white spaces.
 	doIndent bool
 
+	// stats, if set, contains the runtime statistics of the nodes of the plan,
+	// aggregated over all the processors planned for each node (EXPLAIN
+	// ANALYZE).
+	stats map[planNode]distsqlrun.ProcessorStats
+
 	// makeRow produces one row of EXPLAIN output.
 	makeRow func(level int, typ, field, desc string, plan planNode)
 
@@ -67,7 +75,7 @@ type explainer struct {
 
 // newExplainPlanNode instantiates a planNode that runs an EXPLAIN query.
 func (p *planner) makeExplainPlanNode(
-	explainer explainer, expanded, optimized bool, origStmt tree.Statement, plan planNode,
+	explainer explainer, expanded, optimized, analyze bool, origStmt tree.Statement, plan planNode,
 ) planNode {
 	columns := sqlbase.ResultColumns{
 		// Level is the depth of the node in the tree.
@@ -103,6 +111,7 @@ func (p *planner) makeExplainPlanNode(
 		explainer: explainer,
 		expanded:  expanded,
 		optimized: optimized,
+		analyze:   analyze,
 		plan:      plan,
 		results:   p.newContainerValuesNode(columns, 0),
 	}
@@ -207,6 +216,20 @@ func (e *explainer) enterNode(_ context.Context, name string, plan planNode) boo
 	e.makeRow(e.level, name, "", desc, plan)
 
 	e.level++
+	if s, ok := e.stats[plan]; ok {
+		e.attr(name, "rows in", strconv.FormatInt(s.InputRows, 10))
+		e.attr(name, "rows out", strconv.FormatInt(s.OutputRows, 10))
+		if s.KVBytesRead > 0 {
+			e.attr(name, "kv bytes read", humanizeutil.IBytes(s.KVBytesRead))
+		}
+		e.attr(name, "time", s.Time.String())
+		if s.MaxMemory > 0 {
+			e.attr(name, "max memory", humanizeutil.IBytes(s.MaxMemory))
+		}
+		if s.MaxDisk > 0 {
+			e.attr(name, "max disk", humanizeutil.IBytes(s.MaxDisk))
+		}
+	}
 	return true
 }
 
@@ -279,6 +302,10 @@ type explainPlanNode struct {
 
 	// optimized indicates whether to invoke setNeededColumns() on the sub-node.
 	optimized bool
+
+	// analyze indicates whether to run the sub-node through DistSQL and
+	// annotate the plan with the collected statistics (EXPLAIN ANALYZE).
+	analyze bool
 }
 
 func (e *explainPlanNode) Next(params runParams) (bool, error) { return e.results.Next(params) }
@@ -287,7 +314,22 @@ func (e *explainPlanNode) Values() tree.Datums                 { return e.result
 func (e *explainPlanNode) Start(params runParams) error {
 	// Note that we don't call start on e.plan. That's on purpose, Start() can
 	// have side effects. And it's supposed to not be needed for the way in which
-	// we're going to use e.plan.
+	// we're going to use e.plan. EXPLAIN ANALYZE is the exception: it runs the
+	// plan through DistSQL instead.
+	if e.analyze {
+		res, err := runExplainAnalyze(params, params.p.session.distSQLPlanner, e.plan)
+		if err != nil {
+			return err
+		}
+		e.explainer.stats = make(map[planNode]distsqlrun.ProcessorStats)
+		for id, s := range res.stats {
+			if node, ok := res.processorNodes[id]; ok {
+				nodeStats := e.explainer.stats[node]
+				nodeStats.Add(s)
+				e.explainer.stats[node] = nodeStats
+			}
+		}
+	}
 	return params.p.populateExplain(params.ctx, &e.explainer, e.results, e.plan)
 }
 
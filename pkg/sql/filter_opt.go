@@ -312,6 +312,7 @@ func (p *planner) propagateFilters(
 	case *alterTableNode:
 	case *alterSequenceNode:
 	case *alterUserSetPasswordNode:
+	case *alterUserConnLimitNode:
 	case *cancelQueryNode:
 	case *scrubNode:
 	case *controlJobNode:
@@ -327,6 +328,11 @@ func (p *planner) propagateFilters(
 	case *dropViewNode:
 	case *dropSequenceNode:
 	case *dropUserNode:
+	case *declareCursorNode:
+	case *fetchNode:
+	case *moveNode:
+	case *listenNode:
+	case *notifyNode:
 	case *hookFnNode:
 	case *valueGenerator:
 	case *valuesNode:
@@ -775,6 +781,23 @@ func (p *planner) addJoinFilter(
 	case joinTypeFullOuter:
 		// Not much we can do for full outer joins.
 		filterRemainder = extraFilter
+
+	case joinTypeLeftSemi, joinTypeLeftAnti, joinTypeLeftAntiNullAware:
+		// The results of semi and anti joins only contain the left columns, so
+		// the filter is propagated to the left side. We transform:
+		//   SELECT * FROM
+		//          l SEMI JOIN r ON (onLeft AND onRight AND onCombined)
+		//   WHERE filterLeft
+		// to:
+		//   SELECT * FROM
+		//          (SELECT * FROM l WHERE filterLeft)
+		//          SEMI JOIN
+		//          (SELECT * from r WHERE onRight)
+		//          ON (onLeft AND onCombined)
+		//
+		// Null-aware anti joins have no ON condition.
+		propagateLeft, filterRemainder = splitJoinFilterLeft(n, numLeft, extraFilter)
+		propagateRight, onCond = splitJoinFilterRight(n, numLeft, onCond)
 	}
 
 	// Propagate the left and right predicates to the left and right sides of the
@@ -20,7 +20,6 @@ import (
 
 	"golang.org/x/net/context"
 
-	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/builtins"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/types"
@@ -397,8 +396,15 @@ func (n *groupNode) Next(params runParams) (bool, error) {
 			if f.argRenderIdx != noRenderIdx {
 				value = values[f.argRenderIdx]
 			}
+			var otherArgs tree.Datums
+			if len(f.otherArgRenderIdxs) > 0 {
+				otherArgs = make(tree.Datums, len(f.otherArgRenderIdxs))
+				for i, idx := range f.otherArgRenderIdxs {
+					otherArgs[i] = values[idx]
+				}
+			}
 
-			if err := f.add(params.ctx, n.planner.session, bucket, value); err != nil {
+			if err := f.add(params.ctx, n.planner.session, bucket, value, otherArgs); err != nil {
 				return false, err
 			}
 		}
@@ -583,32 +589,31 @@ func (v *extractAggregatesVisitor) VisitPre(expr tree.Expr) (recurse bool, newEx
 				// COUNT_ROWS has no arguments.
 				f = v.groupNode.newAggregateFuncHolder(t, noRenderIdx, false /* not ident */, agg)
 
-			case 1:
-				argExpr := t.Exprs[0].(tree.TypedExpr)
-
-				if err := v.planner.txCtx.AssertNoAggregationOrWindowing(
-					argExpr,
-					fmt.Sprintf("the argument of %s()", t.Func),
-					v.planner.session.SearchPath,
-				); err != nil {
-					v.err = err
-					return false, expr
-				}
-
-				// Add a render for the argument.
-				col := sqlbase.ResultColumn{
-					Name: argExpr.String(),
-					Typ:  argExpr.ResolvedType(),
+			default:
+				// Add a render for each argument.
+				argRenderIdxs := make([]int, len(t.Exprs))
+				for i := range t.Exprs {
+					argExpr := t.Exprs[i].(tree.TypedExpr)
+
+					if err := v.planner.txCtx.AssertNoAggregationOrWindowing(
+						argExpr,
+						fmt.Sprintf("the argument of %s()", t.Func),
+						v.planner.session.SearchPath,
+					); err != nil {
+						v.err = err
+						return false, expr
+					}
+
+					col := sqlbase.ResultColumn{
+						Name: argExpr.String(),
+						Typ:  argExpr.ResolvedType(),
+					}
+
+					argRenderIdxs[i] = v.preRender.addOrReuseRender(col, argExpr, true /* reuse */)
 				}
 
-				argRenderIdx := v.preRender.addOrReuseRender(col, argExpr, true /* reuse */)
-
-				f = v.groupNode.newAggregateFuncHolder(t, argRenderIdx, false /* not ident */, agg)
-
-			default:
-				// TODO: #10495
-				v.err = pgerror.UnimplementedWithIssueError(10495, "aggregate functions with multiple arguments are not supported yet")
-				return false, expr
+				f = v.groupNode.newAggregateFuncHolder(t, argRenderIdxs[0], false /* not ident */, agg)
+				f.otherArgRenderIdxs = argRenderIdxs[1:]
 			}
 
 			if t.Type == tree.DistinctFuncType {
@@ -670,10 +675,14 @@ type aggregateFuncHolder struct {
 	// SELECT v+w FROM kvw GROUP BY v+w).
 	expr tree.TypedExpr
 
-	// The argument of the function is a single value produced by the renderNode
-	// underneath.
+	// The first argument of the function is a single value produced by the
+	// renderNode underneath.
 	argRenderIdx int
-	hasFilter    bool
+	// If the function takes more than one argument, the remaining arguments are
+	// also single values produced by the renderNode underneath.
+	otherArgRenderIdxs []int
+
+	hasFilter bool
 	// If there is a filter, the result is a single value produced by the
 	// renderNode underneath.
 	filterRenderIdx int
@@ -730,9 +739,9 @@ func (a *aggregateFuncHolder) close(ctx context.Context, s *Session) {
 }
 
 // add accumulates one more value for a particular bucket into an aggregation
-// function.
+// function. otherArgs holds the values of any arguments beyond the first.
 func (a *aggregateFuncHolder) add(
-	ctx context.Context, s *Session, bucket []byte, d tree.Datum,
+	ctx context.Context, s *Session, bucket []byte, d tree.Datum, otherArgs tree.Datums,
 ) error {
 	// NB: the compiler *should* optimize `myMap[string(myBytes)]`. See:
 	// https://github.com/golang/go/commit/f5f5a8b6209f84961687d993b93ea0d397f5d5bf
@@ -742,6 +751,12 @@ func (a *aggregateFuncHolder) add(
 		if err != nil {
 			return err
 		}
+		if otherArgs != nil {
+			encoded, err = sqlbase.EncodeDatums(encoded, otherArgs)
+			if err != nil {
+				return err
+			}
+		}
 		if _, ok := a.seen[string(encoded)]; ok {
 			// skip
 			return nil
@@ -758,5 +773,5 @@ func (a *aggregateFuncHolder) add(
 		a.buckets[string(bucket)] = impl
 	}
 
-	return impl.Add(ctx, d)
+	return impl.Add(ctx, d, otherArgs...)
 }
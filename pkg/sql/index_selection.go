@@ -89,6 +89,15 @@ func (p *planner) selectIndex(
 		return s, nil
 	}
 
+	scanIdx := -1
+	var cached *cachedScan
+	if p.planCache != nil {
+		scanIdx, cached = p.planCache.startScan(s.desc)
+		if p.planCache.building {
+			return p.selectGenericIndex(ctx, s, cached, analyzeOrdering, preferOrderMatching)
+		}
+	}
+
 	if s.filter == nil && analyzeOrdering == nil && s.specifiedIndex == nil {
 		// No where-clause, no ordering, and no specified index.
 		s.initOrdering(0)
@@ -100,35 +109,12 @@ func (p *planner) selectIndex(
 		return s, nil
 	}
 
-	candidates := make([]*indexInfo, 0, len(s.desc.Indexes)+1)
-	if s.specifiedIndex != nil {
-		// An explicit secondary index was requested. Only add it to the candidate
-		// indexes list.
-		candidates = append(candidates, &indexInfo{
-			desc:  s.desc,
-			index: s.specifiedIndex,
-		})
-	} else {
-		candidates = append(candidates, &indexInfo{
-			desc:  s.desc,
-			index: &s.desc.PrimaryIndex,
-		})
-		for i := range s.desc.Indexes {
-			candidates = append(candidates, &indexInfo{
-				desc:  s.desc,
-				index: &s.desc.Indexes[i],
-			})
-		}
-	}
-
-	for _, c := range candidates {
-		c.init(s)
-	}
-
+	var exprs []tree.TypedExprs
 	if s.filter != nil {
 		// Analyze the filter expression, simplifying it and splitting it up into
 		// possibly overlapping ranges.
-		exprs, equivalent := analyzeExpr(&p.evalCtx, s.filter)
+		var equivalent bool
+		exprs, equivalent = analyzeExpr(&p.evalCtx, s.filter)
 		if log.V(2) {
 			log.Infof(ctx, "analyzeExpr: %s -> %s [equivalent=%v]", s.filter, exprs, equivalent)
 		}
@@ -162,63 +148,36 @@ func (p *planner) selectIndex(
 		// multi-index-join. There are complexities: if there are a large
 		// number of disjunctive expressions we should limit how many indexes we
 		// use.
-
-		for _, c := range candidates {
-			c.analyzeExprs(&s.p.evalCtx, exprs)
-		}
 	}
 
-	if s.noIndexJoin {
-		// Eliminate non-covering indexes. We do this after the check above for
-		// constant false filter.
-		for i := 0; i < len(candidates); {
-			if !candidates[i].covering {
-				candidates[i] = candidates[len(candidates)-1]
-				candidates = candidates[:len(candidates)-1]
-			} else {
-				i++
-			}
-		}
-		if len(candidates) == 0 {
-			// The primary index is always covering. So the only way this can
-			// happen is if we had a specified index.
-			if s.specifiedIndex == nil {
-				panic("no covering indexes")
-			}
-			return nil, fmt.Errorf("index \"%s\" is not covering and NO_INDEX_JOIN was specified",
-				s.specifiedIndex.Name)
-		}
+	var c *indexInfo
+	if cached != nil {
+		c = p.cachedIndex(ctx, s, cached, exprs, analyzeOrdering, preferOrderMatching)
 	}
-
-	for _, c := range candidates {
-		// Compute the prefix of the index for which we have exact constraints. This
-		// prefix is inconsequential for ordering because the values are identical.
-		c.exactPrefix = c.constraints.exactPrefix(&s.p.evalCtx)
-		if analyzeOrdering != nil {
-			c.analyzeOrdering(ctx, s, analyzeOrdering, preferOrderMatching)
+	if c == nil {
+		var err error
+		c, err = p.bestIndex(ctx, s, exprs, analyzeOrdering, preferOrderMatching)
+		if err != nil {
+			return nil, err
 		}
 	}
-
-	indexInfoByCost(candidates).Sort()
-
-	if log.V(2) {
-		for i, c := range candidates {
-			log.Infof(ctx, "%d: selectIndex(%s): cost=%v constraints=%s reverse=%t",
-				i, c.index.Name, c.cost, c.constraints, c.reverse)
-		}
+	if scanIdx >= 0 {
+		p.planCache.selectIndex(scanIdx, c.index.ID, c.cost)
 	}
 
-	// After sorting, candidates[0] contains the best index. Copy its info into
-	// the scanNode.
-	c := candidates[0]
+	// Copy the info of the selected index into the scanNode.
 	s.index = c.index
 	s.specifiedIndex = nil
 	s.isSecondaryIndex = (c.index != &s.desc.PrimaryIndex)
-	var err error
-	s.spans, err = makeSpans(&s.p.evalCtx, c.constraints, c.desc, c.index)
-	if err != nil {
-		return nil, errors.Wrapf(err, "constraints = %v, table ID = %d, index ID = %d",
-			c.constraints, s.desc.ID, s.index.ID)
+	if c.invertedSpans != nil {
+		s.spans = c.invertedSpans
+	} else {
+		var err error
+		s.spans, err = makeSpans(&s.p.evalCtx, c.constraints, c.desc, c.index)
+		if err != nil {
+			return nil, errors.Wrapf(err, "constraints = %v, table ID = %d, index ID = %d",
+				c.constraints, s.desc.ID, s.index.ID)
+		}
 	}
 	if len(s.spans) == 0 {
 		// There are no spans to scan.
@@ -267,6 +226,207 @@ func (p *planner) selectIndex(
 	return plan, nil
 }
 
+// bestIndex ranks the indexes that can be used by the scanNode, given the
+// analyzed filter expressions, and returns the best one.
+func (p *planner) bestIndex(
+	ctx context.Context,
+	s *scanNode,
+	exprs []tree.TypedExprs,
+	analyzeOrdering analyzeOrderingFn,
+	preferOrderMatching bool,
+) (*indexInfo, error) {
+	candidates := make([]*indexInfo, 0, len(s.desc.Indexes)+1)
+	if s.specifiedIndex != nil {
+		// An explicit secondary index was requested. Only add it to the candidate
+		// indexes list.
+		candidates = append(candidates, &indexInfo{
+			desc:  s.desc,
+			index: s.specifiedIndex,
+		})
+	} else {
+		candidates = append(candidates, &indexInfo{
+			desc:  s.desc,
+			index: &s.desc.PrimaryIndex,
+		})
+		for i := range s.desc.Indexes {
+			candidates = append(candidates, &indexInfo{
+				desc:  s.desc,
+				index: &s.desc.Indexes[i],
+			})
+		}
+	}
+
+	for _, c := range candidates {
+		c.init(s)
+	}
+
+	if exprs != nil {
+		for _, c := range candidates {
+			if c.index.Type == sqlbase.IndexDescriptor_INVERTED {
+				c.analyzeInvertedFilter(s.filter)
+				continue
+			}
+			c.analyzeExprs(&s.p.evalCtx, exprs)
+		}
+	}
+
+	// Inverted indexes can only be used to look up the rows containing a
+	// lexeme; eliminate the ones for which the filter provides none.
+	for i := 0; i < len(candidates); {
+		if candidates[i].index.Type == sqlbase.IndexDescriptor_INVERTED &&
+			candidates[i].invertedSpans == nil {
+			candidates[i] = candidates[len(candidates)-1]
+			candidates = candidates[:len(candidates)-1]
+		} else {
+			i++
+		}
+	}
+	if len(candidates) == 0 {
+		// The primary index is always a candidate. So the only way this can
+		// happen is if we had a specified index.
+		return nil, fmt.Errorf("inverted index \"%s\" can only be used with a full-text "+
+			"search filter matching a constant query", s.specifiedIndex.Name)
+	}
+
+	if s.noIndexJoin {
+		// Eliminate non-covering indexes. We do this after the check above for
+		// constant false filter.
+		for i := 0; i < len(candidates); {
+			if !candidates[i].covering {
+				candidates[i] = candidates[len(candidates)-1]
+				candidates = candidates[:len(candidates)-1]
+			} else {
+				i++
+			}
+		}
+		if len(candidates) == 0 {
+			// The primary index is always covering. So the only way this can
+			// happen is if we had a specified index.
+			if s.specifiedIndex == nil {
+				panic("no covering indexes")
+			}
+			return nil, fmt.Errorf("index \"%s\" is not covering and NO_INDEX_JOIN was specified",
+				s.specifiedIndex.Name)
+		}
+	}
+
+	for _, c := range candidates {
+		if c.index.Type == sqlbase.IndexDescriptor_INVERTED {
+			// Inverted indexes provide no useful ordering.
+			continue
+		}
+		// Compute the prefix of the index for which we have exact constraints. This
+		// prefix is inconsequential for ordering because the values are identical.
+		c.exactPrefix = c.constraints.exactPrefix(&s.p.evalCtx)
+		if analyzeOrdering != nil {
+			c.analyzeOrdering(ctx, s, analyzeOrdering, preferOrderMatching)
+		}
+	}
+
+	indexInfoByCost(candidates).Sort()
+
+	if log.V(2) {
+		for i, c := range candidates {
+			log.Infof(ctx, "%d: selectIndex(%s): cost=%v constraints=%s reverse=%t",
+				i, c.index.Name, c.cost, c.constraints, c.reverse)
+		}
+	}
+
+	// After sorting, candidates[0] contains the best index.
+	return candidates[0], nil
+}
+
+// cachedIndex returns the index cached for the scanNode by the generic plan
+// of the prepared statement being planned, or nil if the generic plan should
+// not be used for the scanNode: when the index can't be used by the scan, or
+// when it is much more expensive for the current values of the placeholders
+// than it was on average for the custom plans.
+func (p *planner) cachedIndex(
+	ctx context.Context,
+	s *scanNode,
+	cached *cachedScan,
+	exprs []tree.TypedExprs,
+	analyzeOrdering analyzeOrderingFn,
+	preferOrderMatching bool,
+) *indexInfo {
+	index, err := s.desc.FindIndexByID(cached.indexID)
+	if err != nil || index.Type == sqlbase.IndexDescriptor_INVERTED ||
+		(s.specifiedIndex != nil && s.specifiedIndex.ID != index.ID) {
+		return nil
+	}
+	c := &indexInfo{desc: s.desc, index: index}
+	c.init(s)
+	if s.noIndexJoin && !c.covering {
+		return nil
+	}
+	if exprs != nil {
+		c.analyzeExprs(&p.evalCtx, exprs)
+	}
+	c.exactPrefix = c.constraints.exactPrefix(&p.evalCtx)
+	if analyzeOrdering != nil {
+		c.analyzeOrdering(ctx, s, analyzeOrdering, preferOrderMatching)
+	}
+	if avgCost := cached.avgCost(); c.cost > genericPlanCostFactor*avgCost {
+		if log.V(2) {
+			log.Infof(ctx, "cachedIndex(%s): cost=%v exceeds the average cost %v of custom plans",
+				c.index.Name, c.cost, avgCost)
+		}
+		return nil
+	}
+	return c
+}
+
+// selectGenericIndex selects the index cached for the scanNode while the
+// generic plan of a prepared statement is built. The spans and the filter of
+// the scan depend on the values of the placeholders; the scan is set up to
+// read the whole index, and is bound to the values of the placeholders by the
+// executions of the plan.
+func (p *planner) selectGenericIndex(
+	ctx context.Context,
+	s *scanNode,
+	cached *cachedScan,
+	analyzeOrdering analyzeOrderingFn,
+	preferOrderMatching bool,
+) (planNode, error) {
+	index := s.index
+	if cached != nil {
+		var err error
+		if index, err = s.desc.FindIndexByID(cached.indexID); err != nil {
+			return nil, errNoGenericPlan
+		}
+	} else if s.filter != nil || analyzeOrdering != nil || s.specifiedIndex != nil {
+		return nil, errNoGenericPlan
+	}
+	if index.Type == sqlbase.IndexDescriptor_INVERTED ||
+		(s.specifiedIndex != nil && s.specifiedIndex.ID != index.ID) {
+		return nil, errNoGenericPlan
+	}
+	c := &indexInfo{desc: s.desc, index: index}
+	c.init(s)
+	if !c.covering {
+		return nil, errNoGenericPlan
+	}
+	if analyzeOrdering != nil {
+		c.analyzeOrdering(ctx, s, analyzeOrdering, preferOrderMatching)
+	}
+
+	s.index = c.index
+	s.specifiedIndex = nil
+	s.isSecondaryIndex = (c.index != &s.desc.PrimaryIndex)
+	var err error
+	s.spans, err = makeSpans(&p.evalCtx, nil /* constraints */, s.desc, s.index)
+	if err != nil {
+		return nil, errors.Wrapf(err, "table ID = %d, index ID = %d", s.desc.ID, s.index.ID)
+	}
+	s.origFilter = s.filter
+	s.filterVars.Rebind(s.filter, true, false)
+	s.reverse = c.reverse
+	s.initOrdering(0)
+	p.planCache.genericScans = append(p.planCache.genericScans,
+		genericScan{n: s, filter: s.filter, spans: s.spans})
+	return s, nil
+}
+
 type indexConstraint struct {
 	start *tree.ComparisonExpr
 	end   *tree.ComparisonExpr
@@ -346,6 +506,9 @@ type indexInfo struct {
 	covering    bool // Does the index cover the required IndexedVars?
 	reverse     bool
 	exactPrefix int
+	// invertedSpans, for inverted indexes, contains the span of the index
+	// entries for a lexeme which all the rows matching the filter contain.
+	invertedSpans roachpb.Spans
 }
 
 func (v *indexInfo) init(s *scanNode) {
@@ -404,6 +567,43 @@ func (v *indexInfo) analyzeExprs(evalCtx *tree.EvalContext, exprs []tree.TypedEx
 	}
 }
 
+// analyzeInvertedFilter looks for a conjunct of the filter matching the column
+// of an inverted index against a constant query, e.g. `v @@ 'fox & dog'`. If
+// the query requires a lexeme to be present in matching documents, only the
+// index entries for that lexeme need to be scanned; the filter is still
+// applied to the rows looked up through them.
+func (v *indexInfo) analyzeInvertedFilter(filter tree.TypedExpr) {
+	colID := v.index.ColumnIDs[0]
+	for _, e := range splitAndExpr(nil, filter, nil) {
+		c, ok := e.(*tree.ComparisonExpr)
+		if !ok || c.Operator != tree.Matches {
+			continue
+		}
+		left, right := c.TypedLeft(), c.TypedRight()
+		if _, ok := left.(*tree.DTSQuery); ok {
+			left, right = right, left
+		}
+		ok, colIdx := getColVarIdx(left)
+		if !ok || colIdx >= len(v.desc.Columns) || v.desc.Columns[colIdx].ID != colID {
+			continue
+		}
+		q, ok := right.(*tree.DTSQuery)
+		if !ok {
+			continue
+		}
+		lexeme, ok := q.IndexLexeme()
+		if !ok {
+			continue
+		}
+		key := sqlbase.MakeIndexKeyPrefix(v.desc, v.index.ID)
+		key = encoding.EncodeStringAscending(key, lexeme)
+		v.invertedSpans = roachpb.Spans{{Key: key, EndKey: roachpb.Key(key).PrefixEnd()}}
+		// The scan is as restrictive as an exact lookup in a forward index,
+		// which is what the cost computed by init() assumes.
+		return
+	}
+}
+
 // analyzeOrdering analyzes the ordering provided by the index and determines
 // if it matches the ordering requested by the query. Non-matching orderings
 // increase the cost of using the index.
@@ -797,6 +997,10 @@ func (v *indexInfo) isCoveringIndex(scan *scanNode) bool {
 		// The primary key index always covers all of the columns.
 		return true
 	}
+	if v.index.Type == sqlbase.IndexDescriptor_INVERTED {
+		// The entries of an inverted index do not contain the indexed values.
+		return false
+	}
 
 	for _, colIdx := range scan.valNeededForCol.Ordered() {
 		// This is possible during a schema change when we have
@@ -55,6 +55,7 @@ type insertNode struct {
 
 	insertCols            []sqlbase.ColumnDescriptor
 	insertColIDtoRowIndex map[sqlbase.ColumnID]int
+	fkTables              sqlbase.TableLookupsByID
 	tw                    tableWriter
 
 	isUpsertReturning bool
@@ -258,6 +259,7 @@ func (p *planner) Insert(
 		defaultExprs:          defaultExprs,
 		insertCols:            ri.InsertCols,
 		insertColIDtoRowIndex: ri.InsertColIDtoRowIndex,
+		fkTables:              fkTables,
 		isUpsertReturning:     isUpsertReturning,
 		tw:                    tw,
 	}
@@ -34,8 +34,25 @@ const (
 	joinTypeLeftOuter
 	joinTypeRightOuter
 	joinTypeFullOuter
+	// joinTypeLeftSemi and joinTypeLeftAnti output the left rows that have,
+	// respectively don't have, a match on the right; the right columns are not
+	// part of the results. They are not available through SQL syntax and are
+	// only planned for IN and NOT IN subqueries (see makeSubqueryJoin).
+	joinTypeLeftSemi
+	joinTypeLeftAnti
+	// joinTypeLeftAntiNullAware is an anti join with the semantics of NOT IN:
+	// no row is output if the right side has a NULL on the (single) equality
+	// column, and a left row with a NULL on the equality column is only output
+	// if the right side is empty.
+	joinTypeLeftAntiNullAware
 )
 
+// isSemiOrAnti returns true for the join types whose results only contain the
+// left columns.
+func (t joinType) isSemiOrAnti() bool {
+	return t == joinTypeLeftSemi || t == joinTypeLeftAnti || t == joinTypeLeftAntiNullAware
+}
+
 // bucket here is the set of rows for a given group key (comprised of
 // columns specified by the join constraints), 'seen' is used to determine if
 // there was a matching row in the opposite stream.
@@ -120,8 +137,8 @@ func (b *buckets) Fetch(encoding []byte) (*bucket, bool) {
 	return bk, ok
 }
 
-// joinNode is a planNode whose rows are the result of an inner or
-// left/right outer join.
+// joinNode is a planNode whose rows are the result of an inner, left/right
+// outer, semi or anti join.
 type joinNode struct {
 	planner  *planner
 	joinType joinType
@@ -147,7 +164,10 @@ type joinNode struct {
 	// columns contains the metadata for the results of this node.
 	columns sqlbase.ResultColumns
 
-	// output contains the last generated row of results from this node.
+	// output contains the last generated row of results from this node. It
+	// has room for all the columns of both sides, as it is also used to
+	// evaluate the ON condition; for semi and anti joins, only the left columns
+	// are part of the results.
 	output tree.Datums
 
 	// buffer is our intermediate row store where we effectively 'stash' a batch
@@ -169,6 +189,10 @@ type joinNode struct {
 	// finishedOutput indicates that we've finished writing all of the rows for
 	// this join and that we can quit as soon as our buffer is empty.
 	finishedOutput bool
+
+	// rightHasNull is set if a row of the right side has a NULL on an equality
+	// column; it is used by null-aware anti joins.
+	rightHasNull bool
 }
 
 // commonColumns returns the names of columns common on the
@@ -260,31 +284,7 @@ func (p *planner) makeJoin(
 		return planDataSource{}, err
 	}
 
-	n := &joinNode{
-		planner:  p,
-		left:     left,
-		right:    right,
-		joinType: typ,
-		pred:     pred,
-		columns:  info.sourceColumns,
-	}
-
-	n.buffer = &RowBuffer{
-		RowContainer: sqlbase.NewRowContainer(
-			p.session.TxnState.makeBoundAccount(), sqlbase.ColTypeInfoFromResCols(planColumns(n)), 0,
-		),
-	}
-
-	n.bucketsMemAcc = p.session.TxnState.OpenAccount()
-	n.buckets = buckets{
-		buckets: make(map[string]*bucket),
-		rowContainer: sqlbase.NewRowContainer(
-			p.session.TxnState.makeBoundAccount(),
-			sqlbase.ColTypeInfoFromResCols(planColumns(n.right.plan)),
-			0,
-		),
-	}
-
+	n := p.newJoinNode(typ, left, right, pred, info.sourceColumns)
 	joinDataSource := planDataSource{info: info, plan: n}
 
 	if mergedColumns == nil {
@@ -464,6 +464,135 @@ func (p *planner) makeJoin(
 	return planDataSource{info: rInfo, plan: r}, nil
 }
 
+// newJoinNode creates a joinNode; columns are the result columns of the join.
+func (p *planner) newJoinNode(
+	typ joinType,
+	left planDataSource,
+	right planDataSource,
+	pred *joinPredicate,
+	columns sqlbase.ResultColumns,
+) *joinNode {
+	n := &joinNode{
+		planner:  p,
+		left:     left,
+		right:    right,
+		joinType: typ,
+		pred:     pred,
+		columns:  columns,
+	}
+
+	n.buffer = &RowBuffer{
+		RowContainer: sqlbase.NewRowContainer(
+			p.session.TxnState.makeBoundAccount(), sqlbase.ColTypeInfoFromResCols(planColumns(n)), 0,
+		),
+	}
+
+	n.bucketsMemAcc = p.session.TxnState.OpenAccount()
+	n.buckets = buckets{
+		buckets: make(map[string]*bucket),
+		rowContainer: sqlbase.NewRowContainer(
+			p.session.TxnState.makeBoundAccount(),
+			sqlbase.ColTypeInfoFromResCols(planColumns(n.right.plan)),
+			0,
+		),
+	}
+	return n
+}
+
+// makeSubqueryJoins plans the conjuncts of a WHERE filter of the form
+// `x IN (subquery)` and `x NOT IN (subquery)`, where x is a column of the
+// source and the subquery returns a single column, as semi and anti joins
+// between the source and the subquery. This avoids materializing the results
+// of the subquery in memory on the gateway (see subquery.doEval). It returns
+// the new source, which has the same columns as the original source, and the
+// remaining filter.
+//
+// The following cases are not rewritten and still go through subquery.doEval:
+//  - EXISTS and NOT EXISTS: subqueries can't be correlated in this tree, so
+//    these are evaluated once and only read the first row of the subquery.
+//  - IN and NOT IN that aren't top-level conjuncts of the filter (e.g. under
+//    an OR), whose left side isn't a column of the source, whose subquery
+//    returns several columns, or whose column types differ.
+//  - = ANY, = SOME and <> ALL, and subqueries outside of WHERE filters.
+func (p *planner) makeSubqueryJoins(
+	src planDataSource, filter tree.TypedExpr,
+) (planDataSource, tree.TypedExpr, error) {
+	conjuncts := splitAndExpr(&p.evalCtx, filter, nil)
+	var remaining tree.TypedExpr = tree.DBoolTrue
+	changed := false
+	for _, e := range conjuncts {
+		c, ok := e.(*tree.ComparisonExpr)
+		if !ok || (c.Operator != tree.In && c.Operator != tree.NotIn) {
+			remaining = mergeConj(remaining, e)
+			continue
+		}
+		leftVar, ok := c.Left.(*tree.IndexedVar)
+		if !ok {
+			remaining = mergeConj(remaining, e)
+			continue
+		}
+		sq, ok := c.Right.(*subquery)
+		if !ok || sq.execMode != execModeAllRowsNormalized || len(planColumns(sq.plan)) != 1 {
+			remaining = mergeConj(remaining, e)
+			continue
+		}
+		// The joiners compare the encodings of the values, so the types on both
+		// sides must be the same.
+		if colTyp := planColumns(sq.plan)[0].Typ; colTyp == types.Null ||
+			!leftVar.ResolvedType().Equivalent(colTyp) {
+			remaining = mergeConj(remaining, e)
+			continue
+		}
+
+		typ := joinTypeLeftSemi
+		if c.Operator == tree.NotIn {
+			typ = joinTypeLeftAntiNullAware
+		}
+		var err error
+		src, err = p.makeSubqueryJoin(typ, src, leftVar.Idx, sq)
+		if err != nil {
+			return planDataSource{}, nil, err
+		}
+		changed = true
+	}
+	if !changed {
+		return src, filter, nil
+	}
+	return src, remaining, nil
+}
+
+// makeSubqueryJoin constructs a semi or anti join between the given source and
+// the plan of a single-column subquery, on the equality of the leftCol column
+// of the source and the column of the subquery.
+func (p *planner) makeSubqueryJoin(
+	typ joinType, left planDataSource, leftCol int, sq *subquery,
+) (planDataSource, error) {
+	right := planDataSource{
+		info: newSourceInfoForSingleTable(anonymousTable, planColumns(sq.plan)),
+		plan: sq.plan,
+	}
+	pred, _, err := makeCrossPredicate(typ, left.info, right.info)
+	if err != nil {
+		return planDataSource{}, err
+	}
+	eq := &tree.ComparisonExpr{
+		Operator: tree.EQ,
+		Left:     pred.iVarHelper.IndexedVar(leftCol),
+		Right:    pred.iVarHelper.IndexedVar(pred.numLeftCols),
+	}
+	if !pred.tryAddEqualityFilter(eq, left.info, right.info) {
+		return planDataSource{}, errors.Errorf("cannot plan %s as a join", eq)
+	}
+
+	// The results of the join are the rows of the left side.
+	columns := append(sqlbase.ResultColumns(nil), left.info.sourceColumns...)
+	n := p.newJoinNode(typ, left, right, pred, columns)
+	return planDataSource{
+		info: &dataSourceInfo{sourceColumns: columns, sourceAliases: left.info.sourceAliases},
+		plan: n,
+	}, nil
+}
+
 // Start implements the planNode interface.
 func (n *joinNode) Start(params runParams) error {
 	if err := n.left.plan.Start(params); err != nil {
@@ -478,7 +607,7 @@ func (n *joinNode) Start(params runParams) error {
 	}
 
 	// Pre-allocate the space for output rows.
-	n.output = make(tree.Datums, len(n.columns))
+	n.output = make(tree.Datums, n.pred.numLeftCols+n.pred.numRightCols)
 
 	// If needed, pre-allocate left and right rows of NULL tuples for when the
 	// join predicate fails to match.
@@ -512,10 +641,13 @@ func (n *joinNode) hashJoinStart(params runParams) error {
 			break
 		}
 		row := n.right.plan.Values()
-		encoding, _, err := n.pred.encode(scratch, row, n.pred.rightEqualityIndices)
+		encoding, containsNull, err := n.pred.encode(scratch, row, n.pred.rightEqualityIndices)
 		if err != nil {
 			return err
 		}
+		if containsNull {
+			n.rightHasNull = true
+		}
 
 		if err := n.buckets.AddRow(ctx, acc, encoding, row); err != nil {
 			return err
@@ -529,6 +661,12 @@ func (n *joinNode) hashJoinStart(params runParams) error {
 	return nil
 }
 
+// addOutputRow adds the row in n.output to the buffer.
+func (n *joinNode) addOutputRow(ctx context.Context) error {
+	_, err := n.buffer.AddRow(ctx, n.output[:len(n.columns)])
+	return err
+}
+
 // Next implements the planNode interface.
 func (n *joinNode) Next(params runParams) (res bool, err error) {
 	// If results available from from previously computed results, we just
@@ -542,9 +680,14 @@ func (n *joinNode) Next(params runParams) (res bool, err error) {
 		return false, nil
 	}
 
-	wantUnmatchedLeft := n.joinType == joinTypeLeftOuter || n.joinType == joinTypeFullOuter
+	wantUnmatchedLeft := n.joinType == joinTypeLeftOuter || n.joinType == joinTypeFullOuter ||
+		n.joinType == joinTypeLeftAnti || n.joinType == joinTypeLeftAntiNullAware
 	wantUnmatchedRight := n.joinType == joinTypeRightOuter || n.joinType == joinTypeFullOuter
 
+	if n.joinType == joinTypeLeftAntiNullAware && n.rightHasNull {
+		// x NOT IN (..., NULL, ...) is never true.
+		return false, nil
+	}
 	if len(n.buckets.Buckets()) == 0 {
 		if !wantUnmatchedLeft {
 			// No rows on right; don't even try.
@@ -617,15 +760,17 @@ func (n *joinNode) Next(params runParams) (res bool, err error) {
 		//    | NULL |  52  |
 		//    | NULL |  52  |
 		if containsNull {
-			if !wantUnmatchedLeft {
+			if !wantUnmatchedLeft ||
+				(n.joinType == joinTypeLeftAntiNullAware && len(n.buckets.Buckets()) > 0) {
 				scratch = encoding[:0]
-				// Failed to match -- no matching row, nothing to do.
+				// Failed to match -- no matching row, nothing to do. Note that
+				// NULL NOT IN (...) is only true if the right side is empty.
 				continue
 			}
 			// We append an empty right row to the left row, adding the result
 			// to our buffer for the subsequent call to Next().
 			n.pred.prepareRow(n.output, lrow, n.emptyRight)
-			if _, err := n.buffer.AddRow(params.ctx, n.output); err != nil {
+			if err := n.addOutputRow(params.ctx); err != nil {
 				return false, err
 			}
 			return n.buffer.Next(), nil
@@ -642,7 +787,7 @@ func (n *joinNode) Next(params runParams) (res bool, err error) {
 			// empty right row to the left row, adding the result to our buffer
 			// for the subsequent call to Next().
 			n.pred.prepareRow(n.output, lrow, n.emptyRight)
-			if _, err := n.buffer.AddRow(params.ctx, n.output); err != nil {
+			if err := n.addOutputRow(params.ctx); err != nil {
 				return false, err
 			}
 			return n.buffer.Next(), nil
@@ -661,6 +806,10 @@ func (n *joinNode) Next(params runParams) (res bool, err error) {
 				continue
 			}
 			foundMatch = true
+			if n.joinType.isSemiOrAnti() {
+				// We only need to know whether the left row has a match.
+				break
+			}
 
 			n.pred.prepareRow(n.output, lrow, rrow)
 			if wantUnmatchedRight {
@@ -668,16 +817,22 @@ func (n *joinNode) Next(params runParams) (res bool, err error) {
 				// without matches for right or full joins later.
 				b.MarkSeen(idx)
 			}
-			if _, err := n.buffer.AddRow(params.ctx, n.output); err != nil {
+			if err := n.addOutputRow(params.ctx); err != nil {
+				return false, err
+			}
+		}
+		if foundMatch && n.joinType == joinTypeLeftSemi {
+			n.pred.prepareRow(n.output, lrow, nil /* rightRow */)
+			if err := n.addOutputRow(params.ctx); err != nil {
 				return false, err
 			}
 		}
 		if !foundMatch && wantUnmatchedLeft {
 			// If none of the rows matched the on condition and we are computing a
-			// left or full outer join, we need to add a row with an empty
-			// right side.
+			// left or full outer (or anti) join, we need to add a row with an
+			// empty right side.
 			n.pred.prepareRow(n.output, lrow, n.emptyRight)
-			if _, err := n.buffer.AddRow(params.ctx, n.output); err != nil {
+			if err := n.addOutputRow(params.ctx); err != nil {
 				return false, err
 			}
 		}
@@ -699,7 +854,7 @@ func (n *joinNode) Next(params runParams) (res bool, err error) {
 			}
 			if !b.Seen(idx) {
 				n.pred.prepareRow(n.output, n.emptyLeft, rrow)
-				if _, err := n.buffer.AddRow(params.ctx, n.output); err != nil {
+				if err := n.addOutputRow(params.ctx); err != nil {
 					return false, err
 				}
 			}
@@ -727,6 +882,13 @@ func (n *joinNode) Close(ctx context.Context) {
 }
 
 func (n *joinNode) joinOrdering() physicalProps {
+	if n.joinType.isSemiOrAnti() {
+		// Semi and anti joins output a subset of the left rows, in the order in
+		// which they are read, with the same columns; all the properties of the
+		// left side hold for the results.
+		leftProps := planPhysicalProps(n.left.plan)
+		return leftProps.copy()
+	}
 	if len(n.mergeJoinOrdering) == 0 {
 		return physicalProps{}
 	}
@@ -331,8 +331,13 @@ func (p *joinPredicate) getNeededColumns(neededJoined []bool) ([]bool, []bool) {
 	// are effectively needed.
 	p.onCond = p.iVarHelper.Rebind(p.onCond, true, false)
 
-	// The columns that are part of the expression are always needed.
+	// The columns that are part of the expression are always needed. The
+	// results of semi and anti joins don't include the right columns, so
+	// neededJoined may only cover the left columns.
 	neededJoined = append([]bool(nil), neededJoined...)
+	for len(neededJoined) < p.numLeftCols+p.numRightCols {
+		neededJoined = append(neededJoined, false)
+	}
 	for i := range neededJoined {
 		if p.iVarHelper.IndexedVarUsed(i) {
 			neededJoined[i] = true
@@ -166,6 +166,7 @@ func applyLimit(plan planNode, numRows int64, soft bool) {
 	case *alterTableNode:
 	case *alterSequenceNode:
 	case *alterUserSetPasswordNode:
+	case *alterUserConnLimitNode:
 	case *cancelQueryNode:
 	case *scrubNode:
 	case *controlJobNode:
@@ -181,6 +182,11 @@ func applyLimit(plan planNode, numRows int64, soft bool) {
 	case *dropViewNode:
 	case *dropSequenceNode:
 	case *dropUserNode:
+	case *declareCursorNode:
+	case *fetchNode:
+	case *moveNode:
+	case *listenNode:
+	case *notifyNode:
 	case *zeroNode:
 	case *unaryNode:
 	case *hookFnNode:
@@ -190,6 +190,7 @@ func setNeededColumns(plan planNode, needed []bool) {
 	case *alterTableNode:
 	case *alterSequenceNode:
 	case *alterUserSetPasswordNode:
+	case *alterUserConnLimitNode:
 	case *cancelQueryNode:
 	case *controlJobNode:
 	case *scrubNode:
@@ -205,6 +206,11 @@ func setNeededColumns(plan planNode, needed []bool) {
 	case *dropViewNode:
 	case *dropSequenceNode:
 	case *dropUserNode:
+	case *declareCursorNode:
+	case *fetchNode:
+	case *moveNode:
+	case *listenNode:
+	case *notifyNode:
 	case *zeroNode:
 	case *unaryNode:
 	case *hookFnNode:
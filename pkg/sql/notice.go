@@ -0,0 +1,70 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+)
+
+// SendNotice implements the tree.EvalPlanner interface. Like in Postgres,
+// the INFO notices are sent regardless of client_min_messages.
+func (p *planner) SendNotice(notice *pgerror.Notice) {
+	if p.noticeSender == nil {
+		return
+	}
+	if notice.Severity != pgerror.SeverityInfo && notice.Severity < p.session.ClientMinMessages {
+		return
+	}
+	p.noticeSender.AddNotice(notice)
+}
+
+// noticef sends a notice with the given severity and a formatted message.
+func (p *planner) noticef(severity pgerror.Severity, format string, args ...interface{}) {
+	p.SendNotice(pgerror.NewNoticef(severity, format, args...))
+}
+
+// noticeSkipping sends the notice of the DROP ... IF EXISTS statements when
+// the object to drop doesn't exist.
+func (p *planner) noticeSkipping(objType string, name string) {
+	p.noticef(pgerror.SeverityNotice, "%s %q does not exist, skipping", objType, name)
+}
+
+// parallelNotices is the NoticeSender of the parallelized statements. The
+// mocked out results of these statements are sent to the client before they
+// execute, so their notices are held until the next statement which waits for
+// them, and sent with its results.
+type parallelNotices struct {
+	mu      syncutil.Mutex
+	notices []*pgerror.Notice
+}
+
+// AddNotice implements the NoticeSender interface.
+func (n *parallelNotices) AddNotice(notice *pgerror.Notice) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.notices = append(n.notices, notice)
+}
+
+// flush sends the held notices to the given NoticeSender.
+func (n *parallelNotices) flush(sender NoticeSender) {
+	n.mu.Lock()
+	notices := n.notices
+	n.notices = nil
+	n.mu.Unlock()
+	for _, notice := range notices {
+		sender.AddNotice(notice)
+	}
+}
@@ -0,0 +1,523 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"sort"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/gossip"
+	"github.com/cockroachdb/cockroach/pkg/internal/client"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/rpc"
+	"github.com/cockroachdb/cockroach/pkg/server/serverpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/stop"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"github.com/cockroachdb/cockroach/pkg/util/uuid"
+)
+
+// maxNotificationPayloadLen is the maximum length in bytes of the payload of
+// a notification. This is the limit of Postgres.
+const maxNotificationPayloadLen = 7999
+
+// maxChannelNameLen is the maximum length in bytes of the name of a
+// notification channel. This is the limit of Postgres on identifiers.
+const maxChannelNameLen = 63
+
+// maxPendingNotifications is the maximum number of notifications queued for
+// a session that haven't been sent to its client yet, and for a node that
+// haven't been sent to it yet. Further notifications are dropped.
+const maxPendingNotifications = 10000
+
+// notificationSendTimeout is the amount of time given to a node to accept
+// the notifications sent to it.
+const notificationSendTimeout = 5 * time.Second
+
+// NotificationRegistry delivers the notifications committed on any node to
+// the sessions of this node that listen on their channel. The notifications
+// committed on a node are delivered directly to the sessions of that node,
+// and sent with the Notify RPC of the status server to the other nodes whose
+// sessions listen on their channel. Each node gossips the set of channels on
+// which its sessions listen; the notifications themselves don't go through
+// gossip. The notifications that a node sends to another one arrive in the
+// order in which they were committed, but the notifications committed on
+// different nodes are not ordered.
+//
+// Since the other nodes only learn about a new channel once its gossip update
+// reaches them, the notifications committed on them in the seconds following
+// a LISTEN are not delivered to the session. Notifications committed on the
+// node of the session are delivered as soon as the LISTEN commits.
+type NotificationRegistry struct {
+	log.AmbientContext
+	gossip     *gossip.Gossip
+	nodeID     *base.NodeIDContainer
+	rpcContext *rpc.Context
+	stopper    *stop.Stopper
+
+	mu struct {
+		syncutil.Mutex
+		// listeners maps the channels to the sessions which listen on them.
+		listeners map[string]map[*Session]struct{}
+		// remoteListeners maps the other nodes to the channels on which their
+		// sessions listen, as gossiped by these nodes.
+		remoteListeners map[roachpb.NodeID]map[string]struct{}
+		// outboxes holds the notifications waiting to be sent to each of the
+		// other nodes.
+		outboxes map[roachpb.NodeID]*notificationOutbox
+	}
+}
+
+// notificationOutbox holds the notifications waiting to be sent to a node.
+// They are sent by a single task at a time, so that they arrive in order.
+type notificationOutbox struct {
+	queue   []serverpb.Notification
+	sending bool
+}
+
+// NewNotificationRegistry creates a NotificationRegistry and subscribes it to
+// the channels of the listening sessions gossiped by the other nodes.
+func NewNotificationRegistry(
+	ambient log.AmbientContext,
+	g *gossip.Gossip,
+	nodeID *base.NodeIDContainer,
+	rpcContext *rpc.Context,
+	stopper *stop.Stopper,
+) *NotificationRegistry {
+	r := &NotificationRegistry{
+		AmbientContext: ambient,
+		gossip:         g,
+		nodeID:         nodeID,
+		rpcContext:     rpcContext,
+		stopper:        stopper,
+	}
+	r.mu.listeners = make(map[string]map[*Session]struct{})
+	r.mu.remoteListeners = make(map[roachpb.NodeID]map[string]struct{})
+	r.mu.outboxes = make(map[roachpb.NodeID]*notificationOutbox)
+	g.RegisterCallback(
+		gossip.MakePrefixPattern(gossip.KeyNotificationListenersPrefix), r.gossipCallback,
+	)
+	return r
+}
+
+func (r *NotificationRegistry) listen(s *Session, channel string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sessions, ok := r.mu.listeners[channel]
+	if !ok {
+		sessions = make(map[*Session]struct{})
+		r.mu.listeners[channel] = sessions
+		r.gossipListenersLocked()
+	}
+	sessions[s] = struct{}{}
+}
+
+func (r *NotificationRegistry) unlisten(s *Session, channel string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sessions, ok := r.mu.listeners[channel]
+	if !ok {
+		return
+	}
+	delete(sessions, s)
+	if len(sessions) == 0 {
+		delete(r.mu.listeners, channel)
+		r.gossipListenersLocked()
+	}
+}
+
+// gossipListenersLocked gossips the channels on which the sessions of the
+// node listen, so that the other nodes send it the notifications on these
+// channels. It is called when the set of channels changes, which is much less
+// frequent than notifications. The other nodes only send the notifications
+// on a new channel once the update reaches them. r.mu must be held, so that
+// the updates are gossiped in order.
+func (r *NotificationRegistry) gossipListenersLocked() {
+	listeners := serverpb.NotificationListeners{
+		Channels: make([]string, 0, len(r.mu.listeners)),
+	}
+	for channel := range r.mu.listeners {
+		listeners.Channels = append(listeners.Channels, channel)
+	}
+	sort.Strings(listeners.Channels)
+	key := gossip.MakeNotificationListenersKey(r.nodeID.Get())
+	if err := r.gossip.AddInfoProto(key, &listeners, 0 /* ttl */); err != nil {
+		log.Warningf(r.AnnotateCtx(context.TODO()), "unable to gossip notification channels: %v", err)
+	}
+}
+
+// gossipCallback records the channels on which the sessions of another node
+// listen.
+func (r *NotificationRegistry) gossipCallback(key string, content roachpb.Value) {
+	ctx := r.AnnotateCtx(context.TODO())
+	nodeID, err := gossip.NodeIDFromNotificationListenersKey(key)
+	if err != nil {
+		log.Warningf(ctx, "invalid notification channels in gossip: %v", err)
+		return
+	}
+	if nodeID == r.nodeID.Get() {
+		return
+	}
+	var listeners serverpb.NotificationListeners
+	if err := content.GetProto(&listeners); err != nil {
+		log.Warningf(ctx, "invalid notification channels in gossip: %v", err)
+		return
+	}
+	channels := make(map[string]struct{}, len(listeners.Channels))
+	for _, channel := range listeners.Channels {
+		channels[channel] = struct{}{}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(channels) == 0 {
+		delete(r.mu.remoteListeners, nodeID)
+	} else {
+		r.mu.remoteListeners[nodeID] = channels
+	}
+}
+
+// notify delivers notifications to the sessions of the node, and sends them
+// to the other nodes whose sessions listen on their channel.
+func (r *NotificationRegistry) notify(ctx context.Context, notifications []serverpb.Notification) {
+	r.Deliver(notifications)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for nodeID, channels := range r.mu.remoteListeners {
+		var toSend []serverpb.Notification
+		for _, n := range notifications {
+			if _, ok := channels[n.Channel]; ok {
+				toSend = append(toSend, n)
+			}
+		}
+		if len(toSend) > 0 {
+			r.queueLocked(ctx, nodeID, toSend)
+		}
+	}
+}
+
+// queueLocked queues notifications for another node, and starts a task
+// sending them unless one is already running. r.mu must be held.
+func (r *NotificationRegistry) queueLocked(
+	ctx context.Context, nodeID roachpb.NodeID, notifications []serverpb.Notification,
+) {
+	outbox, ok := r.mu.outboxes[nodeID]
+	if !ok {
+		outbox = &notificationOutbox{}
+		r.mu.outboxes[nodeID] = outbox
+	}
+	if len(outbox.queue)+len(notifications) > maxPendingNotifications {
+		log.Warningf(ctx, "dropping %d notifications: too many notifications are "+
+			"waiting to be sent to node %d", len(notifications), nodeID)
+		return
+	}
+	outbox.queue = append(outbox.queue, notifications...)
+	if outbox.sending {
+		return
+	}
+	outbox.sending = true
+	if err := r.stopper.RunAsyncTask(
+		r.AnnotateCtx(context.Background()), "sql.NotificationRegistry: send notifications",
+		func(ctx context.Context) { r.sendOutbox(ctx, nodeID, outbox) },
+	); err != nil {
+		delete(r.mu.outboxes, nodeID)
+	}
+}
+
+// sendOutbox sends the notifications queued for a node until the queue is
+// empty.
+func (r *NotificationRegistry) sendOutbox(
+	ctx context.Context, nodeID roachpb.NodeID, outbox *notificationOutbox,
+) {
+	for {
+		r.mu.Lock()
+		notifications := outbox.queue
+		outbox.queue = nil
+		if len(notifications) == 0 {
+			delete(r.mu.outboxes, nodeID)
+			r.mu.Unlock()
+			return
+		}
+		r.mu.Unlock()
+
+		if err := r.send(ctx, nodeID, notifications); err != nil {
+			log.Warningf(ctx, "unable to send %d notifications to node %d: %v",
+				len(notifications), nodeID, err)
+		}
+	}
+}
+
+// send sends notifications to another node with the Notify RPC.
+func (r *NotificationRegistry) send(
+	ctx context.Context, nodeID roachpb.NodeID, notifications []serverpb.Notification,
+) error {
+	addr, err := r.gossip.GetNodeIDAddress(nodeID)
+	if err != nil {
+		return err
+	}
+	conn, err := r.rpcContext.GRPCDial(addr.String())
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, notificationSendTimeout)
+	defer cancel()
+	_, err = serverpb.NewStatusClient(conn).Notify(ctx, &serverpb.NotifyRequest{
+		NodeID:        nodeID.String(),
+		Notifications: notifications,
+	})
+	return err
+}
+
+// Deliver queues notifications for the sessions of the node which listen on
+// their channel. It is called by the Notify RPC for the notifications
+// committed on other nodes.
+func (r *NotificationRegistry) Deliver(notifications []serverpb.Notification) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, n := range notifications {
+		for s := range r.mu.listeners[n.Channel] {
+			s.queueNotification(n)
+		}
+	}
+}
+
+// listenAction is a LISTEN or UNLISTEN statement of a transaction. An empty
+// channel means all the channels.
+type listenAction struct {
+	channel string
+	listen  bool
+}
+
+// txnNotifications holds the LISTEN, UNLISTEN and NOTIFY statements of an
+// attempt of a transaction, which take effect if the transaction commits.
+type txnNotifications struct {
+	txn   *client.Txn
+	txnID uuid.UUID
+	epoch uint32
+
+	listens       []listenAction
+	notifications []serverpb.Notification
+	// sent is the set of the notifications, used to send identical
+	// notifications once.
+	sent map[serverpb.Notification]struct{}
+}
+
+// getTxnNotifications returns the pending LISTEN, UNLISTEN and NOTIFY
+// statements of the current attempt of txn. Those of earlier attempts are
+// discarded.
+func (s *Session) getTxnNotifications(txn *client.Txn) *txnNotifications {
+	p := &s.pendingNotifications
+	if p.txn != txn || p.txnID != txn.ID() || p.epoch != txn.Proto().Epoch {
+		*p = txnNotifications{txn: txn, txnID: txn.ID(), epoch: txn.Proto().Epoch}
+	}
+	return p
+}
+
+// finishNotifications is called when a SQL transaction finishes. If the
+// transaction committed, its LISTEN and UNLISTEN statements are applied and
+// its notifications are sent.
+func (s *Session) finishNotifications(ctx context.Context) {
+	p := s.pendingNotifications
+	s.pendingNotifications = txnNotifications{}
+	if p.txn == nil || !p.txn.IsCommitted() || p.txnID != p.txn.ID() || p.epoch != p.txn.Proto().Epoch {
+		return
+	}
+
+	registry := s.execCfg.NotificationRegistry
+	for _, a := range p.listens {
+		switch {
+		case a.listen:
+			if _, ok := s.listenChannels[a.channel]; !ok {
+				s.listenChannels[a.channel] = struct{}{}
+				registry.listen(s, a.channel)
+			}
+		case a.channel == "":
+			s.unlistenAll()
+		default:
+			if _, ok := s.listenChannels[a.channel]; ok {
+				delete(s.listenChannels, a.channel)
+				registry.unlisten(s, a.channel)
+			}
+		}
+	}
+	if len(p.notifications) > 0 {
+		registry.notify(ctx, p.notifications)
+	}
+}
+
+// unlistenAll stops listening on all the channels.
+func (s *Session) unlistenAll() {
+	for channel := range s.listenChannels {
+		s.execCfg.NotificationRegistry.unlisten(s, channel)
+		delete(s.listenChannels, channel)
+	}
+}
+
+// IsListening returns whether the session listens on any channel.
+func (s *Session) IsListening() bool {
+	return len(s.listenChannels) > 0
+}
+
+// queueNotification queues a notification for the client of the session.
+func (s *Session) queueNotification(n serverpb.Notification) {
+	s.notifications.Lock()
+	defer s.notifications.Unlock()
+	if len(s.notifications.queue) >= maxPendingNotifications {
+		log.Warningf(s.context, "dropping notification on channel %q: "+
+			"too many notifications are waiting to be sent to the client", n.Channel)
+		return
+	}
+	s.notifications.queue = append(s.notifications.queue, n)
+	select {
+	case s.notifications.ready <- struct{}{}:
+	default:
+	}
+}
+
+// NotificationsReady returns a channel which receives a value when
+// notifications are queued for the client of the session.
+func (s *Session) NotificationsReady() <-chan struct{} {
+	return s.notifications.ready
+}
+
+// TakeNotifications returns the notifications queued for the client of the
+// session, and empties the queue.
+func (s *Session) TakeNotifications() []serverpb.Notification {
+	s.notifications.Lock()
+	defer s.notifications.Unlock()
+	queue := s.notifications.queue
+	s.notifications.queue = nil
+	return queue
+}
+
+// checkChannelName checks the name of a notification channel.
+func checkChannelName(channel string) error {
+	if channel == "" {
+		return pgerror.NewError(pgerror.CodeInvalidParameterValueError,
+			"channel name cannot be empty")
+	}
+	if len(channel) > maxChannelNameLen {
+		return pgerror.NewError(pgerror.CodeInvalidParameterValueError,
+			"channel name too long")
+	}
+	return nil
+}
+
+// Listen implements the LISTEN statement.
+// See https://www.postgresql.org/docs/current/static/sql-listen.html for details.
+func (p *planner) Listen(ctx context.Context, n *tree.Listen) (planNode, error) {
+	if err := checkChannelName(string(n.Channel)); err != nil {
+		return nil, err
+	}
+	return &listenNode{channel: string(n.Channel), listen: true}, nil
+}
+
+// Unlisten implements the UNLISTEN statement.
+// See https://www.postgresql.org/docs/current/static/sql-unlisten.html for details.
+func (p *planner) Unlisten(ctx context.Context, n *tree.Unlisten) (planNode, error) {
+	return &listenNode{channel: string(n.Channel)}, nil
+}
+
+// listenNode records a LISTEN or UNLISTEN statement in the pending
+// notifications of the transaction when it runs. An empty channel stands for
+// UNLISTEN *.
+type listenNode struct {
+	channel string
+	listen  bool
+}
+
+func (n *listenNode) Start(params runParams) error {
+	pending := params.p.session.getTxnNotifications(params.p.txn)
+	pending.listens = append(pending.listens, listenAction{channel: n.channel, listen: n.listen})
+	return nil
+}
+
+func (*listenNode) Next(runParams) (bool, error) { return false, nil }
+func (*listenNode) Values() tree.Datums          { return nil }
+func (*listenNode) Close(context.Context)        {}
+
+// Notify implements the NOTIFY statement.
+// See https://www.postgresql.org/docs/current/static/sql-notify.html for details.
+func (p *planner) Notify(ctx context.Context, n *tree.Notify) (planNode, error) {
+	if err := checkNotification(string(n.Channel), n.Payload); err != nil {
+		return nil, err
+	}
+	return &notifyNode{channel: string(n.Channel), payload: n.Payload}, nil
+}
+
+// notifyNode queues a notification when it runs.
+type notifyNode struct {
+	channel string
+	payload string
+}
+
+func (n *notifyNode) Start(params runParams) error {
+	return params.p.SendNotification(params.ctx, n.channel, n.payload)
+}
+
+func (*notifyNode) Next(runParams) (bool, error) { return false, nil }
+func (*notifyNode) Values() tree.Datums          { return nil }
+func (*notifyNode) Close(context.Context)        {}
+
+// checkNotification checks the channel and payload of a notification.
+func checkNotification(channel, payload string) error {
+	if err := checkChannelName(channel); err != nil {
+		return err
+	}
+	if len(payload) > maxNotificationPayloadLen {
+		return pgerror.NewError(pgerror.CodeInvalidParameterValueError,
+			"payload string too long")
+	}
+	return nil
+}
+
+// SendNotification implements the tree.EvalPlanner interface. Like in
+// Postgres, the identical notifications of a transaction are sent once.
+func (p *planner) SendNotification(ctx context.Context, channel, payload string) error {
+	if err := checkNotification(channel, payload); err != nil {
+		return err
+	}
+	pending := p.session.getTxnNotifications(p.txn)
+	n := serverpb.Notification{
+		Channel: channel,
+		Payload: payload,
+		PID:     p.session.ProcessID,
+	}
+	if _, ok := pending.sent[n]; ok {
+		return nil
+	}
+	if pending.sent == nil {
+		pending.sent = make(map[serverpb.Notification]struct{})
+	}
+	pending.sent[n] = struct{}{}
+	pending.notifications = append(pending.notifications, n)
+	return nil
+}
+
+// ListeningChannels implements the tree.EvalPlanner interface.
+func (p *planner) ListeningChannels() []string {
+	channels := make([]string, 0, len(p.session.listenChannels))
+	for channel := range p.session.listenChannels {
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+	return channels
+}
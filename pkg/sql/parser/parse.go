@@ -173,6 +173,10 @@ func ParseStringAs(t types.T, s string, evalCtx *tree.EvalContext) (tree.Datum,
 		d, err = tree.ParseDIPAddrFromINetString(s)
 	case types.JSON:
 		d, err = tree.ParseDJSON(s)
+	case types.TSVector:
+		d, err = tree.ParseDTSVector(s)
+	case types.TSQuery:
+		d, err = tree.ParseDTSQuery(s)
 	default:
 		if a, ok := t.(types.TArray); ok {
 			typ, err := coltypes.DatumTypeToColumnType(a.Typ)
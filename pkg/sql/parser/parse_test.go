@@ -79,11 +79,16 @@ func TestParse(t *testing.T) {
 		{`CREATE INDEX ON a (b) INTERLEAVE IN PARENT c (d)`},
 		{`CREATE INDEX ON a (b) INTERLEAVE IN PARENT c.d (e)`},
 		{`CREATE INDEX ON a (b ASC, c DESC)`},
+		{`CREATE INDEX ON a (b COLLATE en)`},
+		{`CREATE INDEX ON a (b COLLATE "en-u-ks-level2" DESC, c)`},
 		{`CREATE UNIQUE INDEX a ON b (c)`},
 		{`CREATE UNIQUE INDEX a ON b (c) STORING (d)`},
 		{`CREATE UNIQUE INDEX a ON b (c) INTERLEAVE IN PARENT d (e, f)`},
 		{`CREATE UNIQUE INDEX a ON b (c) INTERLEAVE IN PARENT d.e (f, g)`},
 		{`CREATE UNIQUE INDEX a ON b.c (d)`},
+		{`CREATE INVERTED INDEX a ON b (c)`},
+		{`CREATE INVERTED INDEX IF NOT EXISTS a ON b (c)`},
+		{`CREATE INVERTED INDEX ON a (b)`},
 
 		{`CREATE TABLE a ()`},
 		{`CREATE TABLE a (b INT)`},
@@ -101,6 +106,9 @@ func TestParse(t *testing.T) {
 		{`CREATE TABLE a (b TIME)`},
 		{`CREATE TABLE a (b UUID)`},
 		{`CREATE TABLE a (b INET)`},
+		{`CREATE TABLE a (b TSVECTOR, c TSQUERY)`},
+		{`CREATE TABLE a (b TSVECTOR, INVERTED INDEX (b))`},
+		{`CREATE TABLE a (b TSVECTOR, INVERTED INDEX c (b))`},
 		{`CREATE TABLE a (b INT NULL)`},
 		{`CREATE TABLE a (b INT CONSTRAINT maybe NULL)`},
 		{`CREATE TABLE a (b INT NOT NULL)`},
@@ -146,6 +154,7 @@ func TestParse(t *testing.T) {
 		{`CREATE TABLE a (b INT, c TEXT, CONSTRAINT d UNIQUE (b, c) INTERLEAVE IN PARENT d (e, f))`},
 		{`CREATE TABLE a (b INT, UNIQUE (b))`},
 		{`CREATE TABLE a (b INT, UNIQUE (b) STORING (c))`},
+		{`CREATE TABLE a (b STRING COLLATE en, UNIQUE (b COLLATE en))`},
 		{`CREATE TABLE a (b INT, INDEX (b))`},
 		{`CREATE TABLE a (b INT, c INT REFERENCES foo)`},
 		{`CREATE TABLE a (b INT, c INT REFERENCES foo ON UPDATE RESTRICT)`},
@@ -298,6 +307,7 @@ func TestParse(t *testing.T) {
 		{`EXPLAIN SELECT 1`},
 		{`EXPLAIN EXPLAIN SELECT 1`},
 		{`EXPLAIN (A, B, C) SELECT 1`},
+		{`EXPLAIN (ANALYZE, DISTSQL) SELECT 1`},
 		{`SELECT * FROM [EXPLAIN SELECT 1]`},
 		{`SELECT * FROM [SHOW TRANSACTION STATUS]`},
 
@@ -397,6 +407,26 @@ func TestParse(t *testing.T) {
 		{`DEALLOCATE a`},
 		{`DEALLOCATE ALL`},
 
+		{`DECLARE a CURSOR FOR SELECT 1`},
+		{`DECLARE a CURSOR WITH HOLD FOR SELECT * FROM t ORDER BY k`},
+		{`FETCH 1 FROM a`},
+		{`FETCH 10 FROM a`},
+		{`FETCH ALL FROM a`},
+		{`MOVE 1 FROM a`},
+		{`MOVE ALL FROM a`},
+		{`CLOSE a`},
+		{`CLOSE ALL`},
+
+		{`LISTEN a`},
+		{`LISTEN "Foo"`},
+		{`UNLISTEN a`},
+		{`UNLISTEN *`},
+		{`NOTIFY a`},
+		{`NOTIFY a, 'foo''s payload'`},
+		{`EXPLAIN LISTEN a`},
+		{`EXPLAIN UNLISTEN *`},
+		{`EXPLAIN NOTIFY a, 'b'`},
+
 		// Tables are the default, but can also be specified with
 		// GRANT x ON TABLE y. However, the stringer does not output TABLE.
 		{`GRANT SELECT ON foo TO root`},
@@ -483,6 +513,9 @@ func TestParse(t *testing.T) {
 		{`SELECT (1, 2, 3)`},
 		{`SELECT (ROW(1, 2, 3))`},
 		{`SELECT (ROW())`},
+		{`SELECT ((1, 2) AS a, b)`},
+		{`SELECT (ROW(1) AS a)`},
+		{`SELECT ((1, 2) AS "x y", "select")`},
 		{`SELECT (TABLE a)`},
 		{`SELECT 0x1`},
 		{`SELECT 'Deutsch' COLLATE "DE"`},
@@ -491,6 +524,8 @@ func TestParse(t *testing.T) {
 		{`SELECT a ? b`},
 		{`SELECT a ?| b`},
 		{`SELECT a ?& b`},
+		{`SELECT a @? b`},
+		{`SELECT a @@ b`},
 		{`SELECT a->'x'`},
 		{`SELECT a#>'{x}'`},
 		{`SELECT a#>>'{x}'`},
@@ -817,6 +852,13 @@ func TestParse(t *testing.T) {
 
 		{`COPY t FROM STDIN`},
 		{`COPY t (a, b, c) FROM STDIN`},
+		{`COPY t FROM STDIN WITH (FORMAT csv, HEADER true, DELIMITER ';', NULL '', QUOTE e'\'')`},
+		{`COPY t FROM STDIN WITH (FORMAT binary)`},
+		{`COPY t TO STDOUT`},
+		{`COPY t (a, b) TO STDOUT`},
+		{`COPY t TO STDOUT WITH (FORMAT text, DELIMITER '|', NULL 'null')`},
+		{`COPY (SELECT a FROM t WHERE b > 1) TO STDOUT`},
+		{`COPY (SELECT 1) TO STDOUT WITH (FORMAT csv, HEADER false)`},
 
 		{`ALTER TABLE a SPLIT AT VALUES (1)`},
 		{`ALTER TABLE a SPLIT AT SELECT * FROM t`},
@@ -913,6 +955,16 @@ func TestParse2(t *testing.T) {
 	}{
 		{`CREATE DATABASE a WITH ENCODING = 'foo'`,
 			`CREATE DATABASE a ENCODING = 'foo'`},
+		{`COPY t FROM STDIN CSV HEADER`,
+			`COPY t FROM STDIN WITH (FORMAT csv, HEADER true)`},
+		{`COPY t FROM STDIN WITH CSV DELIMITER AS ';' NULL AS 'x' QUOTE '"'`,
+			`COPY t FROM STDIN WITH (FORMAT csv, DELIMITER ';', NULL 'x', QUOTE '"')`},
+		{`COPY t TO STDOUT BINARY`,
+			`COPY t TO STDOUT WITH (FORMAT binary)`},
+		{`COPY t TO STDOUT (format 'CSV', header, delimiter E'\t')`,
+			`COPY t TO STDOUT WITH (FORMAT csv, HEADER true, DELIMITER e'\t')`},
+		{`COPY t TO STDOUT WITH (HEADER off)`,
+			`COPY t TO STDOUT WITH (HEADER false)`},
 		{`CREATE DATABASE a TEMPLATE = template0`,
 			`CREATE DATABASE a TEMPLATE = 'template0'`},
 		{`CREATE DATABASE a TEMPLATE = invalid`,
@@ -1185,6 +1237,18 @@ func TestParse2(t *testing.T) {
 			`DEALLOCATE a`},
 		{`DEALLOCATE PREPARE ALL`,
 			`DEALLOCATE ALL`},
+		{`DECLARE a NO SCROLL CURSOR WITHOUT HOLD FOR SELECT 1`,
+			`DECLARE a CURSOR FOR SELECT 1`},
+		{`NOTIFY a, ''`, `NOTIFY a`},
+		{`FETCH a`, `FETCH 1 FROM a`},
+		{`FETCH IN a`, `FETCH 1 FROM a`},
+		{`FETCH NEXT a`, `FETCH 1 FROM a`},
+		{`FETCH 3 IN a`, `FETCH 3 FROM a`},
+		{`FETCH FORWARD FROM a`, `FETCH 1 FROM a`},
+		{`FETCH FORWARD 3 a`, `FETCH 3 FROM a`},
+		{`FETCH FORWARD ALL IN a`, `FETCH ALL FROM a`},
+		{`MOVE a`, `MOVE 1 FROM a`},
+		{`MOVE FORWARD 5 IN a`, `MOVE 5 FROM a`},
 
 		{`BACKUP DATABASE foo TO bar`,
 			`BACKUP DATABASE foo TO 'bar'`},
@@ -1222,6 +1286,10 @@ func TestParse2(t *testing.T) {
 			`DROP USER 'foo', 'bar'`},
 		{`ALTER USER foo WITH PASSWORD bar`,
 			`ALTER USER 'foo' WITH PASSWORD 'bar'`},
+		{`ALTER USER foo CONNECTION LIMIT 10`,
+			`ALTER USER 'foo' WITH CONNECTION LIMIT 10`},
+		{`ALTER USER IF EXISTS foo WITH CONNECTION LIMIT -1`,
+			`ALTER USER IF EXISTS 'foo' WITH CONNECTION LIMIT -1`},
 
 		{
 			`CREATE TABLE a (b INT, FOREIGN KEY (b) REFERENCES other ON UPDATE NO ACTION ON DELETE NO ACTION)`,
@@ -1291,6 +1359,8 @@ func TestParse2(t *testing.T) {
 			`CREATE TABLE a (b INT, FOREIGN KEY (b) REFERENCES other ON UPDATE SET DEFAULT ON DELETE RESTRICT)`,
 			`CREATE TABLE a (b INT, FOREIGN KEY (b) REFERENCES other ON DELETE RESTRICT ON UPDATE SET DEFAULT)`,
 		},
+		{`EXPLAIN ANALYZE SELECT 1`, `EXPLAIN (ANALYZE) SELECT 1`},
+		{`EXPLAIN ANALYSE (DISTSQL) SELECT 1`, `EXPLAIN (ANALYZE, DISTSQL) SELECT 1`},
 	}
 	for _, d := range testData {
 		stmts, err := Parse(d.sql)
@@ -1378,6 +1448,22 @@ SET TIME ZONE INTERVAL 'foobar'
 		{`SELECT INTERVAL 'foo'`, `could not parse "foo" as type interval: interval: missing unit at position 0: "foo" at or near "EOF"
 SELECT INTERVAL 'foo'
                      ^
+`},
+		{`COPY t TO STDOUT WITH (FORMAT csv, FORMAT text)`, `conflicting or redundant options at or near "text"
+COPY t TO STDOUT WITH (FORMAT csv, FORMAT text)
+                                          ^
+`},
+		{`COPY t TO STDOUT CSV CSV`, `conflicting or redundant options at or near "csv"
+COPY t TO STDOUT CSV CSV
+                     ^
+`},
+		{`COPY t FROM STDIN WITH (FORMAT xml)`, `COPY format "xml" not recognized at or near "xml"
+COPY t FROM STDIN WITH (FORMAT xml)
+                               ^
+`},
+		{`COPY t FROM STDIN WITH (ENCODING 'utf8')`, `option "encoding" not recognized at or near "utf8"
+COPY t FROM STDIN WITH (ENCODING 'utf8')
+                                 ^
 `},
 		{`SELECT 1 /* hello`, `unterminated comment
 SELECT 1 /* hello
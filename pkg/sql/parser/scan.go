@@ -421,6 +421,14 @@ func (s *Scanner) scan(lval *sqlSymType) {
 			s.pos++
 			lval.id = CONTAINS
 			return
+		case '?': // @?
+			s.pos++
+			lval.id = JSONPATH_EXISTS
+			return
+		case '@': // @@
+			s.pos++
+			lval.id = MATCHES
+			return
 		}
 		return
 
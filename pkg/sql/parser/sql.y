@@ -45,6 +45,21 @@ func unimplementedWithIssue(sqllex sqlLexer, issue int) int {
     sqllex.(*Scanner).UnimplementedWithIssue(issue)
     return 1
 }
+
+// labelTuple sets the labels of a tuple. It reports an error and returns
+// false if the number of labels doesn't match the number of elements.
+func labelTuple(sqllex sqlLexer, t *tree.Tuple, labels tree.NameList) bool {
+    if len(labels) != len(t.Exprs) {
+        sqllex.Error(fmt.Sprintf("mismatch in tuple definition: %d expressions, %d labels",
+            len(t.Exprs), len(labels)))
+        return false
+    }
+    t.Labels = make([]string, len(labels))
+    for i, l := range labels {
+        t.Labels[i] = string(l)
+    }
+    return true
+}
 %}
 
 %{
@@ -355,6 +370,9 @@ func (u *sqlSymUnion) rangePartition() tree.RangePartition {
 func (u *sqlSymUnion) rangePartitions() []tree.RangePartition {
     return u.val.([]tree.RangePartition)
 }
+func (u *sqlSymUnion) cursorStmt() *tree.CursorStmt {
+    return u.val.(*tree.CursorStmt)
+}
 func (u *sqlSymUnion) tuples() []*tree.Tuple {
     return u.val.([]*tree.Tuple)
 }
@@ -385,6 +403,9 @@ func (u *sqlSymUnion) kvOptions() []tree.KVOption {
 func (u *sqlSymUnion) transactionModes() tree.TransactionModes {
     return u.val.(tree.TransactionModes)
 }
+func (u *sqlSymUnion) copyOptions() tree.CopyOptions {
+    return u.val.(tree.CopyOptions)
+}
 func (u *sqlSymUnion) referenceAction() tree.ReferenceAction {
     return u.val.(tree.ReferenceAction)
 }
@@ -423,20 +444,20 @@ func (u *sqlSymUnion) scrubOption() tree.ScrubOption {
 %token <str>   ALL ALL_EXISTENCE ALTER ANALYSE ANALYZE AND ANY ANNOTATE_TYPE ARRAY AS ASC
 %token <str>   ASYMMETRIC AT
 
-%token <str>   BACKUP BEGIN BETWEEN BIGINT BIGSERIAL BIT
+%token <str>   BACKUP BEGIN BETWEEN BIGINT BIGSERIAL BINARY BIT
 %token <str>   BLOB BOOL BOOLEAN BOTH BY BYTEA BYTES
 
 %token <str>   CACHE CANCEL CASCADE CASE CAST CHAR
 %token <str>   CHARACTER CHARACTERISTICS CHECK
-%token <str>   CLUSTER COALESCE COLLATE COLLATION COLUMN COLUMNS COMMIT
+%token <str>   CLOSE CLUSTER COALESCE COLLATE COLLATION COLUMN COLUMNS COMMIT
 %token <str>   COMMITTED CONCAT CONFIGURATION CONFIGURATIONS CONFIGURE
-%token <str>   CONFLICT CONSTRAINT CONSTRAINTS CONTAINS COPY COVERING CREATE
+%token <str>   CONFLICT CONNECTION CONSTRAINT CONSTRAINTS CONTAINS COPY COVERING CREATE
 %token <str>   CROSS CSV CUBE CURRENT CURRENT_CATALOG CURRENT_DATE CURRENT_SCHEMA
 %token <str>   CURRENT_ROLE CURRENT_TIME CURRENT_TIMESTAMP
-%token <str>   CURRENT_USER CYCLE
+%token <str>   CURRENT_USER CURSOR CYCLE
 
 %token <str>   DATA DATABASE DATABASES DATE DAY DEC DECIMAL DEFAULT
-%token <str>   DEALLOCATE DEFERRABLE DELETE DESC
+%token <str>   DEALLOCATE DECLARE DEFERRABLE DELETE DELIMITER DESC
 %token <str>   DISCARD DISTINCT DO DOUBLE DROP
 
 %token <str>   ELSE ENCODING END ESCAPE EXCEPT
@@ -444,29 +465,29 @@ func (u *sqlSymUnion) scrubOption() tree.ScrubOption {
 %token <str>   EXPLAIN EXTRACT EXTRACT_DURATION
 
 %token <str>   FALSE FAMILY FETCH FETCHVAL FETCHTEXT FETCHVAL_PATH FETCHTEXT_PATH FILTER
-%token <str>   FIRST FLOAT FLOAT4 FLOAT8 FLOORDIV FOLLOWING FOR FORCE_INDEX FOREIGN FROM FULL
+%token <str>   FIRST FLOAT FLOAT4 FLOAT8 FLOORDIV FOLLOWING FOR FORCE_INDEX FOREIGN FORWARD FROM FULL
 
 %token <str>   GRANT GRANTS GREATEST GROUP GROUPING
 
-%token <str>   HAVING HELP HIGH HOUR
+%token <str>   HAVING HEADER HELP HIGH HOLD HOUR
 
 %token <str>   IMPORT INCREMENT INCREMENTAL IF IFNULL ILIKE IN INET INTERLEAVE
 %token <str>   INDEX INDEXES INITIALLY
 %token <str>   INNER INSERT INT INT2VECTOR INT2 INT4 INT8 INT64 INTEGER
-%token <str>   INTERSECT INTERVAL INTO IS ISOLATION
+%token <str>   INTERSECT INTERVAL INTO INVERTED IS ISOLATION
 
-%token <str>   JOB JOBS JOIN JSON JSONB
+%token <str>   JOB JOBS JOIN JSON JSONB JSONPATH_EXISTS
 
 %token <str>   KEY KEYS KV
 
 %token <str>   LATERAL LC_CTYPE LC_COLLATE
-%token <str>   LEADING LEAST LEFT LESS LEVEL LIKE LIMIT LIST LOCAL
+%token <str>   LEADING LEAST LEFT LESS LEVEL LIKE LIMIT LIST LISTEN LOCAL
 %token <str>   LOCALTIME LOCALTIMESTAMP LOW LSHIFT
 
-%token <str>   MATCH MINVALUE MAXVALUE MINUTE MONTH
+%token <str>   MATCH MATCHES MINVALUE MAXVALUE MINUTE MONTH MOVE
 
 %token <str>   NAN NAME NAMES NATURAL NEXT NO NO_INDEX_JOIN NORMAL
-%token <str>   NOT NOTHING NULL NULLIF
+%token <str>   NOT NOTHING NOTIFY NULL NULLIF
 %token <str>   NULLS NUMERIC
 
 %token <str>   OF OFF OFFSET OID ON ONLY OPTIONS OR
@@ -475,7 +496,7 @@ func (u *sqlSymUnion) scrubOption() tree.ScrubOption {
 %token <str>   PARENT PARTIAL PARTITION PASSWORD PAUSE PHYSICAL PLACING
 %token <str>   PLANS POSITION PRECEDING PRECISION PREPARE PRIMARY PRIORITY
 
-%token <str>   QUERIES QUERY
+%token <str>   QUERIES QUERY QUOTE
 
 %token <str>   RANGE READ REAL RECURSIVE REF REFERENCES
 %token <str>   REGCLASS REGPROC REGPROCEDURE REGNAMESPACE REGTYPE
@@ -483,17 +504,17 @@ func (u *sqlSymUnion) scrubOption() tree.ScrubOption {
 %token <str>   RELEASE RESET RESTORE RESTRICT RESUME RETURNING REVOKE RIGHT
 %token <str>   ROLLBACK ROLLUP ROW ROWS RSHIFT
 
-%token <str>   SAVEPOINT SCATTER SCRUB SEARCH SECOND SELECT SEQUENCE SEQUENCES
+%token <str>   SAVEPOINT SCATTER SCROLL SCRUB SEARCH SECOND SELECT SEQUENCE SEQUENCES
 %token <str>   SERIAL SERIALIZABLE SESSION SESSIONS SESSION_USER SET SETTING SETTINGS
 %token <str>   SHOW SIMILAR SIMPLE SMALLINT SMALLSERIAL SNAPSHOT SOME SOME_EXISTENCE SPLIT SQL
-%token <str>   START STATUS STDIN STRICT STRING STORE STORING SUBSTRING
+%token <str>   START STATUS STDIN STDOUT STRICT STRING STORE STORING SUBSTRING
 %token <str>   SYMMETRIC SYSTEM
 
 %token <str>   TABLE TABLES TEMP TEMPLATE TEMPORARY TESTING_RANGES TESTING_RELOCATE TEXT THAN THEN
 %token <str>   TIME TIMESTAMP TIMESTAMPTZ TO TRAILING TRACE TRANSACTION TREAT TRIM TRUE
-%token <str>   TRUNCATE TYPE
+%token <str>   TRUNCATE TSQUERY TSVECTOR TYPE
 
-%token <str>   UNBOUNDED UNCOMMITTED UNION UNIQUE UNKNOWN
+%token <str>   UNBOUNDED UNCOMMITTED UNION UNIQUE UNKNOWN UNLISTEN
 %token <str>   UPDATE UPSERT USE USER USERS USING UUID
 
 %token <str>   VALID VALIDATE VALUE VALUES VARCHAR VARIADIC VIEW VARYING
@@ -553,6 +574,7 @@ func (u *sqlSymUnion) scrubOption() tree.ScrubOption {
 
 // ALTER USER
 %type <tree.Statement> alter_user_password_stmt
+%type <tree.Statement> alter_user_connection_limit_stmt
 
 // ALTER INDEX
 %type <tree.Statement> alter_scatter_index_stmt
@@ -584,6 +606,12 @@ func (u *sqlSymUnion) scrubOption() tree.ScrubOption {
 
 %type <tree.Statement> commit_stmt
 %type <tree.Statement> copy_from_stmt
+%type <tree.Statement> copy_to_stmt
+%type <tree.CopyOptions> opt_copy_options copy_options
+%type <tree.CopyOptions> copy_generic_option_list copy_generic_option
+%type <tree.CopyOptions> copy_legacy_option_list copy_legacy_option
+%type <str> copy_generic_option_arg
+%type <empty> opt_as
 
 %type <tree.Statement> create_stmt
 %type <tree.Statement> create_ddl_stmt
@@ -612,9 +640,19 @@ func (u *sqlSymUnion) scrubOption() tree.ScrubOption {
 %type <tree.Statement> explainable_stmt
 %type <tree.Statement> execute_stmt
 %type <tree.Statement> deallocate_stmt
+%type <tree.Statement> declare_stmt
+%type <tree.Statement> fetch_stmt
+%type <tree.Statement> move_stmt
+%type <tree.Statement> close_stmt
+%type <*tree.CursorStmt> fetch_args
+%type <bool> opt_hold
+%type <empty> opt_cursor_options from_in opt_from_in
 %type <tree.Statement> grant_stmt
 %type <tree.Statement> insert_stmt
 %type <tree.Statement> import_stmt
+%type <tree.Statement> listen_stmt
+%type <tree.Statement> notify_stmt
+%type <tree.Statement> unlisten_stmt
 %type <tree.Statement> pause_stmt
 %type <tree.Statement> release_stmt
 %type <tree.Statement> reset_stmt reset_session_stmt reset_csetting_stmt
@@ -698,7 +736,7 @@ func (u *sqlSymUnion) scrubOption() tree.ScrubOption {
 
 %type <tree.Operator> subquery_op
 %type <tree.FunctionReference> func_name
-%type <empty> opt_collate
+%type <str> opt_collate
 
 %type <tree.UnresolvedName> qualified_name
 %type <tree.UnresolvedName> table_pattern
@@ -800,7 +838,7 @@ func (u *sqlSymUnion) scrubOption() tree.ScrubOption {
 %type <tree.Expr>  interval
 %type <[]coltypes.T> type_list prep_type_clause
 %type <tree.Exprs> array_expr_list
-%type <tree.Expr>  row explicit_row implicit_row
+%type <tree.Expr>  row explicit_row implicit_row labeled_row
 %type <tree.Expr>  case_expr case_arg case_default
 %type <*tree.When>  when_clause
 %type <[]*tree.When> when_clause_list
@@ -819,6 +857,7 @@ func (u *sqlSymUnion) scrubOption() tree.ScrubOption {
 %type <tree.AsOfClause> opt_as_of_clause
 
 %type <str> explain_option_name
+%type <str> analyze_target
 %type <[]string> explain_option_list
 
 %type <coltypes.T> typename simple_typename const_typename
@@ -891,7 +930,7 @@ func (u *sqlSymUnion) scrubOption() tree.ScrubOption {
 %left      AND
 %right     NOT
 %nonassoc  IS                  // IS sets precedence for IS NULL, etc
-%nonassoc  '<' '>' '=' LESS_EQUALS GREATER_EQUALS NOT_EQUALS CONTAINS CONTAINED_BY '?' SOME_EXISTENCE ALL_EXISTENCE
+%nonassoc  '<' '>' '=' LESS_EQUALS GREATER_EQUALS NOT_EQUALS CONTAINS CONTAINED_BY '?' SOME_EXISTENCE ALL_EXISTENCE JSONPATH_EXISTS MATCHES
 %nonassoc  '~' BETWEEN IN LIKE ILIKE SIMILAR NOT_REGMATCH REGIMATCH NOT_REGIMATCH NOT_LA
 %nonassoc  ESCAPE              // ESCAPE must be just above LIKE/ILIKE/SIMILAR
 %nonassoc  OVERLAPS
@@ -975,18 +1014,25 @@ stmt:
 | alter_stmt      // help texts in sub-rule
 | backup_stmt     // EXTEND WITH HELP: BACKUP
 | cancel_stmt     // help texts in sub-rule
+| close_stmt      // EXTEND WITH HELP: CLOSE
 | scrub_stmt
 | copy_from_stmt
+| copy_to_stmt
 | create_stmt     // help texts in sub-rule
 | deallocate_stmt // EXTEND WITH HELP: DEALLOCATE
+| declare_stmt    // EXTEND WITH HELP: DECLARE
 | delete_stmt     // EXTEND WITH HELP: DELETE
 | discard_stmt    // EXTEND WITH HELP: DISCARD
 | drop_stmt       // help texts in sub-rule
 | execute_stmt    // EXTEND WITH HELP: EXECUTE
 | explain_stmt    // EXTEND WITH HELP: EXPLAIN
+| fetch_stmt      // EXTEND WITH HELP: FETCH
 | grant_stmt      // EXTEND WITH HELP: GRANT
 | insert_stmt     // EXTEND WITH HELP: INSERT
 | import_stmt     // EXTEND WITH HELP: IMPORT
+| listen_stmt     // EXTEND WITH HELP: LISTEN
+| move_stmt       // EXTEND WITH HELP: MOVE
+| notify_stmt     // EXTEND WITH HELP: NOTIFY
 | pause_stmt      // EXTEND WITH HELP: PAUSE JOB
 | prepare_stmt    // EXTEND WITH HELP: PREPARE
 | restore_stmt    // EXTEND WITH HELP: RESTORE
@@ -1003,6 +1049,7 @@ stmt:
 | show_stmt        // help texts in sub-rule
 | transaction_stmt // help texts in sub-rule
 | truncate_stmt    // EXTEND WITH HELP: TRUNCATE
+| unlisten_stmt    // EXTEND WITH HELP: UNLISTEN
 | update_stmt      // EXTEND WITH HELP: UPDATE
 | upsert_stmt      // EXTEND WITH HELP: UPSERT
 | /* EMPTY */
@@ -1102,9 +1149,11 @@ alter_sequence_options_stmt:
 // %Category: Priv
 // %Text:
 // ALTER USER [IF EXISTS] <name> WITH PASSWORD <password>
+// ALTER USER [IF EXISTS] <name> [WITH] CONNECTION LIMIT <limit>
 // %SeeAlso: CREATE USER
 alter_user_stmt:
   alter_user_password_stmt
+| alter_user_connection_limit_stmt
 | ALTER USER error // SHOW HELP: ALTER USER
 
 // %Help: ALTER DATABASE - change the definition of a database
@@ -1550,19 +1599,150 @@ opt_with_options:
 | /* EMPTY */ {}
 
 copy_from_stmt:
-  COPY qualified_name FROM STDIN
+  COPY qualified_name FROM STDIN opt_copy_options
+  {
+    $$.val = &tree.CopyFrom{Table: $2.normalizableTableName(), Stdin: true, Options: $5.copyOptions()}
+  }
+| COPY qualified_name '(' ')' FROM STDIN opt_copy_options
+  {
+    $$.val = &tree.CopyFrom{Table: $2.normalizableTableName(), Stdin: true, Options: $7.copyOptions()}
+  }
+| COPY qualified_name '(' qualified_name_list ')' FROM STDIN opt_copy_options
+  {
+    $$.val = &tree.CopyFrom{Table: $2.normalizableTableName(), Columns: $4.unresolvedNames(), Stdin: true, Options: $8.copyOptions()}
+  }
+
+copy_to_stmt:
+  COPY qualified_name TO STDOUT opt_copy_options
+  {
+    $$.val = &tree.CopyTo{Table: $2.normalizableTableName(), Options: $5.copyOptions()}
+  }
+| COPY qualified_name '(' qualified_name_list ')' TO STDOUT opt_copy_options
+  {
+    $$.val = &tree.CopyTo{Table: $2.normalizableTableName(), Columns: $4.unresolvedNames(), Options: $8.copyOptions()}
+  }
+| COPY select_with_parens TO STDOUT opt_copy_options
+  {
+    $$.val = &tree.CopyTo{Query: $2.selectStmt().(*tree.ParenSelect).Select, Options: $5.copyOptions()}
+  }
+
+opt_copy_options:
+  WITH copy_options
+  {
+    $$.val = $2.copyOptions()
+  }
+| copy_options
+  {
+    $$.val = $1.copyOptions()
+  }
+| /* EMPTY */
+  {
+    $$.val = tree.CopyOptions{}
+  }
+
+// The parenthesized option list is the current syntax; the options
+// without parentheses are the syntax of PostgreSQL 9.0 and earlier, which
+// psql still uses for \copy.
+copy_options:
+  '(' copy_generic_option_list ')'
+  {
+    $$.val = $2.copyOptions()
+  }
+| copy_legacy_option_list
   {
-    $$.val = &tree.CopyFrom{Table: $2.normalizableTableName(), Stdin: true}
+    $$.val = $1.copyOptions()
   }
-| COPY qualified_name '(' ')' FROM STDIN
+
+copy_generic_option_list:
+  copy_generic_option
   {
-    $$.val = &tree.CopyFrom{Table: $2.normalizableTableName(), Stdin: true}
+    $$.val = $1.copyOptions()
   }
-| COPY qualified_name '(' qualified_name_list ')' FROM STDIN
+| copy_generic_option_list ',' copy_generic_option
   {
-    $$.val = &tree.CopyFrom{Table: $2.normalizableTableName(), Columns: $4.unresolvedNames(), Stdin: true}
+    a := $1.copyOptions()
+    if err := a.Merge($3.copyOptions()); err != nil {
+      sqllex.Error(err.Error())
+      return 1
+    }
+    $$.val = a
   }
 
+copy_generic_option:
+  unrestricted_name copy_generic_option_arg
+  {
+    opts, err := tree.MakeCopyOption($1, $2, true /* hasValue */)
+    if err != nil {
+      sqllex.Error(err.Error())
+      return 1
+    }
+    $$.val = opts
+  }
+| unrestricted_name
+  {
+    opts, err := tree.MakeCopyOption($1, "", false /* hasValue */)
+    if err != nil {
+      sqllex.Error(err.Error())
+      return 1
+    }
+    $$.val = opts
+  }
+
+copy_generic_option_arg:
+  non_reserved_word_or_sconst
+| TRUE
+| FALSE
+| ON
+
+copy_legacy_option_list:
+  copy_legacy_option
+  {
+    $$.val = $1.copyOptions()
+  }
+| copy_legacy_option_list copy_legacy_option
+  {
+    a := $1.copyOptions()
+    if err := a.Merge($2.copyOptions()); err != nil {
+      sqllex.Error(err.Error())
+      return 1
+    }
+    $$.val = a
+  }
+
+copy_legacy_option:
+  BINARY
+  {
+    $$.val = tree.CopyOptions{FileFormat: tree.CopyFormatBinary}
+  }
+| CSV
+  {
+    $$.val = tree.CopyOptions{FileFormat: tree.CopyFormatCSV}
+  }
+| HEADER
+  {
+    header := true
+    $$.val = tree.CopyOptions{Header: &header}
+  }
+| DELIMITER opt_as SCONST
+  {
+    delimiter := $3
+    $$.val = tree.CopyOptions{Delimiter: &delimiter}
+  }
+| NULL opt_as SCONST
+  {
+    null := $3
+    $$.val = tree.CopyOptions{Null: &null}
+  }
+| QUOTE opt_as SCONST
+  {
+    quote := $3
+    $$.val = tree.CopyOptions{Quote: &quote}
+  }
+
+opt_as:
+  AS {}
+| /* EMPTY */ {}
+
 // %Help: CANCEL
 // %Category: Group
 // %Text: CANCEL JOB, CANCEL QUERY
@@ -1803,13 +1983,17 @@ attrs:
 // %Text:
 // EXPLAIN <statement>
 // EXPLAIN [( [PLAN ,] <planoptions...> )] <statement>
+// EXPLAIN ANALYZE [( <planoptions...> )] <statement>
 //
 // Explainable statements:
 //     SELECT, CREATE, DROP, ALTER, INSERT, UPSERT, UPDATE, DELETE,
 //     SHOW, EXPLAIN, EXECUTE
 //
 // Plan options:
-//     TYPES, EXPRS, METADATA, QUALIFY, INDENT, VERBOSE, DIST_SQL
+//     TYPES, EXPRS, METADATA, QUALIFY, INDENT, VERBOSE, DIST_SQL, ANALYZE
+//
+// EXPLAIN ANALYZE runs the statement and annotates the plan with runtime
+// statistics collected for each DistSQL processor.
 //
 // %SeeAlso: WEBDOCS/explain.html
 explain_stmt:
@@ -1822,6 +2006,14 @@ explain_stmt:
   {
     $$.val = &tree.Explain{Options: $3.strs(), Statement: $5.stmt()}
   }
+| EXPLAIN analyze_target explainable_stmt
+  {
+    $$.val = &tree.Explain{Options: []string{"analyze"}, Statement: $3.stmt()}
+  }
+| EXPLAIN analyze_target '(' explain_option_list ')' explainable_stmt
+  {
+    $$.val = &tree.Explain{Options: append([]string{"analyze"}, $4.strs()...), Statement: $6.stmt()}
+  }
 // This second error rule is necessary, because otherwise
 // explainable_stmt also provides "selectclause := '(' error ..."  and
 // cause a help text for the select clause, which will be confusing in
@@ -1858,6 +2050,9 @@ explainable_stmt:
 | drop_ddl_stmt    // help texts in sub-rule
 | execute_stmt     // EXTEND WITH HELP: EXECUTE
 | explain_stmt { /* SKIP DOC */ }
+| listen_stmt      // EXTEND WITH HELP: LISTEN
+| notify_stmt      // EXTEND WITH HELP: NOTIFY
+| unlisten_stmt    // EXTEND WITH HELP: UNLISTEN
 
 explain_option_list:
   explain_option_name
@@ -1871,6 +2066,14 @@ explain_option_list:
 
 explain_option_name:
   non_reserved_word
+| analyze_target
+  {
+    $$ = "analyze"
+  }
+
+analyze_target:
+  ANALYZE
+| ANALYSE
 
 // %Help: PREPARE - prepare a statement for later execution
 // %Category: Misc
@@ -1946,6 +2149,174 @@ deallocate_stmt:
   }
 | DEALLOCATE error // SHOW HELP: DEALLOCATE
 
+// %Help: DECLARE - define a cursor
+// %Category: Misc
+// %Text:
+// DECLARE <name> [NO SCROLL] CURSOR [ { WITH | WITHOUT } HOLD ] FOR <selectclause>
+// %SeeAlso: FETCH, MOVE, CLOSE
+declare_stmt:
+  DECLARE name opt_cursor_options CURSOR opt_hold FOR select_stmt
+  {
+    $$.val = &tree.DeclareCursor{
+      Name: tree.Name($2),
+      Hold: $5.bool(),
+      Select: $7.slct(),
+    }
+  }
+| DECLARE error // SHOW HELP: DECLARE
+
+opt_cursor_options:
+  NO SCROLL {}
+| SCROLL { return unimplemented(sqllex, "scroll cursor") }
+| /* EMPTY */ {}
+
+opt_hold:
+  WITH HOLD
+  {
+    $$.val = true
+  }
+| WITHOUT HOLD
+  {
+    $$.val = false
+  }
+| /* EMPTY */
+  {
+    $$.val = false
+  }
+
+// %Help: FETCH - retrieve rows from a cursor
+// %Category: Misc
+// %Text:
+// FETCH [ <direction> ] [ { FROM | IN } ] <name>
+//
+// Directions:
+//   NEXT, <count>, ALL, FORWARD, FORWARD <count>, FORWARD ALL
+// %SeeAlso: DECLARE, MOVE, CLOSE
+fetch_stmt:
+  FETCH fetch_args
+  {
+    $$.val = &tree.FetchCursor{CursorStmt: *$2.cursorStmt()}
+  }
+| FETCH error // SHOW HELP: FETCH
+
+// %Help: MOVE - position a cursor
+// %Category: Misc
+// %Text:
+// MOVE [ <direction> ] [ { FROM | IN } ] <name>
+//
+// Directions:
+//   NEXT, <count>, ALL, FORWARD, FORWARD <count>, FORWARD ALL
+// %SeeAlso: DECLARE, FETCH, CLOSE
+move_stmt:
+  MOVE fetch_args
+  {
+    $$.val = &tree.MoveCursor{CursorStmt: *$2.cursorStmt()}
+  }
+| MOVE error // SHOW HELP: MOVE
+
+fetch_args:
+  name
+  {
+    $$.val = &tree.CursorStmt{Name: tree.Name($1), Count: 1}
+  }
+| from_in name
+  {
+    $$.val = &tree.CursorStmt{Name: tree.Name($2), Count: 1}
+  }
+| NEXT opt_from_in name
+  {
+    $$.val = &tree.CursorStmt{Name: tree.Name($3), Count: 1}
+  }
+| signed_iconst64 opt_from_in name
+  {
+    $$.val = &tree.CursorStmt{Name: tree.Name($3), Count: $1.int64()}
+  }
+| ALL opt_from_in name
+  {
+    $$.val = &tree.CursorStmt{Name: tree.Name($3), All: true}
+  }
+| FORWARD opt_from_in name
+  {
+    $$.val = &tree.CursorStmt{Name: tree.Name($3), Count: 1}
+  }
+| FORWARD signed_iconst64 opt_from_in name
+  {
+    $$.val = &tree.CursorStmt{Name: tree.Name($4), Count: $2.int64()}
+  }
+| FORWARD ALL opt_from_in name
+  {
+    $$.val = &tree.CursorStmt{Name: tree.Name($4), All: true}
+  }
+
+from_in:
+  FROM {}
+| IN {}
+
+opt_from_in:
+  from_in {}
+| /* EMPTY */ {}
+
+// %Help: CLOSE - close a cursor
+// %Category: Misc
+// %Text: CLOSE { <name> | ALL }
+// %SeeAlso: DECLARE, FETCH, MOVE
+close_stmt:
+  CLOSE name
+  {
+    $$.val = &tree.CloseCursor{Name: tree.Name($2)}
+  }
+| CLOSE ALL
+  {
+    $$.val = &tree.CloseCursor{}
+  }
+| CLOSE error // SHOW HELP: CLOSE
+
+// %Help: LISTEN - listen for notifications
+// %Category: Misc
+// %Text: LISTEN <channel>
+//
+// The other nodes learn through gossip that the session listens on the
+// channel. Until they do, which usually takes a few seconds, the
+// notifications committed on them are not sent to the session.
+//
+// %SeeAlso: NOTIFY, UNLISTEN
+listen_stmt:
+  LISTEN name
+  {
+    $$.val = &tree.Listen{Channel: tree.Name($2)}
+  }
+| LISTEN error // SHOW HELP: LISTEN
+
+// %Help: UNLISTEN - stop listening for notifications
+// %Category: Misc
+// %Text: UNLISTEN { <channel> | * }
+// %SeeAlso: LISTEN, NOTIFY
+unlisten_stmt:
+  UNLISTEN name
+  {
+    $$.val = &tree.Unlisten{Channel: tree.Name($2)}
+  }
+| UNLISTEN '*'
+  {
+    $$.val = &tree.Unlisten{}
+  }
+| UNLISTEN error // SHOW HELP: UNLISTEN
+
+// %Help: NOTIFY - generate a notification
+// %Category: Misc
+// %Text: NOTIFY <channel> [, <payload>]
+// %SeeAlso: LISTEN, UNLISTEN
+notify_stmt:
+  NOTIFY name
+  {
+    $$.val = &tree.Notify{Channel: tree.Name($2)}
+  }
+| NOTIFY name ',' SCONST
+  {
+    $$.val = &tree.Notify{Channel: tree.Name($2), Payload: $4}
+  }
+| NOTIFY error // SHOW HELP: NOTIFY
+
 // %Help: GRANT - define access privileges
 // %Category: Priv
 // %Text:
@@ -3077,6 +3448,14 @@ index_def:
       },
     }
   }
+| INVERTED INDEX opt_name '(' index_params ')'
+  {
+    $$.val = &tree.IndexTableDef{
+      Name:     tree.Name($3),
+      Columns:  $5.idxElems(),
+      Inverted: true,
+    }
+  }
 
 family_def:
   FAMILY opt_name '(' name_list ')'
@@ -3369,12 +3748,17 @@ create_view_stmt:
 // %Category: DDL
 // %Text:
 // CREATE [UNIQUE] INDEX [IF NOT EXISTS] [<idxname>]
-//        ON <tablename> ( <colname> [ASC | DESC] [, ...] )
+//        ON <tablename> ( <colname> [COLLATE <collationname>] [ASC | DESC] [, ...] )
 //        [STORING ( <colnames...> )] [<interleave>]
+// CREATE INVERTED INDEX [IF NOT EXISTS] [<idxname>]
+//        ON <tablename> ( <colname> )
 //
 // Interleave clause:
 //    INTERLEAVE IN PARENT <tablename> ( <colnames...> ) [CASCADE | RESTRICT]
 //
+// An inverted index maps each lexeme of a TSVECTOR column to the rows
+// containing it, and is used to speed up full-text searches using @@.
+//
 // %SeeAlso: CREATE TABLE, SHOW INDEXES, SHOW CREATE INDEX,
 // WEBDOCS/create-index.html
 create_index_stmt:
@@ -3401,6 +3785,25 @@ create_index_stmt:
       Interleave: $14.interleave(),
     }
   }
+| CREATE INVERTED INDEX opt_name ON qualified_name '(' index_params ')'
+  {
+    $$.val = &tree.CreateIndex{
+      Name:     tree.Name($4),
+      Table:    $6.normalizableTableName(),
+      Inverted: true,
+      Columns:  $8.idxElems(),
+    }
+  }
+| CREATE INVERTED INDEX IF NOT EXISTS name ON qualified_name '(' index_params ')'
+  {
+    $$.val = &tree.CreateIndex{
+      Name:        tree.Name($7),
+      Table:       $9.normalizableTableName(),
+      Inverted:    true,
+      IfNotExists: true,
+      Columns:     $11.idxElems(),
+    }
+  }
 | CREATE opt_unique INDEX error // SHOW HELP: CREATE INDEX
 
 opt_unique:
@@ -3429,14 +3832,20 @@ index_params:
 index_elem:
   name opt_collate opt_asc_desc
   {
-    $$.val = tree.IndexElem{Column: tree.Name($1), Direction: $3.dir()}
+    $$.val = tree.IndexElem{Column: tree.Name($1), Collation: $2, Direction: $3.dir()}
   }
 | func_expr_windowless opt_collate opt_asc_desc { return unimplemented(sqllex, "index_elem func expr") }
 | '(' a_expr ')' opt_collate opt_asc_desc { return unimplemented(sqllex, "index_elem a_expr") }
 
 opt_collate:
-  COLLATE unrestricted_name { return unimplementedWithIssue(sqllex, 16619) }
-| /* EMPTY */ {}
+  COLLATE unrestricted_name
+  {
+    $$ = $2
+  }
+| /* EMPTY */
+  {
+    $$ = ""
+  }
 
 opt_asc_desc:
   ASC
@@ -3469,6 +3878,16 @@ alter_user_password_stmt:
     $$.val = &tree.AlterUserSetPassword{Name: $5.expr(), Password: $8.expr(), IfExists: true}
   }
 
+alter_user_connection_limit_stmt:
+  ALTER USER string_or_placeholder opt_with CONNECTION LIMIT signed_iconst64
+  {
+    $$.val = &tree.AlterUserSetConnLimit{Name: $3.expr(), Limit: $7.int64()}
+  }
+| ALTER USER IF EXISTS string_or_placeholder opt_with CONNECTION LIMIT signed_iconst64
+  {
+    $$.val = &tree.AlterUserSetConnLimit{Name: $5.expr(), Limit: $9.int64(), IfExists: true}
+  }
+
 alter_rename_table_stmt:
   ALTER TABLE relation_expr RENAME TO qualified_name
   {
@@ -4917,6 +5336,14 @@ simple_typename:
   {
     $$.val = coltypes.INet
   }
+| TSVECTOR
+  {
+    $$.val = coltypes.TSVector
+  }
+| TSQUERY
+  {
+    $$.val = coltypes.TSQuery
+  }
 | BIGSERIAL
   {
     $$.val = coltypes.BigSerial
@@ -5406,6 +5833,14 @@ a_expr:
   {
     $$.val = &tree.ComparisonExpr{Operator: tree.ContainedBy, Left: $1.expr(), Right: $3.expr()}
   }
+| a_expr JSONPATH_EXISTS a_expr
+  {
+    $$.val = &tree.ComparisonExpr{Operator: tree.JSONPathExists, Left: $1.expr(), Right: $3.expr()}
+  }
+| a_expr MATCHES a_expr
+  {
+    $$.val = &tree.ComparisonExpr{Operator: tree.Matches, Left: $1.expr(), Right: $3.expr()}
+  }
 | a_expr '=' a_expr
   {
     $$.val = &tree.ComparisonExpr{Operator: tree.EQ, Left: $1.expr(), Right: $3.expr()}
@@ -5810,6 +6245,7 @@ d_expr:
   {
     $$.val = $1.expr()
   }
+| labeled_row
 // TODO(pmattis): Support this notation?
 // | GROUPING '(' expr_list ')' { return unimplemented(sqllex) }
 
@@ -6137,6 +6573,24 @@ implicit_row:
     $$.val = &tree.Tuple{Exprs: append($2.exprs(), $4.expr())}
   }
 
+// A labeled row names the elements of a tuple, e.g. ((1, 'foo') AS a, b). The
+// labels are used as the keys of the tuple's JSON representation.
+labeled_row:
+  '(' explicit_row AS name_list ')'
+  {
+    if !labelTuple(sqllex, $2.expr().(*tree.Tuple), $4.nameList()) {
+      return 1
+    }
+    $$.val = $2.expr()
+  }
+| '(' implicit_row AS name_list ')'
+  {
+    if !labelTuple(sqllex, $2.expr().(*tree.Tuple), $4.nameList()) {
+      return 1
+    }
+    $$.val = $2.expr()
+  }
+
 sub_type:
   ANY
   {
@@ -6777,11 +7231,13 @@ unreserved_keyword:
 | AT
 | BACKUP
 | BEGIN
+| BINARY
 | BLOB
 | BY
 | CACHE
 | CANCEL
 | CASCADE
+| CLOSE
 | CLUSTER
 | COLUMNS
 | COMMIT
@@ -6790,19 +7246,23 @@ unreserved_keyword:
 | CONFIGURATION
 | CONFIGURATIONS
 | CONFIGURE
+| CONNECTION
 | CONSTRAINTS
 | COPY
 | COVERING
 | CSV
 | CUBE
 | CURRENT
+| CURSOR
 | CYCLE
 | DATA
 | DATABASE
 | DATABASES
 | DAY
 | DEALLOCATE
+| DECLARE
 | DELETE
+| DELIMITER
 | DISCARD
 | DOUBLE
 | DROP
@@ -6815,8 +7275,11 @@ unreserved_keyword:
 | FIRST
 | FOLLOWING
 | FORCE_INDEX
+| FORWARD
 | GRANTS
+| HEADER
 | HIGH
+| HOLD
 | HOUR
 | IMPORT
 | INCREMENT
@@ -6825,6 +7288,7 @@ unreserved_keyword:
 | INSERT
 | INT2VECTOR
 | INTERLEAVE
+| INVERTED
 | ISOLATION
 | JOB
 | JOBS
@@ -6836,18 +7300,21 @@ unreserved_keyword:
 | LESS
 | LEVEL
 | LIST
+| LISTEN
 | LOCAL
 | LOW
 | MATCH
 | MINUTE
 | MINVALUE
 | MONTH
+| MOVE
 | NAMES
 | NAN
 | NEXT
 | NO
 | NORMAL
 | NO_INDEX_JOIN
+| NOTIFY
 | NULLS
 | OF
 | OFF
@@ -6868,6 +7335,7 @@ unreserved_keyword:
 | PRIORITY
 | QUERIES
 | QUERY
+| QUOTE
 | RANGE
 | READ
 | RECURSIVE
@@ -6893,6 +7361,7 @@ unreserved_keyword:
 | STATUS
 | SAVEPOINT
 | SCATTER
+| SCROLL
 | SCRUB
 | SEARCH
 | SECOND
@@ -6908,6 +7377,7 @@ unreserved_keyword:
 | SQL
 | START
 | STDIN
+| STDOUT
 | STORE
 | STORING
 | STRICT
@@ -6924,10 +7394,13 @@ unreserved_keyword:
 | TRACE
 | TRANSACTION
 | TRUNCATE
+| TSQUERY
+| TSVECTOR
 | TYPE
 | UNBOUNDED
 | UNCOMMITTED
 | UNKNOWN
+| UNLISTEN
 | UPDATE
 | UPSERT
 | USE
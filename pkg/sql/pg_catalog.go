@@ -20,8 +20,10 @@ import (
 	"hash"
 	"hash/fnv"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 	"unicode"
 
 	"github.com/lib/pq/oid"
@@ -59,6 +61,7 @@ var pgCatalog = virtualSchema{
 		pgCatalogClassTable,
 		pgCatalogCollationTable,
 		pgCatalogConstraintTable,
+		pgCatalogCursorsTable,
 		pgCatalogDatabaseTable,
 		pgCatalogDependTable,
 		pgCatalogDescriptionTable,
@@ -579,6 +582,44 @@ func colIDArrayToVector(arr []sqlbase.ColumnID) (tree.Datum, error) {
 	return tree.NewDIntVectorFromDArray(tree.MustBeDArray(dArr)), nil
 }
 
+// See https://www.postgresql.org/docs/9.6/static/view-pg-cursors.html.
+var pgCatalogCursorsTable = virtualSchemaTable{
+	schema: `
+CREATE TABLE pg_catalog.pg_cursors (
+	name STRING,
+	statement STRING,
+	is_holdable BOOL,
+	is_binary BOOL,
+	is_scrollable BOOL,
+	creation_time TIMESTAMPTZ
+);
+`,
+	populate: func(ctx context.Context, p *planner, _ string, addRow func(...tree.Datum) error) error {
+		names := make([]string, 0, len(p.session.cursors))
+		for name := range p.session.cursors {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			c, ok := p.session.getCursor(ctx, p.txn, name)
+			if !ok {
+				continue
+			}
+			if err := addRow(
+				tree.NewDString(c.name),                                 // name
+				tree.NewDString(c.statement),                            // statement
+				tree.MakeDBool(tree.DBool(c.hold)),                      // is_holdable
+				tree.DBoolFalse,                                         // is_binary
+				tree.DBoolFalse,                                         // is_scrollable
+				tree.MakeDTimestampTZ(c.creationTime, time.Microsecond), // creation_time
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
 // See https://www.postgresql.org/docs/9.6/static/catalog-pg-database.html.
 var pgCatalogDatabaseTable = virtualSchemaTable{
 	schema: `
@@ -1557,6 +1598,8 @@ var datumToTypeCategory = map[reflect.Type]*tree.DString{
 	reflect.TypeOf(types.Int):         typCategoryNumeric,
 	reflect.TypeOf(types.Interval):    typCategoryTimespan,
 	reflect.TypeOf(types.JSON):        typCategoryUserDefined,
+	reflect.TypeOf(types.TSVector):    typCategoryUserDefined,
+	reflect.TypeOf(types.TSQuery):     typCategoryUserDefined,
 	reflect.TypeOf(types.Decimal):     typCategoryNumeric,
 	reflect.TypeOf(types.String):      typCategoryString,
 	reflect.TypeOf(types.Timestamp):   typCategoryDateTime,
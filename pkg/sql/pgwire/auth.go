@@ -0,0 +1,217 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package pgwire
+
+import (
+	"crypto/rand"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/cockroachdb/cockroach/pkg/security"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+)
+
+// The password authentication methods, named like in the pg_hba.conf file of
+// Postgres.
+const (
+	authMethodSCRAM    = "scram-sha-256"
+	authMethodMD5      = "md5"
+	authMethodPassword = "password"
+)
+
+// passwordAuthMethods are the methods accepted for password authentication.
+// The methods are read when the connection is established.
+var passwordAuthMethods = settings.RegisterValidatedStringSetting(
+	"server.password_authentication.methods",
+	"comma-separated list of the methods accepted to authenticate clients with a "+
+		"password, among scram-sha-256, md5 and password (cleartext); clients use the "+
+		"strongest of them for which the user has credentials",
+	strings.Join([]string{authMethodSCRAM, authMethodMD5, authMethodPassword}, ","),
+	func(s string) error {
+		_, err := parsePasswordAuthMethods(s)
+		return err
+	},
+)
+
+// parsePasswordAuthMethods parses the value of the
+// server.password_authentication.methods setting into the set of accepted
+// methods.
+func parsePasswordAuthMethods(s string) (map[string]bool, error) {
+	methods := make(map[string]bool)
+	for _, m := range strings.Split(s, ",") {
+		m = strings.ToLower(strings.TrimSpace(m))
+		switch m {
+		case authMethodSCRAM, authMethodMD5, authMethodPassword:
+			methods[m] = true
+		case "":
+		default:
+			return nil, errors.Errorf("unknown password authentication method %q", m)
+		}
+	}
+	return methods, nil
+}
+
+// handlePasswordAuthentication authenticates the client with a password,
+// using the strongest accepted method for which the user has credentials. The
+// returned error must be sent to the client and the connection closed.
+func (c *v3Conn) handlePasswordAuthentication(insecure bool, creds sql.UserCredentials) error {
+	methods, err := parsePasswordAuthMethods(c.passwordAuthMethods)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case insecure:
+		// Insecure servers accept any password, like the authentication hooks
+		// do. A SCRAM exchange can't succeed with a wrong password, since the
+		// client checks the signature of the server against its own password,
+		// so the password is requested in cleartext.
+		password, err := c.sendAuthPasswordRequest()
+		if err != nil {
+			return err
+		}
+		return security.UserAuthPasswordHook(
+			insecure, password, creds.HashedPassword,
+		)(c.sessionArgs.User, true /* public */)
+
+	case methods[authMethodSCRAM] && creds.ScramCredentials != "":
+		return c.handleAuthSCRAM(creds.ScramCredentials)
+
+	case methods[authMethodMD5] && creds.MD5Password != "":
+		var salt [4]byte
+		if _, err := rand.Read(salt[:]); err != nil {
+			return err
+		}
+		response, err := c.sendAuthMD5Request(salt[:])
+		if err != nil {
+			return err
+		}
+		return security.UserAuthMD5Hook(
+			insecure, response, salt[:], creds.MD5Password,
+		)(c.sessionArgs.User, true /* public */)
+
+	case methods[authMethodPassword]:
+		password, err := c.sendAuthPasswordRequest()
+		if err != nil {
+			return err
+		}
+		return security.UserAuthPasswordHook(
+			insecure, password, creds.HashedPassword,
+		)(c.sessionArgs.User, true /* public */)
+	}
+
+	return errors.Errorf(
+		"user %s has no credentials for the accepted password authentication methods (%s)",
+		c.sessionArgs.User, c.passwordAuthMethods,
+	)
+}
+
+// sendAuthMD5Request requests the MD5 hash of the password, salted with the
+// given salt, from the client and returns it.
+func (c *v3Conn) sendAuthMD5Request(salt []byte) (string, error) {
+	c.writeBuf.initMsg(serverMsgAuth)
+	c.writeBuf.putInt32(authMD5Password)
+	c.writeBuf.write(salt)
+	if err := c.readAuthResponse(); err != nil {
+		return "", err
+	}
+	return c.readBuf.getString()
+}
+
+// handleAuthSCRAM runs a SCRAM-SHA-256 exchange, through which the client
+// proves that it knows the password with the given credentials.
+// See: https://www.postgresql.org/docs/current/static/sasl-authentication.html
+func (c *v3Conn) handleAuthSCRAM(encodedCreds string) error {
+	creds, err := security.ParseScramCredentials(encodedCreds)
+	if err != nil {
+		return err
+	}
+	nonce, err := security.MakeScramNonce()
+	if err != nil {
+		return err
+	}
+	server := security.NewScramServer(creds, nonce)
+
+	// Offer the mechanisms we support, and read the initial response of the
+	// client, which starts with the chosen mechanism.
+	c.writeBuf.initMsg(serverMsgAuth)
+	c.writeBuf.putInt32(authSASL)
+	c.writeBuf.writeTerminatedString(security.ScramMechanism)
+	c.writeBuf.nullTerminate()
+	if err := c.readAuthResponse(); err != nil {
+		return err
+	}
+	mechanism, err := c.readBuf.getString()
+	if err != nil {
+		return err
+	}
+	if mechanism != security.ScramMechanism {
+		return errors.Errorf("unsupported SASL mechanism %q", mechanism)
+	}
+	n, err := c.readBuf.getUint32()
+	if err != nil {
+		return err
+	}
+	if int32(n) < 0 {
+		return errors.New("missing SCRAM client-first-message")
+	}
+	clientFirst, err := c.readBuf.getBytes(int(n))
+	if err != nil {
+		return err
+	}
+
+	serverFirst, err := server.ServerFirst(clientFirst)
+	if err != nil {
+		return err
+	}
+	c.writeBuf.initMsg(serverMsgAuth)
+	c.writeBuf.putInt32(authSASLContinue)
+	c.writeBuf.write(serverFirst)
+	if err := c.readAuthResponse(); err != nil {
+		return err
+	}
+
+	serverFinal, err := server.ServerFinal(c.readBuf.msg)
+	if err != nil {
+		return err
+	}
+	c.writeBuf.initMsg(serverMsgAuth)
+	c.writeBuf.putInt32(authSASLFinal)
+	c.writeBuf.write(serverFinal)
+	return c.writeBuf.finishMsg(c.wr)
+}
+
+// readAuthResponse sends the authentication request prepared in c.writeBuf
+// and reads the response of the client into c.readBuf.
+func (c *v3Conn) readAuthResponse() error {
+	if err := c.writeBuf.finishMsg(c.wr); err != nil {
+		return err
+	}
+	if err := c.wr.Flush(); err != nil {
+		return err
+	}
+
+	typ, n, err := c.readBuf.readTypedMsg(c.rd)
+	c.metrics.BytesInCount.Inc(int64(n))
+	if err != nil {
+		return err
+	}
+	if typ != clientMsgPassword {
+		return errors.Errorf("invalid response to authentication request: %s", typ)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package pgwire
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/cockroachdb/cockroach/pkg/security"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+)
+
+// maxConnections is the maximum number of connections to a node. The
+// connections of the root user are not limited nor counted, so that an
+// administrator can always connect.
+var maxConnections = settings.RegisterValidatedIntSetting(
+	"server.max_connections",
+	"maximum number of SQL connections to each node, not counting those of the "+
+		"root user (0 = no limit)",
+	0,
+	func(v int64) error {
+		if v < 0 {
+			return errors.Errorf("cannot set server.max_connections to a negative value: %d", v)
+		}
+		return nil
+	},
+)
+
+// admitConn checks that a new connection of the given authenticated user
+// doesn't exceed the connection limit of the node nor userLimit, the one set by
+// ALTER USER ... CONNECTION LIMIT (-1 if there is none), in which case it
+// returns a too_many_connections error. Like in Postgres, the limits are
+// enforced for each node. The connection is counted against the limits until
+// the returned function is called.
+func (s *Server) admitConn(user string, userLimit int64) (func(), error) {
+	if user == security.RootUser {
+		return func() {}, nil
+	}
+	nodeLimit := maxConnections.Get(&s.st.SV)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if nodeLimit > 0 && int64(s.mu.limitedConns) >= nodeLimit {
+		s.metrics.ConnsRejected.Inc(1)
+		return nil, pgerror.NewErrorf(pgerror.CodeTooManyConnectionsError,
+			"sorry, too many clients already (server.max_connections = %d)", nodeLimit)
+	}
+	if userLimit >= 0 && int64(s.mu.userConns[user]) >= userLimit {
+		s.metrics.ConnsRejected.Inc(1)
+		return nil, pgerror.NewErrorf(pgerror.CodeTooManyConnectionsError,
+			"too many connections for user %s (connection limit %d)", user, userLimit)
+	}
+	s.mu.limitedConns++
+	s.mu.userConns[user]++
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.mu.limitedConns--
+		if s.mu.userConns[user]--; s.mu.userConns[user] == 0 {
+			delete(s.mu.userConns, user)
+		}
+	}, nil
+}
@@ -0,0 +1,201 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package pgwire
+
+import (
+	"io"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/types"
+)
+
+func init() {
+	// The fields of binary COPY data use the binary encoding of the wire
+	// protocol.
+	sql.DecodeCopyBinaryField = func(typ types.T, b []byte) (tree.Datum, error) {
+		return decodeOidDatum(pgTypeForParserType(typ).oid, formatBinary, b)
+	}
+}
+
+// copyFormatCode returns the format code announced to the client for the
+// columns of COPY data in the given format.
+func copyFormatCode(f sql.CopyFormat) formatCode {
+	if f.Format == tree.CopyFormatBinary {
+		return formatBinary
+	}
+	return formatText
+}
+
+// beginCopyOut begins the COPY OUT data flow of a COPY ... TO STDOUT
+// statement by sending the number of columns and their format to the
+// client, followed by the header of the data, if any.
+// See: https://www.postgresql.org/docs/current/static/protocol-flow.html#PROTOCOL-COPY
+func (c *v3Conn) beginCopyOut(w io.Writer) error {
+	state := &c.streamingState
+	code := copyFormatCode(state.copyFormat)
+	c.writeBuf.initMsg(serverMsgCopyOutResponse)
+	c.writeBuf.writeByte(byte(code))
+	c.writeBuf.putInt16(int16(len(state.columns)))
+	for range state.columns {
+		c.writeBuf.putInt16(int16(code))
+	}
+	if err := c.writeBuf.finishMsg(w); err != nil {
+		return err
+	}
+
+	switch f := state.copyFormat; {
+	case f.Format == tree.CopyFormatBinary:
+		c.writeBuf.initMsg(serverMsgCopyData)
+		c.writeBuf.writeString(sql.CopyBinarySignature)
+		c.writeBuf.putInt32(0) // Flags.
+		c.writeBuf.putInt32(0) // Length of the header extension area.
+		return c.writeBuf.finishMsg(w)
+
+	case f.Format == tree.CopyFormatCSV && f.Header:
+		c.writeBuf.initMsg(serverMsgCopyData)
+		for i, col := range state.columns {
+			if i > 0 {
+				c.writeBuf.writeByte(f.Delimiter)
+			}
+			writeCopyCSVValue(&c.writeBuf, []byte(col.Name), f)
+		}
+		c.writeBuf.writeByte('\n')
+		return c.writeBuf.finishMsg(w)
+	}
+	return nil
+}
+
+// sendCopyData sends a row of the result of a COPY ... TO STDOUT statement
+// as a CopyData message.
+func (c *v3Conn) sendCopyData(ctx context.Context, row tree.Datums, w io.Writer) error {
+	f := c.streamingState.copyFormat
+	c.writeBuf.initMsg(serverMsgCopyData)
+	if f.Format == tree.CopyFormatBinary {
+		// Like in a DataRow message, the values are prefixed by their length.
+		c.writeBuf.putInt16(int16(len(row)))
+		for _, d := range row {
+			c.writeBuf.writeBinaryDatum(ctx, d, c.session.Location)
+		}
+		return c.writeBuf.finishMsg(w)
+	}
+
+	for i, d := range row {
+		if i > 0 {
+			c.writeBuf.writeByte(f.Delimiter)
+		}
+		if d == tree.DNull {
+			c.writeBuf.writeString(f.Null)
+			continue
+		}
+		// Encode the value in copyBuf and strip its length prefix.
+		c.copyBuf.reset()
+		c.copyBuf.writeTextDatum(ctx, d, c.session.Location)
+		if c.copyBuf.err != nil {
+			c.writeBuf.setError(c.copyBuf.err)
+			break
+		}
+		s := c.copyBuf.wrapped.Bytes()[4:]
+		if f.Format == tree.CopyFormatCSV {
+			writeCopyCSVValue(&c.writeBuf, s, f)
+		} else {
+			writeCopyTextValue(&c.writeBuf, s, f.Delimiter)
+		}
+	}
+	c.writeBuf.writeByte('\n')
+	return c.writeBuf.finishMsg(w)
+}
+
+// finishCopyOut ends the COPY OUT data flow, after the trailer of the data
+// if any.
+func (c *v3Conn) finishCopyOut(w io.Writer) error {
+	if c.streamingState.copyFormat.Format == tree.CopyFormatBinary {
+		c.writeBuf.initMsg(serverMsgCopyData)
+		c.writeBuf.putInt16(-1)
+		if err := c.writeBuf.finishMsg(w); err != nil {
+			return err
+		}
+	}
+	c.writeBuf.initMsg(serverMsgCopyDone)
+	return c.writeBuf.finishMsg(w)
+}
+
+// writeCopyTextValue writes a value in the text format of COPY, escaping the
+// backslashes, the control characters and the delimiter.
+// See: https://www.postgresql.org/docs/current/static/sql-copy.html
+func writeCopyTextValue(b *writeBuffer, s []byte, delim byte) {
+	start := 0
+	for i, ch := range s {
+		var esc byte
+		switch ch {
+		case '\\':
+			esc = '\\'
+		case '\b':
+			esc = 'b'
+		case '\f':
+			esc = 'f'
+		case '\n':
+			esc = 'n'
+		case '\r':
+			esc = 'r'
+		case '\t':
+			esc = 't'
+		case '\v':
+			esc = 'v'
+		case delim:
+			esc = delim
+		default:
+			continue
+		}
+		b.write(s[start:i])
+		b.writeByte('\\')
+		b.writeByte(esc)
+		start = i + 1
+	}
+	b.write(s[start:])
+}
+
+// writeCopyCSVValue writes a value in the CSV format of COPY. The value is
+// quoted, with its quote characters doubled, if it contains the delimiter,
+// the quote character or a line break, or if it could be mistaken for NULL
+// or for the end-of-data marker.
+// See: https://www.postgresql.org/docs/current/static/sql-copy.html
+func writeCopyCSVValue(b *writeBuffer, s []byte, f sql.CopyFormat) {
+	needsQuotes := string(s) == f.Null || string(s) == `\.`
+	for _, ch := range s {
+		if ch == f.Delimiter || ch == f.Quote || ch == '\n' || ch == '\r' {
+			needsQuotes = true
+			break
+		}
+	}
+	if !needsQuotes {
+		b.write(s)
+		return
+	}
+
+	b.writeByte(f.Quote)
+	start := 0
+	for i, ch := range s {
+		if ch == f.Quote {
+			b.write(s[start : i+1])
+			b.writeByte(f.Quote)
+			start = i + 1
+		}
+	}
+	b.write(s[start:])
+	b.writeByte(f.Quote)
+}
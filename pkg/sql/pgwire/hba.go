@@ -0,0 +1,268 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package pgwire
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+)
+
+// The connection types of the host-based authentication entries. Since
+// clients of secure servers must use SSL, hostnossl entries only match the
+// connections of insecure servers. Insecure servers don't authenticate
+// clients, so their connections are only subject to the address, user and
+// database of the entries, and to the reject and cert methods: the latter
+// can't be satisfied without SSL.
+const (
+	hbaConnHost      = "host"
+	hbaConnHostSSL   = "hostssl"
+	hbaConnHostNoSSL = "hostnossl"
+)
+
+// The authentication methods of the host-based authentication entries.
+const (
+	// hbaMethodCert requires a client certificate.
+	hbaMethodCert = "cert"
+	// hbaMethodPassword requires a password, with one of the methods of
+	// server.password_authentication.methods.
+	hbaMethodPassword = "password"
+	// hbaMethodCertPassword requires a client certificate if the client
+	// presents one, and a password otherwise.
+	hbaMethodCertPassword = "cert-password"
+	// hbaMethodReject rejects the connection.
+	hbaMethodReject = "reject"
+)
+
+// hbaAll is the keyword which matches any database, user or address.
+const hbaAll = "all"
+
+// hbaConfSetting is the host-based authentication configuration, in the
+// format of the pg_hba.conf file of Postgres. The configuration is read
+// when the connection is established.
+var hbaConfSetting = settings.RegisterValidatedStringSetting(
+	"server.host_based_authentication.configuration",
+	"host-based authentication rules, one per line, in the format of pg_hba.conf: "+
+		"<host|hostssl|hostnossl> <databases> <users> <all|CIDR> <cert|password|cert-password|reject>; "+
+		"the first matching rule applies and connections matching no rule are rejected; "+
+		"if empty, clients authenticate with a certificate if they present one and with a password otherwise",
+	"",
+	func(s string) error {
+		_, err := parseHBAConf(s)
+		return err
+	},
+)
+
+// hbaConf is a parsed host-based authentication configuration.
+type hbaConf struct {
+	entries []hbaEntry
+}
+
+// hbaEntry is a rule of a host-based authentication configuration. The nil
+// values of databases, users and address match anything.
+type hbaEntry struct {
+	// line is the line number of the entry in the configuration.
+	line      int
+	connType  string
+	databases []string
+	users     []string
+	address   *net.IPNet
+	method    string
+}
+
+// defaultHBAConf is the configuration used when the setting is empty.
+var defaultHBAConf = &hbaConf{
+	entries: []hbaEntry{{connType: hbaConnHost, method: hbaMethodCertPassword}},
+}
+
+// parseHBAConf parses a host-based authentication configuration. Each
+// non-empty line is an entry made of whitespace-separated fields, and the
+// '#' character starts a comment.
+func parseHBAConf(s string) (*hbaConf, error) {
+	if strings.TrimSpace(s) == "" {
+		return defaultHBAConf, nil
+	}
+	conf := &hbaConf{}
+	for i, line := range strings.Split(s, "\n") {
+		if j := strings.IndexByte(line, '#'); j >= 0 {
+			line = line[:j]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		entry, err := parseHBAEntry(fields)
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", i+1)
+		}
+		entry.line = i + 1
+		conf.entries = append(conf.entries, entry)
+	}
+	if len(conf.entries) == 0 {
+		// This would reject all the connections.
+		return nil, errors.New("no rules; use an empty configuration for the default rules")
+	}
+	return conf, nil
+}
+
+func parseHBAEntry(fields []string) (hbaEntry, error) {
+	var entry hbaEntry
+	switch fields[0] {
+	case hbaConnHost, hbaConnHostSSL, hbaConnHostNoSSL:
+		entry.connType = fields[0]
+	case "local":
+		return entry, errors.New("local connections are not supported")
+	default:
+		return entry, errors.Errorf("unknown connection type %q", fields[0])
+	}
+	if len(fields) != 5 {
+		return entry, errors.Errorf(
+			"expected 5 fields (type, databases, users, address, method), got %d", len(fields))
+	}
+	entry.databases = parseHBANames(fields[1])
+	entry.users = parseHBANames(fields[2])
+
+	if fields[3] != hbaAll {
+		_, ipNet, err := net.ParseCIDR(fields[3])
+		if err != nil {
+			return entry, errors.Errorf("invalid address %q: expected \"all\" or a CIDR", fields[3])
+		}
+		entry.address = ipNet
+	}
+
+	switch fields[4] {
+	case hbaMethodCert, hbaMethodPassword, hbaMethodCertPassword, hbaMethodReject:
+		entry.method = fields[4]
+	default:
+		return entry, errors.Errorf("unknown authentication method %q", fields[4])
+	}
+	return entry, nil
+}
+
+// parseHBANames parses a comma-separated list of database or user names,
+// which are normalized like SQL identifiers.
+func parseHBANames(s string) []string {
+	if s == hbaAll {
+		return nil
+	}
+	names := strings.Split(s, ",")
+	for i := range names {
+		names[i] = tree.Name(names[i]).Normalize()
+	}
+	return names
+}
+
+// findEntry returns the first entry which matches a connection, or nil if
+// there is none. The IP address of the client is nil if it isn't known, in
+// which case it only matches the entries for all addresses.
+func (c *hbaConf) findEntry(ip net.IP, ssl bool, user, database string) *hbaEntry {
+	user = tree.Name(user).Normalize()
+	database = tree.Name(database).Normalize()
+	for i := range c.entries {
+		e := &c.entries[i]
+		if (e.connType == hbaConnHostSSL && !ssl) || (e.connType == hbaConnHostNoSSL && ssl) {
+			continue
+		}
+		if !hbaNamesMatch(e.databases, database) || !hbaNamesMatch(e.users, user) {
+			continue
+		}
+		if e.address != nil && (ip == nil || !e.address.Contains(ip)) {
+			continue
+		}
+		return e
+	}
+	return nil
+}
+
+func hbaNamesMatch(names []string, name string) bool {
+	if names == nil {
+		return true
+	}
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// checkHBA evaluates the host-based authentication rules for the connection
+// and returns the matching entry. If the rules reject the connection, the
+// rejection is logged and an error is returned.
+func (c *v3Conn) checkHBA(ctx context.Context, ssl bool) (*hbaEntry, error) {
+	conf, err := parseHBAConf(c.hbaConf)
+	if err != nil {
+		return nil, err
+	}
+	host := c.remoteHost()
+	entry := conf.findEntry(net.ParseIP(host), ssl, c.sessionArgs.User, c.sessionArgs.Database)
+	switch {
+	case entry == nil:
+		return nil, c.rejectConnection(ctx, "no matching host-based authentication rule")
+	case entry.method == hbaMethodReject:
+		return nil, c.rejectConnection(ctx, fmt.Sprintf(
+			"rejected by the host-based authentication rule at line %d", entry.line))
+	}
+	return entry, nil
+}
+
+// rejectConnection logs that the connection was rejected for the given
+// reason, counts it in the sql.conns.denied metric, records it in the event
+// log and returns the error to send to the client. The connection isn't
+// authenticated yet, so the event log entries are throttled: a client could
+// otherwise make the node write to the event log at the rate at which it
+// opens connections. Each entry carries the number of connections rejected
+// since the node started, which accounts for the rejections that weren't
+// recorded.
+func (c *v3Conn) rejectConnection(ctx context.Context, reason string) error {
+	c.metrics.ConnsDenied.Inc(1)
+	log.Infof(ctx, "connection of user %s to database %q from %s rejected: %s",
+		c.sessionArgs.User, c.sessionArgs.Database, c.conn.RemoteAddr(), reason)
+	if c.connDeniedEvents != nil && c.connDeniedEvents.Allow() {
+		if err := c.executor.LogEvent(ctx, sql.EventLogConnectionDenied, 0, struct {
+			User              string
+			Database          string
+			RemoteAddr        string
+			Reason            string
+			DeniedConnections int64
+		}{
+			c.sessionArgs.User, c.sessionArgs.Database, c.conn.RemoteAddr().String(), reason,
+			c.metrics.ConnsDenied.Count(),
+		}); err != nil {
+			log.Warningf(ctx, "unable to record the rejection of a connection: %v", err)
+		}
+	}
+	return pgerror.NewErrorf(pgerror.CodeInvalidAuthorizationSpecificationError,
+		"connection rejected for host %q, user %q, database %q: %s",
+		c.remoteHost(), c.sessionArgs.User, c.sessionArgs.Database, reason)
+}
+
+// remoteHost returns the host of the client.
+func (c *v3Conn) remoteHost() string {
+	addr := c.conn.RemoteAddr().String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
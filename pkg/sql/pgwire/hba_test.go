@@ -0,0 +1,100 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package pgwire
+
+import (
+	"net"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func TestParseHBAConf(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	testCases := []struct {
+		conf     string
+		expected string
+	}{
+		{"", ""},
+		{"# only a comment\n\n", "no rules"},
+		{"host all all all cert", ""},
+		{"hostssl db1,DB2 root 10.0.0.0/8 password # comment", ""},
+		{"hostnossl all all ::1/128 reject", ""},
+		{"local all all trust", "line 1: local connections are not supported"},
+		{"hosts all all all cert", `line 1: unknown connection type "hosts"`},
+		{"\nhost all all cert", "line 2: expected 5 fields"},
+		{"host all all all cert extra", "line 1: expected 5 fields"},
+		{"host all all 10.0.0.1 cert", `line 1: invalid address "10.0.0.1"`},
+		{"host all all all md5", `line 1: unknown authentication method "md5"`},
+	}
+	for _, tc := range testCases {
+		_, err := parseHBAConf(tc.conf)
+		if !testutils.IsError(err, tc.expected) {
+			t.Errorf("%q: expected error %q, got %v", tc.conf, tc.expected, err)
+		}
+	}
+}
+
+func TestHBAFindEntry(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	conf, err := parseHBAConf(`
+# Root must use certificates.
+host      all     root     all           cert
+host      system  all      all           reject
+host      all     foo,Bar  10.0.0.0/8    password
+hostnossl all     all      all           password
+host      all     all      192.168.0.0/16 cert-password
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		ip       string
+		ssl      bool
+		user     string
+		database string
+		line     int
+	}{
+		{"1.2.3.4", true, "root", "", 3},
+		{"1.2.3.4", true, "ROOT", "system", 3},
+		{"10.1.2.3", true, "foo", "system", 4},
+		{"10.1.2.3", true, "foo", "d", 5},
+		{"10.1.2.3", true, "bar", "d", 5},
+		{"11.1.2.3", true, "foo", "d", 0},
+		{"11.1.2.3", false, "foo", "d", 6},
+		{"192.168.1.1", true, "foo", "d", 7},
+		{"", true, "foo", "d", 0},
+	}
+	for _, tc := range testCases {
+		entry := conf.findEntry(net.ParseIP(tc.ip), tc.ssl, tc.user, tc.database)
+		line := 0
+		if entry != nil {
+			line = entry.line
+		}
+		if line != tc.line {
+			t.Errorf("%+v: expected line %d, got %d", tc, tc.line, line)
+		}
+	}
+
+	// The default configuration matches all the connections.
+	if entry := defaultHBAConf.findEntry(nil, true, "foo", ""); entry == nil ||
+		entry.method != hbaMethodCertPassword {
+		t.Errorf("unexpected default entry %+v", entry)
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package pgwire
+
+import (
+	"github.com/cockroachdb/cockroach/pkg/sql"
+)
+
+// sendNotifications sends the notifications queued for the session to the
+// client, with NotificationResponse messages.
+func (c *v3Conn) sendNotifications() error {
+	notifications := c.session.TakeNotifications()
+	if len(notifications) == 0 {
+		return nil
+	}
+	for _, n := range notifications {
+		c.writeBuf.initMsg(serverMsgNotificationResponse)
+		c.writeBuf.putInt32(n.PID)
+		c.writeBuf.writeTerminatedString(n.Channel)
+		c.writeBuf.writeTerminatedString(n.Payload)
+		if err := c.writeBuf.finishMsg(c.wr); err != nil {
+			return err
+		}
+	}
+	return c.wr.Flush()
+}
+
+// readResult is the result of the reading of a message of the client.
+type readResult struct {
+	typ clientMessageType
+	n   int
+	err error
+}
+
+// msgReader is a goroutine which reads the messages of the client on behalf
+// of the goroutine serving the connection, one message per request. The
+// serving goroutine doesn't use the read buffer while a request is pending,
+// and the reader doesn't use it between requests.
+type msgReader struct {
+	requests chan struct{}
+	results  chan readResult
+}
+
+func (c *v3Conn) startReader() *msgReader {
+	r := &msgReader{
+		requests: make(chan struct{}),
+		// The result of the last request is buffered, so that the reader can
+		// exit when the connection is closed while a request is pending.
+		results: make(chan readResult, 1),
+	}
+	go func() {
+		for range r.requests {
+			typ, n, err := c.readBuf.readTypedMsg(c.rd)
+			r.results <- readResult{typ: typ, n: n, err: err}
+		}
+	}()
+	return r
+}
+
+// stop makes the reader exit once its pending request, if any, is done. It
+// is called when the connection is closed, which ends a pending read.
+func (r *msgReader) stop() {
+	close(r.requests)
+}
+
+// readTypedMsgOrNotify reads the next message of the client. Like in
+// Postgres, the notifications received while the session is idle are sent to
+// the client as they arrive: the message is then read by the reader of the
+// connection, while this goroutine sends the notifications.
+func (c *v3Conn) readTypedMsgOrNotify() (clientMessageType, int, error) {
+	if c.doingExtendedQueryMessage || c.session.TxnState.State() != sql.NoTxn ||
+		!c.session.IsListening() {
+		return c.readBuf.readTypedMsg(c.rd)
+	}
+
+	if c.reader == nil {
+		c.reader = c.startReader()
+	}
+	c.reader.requests <- struct{}{}
+	for {
+		select {
+		case res := <-c.reader.results:
+			return res.typ, res.n, res.err
+		case <-c.session.NotificationsReady():
+			if err := c.sendNotifications(); err != nil {
+				return 0, 0, err
+			}
+		}
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package pgerror
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity is the severity level of a message sent to the client. The levels
+// are ordered like the ones used by the client_min_messages variable of
+// Postgres.
+type Severity int
+
+const (
+	// SeverityDebug is used for messages intended for developers.
+	SeverityDebug Severity = iota
+	// SeverityLog is used for messages intended for administrators.
+	SeverityLog
+	// SeverityInfo is used for messages implicitly requested by the user,
+	// which are always sent regardless of the client_min_messages variable.
+	SeverityInfo
+	// SeverityNotice is used for messages which might be helpful to the user.
+	SeverityNotice
+	// SeverityWarning is used for messages warning the user of likely
+	// problems, e.g. the use of deprecated features.
+	SeverityWarning
+	// SeverityError is the severity of errors. Notices never have it, but
+	// client_min_messages can be set to it to only receive the INFO messages.
+	SeverityError
+)
+
+var severityNames = [...]string{
+	SeverityDebug:   "DEBUG",
+	SeverityLog:     "LOG",
+	SeverityInfo:    "INFO",
+	SeverityNotice:  "NOTICE",
+	SeverityWarning: "WARNING",
+	SeverityError:   "ERROR",
+}
+
+func (s Severity) String() string {
+	if s < 0 || int(s) >= len(severityNames) {
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+	return severityNames[s]
+}
+
+// ParseSeverity parses the name of a severity level, case-insensitively.
+// Like in Postgres, the DEBUG1 to DEBUG5 levels are accepted as synonyms of
+// DEBUG.
+func ParseSeverity(s string) (Severity, bool) {
+	s = strings.ToUpper(s)
+	switch s {
+	case "DEBUG1", "DEBUG2", "DEBUG3", "DEBUG4", "DEBUG5":
+		return SeverityDebug, true
+	}
+	for i, name := range severityNames {
+		if s == name {
+			return Severity(i), true
+		}
+	}
+	return 0, false
+}
+
+// Notice is a message sent to the client which, unlike an Error, doesn't
+// interrupt the statement which raised it.
+type Notice struct {
+	Severity Severity
+	Code     string
+	Message  string
+	Detail   string
+	Hint     string
+}
+
+// NewNoticef creates a Notice with a format string. Like in Postgres, the
+// code of the notice is CodeWarningError for warnings and
+// CodeSuccessfulCompletionError otherwise.
+func NewNoticef(severity Severity, format string, args ...interface{}) *Notice {
+	code := CodeSuccessfulCompletionError
+	if severity >= SeverityWarning {
+		code = CodeWarningError
+	}
+	return &Notice{
+		Severity: severity,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+	}
+}
+
+// SetHintf annotates a Notice object with a hint.
+func (n *Notice) SetHintf(f string, args ...interface{}) *Notice {
+	n.Hint = fmt.Sprintf(f, args...)
+	return n
+}
+
+// SetDetailf annotates a Notice object with details.
+func (n *Notice) SetDetailf(f string, args ...interface{}) *Notice {
+	n.Detail = fmt.Sprintf(f, args...)
+	return n
+}
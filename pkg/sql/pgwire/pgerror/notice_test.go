@@ -0,0 +1,51 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package pgerror
+
+import "testing"
+
+func TestParseSeverity(t *testing.T) {
+	testCases := []struct {
+		s        string
+		severity Severity
+		ok       bool
+	}{
+		{"debug", SeverityDebug, true},
+		{"DEBUG3", SeverityDebug, true},
+		{"log", SeverityLog, true},
+		{"Info", SeverityInfo, true},
+		{"notice", SeverityNotice, true},
+		{"warning", SeverityWarning, true},
+		{"error", SeverityError, true},
+		{"debug6", 0, false},
+		{"", 0, false},
+	}
+	for _, tc := range testCases {
+		severity, ok := ParseSeverity(tc.s)
+		if severity != tc.severity || ok != tc.ok {
+			t.Errorf("%q: expected %s, %t, got %s, %t", tc.s, tc.severity, tc.ok, severity, ok)
+		}
+	}
+}
+
+func TestNoticeCode(t *testing.T) {
+	if n := NewNoticef(SeverityNotice, "n%d", 1); n.Code != CodeSuccessfulCompletionError ||
+		n.Message != "n1" {
+		t.Errorf("unexpected notice %+v", n)
+	}
+	if n := NewNoticef(SeverityWarning, "w"); n.Code != CodeWarningError {
+		t.Errorf("unexpected notice %+v", n)
+	}
+}
@@ -39,6 +39,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/server"
 	"github.com/cockroachdb/cockroach/pkg/server/serverpb"
 	"github.com/cockroachdb/cockroach/pkg/sql/pgwire"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	"github.com/cockroachdb/cockroach/pkg/testutils"
 	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
 	"github.com/cockroachdb/cockroach/pkg/testutils/sqlutils"
@@ -673,7 +674,10 @@ func TestPGPreparedQuery(t *testing.T) {
 		"SHOW COLUMNS FROM system.users": {
 			baseTest.
 				Results("username", "STRING", false, gosql.NullBool{}, "{\"primary\"}").
-				Results("hashedPassword", "BYTES", true, gosql.NullBool{}, "{}"),
+				Results("hashedPassword", "BYTES", true, gosql.NullBool{}, "{}").
+				Results("scramCredentials", "STRING", true, gosql.NullBool{}, "{}").
+				Results("md5Password", "STRING", true, gosql.NullBool{}, "{}").
+				Results("connectionLimit", "INT", true, gosql.NullBool{}, "{}"),
 		},
 		"SHOW DATABASES": {
 			baseTest.Results("crdb_internal").Results("d").Results("information_schema").Results("pg_catalog").Results("system"),
@@ -1790,3 +1794,147 @@ func TestPGWireTooManyArguments(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 }
+
+// TestPGWireResultsBufferSize verifies that the results of a statement that
+// fit in the buffer configured through sql.defaults.results_buffer.size are not
+// sent to the client before the statement finishes, and thus that the
+// statement can be retried automatically.
+func TestPGWireResultsBufferSize(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	s, db, _ := serverutils.StartServer(t, base.TestServerArgs{})
+	defer s.Stopper().Stop(context.TODO())
+
+	if _, err := db.Exec(
+		`SET CLUSTER SETTING sql.defaults.results_buffer.size = '1MiB'`,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	pgURL, cleanupFn := sqlutils.PGUrl(t, s.ServingAddr(), t.Name(), url.User(security.RootUser))
+	defer cleanupFn()
+
+	// The statement produces more results than fit in the default buffer
+	// before running into a retryable error; with the default buffer size, the
+	// error is returned to the client.
+	const query = `
+SELECT generate_series(1, 10000)
+UNION ALL
+SELECT crdb_internal.force_retry('500ms')`
+
+	// The buffer size is read when a connection is established, and the
+	// setting is propagated asynchronously.
+	testutils.SucceedsSoon(t, func() error {
+		conn, err := gosql.Open("postgres", pgURL.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		_, err = conn.Exec(query)
+		return err
+	})
+}
+
+// TestPGWireConnectionLimits verifies that the connections over the
+// connection limit of the node or of their user, except those of root, and
+// the statements over the concurrency limit of their application are rejected
+// with a too_many_connections error.
+func TestPGWireConnectionLimits(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	s, db, _ := serverutils.StartServer(t, base.TestServerArgs{Insecure: true})
+	defer s.Stopper().Stop(context.TODO())
+
+	if _, err := db.Exec(`
+CREATE USER foo;
+CREATE USER bar;
+ALTER USER foo CONNECTION LIMIT 1;
+`); err != nil {
+		t.Fatal(err)
+	}
+
+	connect := func(user string) (driver.Conn, error) {
+		pgURL := url.URL{
+			Scheme:   "postgres",
+			User:     url.User(user),
+			Host:     s.ServingAddr(),
+			RawQuery: "sslmode=disable",
+		}
+		return pq.Open(pgURL.String())
+	}
+	isTooManyConnections := func(err error) bool {
+		pqErr, ok := err.(*pq.Error)
+		return ok && pqErr.Code == pgerror.CodeTooManyConnectionsError
+	}
+	expectRejected := func(n int64) {
+		if rejected := s.MustGetSQLNetworkCounter(pgwire.MetaConnsRejected.Name); rejected != n {
+			t.Fatalf("expected %d rejected connections, got %d", n, rejected)
+		}
+	}
+
+	foo, err := connect("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := connect("foo"); !isTooManyConnections(err) {
+		t.Fatalf("expected a too_many_connections error, got %v", err)
+	}
+	expectRejected(1)
+	bar, err := connect("bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bar.Close()
+
+	// The connections of root don't count against server.max_connections.
+	if _, err := db.Exec(`SET CLUSTER SETTING server.max_connections = 2`); err != nil {
+		t.Fatal(err)
+	}
+	testutils.SucceedsSoon(t, func() error {
+		if conn, err := connect("bar"); !isTooManyConnections(err) {
+			if err == nil {
+				_ = conn.Close()
+			}
+			return errors.Errorf("expected a too_many_connections error, got %v", err)
+		}
+		return nil
+	})
+	root, err := connect(security.RootUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer root.Close()
+
+	// The connections release their slots when they are closed.
+	if err := foo.Close(); err != nil {
+		t.Fatal(err)
+	}
+	testutils.SucceedsSoon(t, func() error {
+		conn, err := connect("foo")
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	})
+
+	// The statements of the applications over their concurrency limit are
+	// rejected.
+	if _, err := db.Exec(
+		`SET CLUSTER SETTING sql.application_concurrency.limits = 'blocked=0'`,
+	); err != nil {
+		t.Fatal(err)
+	}
+	blocked, err := gosql.Open("postgres", fmt.Sprintf(
+		"postgres://%s@%s/?sslmode=disable&application_name=blocked",
+		security.RootUser, s.ServingAddr(),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer blocked.Close()
+	testutils.SucceedsSoon(t, func() error {
+		if _, err := blocked.Exec(`SELECT 1`); !isTooManyConnections(err) {
+			return errors.Errorf("expected a too_many_connections error, got %v", err)
+		}
+		return nil
+	})
+}
@@ -16,13 +16,17 @@ package pgwire
 
 import (
 	"crypto/tls"
+	"fmt"
 	"io"
 	"net"
 	"time"
 
 	"golang.org/x/net/context"
+	"golang.org/x/time/rate"
 
 	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/server/serverpb"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
 	"github.com/cockroachdb/cockroach/pkg/sql"
 	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
@@ -32,6 +36,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/util/metric"
 	"github.com/cockroachdb/cockroach/pkg/util/mon"
 	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
 	"github.com/pkg/errors"
 )
 
@@ -56,11 +61,21 @@ var (
 	MetaBytesOut = metric.Metadata{
 		Name: "sql.bytesout",
 		Help: "Number of sql bytes sent"}
+	MetaConnsRejected = metric.Metadata{
+		Name: "sql.conns.rejected",
+		Help: "Number of sql connections rejected because of connection limits"}
+	MetaConnsDenied = metric.Metadata{
+		Name: "sql.conns.denied",
+		Help: "Number of sql connections rejected by the host-based authentication rules"}
+	MetaCancelsFailed = metric.Metadata{
+		Name: "sql.cancels.failed",
+		Help: "Number of cancel requests whose key matched no session or which were throttled"}
 )
 
 const (
-	version30  = 196608
-	versionSSL = 80877103
+	version30     = 196608
+	versionSSL    = 80877103
+	versionCancel = 80877102
 )
 
 const (
@@ -70,6 +85,22 @@ const (
 	// cancelMaxWait is the amount of time a draining server gives to sessions
 	// to react to cancellation and return before a forceful shutdown.
 	cancelMaxWait = 1 * time.Second
+	// cancelForwardTimeout is the amount of time given to the node owning a
+	// session to process a cancel request forwarded to it.
+	cancelForwardTimeout = 5 * time.Second
+	// cancelFailureRate and cancelFailureBurst bound the rate of the cancel
+	// requests whose key matches no session, which can be attempts to guess
+	// the key of a session. Once they are exceeded, the cancel requests are
+	// dropped without being processed.
+	cancelFailureRate  = 10
+	cancelFailureBurst = 100
+	// cancelFailureLogInterval is the minimum interval between two log
+	// messages about failed cancel requests.
+	cancelFailureLogInterval = 10 * time.Second
+	// connDeniedEventInterval is the minimum interval between two event log
+	// entries about the connections rejected by the host-based
+	// authentication rules.
+	connDeniedEventInterval = 10 * time.Second
 )
 
 // baseSQLMemoryBudget is the amount of memory pre-allocated in each connection.
@@ -93,8 +124,20 @@ type cancelChanMap map[chan struct{}]context.CancelFunc
 type Server struct {
 	AmbientCtx log.AmbientContext
 	cfg        *base.Config
+	st         *cluster.Settings
 	executor   *sql.Executor
 
+	// statusServer is used to route the cancel requests to the node owning
+	// the session they target.
+	statusServer serverpb.StatusServer
+	// cancelFailures throttles the cancel requests whose key matches no
+	// session, and cancelFailureLog the log messages about them.
+	cancelFailures   *rate.Limiter
+	cancelFailureLog *rate.Limiter
+	// connDeniedEvents throttles the event log entries about the connections
+	// rejected by the host-based authentication rules.
+	connDeniedEvents *rate.Limiter
+
 	metrics ServerMetrics
 
 	mu struct {
@@ -105,6 +148,10 @@ type Server struct {
 		// that is closed when the connection is done.
 		connCancelMap cancelChanMap
 		draining      bool
+		// limitedConns is the number of open connections which count against
+		// server.max_connections, and userConns is their number per user.
+		limitedConns int
+		userConns    map[string]int
 	}
 
 	sqlMemoryPool mon.BytesMonitor
@@ -116,6 +163,9 @@ type ServerMetrics struct {
 	BytesInCount   *metric.Counter
 	BytesOutCount  *metric.Counter
 	Conns          *metric.Counter
+	ConnsRejected  *metric.Counter
+	ConnsDenied    *metric.Counter
+	CancelsFailed  *metric.Counter
 	ConnMemMetrics sql.MemoryMetrics
 	SQLMemMetrics  sql.MemoryMetrics
 
@@ -127,6 +177,9 @@ func makeServerMetrics(
 ) ServerMetrics {
 	return ServerMetrics{
 		Conns:              metric.NewCounter(MetaConns),
+		ConnsRejected:      metric.NewCounter(MetaConnsRejected),
+		ConnsDenied:        metric.NewCounter(MetaConnsDenied),
+		CancelsFailed:      metric.NewCounter(MetaCancelsFailed),
 		BytesInCount:       metric.NewCounter(MetaBytesIn),
 		BytesOutCount:      metric.NewCounter(MetaBytesOut),
 		ConnMemMetrics:     sql.MakeMemMetrics("conns", histogramWindow),
@@ -149,16 +202,24 @@ var noteworthyConnMemoryUsageBytes = envutil.EnvOrDefaultInt64("COCKROACH_NOTEWO
 func MakeServer(
 	ambientCtx log.AmbientContext,
 	cfg *base.Config,
+	st *cluster.Settings,
 	executor *sql.Executor,
+	statusServer serverpb.StatusServer,
 	internalMemMetrics *sql.MemoryMetrics,
 	parentMemoryMonitor *mon.BytesMonitor,
 	histogramWindow time.Duration,
 ) *Server {
 	server := &Server{
-		AmbientCtx: ambientCtx,
-		cfg:        cfg,
-		executor:   executor,
-		metrics:    makeServerMetrics(internalMemMetrics, histogramWindow),
+		AmbientCtx:   ambientCtx,
+		cfg:          cfg,
+		st:           st,
+		executor:     executor,
+		statusServer: statusServer,
+		cancelFailures: rate.NewLimiter(
+			rate.Limit(cancelFailureRate), cancelFailureBurst),
+		cancelFailureLog: rate.NewLimiter(rate.Every(cancelFailureLogInterval), 1),
+		connDeniedEvents: rate.NewLimiter(rate.Every(connDeniedEventInterval), 1),
+		metrics:          makeServerMetrics(internalMemMetrics, histogramWindow),
 	}
 	server.sqlMemoryPool = mon.MakeMonitor("sql",
 		mon.MemoryResource,
@@ -176,6 +237,7 @@ func MakeServer(
 
 	server.mu.Lock()
 	server.mu.connCancelMap = make(cancelChanMap)
+	server.mu.userConns = make(map[string]int)
 	server.mu.Unlock()
 
 	return server
@@ -192,7 +254,7 @@ func Match(rd io.Reader) bool {
 	if err != nil {
 		return false
 	}
-	return version == version30 || version == versionSSL
+	return version == version30 || version == versionSSL || version == versionCancel
 }
 
 // IsDraining returns true if the server is not currently accepting
@@ -381,11 +443,18 @@ func (s *Server) ServeConn(ctx context.Context, conn net.Conn) error {
 		errSSLRequired = true
 	}
 
+	if version == versionCancel {
+		// Like in Postgres, cancel requests don't need an encrypted connection:
+		// they are authenticated by the secret key of the session.
+		return s.handleCancel(ctx, conn.RemoteAddr(), &buf)
+	}
+
 	if version == version30 {
 		// We make a connection before anything. If there is an error
 		// parsing the connection arguments, the connection will only be
 		// used to send a report of that error.
-		v3conn := makeV3Conn(conn, &s.metrics, &s.sqlMemoryPool, s.executor)
+		v3conn := makeV3Conn(conn, s.st, &s.metrics, &s.sqlMemoryPool, s.executor)
+		v3conn.connDeniedEvents = s.connDeniedEvents
 		defer v3conn.finish(ctx)
 
 		if v3conn.sessionArgs, err = parseOptions(ctx, buf.msg); err != nil {
@@ -401,9 +470,20 @@ func (s *Server) ServeConn(ctx context.Context, conn net.Conn) error {
 
 		v3conn.sessionArgs.User = tree.Name(v3conn.sessionArgs.User).Normalize()
 		if err := v3conn.handleAuthentication(ctx, s.cfg.Insecure); err != nil {
-			return v3conn.sendError(pgerror.NewError(pgerror.CodeInvalidPasswordError, err.Error()))
+			// Returning closes the connection, so that a client which ignores
+			// the error can't go on as the requested user.
+			if _, ok := pgerror.GetPGCause(err); !ok {
+				err = pgerror.NewError(pgerror.CodeInvalidPasswordError, err.Error())
+			}
+			return v3conn.sendError(err)
 		}
 
+		release, err := s.admitConn(v3conn.sessionArgs.User, v3conn.connLimit)
+		if err != nil {
+			return v3conn.sendError(err)
+		}
+		defer release()
+
 		// Reserve some memory for this connection using the server's
 		// monitor. This reduces pressure on the shared pool because the
 		// server monitor allocates in chunks from the shared pool and
@@ -419,10 +499,13 @@ func (s *Server) ServeConn(ctx context.Context, conn net.Conn) error {
 				baseSQLMemoryBudget, err)
 		}
 
-		err := v3conn.serve(ctx, s.IsDraining, acc)
+		err = v3conn.serve(ctx, s.IsDraining, acc)
 		// If the error that closed the connection is related to an
-		// administrative shutdown, relay that information to the client.
-		if pgErr, ok := pgerror.GetPGCause(err); ok && pgErr.Code == pgerror.CodeAdminShutdownError {
+		// administrative shutdown or to the idle-in-transaction timeout, relay
+		// that information to the client.
+		if pgErr, ok := pgerror.GetPGCause(err); ok &&
+			(pgErr.Code == pgerror.CodeAdminShutdownError ||
+				pgErr.Code == pgerror.CodeIdleInTransactionSessionTimeoutError) {
 			return v3conn.sendError(err)
 		}
 		return err
@@ -430,3 +513,70 @@ func (s *Server) ServeConn(ctx context.Context, conn net.Conn) error {
 
 	return errors.Errorf("unknown protocol version %d", version)
 }
+
+// handleCancel handles a CancelRequest, which carries the process ID and the
+// secret key sent to the client in the BackendKeyData message of a session.
+// The node owning the session is found from the process ID. The request is
+// passed to the CancelSession endpoint of the status server, which cancels
+// the queries in flight of the session if it is owned by this node, and
+// otherwise forwards the request to the owning node over its RPC connection.
+// The endpoint is only reachable over RPC, not over HTTP. Like in Postgres,
+// nothing is sent back to the client, which doesn't learn whether the
+// request succeeded.
+//
+// Since process IDs are predictable, the secret key is the only part of the
+// request that a client can't guess: a cancel request is authenticated by 32
+// random bits. This is also the case in Postgres, whose process IDs are
+// predictable. To slow down the attempts to guess a key, the requests whose
+// key matches no session are counted in the sql.cancels.failed metric,
+// logged, and throttled: past cancelFailureRate per second, the requests are
+// dropped.
+// See: https://www.postgresql.org/docs/current/static/protocol-flow.html#AEN112861
+func (s *Server) handleCancel(ctx context.Context, remoteAddr net.Addr, buf *readBuffer) error {
+	processID, err := buf.getUint32()
+	if err != nil {
+		return err
+	}
+	secretKey, err := buf.getUint32()
+	if err != nil {
+		return err
+	}
+
+	// Reserve the failure before processing the request; the reservation is
+	// given back if the request turns out to match a session.
+	now := timeutil.Now()
+	failure := s.cancelFailures.ReserveN(now, 1)
+	if !failure.OK() || failure.DelayFrom(now) > 0 {
+		failure.CancelAt(now)
+		s.noteCancelFailure(ctx, remoteAddr, "throttled cancel request")
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cancelForwardTimeout)
+	defer cancel()
+	nodeID := sql.ProcessIDNodeID(int32(processID))
+	resp, err := s.statusServer.CancelSession(ctx, &serverpb.CancelSessionRequest{
+		NodeID:    nodeID.String(),
+		CancelKey: int32(secretKey),
+	})
+	if err != nil {
+		failure.Cancel()
+		return errors.Wrapf(err, "could not forward cancel request to node %d", nodeID)
+	}
+	if resp.Cancelled {
+		failure.Cancel()
+		return nil
+	}
+	s.noteCancelFailure(ctx, remoteAddr,
+		fmt.Sprintf("cancel request for unknown session on node %d", nodeID))
+	return nil
+}
+
+// noteCancelFailure records a cancel request which didn't cancel a session.
+func (s *Server) noteCancelFailure(ctx context.Context, remoteAddr net.Addr, msg string) {
+	s.metrics.CancelsFailed.Inc(1)
+	if s.cancelFailureLog.Allow() {
+		log.Warningf(ctx, "pgwire: %s from %s (%d failed cancel requests since the node started)",
+			msg, remoteAddr, s.metrics.CancelsFailed.Count())
+	}
+}
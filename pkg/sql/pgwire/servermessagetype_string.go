@@ -5,25 +5,31 @@ package pgwire
 import "fmt"
 
 const (
-	_serverMessageType_name_0 = "serverMsgParseCompleteserverMsgBindCompleteserverMsgCloseComplete"
-	_serverMessageType_name_1 = "serverMsgCommandCompleteserverMsgDataRowserverMsgErrorResponse"
-	_serverMessageType_name_2 = "serverMsgCopyInResponse"
-	_serverMessageType_name_3 = "serverMsgEmptyQuery"
-	_serverMessageType_name_4 = "serverMsgAuthserverMsgParameterStatusserverMsgRowDescription"
-	_serverMessageType_name_5 = "serverMsgReady"
-	_serverMessageType_name_6 = "serverMsgNoData"
-	_serverMessageType_name_7 = "serverMsgParameterDescription"
+	_serverMessageType_name_0  = "serverMsgParseCompleteserverMsgBindCompleteserverMsgCloseComplete"
+	_serverMessageType_name_1  = "serverMsgNotificationResponse"
+	_serverMessageType_name_2  = "serverMsgCommandCompleteserverMsgDataRowserverMsgErrorResponse"
+	_serverMessageType_name_3  = "serverMsgCopyInResponseserverMsgCopyOutResponseserverMsgEmptyQuery"
+	_serverMessageType_name_4  = "serverMsgBackendKeyData"
+	_serverMessageType_name_5  = "serverMsgNoticeResponse"
+	_serverMessageType_name_6  = "serverMsgAuthserverMsgParameterStatusserverMsgRowDescription"
+	_serverMessageType_name_7  = "serverMsgReady"
+	_serverMessageType_name_8  = "serverMsgCopyDoneserverMsgCopyData"
+	_serverMessageType_name_9  = "serverMsgNoData"
+	_serverMessageType_name_10 = "serverMsgPortalSuspendedserverMsgParameterDescription"
 )
 
 var (
-	_serverMessageType_index_0 = [...]uint8{0, 22, 43, 65}
-	_serverMessageType_index_1 = [...]uint8{0, 24, 40, 62}
-	_serverMessageType_index_2 = [...]uint8{0, 23}
-	_serverMessageType_index_3 = [...]uint8{0, 19}
-	_serverMessageType_index_4 = [...]uint8{0, 13, 37, 60}
-	_serverMessageType_index_5 = [...]uint8{0, 14}
-	_serverMessageType_index_6 = [...]uint8{0, 15}
-	_serverMessageType_index_7 = [...]uint8{0, 29}
+	_serverMessageType_index_0  = [...]uint8{0, 22, 43, 65}
+	_serverMessageType_index_1  = [...]uint8{0, 29}
+	_serverMessageType_index_2  = [...]uint8{0, 24, 40, 62}
+	_serverMessageType_index_3  = [...]uint8{0, 23, 47, 66}
+	_serverMessageType_index_4  = [...]uint8{0, 23}
+	_serverMessageType_index_5  = [...]uint8{0, 23}
+	_serverMessageType_index_6  = [...]uint8{0, 13, 37, 60}
+	_serverMessageType_index_7  = [...]uint8{0, 14}
+	_serverMessageType_index_8  = [...]uint8{0, 17, 34}
+	_serverMessageType_index_9  = [...]uint8{0, 15}
+	_serverMessageType_index_10 = [...]uint8{0, 24, 53}
 )
 
 func (i serverMessageType) String() string {
@@ -31,22 +37,31 @@ func (i serverMessageType) String() string {
 	case 49 <= i && i <= 51:
 		i -= 49
 		return _serverMessageType_name_0[_serverMessageType_index_0[i]:_serverMessageType_index_0[i+1]]
+	case i == 65:
+		return _serverMessageType_name_1
 	case 67 <= i && i <= 69:
 		i -= 67
-		return _serverMessageType_name_1[_serverMessageType_index_1[i]:_serverMessageType_index_1[i+1]]
-	case i == 71:
-		return _serverMessageType_name_2
-	case i == 73:
-		return _serverMessageType_name_3
+		return _serverMessageType_name_2[_serverMessageType_index_2[i]:_serverMessageType_index_2[i+1]]
+	case 71 <= i && i <= 73:
+		i -= 71
+		return _serverMessageType_name_3[_serverMessageType_index_3[i]:_serverMessageType_index_3[i+1]]
+	case i == 75:
+		return _serverMessageType_name_4
+	case i == 78:
+		return _serverMessageType_name_5
 	case 82 <= i && i <= 84:
 		i -= 82
-		return _serverMessageType_name_4[_serverMessageType_index_4[i]:_serverMessageType_index_4[i+1]]
+		return _serverMessageType_name_6[_serverMessageType_index_6[i]:_serverMessageType_index_6[i+1]]
 	case i == 90:
-		return _serverMessageType_name_5
-	case i == 110:
-		return _serverMessageType_name_6
-	case i == 116:
 		return _serverMessageType_name_7
+	case 99 <= i && i <= 100:
+		i -= 99
+		return _serverMessageType_name_8[_serverMessageType_index_8[i]:_serverMessageType_index_8[i+1]]
+	case i == 110:
+		return _serverMessageType_name_9
+	case 115 <= i && i <= 116:
+		i -= 115
+		return _serverMessageType_name_10[_serverMessageType_index_10[i]:_serverMessageType_index_10[i+1]]
 	default:
 		return fmt.Sprintf("serverMessageType(%d)", i)
 	}
@@ -182,6 +182,12 @@ func (b *writeBuffer) writeTextDatum(ctx context.Context, d tree.Datum, sessionL
 	case *tree.DJSON:
 		b.writeLengthPrefixedString(v.JSON.String())
 
+	case *tree.DTSVector:
+		b.writeLengthPrefixedString(v.Vector.String())
+
+	case *tree.DTSQuery:
+		b.writeLengthPrefixedString(v.Query.String())
+
 	case *tree.DTuple:
 		b.variablePutbuf.WriteString("(")
 		for i, d := range v.D {
@@ -636,6 +642,10 @@ func decodeOidDatum(id oid.Oid, code formatCode, b []byte) (tree.Datum, error) {
 				return nil, errors.Errorf("could not parse string %q as inet", b)
 			}
 			return d, nil
+		case oid.T_tsvector:
+			return tree.ParseDTSVector(string(b))
+		case oid.T_tsquery:
+			return tree.ParseDTSQuery(string(b))
 		case oid.T__int2, oid.T__int4, oid.T__int8:
 			var arr pq.Int64Array
 			if err := (&arr).Scan(b); err != nil {
@@ -26,12 +26,15 @@ import (
 	"github.com/lib/pq/oid"
 	"github.com/pkg/errors"
 	"golang.org/x/net/context"
+	"golang.org/x/time/rate"
 
 	"bytes"
 	"io"
 
 	"github.com/cockroachdb/cockroach/pkg/build"
 	"github.com/cockroachdb/cockroach/pkg/security"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
 	"github.com/cockroachdb/cockroach/pkg/sql"
 	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
@@ -66,17 +69,24 @@ const (
 	clientMsgTerminate   clientMessageType = 'X'
 
 	serverMsgAuth                 serverMessageType = 'R'
+	serverMsgBackendKeyData       serverMessageType = 'K'
 	serverMsgBindComplete         serverMessageType = '2'
 	serverMsgCommandComplete      serverMessageType = 'C'
 	serverMsgCloseComplete        serverMessageType = '3'
+	serverMsgCopyData             serverMessageType = 'd'
+	serverMsgCopyDone             serverMessageType = 'c'
 	serverMsgCopyInResponse       serverMessageType = 'G'
+	serverMsgCopyOutResponse      serverMessageType = 'H'
 	serverMsgDataRow              serverMessageType = 'D'
 	serverMsgEmptyQuery           serverMessageType = 'I'
 	serverMsgErrorResponse        serverMessageType = 'E'
 	serverMsgNoData               serverMessageType = 'n'
+	serverMsgNoticeResponse       serverMessageType = 'N'
+	serverMsgNotificationResponse serverMessageType = 'A'
 	serverMsgParameterDescription serverMessageType = 't'
 	serverMsgParameterStatus      serverMessageType = 'S'
 	serverMsgParseComplete        serverMessageType = '1'
+	serverMsgPortalSuspended      serverMessageType = 's'
 	serverMsgReady                serverMessageType = 'Z'
 	serverMsgRowDescription       serverMessageType = 'T'
 )
@@ -107,11 +117,24 @@ const (
 const (
 	authOK                int32 = 0
 	authCleartextPassword int32 = 3
+	authMD5Password       int32 = 5
+	authSASL              int32 = 10
+	authSASLContinue      int32 = 11
+	authSASLFinal         int32 = 12
 )
 
-// connResultsBufferSizeBytes refers to the size of the result set which we
-// buffer into memory prior to flushing to the client.
-const connResultsBufferSizeBytes = 16 << 10
+// connResultsBufferSize is the size of the results which we buffer in memory
+// prior to flushing them to the client. Results are streamed to the client
+// once this size is exceeded; until then, the transaction can be retried
+// automatically on retryable errors, since no results have been sent to the
+// client yet. The buffer size is read when the connection is established.
+var connResultsBufferSize = settings.RegisterByteSizeSetting(
+	"sql.defaults.results_buffer.size",
+	"size of the buffer that accumulates results for a statement or a batch of "+
+		"statements before they are sent to the client; results are streamed once "+
+		"the buffer is full, after which automatic retries are no longer possible",
+	16<<10, /* 16KiB */
+)
 
 // preparedStatementMeta is pgwire-specific metadata which is attached to each
 // sql.PreparedStatement on a v3Conn's sql.Session.
@@ -181,6 +204,18 @@ type v3Conn struct {
 	sessionArgs sql.SessionArgs
 	session     *sql.Session
 
+	// copyBuf is used to encode the values sent by COPY ... TO STDOUT.
+	copyBuf writeBuffer
+
+	// reader reads the messages of the client while the session waits for
+	// notifications (see readTypedMsgOrNotify). It is started the first time
+	// it is needed.
+	reader *msgReader
+
+	// connLimit is the connection limit of the authenticated user, or -1 if
+	// the user has no limit. It is set by handleAuthentication.
+	connLimit int64
+
 	// The logic governing these guys is hairy, and is not sufficiently
 	// specified in documentation. Consult the sources before you modify:
 	// https://github.com/postgres/postgres/blob/master/src/backend/tcop/postgres.c
@@ -192,10 +227,30 @@ type v3Conn struct {
 	// it gets extra data after an error happened during a COPY operation.
 	doNotSendReadyForQuery bool
 
+	// idleInTxnSince is set while the session waits for the next query of an
+	// open transaction, to the time when it started waiting.
+	idleInTxnSince time.Time
+
 	metrics *ServerMetrics
 
 	sqlMemoryPool *mon.BytesMonitor
 
+	// resultsBufferSize is the size above which the buffered results are
+	// flushed to the client (see connResultsBufferSize).
+	resultsBufferSize int
+
+	// passwordAuthMethods is the value of the
+	// server.password_authentication.methods setting when the connection was
+	// established.
+	passwordAuthMethods string
+	// hbaConf is the value of the server.host_based_authentication.configuration
+	// setting when the connection was established.
+	hbaConf string
+	// connDeniedEvents throttles the event log entries about the connections
+	// rejected by hbaConf. It is shared by the connections of the server, and
+	// nil if the rejections aren't recorded in the event log.
+	connDeniedEvents *rate.Limiter
+
 	streamingState streamingState
 }
 
@@ -238,6 +293,11 @@ type streamingState struct {
 	// copyIn is set to true if we are currently copying in so that we do not
 	// send tree.RowsAffected command complete tags.
 	copyIn bool
+	// copyOut is set if the rows of the current statement are sent as the
+	// data of a COPY ... TO STDOUT.
+	copyOut bool
+	// copyFormat is the format of the data of the current COPY statement.
+	copyFormat sql.CopyFormat
 }
 
 func (s *streamingState) reset(formatCodes []formatCode, sendDescription bool, limit int) {
@@ -253,16 +313,24 @@ func (s *streamingState) reset(formatCodes []formatCode, sendDescription bool, l
 }
 
 func makeV3Conn(
-	conn net.Conn, metrics *ServerMetrics, sqlMemoryPool *mon.BytesMonitor, executor *sql.Executor,
+	conn net.Conn,
+	st *cluster.Settings,
+	metrics *ServerMetrics,
+	sqlMemoryPool *mon.BytesMonitor,
+	executor *sql.Executor,
 ) v3Conn {
 	return v3Conn{
-		conn:          conn,
-		rd:            bufio.NewReader(conn),
-		wr:            bufio.NewWriter(conn),
-		writeBuf:      writeBuffer{bytecount: metrics.BytesOutCount},
-		metrics:       metrics,
-		executor:      executor,
-		sqlMemoryPool: sqlMemoryPool,
+		conn:              conn,
+		rd:                bufio.NewReader(conn),
+		wr:                bufio.NewWriter(conn),
+		writeBuf:          writeBuffer{bytecount: metrics.BytesOutCount},
+		metrics:           metrics,
+		executor:          executor,
+		sqlMemoryPool:     sqlMemoryPool,
+		resultsBufferSize: int(connResultsBufferSize.Get(&st.SV)),
+
+		passwordAuthMethods: passwordAuthMethods.Get(&st.SV),
+		hbaConf:             hbaConfSetting.Get(&st.SV),
 	}
 }
 
@@ -272,6 +340,9 @@ func (c *v3Conn) finish(ctx context.Context) {
 		log.Error(ctx, err)
 	}
 	_ = c.conn.Close()
+	if c.reader != nil {
+		c.reader.stop()
+	}
 }
 
 func parseOptions(ctx context.Context, data []byte) (sql.SessionArgs, error) {
@@ -334,48 +405,84 @@ var statusReportParams = map[string]string{
 // name, if different from the one given initially. Note: at this
 // point the sql.Session does not exist yet! If need exists to access the
 // database to look up authentication data, use the internal executor.
+// If the client can't be authenticated, or the host-based authentication
+// rules reject the connection, the returned error must be sent to the
+// client and the connection closed.
 func (c *v3Conn) handleAuthentication(ctx context.Context, insecure bool) error {
 	if tlsConn, ok := c.conn.(*tls.Conn); ok {
-		var authenticationHook security.UserAuthHook
+		// Find the authentication method required by the host-based
+		// authentication rules.
+		hbaEntry, err := c.checkHBA(ctx, true /* ssl */)
+		if err != nil {
+			return err
+		}
 
-		// Check that the requested user exists and retrieve the hashed
-		// password in case password authentication is needed.
-		exists, hashedPassword, err := sql.GetUserHashedPassword(
+		// Check that the requested user exists and retrieve the password
+		// credentials in case password authentication is needed.
+		exists, creds, err := sql.GetUserCredentials(
 			ctx, c.executor, c.metrics.internalMemMetrics, c.sessionArgs.User,
 		)
 		if err != nil {
-			return c.sendError(err)
+			return err
 		}
 		if !exists {
-			return c.sendError(errors.Errorf("user %s does not exist", c.sessionArgs.User))
+			return errors.Errorf("user %s does not exist", c.sessionArgs.User)
 		}
+		c.connLimit = creds.ConnectionLimit
 
 		tlsState := tlsConn.ConnectionState()
 		// If no certificates are provided, default to password
-		// authentication.
-		if len(tlsState.PeerCertificates) == 0 {
-			password, err := c.sendAuthPasswordRequest()
-			if err != nil {
-				return c.sendError(err)
+		// authentication, unless the rules require a certificate.
+		usePassword := len(tlsState.PeerCertificates) == 0
+		switch hbaEntry.method {
+		case hbaMethodCert:
+			if usePassword {
+				return c.rejectConnection(ctx, fmt.Sprintf(
+					"the host-based authentication rule at line %d requires a client certificate",
+					hbaEntry.line))
+			}
+		case hbaMethodPassword:
+			usePassword = true
+		}
+		if usePassword {
+			if err := c.handlePasswordAuthentication(insecure, creds); err != nil {
+				return err
 			}
-			authenticationHook = security.UserAuthPasswordHook(
-				insecure, password, hashedPassword,
-			)
 		} else {
 			// Normalize the username contained in the certificate.
 			tlsState.PeerCertificates[0].Subject.CommonName = tree.Name(
 				tlsState.PeerCertificates[0].Subject.CommonName,
 			).Normalize()
-			var err error
-			authenticationHook, err = security.UserAuthCertHook(insecure, &tlsState)
+			authenticationHook, err := security.UserAuthCertHook(insecure, &tlsState)
 			if err != nil {
-				return c.sendError(err)
+				return err
+			}
+			if err := authenticationHook(c.sessionArgs.User, true /* public */); err != nil {
+				return err
 			}
 		}
-
-		if err := authenticationHook(c.sessionArgs.User, true /* public */); err != nil {
-			return c.sendError(err)
+	} else {
+		// Only insecure servers accept connections without SSL, and they don't
+		// authenticate clients. The host-based authentication rules can still
+		// reject the connection.
+		hbaEntry, err := c.checkHBA(ctx, false /* ssl */)
+		if err != nil {
+			return err
+		}
+		if hbaEntry.method == hbaMethodCert {
+			return c.rejectConnection(ctx, fmt.Sprintf(
+				"the host-based authentication rule at line %d requires a client certificate",
+				hbaEntry.line))
 		}
+		// The user doesn't need to exist, but its connection limit is still
+		// enforced if it does.
+		_, creds, err := sql.GetUserCredentials(
+			ctx, c.executor, c.metrics.internalMemMetrics, c.sessionArgs.User,
+		)
+		if err != nil {
+			return err
+		}
+		c.connLimit = creds.ConnectionLimit
 	}
 
 	c.writeBuf.initMsg(serverMsgAuth)
@@ -413,6 +520,15 @@ func (c *v3Conn) serve(ctx context.Context, draining func() bool, reserved mon.B
 	if err := c.setupSession(ctx, reserved); err != nil {
 		return err
 	}
+
+	// Give the client the key with which it can cancel the queries of the
+	// session.
+	c.writeBuf.initMsg(serverMsgBackendKeyData)
+	c.writeBuf.putInt32(c.session.ProcessID)
+	c.writeBuf.putInt32(c.session.CancelKey)
+	if err := c.writeBuf.finishMsg(c.wr); err != nil {
+		return err
+	}
 	// Now that a Session has been set up, further operations done on behalf of
 	// this session use Session.Ctx() (which may diverge from this method's ctx).
 
@@ -438,12 +554,24 @@ func (c *v3Conn) serve(ctx context.Context, draining func() bool, reserved mon.B
 		}(); err != nil {
 			return newAdminShutdownErr(err)
 		}
-		return nil
+		return c.checkIdleInTransaction()
 	})
 	c.rd = bufio.NewReader(c.conn)
 
 	for {
 		if !c.doingExtendedQueryMessage && !c.doNotSendReadyForQuery {
+			if c.session.TxnState.State() != sql.Open {
+				// The transaction in which the suspended portals were executed, if
+				// any, is over.
+				c.session.PreparedPortals.DeleteSuspended(c.session.Ctx())
+			}
+			if c.session.TxnState.State() == sql.NoTxn {
+				// Deliver the notifications received during the transaction.
+				if err := c.sendNotifications(); err != nil {
+					return err
+				}
+			}
+
 			c.writeBuf.initMsg(serverMsgReady)
 			var txnStatus byte
 			switch c.session.TxnState.State() {
@@ -476,9 +604,13 @@ func (c *v3Conn) serve(ctx context.Context, draining func() bool, reserved mon.B
 			if err := c.wr.Flush(); err != nil {
 				return err
 			}
+			if c.session.TxnState.State() != sql.NoTxn {
+				c.idleInTxnSince = timeutil.Now()
+			}
 		}
 		c.doNotSendReadyForQuery = false
-		typ, n, err := c.readBuf.readTypedMsg(c.rd)
+		typ, n, err := c.readTypedMsgOrNotify()
+		c.idleInTxnSince = time.Time{}
 		c.metrics.BytesInCount.Inc(int64(n))
 		if err != nil {
 			return err
@@ -578,23 +710,9 @@ func (c *v3Conn) serve(ctx context.Context, draining func() bool, reserved mon.B
 func (c *v3Conn) sendAuthPasswordRequest() (string, error) {
 	c.writeBuf.initMsg(serverMsgAuth)
 	c.writeBuf.putInt32(authCleartextPassword)
-	if err := c.writeBuf.finishMsg(c.wr); err != nil {
-		return "", err
-	}
-	if err := c.wr.Flush(); err != nil {
-		return "", err
-	}
-
-	typ, n, err := c.readBuf.readTypedMsg(c.rd)
-	c.metrics.BytesInCount.Inc(int64(n))
-	if err != nil {
+	if err := c.readAuthResponse(); err != nil {
 		return "", err
 	}
-
-	if typ != clientMsgPassword {
-		return "", errors.Errorf("invalid response to authentication request: %s", typ)
-	}
-
 	return c.readBuf.getString()
 }
 
@@ -717,6 +835,10 @@ func (c *v3Conn) handleParse(buf *readBuffer) error {
 // stmtHasNoData returns true if describing a result of the input statement
 // type should return NoData.
 func stmtHasNoData(stmt tree.Statement) bool {
+	if _, ok := stmt.(*tree.CopyTo); ok {
+		// The rows of a COPY are sent as COPY data.
+		return true
+	}
 	return stmt == nil || stmt.StatementType() != tree.Rows
 }
 
@@ -956,7 +1078,7 @@ func (c *v3Conn) handleExecute(buf *readBuffer) error {
 	tracing.AnnotateTrace()
 	c.streamingState.reset(portalMeta.outFormats, false /* sendDescription */, int(limit))
 	c.session.ResultsWriter = c
-	err = c.executor.ExecutePreparedStatement(c.session, stmt, pinfo)
+	err = c.executor.ExecutePortal(c.session, portal, pinfo, int(limit))
 	if err != nil {
 		if err := c.setError(err); err != nil {
 			return err
@@ -1082,15 +1204,17 @@ func (c *v3Conn) sendRowDescription(
 
 // beginCopyIn begins the COPY IN data flow after we receive a
 // COPY ... FROM STDIN statement by sending the number of columns we expect
-// along with their expected formats to the client. Currently, we only support
-// the "text" format for COPY IN.
+// along with their expected formats to the client.
 // See: https://www.postgresql.org/docs/current/static/protocol-flow.html#PROTOCOL-COPY
-func (c *v3Conn) beginCopyIn(ctx context.Context, columns []sqlbase.ResultColumn) error {
+func (c *v3Conn) beginCopyIn(
+	ctx context.Context, columns []sqlbase.ResultColumn, format sql.CopyFormat,
+) error {
+	code := copyFormatCode(format)
 	c.writeBuf.initMsg(serverMsgCopyInResponse)
-	c.writeBuf.writeByte(byte(formatText))
+	c.writeBuf.writeByte(byte(code))
 	c.writeBuf.putInt16(int16(len(columns)))
 	for range columns {
-		c.writeBuf.putInt16(int16(formatText))
+		c.writeBuf.putInt16(int16(code))
 	}
 	if err := c.writeBuf.finishMsg(c.wr); err != nil {
 		return sql.NewWireFailureError(err)
@@ -1203,6 +1327,15 @@ func (c *v3Conn) BeginResult(stmt tree.Statement) {
 	state.statementType = stmt.StatementType()
 	state.rowsAffected = 0
 	state.firstRow = true
+	state.copyOut = false
+	// Invalid COPY options are reported when the statement is planned.
+	switch n := stmt.(type) {
+	case *tree.CopyFrom:
+		state.copyFormat, _ = sql.MakeCopyFormat(n.Options)
+	case *tree.CopyTo:
+		state.copyOut = true
+		state.copyFormat, _ = sql.MakeCopyFormat(n.Options)
+	}
 }
 
 // GetPGTag implements the StatementResult interface.
@@ -1233,19 +1366,11 @@ func (c *v3Conn) CloseResult() error {
 
 	ctx := c.session.Ctx()
 	formatCodes := state.formatCodes
-	limit := state.limit
 
 	if err := c.flush(false /* forceSend */); err != nil {
 		return err
 	}
 
-	if limit != 0 && state.statementType == tree.Rows && state.rowsAffected > state.limit {
-		return c.setError(pgerror.NewErrorf(
-			pgerror.CodeInternalError,
-			"execute row count limits not supported: %d of %d", limit, state.rowsAffected,
-		))
-	}
-
 	if state.pgTag == "INSERT" {
 		// From the postgres docs (49.5. Message Formats):
 		// `INSERT oid rows`... oid is the object ID of the inserted row if
@@ -1261,12 +1386,33 @@ func (c *v3Conn) CloseResult() error {
 		return c.sendCommandComplete(tag, &state.buf)
 
 	case tree.Rows:
+		if state.copyOut {
+			if state.firstRow {
+				if err := c.beginCopyOut(&state.buf); err != nil {
+					return err
+				}
+			}
+			if err := c.finishCopyOut(&state.buf); err != nil {
+				return err
+			}
+			tag = append(tag, ' ')
+			tag = strconv.AppendUint(tag, uint64(state.rowsAffected), 10)
+			return c.sendCommandComplete(tag, &state.buf)
+		}
+
 		if state.firstRow && state.sendDescription {
 			if err := c.sendRowDescription(ctx, state.columns, formatCodes, &state.buf); err != nil {
 				return err
 			}
 		}
 
+		if state.limit != 0 && state.rowsAffected >= state.limit {
+			// The execution of the portal stopped at the row limit of the Execute
+			// message; the client has to ask for the next rows.
+			c.writeBuf.initMsg(serverMsgPortalSuspended)
+			return c.writeBuf.finishMsg(&state.buf)
+		}
+
 		tag = append(tag, ' ')
 		tag = strconv.AppendUint(tag, uint64(state.rowsAffected), 10)
 		return c.sendCommandComplete(tag, &state.buf)
@@ -1280,7 +1426,7 @@ func (c *v3Conn) CloseResult() error {
 
 	case tree.CopyIn:
 		state.copyIn = true
-		if err := c.beginCopyIn(ctx, state.columns); err != nil {
+		if err := c.beginCopyIn(ctx, state.columns, state.copyFormat); err != nil {
 			if err := c.setError(err); err != nil {
 				return err
 			}
@@ -1324,6 +1470,43 @@ func (c *v3Conn) IncrementRowsAffected(n int) {
 	c.streamingState.rowsAffected += n
 }
 
+// AddNotice implements the StatementResult interface. The notice is sent
+// with the results of the statement.
+func (c *v3Conn) AddNotice(notice *pgerror.Notice) {
+	state := &c.streamingState
+	if state.err != nil {
+		return
+	}
+
+	c.writeBuf.initMsg(serverMsgNoticeResponse)
+
+	c.writeBuf.putErrFieldMsg(serverErrFieldSeverity)
+	c.writeBuf.writeTerminatedString(notice.Severity.String())
+
+	c.writeBuf.putErrFieldMsg(serverErrFieldSQLState)
+	c.writeBuf.writeTerminatedString(notice.Code)
+
+	if notice.Detail != "" {
+		c.writeBuf.putErrFieldMsg(serverErrFileldDetail)
+		c.writeBuf.writeTerminatedString(notice.Detail)
+	}
+
+	if notice.Hint != "" {
+		c.writeBuf.putErrFieldMsg(serverErrFileldHint)
+		c.writeBuf.writeTerminatedString(notice.Hint)
+	}
+
+	c.writeBuf.putErrFieldMsg(serverErrFieldMsgPrimary)
+	c.writeBuf.writeTerminatedString(notice.Message)
+
+	c.writeBuf.nullTerminate()
+	if err := c.writeBuf.finishMsg(&state.buf); err != nil {
+		// AddNotice doesn't return errors: the error is returned when the next
+		// result is added.
+		_ = c.setError(err)
+	}
+}
+
 // AddRow implements the StatementResult interface.
 func (c *v3Conn) AddRow(ctx context.Context, row tree.Datums) error {
 	state := &c.streamingState
@@ -1339,6 +1522,19 @@ func (c *v3Conn) AddRow(ctx context.Context, row tree.Datums) error {
 	// The final tag will need to know the total row count.
 	state.rowsAffected++
 
+	if state.copyOut {
+		if state.firstRow {
+			if err := c.beginCopyOut(&state.buf); err != nil {
+				return err
+			}
+			state.firstRow = false
+		}
+		if err := c.sendCopyData(ctx, row, &state.buf); err != nil {
+			return err
+		}
+		return c.flush(false /* forceSend */)
+	}
+
 	formatCodes := state.formatCodes
 
 	// First row and description needed: do it.
@@ -1407,7 +1603,7 @@ func (c *v3Conn) flush(forceSend bool) error {
 		return nil
 	}
 
-	if forceSend || state.buf.Len() > connResultsBufferSizeBytes {
+	if forceSend || state.buf.Len() > c.resultsBufferSize {
 		state.hasSentResults = true
 		state.txnStartIdx = 0
 		if _, err := state.buf.WriteTo(c.wr); err != nil {
@@ -1421,6 +1617,21 @@ func (c *v3Conn) flush(forceSend bool) error {
 	return nil
 }
 
+// checkIdleInTransaction returns an error if the session has been waiting for
+// the next query of an open transaction for longer than its
+// idle_in_transaction_session_timeout, in which case the connection is
+// closed and the transaction rolled back.
+func (c *v3Conn) checkIdleInTransaction() error {
+	if c.idleInTxnSince.IsZero() {
+		return nil
+	}
+	timeout := c.session.IdleInTransactionSessionTimeout
+	if timeout == 0 || timeutil.Since(c.idleInTxnSince) < timeout {
+		return nil
+	}
+	return sqlbase.NewIdleInTransactionSessionTimeoutError()
+}
+
 func newUnrecognizedMsgTypeErr(typ clientMessageType) error {
 	return pgerror.NewErrorf(
 		pgerror.CodeProtocolViolationError, "unrecognized client message type %v", typ)
@@ -15,21 +15,42 @@
 package pgwire
 
 import (
+	"bufio"
 	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/tls"
+	gosql "database/sql"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"golang.org/x/net/context"
 
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/gossip"
+	"github.com/cockroachdb/cockroach/pkg/security"
+	"github.com/cockroachdb/cockroach/pkg/server/serverpb"
 	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
 	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
 	"github.com/cockroachdb/cockroach/pkg/util"
 	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/cockroach/pkg/util/metric"
 	"github.com/cockroachdb/cockroach/pkg/util/mon"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
 	"github.com/pkg/errors"
 )
 
@@ -49,7 +70,7 @@ func makeTestV3Conn(c net.Conn) v3Conn {
 		},
 		nil, /* stopper */
 	)
-	return makeV3Conn(c, &metrics, &mon, exec)
+	return makeV3Conn(c, st, &metrics, &mon, exec)
 }
 
 // TestMaliciousInputs verifies that known malicious inputs sent to
@@ -178,3 +199,1096 @@ func TestReadTimeoutConnExits(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 }
+
+// testClient is a minimal pgwire client, used to send the extended protocol
+// messages which lib/pq doesn't give control over.
+type testClient struct {
+	t        *testing.T
+	conn     net.Conn
+	rd       *bufio.Reader
+	readBuf  readBuffer
+	writeBuf writeBuffer
+
+	// processID and secretKey are the contents of the BackendKeyData message
+	// received at the start of the session.
+	processID int32
+	secretKey int32
+}
+
+func newTestClient(t *testing.T, addr string) *testClient {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := makeTestClient(t, conn)
+	c.sendStartup(security.RootUser)
+	c.receive()
+	return c
+}
+
+// newTLSTestClient connects to the secure server at addr as the given user.
+// It returns before the authentication, which is left to the caller.
+func newTLSTestClient(t *testing.T, addr string, user string) *testClient {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var req [8]byte
+	binary.BigEndian.PutUint32(req[0:], uint32(len(req)))
+	binary.BigEndian.PutUint32(req[4:], versionSSL)
+	if _, err := conn.Write(req[:]); err != nil {
+		t.Fatal(err)
+	}
+	var resp [1]byte
+	if _, err := io.ReadFull(conn, resp[:]); err != nil {
+		t.Fatal(err)
+	}
+	if resp[0] != sslSupported[0] {
+		t.Fatalf("unexpected response to SSL request: %q", resp[0])
+	}
+	c := makeTestClient(t, tls.Client(conn, &tls.Config{InsecureSkipVerify: true}))
+	c.sendStartup(user)
+	return c
+}
+
+func makeTestClient(t *testing.T, conn net.Conn) *testClient {
+	return &testClient{
+		t:        t,
+		conn:     conn,
+		rd:       bufio.NewReader(conn),
+		writeBuf: writeBuffer{bytecount: metric.NewCounter(metric.Metadata{})},
+	}
+}
+
+// sendStartup sends the startup message of a session of the given user.
+func (c *testClient) sendStartup(user string) {
+	var startup bytes.Buffer
+	startup.Write(make([]byte, 4))
+	_ = binary.Write(&startup, binary.BigEndian, int32(version30))
+	startup.WriteString("user\x00" + user + "\x00\x00")
+	msg := startup.Bytes()
+	binary.BigEndian.PutUint32(msg, uint32(len(msg)))
+	if _, err := c.conn.Write(msg); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// receiveAuth reads an authentication request and returns its type. The data
+// of the request is left in c.readBuf. ErrorResponses are returned as errors.
+func (c *testClient) receiveAuth() (int32, error) {
+	typ, _, err := c.readBuf.readTypedMsg(c.rd)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	switch serverMessageType(typ) {
+	case serverMsgAuth:
+		authType, err := c.readBuf.getUint32()
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		return int32(authType), nil
+	case serverMsgErrorResponse:
+		return 0, errors.Errorf("%q", c.readBuf.msg)
+	}
+	c.t.Fatalf("unexpected message %s", serverMessageType(typ))
+	return 0, nil
+}
+
+// send sends a message with the given fields, which can be bytes, strings,
+// int16s or int32s. Byte slices are sent as is.
+func (c *testClient) send(typ clientMessageType, fields ...interface{}) {
+	c.writeBuf.initMsg(serverMessageType(typ))
+	for _, f := range fields {
+		switch f := f.(type) {
+		case byte:
+			c.writeBuf.writeByte(f)
+		case []byte:
+			c.writeBuf.write(f)
+		case string:
+			c.writeBuf.writeTerminatedString(f)
+		case int16:
+			c.writeBuf.putInt16(f)
+		case int32:
+			c.writeBuf.putInt32(f)
+		default:
+			c.t.Fatalf("unsupported field %T", f)
+		}
+	}
+	if err := c.writeBuf.finishMsg(c.conn); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// receive reads messages up to a ReadyForQuery message and returns their
+// description: the message type, followed by the value of the first column
+// for DataRows, the data for CopyData, the tag for CommandCompletes, the
+// channel and payload for NotificationResponses, the severity, code and
+// message for NoticeResponses and the transaction status for ReadyForQuery.
+// Authentication, parameter status and backend key data messages are skipped.
+func (c *testClient) receive() []string {
+	var msgs []string
+	for {
+		t, _, err := c.readBuf.readTypedMsg(c.rd)
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		typ := serverMessageType(t)
+		desc := string(typ)
+		switch typ {
+		case serverMsgAuth, serverMsgParameterStatus:
+			continue
+		case serverMsgBackendKeyData:
+			for _, v := range []*int32{&c.processID, &c.secretKey} {
+				n, err := c.readBuf.getUint32()
+				if err != nil {
+					c.t.Fatal(err)
+				}
+				*v = int32(n)
+			}
+			continue
+		case serverMsgDataRow:
+			if _, err := c.readBuf.getUint16(); err != nil {
+				c.t.Fatal(err)
+			}
+			n, err := c.readBuf.getUint32()
+			if err != nil {
+				c.t.Fatal(err)
+			}
+			b, err := c.readBuf.getBytes(int(n))
+			if err != nil {
+				c.t.Fatal(err)
+			}
+			desc = fmt.Sprintf("%s %s", desc, b)
+		case serverMsgCopyData:
+			desc = fmt.Sprintf("%s %s", desc, c.readBuf.msg)
+		case serverMsgCommandComplete:
+			tag, err := c.readBuf.getString()
+			if err != nil {
+				c.t.Fatal(err)
+			}
+			desc = fmt.Sprintf("%s %s", desc, tag)
+		case serverMsgNotificationResponse:
+			_, channel, payload := c.readNotification()
+			desc = fmt.Sprintf("%s %s %s", desc, channel, payload)
+		case serverMsgNoticeResponse:
+			fields := make(map[serverErrFieldType]string)
+			for {
+				b, err := c.readBuf.getBytes(1)
+				if err != nil {
+					c.t.Fatal(err)
+				}
+				if b[0] == 0 {
+					break
+				}
+				if fields[serverErrFieldType(b[0])], err = c.readBuf.getString(); err != nil {
+					c.t.Fatal(err)
+				}
+			}
+			desc = fmt.Sprintf("%s %s %s %s", desc, fields[serverErrFieldSeverity],
+				fields[serverErrFieldSQLState], fields[serverErrFieldMsgPrimary])
+		case serverMsgReady:
+			b, err := c.readBuf.getBytes(1)
+			if err != nil {
+				c.t.Fatal(err)
+			}
+			msgs = append(msgs, fmt.Sprintf("%s %s", desc, b))
+			return msgs
+		}
+		msgs = append(msgs, desc)
+	}
+}
+
+func (c *testClient) expect(expected ...string) {
+	c.t.Helper()
+	if msgs := c.receive(); !reflect.DeepEqual(msgs, expected) {
+		c.t.Fatalf("expected:\n%q\ngot:\n%q", expected, msgs)
+	}
+}
+
+// expectClosed sends a query, and checks that the server closed the
+// connection instead of running it.
+func (c *testClient) expectClosed() {
+	c.t.Helper()
+	c.writeBuf.initMsg(serverMessageType(clientMsgSimpleQuery))
+	c.writeBuf.writeTerminatedString("SELECT 1")
+	if err := c.writeBuf.finishMsg(c.conn); err != nil {
+		// The server already closed the connection.
+		return
+	}
+	if typ, _, err := c.readBuf.readTypedMsg(c.rd); err == nil {
+		c.t.Fatalf("expected the connection to be closed, got %s", serverMessageType(typ))
+	}
+}
+
+// readNotification reads the contents of a NotificationResponse message.
+func (c *testClient) readNotification() (pid int32, channel, payload string) {
+	n, err := c.readBuf.getUint32()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if channel, err = c.readBuf.getString(); err != nil {
+		c.t.Fatal(err)
+	}
+	if payload, err = c.readBuf.getString(); err != nil {
+		c.t.Fatal(err)
+	}
+	return int32(n), channel, payload
+}
+
+// receiveNotification waits for a NotificationResponse message and returns
+// its description: the process ID of the notifying session, the channel and
+// the payload.
+func (c *testClient) receiveNotification() string {
+	c.t.Helper()
+	if err := c.conn.SetReadDeadline(timeutil.Now().Add(testutils.DefaultSucceedsSoonDuration)); err != nil {
+		c.t.Fatal(err)
+	}
+	defer func() {
+		if err := c.conn.SetReadDeadline(time.Time{}); err != nil {
+			c.t.Fatal(err)
+		}
+	}()
+	typ, _, err := c.readBuf.readTypedMsg(c.rd)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if serverMessageType(typ) != serverMsgNotificationResponse {
+		c.t.Fatalf("expected a notification, got %s", serverMessageType(typ))
+	}
+	pid, channel, payload := c.readNotification()
+	return fmt.Sprintf("%d %s %s", pid, channel, payload)
+}
+
+// sendCancelRequest sends a CancelRequest for the given session to the server
+// at addr, and waits for the server to close the connection.
+func sendCancelRequest(t *testing.T, addr string, processID, secretKey int32) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	var msg [16]byte
+	binary.BigEndian.PutUint32(msg[0:], uint32(len(msg)))
+	binary.BigEndian.PutUint32(msg[4:], versionCancel)
+	binary.BigEndian.PutUint32(msg[8:], uint32(processID))
+	binary.BigEndian.PutUint32(msg[12:], uint32(secretKey))
+	if _, err := conn.Write(msg[:]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("expected the connection to be closed, got %v", err)
+	}
+}
+
+// TestCancelRequest verifies that a CancelRequest cancels the query in flight
+// of the session it targets, including when it is sent to another node than
+// the one serving the session.
+func TestCancelRequest(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	tc := serverutils.StartTestCluster(t, 2, base.TestClusterArgs{
+		ReplicationMode: base.ReplicationManual,
+		ServerArgs:      base.TestServerArgs{Insecure: true},
+	})
+	defer tc.Stopper().Stop(context.TODO())
+
+	c := newTestClient(t, tc.Server(1).ServingAddr())
+	defer c.conn.Close()
+	if nodeID := sql.ProcessIDNodeID(c.processID); nodeID != tc.Server(1).NodeID() {
+		t.Fatalf("expected process ID of node %d, got node %d", tc.Server(1).NodeID(), nodeID)
+	}
+	// Each session has its own process ID.
+	other := newTestClient(t, tc.Server(1).ServingAddr())
+	defer other.conn.Close()
+	if other.processID == c.processID {
+		t.Fatalf("expected distinct process IDs, got %d twice", c.processID)
+	}
+	if c.secretKey == 0 {
+		t.Fatal("expected a secret key")
+	}
+
+	// A request with a wrong key doesn't affect the session, and is counted
+	// as a failure.
+	sendCancelRequest(t, tc.Server(1).ServingAddr(), c.processID, c.secretKey+1)
+	c.send(clientMsgSimpleQuery, "SELECT 1")
+	c.expect("T", "D 1", "C SELECT 1", "Z I")
+	if count := tc.Server(1).MustGetSQLNetworkCounter(MetaCancelsFailed.Name); count != 1 {
+		t.Fatalf("expected 1 failed cancel request, got %d", count)
+	}
+
+	// Wait for the query to return its first rows, then cancel it through the
+	// other node.
+	c.send(clientMsgSimpleQuery, "SELECT * FROM generate_series(1, 100000000)")
+	canceled := false
+	for {
+		typ, _, err := c.readBuf.readTypedMsg(c.rd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch serverMessageType(typ) {
+		case serverMsgDataRow:
+			if !canceled {
+				sendCancelRequest(t, tc.Server(0).ServingAddr(), c.processID, c.secretKey)
+				canceled = true
+			}
+			continue
+		case serverMsgErrorResponse:
+			if msg := string(c.readBuf.msg); !strings.Contains(msg, "cancel") {
+				t.Fatalf("expected a cancellation error, got %q", msg)
+			}
+		default:
+			continue
+		}
+		break
+	}
+	c.expect("Z I")
+
+	// The session remains usable.
+	c.send(clientMsgSimpleQuery, "SELECT 2")
+	c.expect("T", "D 2", "C SELECT 1", "Z I")
+}
+
+// TestNotifications verifies that the notifications committed on any node
+// are sent to the sessions which listen on their channel.
+func TestNotifications(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	tc := serverutils.StartTestCluster(t, 2, base.TestClusterArgs{
+		ReplicationMode: base.ReplicationManual,
+		ServerArgs:      base.TestServerArgs{Insecure: true},
+	})
+	defer tc.Stopper().Stop(context.TODO())
+
+	listener := newTestClient(t, tc.Server(1).ServingAddr())
+	defer listener.conn.Close()
+	notifier := newTestClient(t, tc.Server(0).ServingAddr())
+	defer notifier.conn.Close()
+
+	listener.send(clientMsgSimpleQuery, "LISTEN foo")
+	listener.expect("C LISTEN", "Z I")
+
+	// The notifications are only sent to the nodes which have gossiped that
+	// their sessions listen on the channel.
+	testutils.SucceedsSoon(t, func() error {
+		var listeners serverpb.NotificationListeners
+		key := gossip.MakeNotificationListenersKey(tc.Server(1).NodeID())
+		if err := tc.Server(0).Gossip().GetInfoProto(key, &listeners); err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(listeners.Channels, []string{"foo"}) {
+			return errors.Errorf("expected channels [foo], got %v", listeners.Channels)
+		}
+		return nil
+	})
+
+	// The notifications are sent when their transaction commits, once per
+	// channel and payload. The idle listener receives them as they arrive.
+	notifier.send(clientMsgSimpleQuery, "BEGIN; NOTIFY foo, 'rolled back'; ROLLBACK")
+	notifier.expect("C BEGIN", "C NOTIFY", "C ROLLBACK", "Z I")
+	notifier.send(clientMsgSimpleQuery, `BEGIN;
+NOTIFY foo, 'a';
+SELECT pg_notify('foo', 'a') IS NULL;
+NOTIFY bar, 'b';
+NOTIFY foo;
+COMMIT`)
+	notifier.expect(
+		"C BEGIN", "C NOTIFY", "T", "D t", "C SELECT 1", "C NOTIFY", "C NOTIFY", "C COMMIT", "Z I",
+	)
+	pid := notifier.processID
+	for _, expected := range []string{
+		fmt.Sprintf("%d foo a", pid),
+		fmt.Sprintf("%d foo ", pid),
+	} {
+		if n := listener.receiveNotification(); n != expected {
+			t.Fatalf("expected notification %q, got %q", expected, n)
+		}
+	}
+
+	// A session receives its own notifications, before the end of the
+	// command which committed them.
+	listener.send(clientMsgSimpleQuery, "NOTIFY foo, 'self'")
+	listener.expect("C NOTIFY", "A foo self", "Z I")
+
+	// EXPLAIN doesn't send the notification.
+	listener.send(clientMsgSimpleQuery, "EXPLAIN NOTIFY foo, 'explained'")
+	listener.expect("T", "D 0", "D 0", "C SELECT 2", "Z I")
+
+	// LISTEN and UNLISTEN take effect when their transaction commits.
+	listener.send(clientMsgSimpleQuery, "BEGIN; LISTEN bar; ROLLBACK")
+	listener.expect("C BEGIN", "C LISTEN", "C ROLLBACK", "Z I")
+	listener.send(clientMsgSimpleQuery, "NOTIFY bar")
+	listener.expect("C NOTIFY", "Z I")
+	listener.send(clientMsgSimpleQuery, "BEGIN; UNLISTEN *; NOTIFY foo; COMMIT")
+	listener.expect("C BEGIN", "C UNLISTEN", "C NOTIFY", "C COMMIT", "Z I")
+}
+
+// TestNotices verifies that the notices are sent with the results of the
+// statement which raised them, unless client_min_messages filters them out.
+func TestNotices(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	s, db, _ := serverutils.StartServer(t, base.TestServerArgs{Insecure: true})
+	defer s.Stopper().Stop(context.TODO())
+
+	if _, err := db.Exec(`CREATE DATABASE d`); err != nil {
+		t.Fatal(err)
+	}
+
+	c := newTestClient(t, s.ServingAddr())
+	defer c.conn.Close()
+
+	c.send(clientMsgSimpleQuery, "DROP TABLE IF EXISTS d.foo, d.bar")
+	c.expect(
+		`N NOTICE 00000 table "d.foo" does not exist, skipping`,
+		`N NOTICE 00000 table "d.bar" does not exist, skipping`,
+		"C DROP TABLE", "Z I",
+	)
+
+	// The INFO notices are sent regardless of client_min_messages.
+	c.send(clientMsgSimpleQuery,
+		"SELECT crdb_internal.notice('info', 'i') + crdb_internal.notice('debug', 'd')")
+	c.expect("N INFO 00000 i", "T", "D 0", "C SELECT 1", "Z I")
+
+	c.send(clientMsgSimpleQuery, "SET client_min_messages = warning")
+	c.expect("C SET", "Z I")
+	c.send(clientMsgSimpleQuery, `DROP DATABASE IF EXISTS e;
+SELECT crdb_internal.notice('warning', 'w') + crdb_internal.notice('n')`)
+	c.expect("C DROP DATABASE", "N WARNING 01000 w", "T", "D 0", "C SELECT 1", "Z I")
+
+	c.send(clientMsgSimpleQuery, "SET client_min_messages = debug")
+	c.expect("C SET", "Z I")
+	c.send(clientMsgSimpleQuery, "SELECT crdb_internal.notice('debug', 'd')")
+	c.expect("N DEBUG 00000 d", "T", "D 0", "C SELECT 1", "Z I")
+
+	// The notices of the parallelized statements are sent with the results of
+	// the statement which waits for them.
+	c.send(clientMsgSimpleQuery, "CREATE TABLE d.t (x INT)")
+	c.expect("C CREATE TABLE", "Z I")
+	c.send(clientMsgSimpleQuery, `BEGIN;
+INSERT INTO d.t VALUES (crdb_internal.notice('p')) RETURNING NOTHING;
+COMMIT`)
+	c.expect("C BEGIN", "C INSERT 0 0", "N NOTICE 00000 p", "C COMMIT", "Z I")
+}
+
+// TestStatementTimeouts verifies that the statements running for longer than
+// statement_timeout are canceled, and that the sessions idle in a transaction
+// for longer than idle_in_transaction_session_timeout are terminated.
+func TestStatementTimeouts(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	s, _, _ := serverutils.StartServer(t, base.TestServerArgs{Insecure: true})
+	defer s.Stopper().Stop(context.TODO())
+
+	c := newTestClient(t, s.ServingAddr())
+	defer c.conn.Close()
+
+	c.send(clientMsgSimpleQuery, "SET statement_timeout = 100")
+	c.expect("C SET", "Z I")
+	c.send(clientMsgSimpleQuery, "SELECT * FROM generate_series(1, 100000000)")
+	for {
+		typ, _, err := c.readBuf.readTypedMsg(c.rd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if serverMessageType(typ) == serverMsgErrorResponse {
+			if msg := string(c.readBuf.msg); !strings.Contains(msg, "statement timeout") {
+				t.Fatalf("expected a statement timeout error, got %q", msg)
+			}
+			break
+		}
+	}
+	c.expect("Z I")
+
+	// Statements running for less than the timeout are unaffected, and so are
+	// the sessions idle outside of a transaction.
+	c.send(clientMsgSimpleQuery, "SET idle_in_transaction_session_timeout = 200")
+	c.expect("C SET", "Z I")
+	time.Sleep(500 * time.Millisecond)
+	c.send(clientMsgSimpleQuery, "BEGIN; SELECT 1")
+	c.expect("C BEGIN", "T", "D 1", "C SELECT 1", "Z T")
+
+	// The session idle in its transaction is terminated.
+	typ, _, err := c.readBuf.readTypedMsg(c.rd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg := string(c.readBuf.msg); serverMessageType(typ) != serverMsgErrorResponse ||
+		!strings.Contains(msg, pgerror.CodeIdleInTransactionSessionTimeoutError) {
+		t.Fatalf("expected an idle-in-transaction timeout error, got %s %q", serverMessageType(typ), msg)
+	}
+	if _, _, err := c.readBuf.readTypedMsg(c.rd); err != io.EOF {
+		t.Fatalf("expected the connection to be closed, got %v", err)
+	}
+}
+
+// TestLockTimeout verifies that the statements waiting for a conflicting
+// transaction for longer than lock_timeout fail with lock_not_available.
+func TestLockTimeout(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	s, _, _ := serverutils.StartServer(t, base.TestServerArgs{Insecure: true})
+	defer s.Stopper().Stop(context.TODO())
+
+	holder := newTestClient(t, s.ServingAddr())
+	defer holder.conn.Close()
+	holder.send(clientMsgSimpleQuery, `CREATE DATABASE d;
+CREATE TABLE d.t (k INT PRIMARY KEY, v INT);
+INSERT INTO d.t VALUES (1, 1)`)
+	holder.expect("C CREATE DATABASE", "C CREATE TABLE", "C INSERT 0 1", "Z I")
+	// The high priority of the transaction holding the intent makes the other
+	// transactions wait for it instead of aborting it.
+	holder.send(clientMsgSimpleQuery, "BEGIN TRANSACTION PRIORITY HIGH; UPDATE d.t SET v = 2 WHERE k = 1")
+	holder.expect("C BEGIN", "C UPDATE 1", "Z T")
+
+	c := newTestClient(t, s.ServingAddr())
+	defer c.conn.Close()
+	c.send(clientMsgSimpleQuery, "SET lock_timeout = 100")
+	c.expect("C SET", "Z I")
+	c.send(clientMsgSimpleQuery, "UPDATE d.t SET v = 3 WHERE k = 1")
+	typ, _, err := c.readBuf.readTypedMsg(c.rd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg := string(c.readBuf.msg); serverMessageType(typ) != serverMsgErrorResponse ||
+		!strings.Contains(msg, pgerror.CodeLockNotAvailableError) {
+		t.Fatalf("expected a lock timeout error, got %s %q", serverMessageType(typ), msg)
+	}
+	c.expect("Z I")
+
+	// Once the conflicting transaction finishes, the statement succeeds.
+	holder.send(clientMsgSimpleQuery, "COMMIT")
+	holder.expect("C COMMIT", "Z I")
+	c.send(clientMsgSimpleQuery, "UPDATE d.t SET v = 3 WHERE k = 1")
+	c.expect("C UPDATE 1", "Z I")
+}
+
+// TestPasswordAuthMethods verifies that clients authenticate with the
+// strongest accepted password authentication method for which the user has
+// credentials, and that the connections of the clients which fail to
+// authenticate are closed.
+func TestPasswordAuthMethods(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	s, db, _ := serverutils.StartServer(t, base.TestServerArgs{})
+	defer s.Stopper().Stop(context.TODO())
+
+	if _, err := db.Exec(`CREATE USER foo WITH PASSWORD 'pencil'`); err != nil {
+		t.Fatal(err)
+	}
+	// A user whose password was set before the SCRAM and MD5 credentials
+	// were stored.
+	if _, err := db.Exec(`
+CREATE USER bar WITH PASSWORD 'pencil';
+UPDATE system.users SET "scramCredentials" = NULL, "md5Password" = NULL WHERE username = 'bar';
+`); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		methods  string
+		user     string
+		password string
+		authType int32
+		err      string
+	}{
+		{"scram-sha-256,md5,password", "foo", "pencil", authSASL, ""},
+		{"scram-sha-256,md5,password", "foo", "pen", authSASL, "invalid password"},
+		{"scram-sha-256,md5,password", "bar", "pencil", authCleartextPassword, ""},
+		{"md5,password", "foo", "pencil", authMD5Password, ""},
+		{"md5,password", "foo", "pen", authMD5Password, "invalid password"},
+		{"password", "foo", "pencil", authCleartextPassword, ""},
+		{"password", "foo", "pen", authCleartextPassword, "invalid password"},
+		{"scram-sha-256", "bar", "", 0, "no credentials for the accepted password authentication methods"},
+	}
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%s/%s/%s", tc.methods, tc.user, tc.password), func(t *testing.T) {
+			if _, err := db.Exec(
+				`SET CLUSTER SETTING server.password_authentication.methods = $1`, tc.methods,
+			); err != nil {
+				t.Fatal(err)
+			}
+			testutils.SucceedsSoon(t, func() error {
+				if v := passwordAuthMethods.Get(&s.ClusterSettings().SV); v != tc.methods {
+					return errors.Errorf("setting is still %q", v)
+				}
+				return nil
+			})
+
+			c := newTLSTestClient(t, s.ServingAddr(), tc.user)
+			defer c.conn.Close()
+			authType, err := c.receiveAuth()
+			if err == nil {
+				if authType != tc.authType {
+					t.Fatalf("expected authentication request %d, got %d", tc.authType, authType)
+				}
+				switch authType {
+				case authCleartextPassword:
+					c.send(clientMsgPassword, tc.password)
+				case authMD5Password:
+					salt := string(c.readBuf.msg)
+					c.send(clientMsgPassword, md5Hex(md5Hex(tc.password + tc.user)[3:]+salt))
+				case authSASL:
+					err = c.authenticateSCRAM(tc.password)
+				}
+			}
+			if err == nil {
+				authType, err = c.receiveAuth()
+			}
+			if !testutils.IsError(err, tc.err) {
+				t.Fatalf("expected error %q, got %v", tc.err, err)
+			}
+			if err != nil {
+				// A client which ignores the error can't run queries.
+				c.expectClosed()
+			} else if authType != authOK {
+				t.Fatalf("expected authentication to succeed, got request %d", authType)
+			}
+		})
+	}
+}
+
+// TestHostBasedAuthentication verifies that the host-based authentication
+// rules decide how clients authenticate, and that the rejected connections
+// are counted and closed.
+func TestHostBasedAuthentication(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	s, db, _ := serverutils.StartServer(t, base.TestServerArgs{})
+	defer s.Stopper().Stop(context.TODO())
+
+	if _, err := db.Exec(`CREATE USER foo WITH PASSWORD 'pencil'`); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first rule lets the test connect as root.
+	const rootRule = "host all root all cert\n"
+	testCases := []struct {
+		conf string
+		err  string
+	}{
+		{"host all foo all reject", "rejected by the host-based authentication rule at line 2"},
+		{"host all foo 10.0.0.0/8 password", "no matching host-based authentication rule"},
+		{"host all foo all cert", "the host-based authentication rule at line 2 requires a client certificate"},
+		{"host system foo all reject\nhost all foo 127.0.0.0/8 password\nhost all all all reject", ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.conf, func(t *testing.T) {
+			conf := rootRule + tc.conf
+			if _, err := db.Exec(
+				`SET CLUSTER SETTING server.host_based_authentication.configuration = $1`, conf,
+			); err != nil {
+				t.Fatal(err)
+			}
+			testutils.SucceedsSoon(t, func() error {
+				if v := hbaConfSetting.Get(&s.ClusterSettings().SV); v != conf {
+					return errors.Errorf("setting is still %q", v)
+				}
+				return nil
+			})
+
+			c := newTLSTestClient(t, s.ServingAddr(), "foo")
+			defer c.conn.Close()
+			authType, err := c.receiveAuth()
+			if err == nil {
+				if authType != authSASL {
+					t.Fatalf("expected authentication request %d, got %d", authSASL, authType)
+				}
+				if err = c.authenticateSCRAM("pencil"); err == nil {
+					authType, err = c.receiveAuth()
+				}
+			}
+			if !testutils.IsError(err, tc.err) {
+				t.Fatalf("expected error %q, got %v", tc.err, err)
+			}
+			if err != nil {
+				c.expectClosed()
+			} else if authType != authOK {
+				t.Fatalf("expected authentication to succeed, got request %d", authType)
+			}
+		})
+	}
+
+	if count := s.MustGetSQLNetworkCounter(MetaConnsDenied.Name); count != 3 {
+		t.Fatalf("expected 3 rejected connections, got %d", count)
+	}
+
+	// The rejections are recorded in the event log, at most once per
+	// connDeniedEventInterval: the first one is always recorded.
+	var info string
+	if err := db.QueryRow(
+		`SELECT info FROM system.eventlog WHERE "eventType" = $1 ORDER BY timestamp LIMIT 1`,
+		string(sql.EventLogConnectionDenied),
+	).Scan(&info); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		`"User":"foo"`,
+		`"Reason":"rejected by the host-based authentication rule at line 2"`,
+		`"DeniedConnections":1`,
+	} {
+		if !strings.Contains(info, expected) {
+			t.Fatalf("expected %s in the event info, got %s", expected, info)
+		}
+	}
+
+	// Invalid configurations are rejected.
+	if _, err := db.Exec(
+		`SET CLUSTER SETTING server.host_based_authentication.configuration = 'host all all all trust'`,
+	); !testutils.IsError(err, `unknown authentication method "trust"`) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// TestHostBasedAuthenticationInsecure verifies that the host-based
+// authentication rules apply to the connections of insecure servers.
+func TestHostBasedAuthenticationInsecure(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	s, db, _ := serverutils.StartServer(t, base.TestServerArgs{Insecure: true})
+	defer s.Stopper().Stop(context.TODO())
+
+	// The first rule lets the test connect as root.
+	const rootRule = "host all root all cert-password\n"
+	testCases := []struct {
+		conf string
+		err  string
+	}{
+		{"host all foo all reject", "rejected by the host-based authentication rule at line 2"},
+		{"host all foo 10.0.0.0/8 password", "no matching host-based authentication rule"},
+		{"hostssl all foo all password", "no matching host-based authentication rule"},
+		{"hostnossl all foo all cert", "the host-based authentication rule at line 2 requires a client certificate"},
+		{"hostnossl all foo 127.0.0.0/8 password", ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.conf, func(t *testing.T) {
+			conf := rootRule + tc.conf
+			if _, err := db.Exec(
+				`SET CLUSTER SETTING server.host_based_authentication.configuration = $1`, conf,
+			); err != nil {
+				t.Fatal(err)
+			}
+			testutils.SucceedsSoon(t, func() error {
+				if v := hbaConfSetting.Get(&s.ClusterSettings().SV); v != conf {
+					return errors.Errorf("setting is still %q", v)
+				}
+				return nil
+			})
+
+			conn, err := net.Dial("tcp", s.ServingAddr())
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := makeTestClient(t, conn)
+			defer c.conn.Close()
+			c.sendStartup("foo")
+			authType, err := c.receiveAuth()
+			if !testutils.IsError(err, tc.err) {
+				t.Fatalf("expected error %q, got %v", tc.err, err)
+			}
+			if err != nil {
+				c.expectClosed()
+			} else if authType != authOK {
+				t.Fatalf("expected authentication to succeed, got request %d", authType)
+			}
+		})
+	}
+
+	if count := s.MustGetSQLNetworkCounter(MetaConnsDenied.Name); count != 4 {
+		t.Fatalf("expected 4 rejected connections, got %d", count)
+	}
+}
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return "md5" + hex.EncodeToString(sum[:])
+}
+
+// authenticateSCRAM runs the client side of a SCRAM-SHA-256 exchange, after
+// the SASL authentication request.
+func (c *testClient) authenticateSCRAM(password string) error {
+	mechanisms := string(c.readBuf.msg)
+	if expected := security.ScramMechanism + "\x00\x00"; mechanisms != expected {
+		c.t.Fatalf("expected mechanisms %q, got %q", expected, mechanisms)
+	}
+	const clientFirstBare = "n=,r=rOprNGfwEbeRWgbNEkqO"
+	clientFirst := "n,," + clientFirstBare
+	c.send(clientMsgPassword, security.ScramMechanism, int32(len(clientFirst)), []byte(clientFirst))
+	authType, err := c.receiveAuth()
+	if err != nil {
+		return err
+	}
+	if authType != authSASLContinue {
+		c.t.Fatalf("expected authentication request %d, got %d", authSASLContinue, authType)
+	}
+
+	serverFirst := string(c.readBuf.msg)
+	var nonce, salt string
+	var iterations int
+	if _, err := fmt.Sscanf(
+		strings.Replace(serverFirst, ",", " ", -1), "r=%s s=%s i=%d", &nonce, &salt, &iterations,
+	); err != nil {
+		c.t.Fatal(err)
+	}
+	saltBytes, err := base64.StdEncoding.DecodeString(salt)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	// SaltedPassword is PBKDF2 with HMAC-SHA-256 and a single output block.
+	mac := func(key []byte, msg string) []byte {
+		h := hmac.New(sha256.New, key)
+		_, _ = h.Write([]byte(msg))
+		return h.Sum(nil)
+	}
+	u := mac([]byte(password), string(saltBytes)+"\x00\x00\x00\x01")
+	saltedPassword := append([]byte(nil), u...)
+	for i := 1; i < iterations; i++ {
+		u = mac([]byte(password), string(u))
+		for j := range saltedPassword {
+			saltedPassword[j] ^= u[j]
+		}
+	}
+	clientKey := mac(saltedPassword, "Client Key")
+	storedKey := sha256.Sum256(clientKey)
+
+	withoutProof := "c=biws,r=" + nonce
+	authMessage := clientFirstBare + "," + serverFirst + "," + withoutProof
+	proof := mac(storedKey[:], authMessage)
+	for i := range proof {
+		proof[i] ^= clientKey[i]
+	}
+	c.send(clientMsgPassword, []byte(withoutProof+",p="+base64.StdEncoding.EncodeToString(proof)))
+	if authType, err = c.receiveAuth(); err != nil {
+		return err
+	}
+	if authType != authSASLFinal {
+		c.t.Fatalf("expected authentication request %d, got %d", authSASLFinal, authType)
+	}
+	serverSignature := mac(mac(saltedPassword, "Server Key"), authMessage)
+	if expected := "v=" + base64.StdEncoding.EncodeToString(serverSignature); string(c.readBuf.msg) != expected {
+		c.t.Fatalf("expected server signature %q, got %q", expected, c.readBuf.msg)
+	}
+	return nil
+}
+
+// TestExecuteRowLimit verifies that Execute messages with a row limit suspend
+// the portal, that the SELECT statements of explicit transactions are only run
+// up to the limit, and that suspended portals are destroyed at the end of
+// their transaction.
+func TestExecuteRowLimit(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	s, db, _ := serverutils.StartServer(t, base.TestServerArgs{Insecure: true})
+	defer s.Stopper().Stop(context.TODO())
+
+	if _, err := db.Exec(`
+CREATE DATABASE d;
+CREATE TABLE d.t (a INT PRIMARY KEY);
+INSERT INTO d.t VALUES (1), (2), (3), (4), (5);
+`); err != nil {
+		t.Fatal(err)
+	}
+
+	c := newTestClient(t, s.ServingAddr())
+	defer c.conn.Close()
+
+	c.send(clientMsgParse, "s", "SELECT a FROM d.t ORDER BY a", int16(0))
+	c.send(clientMsgBind, "p", "s", int16(0), int16(0), int16(0))
+	c.send(clientMsgExecute, "p", int32(2))
+	c.send(clientMsgExecute, "p", int32(2))
+	c.send(clientMsgExecute, "p", int32(2))
+	c.send(clientMsgExecute, "p", int32(2))
+	c.send(clientMsgSync)
+	c.expect(
+		"1", "2",
+		"D 1", "D 2", "s",
+		"D 3", "D 4", "s",
+		"D 5", "C SELECT 1",
+		"C SELECT 0",
+		"Z I",
+	)
+
+	// The portal was destroyed at the end of the implicit transaction.
+	c.send(clientMsgExecute, "p", int32(2))
+	c.send(clientMsgSync)
+	c.expect("E", "Z I")
+
+	// A limit over the number of rows doesn't suspend the portal.
+	c.send(clientMsgBind, "p", "s", int16(0), int16(0), int16(0))
+	c.send(clientMsgExecute, "p", int32(6))
+	c.send(clientMsgClose, byte(preparePortal), "p")
+	c.send(clientMsgSync)
+	c.expect("2", "D 1", "D 2", "D 3", "D 4", "D 5", "C SELECT 5", "3", "Z I")
+
+	// In an explicit transaction, the portal survives Sync messages.
+	c.send(clientMsgSimpleQuery, "BEGIN")
+	c.expect("C BEGIN", "Z T")
+	c.send(clientMsgBind, "p", "s", int16(0), int16(0), int16(0))
+	c.send(clientMsgExecute, "p", int32(1))
+	c.send(clientMsgSync)
+	c.expect("2", "D 1", "s", "Z T")
+	c.send(clientMsgExecute, "p", int32(0))
+	c.send(clientMsgSync)
+	c.expect("D 2", "D 3", "D 4", "D 5", "C SELECT 4", "Z T")
+	c.send(clientMsgSimpleQuery, "COMMIT")
+	c.expect("C COMMIT", "Z I")
+	c.send(clientMsgExecute, "p", int32(0))
+	c.send(clientMsgSync)
+	c.expect("E", "Z I")
+
+	// A suspended SELECT doesn't compute the rows past the limit: the division
+	// by zero of the third row fails the second Execute.
+	c.send(clientMsgSimpleQuery, "BEGIN")
+	c.expect("C BEGIN", "Z T")
+	c.send(clientMsgParse, "div", "SELECT 6 // (3 - a) FROM d.t ORDER BY a", int16(0))
+	c.send(clientMsgBind, "p", "div", int16(0), int16(0), int16(0))
+	c.send(clientMsgExecute, "p", int32(2))
+	c.send(clientMsgSync)
+	c.expect("1", "2", "D 3", "D 6", "s", "Z T")
+	c.send(clientMsgExecute, "p", int32(2))
+	c.send(clientMsgSync)
+	c.expect("E", "Z E")
+	c.send(clientMsgSimpleQuery, "ROLLBACK")
+	c.expect("C ROLLBACK", "Z I")
+
+	// The other statements run to completion on their first Execute.
+	c.send(clientMsgSimpleQuery, "BEGIN")
+	c.expect("C BEGIN", "Z T")
+	c.send(clientMsgParse, "ins", "INSERT INTO d.t VALUES (6), (7) RETURNING a", int16(0))
+	c.send(clientMsgBind, "p", "ins", int16(0), int16(0), int16(0))
+	c.send(clientMsgExecute, "p", int32(1))
+	c.send(clientMsgSync)
+	c.expect("1", "2", "D 6", "s", "Z T")
+	c.send(clientMsgSimpleQuery, "SELECT count(*) FROM d.t")
+	c.expect("T", "D 7", "C SELECT 1", "Z T")
+	c.send(clientMsgExecute, "p", int32(0))
+	c.send(clientMsgSync)
+	c.expect("D 7", "C INSERT 0 1", "Z T")
+	c.send(clientMsgSimpleQuery, "ROLLBACK")
+	c.expect("C ROLLBACK", "Z I")
+
+	// Closing a suspended portal releases its rows.
+	c.send(clientMsgBind, "p", "s", int16(0), int16(0), int16(0))
+	c.send(clientMsgExecute, "p", int32(4))
+	c.send(clientMsgClose, byte(preparePortal), "p")
+	c.send(clientMsgSync)
+	c.expect("2", "D 1", "D 2", "D 3", "D 4", "s", "3", "Z I")
+}
+
+// TestCopyOut verifies the data sent by COPY ... TO STDOUT in each format.
+func TestCopyOut(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	s, db, _ := serverutils.StartServer(t, base.TestServerArgs{Insecure: true})
+	defer s.Stopper().Stop(context.TODO())
+
+	if _, err := db.Exec(`
+CREATE DATABASE d;
+CREATE TABLE d.t (a INT PRIMARY KEY, b STRING);
+INSERT INTO d.t VALUES (1, 'one'), (2, e'tab\tand "quote"'), (3, NULL);
+`); err != nil {
+		t.Fatal(err)
+	}
+
+	c := newTestClient(t, s.ServingAddr())
+	defer c.conn.Close()
+
+	c.send(clientMsgSimpleQuery, "COPY d.t TO STDOUT")
+	c.expect(
+		"H",
+		"d 1\tone\n", "d 2\ttab\\tand \"quote\"\n", "d 3\t\\N\n",
+		"c", "C COPY 3", "Z I",
+	)
+
+	c.send(clientMsgSimpleQuery, "COPY d.t (b) TO STDOUT WITH (DELIMITER '|', NULL 'null')")
+	c.expect("H", "d one\n", "d tab\\tand \"quote\"\n", "d null\n", "c", "C COPY 3", "Z I")
+
+	c.send(clientMsgSimpleQuery,
+		"COPY (SELECT a, b FROM d.t ORDER BY a) TO STDOUT WITH (FORMAT csv, HEADER)")
+	c.expect(
+		"H",
+		"d a,b\n", "d 1,one\n", "d 2,\"tab\tand \"\"quote\"\"\"\n", "d 3,\n",
+		"c", "C COPY 3", "Z I",
+	)
+
+	c.send(clientMsgSimpleQuery, "COPY (SELECT a FROM d.t WHERE a = 1) TO STDOUT BINARY")
+	c.expect(
+		"H",
+		"d PGCOPY\n\377\r\n\x00\x00\x00\x00\x00\x00\x00\x00\x00",
+		"d \x00\x01\x00\x00\x00\x08\x00\x00\x00\x00\x00\x00\x00\x01",
+		"d \xff\xff",
+		"c", "C COPY 1", "Z I",
+	)
+
+	c.send(clientMsgSimpleQuery, "COPY (SELECT 1 WHERE false) TO STDOUT")
+	c.expect("H", "c", "C COPY 0", "Z I")
+
+	c.send(clientMsgSimpleQuery, "COPY d.t TO STDOUT WITH (HEADER)")
+	c.expect("E", "Z I")
+}
+
+// TestCopyInFormats verifies that COPY ... FROM STDIN accepts data in the CSV
+// and binary formats, split at arbitrary points across CopyData messages.
+func TestCopyInFormats(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	s, db, _ := serverutils.StartServer(t, base.TestServerArgs{Insecure: true})
+	defer s.Stopper().Stop(context.TODO())
+
+	if _, err := db.Exec(`
+CREATE DATABASE d;
+CREATE TABLE d.t (a INT PRIMARY KEY, b STRING);
+`); err != nil {
+		t.Fatal(err)
+	}
+
+	c := newTestClient(t, s.ServingAddr())
+	defer c.conn.Close()
+
+	c.send(clientMsgSimpleQuery, "COPY d.t FROM STDIN WITH CSV HEADER")
+	c.send(clientMsgCopyData, []byte("a,b\n1,\"x"))
+	c.send(clientMsgCopyData, []byte(",\""))
+	c.send(clientMsgCopyData, []byte("\"y\"\"\"\n2,"))
+	c.send(clientMsgCopyData, []byte("\n3,\"\"\n\\.\n"))
+	c.send(clientMsgCopyDone)
+	c.expect("G", "C COPY 3", "Z I")
+
+	var header bytes.Buffer
+	header.WriteString(sql.CopyBinarySignature)
+	header.Write(make([]byte, 8))
+	c.send(clientMsgSimpleQuery, "COPY d.t FROM STDIN WITH (FORMAT binary)")
+	c.send(clientMsgCopyData, header.Bytes(), int16(2), int32(8), []byte{0, 0, 0})
+	c.send(clientMsgCopyData, []byte{0, 0, 0, 0, 4}, int32(1), []byte("z"))
+	c.send(clientMsgCopyData, int16(2), int32(8), []byte{0, 0, 0, 0, 0, 0, 0, 5}, int32(-1))
+	c.send(clientMsgCopyData, int16(-1))
+	c.send(clientMsgCopyDone)
+	c.expect("G", "C COPY 2", "Z I")
+
+	rows, err := db.Query(`SELECT a, b FROM d.t ORDER BY a`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var results []string
+	for rows.Next() {
+		var a int
+		var b gosql.NullString
+		if err := rows.Scan(&a, &b); err != nil {
+			t.Fatal(err)
+		}
+		results = append(results, fmt.Sprintf("%d %q %t", a, b.String, b.Valid))
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		`1 "x,\"y\"" true`,
+		`2 "" false`,
+		`3 "" true`,
+		`4 "z" true`,
+		`5 "" false`,
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Fatalf("expected %q, got %q", expected, results)
+	}
+}
@@ -150,6 +150,7 @@ var _ planNode = &createIndexNode{}
 var _ planNode = &createTableNode{}
 var _ planNode = &createViewNode{}
 var _ planNode = &createSequenceNode{}
+var _ planNode = &declareCursorNode{}
 var _ planNode = &delayedNode{}
 var _ planNode = &deleteNode{}
 var _ planNode = &distinctNode{}
@@ -163,6 +164,7 @@ var _ planNode = &unaryNode{}
 var _ planNode = &explainDistSQLNode{}
 var _ planNode = &explainPlanNode{}
 var _ planNode = &traceNode{}
+var _ planNode = &fetchNode{}
 var _ planNode = &filterNode{}
 var _ planNode = &groupNode{}
 var _ planNode = &hookFnNode{}
@@ -170,6 +172,9 @@ var _ planNode = &indexJoinNode{}
 var _ planNode = &insertNode{}
 var _ planNode = &joinNode{}
 var _ planNode = &limitNode{}
+var _ planNode = &listenNode{}
+var _ planNode = &moveNode{}
+var _ planNode = &notifyNode{}
 var _ planNode = &ordinalityNode{}
 var _ planNode = &testingRelocateNode{}
 var _ planNode = &renderNode{}
@@ -189,6 +194,7 @@ var _ planNode = &dropUserNode{}
 
 var _ planNodeFastPath = &deleteNode{}
 var _ planNodeFastPath = &dropUserNode{}
+var _ planNodeFastPath = &moveNode{}
 
 // makePlan implements the Planner interface.
 func (p *planner) makePlan(ctx context.Context, stmt Statement) (planNode, error) {
@@ -214,6 +220,10 @@ func (p *planner) makePlan(ctx context.Context, stmt Statement) (planNode, error
 		plan.Close(ctx)
 		return nil, err
 	}
+	if p.planCache != nil {
+		p.planCache.finish(ctx)
+		p.planCache = nil
+	}
 
 	if log.V(3) {
 		log.Infof(ctx, "statement %s compiled to:\n%s", stmt, planToString(ctx, plan))
@@ -338,6 +348,8 @@ func (p *planner) newPlan(
 		return p.AlterSequence(ctx, n)
 	case *tree.AlterUserSetPassword:
 		return p.AlterUserSetPassword(ctx, n)
+	case *tree.AlterUserSetConnLimit:
+		return p.AlterUserSetConnLimit(ctx, n)
 	case *tree.BeginTransaction:
 		return p.BeginTransaction(n)
 	case *tree.CancelQuery:
@@ -350,6 +362,8 @@ func (p *planner) newPlan(
 		return p.CopyData(ctx, n)
 	case *tree.CopyFrom:
 		return p.CopyFrom(ctx, n)
+	case *tree.CopyTo:
+		return p.CopyTo(ctx, n)
 	case *tree.CreateDatabase:
 		return p.CreateDatabase(n)
 	case *tree.CreateIndex:
@@ -362,8 +376,12 @@ func (p *planner) newPlan(
 		return p.CreateView(ctx, n)
 	case *tree.CreateSequence:
 		return p.CreateSequence(ctx, n)
+	case *tree.CloseCursor:
+		return p.CloseCursor(ctx, n)
 	case *tree.Deallocate:
 		return p.Deallocate(ctx, n)
+	case *tree.DeclareCursor:
+		return p.DeclareCursor(ctx, n)
 	case *tree.Delete:
 		return p.Delete(ctx, n, desiredTypes)
 	case *tree.Discard:
@@ -384,10 +402,18 @@ func (p *planner) newPlan(
 		return p.Execute(ctx, n)
 	case *tree.Explain:
 		return p.Explain(ctx, n)
+	case *tree.FetchCursor:
+		return p.FetchCursor(ctx, n)
 	case *tree.Grant:
 		return p.Grant(ctx, n)
 	case *tree.Insert:
 		return p.Insert(ctx, n, desiredTypes)
+	case *tree.Listen:
+		return p.Listen(ctx, n)
+	case *tree.MoveCursor:
+		return p.MoveCursor(ctx, n)
+	case *tree.Notify:
+		return p.Notify(ctx, n)
 	case *tree.ParenSelect:
 		return p.newPlan(ctx, n.Select, desiredTypes)
 	case *tree.PauseJob:
@@ -470,6 +496,8 @@ func (p *planner) newPlan(
 		return p.Truncate(ctx, n)
 	case *tree.UnionClause:
 		return p.UnionClause(ctx, n, desiredTypes)
+	case *tree.Unlisten:
+		return p.Unlisten(ctx, n)
 	case *tree.Update:
 		return p.Update(ctx, n, desiredTypes)
 	case *tree.ValuesClause:
@@ -492,6 +520,8 @@ func (p *planner) prepare(ctx context.Context, stmt tree.Statement) (planNode, e
 	switch n := stmt.(type) {
 	case *tree.AlterUserSetPassword:
 		return p.AlterUserSetPassword(ctx, n)
+	case *tree.AlterUserSetConnLimit:
+		return p.AlterUserSetConnLimit(ctx, n)
 	case *tree.CancelQuery:
 		return p.CancelQuery(ctx, n)
 	case *tree.CancelJob:
@@ -504,6 +534,8 @@ func (p *planner) prepare(ctx context.Context, stmt tree.Statement) (planNode, e
 		return p.DropUser(ctx, n)
 	case *tree.Explain:
 		return p.Explain(ctx, n)
+	case *tree.FetchCursor:
+		return p.FetchCursor(ctx, n)
 	case *tree.Insert:
 		return p.Insert(ctx, n, nil)
 	case *tree.PauseJob:
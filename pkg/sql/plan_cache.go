@@ -0,0 +1,546 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/sql/privilege"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/util/cache"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+)
+
+// The plan cache speeds up the executions of prepared statements by reusing
+// the plans built for their previous executions.
+//
+// Like the plan cache of PostgreSQL, the first executions of a statement are
+// planned from scratch, yielding "custom" plans that take the values of the
+// placeholders into account. If all the custom plans select the same indexes,
+// the following executions use a "generic" plan, which selects the cached
+// index of each scan whatever the values of the placeholders.
+//
+// When the statement reads a single table through a covering index, without
+// ordering or limit, the generic plan is built once, with unevaluated
+// placeholders, and reused by the following executions: an execution only
+// derives the spans and the filter of the scan from the values of its
+// placeholders, skipping name resolution, type checking and optimization. The
+// reused plan is executed locally.
+//
+// For the other statements, each execution still builds its plan nodes, since
+// they hold their execution state, but index selection only considers the
+// cached index of each scan. A generic plan is abandoned for a scan, which
+// then considers all indexes again, when the estimated cost of the cached
+// index for the values of the placeholders is much higher than the average
+// cost of the custom plans.
+//
+// The cached plan of a statement is keyed on the statement and the types of
+// its placeholders, and depends on the versions of the descriptors of the
+// tables it reads: it is invalidated when one of them changes, for example
+// when an index is added or dropped.
+//
+// The cache belongs to the session rather than the node: the plans are keyed
+// by the prepared statements of the session, which other sessions cannot
+// execute, and a per-session cache needs no synchronization.
+
+var planCacheEnabled = settings.RegisterBoolSetting(
+	"sql.plan_cache.enabled",
+	"set to true to reuse the plans of prepared statements across executions",
+	true,
+)
+
+const (
+	// planCacheSize is the number of statements whose plans are cached by
+	// each session.
+	planCacheSize = 128
+	// planCacheCustomPlans is the number of custom plans that are built for a
+	// statement before a generic plan is considered.
+	planCacheCustomPlans = 5
+	// genericPlanCostFactor is how much more expensive than the average cost
+	// of the custom plans the index cached for a scan can be before the
+	// generic plan is abandoned for that scan.
+	genericPlanCostFactor = 2
+)
+
+// planCache caches the plans of the prepared statements of a session. It is
+// keyed by the statement and the types of its placeholders.
+type planCache struct {
+	cache *cache.UnorderedCache
+}
+
+// makePlanCache creates a planCache. The generic plans of the statements
+// evicted from the cache are closed with the given context.
+func makePlanCache(ctx context.Context) planCache {
+	return planCache{
+		cache: cache.NewUnorderedCache(cache.Config{
+			Policy: cache.CacheLRU,
+			ShouldEvict: func(s int, key, value interface{}) bool {
+				return s > planCacheSize
+			},
+			OnEvicted: func(key, value interface{}) {
+				value.(*cachedPlan).reset(ctx)
+			},
+		}),
+	}
+}
+
+// planCacheKey identifies a cached plan.
+type planCacheKey struct {
+	stmt string
+	// placeholderTypes is the formatted list of the types of the placeholders.
+	placeholderTypes string
+}
+
+func makePlanCacheKey(stmt *PreparedStatement) planCacheKey {
+	names := make([]string, 0, len(stmt.Types))
+	for name := range stmt.Types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var buf bytes.Buffer
+	for _, name := range names {
+		buf.WriteString(name)
+		buf.WriteByte(':')
+		buf.WriteString(stmt.Types[name].String())
+		buf.WriteByte(',')
+	}
+	return planCacheKey{stmt: stmt.Str, placeholderTypes: buf.String()}
+}
+
+// get returns the cached plan of a prepared statement, creating an empty one
+// if the statement isn't cached.
+func (pc *planCache) get(stmt *PreparedStatement) *cachedPlan {
+	key := makePlanCacheKey(stmt)
+	if v, ok := pc.cache.Get(key); ok {
+		return v.(*cachedPlan)
+	}
+	plan := &cachedPlan{}
+	pc.cache.Add(key, plan)
+	return plan
+}
+
+// drop removes the cached plan of a prepared statement, closing its generic
+// plan. It is called when the statement is deallocated; the other prepared
+// statements with the same key, if any, plan their next executions from
+// scratch.
+func (pc *planCache) drop(stmt *PreparedStatement) {
+	pc.cache.Del(makePlanCacheKey(stmt))
+}
+
+// clear removes all the cached plans, closing their generic plans.
+func (pc *planCache) clear() {
+	pc.cache.Clear()
+}
+
+// cachedPlan records the decisions made while planning a prepared statement.
+type cachedPlan struct {
+	// scans contains the decisions made for the scans of the statement, in
+	// the order in which their index was selected.
+	scans []cachedScan
+	// numCustomPlans is the number of custom plans built for the statement.
+	numCustomPlans int
+	// generic is set when the custom plans agreed on the selected indexes;
+	// the following executions use a generic plan.
+	generic bool
+	// unstable is set when the custom plans disagreed on the selected indexes;
+	// the following executions use custom plans.
+	unstable bool
+	// genericPlan is the plan reused by the executions of the statement once
+	// generic is set, if any.
+	genericPlan *genericPlan
+	// noGenericPlan is set when the generic plan of the statement cannot be
+	// reused.
+	noGenericPlan bool
+	// genericExecs is the number of executions of genericPlan.
+	genericExecs int
+}
+
+// cachedScan records the index selected for a scan.
+type cachedScan struct {
+	tableID sqlbase.ID
+	// version is the version of the table descriptor at the time the index
+	// was selected.
+	version sqlbase.DescriptorVersion
+	// indexID is the ID of the selected index. It is 0 if no index was
+	// selected, for example because the filter of the scan is always false.
+	indexID sqlbase.IndexID
+	// totalCost is the sum of the estimated costs of the selected index in
+	// the custom plans, and numCosts the number of costs in the sum.
+	totalCost float64
+	numCosts  int
+}
+
+func (s *cachedScan) avgCost() float64 {
+	return s.totalCost / float64(s.numCosts)
+}
+
+// reset discards all the decisions recorded for the statement, and closes its
+// generic plan.
+func (cp *cachedPlan) reset(ctx context.Context) {
+	if cp.genericPlan != nil {
+		cp.genericPlan.close(ctx)
+	}
+	*cp = cachedPlan{}
+}
+
+// planCacheState is the state of the planner with regard to the plan cache,
+// while it plans a prepared statement.
+type planCacheState struct {
+	plan *cachedPlan
+	// scans records the decisions made for the scans of the statement.
+	scans []cachedScan
+	// invalidated is set when the decisions cached for one of the scans were
+	// invalidated by a schema change.
+	invalidated bool
+
+	// building is set while the generic plan of the statement is built.
+	building bool
+	// deps records the versions of the descriptors used by the generic plan.
+	deps []descVersion
+	// reusable is cleared when the generic plan uses descriptors that aren't
+	// tables, like views, whose versions aren't checked by its executions.
+	reusable bool
+	// genericScans records the scans of the generic plan.
+	genericScans []genericScan
+}
+
+// startScan records the start of the index selection of a scan and returns
+// its index in scans. If the statement uses a generic plan and the decision
+// cached for the scan is still valid, it also returns that decision.
+func (st *planCacheState) startScan(desc *sqlbase.TableDescriptor) (int, *cachedScan) {
+	st.scans = append(st.scans, cachedScan{tableID: desc.ID, version: desc.Version})
+	idx := len(st.scans) - 1
+	if st.invalidated || idx >= len(st.plan.scans) {
+		return idx, nil
+	}
+	cached := &st.plan.scans[idx]
+	if cached.tableID != desc.ID || cached.version != desc.Version {
+		st.invalidated = true
+		return idx, nil
+	}
+	if !st.plan.generic || cached.indexID == 0 {
+		return idx, nil
+	}
+	return idx, cached
+}
+
+// selectIndex records the index selected for a scan.
+func (st *planCacheState) selectIndex(idx int, indexID sqlbase.IndexID, cost float64) {
+	st.scans[idx].indexID = indexID
+	st.scans[idx].totalCost = cost
+}
+
+// addDependency records a descriptor used by the generic plan.
+func (st *planCacheState) addDependency(desc *sqlbase.TableDescriptor) {
+	if !st.building {
+		return
+	}
+	if !desc.IsPhysicalTable() {
+		st.reusable = false
+	}
+	st.deps = append(st.deps, descVersion{id: desc.ID, version: desc.Version})
+}
+
+// finish updates the cached plan once the statement has been planned.
+func (st *planCacheState) finish(ctx context.Context) {
+	if st.building {
+		return
+	}
+	cp := st.plan
+	if st.invalidated {
+		// The plan built for this execution is the first custom plan for the
+		// new version of the schema.
+		cp.reset(ctx)
+	}
+	if cp.generic || cp.unstable {
+		return
+	}
+	for i := range st.scans {
+		if st.scans[i].indexID != 0 {
+			st.scans[i].numCosts = 1
+		}
+	}
+	if cp.numCustomPlans == 0 {
+		cp.scans = st.scans
+	} else if !cp.merge(st.scans) {
+		cp.unstable = true
+		cp.scans = nil
+		return
+	}
+	cp.numCustomPlans++
+	cp.generic = cp.numCustomPlans >= planCacheCustomPlans
+}
+
+// merge adds the decisions made by a custom plan to the cached decisions. It
+// returns false if they differ.
+func (cp *cachedPlan) merge(scans []cachedScan) bool {
+	if len(scans) != len(cp.scans) {
+		return false
+	}
+	for i := range scans {
+		s, cached := &scans[i], &cp.scans[i]
+		if s.tableID != cached.tableID || s.version != cached.version {
+			return false
+		}
+		if s.indexID == 0 {
+			continue
+		}
+		if cached.indexID != 0 && cached.indexID != s.indexID {
+			return false
+		}
+		cached.indexID = s.indexID
+		cached.totalCost += s.totalCost
+		cached.numCosts++
+	}
+	return true
+}
+
+// usePlanCache sets up the planner to use the plan cache of the session for
+// the planning of a prepared statement.
+func (p *planner) usePlanCache(stmt *PreparedStatement) {
+	if !planCacheEnabled.Get(&p.session.execCfg.Settings.SV) {
+		return
+	}
+	p.planCache = &planCacheState{plan: p.session.planCache.get(stmt)}
+}
+
+// errNoGenericPlan is returned while building the generic plan of a statement
+// whose scans cannot use their cached index without the values of the
+// placeholders.
+var errNoGenericPlan = errors.New("no generic plan")
+
+// genericPlan is a plan of a prepared statement with unevaluated placeholders,
+// which is reused by its executions.
+type genericPlan struct {
+	// p is the planner that built the plan, which is referenced by its nodes.
+	// It is rebound to the planner of every execution.
+	p    *planner
+	plan planNode
+	// scan is the scan of the plan, whose spans and filter depend on the
+	// values of the placeholders.
+	scan genericScan
+	// deps are the versions of the descriptors used by the plan.
+	deps []descVersion
+	// database and searchPath are the settings of the session that resolved
+	// the names of the statement.
+	database   string
+	searchPath string
+}
+
+// genericScan records a scan of a generic plan.
+type genericScan struct {
+	n *scanNode
+	// filter is the filter of the scan, with unevaluated placeholders.
+	filter tree.TypedExpr
+	// spans are the spans of the whole index.
+	spans []roachpb.Span
+}
+
+// descVersion identifies a version of a descriptor.
+type descVersion struct {
+	id      sqlbase.ID
+	version sqlbase.DescriptorVersion
+}
+
+// genericPlan returns the generic plan of the statement planned by p, bound to
+// the values of its placeholders, building the plan if needed. It returns nil
+// if the statement has no reusable generic plan, in which case it is planned
+// as usual. empty is set if the plan produces no rows for the values of the
+// placeholders.
+func (st *planCacheState) genericPlan(
+	ctx context.Context, p *planner, stmt Statement,
+) (gp *genericPlan, empty bool, err error) {
+	cp := st.plan
+	if !cp.generic || cp.noGenericPlan || p.avoidCachedDescriptors {
+		return nil, false, nil
+	}
+	if cp.genericPlan == nil {
+		if cp.genericPlan = p.makeGenericPlan(ctx, stmt, cp); cp.genericPlan == nil {
+			cp.noGenericPlan = true
+			return nil, false, nil
+		}
+	} else if !cp.genericPlan.valid(ctx, p) {
+		// The statement is planned from scratch, which reports the errors if
+		// any, and yields the first custom plan for the new schema.
+		cp.reset(ctx)
+		return nil, false, nil
+	}
+	gp = cp.genericPlan
+	gp.rebind(p)
+	if empty, err = gp.bind(); err != nil {
+		return nil, false, err
+	}
+	cp.genericExecs++
+	return gp, empty, nil
+}
+
+// makeGenericPlan builds the generic plan of a prepared statement: a plan of
+// the statement with unevaluated placeholders, whose scans use the indexes
+// cached for them. It returns nil if the plan cannot be reused.
+func (p *planner) makeGenericPlan(ctx context.Context, stmt Statement, cp *cachedPlan) *genericPlan {
+	sel, ok := stmt.AST.(*tree.Select)
+	if !ok || sel.OrderBy != nil || sel.Limit != nil {
+		return nil
+	}
+	gp := &genericPlan{
+		p:          p.session.newPlanner(nil /* e */, p.txn),
+		database:   p.session.Database,
+		searchPath: p.session.SearchPath.String(),
+	}
+	gp.rebind(p)
+	rp := gp.p
+	// The placeholders are left unevaluated, like when the statement is
+	// prepared.
+	rp.semaCtx.Placeholders.Values = tree.QueryArguments{}
+	rp.semaCtx.Placeholders.PermitUnassigned()
+	rp.evalCtx.Placeholders = nil
+	st := &planCacheState{plan: cp, building: true, reusable: true}
+	rp.planCache = st
+
+	plan, err := rp.makePlan(ctx, stmt)
+	if err != nil {
+		if log.V(2) {
+			log.Infof(ctx, "no generic plan for %s: %v", stmt, err)
+		}
+		return nil
+	}
+	if st.invalidated || !st.reusable || rp.hasSubqueries || len(st.genericScans) != 1 {
+		plan.Close(ctx)
+		return nil
+	}
+	gp.scan = st.genericScans[0]
+	switch n := plan.(type) {
+	case *scanNode:
+		ok = n == gp.scan.n
+	case *renderNode:
+		ok = n.source.plan == gp.scan.n
+	default:
+		ok = false
+	}
+	if !ok {
+		plan.Close(ctx)
+		return nil
+	}
+	gp.plan = plan
+	gp.deps = st.deps
+	return gp
+}
+
+// valid returns whether the generic plan can be used by the execution planned
+// by p: the names of the statement resolve to the same tables, which haven't
+// changed and can still be read by the user.
+func (gp *genericPlan) valid(ctx context.Context, p *planner) bool {
+	if p.session.Database != gp.database || p.session.SearchPath.String() != gp.searchPath {
+		return false
+	}
+	for _, dep := range gp.deps {
+		desc, err := p.session.tables.getTableVersionByID(ctx, p.txn, dep.id)
+		if err != nil || desc.Version != dep.version {
+			return false
+		}
+		if err := p.CheckPrivilege(desc, privilege.SELECT); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// close releases the nodes of the generic plan, once it is dropped from the
+// plan cache.
+func (gp *genericPlan) close(ctx context.Context) {
+	gp.plan.Close(ctx)
+	gp.plan = nil
+}
+
+// rebind sets up the planner of the generic plan to execute the statement
+// planned by p.
+func (gp *genericPlan) rebind(p *planner) {
+	rp := gp.p
+	rp.txn = p.txn
+	rp.session = p.session
+	rp.semaCtx = p.semaCtx
+	rp.evalCtx = p.evalCtx
+	rp.evalCtx.Planner = rp
+	rp.evalCtx.Placeholders = &rp.semaCtx.Placeholders
+	rp.phaseTimes = p.phaseTimes
+	rp.avoidCachedDescriptors = p.avoidCachedDescriptors
+	rp.autoCommit = p.autoCommit
+	rp.stmt = p.stmt
+	rp.cancelChecker = p.cancelChecker
+	rp.noticeSender = p.noticeSender
+}
+
+// bind derives the spans and the filter of the scan of the generic plan from
+// the values of the placeholders, like selectIndex does for the index of a
+// custom plan, and resets the execution state of the scan. It returns true if
+// the scan produces no rows.
+func (gp *genericPlan) bind() (empty bool, err error) {
+	p, g := gp.p, &gp.scan
+	s := g.n
+	s.scanInitialized = false
+	s.fetcher = sqlbase.MultiRowFetcher{}
+	s.rowIndex = 0
+	s.row = nil
+	s.spans = g.spans
+	s.filter = nil
+	if g.filter != nil {
+		// Normalizing the filter replaces the placeholders by their values.
+		var filter tree.TypedExpr
+		if filter, err = p.evalCtx.NormalizeExpr(g.filter); err != nil {
+			return false, err
+		}
+		exprs, equivalent := analyzeExpr(&p.evalCtx, filter)
+		if len(exprs) == 1 && len(exprs[0]) == 1 {
+			if d, ok := exprs[0][0].(*tree.DBool); ok && bool(!*d) {
+				return true, nil
+			}
+		}
+		if equivalent && len(exprs) == 1 {
+			filter = joinAndExprs(exprs[0])
+		}
+		c := &indexInfo{desc: s.desc, index: s.index}
+		c.init(s)
+		c.analyzeExprs(&p.evalCtx, exprs)
+		s.spans, err = makeSpans(&p.evalCtx, c.constraints, s.desc, s.index)
+		if err != nil {
+			return false, err
+		}
+		if len(s.spans) == 0 {
+			return true, nil
+		}
+		filter = applyIndexConstraints(&p.evalCtx, filter, c.constraints)
+		if filter != nil {
+			if filter, err = p.evalCtx.NormalizeExpr(filter); err != nil {
+				return false, err
+			}
+			if filter == tree.DBoolFalse {
+				return true, nil
+			}
+			if filter == tree.DBoolTrue {
+				filter = nil
+			}
+		}
+		s.filter = filter
+	}
+	s.filterVars.Rebind(s.filter, true, false)
+	return false, nil
+}
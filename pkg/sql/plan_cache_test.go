@@ -0,0 +1,260 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	gosql "database/sql"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/security"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/types"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/sqlutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func TestPlanCacheKey(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	stmt := func(str string, typs ...types.T) *PreparedStatement {
+		ps := &PreparedStatement{Str: str, Types: tree.PlaceholderTypes{}}
+		for i, typ := range typs {
+			ps.Types[strconv.Itoa(i+1)] = typ
+		}
+		return ps
+	}
+
+	pc := makePlanCache(context.TODO())
+	a := pc.get(stmt("SELECT * FROM t WHERE a = $1 AND b = $2", types.Int, types.String))
+	if b := pc.get(stmt("SELECT * FROM t WHERE a = $1 AND b = $2", types.Int, types.String)); a != b {
+		t.Errorf("expected the same plan for the same statement")
+	}
+	if b := pc.get(stmt("SELECT * FROM t WHERE a = $1 AND b = $2", types.Int, types.Int)); a == b {
+		t.Errorf("expected different plans for different placeholder types")
+	}
+	if b := pc.get(stmt("SELECT * FROM t WHERE b = $1 AND a = $2", types.Int, types.String)); a == b {
+		t.Errorf("expected different plans for different statements")
+	}
+}
+
+func TestPlanCacheState(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	tableDesc := &sqlbase.TableDescriptor{ID: 51, Version: 1}
+	cp := &cachedPlan{}
+
+	// plan simulates the planning of a statement with two scans of the table,
+	// selecting the given indexes with the given costs. It returns whether
+	// the generic plan was used for the scans.
+	plan := func(indexIDs [2]sqlbase.IndexID, costs [2]float64) [2]bool {
+		st := planCacheState{plan: cp}
+		var generic [2]bool
+		for i := range indexIDs {
+			idx, cached := st.startScan(tableDesc)
+			if cached != nil && costs[i] <= genericPlanCostFactor*cached.avgCost() {
+				generic[i] = true
+				st.selectIndex(idx, cached.indexID, costs[i])
+				continue
+			}
+			st.selectIndex(idx, indexIDs[i], costs[i])
+		}
+		st.finish(context.TODO())
+		return generic
+	}
+
+	for i := 0; i < planCacheCustomPlans; i++ {
+		if generic := plan([2]sqlbase.IndexID{1, 2}, [2]float64{1, 3}); generic != [2]bool{} {
+			t.Fatalf("%d: expected custom plan, got %v", i, generic)
+		}
+	}
+	if !cp.generic {
+		t.Fatal("expected a generic plan after the custom plans")
+	}
+	// A scan for which the cached index is much more expensive than on average
+	// doesn't use the generic plan.
+	if generic := plan([2]sqlbase.IndexID{1, 3}, [2]float64{1, 30}); generic != [2]bool{true, false} {
+		t.Fatalf("expected the generic plan for the first scan only, got %v", generic)
+	}
+	if generic := plan([2]sqlbase.IndexID{1, 2}, [2]float64{2, 6}); generic != [2]bool{true, true} {
+		t.Fatalf("expected generic plan, got %v", generic)
+	}
+
+	// A schema change invalidates the generic plan.
+	tableDesc.Version++
+	if generic := plan([2]sqlbase.IndexID{1, 2}, [2]float64{1, 3}); generic != [2]bool{} {
+		t.Fatalf("expected custom plan after a schema change, got %v", generic)
+	}
+	if cp.generic || cp.numCustomPlans != 1 {
+		t.Fatalf("expected the cached plan to be reset, got %+v", cp)
+	}
+
+	// A schema change during the custom plans restarts them.
+	tableDesc.Version++
+	plan([2]sqlbase.IndexID{1, 2}, [2]float64{1, 3})
+	if cp.unstable || cp.numCustomPlans != 1 {
+		t.Fatalf("expected the cached plan to be reset, got %+v", cp)
+	}
+
+	// Custom plans that select different indexes prevent the use of a generic
+	// plan.
+	plan([2]sqlbase.IndexID{2, 2}, [2]float64{1, 3})
+	for i := 0; i < planCacheCustomPlans; i++ {
+		if generic := plan([2]sqlbase.IndexID{1, 2}, [2]float64{1, 3}); generic != [2]bool{} {
+			t.Fatalf("%d: expected custom plan, got %v", i, generic)
+		}
+	}
+	if !cp.unstable || cp.generic {
+		t.Fatalf("expected an unstable plan, got %+v", cp)
+	}
+}
+
+func TestPlanCacheGenericPlan(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	const query = "SELECT v FROM t.kv WHERE k = $1"
+	var plans int32
+	params := base.TestServerArgs{
+		Knobs: base.TestingKnobs{
+			SQLExecutor: &ExecutorTestingKnobs{
+				BeforePlan: func(_ context.Context, stmt string) {
+					if stmt == query {
+						atomic.AddInt32(&plans, 1)
+					}
+				},
+			},
+		},
+	}
+	s, db, _ := serverutils.StartServer(t, params)
+	defer s.Stopper().Stop(context.TODO())
+	// The plan cache belongs to the session: all the executions must use the
+	// same connection.
+	db.SetMaxOpenConns(1)
+
+	r := sqlutils.MakeSQLRunner(db)
+	r.Exec(t, `CREATE DATABASE t`)
+	r.Exec(t, `CREATE TABLE t.kv (k INT PRIMARY KEY, v INT)`)
+	r.Exec(t, `INSERT INTO t.kv SELECT i, i * 10 FROM generate_series(1, 100) AS g(i)`)
+
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+
+	check := func(k int) {
+		t.Helper()
+		var v int
+		if err := stmt.QueryRow(k).Scan(&v); err != nil {
+			t.Fatal(err)
+		} else if v != k*10 {
+			t.Fatalf("expected %d for k=%d, got %d", k*10, k, v)
+		}
+	}
+	for k := 1; k <= 3*planCacheCustomPlans; k++ {
+		check(k)
+	}
+	// Only the custom plans went through planning; the generic plan was built
+	// once by the first execution after them, and reused by the next ones.
+	if n := atomic.LoadInt32(&plans); n != planCacheCustomPlans {
+		t.Fatalf("expected %d plans, got %d", planCacheCustomPlans, n)
+	}
+
+	// The generic plan is bound to values that select no rows.
+	var v int
+	if err := stmt.QueryRow(1000).Scan(&v); err != gosql.ErrNoRows {
+		t.Fatalf("expected no rows, got %v", err)
+	}
+	if err := stmt.QueryRow(nil).Scan(&v); err != gosql.ErrNoRows {
+		t.Fatalf("expected no rows, got %v", err)
+	}
+	if n := atomic.LoadInt32(&plans); n != planCacheCustomPlans {
+		t.Fatalf("expected %d plans, got %d", planCacheCustomPlans, n)
+	}
+
+	// A schema change invalidates the generic plan.
+	r.Exec(t, `CREATE INDEX foo ON t.kv (v)`)
+	check(50)
+	if n := atomic.LoadInt32(&plans); n != planCacheCustomPlans+1 {
+		t.Fatalf("expected %d plans, got %d", planCacheCustomPlans+1, n)
+	}
+}
+
+// TestPlanCacheClose verifies that the generic plans are closed when their
+// statement is deallocated, and when their session finishes.
+func TestPlanCacheClose(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	s, _, _ := serverutils.StartServer(t, base.TestServerArgs{})
+	defer s.Stopper().Stop(context.TODO())
+
+	e := s.Executor().(*Executor)
+	session := NewSession(
+		context.TODO(), SessionArgs{User: security.RootUser}, e, nil, &MemoryMetrics{})
+	session.StartUnlimitedMonitor()
+	finished := false
+	defer func() {
+		if !finished {
+			session.Finish(e)
+		}
+	}()
+
+	exec := func(stmts string, numResults int) {
+		t.Helper()
+		res, err := e.ExecuteStatementsBuffered(session, stmts, nil, numResults)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Close(session.Ctx())
+	}
+	// prepare prepares a statement and executes it until it uses a generic
+	// plan, which it returns.
+	prepare := func() *genericPlan {
+		t.Helper()
+		exec(`PREPARE q AS SELECT v FROM t.kv WHERE k = $1`, 1)
+		for i := 0; i <= planCacheCustomPlans; i++ {
+			exec(`EXECUTE q(1)`, 1)
+		}
+		stmt, ok := session.PreparedStatements.Get("q")
+		if !ok {
+			t.Fatal("prepared statement q not found")
+		}
+		gp := session.planCache.get(stmt).genericPlan
+		if gp == nil || gp.plan == nil {
+			t.Fatal("expected a generic plan")
+		}
+		return gp
+	}
+	exec(`CREATE DATABASE t; CREATE TABLE t.kv (k INT PRIMARY KEY, v INT)`, 2)
+
+	gp := prepare()
+	exec(`DEALLOCATE q`, 1)
+	if gp.plan != nil {
+		t.Fatal("expected DEALLOCATE to close the generic plan")
+	}
+
+	gp = prepare()
+	finished = true
+	session.Finish(e)
+	if gp.plan != nil {
+		t.Fatal("expected Finish to close the generic plan")
+	}
+}
@@ -77,6 +77,8 @@ func getPlanColumns(plan planNode, mut bool) sqlbase.ResultColumns {
 		return n.values.columns
 	case *traceNode:
 		return n.columns
+	case *fetchNode:
+		return n.columns
 
 		// Nodes with a fixed schema.
 	case *scrubNode:
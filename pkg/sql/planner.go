@@ -88,6 +88,10 @@ type planner struct {
 	// query.
 	cancelChecker *sqlbase.CancelChecker
 
+	// noticeSender, if set, receives the notices raised by the statement being
+	// planned and executed. Notices are dropped if it isn't set.
+	noticeSender NoticeSender
+
 	// planDeps, if non-nil, collects the table/view dependencies for this query.
 	// Any planNode constructors that resolves a table name or reference in the query
 	// to a descriptor must register this descriptor into planDeps.
@@ -106,6 +110,9 @@ type planner struct {
 	isPreparing bool
 	// plannedExecute is true if this planner has planned an EXECUTE statement.
 	plannedExecute bool
+	// planCache, if non-nil, is used to plan the prepared statement being
+	// executed with the decisions cached by previous executions.
+	planCache *planCacheState
 
 	// Avoid allocations by embedding commonly used objects and visitors.
 	parser                parser.Parser
@@ -65,6 +65,7 @@ type PreparedStatement struct {
 }
 
 func (p *PreparedStatement) close(ctx context.Context, s *Session) {
+	s.planCache.drop(p)
 	p.memAcc.Wsession(s).Close(ctx)
 	p.constantAcc.Close(ctx)
 }
@@ -76,6 +77,12 @@ type Statement struct {
 	AnonymizedStr string
 	queryID       uint128.Uint128
 	queryMeta     *queryMeta
+	// prepared is the prepared statement being executed, if any.
+	prepared *PreparedStatement
+	// portal is the portal being executed, if any, and limit the maximum number
+	// of rows to return for it if not 0.
+	portal *PreparedPortal
+	limit  int
 }
 
 func (s Statement) String() string {
@@ -199,7 +206,7 @@ func (ps PreparedStatements) Delete(ctx context.Context, name string) bool {
 			for portalName := range stmt.portalNames {
 				if portal, ok := ps.session.PreparedPortals.Get(name); ok {
 					delete(ps.session.PreparedPortals.portals, portalName)
-					portal.memAcc.Wsession(ps.session).Close(ctx)
+					portal.close(ctx, ps.session)
 				}
 			}
 		}
@@ -216,7 +223,7 @@ func (ps PreparedStatements) closeAll(ctx context.Context, s *Session) {
 		stmt.close(ctx, s)
 	}
 	for _, portal := range s.PreparedPortals.portals {
-		portal.memAcc.Wsession(s).Close(ctx)
+		portal.close(ctx, s)
 	}
 }
 
@@ -245,9 +252,21 @@ type PreparedPortal struct {
 
 	ProtocolMeta interface{} // a field for protocol implementations to hang metadata off of.
 
+	// plan, if set, is the plan of the portal's statement, which was suspended
+	// at the row limit of an execution.
+	plan *resumablePlan
+
 	memAcc WrappableMemoryAccount
 }
 
+func (p *PreparedPortal) close(ctx context.Context, s *Session) {
+	if p.plan != nil {
+		p.plan.close(ctx)
+		p.plan = nil
+	}
+	p.memAcc.Wsession(s).Close(ctx)
+}
+
 // PreparedPortals is a mapping of PreparedPortal names to their corresponding
 // PreparedPortals.
 type PreparedPortals struct {
@@ -291,7 +310,7 @@ func (pp PreparedPortals) New(
 	stmt.portalNames[name] = struct{}{}
 
 	if prevPortal, ok := pp.Get(name); ok {
-		prevPortal.memAcc.Wsession(pp.session).Close(ctx)
+		prevPortal.close(ctx, pp.session)
 	}
 
 	pp.portals[name] = portal
@@ -303,13 +322,39 @@ func (pp PreparedPortals) New(
 func (pp PreparedPortals) Delete(ctx context.Context, name string) bool {
 	if portal, ok := pp.Get(name); ok {
 		delete(portal.Stmt.portalNames, name)
-		portal.memAcc.Wsession(pp.session).Close(ctx)
+		portal.close(ctx, pp.session)
 		delete(pp.portals, name)
 		return true
 	}
 	return false
 }
 
+// DeleteSuspended removes the PreparedPortals whose execution was suspended
+// at the row limit of an execution. Like in Postgres, they are destroyed at
+// the end of the transaction in which they were executed.
+func (pp PreparedPortals) DeleteSuspended(ctx context.Context) {
+	for name, portal := range pp.portals {
+		if portal.plan != nil {
+			pp.Delete(ctx, name)
+		}
+	}
+}
+
+// finishTxn is called when a SQL transaction finishes. The portals suspended
+// by the transaction can't be resumed anymore, unless their rows were held.
+func (pp PreparedPortals) finishTxn(ctx context.Context) {
+	for _, portal := range pp.portals {
+		if portal.plan == nil {
+			continue
+		}
+		if portal.plan.held() {
+			portal.plan.txn = nil
+			continue
+		}
+		portal.plan.close(ctx)
+	}
+}
+
 // PrepareStmt implements the PREPARE statement.
 // See https://www.postgresql.org/docs/current/static/sql-prepare.html for details.
 func (e *Executor) PrepareStmt(session *Session, s *tree.Prepare) error {
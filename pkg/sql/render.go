@@ -526,6 +526,13 @@ func (r *renderNode) initWhere(ctx context.Context, whereExpr tree.Expr) (*filte
 		); err != nil {
 			return nil, err
 		}
+
+		// Plan the IN and NOT IN subqueries as semi and anti joins.
+		r.source, f.filter, err = r.planner.makeSubqueryJoins(r.source, f.filter)
+		if err != nil {
+			return nil, err
+		}
+		r.sourceInfo = multiSourceInfo{r.source.info}
 	}
 
 	// Insert the newly created filterNode between the renderNode and
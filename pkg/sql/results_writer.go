@@ -19,6 +19,7 @@ import (
 
 	"golang.org/x/net/context"
 
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
 	"github.com/cockroachdb/cockroach/pkg/util/mon"
@@ -141,9 +142,20 @@ type ResultsGroup interface {
 	Reset(context.Context)
 }
 
+// NoticeSender is used to send notices to the SQL client.
+type NoticeSender interface {
+	// AddNotice adds a notice to the current result. Unlike the other results,
+	// notices can be added at any point of the execution of a statement,
+	// including while it is planned, before BeginResult. Like rows, they are
+	// discarded if the results group is reset.
+	AddNotice(notice *pgerror.Notice)
+}
+
 // StatementResult is used to produce results for a single query (see
 // ResultsWriter).
 type StatementResult interface {
+	NoticeSender
+
 	// BeginResult should be called prior to any of the other methods.
 	// TODO(andrei): remove BeginResult and SetColumns, and have
 	// NewStatementResult() take in a tree.Statement
@@ -184,6 +196,9 @@ type bufferedWriter struct {
 	// currentResult and resultInProgress spans a statement.
 	currentResult    Result
 	resultInProgress bool
+
+	// pendingNotices are the notices added before BeginResult.
+	pendingNotices []*pgerror.Notice
 }
 
 func newBufferedWriter(acc mon.BoundAccount) *bufferedWriter {
@@ -225,6 +240,7 @@ func (b *bufferedWriter) Close() {
 	b.pastResults = append(b.pastResults, b.currentGroupResults...)
 	b.currentGroupResults = nil
 	b.resultInProgress = false
+	b.pendingNotices = nil
 }
 
 // Flush implements the ResultsGroup interface.
@@ -244,6 +260,7 @@ func (b *bufferedWriter) Reset(ctx context.Context) {
 		b.currentGroupResults = nil
 	}
 	b.resultInProgress = false
+	b.pendingNotices = nil
 }
 
 // BeginResult implements the StatementResult interface.
@@ -253,6 +270,16 @@ func (b *bufferedWriter) BeginResult(stmt tree.Statement) {
 	}
 	b.resultInProgress = true
 	b.currentResult = Result{PGTag: stmt.StatementTag(), Type: stmt.StatementType()}
+	b.currentResult.Notices, b.pendingNotices = b.pendingNotices, nil
+}
+
+// AddNotice implements the StatementResult interface.
+func (b *bufferedWriter) AddNotice(notice *pgerror.Notice) {
+	if !b.resultInProgress {
+		b.pendingNotices = append(b.pendingNotices, notice)
+		return
+	}
+	b.currentResult.Notices = append(b.currentResult.Notices, notice)
 }
 
 // GetPGTag implements the StatementResult interface.
@@ -0,0 +1,188 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/internal/client"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/util/mon"
+	"github.com/cockroachdb/cockroach/pkg/util/uuid"
+)
+
+// resumablePlan is a plan whose rows are pulled a few at a time by several
+// statements: the Execute messages of a pgwire portal with a row limit, or
+// the FETCH and MOVE statements of a cursor.
+//
+// The plan runs on the local execution engine with a planner of its own, in
+// the transaction that started it; only that attempt of that transaction can
+// resume it. A plan which has to outlive its transaction is held: its
+// remaining rows are read before the transaction commits and stored in a
+// RowContainer, from which they are returned afterwards.
+type resumablePlan struct {
+	p       *planner
+	plan    planNode
+	columns sqlbase.ResultColumns
+	// rowAcc accounts for the memory of the current row of the plan.
+	rowAcc mon.BoundAccount
+	// done is set once the plan returned all its rows.
+	done bool
+
+	// rows holds the remaining rows of the plan once it's held; pos is the
+	// index of the current row.
+	rows *sqlbase.RowContainer
+	pos  int
+
+	// txn is the transaction that started the plan; txnID and epoch identify
+	// the attempt of that transaction. txn is reset when a held plan outlives
+	// its transaction.
+	txn   *client.Txn
+	txnID uuid.UUID
+	epoch uint32
+}
+
+// newResumablePlanner returns a planner for a plan which outlives the
+// statement planned by p. It is configured like p.
+func (p *planner) newResumablePlanner() *planner {
+	rp := p.session.newPlanner(nil /* e */, p.txn)
+	rp.semaCtx = p.semaCtx
+	rp.evalCtx = p.evalCtx
+	rp.evalCtx.Planner = rp
+	rp.evalCtx.Placeholders = &rp.semaCtx.Placeholders
+	rp.phaseTimes = p.phaseTimes
+	rp.avoidCachedDescriptors = p.avoidCachedDescriptors
+	rp.autoCommit = p.autoCommit
+	rp.stmt = p.stmt
+	rp.cancelChecker = p.cancelChecker
+	rp.noticeSender = p.noticeSender
+	return rp
+}
+
+// startResumablePlan starts a plan made by a planner returned by
+// newResumablePlanner. The resumablePlan takes ownership of the plan, even if
+// an error is returned.
+func startResumablePlan(ctx context.Context, p *planner, plan planNode) (*resumablePlan, error) {
+	r := &resumablePlan{
+		p:       p,
+		plan:    plan,
+		columns: planColumns(plan),
+		rowAcc:  p.evalCtx.Mon.MakeBoundAccount(),
+		txn:     p.txn,
+		txnID:   p.txn.ID(),
+		epoch:   p.txn.Proto().Epoch,
+	}
+	p.evalCtx.ActiveMemAcc = &r.rowAcc
+	if err := p.startPlan(ctx, plan); err != nil {
+		r.close(ctx)
+		return nil, err
+	}
+	return r, nil
+}
+
+// startedBy returns whether the plan was started by the current attempt of
+// txn.
+func (r *resumablePlan) startedBy(txn *client.Txn) bool {
+	return r.txn == txn && txn.ID() == r.txnID && txn.Proto().Epoch == r.epoch
+}
+
+// resumeFor prepares the plan to return rows for the statement planned by p.
+func (r *resumablePlan) resumeFor(p *planner) {
+	r.p.stmt = p.stmt
+	r.p.cancelChecker = p.cancelChecker
+	r.p.noticeSender = p.noticeSender
+}
+
+// next advances to the next row of the plan. It returns false once all the
+// rows were returned.
+func (r *resumablePlan) next(ctx context.Context) (bool, error) {
+	if r.rows != nil {
+		if r.pos+1 >= r.rows.Len() {
+			r.pos = r.rows.Len()
+			return false, nil
+		}
+		r.pos++
+		return true, nil
+	}
+	if r.done || r.plan == nil {
+		return false, nil
+	}
+	r.rowAcc.Clear(ctx)
+	next, err := r.plan.Next(runParams{ctx: ctx, p: r.p})
+	if !next {
+		r.done = true
+	}
+	return next, err
+}
+
+// values returns the current row of the plan.
+func (r *resumablePlan) values() tree.Datums {
+	if r.rows != nil {
+		return r.rows.At(r.pos)
+	}
+	return r.plan.Values()
+}
+
+// hold reads the remaining rows of the plan and closes it, so that they can
+// be returned after the end of the transaction.
+func (r *resumablePlan) hold(ctx context.Context) error {
+	if r.rows != nil || r.plan == nil {
+		return nil
+	}
+	rows := sqlbase.NewRowContainer(
+		r.p.session.makeBoundAccount(), sqlbase.ColTypeInfoFromResCols(r.columns), 0,
+	)
+	for {
+		next, err := r.next(ctx)
+		if err != nil {
+			rows.Close(ctx)
+			return err
+		}
+		if !next {
+			break
+		}
+		if _, err := rows.AddRow(ctx, r.plan.Values()); err != nil {
+			rows.Close(ctx)
+			return err
+		}
+	}
+	r.closePlan(ctx)
+	r.rows = rows
+	r.pos = -1
+	return nil
+}
+
+// held returns whether the rows of the plan were stored by hold.
+func (r *resumablePlan) held() bool {
+	return r.rows != nil
+}
+
+func (r *resumablePlan) closePlan(ctx context.Context) {
+	if r.plan != nil {
+		r.plan.Close(ctx)
+		r.plan = nil
+		r.rowAcc.Close(ctx)
+	}
+}
+
+// close releases the resources of the plan. It can be called several times.
+func (r *resumablePlan) close(ctx context.Context) {
+	r.closePlan(ctx)
+	if r.rows != nil {
+		r.rows.Close(ctx)
+		r.rows = nil
+	}
+}
@@ -400,6 +400,13 @@ func (n *scanNode) computePhysicalProps(
 ) physicalProps {
 	var pp physicalProps
 
+	if index.Type == sqlbase.IndexDescriptor_INVERTED {
+		// The entries of an inverted index are ordered by lexeme; they provide
+		// no ordering or keys on the table columns.
+		pp.applyExpr(&n.p.evalCtx, n.origFilter)
+		return pp
+	}
+
 	columnIDs, dirs := index.FullColumnIDs()
 
 	var keySet util.FastIntSet
@@ -22,6 +22,7 @@ import (
 
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/types"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
 )
 
 // invalidSrcIdx is the srcIdx value returned by findColumn() when there is no match.
@@ -50,12 +51,16 @@ type nameResolutionVisitor struct {
 
 var _ tree.Visitor = &nameResolutionVisitor{}
 
-func makeUntypedTuple(texprs []tree.TypedExpr) *tree.Tuple {
+// makeUntypedTuple makes a tuple of the given expressions, labeled with the
+// names of the given columns.
+func makeUntypedTuple(cols sqlbase.ResultColumns, texprs []tree.TypedExpr) *tree.Tuple {
 	exprs := make(tree.Exprs, len(texprs))
+	var labels []string
 	for i, e := range texprs {
 		exprs[i] = e
+		labels = append(labels, cols[i].Name)
 	}
-	return &tree.Tuple{Exprs: exprs}
+	return &tree.Tuple{Exprs: exprs, Labels: labels}
 }
 
 func (v *nameResolutionVisitor) VisitPre(expr tree.Expr) (recurse bool, newNode tree.Expr) {
@@ -80,7 +85,9 @@ func (v *nameResolutionVisitor) VisitPre(expr tree.Expr) (recurse bool, newNode
 		//    SELECT (kv.*) FROM kv               -> SELECT (k, v) FROM kv
 		//    SELECT COUNT(DISTINCT kv.*) FROM kv -> SELECT COUNT(DISTINCT (k, v)) FROM kv
 		//
-		_, exprs, err := v.sources[0].expandStar(t, v.iVarHelper)
+		// The tuple is labeled with the names of the columns, so that e.g.
+		// row_to_json(kv.*) uses them as keys.
+		cols, exprs, err := v.sources[0].expandStar(t, v.iVarHelper)
 		if err != nil {
 			v.err = err
 			return false, expr
@@ -93,7 +100,7 @@ func (v *nameResolutionVisitor) VisitPre(expr tree.Expr) (recurse bool, newNode
 		// We return an untyped tuple because name resolution occurs
 		// before type checking, and type checking will resolve the
 		// tuple's type.
-		return false, makeUntypedTuple(exprs)
+		return false, makeUntypedTuple(cols, exprs)
 
 	case *tree.IndexedVar:
 		// If the indexed var is a standalone ordinal reference, ensure it
@@ -26,7 +26,9 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/types"
 	"github.com/cockroachdb/cockroach/pkg/util/duration"
+	"github.com/cockroachdb/cockroach/pkg/util/json"
 	"github.com/cockroachdb/cockroach/pkg/util/mon"
+	"github.com/pkg/errors"
 )
 
 func initAggregateBuiltins() {
@@ -137,6 +139,26 @@ var Aggregates = map[string][]tree.Builtin{
 		},
 	},
 
+	"json_agg": {
+		makeAggBuiltin([]types.T{types.Any}, types.JSON, newJSONAggregate,
+			"Aggregates values as a JSON or JSONB array."),
+	},
+
+	"jsonb_agg": {
+		makeAggBuiltin([]types.T{types.Any}, types.JSON, newJSONAggregate,
+			"Aggregates values as a JSON or JSONB array."),
+	},
+
+	"json_object_agg": {
+		makeAggBuiltin([]types.T{types.String, types.Any}, types.JSON, newJSONObjectAggregate,
+			"Aggregates name/value pairs as a JSON object."),
+	},
+
+	"jsonb_object_agg": {
+		makeAggBuiltin([]types.T{types.String, types.Any}, types.JSON, newJSONObjectAggregate,
+			"Aggregates name/value pairs as a JSON object."),
+	},
+
 	"max": collectBuiltins(func(t types.T) tree.Builtin {
 		return makeAggBuiltin([]types.T{t}, t, newMaxAggregate,
 			"Identifies the maximum selected value.")
@@ -271,6 +293,8 @@ var _ tree.AggregateFunc = &identAggregate{}
 var _ tree.AggregateFunc = &concatAggregate{}
 var _ tree.AggregateFunc = &bytesXorAggregate{}
 var _ tree.AggregateFunc = &intXorAggregate{}
+var _ tree.AggregateFunc = &jsonAggregate{}
+var _ tree.AggregateFunc = &jsonObjectAggregate{}
 
 // In order to render the unaggregated (i.e. grouped) fields, during aggregation,
 // the values for those fields have to be stored for each bucket.
@@ -1410,3 +1434,96 @@ func (a *intXorAggregate) Result() (tree.Datum, error) {
 
 // Close is part of the tree.AggregateFunc interface.
 func (a *intXorAggregate) Close(context.Context) {}
+
+type jsonAggregate struct {
+	builder *json.ArrayBuilder
+	acc     mon.BoundAccount
+	sawAny  bool
+}
+
+func newJSONAggregate(_ []types.T, evalCtx *tree.EvalContext) tree.AggregateFunc {
+	return &jsonAggregate{
+		builder: json.NewArrayBuilder(0),
+		acc:     evalCtx.Mon.MakeBoundAccount(),
+	}
+}
+
+// Add accumulates the transformed json into the JSON array.
+func (a *jsonAggregate) Add(ctx context.Context, datum tree.Datum, _ ...tree.Datum) error {
+	j, err := tree.AsJSON(datum)
+	if err != nil {
+		return err
+	}
+	if err := a.acc.Grow(ctx, int64(j.Size())); err != nil {
+		return err
+	}
+	a.builder.Add(j)
+	a.sawAny = true
+	return nil
+}
+
+// Result returns a DJSON array of all datums passed to Add.
+func (a *jsonAggregate) Result() (tree.Datum, error) {
+	if a.sawAny {
+		return &tree.DJSON{JSON: a.builder.Build()}, nil
+	}
+	return tree.DNull, nil
+}
+
+// Close allows the aggregate to release the memory it requested during
+// operation.
+func (a *jsonAggregate) Close(ctx context.Context) {
+	a.acc.Close(ctx)
+}
+
+var errJSONObjectAggNullKey = pgerror.NewError(pgerror.CodeInvalidParameterValueError, "field name must not be null")
+
+type jsonObjectAggregate struct {
+	builder *json.ObjectBuilder
+	acc     mon.BoundAccount
+	sawAny  bool
+}
+
+func newJSONObjectAggregate(_ []types.T, evalCtx *tree.EvalContext) tree.AggregateFunc {
+	return &jsonObjectAggregate{
+		builder: json.NewObjectBuilder(0),
+		acc:     evalCtx.Mon.MakeBoundAccount(),
+	}
+}
+
+// Add accumulates the key and the transformed value into the JSON object.
+func (a *jsonObjectAggregate) Add(
+	ctx context.Context, datum tree.Datum, otherArgs ...tree.Datum,
+) error {
+	if len(otherArgs) != 1 {
+		return errors.Errorf("expected exactly one other argument, got %d", len(otherArgs))
+	}
+	if datum == tree.DNull {
+		return errJSONObjectAggNullKey
+	}
+	key := string(tree.MustBeDString(datum))
+	j, err := tree.AsJSON(otherArgs[0])
+	if err != nil {
+		return err
+	}
+	if err := a.acc.Grow(ctx, int64(len(key))+int64(j.Size())); err != nil {
+		return err
+	}
+	a.builder.Add(key, j)
+	a.sawAny = true
+	return nil
+}
+
+// Result returns a DJSON object of all key-value pairs passed to Add.
+func (a *jsonObjectAggregate) Result() (tree.Datum, error) {
+	if a.sawAny {
+		return &tree.DJSON{JSON: a.builder.Build()}, nil
+	}
+	return tree.DNull, nil
+}
+
+// Close allows the aggregate to release the memory it requested during
+// operation.
+func (a *jsonObjectAggregate) Close(ctx context.Context) {
+	a.acc.Close(ctx)
+}
@@ -45,10 +45,12 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/types"
 	"github.com/cockroachdb/cockroach/pkg/util/duration"
 	"github.com/cockroachdb/cockroach/pkg/util/ipaddr"
+	"github.com/cockroachdb/cockroach/pkg/util/json"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
 	"github.com/cockroachdb/cockroach/pkg/util/timeofday"
 	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/cockroachdb/cockroach/pkg/util/tsearch"
 	"github.com/cockroachdb/cockroach/pkg/util/uuid"
 	"github.com/pkg/errors"
 )
@@ -65,14 +67,16 @@ var (
 const errInsufficientArgsFmtString = "unknown signature: %s()"
 
 const (
-	categoryComparison    = "Comparison"
-	categoryCompatibility = "Compatibility"
-	categoryDateAndTime   = "Date and Time"
-	categoryIDGeneration  = "ID Generation"
-	categoryMath          = "Math and Numeric"
-	categoryString        = "String and Byte"
-	categoryArray         = "Array"
-	categorySystemInfo    = "System Info"
+	categoryComparison     = "Comparison"
+	categoryCompatibility  = "Compatibility"
+	categoryDateAndTime    = "Date and Time"
+	categoryFullTextSearch = "Full Text Search"
+	categoryIDGeneration   = "ID Generation"
+	categoryJSON           = "JSONB"
+	categoryMath           = "Math and Numeric"
+	categoryString         = "String and Byte"
+	categoryArray          = "Array"
+	categorySystemInfo     = "System Info"
 )
 
 func categorizeType(t types.T) string {
@@ -1491,8 +1495,153 @@ CockroachDB supports the following flags:
 	},
 
 	"json_remove_path": {
-	// TODO(justin): added here so #- can be desugared into it, still needs to be
-	// implemented.
+		tree.Builtin{
+			Types:      tree.ArgTypes{{"val", types.JSON}, {"path", types.TArray{Typ: types.String}}},
+			ReturnType: tree.FixedReturnType(types.JSON),
+			Category:   categoryJSON,
+			Fn: func(_ *tree.EvalContext, args tree.Datums) (tree.Datum, error) {
+				path, err := darrayToStringSlice(*tree.MustBeDArray(args[1]))
+				if err != nil {
+					return nil, err
+				}
+				j, err := json.RemovePath(args[0].(*tree.DJSON).JSON, path)
+				if err != nil {
+					return nil, err
+				}
+				return &tree.DJSON{JSON: j}, nil
+			},
+			Info: "Remove the specified path from the JSON object.",
+		},
+	},
+
+	"to_json":  toJSONImpls,
+	"to_jsonb": toJSONImpls,
+
+	"json_build_array":  jsonBuildArrayImpls,
+	"jsonb_build_array": jsonBuildArrayImpls,
+
+	"json_build_object":  jsonBuildObjectImpls,
+	"jsonb_build_object": jsonBuildObjectImpls,
+
+	"json_typeof":  jsonTypeOfImpls,
+	"jsonb_typeof": jsonTypeOfImpls,
+
+	"json_strip_nulls":  jsonStripNullsImpls,
+	"jsonb_strip_nulls": jsonStripNullsImpls,
+
+	"jsonb_pretty": {
+		tree.Builtin{
+			Types:      tree.ArgTypes{{"val", types.JSON}},
+			ReturnType: tree.FixedReturnType(types.String),
+			Category:   categoryJSON,
+			Fn: func(_ *tree.EvalContext, args tree.Datums) (tree.Datum, error) {
+				return tree.NewDString(json.Pretty(args[0].(*tree.DJSON).JSON)), nil
+			},
+			Info: "Returns the given JSON value as a STRING indented and with newlines.",
+		},
+	},
+
+	"jsonb_set": {
+		makeJSONSetBuiltin(tree.ArgTypes{
+			{"val", types.JSON},
+			{"path", types.TArray{Typ: types.String}},
+			{"to", types.JSON},
+		}),
+		makeJSONSetBuiltin(tree.ArgTypes{
+			{"val", types.JSON},
+			{"path", types.TArray{Typ: types.String}},
+			{"to", types.JSON},
+			{"create_missing", types.Bool},
+		}),
+	},
+
+	"jsonb_insert": {
+		makeJSONInsertBuiltin(tree.ArgTypes{
+			{"target", types.JSON},
+			{"path", types.TArray{Typ: types.String}},
+			{"new_val", types.JSON},
+		}),
+		makeJSONInsertBuiltin(tree.ArgTypes{
+			{"target", types.JSON},
+			{"path", types.TArray{Typ: types.String}},
+			{"new_val", types.JSON},
+			{"insert_after", types.Bool},
+		}),
+	},
+
+	"jsonb_path_exists": {
+		makeJSONPathExistsBuiltin(jsonPathArgTypes(2)),
+		makeJSONPathExistsBuiltin(jsonPathArgTypes(3)),
+		makeJSONPathExistsBuiltin(jsonPathArgTypes(4)),
+	},
+
+	"jsonb_path_match": {
+		makeJSONPathMatchBuiltin(jsonPathArgTypes(2)),
+		makeJSONPathMatchBuiltin(jsonPathArgTypes(3)),
+		makeJSONPathMatchBuiltin(jsonPathArgTypes(4)),
+	},
+
+	"row_to_json": {
+		tree.Builtin{
+			Types:      tree.ArgTypes{{"row", types.Any}},
+			ReturnType: tree.FixedReturnType(types.JSON),
+			Category:   categoryJSON,
+			Fn: func(_ *tree.EvalContext, args tree.Datums) (tree.Datum, error) {
+				tuple, ok := args[0].(*tree.DTuple)
+				if !ok {
+					return nil, pgerror.NewErrorf(pgerror.CodeDatatypeMismatchError,
+						"row_to_json: argument must be a tuple, not %s", args[0].ResolvedType())
+				}
+				j, err := tree.AsJSON(tuple)
+				if err != nil {
+					return nil, err
+				}
+				return &tree.DJSON{JSON: j}, nil
+			},
+			Info: "Returns the row as a JSON object, keyed by the labels of its fields, or f1, f2, etc. " +
+				"if they have none.",
+		},
+	},
+
+	// Full-text search functions.
+	// https://www.postgresql.org/docs/10/static/functions-textsearch.html
+
+	"to_tsvector": makeTextSearchBuiltins(types.TSVector,
+		func(config *tsearch.Config, text string) (tree.Datum, error) {
+			return tree.NewDTSVector(config.ToVector(text)), nil
+		},
+		"Reduces `text` to a TSVECTOR of lexemes using the `config` text search configuration."),
+
+	"to_tsquery": makeTextSearchBuiltins(types.TSQuery,
+		func(config *tsearch.Config, text string) (tree.Datum, error) {
+			q, err := config.ToQuery(text)
+			if err != nil {
+				return nil, err
+			}
+			return tree.NewDTSQuery(q), nil
+		},
+		"Parses `text`, written in the TSQUERY syntax, into a TSQUERY, normalizing its words "+
+			"into lexemes using the `config` text search configuration."),
+
+	"plainto_tsquery": makeTextSearchBuiltins(types.TSQuery,
+		func(config *tsearch.Config, text string) (tree.Datum, error) {
+			return tree.NewDTSQuery(config.PlainToQuery(text)), nil
+		},
+		"Converts unformatted `text` into a TSQUERY matching documents that contain all of "+
+			"its words, normalized using the `config` text search configuration."),
+
+	"ts_rank": {
+		tree.Builtin{
+			Types:      tree.ArgTypes{{"vector", types.TSVector}, {"query", types.TSQuery}},
+			ReturnType: tree.FixedReturnType(types.Float),
+			Category:   categoryFullTextSearch,
+			Fn: func(_ *tree.EvalContext, args tree.Datums) (tree.Datum, error) {
+				v, q := tree.MustBeDTSVector(args[0]), tree.MustBeDTSQuery(args[1])
+				return tree.NewDFloat(tree.DFloat(tsearch.Rank(v.Vector, q.Query))), nil
+			},
+			Info: "Ranks `vector` by its relevance to `query`, based on the frequency of its " +
+				"matching lexemes.",
+		},
 	},
 
 	"ln": {
@@ -2123,6 +2272,40 @@ CockroachDB supports the following flags:
 		},
 	},
 
+	// notice sends a notice to the client, like the RAISE statement of
+	// PL/pgSQL.
+	"crdb_internal.notice": {
+		tree.Builtin{
+			Types:            tree.ArgTypes{{"msg", types.String}},
+			ReturnType:       tree.FixedReturnType(types.Int),
+			Impure:           true,
+			DistsqlBlacklist: true,
+			Fn: func(ctx *tree.EvalContext, args tree.Datums) (tree.Datum, error) {
+				return sendNotice(ctx, pgerror.SeverityNotice, string(tree.MustBeDString(args[0])))
+			},
+			Category: categorySystemInfo,
+			Info:     "Sends a notice with the NOTICE severity level to the client.",
+		},
+		tree.Builtin{
+			Types:            tree.ArgTypes{{"severity", types.String}, {"msg", types.String}},
+			ReturnType:       tree.FixedReturnType(types.Int),
+			Impure:           true,
+			DistsqlBlacklist: true,
+			Fn: func(ctx *tree.EvalContext, args tree.Datums) (tree.Datum, error) {
+				s := string(tree.MustBeDString(args[0]))
+				severity, ok := pgerror.ParseSeverity(s)
+				if !ok || severity == pgerror.SeverityError {
+					return nil, pgerror.NewErrorf(pgerror.CodeInvalidParameterValueError,
+						"unknown severity level %q: expected one of debug, log, info, notice or warning", s)
+				}
+				return sendNotice(ctx, severity, string(tree.MustBeDString(args[1])))
+			},
+			Category: categorySystemInfo,
+			Info: "Sends a notice with the given severity level (debug, log, info, " +
+				"notice or warning) to the client.",
+		},
+	},
+
 	"crdb_internal.set_vmodule": {
 		tree.Builtin{
 			Types:      tree.ArgTypes{{"vmodule_string", types.String}},
@@ -2139,6 +2322,13 @@ CockroachDB supports the following flags:
 	},
 }
 
+// sendNotice sends a notice with the given severity level and message to the
+// client.
+func sendNotice(ctx *tree.EvalContext, severity pgerror.Severity, msg string) (tree.Datum, error) {
+	ctx.Planner.SendNotice(pgerror.NewNoticef(severity, "%s", msg))
+	return tree.DZero, nil
+}
+
 var substringImpls = []tree.Builtin{
 	{
 		Types: tree.ArgTypes{
@@ -2296,6 +2486,318 @@ var powImpls = []tree.Builtin{
 	},
 }
 
+var toJSONImpls = []tree.Builtin{
+	{
+		Types:      tree.ArgTypes{{"val", types.Any}},
+		ReturnType: tree.FixedReturnType(types.JSON),
+		Category:   categoryJSON,
+		Fn: func(_ *tree.EvalContext, args tree.Datums) (tree.Datum, error) {
+			j, err := tree.AsJSON(args[0])
+			if err != nil {
+				return nil, err
+			}
+			return &tree.DJSON{JSON: j}, nil
+		},
+		Info: "Returns the value as JSON or JSONB.",
+	},
+}
+
+var jsonBuildArrayImpls = []tree.Builtin{
+	{
+		Types:        tree.VariadicType{Typ: types.Any},
+		ReturnType:   tree.FixedReturnType(types.JSON),
+		NullableArgs: true,
+		Category:     categoryJSON,
+		Fn: func(_ *tree.EvalContext, args tree.Datums) (tree.Datum, error) {
+			builder := json.NewArrayBuilder(len(args))
+			for _, arg := range args {
+				j, err := tree.AsJSON(arg)
+				if err != nil {
+					return nil, err
+				}
+				builder.Add(j)
+			}
+			return &tree.DJSON{JSON: builder.Build()}, nil
+		},
+		Info: "Builds a possibly-heterogeneously-typed JSON or JSONB array out of a variadic argument list.",
+	},
+}
+
+var errJSONBuildObjectOddArgs = pgerror.NewError(
+	pgerror.CodeInvalidParameterValueError, "argument list must have even number of elements",
+)
+
+var jsonBuildObjectImpls = []tree.Builtin{
+	{
+		Types:        tree.VariadicType{Typ: types.Any},
+		ReturnType:   tree.FixedReturnType(types.JSON),
+		NullableArgs: true,
+		Category:     categoryJSON,
+		Fn: func(_ *tree.EvalContext, args tree.Datums) (tree.Datum, error) {
+			if len(args)%2 != 0 {
+				return nil, errJSONBuildObjectOddArgs
+			}
+			builder := json.NewObjectBuilder(len(args) / 2)
+			for i := 0; i < len(args); i += 2 {
+				key, err := asJSONBuildObjectKey(i, args[i])
+				if err != nil {
+					return nil, err
+				}
+				val, err := tree.AsJSON(args[i+1])
+				if err != nil {
+					return nil, err
+				}
+				builder.Add(key, val)
+			}
+			return &tree.DJSON{JSON: builder.Build()}, nil
+		},
+		Info: "Builds a JSON object out of a variadic argument list.",
+	},
+}
+
+// asJSONBuildObjectKey returns the text of the argument at position i of
+// json_build_object, which is used as an object key.
+func asJSONBuildObjectKey(i int, d tree.Datum) (string, error) {
+	switch t := d.(type) {
+	case *tree.DString:
+		return string(*t), nil
+	case *tree.DCollatedString:
+		return t.Contents, nil
+	case *tree.DJSON, *tree.DArray, *tree.DTuple:
+		return "", pgerror.NewError(pgerror.CodeInvalidParameterValueError,
+			"key value must be scalar, not array, tuple, or json")
+	}
+	if d == tree.DNull {
+		return "", pgerror.NewErrorf(pgerror.CodeInvalidParameterValueError,
+			"argument %d cannot be null", i+1)
+	}
+	return tree.AsStringWithFlags(d, tree.FmtBareStrings), nil
+}
+
+var jsonTypeOfImpls = []tree.Builtin{
+	{
+		Types:      tree.ArgTypes{{"val", types.JSON}},
+		ReturnType: tree.FixedReturnType(types.String),
+		Category:   categoryJSON,
+		Fn: func(_ *tree.EvalContext, args tree.Datums) (tree.Datum, error) {
+			switch args[0].(*tree.DJSON).Type() {
+			case json.NullJSONType:
+				return tree.NewDString("null"), nil
+			case json.StringJSONType:
+				return tree.NewDString("string"), nil
+			case json.NumberJSONType:
+				return tree.NewDString("number"), nil
+			case json.FalseJSONType, json.TrueJSONType:
+				return tree.NewDString("boolean"), nil
+			case json.ArrayJSONType:
+				return tree.NewDString("array"), nil
+			case json.ObjectJSONType:
+				return tree.NewDString("object"), nil
+			}
+			return nil, errors.Errorf("unexpected JSON type %d", args[0].(*tree.DJSON).Type())
+		},
+		Info: "Returns the type of the outermost JSON value as a text string.",
+	},
+}
+
+var jsonStripNullsImpls = []tree.Builtin{
+	{
+		Types:      tree.ArgTypes{{"from_json", types.JSON}},
+		ReturnType: tree.FixedReturnType(types.JSON),
+		Category:   categoryJSON,
+		Fn: func(_ *tree.EvalContext, args tree.Datums) (tree.Datum, error) {
+			return &tree.DJSON{JSON: args[0].(*tree.DJSON).StripNulls()}, nil
+		},
+		Info: "Returns from_json with all object fields that have null values omitted. " +
+			"Other null values are untouched.",
+	},
+}
+
+func makeJSONSetBuiltin(argTypes tree.ArgTypes) tree.Builtin {
+	return tree.Builtin{
+		Types:      argTypes,
+		ReturnType: tree.FixedReturnType(types.JSON),
+		Category:   categoryJSON,
+		Fn: func(_ *tree.EvalContext, args tree.Datums) (tree.Datum, error) {
+			path, err := darrayToStringSlice(*tree.MustBeDArray(args[1]))
+			if err != nil {
+				return nil, err
+			}
+			createMissing := true
+			if len(args) > 3 {
+				createMissing = bool(*args[3].(*tree.DBool))
+			}
+			j, err := json.DeepSet(
+				args[0].(*tree.DJSON).JSON, path, args[2].(*tree.DJSON).JSON, createMissing,
+			)
+			if err != nil {
+				return nil, err
+			}
+			return &tree.DJSON{JSON: j}, nil
+		},
+		Info: "Returns `val` with the value at `path` replaced by `to`. If `path` designates " +
+			"a missing object key or an array position past either end of its array, `to` is " +
+			"inserted there, unless `create_missing` is false.",
+	}
+}
+
+func makeJSONInsertBuiltin(argTypes tree.ArgTypes) tree.Builtin {
+	return tree.Builtin{
+		Types:      argTypes,
+		ReturnType: tree.FixedReturnType(types.JSON),
+		Category:   categoryJSON,
+		Fn: func(_ *tree.EvalContext, args tree.Datums) (tree.Datum, error) {
+			path, err := darrayToStringSlice(*tree.MustBeDArray(args[1]))
+			if err != nil {
+				return nil, err
+			}
+			insertAfter := false
+			if len(args) > 3 {
+				insertAfter = bool(*args[3].(*tree.DBool))
+			}
+			j, err := json.DeepInsert(
+				args[0].(*tree.DJSON).JSON, path, args[2].(*tree.DJSON).JSON, insertAfter,
+			)
+			if err != nil {
+				return nil, err
+			}
+			return &tree.DJSON{JSON: j}, nil
+		},
+		Info: "Returns `target` with `new_val` inserted at `path`. If `path` designates an " +
+			"array element, `new_val` is inserted before it, or after it if `insert_after` is " +
+			"true. If it designates an object key, the key is created; it must not already exist.",
+	}
+}
+
+// makeTextSearchBuiltins returns the overloads of a full-text search
+// builtin taking an optional text search configuration name followed by the
+// text to process.
+func makeTextSearchBuiltins(
+	returnType types.T, fn func(*tsearch.Config, string) (tree.Datum, error), info string,
+) []tree.Builtin {
+	return []tree.Builtin{
+		{
+			Types:      tree.ArgTypes{{"text", types.String}},
+			ReturnType: tree.FixedReturnType(returnType),
+			Category:   categoryFullTextSearch,
+			Fn: func(_ *tree.EvalContext, args tree.Datums) (tree.Datum, error) {
+				return fn(tsearch.DefaultConfig, string(tree.MustBeDString(args[0])))
+			},
+			Info: info + " The default configuration is `english`.",
+		},
+		{
+			Types:      tree.ArgTypes{{"config", types.String}, {"text", types.String}},
+			ReturnType: tree.FixedReturnType(returnType),
+			Category:   categoryFullTextSearch,
+			Fn: func(_ *tree.EvalContext, args tree.Datums) (tree.Datum, error) {
+				config, err := tsearch.GetConfig(string(tree.MustBeDString(args[0])))
+				if err != nil {
+					return nil, err
+				}
+				return fn(config, string(tree.MustBeDString(args[1])))
+			},
+			Info: info,
+		},
+	}
+}
+
+// jsonPathArgTypes returns the first n of the argument types shared by the
+// jsonb_path_* builtins.
+func jsonPathArgTypes(n int) tree.ArgTypes {
+	return tree.ArgTypes{
+		{"target", types.JSON},
+		{"path", types.String},
+		{"vars", types.JSON},
+		{"silent", types.Bool},
+	}[:n]
+}
+
+// jsonPathArgs extracts the arguments of a jsonb_path_* builtin: the target
+// JSON value, the parsed path, and the optional vars object and silent
+// flag.
+func jsonPathArgs(
+	args tree.Datums,
+) (target json.JSON, path *json.Path, vars json.JSON, silent bool, err error) {
+	path, err = json.ParsePath(string(tree.MustBeDString(args[1])))
+	if err != nil {
+		return nil, nil, nil, false, err
+	}
+	if len(args) > 2 {
+		vars = args[2].(*tree.DJSON).JSON
+	}
+	if len(args) > 3 {
+		silent = bool(*args[3].(*tree.DBool))
+	}
+	return args[0].(*tree.DJSON).JSON, path, vars, silent, nil
+}
+
+func makeJSONPathExistsBuiltin(argTypes tree.ArgTypes) tree.Builtin {
+	return tree.Builtin{
+		Types:      argTypes,
+		ReturnType: tree.FixedReturnType(types.Bool),
+		Category:   categoryJSON,
+		Fn: func(_ *tree.EvalContext, args tree.Datums) (tree.Datum, error) {
+			target, path, vars, silent, err := jsonPathArgs(args)
+			if err != nil {
+				return nil, err
+			}
+			exists, err := path.Exists(target, vars)
+			if err != nil {
+				if silent {
+					return tree.DNull, nil
+				}
+				return nil, err
+			}
+			return tree.MakeDBool(tree.DBool(exists)), nil
+		},
+		Info: "Returns whether the SQL/JSON path returns any item for the JSON value. " +
+			"`vars` is an object holding the values of the variables referenced by the path. " +
+			"If `silent` is true, evaluation errors are suppressed and NULL is returned instead.",
+	}
+}
+
+func makeJSONPathMatchBuiltin(argTypes tree.ArgTypes) tree.Builtin {
+	return tree.Builtin{
+		Types:      argTypes,
+		ReturnType: tree.FixedReturnType(types.Bool),
+		Category:   categoryJSON,
+		Fn: func(_ *tree.EvalContext, args tree.Datums) (tree.Datum, error) {
+			target, path, vars, silent, err := jsonPathArgs(args)
+			if err != nil {
+				return nil, err
+			}
+			match, err := path.Match(target, vars)
+			if err != nil {
+				if silent {
+					return tree.DNull, nil
+				}
+				return nil, err
+			}
+			if match == nil {
+				return tree.DNull, nil
+			}
+			return tree.MakeDBool(tree.DBool(*match)), nil
+		},
+		Info: "Returns the result of the SQL/JSON path predicate check for the JSON value. " +
+			"`vars` is an object holding the values of the variables referenced by the path. " +
+			"If `silent` is true, evaluation errors are suppressed and NULL is returned instead.",
+	}
+}
+
+// darrayToStringSlice converts an array of strings to a []string. It returns
+// an error if any element of the array is NULL.
+func darrayToStringSlice(d tree.DArray) ([]string, error) {
+	result := make([]string, len(d.Array))
+	for i, s := range d.Array {
+		if s == tree.DNull {
+			return nil, pgerror.NewErrorf(pgerror.CodeNullValueNotAllowedError,
+				"path element at position %d is null", i+1)
+		}
+		result[i] = string(tree.MustBeDString(s))
+	}
+	return result, nil
+}
+
 func arrayBuiltin(impl func(types.T) tree.Builtin) []tree.Builtin {
 	result := make([]tree.Builtin, 0, len(types.AnyNonArray))
 	for _, typ := range types.AnyNonArray {
@@ -22,6 +22,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/types"
+	"github.com/cockroachdb/cockroach/pkg/util/json"
 )
 
 // See the comments at the start of generators.go for details about
@@ -33,6 +34,10 @@ type generatorFactory func(ctx *tree.EvalContext, args tree.Datums) (tree.ValueG
 
 var _ tree.ValueGenerator = &seriesValueGenerator{}
 var _ tree.ValueGenerator = &arrayValueGenerator{}
+var _ tree.ValueGenerator = &jsonArrayGenerator{}
+var _ tree.ValueGenerator = &jsonObjectKeysGenerator{}
+var _ tree.ValueGenerator = &jsonEachGenerator{}
+var _ tree.ValueGenerator = &jsonPathQueryGenerator{}
 
 func initGeneratorBuiltins() {
 	// Add all windows to the Builtins map after a few sanity checks.
@@ -67,6 +72,14 @@ var Generators = map[string][]tree.Builtin{
 			"Produces a virtual table containing the integer values from `start` to `end`, inclusive, by increment of `step`.",
 		),
 	},
+	"pg_listening_channels": {
+		makeGeneratorBuiltin(
+			tree.ArgTypes{},
+			listeningChannelsValueGeneratorType,
+			makeListeningChannelsGenerator,
+			"Produces a virtual table containing the channels on which the session listens.",
+		),
+	},
 	"pg_get_keywords": {
 		makeGeneratorBuiltin(
 			tree.ArgTypes{},
@@ -91,6 +104,21 @@ var Generators = map[string][]tree.Builtin{
 			"Returns the input array as a set of rows",
 		),
 	},
+	"json_array_elements":       {jsonArrayElementsImpl},
+	"jsonb_array_elements":      {jsonArrayElementsImpl},
+	"json_array_elements_text":  {jsonArrayElementsTextImpl},
+	"jsonb_array_elements_text": {jsonArrayElementsTextImpl},
+	"json_object_keys":          {jsonObjectKeysImpl},
+	"jsonb_object_keys":         {jsonObjectKeysImpl},
+	"json_each":                 {jsonEachImpl},
+	"jsonb_each":                {jsonEachImpl},
+	"json_each_text":            {jsonEachTextImpl},
+	"jsonb_each_text":           {jsonEachTextImpl},
+	"jsonb_path_query": {
+		makeJSONPathQueryImpl(jsonPathArgTypes(2)),
+		makeJSONPathQueryImpl(jsonPathArgTypes(3)),
+		makeJSONPathQueryImpl(jsonPathArgTypes(4)),
+	},
 	"crdb_internal.unary_table": {
 		makeGeneratorBuiltin(
 			tree.ArgTypes{},
@@ -188,6 +216,49 @@ var keywordNames = func() []string {
 	return ret
 }()
 
+// listeningChannelsValueGenerator supports the execution of
+// pg_listening_channels().
+type listeningChannelsValueGenerator struct {
+	channels   []string
+	curChannel int
+}
+
+var listeningChannelsValueGeneratorType = types.TTable{
+	Cols:   types.TTuple{types.String},
+	Labels: []string{"pg_listening_channels"},
+}
+
+func makeListeningChannelsGenerator(
+	ctx *tree.EvalContext, _ tree.Datums,
+) (tree.ValueGenerator, error) {
+	return &listeningChannelsValueGenerator{channels: ctx.Planner.ListeningChannels()}, nil
+}
+
+// ResolvedType implements the tree.ValueGenerator interface.
+func (*listeningChannelsValueGenerator) ResolvedType() types.TTable {
+	return listeningChannelsValueGeneratorType
+}
+
+// Close implements the tree.ValueGenerator interface.
+func (*listeningChannelsValueGenerator) Close() {}
+
+// Start implements the tree.ValueGenerator interface.
+func (c *listeningChannelsValueGenerator) Start() error {
+	c.curChannel = -1
+	return nil
+}
+
+// Next implements the tree.ValueGenerator interface.
+func (c *listeningChannelsValueGenerator) Next() (bool, error) {
+	c.curChannel++
+	return c.curChannel < len(c.channels), nil
+}
+
+// Values implements the tree.ValueGenerator interface.
+func (c *listeningChannelsValueGenerator) Values() tree.Datums {
+	return tree.Datums{tree.NewDString(c.channels[c.curChannel])}
+}
+
 // seriesValueGenerator supports the execution of generate_series()
 // with integer bounds.
 type seriesValueGenerator struct {
@@ -335,3 +406,318 @@ func (s *unaryValueGenerator) Next() (bool, error) {
 
 // Values implements the tree.ValueGenerator interface.
 func (s *unaryValueGenerator) Values() tree.Datums { return tree.Datums{} }
+
+var jsonArrayElementsImpl = makeGeneratorBuiltin(
+	tree.ArgTypes{{"input", types.JSON}},
+	jsonArrayGeneratorType,
+	makeJSONArrayAsJSONGenerator,
+	"Expands a JSON array to a set of JSON values.",
+)
+
+var jsonArrayElementsTextImpl = makeGeneratorBuiltin(
+	tree.ArgTypes{{"input", types.JSON}},
+	jsonArrayTextGeneratorType,
+	makeJSONArrayAsTextGenerator,
+	"Expands a JSON array to a set of text values.",
+)
+
+var jsonArrayGeneratorType = types.TTable{
+	Cols:   types.TTuple{types.JSON},
+	Labels: []string{"value"},
+}
+
+var jsonArrayTextGeneratorType = types.TTable{
+	Cols:   types.TTuple{types.String},
+	Labels: []string{"value"},
+}
+
+// jsonArrayGenerator supports the execution of json_array_elements() and
+// json_array_elements_text().
+type jsonArrayGenerator struct {
+	json      tree.DJSON
+	nextIndex int
+	asText    bool
+	value     tree.Datum
+}
+
+var errJSONCallOnNonArray = pgerror.NewError(pgerror.CodeInvalidParameterValueError,
+	"cannot extract elements from a non-array")
+
+func makeJSONArrayAsJSONGenerator(
+	_ *tree.EvalContext, args tree.Datums,
+) (tree.ValueGenerator, error) {
+	return makeJSONArrayGenerator(args, false)
+}
+
+func makeJSONArrayAsTextGenerator(
+	_ *tree.EvalContext, args tree.Datums,
+) (tree.ValueGenerator, error) {
+	return makeJSONArrayGenerator(args, true)
+}
+
+func makeJSONArrayGenerator(args tree.Datums, asText bool) (tree.ValueGenerator, error) {
+	target := tree.MustBeDJSON(args[0])
+	if target.Type() != json.ArrayJSONType {
+		return nil, errJSONCallOnNonArray
+	}
+	return &jsonArrayGenerator{
+		json:   target,
+		asText: asText,
+	}, nil
+}
+
+// ResolvedType implements the tree.ValueGenerator interface.
+func (g *jsonArrayGenerator) ResolvedType() types.TTable {
+	if g.asText {
+		return jsonArrayTextGeneratorType
+	}
+	return jsonArrayGeneratorType
+}
+
+// Start implements the tree.ValueGenerator interface.
+func (g *jsonArrayGenerator) Start() error {
+	g.nextIndex = -1
+	return nil
+}
+
+// Close implements the tree.ValueGenerator interface.
+func (g *jsonArrayGenerator) Close() {}
+
+// Next implements the tree.ValueGenerator interface.
+func (g *jsonArrayGenerator) Next() (bool, error) {
+	g.nextIndex++
+	next := g.json.FetchValIdx(g.nextIndex)
+	if next == nil {
+		return false, nil
+	}
+	if g.asText {
+		if text := next.AsText(); text != nil {
+			g.value = tree.NewDString(*text)
+		} else {
+			g.value = tree.DNull
+		}
+	} else {
+		g.value = tree.NewDJSON(next)
+	}
+	return true, nil
+}
+
+// Values implements the tree.ValueGenerator interface.
+func (g *jsonArrayGenerator) Values() tree.Datums {
+	return tree.Datums{g.value}
+}
+
+var jsonObjectKeysImpl = makeGeneratorBuiltin(
+	tree.ArgTypes{{"input", types.JSON}},
+	jsonObjectKeysGeneratorType,
+	makeJSONObjectKeysGenerator,
+	"Returns sorted set of keys in the outermost JSON object.",
+)
+
+var jsonObjectKeysGeneratorType = types.TTable{
+	Cols:   types.TTuple{types.String},
+	Labels: []string{"json_object_keys"},
+}
+
+// jsonObjectKeysGenerator supports the execution of json_object_keys().
+type jsonObjectKeysGenerator struct {
+	target tree.DJSON
+	iter   *json.ObjectIterator
+}
+
+var errJSONObjectKeysOnNonObject = pgerror.NewError(pgerror.CodeInvalidParameterValueError,
+	"cannot call json_object_keys on a non-object")
+
+func makeJSONObjectKeysGenerator(
+	_ *tree.EvalContext, args tree.Datums,
+) (tree.ValueGenerator, error) {
+	target := tree.MustBeDJSON(args[0])
+	if target.Type() != json.ObjectJSONType {
+		return nil, errJSONObjectKeysOnNonObject
+	}
+	return &jsonObjectKeysGenerator{target: target}, nil
+}
+
+// ResolvedType implements the tree.ValueGenerator interface.
+func (*jsonObjectKeysGenerator) ResolvedType() types.TTable {
+	return jsonObjectKeysGeneratorType
+}
+
+// Start implements the tree.ValueGenerator interface.
+func (g *jsonObjectKeysGenerator) Start() error {
+	g.iter = g.target.ObjectIter()
+	return nil
+}
+
+// Close implements the tree.ValueGenerator interface.
+func (*jsonObjectKeysGenerator) Close() {}
+
+// Next implements the tree.ValueGenerator interface.
+func (g *jsonObjectKeysGenerator) Next() (bool, error) {
+	return g.iter.Next(), nil
+}
+
+// Values implements the tree.ValueGenerator interface.
+func (g *jsonObjectKeysGenerator) Values() tree.Datums {
+	return tree.Datums{tree.NewDString(g.iter.Key())}
+}
+
+var jsonEachImpl = makeGeneratorBuiltin(
+	tree.ArgTypes{{"input", types.JSON}},
+	jsonEachGeneratorType,
+	makeJSONEachGenerator,
+	"Expands the outermost JSON or JSONB object into a set of key/value pairs.",
+)
+
+var jsonEachTextImpl = makeGeneratorBuiltin(
+	tree.ArgTypes{{"input", types.JSON}},
+	jsonEachTextGeneratorType,
+	makeJSONEachTextGenerator,
+	"Expands the outermost JSON or JSONB object into a set of key/value pairs. "+
+		"The returned values will be of type text.",
+)
+
+var jsonEachGeneratorType = types.TTable{
+	Cols:   types.TTuple{types.String, types.JSON},
+	Labels: []string{"key", "value"},
+}
+
+var jsonEachTextGeneratorType = types.TTable{
+	Cols:   types.TTuple{types.String, types.String},
+	Labels: []string{"key", "value"},
+}
+
+// jsonEachGenerator supports the execution of json_each() and
+// json_each_text().
+type jsonEachGenerator struct {
+	target tree.DJSON
+	iter   *json.ObjectIterator
+	key    tree.Datum
+	value  tree.Datum
+	asText bool
+}
+
+var errJSONEachOnNonObject = pgerror.NewError(pgerror.CodeInvalidParameterValueError,
+	"cannot deconstruct a non-object")
+
+func makeJSONEachGenerator(_ *tree.EvalContext, args tree.Datums) (tree.ValueGenerator, error) {
+	return makeJSONEachImplGenerator(args, false)
+}
+
+func makeJSONEachTextGenerator(
+	_ *tree.EvalContext, args tree.Datums,
+) (tree.ValueGenerator, error) {
+	return makeJSONEachImplGenerator(args, true)
+}
+
+func makeJSONEachImplGenerator(args tree.Datums, asText bool) (tree.ValueGenerator, error) {
+	target := tree.MustBeDJSON(args[0])
+	if target.Type() != json.ObjectJSONType {
+		return nil, errJSONEachOnNonObject
+	}
+	return &jsonEachGenerator{
+		target: target,
+		asText: asText,
+	}, nil
+}
+
+// ResolvedType implements the tree.ValueGenerator interface.
+func (g *jsonEachGenerator) ResolvedType() types.TTable {
+	if g.asText {
+		return jsonEachTextGeneratorType
+	}
+	return jsonEachGeneratorType
+}
+
+// Start implements the tree.ValueGenerator interface.
+func (g *jsonEachGenerator) Start() error {
+	g.iter = g.target.ObjectIter()
+	return nil
+}
+
+// Close implements the tree.ValueGenerator interface.
+func (g *jsonEachGenerator) Close() {}
+
+// Next implements the tree.ValueGenerator interface.
+func (g *jsonEachGenerator) Next() (bool, error) {
+	if !g.iter.Next() {
+		return false, nil
+	}
+	g.key = tree.NewDString(g.iter.Key())
+	if g.asText {
+		if s := g.iter.Value().AsText(); s != nil {
+			g.value = tree.NewDString(*s)
+		} else {
+			g.value = tree.DNull
+		}
+	} else {
+		g.value = tree.NewDJSON(g.iter.Value())
+	}
+	return true, nil
+}
+
+// Values implements the tree.ValueGenerator interface.
+func (g *jsonEachGenerator) Values() tree.Datums {
+	return tree.Datums{g.key, g.value}
+}
+
+func makeJSONPathQueryImpl(argTypes tree.ArgTypes) tree.Builtin {
+	return makeGeneratorBuiltin(
+		argTypes,
+		jsonPathQueryGeneratorType,
+		makeJSONPathQueryGenerator,
+		"Returns the items returned by the SQL/JSON path for the JSON value. "+
+			"`vars` is an object holding the values of the variables referenced by the path. "+
+			"If `silent` is true, evaluation errors are suppressed and no rows are returned.",
+	)
+}
+
+var jsonPathQueryGeneratorType = types.TTable{
+	Cols:   types.TTuple{types.JSON},
+	Labels: []string{"jsonb_path_query"},
+}
+
+// jsonPathQueryGenerator supports the execution of jsonb_path_query().
+type jsonPathQueryGenerator struct {
+	items     []json.JSON
+	nextIndex int
+}
+
+func makeJSONPathQueryGenerator(
+	_ *tree.EvalContext, args tree.Datums,
+) (tree.ValueGenerator, error) {
+	target, path, vars, silent, err := jsonPathArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	items, err := path.Query(target, vars)
+	if err != nil && !silent {
+		return nil, err
+	}
+	return &jsonPathQueryGenerator{items: items}, nil
+}
+
+// ResolvedType implements the tree.ValueGenerator interface.
+func (g *jsonPathQueryGenerator) ResolvedType() types.TTable {
+	return jsonPathQueryGeneratorType
+}
+
+// Start implements the tree.ValueGenerator interface.
+func (g *jsonPathQueryGenerator) Start() error {
+	g.nextIndex = -1
+	return nil
+}
+
+// Close implements the tree.ValueGenerator interface.
+func (g *jsonPathQueryGenerator) Close() {}
+
+// Next implements the tree.ValueGenerator interface.
+func (g *jsonPathQueryGenerator) Next() (bool, error) {
+	g.nextIndex++
+	return g.nextIndex < len(g.items), nil
+}
+
+// Values implements the tree.ValueGenerator interface.
+func (g *jsonPathQueryGenerator) Values() tree.Datums {
+	return tree.Datums{tree.NewDJSON(g.items[g.nextIndex])}
+}
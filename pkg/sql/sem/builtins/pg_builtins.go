@@ -370,6 +370,22 @@ var pgBuiltins = map[string][]tree.Builtin{
 			Info: notUsableInfo,
 		},
 	},
+	// See https://www.postgresql.org/docs/9.6/static/sql-notify.html.
+	"pg_notify": {
+		tree.Builtin{
+			Types:            tree.ArgTypes{{"channel", types.String}, {"payload", types.String}},
+			ReturnType:       tree.FixedReturnType(types.Null),
+			Impure:           true,
+			DistsqlBlacklist: true,
+			Fn: func(ctx *tree.EvalContext, args tree.Datums) (tree.Datum, error) {
+				err := ctx.Planner.SendNotification(ctx.Ctx(),
+					string(tree.MustBeDString(args[0])), string(tree.MustBeDString(args[1])))
+				return tree.DNull, err
+			},
+			Info: "Sends a notification with the given payload on the given channel when " +
+				"the transaction commits, like the NOTIFY statement.",
+		},
+	},
 	// pg_table_is_visible returns true if the input oid corresponds to a table
 	// that is part of the databases on the search path.
 	// https://www.postgresql.org/docs/9.6/static/functions-info.html
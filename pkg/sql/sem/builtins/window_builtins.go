@@ -156,7 +156,10 @@ func (w *aggregateWindowFunc) Compute(
 	// Accumulate all values in the peer group at the same time, as these
 	// must return the same value.
 	for i := 0; i < wf.PeerRowCount; i++ {
-		args := wf.ArgsWithRowOffset(i)
+		args, err := wf.ArgsWithRowOffset(ctx, i)
+		if err != nil {
+			return nil, err
+		}
 		var value tree.Datum
 		// COUNT_ROWS takes no arguments.
 		if len(args) > 0 {
@@ -307,13 +310,17 @@ var errInvalidArgumentForNtile = pgerror.NewErrorf(
 	pgerror.CodeInvalidParameterValueError, "argument of ntile() must be greater than zero")
 
 func (w *ntileWindow) Compute(
-	_ context.Context, _ *tree.EvalContext, wf tree.WindowFrame,
+	ctx context.Context, _ *tree.EvalContext, wf tree.WindowFrame,
 ) (tree.Datum, error) {
 	if w.ntile == nil {
 		// If this is the first call to ntileWindow.Compute, set up the buckets.
 		total := wf.RowCount()
 
-		arg := wf.Args()[0]
+		args, err := wf.Args(ctx)
+		if err != nil {
+			return nil, err
+		}
+		arg := args[0]
 		if arg == tree.DNull {
 			// per spec: If argument is the null value, then the result is the null value.
 			return tree.DNull, nil
@@ -377,11 +384,15 @@ func makeLeadLagWindowConstructor(
 }
 
 func (w *leadLagWindow) Compute(
-	_ context.Context, _ *tree.EvalContext, wf tree.WindowFrame,
+	ctx context.Context, _ *tree.EvalContext, wf tree.WindowFrame,
 ) (tree.Datum, error) {
+	args, err := wf.Args(ctx)
+	if err != nil {
+		return nil, err
+	}
 	offset := 1
 	if w.withOffset {
-		offsetArg := wf.Args()[1]
+		offsetArg := args[1]
 		if offsetArg == tree.DNull {
 			return tree.DNull, nil
 		}
@@ -395,12 +406,16 @@ func (w *leadLagWindow) Compute(
 		// Target row is out of the partition; supply default value if provided,
 		// otherwise return NULL.
 		if w.withDefault {
-			return wf.Args()[2], nil
+			return args[2], nil
 		}
 		return tree.DNull, nil
 	}
 
-	return wf.ArgsWithRowOffset(offset)[0], nil
+	targetArgs, err := wf.ArgsWithRowOffset(ctx, offset)
+	if err != nil {
+		return nil, err
+	}
+	return targetArgs[0], nil
 }
 
 func (w *leadLagWindow) Close(context.Context, *tree.EvalContext) {}
@@ -413,9 +428,13 @@ func newFirstValueWindow([]types.T, *tree.EvalContext) tree.WindowFunc {
 }
 
 func (firstValueWindow) Compute(
-	_ context.Context, _ *tree.EvalContext, wf tree.WindowFrame,
+	ctx context.Context, _ *tree.EvalContext, wf tree.WindowFrame,
 ) (tree.Datum, error) {
-	return wf.Rows[0].Row[wf.ArgIdxStart], nil
+	args, err := wf.ArgsByRowIdx(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	return args[0], nil
 }
 
 func (firstValueWindow) Close(context.Context, *tree.EvalContext) {}
@@ -428,9 +447,13 @@ func newLastValueWindow([]types.T, *tree.EvalContext) tree.WindowFunc {
 }
 
 func (lastValueWindow) Compute(
-	_ context.Context, _ *tree.EvalContext, wf tree.WindowFrame,
+	ctx context.Context, _ *tree.EvalContext, wf tree.WindowFrame,
 ) (tree.Datum, error) {
-	return wf.Rows[wf.FrameSize()-1].Row[wf.ArgIdxStart], nil
+	args, err := wf.ArgsByRowIdx(ctx, wf.FrameSize()-1)
+	if err != nil {
+		return nil, err
+	}
+	return args[0], nil
 }
 
 func (lastValueWindow) Close(context.Context, *tree.EvalContext) {}
@@ -447,9 +470,13 @@ var errInvalidArgumentForNthValue = pgerror.NewErrorf(
 	pgerror.CodeInvalidParameterValueError, "argument of nth_value() must be greater than zero")
 
 func (nthValueWindow) Compute(
-	_ context.Context, _ *tree.EvalContext, wf tree.WindowFrame,
+	ctx context.Context, _ *tree.EvalContext, wf tree.WindowFrame,
 ) (tree.Datum, error) {
-	arg := wf.Args()[1]
+	args, err := wf.Args(ctx)
+	if err != nil {
+		return nil, err
+	}
+	arg := args[1]
 	if arg == tree.DNull {
 		return tree.DNull, nil
 	}
@@ -464,7 +491,11 @@ func (nthValueWindow) Compute(
 	if nth > wf.FrameSize() {
 		return tree.DNull, nil
 	}
-	return wf.Rows[nth-1].Row[wf.ArgIdxStart], nil
+	nthArgs, err := wf.ArgsByRowIdx(ctx, nth-1)
+	if err != nil {
+		return nil, err
+	}
+	return nthArgs[0], nil
 }
 
 func (nthValueWindow) Close(context.Context, *tree.EvalContext) {}
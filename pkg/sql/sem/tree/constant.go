@@ -66,6 +66,12 @@ func isConstant(expr Expr) bool {
 }
 
 func typeCheckConstant(c Constant, ctx *SemaContext, desired types.T) (TypedExpr, error) {
+	if locale, ok := constantCollation(c, desired); ok {
+		// A string literal takes on the collation desired of it, e.g. when
+		// inserted into a collated string column.
+		return (&CollateExpr{Expr: c, Locale: locale}).TypeCheck(ctx, desired)
+	}
+
 	avail := c.AvailableTypes()
 	if desired != types.Any {
 		for _, typ := range avail {
@@ -102,6 +108,9 @@ func naturalConstantType(c Constant) types.T {
 // canConstantBecome returns whether the provided Constant can become resolved
 // as the provided type.
 func canConstantBecome(c Constant, typ types.T) bool {
+	if _, ok := constantCollation(c, typ); ok {
+		return true
+	}
 	avail := c.AvailableTypes()
 	for _, availTyp := range avail {
 		if availTyp.Equivalent(typ) {
@@ -111,6 +120,20 @@ func canConstantBecome(c Constant, typ types.T) bool {
 	return false
 }
 
+// constantCollation returns the locale of typ if it is a collated string type
+// with a known locale and c is a string literal, which can then become a
+// collated string of that locale.
+func constantCollation(c Constant, typ types.T) (string, bool) {
+	if s, ok := c.(*StrVal); !ok || s.bytesEsc {
+		return "", false
+	}
+	t, ok := types.UnwrapType(typ).(types.TCollatedString)
+	if !ok || t.Locale == "" {
+		return "", false
+	}
+	return t.Locale, true
+}
+
 // NumVal represents a constant numeric value.
 type NumVal struct {
 	constant.Value
@@ -393,6 +416,8 @@ var (
 		types.UUID,
 		types.INet,
 		types.JSON,
+		types.TSVector,
+		types.TSQuery,
 	}
 	// StrValAvailBytesString is the set of types convertible to either
 	// byte array or string.
@@ -476,6 +501,10 @@ func (expr *StrVal) ResolveAsType(ctx *SemaContext, typ types.T) (Datum, error)
 		return ParseDIPAddrFromINetString(expr.s)
 	case types.JSON:
 		return ParseDJSON(expr.s)
+	case types.TSVector:
+		return ParseDTSVector(expr.s)
+	case types.TSQuery:
+		return ParseDTSQuery(expr.s)
 	case types.Timestamp:
 		return ParseDTimestamp(expr.s, time.Microsecond)
 	case types.TimestampTZ:
@@ -14,13 +14,20 @@
 
 package tree
 
-import "bytes"
+import (
+	"bytes"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/lex"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+)
 
 // CopyFrom represents a COPY FROM statement.
 type CopyFrom struct {
 	Table   NormalizableTableName
 	Columns UnresolvedNames
 	Stdin   bool
+	Options CopyOptions
 }
 
 // Format implements the NodeFormatter interface.
@@ -36,4 +43,202 @@ func (node *CopyFrom) Format(buf *bytes.Buffer, f FmtFlags) {
 	if node.Stdin {
 		buf.WriteString("STDIN")
 	}
+	FormatNode(buf, f, &node.Options)
+}
+
+// CopyTo represents a COPY TO statement. Either a table, with an optional
+// list of columns, or a query is copied.
+type CopyTo struct {
+	Table   NormalizableTableName
+	Columns UnresolvedNames
+	// Query is set when the results of a query are copied instead of a table.
+	Query   *Select
+	Options CopyOptions
+}
+
+// Format implements the NodeFormatter interface.
+func (node *CopyTo) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("COPY ")
+	if node.Query != nil {
+		buf.WriteString("(")
+		FormatNode(buf, f, node.Query)
+		buf.WriteString(")")
+	} else {
+		FormatNode(buf, f, &node.Table)
+		if len(node.Columns) > 0 {
+			buf.WriteString(" (")
+			FormatNode(buf, f, node.Columns)
+			buf.WriteString(")")
+		}
+	}
+	buf.WriteString(" TO STDOUT")
+	FormatNode(buf, f, &node.Options)
+}
+
+// CopyFormat is the data format of a COPY statement.
+type CopyFormat int
+
+// CopyFormat values.
+const (
+	CopyFormatUnspecified CopyFormat = iota
+	CopyFormatText
+	CopyFormatCSV
+	CopyFormatBinary
+)
+
+var copyFormatNames = [...]string{
+	CopyFormatUnspecified: "",
+	CopyFormatText:        "text",
+	CopyFormatCSV:         "csv",
+	CopyFormatBinary:      "binary",
+}
+
+func (f CopyFormat) String() string {
+	return copyFormatNames[f]
+}
+
+// CopyOptions represents the options of a COPY statement. The fields are
+// left unset (CopyFormatUnspecified or nil) when the option is not given,
+// in which case the default of the format applies.
+type CopyOptions struct {
+	FileFormat CopyFormat
+	Header     *bool
+	Delimiter  *string
+	Null       *string
+	Quote      *string
+}
+
+// Format implements the NodeFormatter interface.
+func (node *CopyOptions) Format(buf *bytes.Buffer, f FmtFlags) {
+	sep := " WITH ("
+	writeSep := func() {
+		buf.WriteString(sep)
+		sep = ", "
+	}
+	if node.FileFormat != CopyFormatUnspecified {
+		writeSep()
+		buf.WriteString("FORMAT ")
+		buf.WriteString(node.FileFormat.String())
+	}
+	if node.Header != nil {
+		writeSep()
+		if *node.Header {
+			buf.WriteString("HEADER true")
+		} else {
+			buf.WriteString("HEADER false")
+		}
+	}
+	for _, o := range []struct {
+		name string
+		val  *string
+	}{
+		{"DELIMITER", node.Delimiter},
+		{"NULL", node.Null},
+		{"QUOTE", node.Quote},
+	} {
+		if o.val != nil {
+			writeSep()
+			buf.WriteString(o.name)
+			buf.WriteByte(' ')
+			lex.EncodeSQLStringWithFlags(buf, *o.val, f.encodeFlags)
+		}
+	}
+	if sep != " WITH (" {
+		buf.WriteString(")")
+	}
+}
+
+// MakeCopyOption builds the options of a COPY statement from a single
+// "name [value]" option of the parenthesized option list. Used in the parser.
+func MakeCopyOption(name string, value string, hasValue bool) (CopyOptions, error) {
+	var o CopyOptions
+	switch strings.ToLower(name) {
+	case "format":
+		if !hasValue {
+			return o, errCopyOptionRequiresParameter(name)
+		}
+		switch strings.ToLower(value) {
+		case "text":
+			o.FileFormat = CopyFormatText
+		case "csv":
+			o.FileFormat = CopyFormatCSV
+		case "binary":
+			o.FileFormat = CopyFormatBinary
+		default:
+			return o, pgerror.NewErrorf(pgerror.CodeInvalidParameterValueError,
+				"COPY format %q not recognized", value)
+		}
+	case "header":
+		header := true
+		if hasValue {
+			switch strings.ToLower(value) {
+			case "true", "on", "1":
+			case "false", "off", "0":
+				header = false
+			default:
+				return o, pgerror.NewErrorf(pgerror.CodeSyntaxError,
+					"%s requires a Boolean value", name)
+			}
+		}
+		o.Header = &header
+	case "delimiter", "null", "quote":
+		if !hasValue {
+			return o, errCopyOptionRequiresParameter(name)
+		}
+		switch strings.ToLower(name) {
+		case "delimiter":
+			o.Delimiter = &value
+		case "null":
+			o.Null = &value
+		case "quote":
+			o.Quote = &value
+		}
+	default:
+		return o, pgerror.NewErrorf(pgerror.CodeSyntaxError,
+			"option %q not recognized", name)
+	}
+	return o, nil
+}
+
+func errCopyOptionRequiresParameter(name string) error {
+	return pgerror.NewErrorf(pgerror.CodeSyntaxError, "%s requires a parameter", name)
+}
+
+var errCopyOptionsConflict = pgerror.NewError(pgerror.CodeSyntaxError,
+	"conflicting or redundant options")
+
+// Merge groups two sets of COPY options together.
+// Used in the parser.
+func (node *CopyOptions) Merge(other CopyOptions) error {
+	if other.FileFormat != CopyFormatUnspecified {
+		if node.FileFormat != CopyFormatUnspecified {
+			return errCopyOptionsConflict
+		}
+		node.FileFormat = other.FileFormat
+	}
+	if other.Header != nil {
+		if node.Header != nil {
+			return errCopyOptionsConflict
+		}
+		node.Header = other.Header
+	}
+	if other.Delimiter != nil {
+		if node.Delimiter != nil {
+			return errCopyOptionsConflict
+		}
+		node.Delimiter = other.Delimiter
+	}
+	if other.Null != nil {
+		if node.Null != nil {
+			return errCopyOptionsConflict
+		}
+		node.Null = other.Null
+	}
+	if other.Quote != nil {
+		if node.Quote != nil {
+			return errCopyOptionsConflict
+		}
+		node.Quote = other.Quote
+	}
+	return nil
 }
@@ -73,13 +73,20 @@ func (node *CreateDatabase) Format(buf *bytes.Buffer, f FmtFlags) {
 
 // IndexElem represents a column with a direction in a CREATE INDEX statement.
 type IndexElem struct {
-	Column    Name
+	Column Name
+	// Collation is the locale specified for the column in the index, if any.
+	// It must match the collation of the column.
+	Collation string
 	Direction Direction
 }
 
 // Format implements the NodeFormatter interface.
 func (node IndexElem) Format(buf *bytes.Buffer, f FmtFlags) {
 	FormatNode(buf, f, node.Column)
+	if node.Collation != "" {
+		buf.WriteString(" COLLATE ")
+		lex.EncodeUnrestrictedSQLIdent(buf, node.Collation, f.encodeFlags)
+	}
 	if node.Direction != DefaultDirection {
 		buf.WriteByte(' ')
 		buf.WriteString(node.Direction.String())
@@ -105,6 +112,7 @@ type CreateIndex struct {
 	Name        Name
 	Table       NormalizableTableName
 	Unique      bool
+	Inverted    bool
 	IfNotExists bool
 	Columns     IndexElemList
 	// Extra columns to be stored together with the indexed ones as an optimization
@@ -119,6 +127,9 @@ func (node *CreateIndex) Format(buf *bytes.Buffer, f FmtFlags) {
 	if node.Unique {
 		buf.WriteString("UNIQUE ")
 	}
+	if node.Inverted {
+		buf.WriteString("INVERTED ")
+	}
 	buf.WriteString("INDEX ")
 	if node.IfNotExists {
 		buf.WriteString("IF NOT EXISTS ")
@@ -470,6 +481,7 @@ type IndexTableDef struct {
 	Columns    IndexElemList
 	Storing    NameList
 	Interleave *InterleaveDef
+	Inverted   bool
 }
 
 // SetName implements the TableDef interface.
@@ -479,6 +491,9 @@ func (node *IndexTableDef) SetName(name Name) {
 
 // Format implements the NodeFormatter interface.
 func (node *IndexTableDef) Format(buf *bytes.Buffer, f FmtFlags) {
+	if node.Inverted {
+		buf.WriteString("INVERTED ")
+	}
 	buf.WriteString("INDEX ")
 	if node.Name != "" {
 		FormatNode(buf, f, node.Name)
@@ -953,6 +968,24 @@ func (node *AlterUserSetPassword) Format(buf *bytes.Buffer, f FmtFlags) {
 	}
 }
 
+// AlterUserSetConnLimit represents an ALTER USER ... CONNECTION LIMIT
+// statement. Like in Postgres, a negative limit removes the limit.
+type AlterUserSetConnLimit struct {
+	Name     Expr
+	Limit    int64
+	IfExists bool
+}
+
+// Format implements the NodeFormatter interface.
+func (node *AlterUserSetConnLimit) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("ALTER USER ")
+	if node.IfExists {
+		buf.WriteString("IF EXISTS ")
+	}
+	FormatNode(buf, f, node.Name)
+	fmt.Fprintf(buf, " WITH CONNECTION LIMIT %d", node.Limit)
+}
+
 // CreateView represents a CREATE VIEW statement.
 type CreateView struct {
 	Name        NormalizableTableName
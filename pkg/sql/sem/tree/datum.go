@@ -46,6 +46,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/util/stringencoding"
 	"github.com/cockroachdb/cockroach/pkg/util/timeofday"
 	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/cockroachdb/cockroach/pkg/util/tsearch"
 	"github.com/cockroachdb/cockroach/pkg/util/uint128"
 	"github.com/cockroachdb/cockroach/pkg/util/uuid"
 )
@@ -1542,6 +1543,11 @@ const (
 
 	// TimestampOutputFormat is used to output all timestamps.
 	TimestampOutputFormat = "2006-01-02 15:04:05.999999-07:00"
+
+	// timestampJSONFormat and timestampTZJSONFormat are the ISO 8601 formats
+	// used by Postgres for timestamps in JSON.
+	timestampJSONFormat   = "2006-01-02T15:04:05.999999"
+	timestampTZJSONFormat = timestampJSONFormat + "-07:00"
 )
 
 var timeFormats = []string{
@@ -2049,6 +2055,22 @@ func (d *DInterval) Size() uintptr {
 // DJSON is the JSON Datum.
 type DJSON struct{ json.JSON }
 
+// NewDJSON is a helper routine to create a DJSON initialized from its
+// argument.
+func NewDJSON(j json.JSON) *DJSON {
+	return &DJSON{j}
+}
+
+// MustBeDJSON attempts to retrieve a DJSON from an Expr, panicking if the
+// assertion fails.
+func MustBeDJSON(e Expr) DJSON {
+	i, ok := e.(*DJSON)
+	if !ok {
+		panic(pgerror.NewErrorf(pgerror.CodeInternalError, "expected *DJSON, found %T", e))
+	}
+	return *i
+}
+
 // ParseDJSON takes a string of JSON and returns a DJSON value.
 func ParseDJSON(s string) (Datum, error) {
 	j, err := json.ParseJSON(s)
@@ -2139,11 +2161,257 @@ func (d *DJSON) Size() uintptr {
 	return unsafe.Sizeof(*d) + d.JSON.Size()
 }
 
+// DTSVector is the TSVECTOR Datum, a document reduced to a list of lexemes
+// for full-text search.
+type DTSVector struct{ tsearch.Vector }
+
+// NewDTSVector is a helper routine to create a DTSVector initialized from its
+// argument.
+func NewDTSVector(v tsearch.Vector) *DTSVector {
+	return &DTSVector{v}
+}
+
+// ParseDTSVector parses the text representation of a tsvector.
+func ParseDTSVector(s string) (*DTSVector, error) {
+	v, err := tsearch.ParseVector(s)
+	if err != nil {
+		return nil, err
+	}
+	return &DTSVector{v}, nil
+}
+
+// MustBeDTSVector attempts to retrieve a DTSVector from an Expr, panicking if
+// the assertion fails.
+func MustBeDTSVector(e Expr) *DTSVector {
+	v, ok := e.(*DTSVector)
+	if !ok {
+		panic(pgerror.NewErrorf(pgerror.CodeInternalError, "expected *DTSVector, found %T", e))
+	}
+	return v
+}
+
+// ResolvedType implements the TypedExpr interface.
+func (*DTSVector) ResolvedType() types.T {
+	return types.TSVector
+}
+
+// Compare implements the Datum interface.
+func (d *DTSVector) Compare(ctx *EvalContext, other Datum) int {
+	if other == DNull {
+		// NULL is less than any non-NULL value.
+		return 1
+	}
+	v, ok := UnwrapDatum(ctx, other).(*DTSVector)
+	if !ok {
+		panic(makeUnsupportedComparisonMessage(d, other))
+	}
+	return d.Vector.Compare(v.Vector)
+}
+
+// Prev implements the Datum interface.
+func (d *DTSVector) Prev(_ *EvalContext) (Datum, bool) {
+	return nil, false
+}
+
+// Next implements the Datum interface.
+func (d *DTSVector) Next(_ *EvalContext) (Datum, bool) {
+	return nil, false
+}
+
+// IsMax implements the Datum interface.
+func (d *DTSVector) IsMax(_ *EvalContext) bool {
+	return false
+}
+
+// IsMin implements the Datum interface.
+func (d *DTSVector) IsMin(_ *EvalContext) bool {
+	return len(d.Vector) == 0
+}
+
+// Max implements the Datum interface.
+func (d *DTSVector) Max(_ *EvalContext) (Datum, bool) {
+	return nil, false
+}
+
+// Min implements the Datum interface.
+func (d *DTSVector) Min(_ *EvalContext) (Datum, bool) {
+	return &DTSVector{}, true
+}
+
+// AmbiguousFormat implements the Datum interface.
+func (*DTSVector) AmbiguousFormat() bool { return true }
+
+// Format implements the NodeFormatter interface.
+func (d *DTSVector) Format(buf *bytes.Buffer, f FmtFlags) {
+	lex.EncodeSQLStringWithFlags(buf, d.Vector.String(), f.encodeFlags)
+}
+
+// Size implements the Datum interface.
+func (d *DTSVector) Size() uintptr {
+	return unsafe.Sizeof(*d) + d.Vector.Size()
+}
+
+// DTSQuery is the TSQUERY Datum, a boolean combination of lexemes that can
+// be matched against a DTSVector.
+type DTSQuery struct{ tsearch.Query }
+
+// NewDTSQuery is a helper routine to create a DTSQuery initialized from its
+// argument.
+func NewDTSQuery(q tsearch.Query) *DTSQuery {
+	return &DTSQuery{q}
+}
+
+// ParseDTSQuery parses the text representation of a tsquery.
+func ParseDTSQuery(s string) (*DTSQuery, error) {
+	q, err := tsearch.ParseQuery(s)
+	if err != nil {
+		return nil, err
+	}
+	return &DTSQuery{q}, nil
+}
+
+// MustBeDTSQuery attempts to retrieve a DTSQuery from an Expr, panicking if
+// the assertion fails.
+func MustBeDTSQuery(e Expr) *DTSQuery {
+	q, ok := e.(*DTSQuery)
+	if !ok {
+		panic(pgerror.NewErrorf(pgerror.CodeInternalError, "expected *DTSQuery, found %T", e))
+	}
+	return q
+}
+
+// ResolvedType implements the TypedExpr interface.
+func (*DTSQuery) ResolvedType() types.T {
+	return types.TSQuery
+}
+
+// Compare implements the Datum interface.
+func (d *DTSQuery) Compare(ctx *EvalContext, other Datum) int {
+	if other == DNull {
+		// NULL is less than any non-NULL value.
+		return 1
+	}
+	v, ok := UnwrapDatum(ctx, other).(*DTSQuery)
+	if !ok {
+		panic(makeUnsupportedComparisonMessage(d, other))
+	}
+	return d.Query.Compare(v.Query)
+}
+
+// Prev implements the Datum interface.
+func (d *DTSQuery) Prev(_ *EvalContext) (Datum, bool) {
+	return nil, false
+}
+
+// Next implements the Datum interface.
+func (d *DTSQuery) Next(_ *EvalContext) (Datum, bool) {
+	return nil, false
+}
+
+// IsMax implements the Datum interface.
+func (d *DTSQuery) IsMax(_ *EvalContext) bool {
+	return false
+}
+
+// IsMin implements the Datum interface.
+func (d *DTSQuery) IsMin(_ *EvalContext) bool {
+	return d.Query.IsEmpty()
+}
+
+// Max implements the Datum interface.
+func (d *DTSQuery) Max(_ *EvalContext) (Datum, bool) {
+	return nil, false
+}
+
+// Min implements the Datum interface.
+func (d *DTSQuery) Min(_ *EvalContext) (Datum, bool) {
+	return &DTSQuery{}, true
+}
+
+// AmbiguousFormat implements the Datum interface.
+func (*DTSQuery) AmbiguousFormat() bool { return true }
+
+// Format implements the NodeFormatter interface.
+func (d *DTSQuery) Format(buf *bytes.Buffer, f FmtFlags) {
+	lex.EncodeSQLStringWithFlags(buf, d.Query.String(), f.encodeFlags)
+}
+
+// Size implements the Datum interface.
+func (d *DTSQuery) Size() uintptr {
+	return unsafe.Sizeof(*d) + d.Query.Size()
+}
+
+// AsJSON converts a datum into our standard json representation. Arrays
+// become JSON arrays and tuples become JSON objects whose keys are the
+// positional field names f1, f2, etc., mirroring Postgres' treatment of
+// anonymous records. Types without a natural JSON counterpart are converted
+// to JSON strings holding their text representation.
+func AsJSON(d Datum) (json.JSON, error) {
+	switch t := UnwrapDatum(nil, d).(type) {
+	case dNull:
+		return json.NullJSONValue, nil
+	case *DBool:
+		return json.FromBool(bool(*t)), nil
+	case *DInt:
+		return json.FromInt64(int64(*t)), nil
+	case *DFloat:
+		return json.FromFloat64(float64(*t))
+	case *DDecimal:
+		return json.FromDecimal(t.Decimal), nil
+	case *DString:
+		return json.FromString(string(*t)), nil
+	case *DCollatedString:
+		return json.FromString(t.Contents), nil
+	case *DBytes:
+		return json.FromString(`\x` + hex.EncodeToString([]byte(*t))), nil
+	case *DJSON:
+		return t.JSON, nil
+	case *DArray:
+		builder := json.NewArrayBuilder(t.Len())
+		for _, e := range t.Array {
+			j, err := AsJSON(e)
+			if err != nil {
+				return nil, err
+			}
+			builder.Add(j)
+		}
+		return builder.Build(), nil
+	case *DTuple:
+		builder := json.NewObjectBuilder(len(t.D))
+		for i, e := range t.D {
+			j, err := AsJSON(e)
+			if err != nil {
+				return nil, err
+			}
+			// Like in Postgres, unlabeled elements are named f1, f2, etc.
+			if t.Labels != nil {
+				builder.Add(t.Labels[i], j)
+			} else {
+				builder.Add(fmt.Sprintf("f%d", i+1), j)
+			}
+		}
+		return builder.Build(), nil
+	case *DTimestamp:
+		return json.FromString(t.UTC().Format(timestampJSONFormat)), nil
+	case *DTimestampTZ:
+		return json.FromString(t.Time.Format(timestampTZJSONFormat)), nil
+	case *DDate, *DTime, *DInterval, *DUuid, *DIPAddr, *DOid:
+		return json.FromString(AsStringWithFlags(t, FmtBareStrings)), nil
+	default:
+		return nil, pgerror.NewErrorf(pgerror.CodeInternalError,
+			"unexpected type %T for AsJSON", d)
+	}
+}
+
 // DTuple is the tuple Datum.
 type DTuple struct {
 	D Datums
 
 	Sorted bool
+
+	// Labels, if set, name the elements of the tuple. They are used as the
+	// keys of the tuple's JSON representation.
+	Labels []string
 }
 
 // NewDTuple creates a *DTuple with the provided datums. When creating a new
@@ -2325,7 +2593,14 @@ func (*DTuple) AmbiguousFormat() bool { return false }
 
 // Format implements the NodeFormatter interface.
 func (d *DTuple) Format(buf *bytes.Buffer, f FmtFlags) {
+	if d.Labels != nil {
+		buf.WriteByte('(')
+		if len(d.D) == 1 {
+			buf.WriteString("ROW")
+		}
+	}
 	FormatNode(buf, f, d.D)
+	formatTupleLabels(buf, f, d.Labels)
 }
 
 // SetSorted sets the sorted flag on the DTuple. This should be used when a
@@ -3092,6 +3367,8 @@ var baseDatumTypeSizes = map[types.T]struct {
 	types.TimestampTZ: {unsafe.Sizeof(DTimestampTZ{}), fixedSize},
 	types.Interval:    {unsafe.Sizeof(DInterval{}), fixedSize},
 	types.JSON:        {unsafe.Sizeof(DJSON{}), variableSize},
+	types.TSVector:    {unsafe.Sizeof(DTSVector{}), variableSize},
+	types.TSQuery:     {unsafe.Sizeof(DTSQuery{}), variableSize},
 	types.UUID:        {unsafe.Sizeof(DUuid{}), fixedSize},
 	types.INet:        {unsafe.Sizeof(DIPAddr{}), fixedSize},
 	// TODO(jordan,justin): This seems suspicious.
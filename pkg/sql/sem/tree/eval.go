@@ -41,6 +41,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/util/mon"
 	"github.com/cockroachdb/cockroach/pkg/util/timeofday"
 	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/cockroachdb/cockroach/pkg/util/tsearch"
 	"github.com/cockroachdb/cockroach/pkg/util/uuid"
 )
 
@@ -1107,6 +1108,18 @@ var BinOps = map[BinaryOperator]binOpOverload{
 				return NewDBytes(*left.(*DBytes) + *right.(*DBytes)), nil
 			},
 		},
+		BinOp{
+			LeftType:   types.JSON,
+			RightType:  types.JSON,
+			ReturnType: types.JSON,
+			fn: func(_ *EvalContext, left Datum, right Datum) (Datum, error) {
+				j, err := json.Concat(left.(*DJSON).JSON, right.(*DJSON).JSON)
+				if err != nil {
+					return nil, err
+				}
+				return &DJSON{j}, nil
+			},
+		},
 	},
 
 	// TODO(pmattis): Check that the shift is valid.
@@ -1927,6 +1940,68 @@ var CmpOps = map[ComparisonOperator]cmpOpOverload{
 			},
 		},
 	},
+
+	// Like in Postgres, the SQL/JSON path operators suppress evaluation
+	// errors, returning NULL instead.
+	JSONPathExists: {
+		CmpOp{
+			LeftType:  types.JSON,
+			RightType: types.String,
+			fn: func(_ *EvalContext, left Datum, right Datum) (Datum, error) {
+				path, err := json.ParsePath(string(MustBeDString(right)))
+				if err != nil {
+					return nil, err
+				}
+				exists, err := path.Exists(left.(*DJSON).JSON, nil)
+				if err != nil {
+					return DNull, nil
+				}
+				return MakeDBool(DBool(exists)), nil
+			},
+		},
+	},
+
+	Matches: {
+		CmpOp{
+			LeftType:  types.JSON,
+			RightType: types.String,
+			fn: func(_ *EvalContext, left Datum, right Datum) (Datum, error) {
+				path, err := json.ParsePath(string(MustBeDString(right)))
+				if err != nil {
+					return nil, err
+				}
+				match, err := path.Match(left.(*DJSON).JSON, nil)
+				if err != nil || match == nil {
+					return DNull, nil
+				}
+				return MakeDBool(DBool(*match)), nil
+			},
+		},
+		CmpOp{
+			LeftType:  types.TSVector,
+			RightType: types.TSQuery,
+			fn: func(_ *EvalContext, left Datum, right Datum) (Datum, error) {
+				return MakeDBool(DBool(MustBeDTSQuery(right).Match(MustBeDTSVector(left).Vector))), nil
+			},
+		},
+		CmpOp{
+			LeftType:  types.TSQuery,
+			RightType: types.TSVector,
+			fn: func(_ *EvalContext, left Datum, right Datum) (Datum, error) {
+				return MakeDBool(DBool(MustBeDTSQuery(left).Match(MustBeDTSVector(right).Vector))), nil
+			},
+		},
+		CmpOp{
+			// Like in Postgres, text is converted to a tsvector using the default
+			// text search configuration.
+			LeftType:  types.String,
+			RightType: types.TSQuery,
+			fn: func(_ *EvalContext, left Datum, right Datum) (Datum, error) {
+				v := tsearch.DefaultConfig.ToVector(string(MustBeDString(left)))
+				return MakeDBool(DBool(MustBeDTSQuery(right).Match(v))), nil
+			},
+		},
+	},
 }
 
 func boolFromCmp(cmp int, op ComparisonOperator) *DBool {
@@ -2138,6 +2213,18 @@ type EvalPlanner interface {
 	// It returns an error if the given name is not a sequence.
 	// The caller must ensure that seqName is fully qualified already.
 	IncrementSequence(context context.Context, seqName *TableName) (int64, error)
+
+	// SendNotification queues a notification, which is sent on the given
+	// channel when the transaction commits.
+	SendNotification(ctx context.Context, channel, payload string) error
+
+	// ListeningChannels returns the channels on which the session listens, in
+	// order.
+	ListeningChannels() []string
+
+	// SendNotice sends a notice to the client, unless its severity is below
+	// the client_min_messages session variable.
+	SendNotice(notice *pgerror.Notice)
 }
 
 // CtxProvider is anything that can return a Context.
@@ -2749,6 +2836,10 @@ func performCast(ctx *EvalContext, d Datum, t coltypes.CastTargetType) (Datum, e
 			s = t.UUID.String()
 		case *DIPAddr:
 			s = t.String()
+		case *DTSVector:
+			s = t.Vector.String()
+		case *DTSQuery:
+			s = t.Query.String()
 		case *DString:
 			s = string(*t)
 		case *DCollatedString:
@@ -2905,6 +2996,24 @@ func performCast(ctx *EvalContext, d Datum, t coltypes.CastTargetType) (Datum, e
 		case *DJSON:
 			return v, nil
 		}
+	case *coltypes.TTSVector:
+		switch v := d.(type) {
+		case *DString:
+			return ParseDTSVector(string(*v))
+		case *DCollatedString:
+			return ParseDTSVector(v.Contents)
+		case *DTSVector:
+			return v, nil
+		}
+	case *coltypes.TTSQuery:
+		switch v := d.(type) {
+		case *DString:
+			return ParseDTSQuery(string(*v))
+		case *DCollatedString:
+			return ParseDTSQuery(v.Contents)
+		case *DTSQuery:
+			return v, nil
+		}
 	case *coltypes.TArray:
 		if s, ok := d.(*DString); ok {
 			return ParseDArrayFromString(ctx, string(*s), typ.ParamType)
@@ -3333,6 +3442,7 @@ func (t *Tuple) Eval(ctx *EvalContext) (Datum, error) {
 		}
 		tuple.D = append(tuple.D, d)
 	}
+	tuple.Labels = t.Labels
 	return tuple, nil
 }
 
@@ -3444,6 +3554,16 @@ func (t *DJSON) Eval(_ *EvalContext) (Datum, error) {
 	return t, nil
 }
 
+// Eval implements the TypedExpr interface.
+func (t *DTSVector) Eval(_ *EvalContext) (Datum, error) {
+	return t, nil
+}
+
+// Eval implements the TypedExpr interface.
+func (t *DTSQuery) Eval(_ *EvalContext) (Datum, error) {
+	return t, nil
+}
+
 // Eval implements the TypedExpr interface.
 func (t dNull) Eval(_ *EvalContext) (Datum, error) {
 	return t, nil
@@ -310,6 +310,8 @@ const (
 	Existence
 	SomeExistence
 	AllExistence
+	JSONPathExists
+	Matches
 
 	// The following operators will always be used with an associated SubOperator.
 	// If Go had algebraic data types they would be defined in a self-contained
@@ -357,6 +359,8 @@ var comparisonOpName = [...]string{
 	Existence:         "?",
 	SomeExistence:     "?|",
 	AllExistence:      "?&",
+	JSONPathExists:    "@?",
+	Matches:           "@@",
 	Any:               "ANY",
 	Some:              "SOME",
 	All:               "ALL",
@@ -672,18 +676,42 @@ type Tuple struct {
 	// Row indicates whether or not the tuple should be textually represented as
 	// ROW ( ... ).
 	Row bool
+	// Labels, if set, name the elements of the tuple, as in
+	// ((1, 'foo') AS a, b).
+	Labels []string
 
 	types types.TTuple
 }
 
 // Format implements the NodeFormatter interface.
 func (node *Tuple) Format(buf *bytes.Buffer, f FmtFlags) {
-	if node.Row {
+	if node.Labels != nil {
+		buf.WriteByte('(')
+	}
+	// A single-element tuple can only be labeled in its ROW form.
+	if node.Row || (node.Labels != nil && len(node.Exprs) == 1) {
 		buf.WriteString("ROW")
 	}
 	buf.WriteByte('(')
 	FormatNode(buf, f, node.Exprs)
 	buf.WriteByte(')')
+	formatTupleLabels(buf, f, node.Labels)
+}
+
+// formatTupleLabels formats the labels of a tuple and the closing parenthesis
+// that follows them, if there are labels.
+func formatTupleLabels(buf *bytes.Buffer, f FmtFlags, labels []string) {
+	if labels == nil {
+		return
+	}
+	buf.WriteString(" AS ")
+	for i, l := range labels {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		FormatNode(buf, f, Name(l))
+	}
+	buf.WriteByte(')')
 }
 
 // ResolvedType implements the TypedExpr interface.
@@ -1124,7 +1152,8 @@ var (
 	decimalCastTypes = []types.T{types.Null, types.Bool, types.Int, types.Float, types.Decimal, types.String, types.FamCollatedString,
 		types.Timestamp, types.TimestampTZ, types.Date, types.Interval}
 	stringCastTypes = []types.T{types.Null, types.Bool, types.Int, types.Float, types.Decimal, types.String, types.FamCollatedString,
-		types.Bytes, types.Timestamp, types.TimestampTZ, types.Interval, types.UUID, types.Date, types.Time, types.Oid, types.INet}
+		types.Bytes, types.Timestamp, types.TimestampTZ, types.Interval, types.UUID, types.Date, types.Time, types.Oid, types.INet,
+		types.TSVector, types.TSQuery}
 	bytesCastTypes     = []types.T{types.Null, types.String, types.FamCollatedString, types.Bytes, types.UUID}
 	dateCastTypes      = []types.T{types.Null, types.String, types.FamCollatedString, types.Date, types.Timestamp, types.TimestampTZ, types.Int}
 	timeCastTypes      = []types.T{types.Null, types.String, types.FamCollatedString, types.Time, types.Timestamp, types.TimestampTZ, types.Interval}
@@ -1135,6 +1164,8 @@ var (
 	inetCastTypes      = []types.T{types.Null, types.String, types.FamCollatedString, types.INet}
 	arrayCastTypes     = []types.T{types.Null, types.String}
 	jsonCastTypes      = []types.T{types.Null, types.String, types.JSON}
+	tsvectorCastTypes  = []types.T{types.Null, types.String, types.FamCollatedString, types.TSVector}
+	tsqueryCastTypes   = []types.T{types.Null, types.String, types.FamCollatedString, types.TSQuery}
 )
 
 // validCastTypes returns a set of types that can be cast into the provided type.
@@ -1162,6 +1193,10 @@ func validCastTypes(t types.T) []types.T {
 		return intervalCastTypes
 	case types.JSON:
 		return jsonCastTypes
+	case types.TSVector:
+		return tsvectorCastTypes
+	case types.TSQuery:
+		return tsqueryCastTypes
 	case types.UUID:
 		return uuidCastTypes
 	case types.INet:
@@ -1282,6 +1317,8 @@ func (node *DFloat) String() string           { return AsString(node) }
 func (node *DInt) String() string             { return AsString(node) }
 func (node *DInterval) String() string        { return AsString(node) }
 func (node *DJSON) String() string            { return AsString(node) }
+func (node *DTSVector) String() string        { return AsString(node) }
+func (node *DTSQuery) String() string         { return AsString(node) }
 func (node *DUuid) String() string            { return AsString(node) }
 func (node *DIPAddr) String() string          { return AsString(node) }
 func (node *DString) String() string          { return AsString(node) }
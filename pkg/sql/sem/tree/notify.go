@@ -0,0 +1,63 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tree
+
+import (
+	"bytes"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/lex"
+)
+
+// Listen represents a LISTEN statement.
+type Listen struct {
+	Channel Name
+}
+
+// Format implements the NodeFormatter interface.
+func (node *Listen) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("LISTEN ")
+	FormatNode(buf, f, node.Channel)
+}
+
+// Unlisten represents an UNLISTEN statement.
+type Unlisten struct {
+	Channel Name // empty for *
+}
+
+// Format implements the NodeFormatter interface.
+func (node *Unlisten) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("UNLISTEN ")
+	if node.Channel == "" {
+		buf.WriteString("*")
+	} else {
+		FormatNode(buf, f, node.Channel)
+	}
+}
+
+// Notify represents a NOTIFY statement.
+type Notify struct {
+	Channel Name
+	Payload string
+}
+
+// Format implements the NodeFormatter interface.
+func (node *Notify) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("NOTIFY ")
+	FormatNode(buf, f, node.Channel)
+	if node.Payload != "" {
+		buf.WriteString(", ")
+		lex.EncodeSQLStringWithFlags(buf, node.Payload, f.encodeFlags)
+	}
+}
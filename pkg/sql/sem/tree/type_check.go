@@ -936,6 +936,14 @@ func (d *DInterval) TypeCheck(_ *SemaContext, _ types.T) (TypedExpr, error) { re
 // identity function for Datum.
 func (d *DJSON) TypeCheck(_ *SemaContext, _ types.T) (TypedExpr, error) { return d, nil }
 
+// TypeCheck implements the Expr interface. It is implemented as an idempotent
+// identity function for Datum.
+func (d *DTSVector) TypeCheck(_ *SemaContext, _ types.T) (TypedExpr, error) { return d, nil }
+
+// TypeCheck implements the Expr interface. It is implemented as an idempotent
+// identity function for Datum.
+func (d *DTSQuery) TypeCheck(_ *SemaContext, _ types.T) (TypedExpr, error) { return d, nil }
+
 // TypeCheck implements the Expr interface. It is implemented as an idempotent
 // identity function for Datum.
 func (d *DTuple) TypeCheck(_ *SemaContext, _ types.T) (TypedExpr, error) { return d, nil }
@@ -1151,6 +1159,36 @@ func typeCheckSubqueryWithIn(left, right types.T) error {
 	return nil
 }
 
+// collateStringLiteral returns expr wrapped in a COLLATE expression if it is a
+// string literal compared to a column or COLLATE expression of a collated
+// string type. Like in Postgres, the literal then takes on the collation it is
+// compared to: for a column `a STRING COLLATE en`, `a = 'foo'` is equivalent
+// to `a = 'foo' COLLATE en`.
+func collateStringLiteral(ctx *SemaContext, expr, other Expr) Expr {
+	c, ok := expr.(Constant)
+	if !ok {
+		return expr
+	}
+	var typ types.T
+	switch t := other.(type) {
+	case *CollateExpr:
+		typ = types.TCollatedString{Locale: t.Locale}
+	case *IndexedVar:
+		typedOther, err := t.TypeCheck(ctx, types.Any)
+		if err != nil {
+			// The error is reported when the comparison is type checked.
+			return expr
+		}
+		typ = typedOther.ResolvedType()
+	default:
+		return expr
+	}
+	if locale, ok := constantCollation(c, typ); ok {
+		return &CollateExpr{Expr: expr, Locale: locale}
+	}
+	return expr
+}
+
 func typeCheckComparisonOp(
 	ctx *SemaContext, op ComparisonOperator, left, right Expr,
 ) (TypedExpr, TypedExpr, CmpOp, error) {
@@ -1211,6 +1249,8 @@ func typeCheckComparisonOp(
 	// defined to return NULL anyways. Should the SQL dialect ever be extended with
 	// comparisons that can return non-NULL on NULL input, the `inBinOp` parameter
 	// may need altering.
+	foldedLeft = collateStringLiteral(ctx, foldedLeft, foldedRight)
+	foldedRight = collateStringLiteral(ctx, foldedRight, foldedLeft)
 	typedSubExprs, fns, err := typeCheckOverloadedExprs(ctx, types.Any, ops, false, foldedLeft, foldedRight)
 	if err != nil {
 		return nil, nil, CmpOp{}, err
@@ -22,10 +22,31 @@ type IndexedRow struct {
 	Row Datums
 }
 
+// IndexedRows are the rows of a partition, which may be kept in memory or on
+// disk.
+type IndexedRows interface {
+	// Len returns the number of rows.
+	Len() int
+	// GetRow returns the row at the given index. The returned row is only
+	// valid until the next call to GetRow.
+	GetRow(ctx context.Context, idx int) (IndexedRow, error)
+}
+
+// IndexedRowSlice is an IndexedRows kept in memory.
+type IndexedRowSlice []IndexedRow
+
+// Len is part of the IndexedRows interface.
+func (s IndexedRowSlice) Len() int { return len(s) }
+
+// GetRow is part of the IndexedRows interface.
+func (s IndexedRowSlice) GetRow(_ context.Context, idx int) (IndexedRow, error) {
+	return s[idx], nil
+}
+
 // WindowFrame is a view into a subset of data over which calculations are made.
 type WindowFrame struct {
 	// constant for all calls to WindowFunc.Add
-	Rows        []IndexedRow
+	Rows        IndexedRows
 	ArgIdxStart int // the index which arguments to the window function begin
 	ArgCount    int // the number of window function arguments
 
@@ -44,7 +65,7 @@ func (wf WindowFrame) Rank() int {
 
 // RowCount returns the number of rows in this frame.
 func (wf WindowFrame) RowCount() int {
-	return len(wf.Rows)
+	return wf.Rows.Len()
 }
 
 // FrameSize returns the size of this frame.
@@ -60,13 +81,24 @@ func (wf WindowFrame) FirstInPeerGroup() bool {
 }
 
 // Args returns the current argument set in the window frame.
-func (wf WindowFrame) Args() Datums {
-	return wf.ArgsWithRowOffset(0)
+func (wf WindowFrame) Args(ctx context.Context) (Datums, error) {
+	return wf.ArgsWithRowOffset(ctx, 0)
 }
 
 // ArgsWithRowOffset returns the argumnent set at the given offset in the window frame.
-func (wf WindowFrame) ArgsWithRowOffset(offset int) Datums {
-	return wf.Rows[wf.RowIdx+offset].Row[wf.ArgIdxStart : wf.ArgIdxStart+wf.ArgCount]
+func (wf WindowFrame) ArgsWithRowOffset(ctx context.Context, offset int) (Datums, error) {
+	return wf.ArgsByRowIdx(ctx, wf.RowIdx+offset)
+}
+
+// ArgsByRowIdx returns the argument set of the row at the given index in the
+// partition. Like the rows of IndexedRows, the returned arguments are only
+// valid until the next call to one of the Args methods.
+func (wf WindowFrame) ArgsByRowIdx(ctx context.Context, idx int) (Datums, error) {
+	row, err := wf.Rows.GetRow(ctx, idx)
+	if err != nil {
+		return nil, err
+	}
+	return row.Row[wf.ArgIdxStart : wf.ArgIdxStart+wf.ArgCount], nil
 }
 
 // WindowFunc performs a computation on each row using data from a provided WindowFrame.
@@ -101,6 +101,8 @@ var OidToType = map[oid.Oid]T{
 	oid.T_timestamp:    Timestamp,
 	oid.T__timestamp:   TArray{Timestamp},
 	oid.T_timestamptz:  TimestampTZ,
+	oid.T_tsquery:      TSQuery,
+	oid.T_tsvector:     TSVector,
 	oid.T__timestamptz: TArray{TimestampTZ},
 	oid.T_uuid:         UUID,
 	oid.T__uuid:        TArray{UUID},
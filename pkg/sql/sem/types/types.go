@@ -72,6 +72,10 @@ var (
 	Interval T = tInterval{}
 	// JSON is the type of a DJSON. Can be compared with ==.
 	JSON T = tJSON{}
+	// TSVector is the type of a DTSVector. Can be compared with ==.
+	TSVector T = tTSVector{}
+	// TSQuery is the type of a DTSQuery. Can be compared with ==.
+	TSQuery T = tTSQuery{}
 	// UUID is the type of a DUuid. Can be compared with ==.
 	UUID T = tUUID{}
 	// INet is the type of a DIPAddr. Can be compared with ==.
@@ -98,6 +102,8 @@ var (
 		UUID,
 		INet,
 		JSON,
+		TSVector,
+		TSQuery,
 		Oid,
 	}
 
@@ -288,6 +294,30 @@ func (tJSON) Oid() oid.Oid             { return oid.T_jsonb }
 func (tJSON) SQLName() string          { return "json" }
 func (tJSON) IsAmbiguous() bool        { return false }
 
+type tTSVector struct{}
+
+func (tTSVector) String() string { return "tsvector" }
+func (tTSVector) Equivalent(other T) bool {
+	return UnwrapType(other) == TSVector || other == Any
+}
+
+func (tTSVector) FamilyEqual(other T) bool { return UnwrapType(other) == TSVector }
+func (tTSVector) Oid() oid.Oid             { return oid.T_tsvector }
+func (tTSVector) SQLName() string          { return "tsvector" }
+func (tTSVector) IsAmbiguous() bool        { return false }
+
+type tTSQuery struct{}
+
+func (tTSQuery) String() string { return "tsquery" }
+func (tTSQuery) Equivalent(other T) bool {
+	return UnwrapType(other) == TSQuery || other == Any
+}
+
+func (tTSQuery) FamilyEqual(other T) bool { return UnwrapType(other) == TSQuery }
+func (tTSQuery) Oid() oid.Oid             { return oid.T_tsquery }
+func (tTSQuery) SQLName() string          { return "tsquery" }
+func (tTSQuery) IsAmbiguous() bool        { return false }
+
 type tUUID struct{}
 
 func (tUUID) String() string           { return "uuid" }
@@ -506,7 +536,7 @@ func IsStringType(t T) bool {
 // can be used in TArray.
 func IsValidArrayElementType(t T) bool {
 	switch t {
-	case JSON:
+	case JSON, TSVector, TSQuery:
 		return false
 	default:
 		return true
@@ -15,6 +15,8 @@
 package sql
 
 import (
+	"crypto/rand"
+	"encoding/binary"
 	"fmt"
 	"net"
 	"strings"
@@ -34,6 +36,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/security"
 	"github.com/cockroachdb/cockroach/pkg/server/serverpb"
 	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
 	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
@@ -79,6 +82,30 @@ var traceSessionEventLogEnabled = settings.RegisterBoolSetting(
 	"set to true to enable session tracing", false,
 )
 
+// defaultStatementTimeout is the cluster default of the statement_timeout
+// session variable.
+var defaultStatementTimeout = settings.RegisterNonNegativeDurationSetting(
+	"sql.defaults.statement_timeout",
+	"duration after which statements are canceled by default (set to 0 to disable)",
+	0,
+)
+
+// defaultIdleInTransactionSessionTimeout is the cluster default of the
+// idle_in_transaction_session_timeout session variable.
+var defaultIdleInTransactionSessionTimeout = settings.RegisterNonNegativeDurationSetting(
+	"sql.defaults.idle_in_transaction_session_timeout",
+	"duration after which sessions idle in an open transaction are terminated by default (set to 0 to disable)",
+	0,
+)
+
+// defaultLockTimeout is the cluster default of the lock_timeout session
+// variable.
+var defaultLockTimeout = settings.RegisterNonNegativeDurationSetting(
+	"sql.defaults.lock_timeout",
+	"duration after which statements waiting for a conflicting transaction are canceled by default (set to 0 to disable)",
+	0,
+)
+
 // DistSQLClusterExecMode controls the cluster default for when DistSQL is used.
 var DistSQLClusterExecMode = settings.RegisterEnumSetting(
 	"sql.defaults.distsql",
@@ -211,6 +238,18 @@ type Session struct {
 	// SafeUpdates causes errors when the client
 	// sends syntax that may have unwanted side effects.
 	SafeUpdates bool
+	// ClientMinMessages is the minimum severity of the notices sent to the
+	// client.
+	ClientMinMessages pgerror.Severity
+	// StatementTimeout is the duration after which statements are canceled,
+	// if non-zero.
+	StatementTimeout time.Duration
+	// IdleInTransactionSessionTimeout is the duration after which the session
+	// is terminated if it is idle in an open transaction, if non-zero.
+	IdleInTransactionSessionTimeout time.Duration
+	// LockTimeout is the duration after which statements which wait for a
+	// conflicting transaction are canceled, if non-zero.
+	LockTimeout time.Duration
 
 	//
 	// Session parameters, non-user-configurable.
@@ -223,6 +262,16 @@ type Session struct {
 	// ClientAddr is the client's IP address and port.
 	ClientAddr string
 
+	// CancelKey is the secret key with which a client can cancel the queries
+	// of the session through the cancellation protocol of pgwire. It is unique
+	// among the sessions of the node, and set when the session is registered.
+	CancelKey int32
+	// ProcessID identifies the session in the BackendKeyData message of pgwire
+	// and in the notifications that it sends. It is unique among the sessions
+	// of the cluster, and set when the session is registered. See
+	// makeProcessID.
+	ProcessID int32
+
 	//
 	// State structures for the logical SQL session.
 	//
@@ -234,6 +283,23 @@ type Session struct {
 	// that have been prepared via pgwire.
 	PreparedStatements PreparedStatements
 	PreparedPortals    PreparedPortals
+	// cursors stores the cursors declared with DECLARE, keyed by name.
+	cursors map[string]*sqlCursor
+	// listenChannels is the set of the channels on which the session listens.
+	listenChannels map[string]struct{}
+	// pendingNotifications holds the LISTEN, UNLISTEN and NOTIFY statements
+	// of the current transaction.
+	pendingNotifications txnNotifications
+	// notifications holds the notifications received on the channels on which
+	// the session listens, which haven't been sent to the client yet.
+	notifications struct {
+		syncutil.Mutex
+		queue []serverpb.Notification
+		// ready receives a value when notifications are queued.
+		ready chan struct{}
+	}
+	// planCache caches the plans of the prepared statements.
+	planCache planCache
 	// virtualSchemas aliases Executor.virtualSchemas.
 	// It is duplicated in Session to provide easier access to
 	// the various methods that need this reference.
@@ -274,6 +340,9 @@ type Session struct {
 	// parallelizeQueue is a queue managing all parallelized SQL statements
 	// running in this session.
 	parallelizeQueue ParallelizeQueue
+	// parallelNotices holds the notices raised by the execution of the
+	// statements in the parallelizeQueue.
+	parallelNotices parallelNotices
 	// mon tracks memory usage for SQL activity within this session. It
 	// is not directly used, but rather indirectly used via sessionMon
 	// and TxnState.mon. sessionMon tracks session-bound objects like prepared
@@ -385,26 +454,111 @@ type SessionArgs struct {
 type SessionRegistry struct {
 	syncutil.Mutex
 	store map[*Session]struct{}
+	// cancelKeys maps the cancel keys of the sessions to the sessions.
+	cancelKeys map[int32]*Session
+	// processIDs is the set of the process IDs of the sessions.
+	processIDs map[int32]struct{}
+	// nextProcessSeq is the sequence number of the process ID of the next
+	// session.
+	nextProcessSeq int32
 }
 
 // MakeSessionRegistry creates a new SessionRegistry with an empty set
 // of sessions.
 func MakeSessionRegistry() *SessionRegistry {
-	return &SessionRegistry{store: make(map[*Session]struct{})}
+	return &SessionRegistry{
+		store:      make(map[*Session]struct{}),
+		cancelKeys: make(map[int32]*Session),
+		processIDs: make(map[int32]struct{}),
+	}
 }
 
 func (r *SessionRegistry) register(s *Session) {
 	r.Lock()
 	r.store[s] = struct{}{}
+	for {
+		key := makeCancelKey()
+		if _, ok := r.cancelKeys[key]; !ok && key != 0 {
+			s.CancelKey = key
+			r.cancelKeys[key] = s
+			break
+		}
+	}
+	nodeID := s.execCfg.NodeID.Get()
+	for {
+		pid := makeProcessID(nodeID, r.nextProcessSeq)
+		r.nextProcessSeq++
+		if _, ok := r.processIDs[pid]; !ok {
+			s.ProcessID = pid
+			r.processIDs[pid] = struct{}{}
+			break
+		}
+	}
 	r.Unlock()
 }
 
 func (r *SessionRegistry) deregister(s *Session) {
 	r.Lock()
 	delete(r.store, s)
+	delete(r.cancelKeys, s.CancelKey)
+	delete(r.processIDs, s.ProcessID)
 	r.Unlock()
 }
 
+// processIDSeqBits is the number of low bits of a process ID holding the
+// sequence number of its session on its node.
+const processIDSeqBits = 20
+
+// makeProcessID returns the process ID of the session with the given
+// sequence number on the given node. The high bits are the node ID, so that
+// the cancel requests, which carry the process ID, can be routed to the node
+// owning the session; the low bits are the sequence number, so that the
+// sessions of a node get different process IDs. Like in Postgres, process IDs
+// are positive, and they are reused once the sequence numbers wrap around.
+// The node IDs of 2048 and above don't fit: their sessions get process IDs
+// which ProcessIDNodeID doesn't map back to their node, so their queries
+// can't be canceled through pgwire.
+func makeProcessID(nodeID roachpb.NodeID, seq int32) int32 {
+	const nodeIDMask = 1<<(31-processIDSeqBits) - 1
+	const seqMask = 1<<processIDSeqBits - 1
+	return (int32(nodeID)&nodeIDMask)<<processIDSeqBits | seq&seqMask
+}
+
+// ProcessIDNodeID returns the ID of the node owning the session with the
+// given process ID.
+func ProcessIDNodeID(processID int32) roachpb.NodeID {
+	return roachpb.NodeID(processID >> processIDSeqBits)
+}
+
+// makeCancelKey returns a random cancel key. Anyone who knows the key of a
+// session can cancel its queries, so the key comes from a cryptographically
+// secure source.
+func makeCancelKey() int32 {
+	var b [4]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(errors.Wrap(err, "could not generate a cancel key"))
+	}
+	return int32(binary.BigEndian.Uint32(b[:]))
+}
+
+// CancelSessionQueries cancels the queries in flight of the session with the
+// given cancel key. It returns false if there is no such session.
+func (r *SessionRegistry) CancelSessionQueries(cancelKey int32) bool {
+	r.Lock()
+	defer r.Unlock()
+
+	session, ok := r.cancelKeys[cancelKey]
+	if !ok {
+		return false
+	}
+	session.mu.Lock()
+	for _, queryMeta := range session.mu.ActiveQueries {
+		queryMeta.cancel()
+	}
+	session.mu.Unlock()
+	return true
+}
+
 // CancelQuery looks up the associated query in the session registry and cancels it.
 func (r *SessionRegistry) CancelQuery(queryIDStr string, username string) (bool, error) {
 	queryID, err := uint128.FromString(queryIDStr)
@@ -481,6 +635,13 @@ func NewSession(
 	s.resetApplicationName(args.ApplicationName)
 	s.PreparedStatements = makePreparedStatements(s)
 	s.PreparedPortals = makePreparedPortals(s)
+	s.planCache = makePlanCache(ctx)
+	s.listenChannels = make(map[string]struct{})
+	s.notifications.ready = make(chan struct{}, 1)
+	s.ClientMinMessages = pgerror.SeverityNotice
+	s.StatementTimeout = defaultStatementTimeout.Get(&e.cfg.Settings.SV)
+	s.IdleInTransactionSessionTimeout = defaultIdleInTransactionSessionTimeout.Get(&e.cfg.Settings.SV)
+	s.LockTimeout = defaultLockTimeout.Get(&e.cfg.Settings.SV)
 	s.Tracing.session = s
 	s.mu.ActiveQueries = make(map[uint128.Uint128]*queryMeta)
 	s.ActiveSyncQueries = make([]uint128.Uint128, 0)
@@ -551,6 +712,9 @@ func (s *Session) Finish(e *Executor) {
 	s.tables.releaseTables(s.context)
 
 	s.ClearStatementsAndPortals(s.context)
+	s.planCache.clear()
+	s.closeCursors(s.context)
+	s.unlistenAll()
 	s.sessionMon.Stop(s.context)
 	s.mon.Stop(s.context)
 
@@ -588,6 +752,9 @@ func (s *Session) EmergencyClose() {
 	// Release the leases - to ensure other sessions don't get stuck.
 	s.tables.releaseTables(s.context)
 
+	// Stop receiving notifications.
+	s.unlistenAll()
+
 	// The KV txn may be unusable - just leave it dead. Simply
 	// shut down its memory monitor.
 	s.TxnState.mon.EmergencyStop(s.context)
@@ -627,6 +794,8 @@ func (s *Session) resetPlanner(p *planner, e *Executor, txn *client.Txn) {
 	p.phaseTimes = s.phaseTimes
 	p.stmt = nil
 	p.cancelChecker = sqlbase.NewCancelChecker(s.Ctx())
+	p.noticeSender = nil
+	p.planCache = nil
 
 	p.semaCtx = tree.MakeSemaContext(s.User == security.RootUser)
 	p.semaCtx.Location = &s.Location
@@ -1151,6 +1320,11 @@ func (ts *txnState) resetStateAndTxn(state TxnStateEnum) {
 // the current SQL txn. This needs to be called before resetForNewSQLTxn() is
 // called for starting another SQL txn.
 func (ts *txnState) finishSQLTxn(s *Session) {
+	// Close the cursors and the suspended portals that don't outlive the
+	// transaction, whose plans use its memory monitor.
+	s.finishCursors(s.context)
+	s.PreparedPortals.finishTxn(s.context)
+
 	ts.mon.Stop(ts.Ctx)
 	if ts.cancel != nil {
 		ts.cancel()
@@ -1164,6 +1338,10 @@ func (ts *txnState) finishSQLTxn(s *Session) {
 	ts.txnResults.Close()
 	ts.txnResults = nil
 
+	// Apply the LISTEN, UNLISTEN and NOTIFY statements of the transaction if
+	// it committed.
+	s.finishNotifications(s.context)
+
 	sampledFor7881 := (ts.sp.BaggageItem(keyFor7881Sample) != "")
 	ts.sp.Finish()
 	if err := s.Tracing.onFinishSQLTxn(ts.sp); err != nil {
@@ -299,6 +299,136 @@ func (ed *EncDatum) GetInt() (int64, error) {
 	}
 }
 
+// GetFloat decodes an EncDatum that is known to be of float type and returns
+// the float value. See GetInt.
+func (ed *EncDatum) GetFloat() (float64, error) {
+	if ed.Datum != nil {
+		if ed.Datum == tree.DNull {
+			return 0, errors.Errorf("NULL FLOAT value")
+		}
+		return float64(*ed.Datum.(*tree.DFloat)), nil
+	}
+
+	switch ed.encoding {
+	case DatumEncoding_ASCENDING_KEY:
+		if _, isNull := encoding.DecodeIfNull(ed.encoded); isNull {
+			return 0, errors.Errorf("NULL FLOAT value")
+		}
+		_, val, err := encoding.DecodeFloatAscending(ed.encoded)
+		return val, err
+
+	case DatumEncoding_DESCENDING_KEY:
+		if _, isNull := encoding.DecodeIfNull(ed.encoded); isNull {
+			return 0, errors.Errorf("NULL FLOAT value")
+		}
+		_, val, err := encoding.DecodeFloatDescending(ed.encoded)
+		return val, err
+
+	case DatumEncoding_VALUE:
+		_, dataOffset, _, typ, err := encoding.DecodeValueTag(ed.encoded)
+		if err != nil {
+			return 0, err
+		}
+		if typ == encoding.Null {
+			return 0, errors.Errorf("NULL FLOAT value")
+		}
+		_, val, err := encoding.DecodeUntaggedFloatValue(ed.encoded[dataOffset:])
+		return val, err
+
+	default:
+		return 0, errors.Errorf("unknown encoding %s", ed.encoding)
+	}
+}
+
+// GetBytes decodes an EncDatum that is known to be of STRING or BYTES type and
+// returns the raw bytes. The returned slice can alias the encoded data and must
+// not be modified. See GetInt.
+func (ed *EncDatum) GetBytes() ([]byte, error) {
+	if ed.Datum != nil {
+		switch d := ed.Datum.(type) {
+		case *tree.DString:
+			return []byte(*d), nil
+		case *tree.DBytes:
+			return []byte(*d), nil
+		}
+		if ed.Datum == tree.DNull {
+			return nil, errors.Errorf("NULL BYTES value")
+		}
+		return nil, errors.Errorf("unexpected datum %s", ed.Datum.ResolvedType())
+	}
+
+	switch ed.encoding {
+	case DatumEncoding_ASCENDING_KEY:
+		if _, isNull := encoding.DecodeIfNull(ed.encoded); isNull {
+			return nil, errors.Errorf("NULL BYTES value")
+		}
+		_, val, err := encoding.DecodeBytesAscending(ed.encoded, nil)
+		return val, err
+
+	case DatumEncoding_DESCENDING_KEY:
+		if _, isNull := encoding.DecodeIfNull(ed.encoded); isNull {
+			return nil, errors.Errorf("NULL BYTES value")
+		}
+		_, val, err := encoding.DecodeBytesDescending(ed.encoded, nil)
+		return val, err
+
+	case DatumEncoding_VALUE:
+		_, dataOffset, _, typ, err := encoding.DecodeValueTag(ed.encoded)
+		if err != nil {
+			return nil, err
+		}
+		if typ == encoding.Null {
+			return nil, errors.Errorf("NULL BYTES value")
+		}
+		_, val, err := encoding.DecodeUntaggedBytesValue(ed.encoded[dataOffset:])
+		return val, err
+
+	default:
+		return nil, errors.Errorf("unknown encoding %s", ed.encoding)
+	}
+}
+
+// GetBool decodes an EncDatum that is known to be of boolean type and returns
+// the boolean value. See GetInt.
+func (ed *EncDatum) GetBool() (bool, error) {
+	if ed.Datum != nil {
+		if ed.Datum == tree.DNull {
+			return false, errors.Errorf("NULL BOOL value")
+		}
+		return bool(*ed.Datum.(*tree.DBool)), nil
+	}
+
+	switch ed.encoding {
+	case DatumEncoding_ASCENDING_KEY:
+		if _, isNull := encoding.DecodeIfNull(ed.encoded); isNull {
+			return false, errors.Errorf("NULL BOOL value")
+		}
+		_, val, err := encoding.DecodeVarintAscending(ed.encoded)
+		return val != 0, err
+
+	case DatumEncoding_DESCENDING_KEY:
+		if _, isNull := encoding.DecodeIfNull(ed.encoded); isNull {
+			return false, errors.Errorf("NULL BOOL value")
+		}
+		_, val, err := encoding.DecodeVarintDescending(ed.encoded)
+		return val != 0, err
+
+	case DatumEncoding_VALUE:
+		_, _, _, typ, err := encoding.DecodeValueTag(ed.encoded)
+		if err != nil {
+			return false, err
+		}
+		if typ == encoding.Null {
+			return false, errors.Errorf("NULL BOOL value")
+		}
+		_, val, err := encoding.DecodeBoolValue(ed.encoded)
+		return val, err
+
+	default:
+		return false, errors.Errorf("unknown encoding %s", ed.encoding)
+	}
+}
+
 // EncDatumRow is a row of EncDatums.
 type EncDatumRow []EncDatum
 
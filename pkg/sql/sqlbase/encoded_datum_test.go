@@ -110,6 +110,72 @@ func TestEncDatum(t *testing.T) {
 	}
 }
 
+func TestEncDatumGetters(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	var a DatumAlloc
+	testCases := []struct {
+		typ   ColumnType
+		datum tree.Datum
+		get   func(*EncDatum) (interface{}, error)
+	}{
+		{
+			typ:   ColumnType{SemanticType: ColumnType_FLOAT},
+			datum: tree.NewDFloat(-1.5),
+			get:   func(ed *EncDatum) (interface{}, error) { return ed.GetFloat() },
+		},
+		{
+			typ:   ColumnType{SemanticType: ColumnType_BOOL},
+			datum: tree.DBoolTrue,
+			get:   func(ed *EncDatum) (interface{}, error) { return ed.GetBool() },
+		},
+		{
+			typ:   ColumnType{SemanticType: ColumnType_BOOL},
+			datum: tree.DBoolFalse,
+			get:   func(ed *EncDatum) (interface{}, error) { return ed.GetBool() },
+		},
+		{
+			typ:   ColumnType{SemanticType: ColumnType_STRING},
+			datum: tree.NewDString("foo"),
+			get: func(ed *EncDatum) (interface{}, error) {
+				b, err := ed.GetBytes()
+				return string(b), err
+			},
+		},
+		{
+			typ:   ColumnType{SemanticType: ColumnType_BYTES},
+			datum: tree.NewDBytes("bar"),
+			get: func(ed *EncDatum) (interface{}, error) {
+				b, err := ed.GetBytes()
+				return string(b), err
+			},
+		},
+	}
+	for _, c := range testCases {
+		x := DatumToEncDatum(c.typ, c.datum)
+		expected, err := c.get(&x)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for enc := range DatumEncoding_name {
+			encoded, err := x.Encode(&c.typ, &a, DatumEncoding(enc), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			y := EncDatumFromEncoded(&c.typ, DatumEncoding(enc), encoded)
+			if val, err := c.get(&y); err != nil {
+				t.Fatal(err)
+			} else if val != expected {
+				t.Errorf("%s: %s encoding: expected %v, got %v", c.datum, DatumEncoding(enc), expected, val)
+			}
+		}
+		n := DatumToEncDatum(c.typ, tree.DNull)
+		if _, err := c.get(&n); err == nil {
+			t.Errorf("%s: expected an error for a NULL value", c.typ.SemanticType)
+		}
+	}
+}
+
 func columnTypeCompatibleWithEncoding(typ ColumnType, enc DatumEncoding) bool {
 	return enc == DatumEncoding_VALUE || columnTypeIsIndexable(typ)
 }
@@ -203,6 +203,28 @@ func NewQueryCanceledError() error {
 	return pgerror.NewErrorf(pgerror.CodeQueryCanceledError, "query execution canceled")
 }
 
+// NewStatementTimeoutError creates the error of a statement canceled because
+// it ran over the statement_timeout of its session.
+func NewStatementTimeoutError() error {
+	return pgerror.NewErrorf(pgerror.CodeQueryCanceledError,
+		"query execution canceled due to statement timeout")
+}
+
+// NewLockNotAvailableError creates the error of a statement which waited for
+// a conflicting transaction for longer than the lock_timeout of its session.
+func NewLockNotAvailableError(err *roachpb.WriteIntentError) *pgerror.Error {
+	return pgerror.NewError(pgerror.CodeLockNotAvailableError,
+		"canceling statement due to lock timeout").SetDetailf("%s", err)
+}
+
+// NewIdleInTransactionSessionTimeoutError creates the error of a session
+// terminated because it was idle in an open transaction for longer than its
+// idle_in_transaction_session_timeout.
+func NewIdleInTransactionSessionTimeoutError() error {
+	return pgerror.NewErrorf(pgerror.CodeIdleInTransactionSessionTimeoutError,
+		"terminating connection due to idle-in-transaction timeout")
+}
+
 // IsQueryCanceledError checks whether this is a query canceled error.
 func IsQueryCanceledError(err error) bool {
 	return errHasCode(err, pgerror.CodeQueryCanceledError) || strings.Contains(err.Error(), "query execution canceled")
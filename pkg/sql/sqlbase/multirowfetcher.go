@@ -163,6 +163,10 @@ type MultiRowFetcher struct {
 	keyRemainingBytes []byte
 	kvEnd             bool
 
+	// bytesRead is the number of bytes of keys and values read from the KV
+	// layer by this MultiRowFetcher.
+	bytesRead int64
+
 	// Buffered allocation of decoded datums.
 	alloc *DatumAlloc
 }
@@ -366,6 +370,7 @@ func (mrf *MultiRowFetcher) NextKey(ctx context.Context) (rowDone bool, err erro
 		if err != nil {
 			return false, err
 		}
+		mrf.bytesRead += int64(len(mrf.kv.Key) + len(mrf.kv.Value.RawBytes))
 		mrf.kvEnd = !ok
 		if mrf.kvEnd {
 			// No more keys in the scan. We need to transition
@@ -862,6 +867,12 @@ func (mrf *MultiRowFetcher) GetRangeInfo() []roachpb.RangeInfo {
 	return mrf.kvFetcher.getRangesInfo()
 }
 
+// BytesRead returns the number of bytes of keys and values read from the KV
+// layer so far.
+func (mrf *MultiRowFetcher) BytesRead() int64 {
+	return mrf.bytesRead
+}
+
 // Only unique secondary indexes have extra columns to decode (namely the
 // primary index columns).
 func hasExtraCols(table *tableInfo) bool {
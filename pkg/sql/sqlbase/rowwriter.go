@@ -55,12 +55,7 @@ type rowHelper struct {
 func (rh *rowHelper) encodeIndexes(
 	colIDtoRowIndex map[ColumnID]int, values []tree.Datum,
 ) (primaryIndexKey []byte, secondaryIndexEntries []IndexEntry, err error) {
-	if rh.primaryIndexKeyPrefix == nil {
-		rh.primaryIndexKeyPrefix = MakeIndexKeyPrefix(rh.TableDesc,
-			rh.TableDesc.PrimaryIndex.ID)
-	}
-	primaryIndexKey, _, err = EncodeIndexKey(
-		rh.TableDesc, &rh.TableDesc.PrimaryIndex, colIDtoRowIndex, values, rh.primaryIndexKeyPrefix)
+	primaryIndexKey, err = rh.encodePrimaryIndex(colIDtoRowIndex, values)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -71,23 +66,54 @@ func (rh *rowHelper) encodeIndexes(
 	return primaryIndexKey, secondaryIndexEntries, nil
 }
 
+// encodePrimaryIndex encodes the primary index key.
+func (rh *rowHelper) encodePrimaryIndex(
+	colIDtoRowIndex map[ColumnID]int, values []tree.Datum,
+) (primaryIndexKey []byte, err error) {
+	if rh.primaryIndexKeyPrefix == nil {
+		rh.primaryIndexKeyPrefix = MakeIndexKeyPrefix(rh.TableDesc,
+			rh.TableDesc.PrimaryIndex.ID)
+	}
+	primaryIndexKey, _, err = EncodeIndexKey(
+		rh.TableDesc, &rh.TableDesc.PrimaryIndex, colIDtoRowIndex, values, rh.primaryIndexKeyPrefix)
+	return primaryIndexKey, err
+}
+
 // encodeSecondaryIndexes encodes the secondary index keys. The
 // secondaryIndexEntries are only valid until the next call to encodeIndexes or
 // encodeSecondaryIndexes.
 func (rh *rowHelper) encodeSecondaryIndexes(
 	colIDtoRowIndex map[ColumnID]int, values []tree.Datum,
 ) (secondaryIndexEntries []IndexEntry, err error) {
-	if len(rh.indexEntries) != len(rh.Indexes) {
-		rh.indexEntries = make([]IndexEntry, len(rh.Indexes))
-	}
-	err = EncodeSecondaryIndexes(
-		rh.TableDesc, rh.Indexes, colIDtoRowIndex, values, rh.indexEntries)
+	rh.indexEntries, err = EncodeSecondaryIndexes(
+		rh.TableDesc, rh.Indexes, colIDtoRowIndex, values, rh.indexEntries[:0])
 	if err != nil {
 		return nil, err
 	}
 	return rh.indexEntries, nil
 }
 
+// encodeSecondaryIndexesByIndex encodes the secondary index keys, grouping
+// them by index: the i-th element of the result holds the entries of
+// rh.Indexes[i]. The entries are stored in (and may reuse the memory of)
+// buf, which is returned.
+func (rh *rowHelper) encodeSecondaryIndexesByIndex(
+	colIDtoRowIndex map[ColumnID]int, values []tree.Datum, buf [][]IndexEntry,
+) ([][]IndexEntry, error) {
+	if len(buf) != len(rh.Indexes) {
+		buf = make([][]IndexEntry, len(rh.Indexes))
+	}
+	for i := range rh.Indexes {
+		var err error
+		buf[i], err = EncodeSecondaryIndexEntries(
+			rh.TableDesc, &rh.Indexes[i], colIDtoRowIndex, values, buf[i][:0])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
 // skipColumnInPK returns true if the value at column colID does not need
 // to be encoded because it is already part of the primary key. Composite
 // datums are considered too, so a composite datum in a PK will return false.
@@ -371,13 +397,14 @@ type RowUpdater struct {
 	Fks fkUpdateHelper
 
 	// For allocation avoidance.
-	marshalled      []roachpb.Value
-	newValues       []tree.Datum
-	key             roachpb.Key
-	indexEntriesBuf []IndexEntry
-	valueBuf        []byte
-	scratch         []byte
-	value           roachpb.Value
+	marshalled         []roachpb.Value
+	newValues          []tree.Datum
+	key                roachpb.Key
+	oldIndexEntriesBuf [][]IndexEntry
+	newIndexEntriesBuf [][]IndexEntry
+	valueBuf           []byte
+	scratch            []byte
+	value              roachpb.Value
 }
 
 type rowUpdaterType int
@@ -574,16 +601,18 @@ func (ru *RowUpdater) UpdateRow(
 		return nil, errors.Errorf("got %d values but expected %d", len(updateValues), len(ru.UpdateCols))
 	}
 
-	primaryIndexKey, secondaryIndexEntries, err := ru.Helper.encodeIndexes(ru.FetchColIDtoRowIndex, oldValues)
+	primaryIndexKey, err := ru.Helper.encodePrimaryIndex(ru.FetchColIDtoRowIndex, oldValues)
 	if err != nil {
 		return nil, err
 	}
-
-	// The secondary index entries returned by rowHelper.encodeIndexes are only
-	// valid until the next call to encodeIndexes. We need to copy them so that
-	// we can compare against the new secondary index entries.
-	secondaryIndexEntries = append(ru.indexEntriesBuf[:0], secondaryIndexEntries...)
-	ru.indexEntriesBuf = secondaryIndexEntries
+	// The secondary index entries are grouped by index, so that the old and new
+	// entries of each index can be compared.
+	secondaryIndexEntries, err := ru.Helper.encodeSecondaryIndexesByIndex(
+		ru.FetchColIDtoRowIndex, oldValues, ru.oldIndexEntriesBuf)
+	if err != nil {
+		return nil, err
+	}
+	ru.oldIndexEntriesBuf = secondaryIndexEntries
 
 	// Check that the new value types match the column types. This needs to
 	// happen before index encoding because certain datum types (i.e. tuple)
@@ -601,22 +630,19 @@ func (ru *RowUpdater) UpdateRow(
 	}
 
 	rowPrimaryKeyChanged := false
-	var newSecondaryIndexEntries []IndexEntry
 	if ru.primaryKeyColChange {
-		var newPrimaryIndexKey []byte
-		newPrimaryIndexKey, newSecondaryIndexEntries, err =
-			ru.Helper.encodeIndexes(ru.FetchColIDtoRowIndex, ru.newValues)
+		newPrimaryIndexKey, err := ru.Helper.encodePrimaryIndex(ru.FetchColIDtoRowIndex, ru.newValues)
 		if err != nil {
 			return nil, err
 		}
 		rowPrimaryKeyChanged = !bytes.Equal(primaryIndexKey, newPrimaryIndexKey)
-	} else {
-		newSecondaryIndexEntries, err =
-			ru.Helper.encodeSecondaryIndexes(ru.FetchColIDtoRowIndex, ru.newValues)
-		if err != nil {
-			return nil, err
-		}
 	}
+	newSecondaryIndexEntries, err := ru.Helper.encodeSecondaryIndexesByIndex(
+		ru.FetchColIDtoRowIndex, ru.newValues, ru.newIndexEntriesBuf)
+	if err != nil {
+		return nil, err
+	}
+	ru.newIndexEntriesBuf = newSecondaryIndexEntries
 
 	if rowPrimaryKeyChanged {
 		if err := ru.Fks.checkIdx(
@@ -625,7 +651,11 @@ func (ru *RowUpdater) UpdateRow(
 			return nil, err
 		}
 		for i := range newSecondaryIndexEntries {
-			if !bytes.Equal(newSecondaryIndexEntries[i].Key, secondaryIndexEntries[i].Key) {
+			if ru.Helper.Indexes[i].Type == IndexDescriptor_INVERTED {
+				// Inverted indexes cannot be referenced by foreign keys.
+				continue
+			}
+			if !bytes.Equal(newSecondaryIndexEntries[i][0].Key, secondaryIndexEntries[i][0].Key) {
 				if err := ru.Fks.checkIdx(ctx, ru.Helper.Indexes[i].ID, oldValues, ru.newValues); err != nil {
 					return nil, err
 				}
@@ -744,8 +774,14 @@ func (ru *RowUpdater) UpdateRow(
 	}
 
 	// Update secondary indexes.
-	for i, newSecondaryIndexEntry := range newSecondaryIndexEntries {
-		secondaryIndexEntry := secondaryIndexEntries[i]
+	for i := range newSecondaryIndexEntries {
+		if ru.Helper.Indexes[i].Type == IndexDescriptor_INVERTED {
+			ru.updateInvertedIndexEntries(
+				ctx, b, i, secondaryIndexEntries[i], newSecondaryIndexEntries[i], traceKV)
+			continue
+		}
+		secondaryIndexEntry := secondaryIndexEntries[i][0]
+		newSecondaryIndexEntry := newSecondaryIndexEntries[i][0]
 		var expValue interface{}
 		if !bytes.Equal(newSecondaryIndexEntry.Key, secondaryIndexEntry.Key) {
 			if err := ru.Fks.checkIdx(ctx, ru.Helper.Indexes[i].ID, oldValues, ru.newValues); err != nil {
@@ -776,6 +812,48 @@ func (ru *RowUpdater) UpdateRow(
 	return ru.newValues, nil
 }
 
+// updateInvertedIndexEntries adds to the batch the kv operations necessary to
+// replace the oldEntries of the i-th index with newEntries. The entries of an
+// inverted index are keyed by the indexed elements, so the entries for the
+// elements that were removed are deleted and the entries for the elements that
+// were added are written. Both lists of entries must be sorted by key.
+func (ru *RowUpdater) updateInvertedIndexEntries(
+	ctx context.Context, b *client.Batch, i int, oldEntries, newEntries []IndexEntry, traceKV bool,
+) {
+	_, deleteOnly := ru.deleteOnlyIndex[i]
+	for len(oldEntries) > 0 || len(newEntries) > 0 {
+		var c int
+		switch {
+		case len(oldEntries) == 0:
+			c = 1
+		case len(newEntries) == 0:
+			c = -1
+		default:
+			c = bytes.Compare(oldEntries[0].Key, newEntries[0].Key)
+		}
+		switch {
+		case c == 0:
+			oldEntries, newEntries = oldEntries[1:], newEntries[1:]
+		case c < 0:
+			if traceKV {
+				log.VEventf(ctx, 2, "Del %s", oldEntries[0].Key)
+			}
+			b.Del(oldEntries[0].Key)
+			oldEntries = oldEntries[1:]
+		default:
+			// Do not update Indexes in the DELETE_ONLY state.
+			if !deleteOnly {
+				e := &newEntries[0]
+				if traceKV {
+					log.VEventf(ctx, 2, "CPut %s -> %v", e.Key, e.Value.PrettyPrint())
+				}
+				b.CPut(e.Key, &e.Value, nil)
+			}
+			newEntries = newEntries[1:]
+		}
+	}
+}
+
 // IsColumnOnlyUpdate returns true if this RowUpdater is only updating column
 // data (in contrast to updating the primary key or other indexes).
 func (ru *RowUpdater) IsColumnOnlyUpdate() bool {
@@ -909,15 +987,17 @@ func (rd *RowDeleter) DeleteIndexRow(
 	if err := rd.Fks.checkAll(ctx, values); err != nil {
 		return err
 	}
-	secondaryIndexEntry, err := EncodeSecondaryIndex(
-		rd.Helper.TableDesc, idx, rd.FetchColIDtoRowIndex, values)
+	secondaryIndexEntries, err := EncodeSecondaryIndexEntries(
+		rd.Helper.TableDesc, idx, rd.FetchColIDtoRowIndex, values, nil)
 	if err != nil {
 		return err
 	}
-	if traceKV {
-		log.VEventf(ctx, 2, "Del %s", secondaryIndexEntry.Key)
+	for _, secondaryIndexEntry := range secondaryIndexEntries {
+		if traceKV {
+			log.VEventf(ctx, 2, "Del %s", secondaryIndexEntry.Key)
+		}
+		b.Del(secondaryIndexEntry.Key)
 	}
-	b.Del(secondaryIndexEntry.Key)
 	return nil
 }
 
@@ -288,6 +288,8 @@ func (desc *IndexDescriptor) ColNamesString() string {
 
 var isUnique = map[bool]string{true: "UNIQUE "}
 
+var isInverted = map[bool]string{true: "INVERTED "}
+
 // SQLString returns the SQL string describing this index. If non-empty,
 // "ON tableName" is included in the output in the correct place.
 func (desc *IndexDescriptor) SQLString(tableName string) string {
@@ -303,8 +305,9 @@ func (desc *IndexDescriptor) SQLString(tableName string) string {
 	if tableName != "" {
 		onTable = fmt.Sprintf("ON %s ", tableName)
 	}
-	return fmt.Sprintf("%sINDEX %s%s (%s)%s",
+	return fmt.Sprintf("%s%sINDEX %s%s (%s)%s",
 		isUnique[desc.Unique],
+		isInverted[desc.Type == IndexDescriptor_INVERTED],
 		onTable,
 		tree.AsString(tree.Name(desc.Name)),
 		desc.ColNamesString(),
@@ -624,7 +627,11 @@ func DatumTypeHasCompositeKeyEncoding(typ types.T) bool {
 // MustBeValueEncoded returns true if columns of the given kind can only be value
 // encoded.
 func MustBeValueEncoded(semanticType ColumnType_SemanticType) bool {
-	return semanticType == ColumnType_ARRAY || semanticType == ColumnType_JSON
+	switch semanticType {
+	case ColumnType_ARRAY, ColumnType_JSON, ColumnType_TSVECTOR, ColumnType_TSQUERY:
+		return true
+	}
+	return false
 }
 
 // HasOldStoredColumns returns whether the index has stored columns in the old
@@ -1583,7 +1590,8 @@ func upperBoundColumnValueEncodedSize(col ColumnDescriptor) (int, bool) {
 		typ = encoding.Float
 	case ColumnType_INTERVAL:
 		typ = encoding.Duration
-	case ColumnType_STRING, ColumnType_BYTES, ColumnType_COLLATEDSTRING, ColumnType_NAME, ColumnType_UUID, ColumnType_INET:
+	case ColumnType_STRING, ColumnType_BYTES, ColumnType_COLLATEDSTRING, ColumnType_NAME, ColumnType_UUID, ColumnType_INET,
+		ColumnType_TSVECTOR, ColumnType_TSQUERY:
 		// STRINGs are counted as runes, so this isn't totally correct, but this
 		// seems better than always assuming the maximum rune width.
 		typ, size = encoding.Bytes, int(col.Type.Width)
@@ -1659,7 +1667,11 @@ func notIndexableError(cols []ColumnDescriptor) error {
 	return errors.New(result)
 }
 
-func checkColumnsValidForIndex(tableDesc *TableDescriptor, indexColNames []string) error {
+func checkColumnsValidForIndex(tableDesc *TableDescriptor, idx *IndexDescriptor) error {
+	if idx.Type == IndexDescriptor_INVERTED {
+		return checkColumnsValidForInvertedIndex(tableDesc, idx)
+	}
+	indexColNames := idx.ColumnNames
 	invalidColumns := make([]ColumnDescriptor, 0, len(indexColNames))
 	for _, indexCol := range indexColNames {
 		for _, col := range tableDesc.Columns {
@@ -1676,6 +1688,65 @@ func checkColumnsValidForIndex(tableDesc *TableDescriptor, indexColNames []strin
 	return nil
 }
 
+// CheckIndexCollations checks that the collations specified for the columns of
+// an index, as in `CREATE INDEX ON t (a COLLATE en)`, match the collations of
+// the columns. Index keys are encoded from the column values, so an index
+// cannot collate a column differently than the column itself.
+func (desc *TableDescriptor) CheckIndexCollations(elems tree.IndexElemList) error {
+	for _, elem := range elems {
+		if elem.Collation == "" {
+			continue
+		}
+		col, _, err := desc.FindColumnByName(elem.Column)
+		if err != nil {
+			return err
+		}
+		switch col.Type.SemanticType {
+		case ColumnType_COLLATEDSTRING:
+			if sameLocale(*col.Type.Locale, elem.Collation) {
+				continue
+			}
+		case ColumnType_STRING:
+		default:
+			return pgerror.NewErrorf(pgerror.CodeDatatypeMismatchError,
+				"collations are not supported by type %s", col.Type.SQLString())
+		}
+		return pgerror.UnimplementedWithIssueErrorf(16619,
+			"collation %s of index column %q differs from the collation of the column",
+			elem.Collation, col.Name)
+	}
+	return nil
+}
+
+// sameLocale returns whether the two locales are the same, ignoring case and
+// whether underscores or dashes are used as separators.
+func sameLocale(a, b string) bool {
+	return strings.EqualFold(strings.Replace(a, "_", "-", -1), strings.Replace(b, "_", "-", -1))
+}
+
+// checkColumnsValidForInvertedIndex checks that an inverted index is defined
+// on a single TSVECTOR column.
+func checkColumnsValidForInvertedIndex(tableDesc *TableDescriptor, idx *IndexDescriptor) error {
+	if len(idx.ColumnNames) != 1 {
+		return pgerror.NewErrorf(pgerror.CodeFeatureNotSupportedError,
+			"inverted indexes must be defined on exactly one column")
+	}
+	if idx.Unique {
+		return pgerror.NewErrorf(pgerror.CodeFeatureNotSupportedError,
+			"inverted indexes cannot be unique")
+	}
+	for _, col := range tableDesc.Columns {
+		if col.Name == idx.ColumnNames[0] {
+			if col.Type.SemanticType != ColumnType_TSVECTOR {
+				return pgerror.NewErrorf(pgerror.CodeDatatypeMismatchError,
+					"column %s is of type %s and thus cannot be indexed by an inverted index",
+					col.Name, col.Type.SemanticType)
+			}
+		}
+	}
+	return nil
+}
+
 // AddColumn adds a column to the table.
 func (desc *TableDescriptor) AddColumn(col ColumnDescriptor) {
 	desc.Columns = append(desc.Columns, col)
@@ -1688,7 +1759,7 @@ func (desc *TableDescriptor) AddFamily(fam ColumnFamilyDescriptor) {
 
 // AddIndex adds an index to the table.
 func (desc *TableDescriptor) AddIndex(idx IndexDescriptor, primary bool) error {
-	if err := checkColumnsValidForIndex(desc, idx.ColumnNames); err != nil {
+	if err := checkColumnsValidForIndex(desc, &idx); err != nil {
 		return err
 	}
 	if primary {
@@ -2038,7 +2109,7 @@ func (desc *TableDescriptor) AddColumnMutation(
 func (desc *TableDescriptor) AddIndexMutation(
 	idx IndexDescriptor, direction DescriptorMutation_Direction,
 ) error {
-	if err := checkColumnsValidForIndex(desc, idx.ColumnNames); err != nil {
+	if err := checkColumnsValidForIndex(desc, &idx); err != nil {
 		return err
 	}
 	m := DescriptorMutation{Descriptor_: &DescriptorMutation_Index{Index: &idx}, Direction: direction}
@@ -2276,6 +2347,10 @@ func DatumTypeToColumnSemanticType(ptyp types.T) (ColumnType_SemanticType, error
 		return ColumnType_INT2VECTOR, nil
 	case types.JSON:
 		return ColumnType_JSON, nil
+	case types.TSVector:
+		return ColumnType_TSVECTOR, nil
+	case types.TSQuery:
+		return ColumnType_TSQUERY, nil
 	default:
 		if ptyp.FamilyEqual(types.FamCollatedString) {
 			return ColumnType_COLLATEDSTRING, nil
@@ -2342,6 +2417,10 @@ func columnSemanticTypeToDatumType(c *ColumnType, k ColumnType_SemanticType) typ
 		return types.INet
 	case ColumnType_JSON:
 		return types.JSON
+	case ColumnType_TSVECTOR:
+		return types.TSVector
+	case ColumnType_TSQUERY:
+		return types.TSQuery
 	case ColumnType_COLLATEDSTRING:
 		if c.Locale == nil {
 			panic("locale is required for COLLATEDSTRING")
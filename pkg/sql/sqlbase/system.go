@@ -48,8 +48,11 @@ CREATE TABLE system.descriptor (
 
 	UsersTableSchema = `
 CREATE TABLE system.users (
-  username         STRING PRIMARY KEY,
-  "hashedPassword" BYTES
+  username           STRING PRIMARY KEY,
+  "hashedPassword"   BYTES,
+  "scramCredentials" STRING,
+  "md5Password"      STRING,
+  "connectionLimit"  INT
 );`
 
 	// Zone settings per DB/Table.
@@ -315,14 +318,20 @@ var (
 		Columns: []ColumnDescriptor{
 			{Name: "username", ID: 1, Type: colTypeString},
 			{Name: "hashedPassword", ID: 2, Type: colTypeBytes, Nullable: true},
+			{Name: "scramCredentials", ID: 3, Type: colTypeString, Nullable: true},
+			{Name: "md5Password", ID: 4, Type: colTypeString, Nullable: true},
+			{Name: "connectionLimit", ID: 5, Type: colTypeInt, Nullable: true},
 		},
-		NextColumnID: 3,
+		NextColumnID: 6,
 		Families: []ColumnFamilyDescriptor{
 			{Name: "primary", ID: 0, ColumnNames: []string{"username"}, ColumnIDs: singleID1},
 			{Name: "fam_2_hashedPassword", ID: 2, ColumnNames: []string{"hashedPassword"}, ColumnIDs: []ColumnID{2}, DefaultColumnID: 2},
+			{Name: "fam_3_scramCredentials", ID: 3, ColumnNames: []string{"scramCredentials"}, ColumnIDs: []ColumnID{3}, DefaultColumnID: 3},
+			{Name: "fam_4_md5Password", ID: 4, ColumnNames: []string{"md5Password"}, ColumnIDs: []ColumnID{4}, DefaultColumnID: 4},
+			{Name: "fam_5_connectionLimit", ID: 5, ColumnNames: []string{"connectionLimit"}, ColumnIDs: []ColumnID{5}, DefaultColumnID: 5},
 		},
 		PrimaryIndex:   pk("username"),
-		NextFamilyID:   3,
+		NextFamilyID:   6,
 		NextIndexID:    2,
 		Privileges:     NewPrivilegeDescriptor(security.RootUser, SystemDesiredPrivileges(keys.UsersTableID)),
 		FormatVersion:  InterleavedFormatVersion,
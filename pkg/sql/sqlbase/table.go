@@ -650,6 +650,10 @@ func EncodeTableValue(
 			return nil, err
 		}
 		return encoding.EncodeJSONValue(appendTo, uint32(colID), encoded), nil
+	case *tree.DTSVector:
+		return encoding.EncodeBytesValue(appendTo, uint32(colID), []byte(t.Vector.String())), nil
+	case *tree.DTSQuery:
+		return encoding.EncodeBytesValue(appendTo, uint32(colID), []byte(t.Query.String())), nil
 	case *tree.DArray:
 		a, err := encodeArray(t, scratch)
 		if err != nil {
@@ -1488,6 +1492,20 @@ func decodeUntaggedDatum(a *DatumAlloc, t types.T, buf []byte) (tree.Datum, []by
 		}
 		_, j, err := json.DecodeJSON(data)
 		return a.NewDJSON(tree.DJSON{JSON: j}), b, err
+	case types.TSVector:
+		b, data, err := encoding.DecodeUntaggedBytesValue(buf)
+		if err != nil {
+			return nil, b, err
+		}
+		d, err := tree.ParseDTSVector(string(data))
+		return d, b, err
+	case types.TSQuery:
+		b, data, err := encoding.DecodeUntaggedBytesValue(buf)
+		if err != nil {
+			return nil, b, err
+		}
+		d, err := tree.ParseDTSQuery(string(data))
+		return d, b, err
 	case types.Oid:
 		b, data, err := encoding.DecodeUntaggedIntValue(buf)
 		return a.NewDOid(tree.MakeDOid(tree.DInt(data))), b, err
@@ -1604,25 +1622,93 @@ func EncodeSecondaryIndex(
 	return entry, nil
 }
 
+// EncodeInvertedIndexKeys encodes the keys of an inverted index: one key per
+// lexeme of the TSVECTOR value of the index's column, followed by the extra
+// (primary key) columns. A NULL or empty value has no keys. colMap maps
+// ColumnIDs to indices in `values`.
+func EncodeInvertedIndexKeys(
+	tableDesc *TableDescriptor, index *IndexDescriptor, colMap map[ColumnID]int, values []tree.Datum,
+) ([][]byte, error) {
+	if len(index.ColumnIDs) != 1 {
+		return nil, errors.Errorf("inverted index %q must have exactly one column", index.Name)
+	}
+	val := findColumnValue(index.ColumnIDs[0], colMap, values)
+	if val == tree.DNull {
+		return nil, nil
+	}
+	vec, ok := val.(*tree.DTSVector)
+	if !ok {
+		return nil, errors.Errorf("cannot build inverted index %q on value of type %s",
+			index.Name, val.ResolvedType())
+	}
+	prefix := MakeIndexKeyPrefix(tableDesc, index.ID)
+	extraKey, _, err := EncodeColumns(index.ExtraColumnIDs, nil, colMap, values, nil)
+	if err != nil {
+		return nil, err
+	}
+	invertedKeys := make([][]byte, len(vec.Vector))
+	for i, word := range vec.Vector.Words() {
+		key := append([]byte(nil), prefix...)
+		key = encoding.EncodeStringAscending(key, word)
+		invertedKeys[i] = append(key, extraKey...)
+	}
+	return invertedKeys, nil
+}
+
+// EncodeSecondaryIndexEntries encodes the key/values for a secondary index,
+// appending them to entries. A forward index has exactly one entry per row,
+// while an inverted index has one entry per element of the indexed value.
+// colMap maps ColumnIDs to indices in `values`.
+func EncodeSecondaryIndexEntries(
+	tableDesc *TableDescriptor,
+	secondaryIndex *IndexDescriptor,
+	colMap map[ColumnID]int,
+	values []tree.Datum,
+	entries []IndexEntry,
+) ([]IndexEntry, error) {
+	if secondaryIndex.Type != IndexDescriptor_INVERTED {
+		entry, err := EncodeSecondaryIndex(tableDesc, secondaryIndex, colMap, values)
+		if err != nil {
+			return nil, err
+		}
+		return append(entries, entry), nil
+	}
+	invertedKeys, err := EncodeInvertedIndexKeys(tableDesc, secondaryIndex, colMap, values)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range invertedKeys {
+		// Index keys are considered "sentinel" keys in that they do not have a
+		// column ID suffix.
+		entry := IndexEntry{Key: keys.MakeFamilyKey(key, 0)}
+		// The zero value for an index-key is a 0-length bytes value.
+		entry.Value.SetBytes([]byte{})
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
 // EncodeSecondaryIndexes encodes key/values for the secondary indexes. colMap
-// maps ColumnIDs to indices in `values`. secondaryIndexEntries is the return
-// value (passed as a parameter so the caller can reuse between rows) and is
-// expected to be the same length as indexes.
+// maps ColumnIDs to indices in `values`. The entries are appended to
+// secondaryIndexEntries (passed as a parameter so the caller can reuse it
+// between rows), which is returned. Note that an inverted index can have any
+// number of entries for a given row.
 func EncodeSecondaryIndexes(
 	tableDesc *TableDescriptor,
 	indexes []IndexDescriptor,
 	colMap map[ColumnID]int,
 	values []tree.Datum,
 	secondaryIndexEntries []IndexEntry,
-) error {
+) ([]IndexEntry, error) {
 	for i := range indexes {
 		var err error
-		secondaryIndexEntries[i], err = EncodeSecondaryIndex(tableDesc, &indexes[i], colMap, values)
+		secondaryIndexEntries, err = EncodeSecondaryIndexEntries(
+			tableDesc, &indexes[i], colMap, values, secondaryIndexEntries)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
-	return nil
+	return secondaryIndexEntries, nil
 }
 
 // CheckColumnType verifies that a given value is compatible
@@ -1753,6 +1839,16 @@ func MarshalColumnValue(col ColumnDescriptor, val tree.Datum) (roachpb.Value, er
 			r.SetBytes(data)
 			return r, nil
 		}
+	case ColumnType_TSVECTOR:
+		if v, ok := val.(*tree.DTSVector); ok {
+			r.SetString(v.Vector.String())
+			return r, nil
+		}
+	case ColumnType_TSQUERY:
+		if v, ok := val.(*tree.DTSQuery); ok {
+			r.SetString(v.Query.String())
+			return r, nil
+		}
 	case ColumnType_ARRAY:
 		if v, ok := val.(*tree.DArray); ok {
 			if err := checkElementType(v.ParamTyp, col.Type); err != nil {
@@ -2029,6 +2125,18 @@ func UnmarshalColumnValue(a *DatumAlloc, typ ColumnType, value roachpb.Value) (t
 			return nil, err
 		}
 		return a.NewDIPAddr(tree.DIPAddr{IPAddr: ipAddr}), nil
+	case ColumnType_TSVECTOR:
+		v, err := value.GetBytes()
+		if err != nil {
+			return nil, err
+		}
+		return tree.ParseDTSVector(string(v))
+	case ColumnType_TSQUERY:
+		v, err := value.GetBytes()
+		if err != nil {
+			return nil, err
+		}
+		return tree.ParseDTSQuery(string(v))
 	case ColumnType_NAME:
 		v, err := value.GetBytes()
 		if err != nil {
@@ -31,6 +31,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/cockroach/pkg/util/timeofday"
 	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/cockroachdb/cockroach/pkg/util/tsearch"
 	"github.com/cockroachdb/cockroach/pkg/util/uuid"
 )
 
@@ -109,6 +110,10 @@ func RandDatum(rng *rand.Rand, typ ColumnType, nullOk bool) tree.Datum {
 			return nil
 		}
 		return &tree.DJSON{JSON: j}
+	case ColumnType_TSVECTOR:
+		return tree.NewDTSVector(tsearch.Simple.ToVector(randTextSearchDocument(rng)))
+	case ColumnType_TSQUERY:
+		return tree.NewDTSQuery(tsearch.Simple.PlainToQuery(randTextSearchDocument(rng)))
 	case ColumnType_STRING:
 		// Generate a random ASCII string.
 		p := make([]byte, rng.Intn(10))
@@ -161,6 +166,18 @@ func RandDatum(rng *rand.Rand, typ ColumnType, nullOk bool) tree.Datum {
 	}
 }
 
+// randTextSearchDocument generates a random document of lowercase ASCII words.
+func randTextSearchDocument(rng *rand.Rand) string {
+	var buf bytes.Buffer
+	for i, n := 0, rng.Intn(5); i < n; i++ {
+		buf.WriteByte(' ')
+		for j, m := 0, 1+rng.Intn(5); j < m; j++ {
+			buf.WriteByte(byte('a' + rng.Intn(26)))
+		}
+	}
+	return buf.String()
+}
+
 var (
 	columnSemanticTypes []ColumnType_SemanticType
 	collationLocales    = [...]string{"da", "de", "en"}
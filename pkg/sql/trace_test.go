@@ -119,6 +119,7 @@ func TestTrace(t *testing.T) {
 				"sql txn implicit",
 				"flow",
 				"table reader",
+				"processor stats",
 				"/cockroach.roachpb.Internal/Batch",
 			},
 			// Depending on whether the data is local or not, we may not see these
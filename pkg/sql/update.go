@@ -118,6 +118,7 @@ type updateNode struct {
 	n             *tree.Update
 	updateCols    []sqlbase.ColumnDescriptor
 	updateColsIdx map[sqlbase.ColumnID]int // index in updateCols slice
+	fkTables      sqlbase.TableLookupsByID
 	tw            tableUpdater
 	checkHelper   checkHelper
 	sourceSlots   []sourceSlot
@@ -384,6 +385,7 @@ func (p *planner) Update(
 		editNodeBase:  en,
 		updateCols:    ru.UpdateCols,
 		updateColsIdx: updateColsIdx,
+		fkTables:      fkTables,
 		tw:            tw,
 		sourceSlots:   sourceSlots,
 	}
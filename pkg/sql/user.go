@@ -20,28 +20,74 @@ import (
 
 	"github.com/cockroachdb/cockroach/pkg/internal/client"
 	"github.com/cockroachdb/cockroach/pkg/security"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 )
 
-// GetUserHashedPassword returns the hashedPassword for the given username if
-// found in system.users.
-func GetUserHashedPassword(
+// UserCredentials are the credentials with which a user can authenticate with
+// a password, as stored in system.users. They are empty if the user has no
+// password. ScramCredentials and MD5Password are also empty if the password
+// was set before they were stored alongside HashedPassword.
+type UserCredentials struct {
+	// HashedPassword is the hash of security.HashPassword, which verifies
+	// cleartext passwords.
+	HashedPassword []byte
+	// ScramCredentials are the SCRAM-SHA-256 credentials, encoded by
+	// security.HashPasswordScram.
+	ScramCredentials string
+	// MD5Password is the hash of security.HashPasswordMD5.
+	MD5Password string
+	// ConnectionLimit is the maximum number of connections which the user can
+	// open to a node, as set by ALTER USER ... CONNECTION LIMIT, or -1 if the
+	// user has no limit.
+	ConnectionLimit int64
+}
+
+// makeUserCredentials computes the credentials of a user with the given
+// password.
+func makeUserCredentials(normalizedUsername, password string) (UserCredentials, error) {
+	hashedPassword, err := security.HashPassword(password)
+	if err != nil {
+		return UserCredentials{}, err
+	}
+	scramCredentials, err := security.HashPasswordScram(password)
+	if err != nil {
+		return UserCredentials{}, err
+	}
+	return UserCredentials{
+		HashedPassword:   hashedPassword,
+		ScramCredentials: scramCredentials,
+		MD5Password:      security.HashPasswordMD5(normalizedUsername, password),
+	}, nil
+}
+
+// GetUserCredentials returns the credentials for the given username if found
+// in system.users. The root user has no password and no connection limit.
+func GetUserCredentials(
 	ctx context.Context, executor *Executor, metrics *MemoryMetrics, username string,
-) (bool, []byte, error) {
+) (bool, UserCredentials, error) {
 	normalizedUsername := tree.Name(username).Normalize()
 	// The root user is not in system.users.
 	if normalizedUsername == security.RootUser {
-		return true, nil, nil
+		return true, UserCredentials{ConnectionLimit: -1}, nil
 	}
 
-	var hashedPassword []byte
+	var creds UserCredentials
 	var exists bool
 	err := executor.cfg.DB.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
 		p := makeInternalPlanner("get-pwd", txn, security.RootUser, metrics)
 		defer finishInternalPlanner(p)
-		const getHashedPassword = `SELECT "hashedPassword" FROM system.users ` +
-			`WHERE username=$1`
-		values, err := p.QueryRow(ctx, getHashedPassword, normalizedUsername)
+		creds = UserCredentials{ConnectionLimit: -1}
+		const getCredentials = `SELECT "hashedPassword", "scramCredentials", "md5Password", ` +
+			`"connectionLimit" FROM system.users WHERE username=$1`
+		values, err := p.QueryRow(ctx, getCredentials, normalizedUsername)
+		if pgErr, ok := pgerror.GetPGCause(err); ok && pgErr.Code == pgerror.CodeUndefinedColumnError {
+			// The migration that adds the connectionLimit column hasn't run
+			// yet, so the user can't have a connection limit.
+			const getCredentialsWithoutLimit = `SELECT "hashedPassword", "scramCredentials", ` +
+				`"md5Password" FROM system.users WHERE username=$1`
+			values, err = p.QueryRow(ctx, getCredentialsWithoutLimit, normalizedUsername)
+		}
 		if err != nil {
 			return errors.Errorf("error looking up user %s", normalizedUsername)
 		}
@@ -49,9 +95,22 @@ func GetUserHashedPassword(
 			return nil
 		}
 		exists = true
-		hashedPassword = []byte(*(values[0].(*tree.DBytes)))
+		if hashedPassword, ok := values[0].(*tree.DBytes); ok {
+			creds.HashedPassword = []byte(*hashedPassword)
+		}
+		if scramCredentials, ok := values[1].(*tree.DString); ok {
+			creds.ScramCredentials = string(*scramCredentials)
+		}
+		if md5Password, ok := values[2].(*tree.DString); ok {
+			creds.MD5Password = string(*md5Password)
+		}
+		if len(values) > 3 {
+			if connectionLimit, ok := values[3].(*tree.DInt); ok {
+				creds.ConnectionLimit = int64(*connectionLimit)
+			}
+		}
 		return nil
 	})
 
-	return exists, hashedPassword, err
+	return exists, creds, err
 }
@@ -23,6 +23,7 @@ import (
 
 	"golang.org/x/net/context"
 
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
 	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
@@ -89,10 +90,29 @@ var varGen = map[string]sessionVar{
 		},
 	},
 
-	// Supported for PG compatibility only.
-	// Controls returned message verbosity. We don't support this.
-	// See https://www.postgresql.org/docs/9.6/static/runtime-config-compatible.html
-	`client_min_messages`: nopVar,
+	// Controls which notices are sent to the client.
+	// See https://www.postgresql.org/docs/9.6/static/runtime-config-logging.html
+	`client_min_messages`: {
+		Set: func(_ context.Context, session *Session, values []tree.TypedExpr) error {
+			s, err := getStringVal(session, `client_min_messages`, values)
+			if err != nil {
+				return err
+			}
+			severity, ok := pgerror.ParseSeverity(s)
+			if !ok {
+				return fmt.Errorf("set client_min_messages: \"%s\" not supported", s)
+			}
+			session.ClientMinMessages = severity
+			return nil
+		},
+		Get: func(session *Session) string {
+			return strings.ToLower(session.ClientMinMessages.String())
+		},
+		Reset: func(session *Session) error {
+			session.ClientMinMessages = pgerror.SeverityNotice
+			return nil
+		},
+	},
 
 	// Supported for PG compatibility only.
 	// See https://www.postgresql.org/docs/9.6/static/multibyte.html
@@ -223,6 +243,43 @@ var varGen = map[string]sessionVar{
 	// See https://www.postgresql.org/docs/9.6/static/runtime-config-client.html
 	`extra_float_digits`: nopVar,
 
+	// See https://www.postgresql.org/docs/9.6/static/runtime-config-client.html
+	`idle_in_transaction_session_timeout`: {
+		Set: func(_ context.Context, session *Session, values []tree.TypedExpr) error {
+			d, err := getTimeoutVal(`idle_in_transaction_session_timeout`, session, values)
+			if err != nil {
+				return err
+			}
+			session.IdleInTransactionSessionTimeout = d
+			return nil
+		},
+		Get: func(session *Session) string {
+			return formatTimeout(session.IdleInTransactionSessionTimeout)
+		},
+		Reset: func(session *Session) error {
+			session.IdleInTransactionSessionTimeout =
+				defaultIdleInTransactionSessionTimeout.Get(&session.execCfg.Settings.SV)
+			return nil
+		},
+	},
+
+	// See https://www.postgresql.org/docs/9.6/static/runtime-config-client.html
+	`lock_timeout`: {
+		Set: func(_ context.Context, session *Session, values []tree.TypedExpr) error {
+			d, err := getTimeoutVal(`lock_timeout`, session, values)
+			if err != nil {
+				return err
+			}
+			session.LockTimeout = d
+			return nil
+		},
+		Get: func(session *Session) string { return formatTimeout(session.LockTimeout) },
+		Reset: func(session *Session) error {
+			session.LockTimeout = defaultLockTimeout.Get(&session.execCfg.Settings.SV)
+			return nil
+		},
+	},
+
 	`max_index_keys`: {
 		// Supported for PG compatibility only.
 		Get: func(*Session) string { return "32" },
@@ -297,6 +354,23 @@ var varGen = map[string]sessionVar{
 		Reset: func(*Session) error { return nil },
 	},
 
+	// See https://www.postgresql.org/docs/9.6/static/runtime-config-client.html
+	`statement_timeout`: {
+		Set: func(_ context.Context, session *Session, values []tree.TypedExpr) error {
+			d, err := getTimeoutVal(`statement_timeout`, session, values)
+			if err != nil {
+				return err
+			}
+			session.StatementTimeout = d
+			return nil
+		},
+		Get: func(session *Session) string { return formatTimeout(session.StatementTimeout) },
+		Reset: func(session *Session) error {
+			session.StatementTimeout = defaultStatementTimeout.Get(&session.execCfg.Settings.SV)
+			return nil
+		},
+	},
+
 	`timezone`: {
 		Get: func(session *Session) string {
 			// If the time zone is a "fixed offset" one, initialized from an offset
@@ -417,6 +491,60 @@ var varNames = func() []string {
 	return res
 }()
 
+// getTimeoutVal returns the value of a timeout session variable. Like in
+// Postgres, integers are milliseconds, and strings are either milliseconds or
+// intervals, e.g. '5s'. Zero disables the timeout.
+func getTimeoutVal(name string, session *Session, values []tree.TypedExpr) (time.Duration, error) {
+	if len(values) != 1 {
+		return 0, fmt.Errorf("set %s requires a single argument", name)
+	}
+	evalCtx := session.evalCtx()
+	val, err := values[0].Eval(&evalCtx)
+	if err != nil {
+		return 0, err
+	}
+	var d time.Duration
+	switch v := tree.UnwrapDatum(&evalCtx, val).(type) {
+	case *tree.DInt:
+		d = time.Duration(*v) * time.Millisecond
+	case *tree.DString:
+		if ms, err := strconv.ParseInt(string(*v), 10, 64); err == nil {
+			d = time.Duration(ms) * time.Millisecond
+			break
+		}
+		i, err := tree.ParseDInterval(string(*v))
+		if err != nil {
+			return 0, fmt.Errorf("set %s: invalid timeout %q", name, string(*v))
+		}
+		nanos, _, _, err := i.Duration.Encode()
+		if err != nil {
+			return 0, err
+		}
+		d = time.Duration(nanos)
+	case *tree.DInterval:
+		nanos, _, _, err := v.Duration.Encode()
+		if err != nil {
+			return 0, err
+		}
+		d = time.Duration(nanos)
+	default:
+		return 0, fmt.Errorf("set %s requires an integer, string or interval value: %s is a %s",
+			name, values[0], val.ResolvedType())
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("set %s: timeout cannot be negative", name)
+	}
+	return d, nil
+}
+
+// formatTimeout formats the value of a timeout session variable.
+func formatTimeout(d time.Duration) string {
+	if d == 0 {
+		return "0"
+	}
+	return d.String()
+}
+
 func getSingleBool(name string, session *Session, values []tree.TypedExpr) (*tree.DBool, error) {
 	if len(values) != 1 {
 		return nil, fmt.Errorf("set %s requires a single argument", name)
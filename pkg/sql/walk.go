@@ -186,6 +186,12 @@ func (v *planVisitor) visit(plan planNode) {
 				jType = "right outer"
 			case joinTypeFullOuter:
 				jType = "full outer"
+			case joinTypeLeftSemi:
+				jType = "semi"
+			case joinTypeLeftAnti:
+				jType = "anti"
+			case joinTypeLeftAntiNullAware:
+				jType = "null-aware anti"
 			}
 			v.observer.attr(name, "type", jType)
 
@@ -408,6 +414,16 @@ func (v *planVisitor) visit(plan planNode) {
 		}
 		v.visit(n.plan)
 
+	case *listenNode:
+		if v.observer.attr != nil && n.channel != "" {
+			v.observer.attr(name, "channel", n.channel)
+		}
+
+	case *notifyNode:
+		if v.observer.attr != nil {
+			v.observer.attr(name, "channel", n.channel)
+		}
+
 	case *cancelQueryNode:
 		subplans := v.expr(name, "queryID", -1, n.queryID, nil)
 		v.subqueries(name, subplans)
@@ -504,6 +520,10 @@ func nodeName(plan planNode) string {
 		if n.emitAll {
 			return "append"
 		}
+	case *listenNode:
+		if !n.listen {
+			return "unlisten"
+		}
 	}
 
 	name, ok := planNodeNames[reflect.TypeOf(plan)]
@@ -521,6 +541,7 @@ var planNodeNames = map[reflect.Type]string{
 	reflect.TypeOf(&alterTableNode{}):           "alter table",
 	reflect.TypeOf(&alterSequenceNode{}):        "alter sequence",
 	reflect.TypeOf(&alterUserSetPasswordNode{}): "alter user",
+	reflect.TypeOf(&alterUserConnLimitNode{}):   "alter user",
 	reflect.TypeOf(&cancelQueryNode{}):          "cancel query",
 	reflect.TypeOf(&controlJobNode{}):           "control job",
 	reflect.TypeOf(&copyNode{}):                 "copy",
@@ -530,6 +551,7 @@ var planNodeNames = map[reflect.Type]string{
 	reflect.TypeOf(&createUserNode{}):           "create user",
 	reflect.TypeOf(&createViewNode{}):           "create view",
 	reflect.TypeOf(&createSequenceNode{}):       "create sequence",
+	reflect.TypeOf(&declareCursorNode{}):        "declare cursor",
 	reflect.TypeOf(&delayedNode{}):              "virtual table",
 	reflect.TypeOf(&deleteNode{}):               "delete",
 	reflect.TypeOf(&distinctNode{}):             "distinct",
@@ -542,6 +564,7 @@ var planNodeNames = map[reflect.Type]string{
 	reflect.TypeOf(&explainDistSQLNode{}):       "explain dist_sql",
 	reflect.TypeOf(&explainPlanNode{}):          "explain plan",
 	reflect.TypeOf(&traceNode{}):                "show trace for",
+	reflect.TypeOf(&fetchNode{}):                "fetch",
 	reflect.TypeOf(&filterNode{}):               "filter",
 	reflect.TypeOf(&groupNode{}):                "group",
 	reflect.TypeOf(&unaryNode{}):                "emptyrow",
@@ -550,6 +573,9 @@ var planNodeNames = map[reflect.Type]string{
 	reflect.TypeOf(&insertNode{}):               "insert",
 	reflect.TypeOf(&joinNode{}):                 "join",
 	reflect.TypeOf(&limitNode{}):                "limit",
+	reflect.TypeOf(&listenNode{}):               "listen",
+	reflect.TypeOf(&moveNode{}):                 "move",
+	reflect.TypeOf(&notifyNode{}):               "notify",
 	reflect.TypeOf(&ordinalityNode{}):           "ordinality",
 	reflect.TypeOf(&testingRelocateNode{}):      "testingRelocate",
 	reflect.TypeOf(&renderNode{}):               "render",
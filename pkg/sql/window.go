@@ -335,6 +335,7 @@ func (n *windowNode) replaceIndexVarsAndAggFuncs(s *renderNode) {
 	n.aggContainer = windowNodeAggContainer{
 		windowNodeIvarContainer: makeWindowNodeIvarContainer(n),
 		aggFuncs:                make(map[int]*tree.FuncExpr),
+		aggIVars:                make(map[*tree.IndexedVar]struct{}),
 	}
 	// The number of aggregation functions that need to be replaced with IndexedVars
 	// is unknown, so we collect them here and bind them to an IndexedVarHelper later.
@@ -380,6 +381,7 @@ func (n *windowNode) replaceIndexVarsAndAggFuncs(s *renderNode) {
 					aggIVars[colIdx] = aggIVar
 					n.aggContainer.idxMap[idx] = colIdx
 					n.aggContainer.aggFuncs[idx] = t
+					n.aggContainer.aggIVars[aggIVar] = struct{}{}
 					return nil, false, aggIVar
 				}
 				return nil, true, expr
@@ -686,7 +688,7 @@ func (n *windowNode) computeWindows(ctx context.Context) error {
 
 			// Iterate over peer groups within partition using a window frame.
 			frame := tree.WindowFrame{
-				Rows:        partition,
+				Rows:        tree.IndexedRowSlice(partition),
 				ArgIdxStart: windowFn.argIdxStart,
 				ArgCount:    windowFn.argCount,
 				RowIdx:      0,
@@ -995,6 +997,9 @@ type windowNodeAggContainer struct {
 
 	// aggFuncs maps the index of IndexedVars to their corresponding aggregate function.
 	aggFuncs map[int]*tree.FuncExpr
+	// aggIVars contains the IndexedVars that replaced the aggregate functions,
+	// which allows them to be told apart from the IndexedVars of colContainer.
+	aggIVars map[*tree.IndexedVar]struct{}
 }
 
 // IndexedVarResolvedType implements the tree.IndexedVarContainer interface.
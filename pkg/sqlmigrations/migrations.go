@@ -95,6 +95,14 @@ var backwardCompatibleMigrations = []migrationDescriptor{
 		newDescriptors: 1,
 		newRanges:      1,
 	},
+	{
+		name:   "add SCRAM and MD5 credentials to system.users",
+		workFn: addUsersCredentialsColumns,
+	},
+	{
+		name:   "add connection limits to system.users",
+		workFn: addUsersConnectionLimitColumn,
+	},
 }
 
 // migrationDescriptor describes a single migration hook that's used to modify
@@ -432,6 +440,18 @@ func disableNetTrace(ctx context.Context, r runner) error {
 	return runStmtAsRootWithRetry(ctx, r, `SET CLUSTER SETTING trace.debug.enable = false`)
 }
 
+func addUsersCredentialsColumns(ctx context.Context, r runner) error {
+	return runStmtAsRootWithRetry(ctx, r, `
+ALTER TABLE system.users
+  ADD COLUMN IF NOT EXISTS "scramCredentials" STRING,
+  ADD COLUMN IF NOT EXISTS "md5Password" STRING`)
+}
+
+func addUsersConnectionLimitColumn(ctx context.Context, r runner) error {
+	return runStmtAsRootWithRetry(ctx, r,
+		`ALTER TABLE system.users ADD COLUMN IF NOT EXISTS "connectionLimit" INT`)
+}
+
 func populateVersionSetting(ctx context.Context, r runner) error {
 	var v roachpb.Version
 	if err := r.db.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
@@ -0,0 +1,92 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package batcheval
+
+import (
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/storage/batcheval/result"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
+)
+
+// FindSplitKeys returns keys that split the request's span into pieces of
+// roughly TargetSize bytes each, as estimated from the data in the range. At
+// most MaxKeys keys are returned; the pieces are made larger if the span
+// holds more than MaxKeys+1 pieces worth of data. Like the keys picked for
+// range splits, the returned keys never fall between the keys of a table row.
+func FindSplitKeys(
+	ctx context.Context, batch engine.ReadWriter, cArgs CommandArgs, resp roachpb.Response,
+) (result.Result, error) {
+	args := cArgs.Args.(*roachpb.FindSplitKeysRequest)
+	reply := resp.(*roachpb.FindSplitKeysResponse)
+
+	if args.TargetSize <= 0 || args.MaxKeys <= 0 {
+		return result.Result{}, nil
+	}
+
+	start, err := keys.Addr(args.Key)
+	if err != nil {
+		return result.Result{}, err
+	}
+	end, err := keys.Addr(args.EndKey)
+	if err != nil {
+		return result.Result{}, err
+	}
+
+	// Use the range's stats if the span covers the whole range; otherwise,
+	// compute the size of the span from its data.
+	var ms enginepb.MVCCStats
+	desc := cArgs.EvalCtx.Desc()
+	if start.Equal(desc.StartKey) && end.Equal(desc.EndKey) {
+		ms = cArgs.EvalCtx.GetMVCCStats()
+	} else {
+		iter := batch.NewIterator(false /* prefix */)
+		defer iter.Close()
+		ms, err = iter.ComputeStats(
+			engine.MakeMVCCMetadataKey(args.Key), engine.MakeMVCCMetadataKey(args.EndKey),
+			cArgs.Header.Timestamp.WallTime)
+		if err != nil {
+			return result.Result{}, err
+		}
+	}
+
+	pieces := ms.Total() / args.TargetSize
+	if pieces > int64(args.MaxKeys)+1 {
+		pieces = int64(args.MaxKeys) + 1
+	}
+	if pieces < 2 {
+		return result.Result{}, nil
+	}
+	pieceSize := ms.Total() / pieces
+
+	for i := int64(1); i < pieces; i++ {
+		splitKey, err := engine.MVCCFindSplitKey(ctx, batch, start, end, pieceSize, true /* allowMeta2Splits */)
+		if err != nil {
+			return result.Result{}, err
+		}
+		// Stop once there is no valid key left strictly inside the remaining
+		// span.
+		rSplitKey := roachpb.RKey(splitKey)
+		if !start.Less(rSplitKey) || !rSplitKey.Less(end) {
+			break
+		}
+		reply.SplitKeys = append(reply.SplitKeys, splitKey)
+		start = rSplitKey
+	}
+	return result.Result{}, nil
+}
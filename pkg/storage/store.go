@@ -2625,7 +2625,24 @@ func (s *Store) Send(
 					clonedTxn := h.Txn.Clone()
 					h.Txn = &clonedTxn
 				}
-				if pErr = s.intentResolver.processWriteIntentError(ctx, pErr, args, h, pushType); pErr != nil {
+				// The push waits for the conflicting transactions for at most the
+				// lock timeout of the batch, if any.
+				pushCtx := ctx
+				var cancel func()
+				if ba.LockTimeout > 0 {
+					pushCtx, cancel = context.WithTimeout(ctx, ba.LockTimeout)
+				}
+				wiErr := pErr.GetDetail().(*roachpb.WriteIntentError)
+				pErr = s.intentResolver.processWriteIntentError(pushCtx, pErr, args, h, pushType)
+				if cancel != nil {
+					if pErr != nil && ctx.Err() == nil && pushCtx.Err() == context.DeadlineExceeded {
+						lockTimeoutErr := *wiErr
+						lockTimeoutErr.LockTimeout = true
+						pErr = roachpb.NewErrorWithTxn(&lockTimeoutErr, ba.Txn)
+					}
+					cancel()
+				}
+				if pErr != nil {
 					// Do not propagate ambiguous results; assume success and retry original op.
 					if _, ok := pErr.GetDetail().(*roachpb.AmbiguousResultError); !ok {
 						// Preserve the error index.
@@ -41,6 +41,9 @@ export const NODE_DECOMMISSIONED = "node_decommissioned";
 export const NODE_RECOMMISSIONED = "node_recommissioned";
 // Recorded when a cluster setting is changed.
 export const SET_CLUSTER_SETTING = "set_cluster_setting";
+// Recorded when a connection is rejected by the host-based authentication
+// rules. Throttled, so that not every rejected connection is recorded.
+export const CONNECTION_DENIED = "connection_denied";
 
 // Node Event Types
 export const nodeEvents = [NODE_JOIN, NODE_RESTART, NODE_DECOMMISSIONED, NODE_RECOMMISSIONED];
@@ -51,7 +54,10 @@ export const tableEvents = [
   FINISH_SCHEMA_CHANGE_ROLLBACK,
 ];
 export const settingsEvents = [SET_CLUSTER_SETTING];
-export const allEvents = [...nodeEvents, ...databaseEvents, ...tableEvents, ...settingsEvents];
+export const connectionEvents = [CONNECTION_DENIED];
+export const allEvents = [
+  ...nodeEvents, ...databaseEvents, ...tableEvents, ...settingsEvents, ...connectionEvents,
+];
 
 interface EventSet {
   [key: string]: number;
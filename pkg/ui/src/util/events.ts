@@ -19,6 +19,10 @@ export function getEventDescription(e: Event$Properties): string {
     ViewName: string,
     SettingName: string,
     Value: string,
+    Database: string,
+    RemoteAddr: string,
+    Reason: string,
+    DeniedConnections: number,
   } = protobuf.util.isset(e, "info") ? JSON.parse(e.info) : {};
   const targetId: number = e.target_id ? e.target_id.toNumber() : null;
 
@@ -64,6 +68,8 @@ export function getEventDescription(e: Event$Properties): string {
       return `Node Rejoined: Node ${targetId} rejoined the cluster`;
     case eventTypes.SET_CLUSTER_SETTING:
       return `Cluster Setting Changed: User ${info.User} set ${info.SettingName} to ${info.Value}`;
+    case eventTypes.CONNECTION_DENIED:
+      return `Connection Denied: Connection of user ${info.User} to database ${info.Database} from ${info.RemoteAddr} was rejected: ${info.Reason} (${info.DeniedConnections} connections rejected since the node started)`;
     default:
       return `Unknown Event Type: ${e.event_type}, content: ${JSON.stringify(info, null, 2)}`;
   }
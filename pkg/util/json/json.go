@@ -30,18 +30,26 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/util/encoding"
 )
 
-type jsonType int
+// Type represents a JSON type.
+type Type int
 
 // This enum defines the ordering of types. It should not be reordered.
 const (
-	_ jsonType = iota
-	nullJSONType
-	stringJSONType
-	numberJSONType
-	falseJSONType
-	trueJSONType
-	arrayJSONType
-	objectJSONType
+	_ Type = iota
+	// NullJSONType is the type of JSON `null`.
+	NullJSONType
+	// StringJSONType is the type of a JSON string.
+	StringJSONType
+	// NumberJSONType is the type of a JSON number.
+	NumberJSONType
+	// FalseJSONType is the type of JSON `false`.
+	FalseJSONType
+	// TrueJSONType is the type of JSON `true`.
+	TrueJSONType
+	// ArrayJSONType is the type of a JSON array.
+	ArrayJSONType
+	// ObjectJSONType is the type of a JSON object.
+	ObjectJSONType
 )
 
 // JSON represents a JSON value.
@@ -49,7 +57,8 @@ type JSON interface {
 	fmt.Stringer
 
 	Compare(JSON) int
-	jsonType() jsonType
+	// Type returns the type of the JSON document.
+	Type() Type
 	// Format writes out the JSON document to the specified buffer.
 	Format(buf *bytes.Buffer)
 	// Size returns the size of the JSON document in bytes.
@@ -84,6 +93,23 @@ type JSON interface {
 	// Exists implements the `?` operator.
 	Exists(string) bool
 
+	// Len returns the number of outermost elements in the JSON document if it
+	// is an object or an array, and 0 otherwise.
+	Len() int
+
+	// AsArray returns the JSON document as a slice of JSON values if it is an
+	// array, and false otherwise.
+	AsArray() ([]JSON, bool)
+
+	// ObjectIter returns an iterator over the key-value pairs of the JSON
+	// document if it is an object, and nil otherwise.
+	ObjectIter() *ObjectIterator
+
+	// StripNulls implements the jsonb_strip_nulls builtin, returning the JSON
+	// document with all object fields that have null values removed,
+	// recursively.
+	StripNulls() JSON
+
 	// isScalar returns whether the JSON document is null, true, false, a string,
 	// or a number.
 	isScalar() bool
@@ -128,15 +154,15 @@ type jsonKeyValuePair struct {
 // pairs, which are unique by key.
 type jsonObject []jsonKeyValuePair
 
-func (jsonNull) jsonType() jsonType   { return nullJSONType }
-func (jsonFalse) jsonType() jsonType  { return falseJSONType }
-func (jsonTrue) jsonType() jsonType   { return trueJSONType }
-func (jsonNumber) jsonType() jsonType { return numberJSONType }
-func (jsonString) jsonType() jsonType { return stringJSONType }
-func (jsonArray) jsonType() jsonType  { return arrayJSONType }
-func (jsonObject) jsonType() jsonType { return objectJSONType }
+func (jsonNull) Type() Type   { return NullJSONType }
+func (jsonFalse) Type() Type  { return FalseJSONType }
+func (jsonTrue) Type() Type   { return TrueJSONType }
+func (jsonNumber) Type() Type { return NumberJSONType }
+func (jsonString) Type() Type { return StringJSONType }
+func (jsonArray) Type() Type  { return ArrayJSONType }
+func (jsonObject) Type() Type { return ObjectJSONType }
 
-func cmpJSONTypes(a jsonType, b jsonType) int {
+func cmpJSONTypes(a Type, b Type) int {
 	if b > a {
 		return -1
 	}
@@ -146,12 +172,12 @@ func cmpJSONTypes(a jsonType, b jsonType) int {
 	return 0
 }
 
-func (j jsonNull) Compare(other JSON) int  { return cmpJSONTypes(j.jsonType(), other.jsonType()) }
-func (j jsonFalse) Compare(other JSON) int { return cmpJSONTypes(j.jsonType(), other.jsonType()) }
-func (j jsonTrue) Compare(other JSON) int  { return cmpJSONTypes(j.jsonType(), other.jsonType()) }
+func (j jsonNull) Compare(other JSON) int  { return cmpJSONTypes(j.Type(), other.Type()) }
+func (j jsonFalse) Compare(other JSON) int { return cmpJSONTypes(j.Type(), other.Type()) }
+func (j jsonTrue) Compare(other JSON) int  { return cmpJSONTypes(j.Type(), other.Type()) }
 
 func (j jsonNumber) Compare(other JSON) int {
-	cmp := cmpJSONTypes(j.jsonType(), other.jsonType())
+	cmp := cmpJSONTypes(j.Type(), other.Type())
 	if cmp != 0 {
 		return cmp
 	}
@@ -161,7 +187,7 @@ func (j jsonNumber) Compare(other JSON) int {
 }
 
 func (j jsonString) Compare(other JSON) int {
-	cmp := cmpJSONTypes(j.jsonType(), other.jsonType())
+	cmp := cmpJSONTypes(j.Type(), other.Type())
 	if cmp != 0 {
 		return cmp
 	}
@@ -176,7 +202,7 @@ func (j jsonString) Compare(other JSON) int {
 }
 
 func (j jsonArray) Compare(other JSON) int {
-	cmp := cmpJSONTypes(j.jsonType(), other.jsonType())
+	cmp := cmpJSONTypes(j.Type(), other.Type())
 	if cmp != 0 {
 		return cmp
 	}
@@ -197,7 +223,7 @@ func (j jsonArray) Compare(other JSON) int {
 }
 
 func (j jsonObject) Compare(other JSON) int {
-	cmp := cmpJSONTypes(j.jsonType(), other.jsonType())
+	cmp := cmpJSONTypes(j.Type(), other.Type())
 	if cmp != 0 {
 		return cmp
 	}
@@ -695,3 +721,503 @@ func (jsonNumber) isScalar() bool { return true }
 func (jsonString) isScalar() bool { return true }
 func (jsonArray) isScalar() bool  { return false }
 func (jsonObject) isScalar() bool { return false }
+
+func (jsonNull) Len() int     { return 0 }
+func (jsonTrue) Len() int     { return 0 }
+func (jsonFalse) Len() int    { return 0 }
+func (jsonNumber) Len() int   { return 0 }
+func (jsonString) Len() int   { return 0 }
+func (j jsonArray) Len() int  { return len(j) }
+func (j jsonObject) Len() int { return len(j) }
+
+func (jsonNull) AsArray() ([]JSON, bool)    { return nil, false }
+func (jsonTrue) AsArray() ([]JSON, bool)    { return nil, false }
+func (jsonFalse) AsArray() ([]JSON, bool)   { return nil, false }
+func (jsonNumber) AsArray() ([]JSON, bool)  { return nil, false }
+func (jsonString) AsArray() ([]JSON, bool)  { return nil, false }
+func (j jsonArray) AsArray() ([]JSON, bool) { return j, true }
+func (jsonObject) AsArray() ([]JSON, bool)  { return nil, false }
+
+// ObjectIterator is an iterator to access the key-value pairs of an object in
+// sorted order of their keys.
+type ObjectIterator struct {
+	src jsonObject
+	idx int
+}
+
+// Next updates the cursor and returns whether the next pair exists.
+func (it *ObjectIterator) Next() bool {
+	if it.idx >= len(it.src)-1 {
+		return false
+	}
+	it.idx++
+	return true
+}
+
+// Key returns the key of the current pair.
+func (it *ObjectIterator) Key() string {
+	return string(it.src[it.idx].k)
+}
+
+// Value returns the value of the current pair.
+func (it *ObjectIterator) Value() JSON {
+	return it.src[it.idx].v
+}
+
+func (jsonNull) ObjectIter() *ObjectIterator   { return nil }
+func (jsonTrue) ObjectIter() *ObjectIterator   { return nil }
+func (jsonFalse) ObjectIter() *ObjectIterator  { return nil }
+func (jsonNumber) ObjectIter() *ObjectIterator { return nil }
+func (jsonString) ObjectIter() *ObjectIterator { return nil }
+func (jsonArray) ObjectIter() *ObjectIterator  { return nil }
+func (j jsonObject) ObjectIter() *ObjectIterator {
+	return &ObjectIterator{src: j, idx: -1}
+}
+
+func (j jsonNull) StripNulls() JSON   { return j }
+func (j jsonTrue) StripNulls() JSON   { return j }
+func (j jsonFalse) StripNulls() JSON  { return j }
+func (j jsonNumber) StripNulls() JSON { return j }
+func (j jsonString) StripNulls() JSON { return j }
+
+func (j jsonArray) StripNulls() JSON {
+	result := make(jsonArray, len(j))
+	for i := range j {
+		result[i] = j[i].StripNulls()
+	}
+	return result
+}
+
+func (j jsonObject) StripNulls() JSON {
+	result := make(jsonObject, 0, len(j))
+	for i := range j {
+		if j[i].v.Type() == NullJSONType {
+			continue
+		}
+		result = append(result, jsonKeyValuePair{k: j[i].k, v: j[i].v.StripNulls()})
+	}
+	return result
+}
+
+// FromString returns a JSON string value.
+func FromString(s string) JSON {
+	return jsonString(s)
+}
+
+// FromBool returns a JSON boolean value.
+func FromBool(b bool) JSON {
+	if b {
+		return TrueJSONValue
+	}
+	return FalseJSONValue
+}
+
+// FromInt64 returns a JSON number value.
+func FromInt64(i int64) JSON {
+	dec := apd.Decimal{}
+	dec.SetCoefficient(i)
+	return jsonNumber(dec)
+}
+
+// FromDecimal returns a JSON number value.
+func FromDecimal(dec apd.Decimal) JSON {
+	return jsonNumber(dec)
+}
+
+// FromFloat64 returns a JSON number value. It returns an error if the float
+// is not representable as a JSON number (NaN or infinity).
+func FromFloat64(f float64) (JSON, error) {
+	dec := apd.Decimal{}
+	if _, err := dec.SetFloat64(f); err != nil {
+		return nil, err
+	}
+	if dec.Form != apd.Finite {
+		return nil, pgerror.NewErrorf(pgerror.CodeInvalidParameterValueError,
+			"cannot convert %g to JSON", f)
+	}
+	return jsonNumber(dec), nil
+}
+
+// ArrayBuilder builds a JSON array by adding values one at a time.
+type ArrayBuilder struct {
+	jsons []JSON
+}
+
+// NewArrayBuilder returns an ArrayBuilder. The builder will reserve
+// numAddsHint elements in advance.
+func NewArrayBuilder(numAddsHint int) *ArrayBuilder {
+	return &ArrayBuilder{jsons: make([]JSON, 0, numAddsHint)}
+}
+
+// Add appends a JSON value to the end of the array being built.
+func (b *ArrayBuilder) Add(j JSON) {
+	b.jsons = append(b.jsons, j)
+}
+
+// Build returns the constructed JSON array. Values added to the builder
+// afterwards do not affect the returned array.
+func (b *ArrayBuilder) Build() JSON {
+	return jsonArray(b.jsons[:len(b.jsons):len(b.jsons)])
+}
+
+// ObjectBuilder builds a JSON object by adding key-value pairs one at a time.
+// If the same key is added more than once, only the last value is kept.
+type ObjectBuilder struct {
+	pairs []jsonKeyValuePair
+}
+
+// NewObjectBuilder returns an ObjectBuilder. The builder will reserve
+// numAddsHint key-value pairs in advance.
+func NewObjectBuilder(numAddsHint int) *ObjectBuilder {
+	return &ObjectBuilder{pairs: make([]jsonKeyValuePair, 0, numAddsHint)}
+}
+
+// Add appends a key-value pair to the object being built.
+func (b *ObjectBuilder) Add(k string, v JSON) {
+	b.pairs = append(b.pairs, jsonKeyValuePair{k: jsonString(k), v: v})
+}
+
+// Build returns the constructed JSON object. Pairs added to the builder
+// afterwards do not affect the returned object.
+func (b *ObjectBuilder) Build() JSON {
+	pairs := make([]jsonKeyValuePair, len(b.pairs))
+	copy(pairs, b.pairs)
+	// The sort is stable so that the last value added for a given key is the
+	// last one among the pairs sharing that key.
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].k < pairs[j].k })
+	result := pairs[:0]
+	for i := range pairs {
+		if i+1 < len(pairs) && pairs[i+1].k == pairs[i].k {
+			continue
+		}
+		result = append(result, pairs[i])
+	}
+	return jsonObject(result)
+}
+
+var errInvalidConcat = pgerror.NewError(pgerror.CodeInvalidParameterValueError, "invalid concatenation of jsonb objects")
+
+// Concat implements the `||` operator. Two objects are merged, with the keys
+// of b taking precedence. Otherwise, both operands are treated as arrays
+// (wrapping scalars and objects in a single-element array) and concatenated.
+func Concat(a, b JSON) (JSON, error) {
+	if a.Type() == ObjectJSONType && b.Type() == ObjectJSONType {
+		builder := NewObjectBuilder(a.Len() + b.Len())
+		for _, j := range []JSON{a, b} {
+			it := j.ObjectIter()
+			for it.Next() {
+				builder.Add(it.Key(), it.Value())
+			}
+		}
+		return builder.Build(), nil
+	}
+	if (a.Type() == ObjectJSONType && b.isScalar()) || (a.isScalar() && b.Type() == ObjectJSONType) {
+		return nil, errInvalidConcat
+	}
+	result := make(jsonArray, 0, a.Len()+b.Len()+2)
+	for _, j := range []JSON{a, b} {
+		if ary, ok := j.AsArray(); ok {
+			result = append(result, ary...)
+		} else {
+			result = append(result, j)
+		}
+	}
+	return result, nil
+}
+
+var errCannotSetPathInScalar = pgerror.NewError(pgerror.CodeInvalidParameterValueError, "cannot set path in scalar")
+
+func errPathElementNotInteger(pos int) error {
+	return pgerror.NewErrorf(pgerror.CodeInvalidParameterValueError,
+		"path element at position %d is not an integer", pos+1)
+}
+
+// arrayPathIndex parses path[pos] as an index into an array of length n. It
+// returns the non-negative index (which may be out of bounds) and whether the
+// index referred to a position before the start of the array.
+func arrayPathIndex(path []string, pos int, n int) (idx int, before bool, err error) {
+	idx, err = strconv.Atoi(path[pos])
+	if err != nil {
+		return 0, false, errPathElementNotInteger(pos)
+	}
+	if idx < 0 {
+		idx += n
+		if idx < 0 {
+			return 0, true, nil
+		}
+	}
+	return idx, false, nil
+}
+
+// DeepSet implements the jsonb_set builtin. It replaces the value designated
+// by path with to. If the last element of path does not exist and
+// createMissing is set, it is created; intermediate path elements are never
+// created.
+func DeepSet(j JSON, path []string, to JSON, createMissing bool) (JSON, error) {
+	if j.isScalar() {
+		return nil, errCannotSetPathInScalar
+	}
+	return deepSet(j, path, 0, to, createMissing)
+}
+
+func deepSet(j JSON, path []string, pos int, to JSON, createMissing bool) (JSON, error) {
+	if pos == len(path) {
+		return to, nil
+	}
+	last := pos == len(path)-1
+	switch v := j.(type) {
+	case jsonObject:
+		key := path[pos]
+		result := make(jsonObject, 0, len(v)+1)
+		found := false
+		for i := range v {
+			if string(v[i].k) == key {
+				found = true
+				next, err := deepSet(v[i].v, path, pos+1, to, createMissing)
+				if err != nil {
+					return nil, err
+				}
+				result = append(result, jsonKeyValuePair{k: v[i].k, v: next})
+				continue
+			}
+			result = append(result, v[i])
+		}
+		if !found {
+			if !last || !createMissing {
+				return j, nil
+			}
+			b := NewObjectBuilder(len(v) + 1)
+			for i := range v {
+				b.Add(string(v[i].k), v[i].v)
+			}
+			b.Add(key, to)
+			return b.Build(), nil
+		}
+		return result, nil
+	case jsonArray:
+		idx, before, err := arrayPathIndex(path, pos, len(v))
+		if err != nil {
+			return nil, err
+		}
+		if before || idx >= len(v) {
+			if !last || !createMissing {
+				return j, nil
+			}
+			result := make(jsonArray, 0, len(v)+1)
+			if before {
+				result = append(result, to)
+				return append(result, v...), nil
+			}
+			result = append(result, v...)
+			return append(result, to), nil
+		}
+		next, err := deepSet(v[idx], path, pos+1, to, createMissing)
+		if err != nil {
+			return nil, err
+		}
+		result := make(jsonArray, len(v))
+		copy(result, v)
+		result[idx] = next
+		return result, nil
+	}
+	// Setting a path below a scalar leaves the document unchanged.
+	return j, nil
+}
+
+var errCannotReplaceExistingKey = pgerror.NewError(pgerror.CodeInvalidParameterValueError, "cannot replace existing key")
+
+// DeepInsert implements the jsonb_insert builtin. It inserts to at the
+// position designated by path. If the target is an array element, to is
+// inserted before it, or after it if after is set. If the target is an object
+// key, the key must not already exist.
+func DeepInsert(j JSON, path []string, to JSON, after bool) (JSON, error) {
+	if j.isScalar() {
+		return nil, errCannotSetPathInScalar
+	}
+	if len(path) == 0 {
+		return j, nil
+	}
+	return deepInsert(j, path, 0, to, after)
+}
+
+func deepInsert(j JSON, path []string, pos int, to JSON, after bool) (JSON, error) {
+	last := pos == len(path)-1
+	switch v := j.(type) {
+	case jsonObject:
+		key := path[pos]
+		child := v.FetchValKey(key)
+		if last {
+			if child != nil {
+				return nil, errCannotReplaceExistingKey
+			}
+			b := NewObjectBuilder(len(v) + 1)
+			for i := range v {
+				b.Add(string(v[i].k), v[i].v)
+			}
+			b.Add(key, to)
+			return b.Build(), nil
+		}
+		if child == nil {
+			return j, nil
+		}
+		next, err := deepInsert(child, path, pos+1, to, after)
+		if err != nil {
+			return nil, err
+		}
+		result := make(jsonObject, len(v))
+		copy(result, v)
+		for i := range result {
+			if string(result[i].k) == key {
+				result[i].v = next
+			}
+		}
+		return result, nil
+	case jsonArray:
+		idx, before, err := arrayPathIndex(path, pos, len(v))
+		if err != nil {
+			return nil, err
+		}
+		if last {
+			switch {
+			case before:
+				idx = 0
+			case idx >= len(v):
+				idx = len(v)
+			case after:
+				idx++
+			}
+			result := make(jsonArray, 0, len(v)+1)
+			result = append(result, v[:idx]...)
+			result = append(result, to)
+			return append(result, v[idx:]...), nil
+		}
+		if before || idx >= len(v) {
+			return j, nil
+		}
+		next, err := deepInsert(v[idx], path, pos+1, to, after)
+		if err != nil {
+			return nil, err
+		}
+		result := make(jsonArray, len(v))
+		copy(result, v)
+		result[idx] = next
+		return result, nil
+	}
+	return j, nil
+}
+
+var errCannotDeletePathInScalar = pgerror.NewError(pgerror.CodeInvalidParameterValueError, "cannot delete path in scalar")
+
+// RemovePath implements the `#-` operator, removing the value designated by
+// path. If the path does not exist, the document is returned unchanged.
+func RemovePath(j JSON, path []string) (JSON, error) {
+	if j.isScalar() {
+		return nil, errCannotDeletePathInScalar
+	}
+	if len(path) == 0 {
+		return j, nil
+	}
+	return removePath(j, path, 0)
+}
+
+func removePath(j JSON, path []string, pos int) (JSON, error) {
+	last := pos == len(path)-1
+	switch v := j.(type) {
+	case jsonObject:
+		child := v.FetchValKey(path[pos])
+		if child == nil {
+			return j, nil
+		}
+		if last {
+			return v.RemoveKey(path[pos])
+		}
+		next, err := removePath(child, path, pos+1)
+		if err != nil {
+			return nil, err
+		}
+		result := make(jsonObject, len(v))
+		copy(result, v)
+		for i := range result {
+			if string(result[i].k) == path[pos] {
+				result[i].v = next
+			}
+		}
+		return result, nil
+	case jsonArray:
+		idx, before, err := arrayPathIndex(path, pos, len(v))
+		if err != nil {
+			return nil, err
+		}
+		if before || idx >= len(v) {
+			return j, nil
+		}
+		if last {
+			return v.RemoveIndex(idx)
+		}
+		next, err := removePath(v[idx], path, pos+1)
+		if err != nil {
+			return nil, err
+		}
+		result := make(jsonArray, len(v))
+		copy(result, v)
+		result[idx] = next
+		return result, nil
+	}
+	return j, nil
+}
+
+const prettyIndent = "    "
+
+// Pretty implements the jsonb_pretty builtin, returning the JSON document
+// formatted over multiple lines with four spaces of indentation per level.
+func Pretty(j JSON) string {
+	var buf bytes.Buffer
+	prettyFormat(&buf, j, 0)
+	return buf.String()
+}
+
+func prettyNewline(buf *bytes.Buffer, depth int) {
+	buf.WriteByte('\n')
+	for i := 0; i < depth; i++ {
+		buf.WriteString(prettyIndent)
+	}
+}
+
+func prettyFormat(buf *bytes.Buffer, j JSON, depth int) {
+	switch v := j.(type) {
+	case jsonArray:
+		if len(v) == 0 {
+			buf.WriteString("[]")
+			return
+		}
+		buf.WriteByte('[')
+		for i := range v {
+			if i != 0 {
+				buf.WriteByte(',')
+			}
+			prettyNewline(buf, depth+1)
+			prettyFormat(buf, v[i], depth+1)
+		}
+		prettyNewline(buf, depth)
+		buf.WriteByte(']')
+	case jsonObject:
+		if len(v) == 0 {
+			buf.WriteString("{}")
+			return
+		}
+		buf.WriteByte('{')
+		for i := range v {
+			if i != 0 {
+				buf.WriteByte(',')
+			}
+			prettyNewline(buf, depth+1)
+			encodeJSONString(buf, string(v[i].k))
+			buf.WriteString(": ")
+			prettyFormat(buf, v[i].v, depth+1)
+		}
+		prettyNewline(buf, depth)
+		buf.WriteByte('}')
+	default:
+		j.Format(buf)
+	}
+}
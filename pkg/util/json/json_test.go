@@ -578,6 +578,272 @@ func TestJSONRemoveIndex(t *testing.T) {
 	}
 }
 
+func TestJSONConcat(t *testing.T) {
+	json := jsonTestShorthand
+	cases := []struct {
+		left, right string
+		expected    JSON
+		errMsg      string
+	}{
+		{left: `{}`, right: `{}`, expected: json(`{}`)},
+		{left: `{"a": 1}`, right: `{"b": 2}`, expected: json(`{"a": 1, "b": 2}`)},
+		{left: `{"a": 1, "b": 2}`, right: `{"b": 3}`, expected: json(`{"a": 1, "b": 3}`)},
+		{left: `[1, 2]`, right: `[3]`, expected: json(`[1, 2, 3]`)},
+		{left: `[1, 2]`, right: `3`, expected: json(`[1, 2, 3]`)},
+		{left: `1`, right: `[2, 3]`, expected: json(`[1, 2, 3]`)},
+		{left: `1`, right: `2`, expected: json(`[1, 2]`)},
+		{left: `[1]`, right: `{"a": 1}`, expected: json(`[1, {"a": 1}]`)},
+		{left: `{"a": 1}`, right: `[1]`, expected: json(`[{"a": 1}, 1]`)},
+		{left: `{"a": 1}`, right: `1`, errMsg: "invalid concatenation"},
+		{left: `null`, right: `{"a": 1}`, errMsg: "invalid concatenation"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.left+`||`+tc.right, func(t *testing.T) {
+			result, err := Concat(json(tc.left), json(tc.right))
+			if tc.errMsg != "" {
+				if err == nil {
+					t.Fatal("expected error")
+				} else if !strings.Contains(err.Error(), tc.errMsg) {
+					t.Fatalf(`expected error message "%s" to contain "%s"`, err.Error(), tc.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Compare(tc.expected) != 0 {
+				t.Fatalf("expected %s, got %s", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestJSONDeepSet(t *testing.T) {
+	json := jsonTestShorthand
+	cases := map[string][]struct {
+		path          []string
+		to            string
+		createMissing bool
+		expected      JSON
+		errMsg        string
+	}{
+		`{"a": 1}`: {
+			{path: []string{}, to: `2`, expected: json(`2`)},
+			{path: []string{`a`}, to: `2`, expected: json(`{"a": 2}`)},
+			{path: []string{`b`}, to: `2`, expected: json(`{"a": 1}`)},
+			{path: []string{`b`}, to: `2`, createMissing: true, expected: json(`{"a": 1, "b": 2}`)},
+			// Intermediate path elements are never created.
+			{path: []string{`b`, `c`}, to: `2`, createMissing: true, expected: json(`{"a": 1}`)},
+		},
+		`{"a": [1, {"b": 2}]}`: {
+			{path: []string{`a`, `1`, `b`}, to: `3`, expected: json(`{"a": [1, {"b": 3}]}`)},
+			{path: []string{`a`, `-1`, `b`}, to: `3`, expected: json(`{"a": [1, {"b": 3}]}`)},
+			{path: []string{`a`, `0`}, to: `"x"`, expected: json(`{"a": ["x", {"b": 2}]}`)},
+			{path: []string{`a`, `x`}, to: `3`, errMsg: "path element at position 2 is not an integer"},
+		},
+		`[1, 2]`: {
+			{path: []string{`5`}, to: `3`, createMissing: true, expected: json(`[1, 2, 3]`)},
+			{path: []string{`-5`}, to: `3`, createMissing: true, expected: json(`[3, 1, 2]`)},
+			{path: []string{`5`}, to: `3`, expected: json(`[1, 2]`)},
+		},
+		`1`: {
+			{path: []string{`a`}, to: `3`, errMsg: "cannot set path in scalar"},
+		},
+	}
+
+	for k, tests := range cases {
+		left, err := ParseJSON(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, tc := range tests {
+			t.Run(fmt.Sprintf("%s-%v", k, tc.path), func(t *testing.T) {
+				result, err := DeepSet(left, tc.path, json(tc.to), tc.createMissing)
+				if tc.errMsg != "" {
+					if err == nil {
+						t.Fatal("expected error")
+					} else if !strings.Contains(err.Error(), tc.errMsg) {
+						t.Fatalf(`expected error message "%s" to contain "%s"`, err.Error(), tc.errMsg)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if result.Compare(tc.expected) != 0 {
+					t.Fatalf("expected %s, got %s", tc.expected, result)
+				}
+			})
+		}
+	}
+}
+
+func TestJSONDeepInsert(t *testing.T) {
+	json := jsonTestShorthand
+	cases := map[string][]struct {
+		path     []string
+		to       string
+		after    bool
+		expected JSON
+		errMsg   string
+	}{
+		`{"a": [0, 1, 2]}`: {
+			{path: []string{`a`, `1`}, to: `"x"`, expected: json(`{"a": [0, "x", 1, 2]}`)},
+			{path: []string{`a`, `1`}, to: `"x"`, after: true, expected: json(`{"a": [0, 1, "x", 2]}`)},
+			{path: []string{`a`, `10`}, to: `"x"`, expected: json(`{"a": [0, 1, 2, "x"]}`)},
+			{path: []string{`a`, `-10`}, to: `"x"`, expected: json(`{"a": ["x", 0, 1, 2]}`)},
+			{path: []string{`b`}, to: `"x"`, expected: json(`{"a": [0, 1, 2], "b": "x"}`)},
+			{path: []string{`a`}, to: `"x"`, errMsg: "cannot replace existing key"},
+			{path: []string{`b`, `c`}, to: `"x"`, expected: json(`{"a": [0, 1, 2]}`)},
+		},
+		`"a"`: {
+			{path: []string{`0`}, to: `1`, errMsg: "cannot set path in scalar"},
+		},
+	}
+
+	for k, tests := range cases {
+		left, err := ParseJSON(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, tc := range tests {
+			t.Run(fmt.Sprintf("%s-%v-%t", k, tc.path, tc.after), func(t *testing.T) {
+				result, err := DeepInsert(left, tc.path, json(tc.to), tc.after)
+				if tc.errMsg != "" {
+					if err == nil {
+						t.Fatal("expected error")
+					} else if !strings.Contains(err.Error(), tc.errMsg) {
+						t.Fatalf(`expected error message "%s" to contain "%s"`, err.Error(), tc.errMsg)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if result.Compare(tc.expected) != 0 {
+					t.Fatalf("expected %s, got %s", tc.expected, result)
+				}
+			})
+		}
+	}
+}
+
+func TestJSONRemovePath(t *testing.T) {
+	json := jsonTestShorthand
+	cases := map[string][]struct {
+		path     []string
+		expected JSON
+		errMsg   string
+	}{
+		`{"a": {"b": [1, 2]}, "c": 3}`: {
+			{path: []string{}, expected: json(`{"a": {"b": [1, 2]}, "c": 3}`)},
+			{path: []string{`c`}, expected: json(`{"a": {"b": [1, 2]}}`)},
+			{path: []string{`a`, `b`}, expected: json(`{"a": {}, "c": 3}`)},
+			{path: []string{`a`, `b`, `0`}, expected: json(`{"a": {"b": [2]}, "c": 3}`)},
+			{path: []string{`a`, `b`, `-1`}, expected: json(`{"a": {"b": [1]}, "c": 3}`)},
+			{path: []string{`a`, `b`, `5`}, expected: json(`{"a": {"b": [1, 2]}, "c": 3}`)},
+			{path: []string{`x`, `y`}, expected: json(`{"a": {"b": [1, 2]}, "c": 3}`)},
+		},
+		`true`: {
+			{path: []string{`a`}, errMsg: "cannot delete path in scalar"},
+		},
+	}
+
+	for k, tests := range cases {
+		left, err := ParseJSON(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, tc := range tests {
+			t.Run(fmt.Sprintf("%s-%v", k, tc.path), func(t *testing.T) {
+				result, err := RemovePath(left, tc.path)
+				if tc.errMsg != "" {
+					if err == nil {
+						t.Fatal("expected error")
+					} else if !strings.Contains(err.Error(), tc.errMsg) {
+						t.Fatalf(`expected error message "%s" to contain "%s"`, err.Error(), tc.errMsg)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if result.Compare(tc.expected) != 0 {
+					t.Fatalf("expected %s, got %s", tc.expected, result)
+				}
+			})
+		}
+	}
+}
+
+func TestJSONStripNulls(t *testing.T) {
+	json := jsonTestShorthand
+	cases := map[string]JSON{
+		`null`:                                  json(`null`),
+		`[1, null, {"a": null}]`:                json(`[1, null, {}]`),
+		`{"a": null, "b": {"c": null, "d": 1}}`: json(`{"b": {"d": 1}}`),
+	}
+
+	for k, expected := range cases {
+		t.Run(k, func(t *testing.T) {
+			result := json(k).StripNulls()
+			if result.Compare(expected) != 0 {
+				t.Fatalf("expected %s, got %s", expected, result)
+			}
+		})
+	}
+}
+
+func TestJSONPretty(t *testing.T) {
+	cases := map[string]string{
+		`1`:  `1`,
+		`[]`: `[]`,
+		`{}`: `{}`,
+		`{"a": [1, {"b": null}], "c": "d"}`: `{
+    "a": [
+        1,
+        {
+            "b": null
+        }
+    ],
+    "c": "d"
+}`,
+	}
+
+	for k, expected := range cases {
+		t.Run(k, func(t *testing.T) {
+			if result := Pretty(jsonTestShorthand(k)); result != expected {
+				t.Fatalf("expected\n%s\ngot\n%s", expected, result)
+			}
+		})
+	}
+}
+
+func TestJSONObjectBuilder(t *testing.T) {
+	b := NewObjectBuilder(4)
+	b.Add("b", FromInt64(1))
+	b.Add("a", FromString("x"))
+	b.Add("b", FromBool(true))
+	b.Add("c", NullJSONValue)
+	result := b.Build()
+	expected := jsonTestShorthand(`{"a": "x", "b": true, "c": null}`)
+	if result.Compare(expected) != 0 {
+		t.Fatalf("expected %s, got %s", expected, result)
+	}
+
+	var keys []string
+	for it := result.ObjectIter(); it.Next(); {
+		keys = append(keys, it.Key())
+	}
+	if strings.Join(keys, ",") != "a,b,c" {
+		t.Fatalf("expected keys a,b,c, got %v", keys)
+	}
+}
+
 func getApdEncoding(num float64) *apd.Decimal {
 	dec := &apd.Decimal{}
 	dec, _ = dec.SetFloat64(num)
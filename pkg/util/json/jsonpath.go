@@ -0,0 +1,1047 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package json
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/cockroachdb/apd"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+)
+
+// Path is a parsed SQL/JSON path expression, as accepted by the
+// jsonb_path_* builtins and the `@?` and `@@` operators. The syntax follows
+// the SQL:2016 standard as implemented by PostgreSQL. A path consists of an
+// optional `lax` (the default) or `strict` mode followed by an expression
+// built from the context item `$`, named variables (`$name`), the current
+// filter item `@`, literals, the accessors `.key`, `.*`, `[subscripts]`,
+// `[*]` and `? (predicate)`, the item methods `.type()`, `.size()`,
+// `.double()`, `.abs()`, `.floor()` and `.ceiling()`, the arithmetic
+// operators `+ - * / %` and the predicates `== != <> < <= > >=`, `&& || !`,
+// `exists (...)`, `like_regex`, `starts with` and `is unknown`.
+//
+// In lax mode structural errors, such as accessing a missing key, are
+// suppressed and arrays are automatically unwrapped. In strict mode they
+// cause an error.
+type Path struct {
+	strict bool
+	expr   pathNode
+}
+
+// Format writes out the canonical representation of the path to the
+// specified buffer.
+func (p *Path) Format(buf *bytes.Buffer) {
+	if p.strict {
+		buf.WriteString("strict ")
+	}
+	formatPathNode(buf, p.expr, 0)
+}
+
+func (p *Path) String() string {
+	var buf bytes.Buffer
+	p.Format(&buf)
+	return buf.String()
+}
+
+// pathNode is a node in the AST of a path expression.
+type pathNode interface {
+	format(buf *bytes.Buffer)
+}
+
+// pathOp is an operator in a path expression.
+type pathOp int
+
+const (
+	pathOpAdd pathOp = iota
+	pathOpSub
+	pathOpMul
+	pathOpDiv
+	pathOpMod
+	pathOpPlus
+	pathOpMinus
+	pathOpEq
+	pathOpNe
+	pathOpLt
+	pathOpLe
+	pathOpGt
+	pathOpGe
+	pathOpAnd
+	pathOpOr
+	pathOpNot
+)
+
+var pathOpName = [...]string{
+	pathOpAdd:   "+",
+	pathOpSub:   "-",
+	pathOpMul:   "*",
+	pathOpDiv:   "/",
+	pathOpMod:   "%",
+	pathOpPlus:  "+",
+	pathOpMinus: "-",
+	pathOpEq:    "==",
+	pathOpNe:    "!=",
+	pathOpLt:    "<",
+	pathOpLe:    "<=",
+	pathOpGt:    ">",
+	pathOpGe:    ">=",
+	pathOpAnd:   "&&",
+	pathOpOr:    "||",
+	pathOpNot:   "!",
+}
+
+func (op pathOp) String() string {
+	return pathOpName[op]
+}
+
+func (op pathOp) isComparison() bool {
+	return op >= pathOpEq && op <= pathOpGe
+}
+
+// The precedence levels of path nodes, used to decide where parentheses are
+// needed when formatting. Higher binds tighter.
+const (
+	pathPrecOr = iota + 1
+	pathPrecAnd
+	pathPrecNot
+	pathPrecComparison
+	pathPrecAdditive
+	pathPrecMultiplicative
+	pathPrecUnary
+	pathPrecAccessor
+)
+
+type (
+	// pathRoot is the context item `$`.
+	pathRoot struct{}
+	// pathCurrent is the current filter item `@`.
+	pathCurrent struct{}
+	// pathLast is the last index of the innermost array being subscripted.
+	pathLast struct{}
+	// pathVariable is a named variable `$name`, looked up in the vars object.
+	pathVariable struct{ name string }
+	// pathLiteral is a JSON scalar literal.
+	pathLiteral struct{ val JSON }
+
+	// pathMember is the member accessor `.key`.
+	pathMember struct {
+		input pathNode
+		key   string
+	}
+	// pathAnyMember is the wildcard member accessor `.*`.
+	pathAnyMember struct{ input pathNode }
+	// pathArray is the array accessor `[subscripts]`, or `[*]` when
+	// subscripts is nil.
+	pathArray struct {
+		input      pathNode
+		subscripts []pathSubscript
+	}
+	// pathFilter is the filter expression `? (pred)`.
+	pathFilter struct {
+		input pathNode
+		pred  pathNode
+	}
+	// pathMethod is an item method such as `.size()`.
+	pathMethod struct {
+		input pathNode
+		name  string
+	}
+
+	// pathBinary is an arithmetic, comparison or logical binary operator.
+	pathBinary struct {
+		op          pathOp
+		left, right pathNode
+	}
+	// pathUnary is the unary `+`, `-` or `!` operator.
+	pathUnary struct {
+		op      pathOp
+		operand pathNode
+	}
+	// pathExists is the `exists (expr)` predicate.
+	pathExists struct{ expr pathNode }
+	// pathLikeRegex is the `expr like_regex "pattern" [flag "flags"]`
+	// predicate.
+	pathLikeRegex struct {
+		input   pathNode
+		pattern string
+		flags   string
+		re      *regexp.Regexp
+	}
+	// pathStartsWith is the `expr starts with prefix` predicate.
+	pathStartsWith struct {
+		input, prefix pathNode
+	}
+	// pathIsUnknown is the `(pred) is unknown` predicate.
+	pathIsUnknown struct{ pred pathNode }
+)
+
+// pathSubscript is a single array subscript, either an index or, when to is
+// not nil, an inclusive range of indexes.
+type pathSubscript struct {
+	from, to pathNode
+}
+
+func isPathPredicate(n pathNode) bool {
+	switch t := n.(type) {
+	case *pathBinary:
+		return t.op.isComparison() || t.op == pathOpAnd || t.op == pathOpOr
+	case *pathUnary:
+		return t.op == pathOpNot
+	case *pathExists, *pathLikeRegex, *pathStartsWith, *pathIsUnknown:
+		return true
+	}
+	return false
+}
+
+func pathPrecedence(n pathNode) int {
+	switch t := n.(type) {
+	case *pathBinary:
+		switch t.op {
+		case pathOpOr:
+			return pathPrecOr
+		case pathOpAnd:
+			return pathPrecAnd
+		case pathOpAdd, pathOpSub:
+			return pathPrecAdditive
+		case pathOpMul, pathOpDiv, pathOpMod:
+			return pathPrecMultiplicative
+		}
+		return pathPrecComparison
+	case *pathUnary:
+		if t.op == pathOpNot {
+			return pathPrecNot
+		}
+		return pathPrecUnary
+	case *pathLikeRegex, *pathStartsWith:
+		return pathPrecComparison
+	}
+	return pathPrecAccessor
+}
+
+// formatPathNode formats n, surrounding it with parentheses if its
+// precedence is lower than minPrec.
+func formatPathNode(buf *bytes.Buffer, n pathNode, minPrec int) {
+	if pathPrecedence(n) < minPrec {
+		buf.WriteByte('(')
+		n.format(buf)
+		buf.WriteByte(')')
+		return
+	}
+	n.format(buf)
+}
+
+func (pathRoot) format(buf *bytes.Buffer)    { buf.WriteByte('$') }
+func (pathCurrent) format(buf *bytes.Buffer) { buf.WriteByte('@') }
+func (pathLast) format(buf *bytes.Buffer)    { buf.WriteString("last") }
+
+func (n *pathVariable) format(buf *bytes.Buffer) {
+	buf.WriteByte('$')
+	encodeJSONString(buf, n.name)
+}
+
+func (n *pathLiteral) format(buf *bytes.Buffer) { n.val.Format(buf) }
+
+func (n *pathMember) format(buf *bytes.Buffer) {
+	formatPathNode(buf, n.input, pathPrecAccessor)
+	buf.WriteByte('.')
+	encodeJSONString(buf, n.key)
+}
+
+func (n *pathAnyMember) format(buf *bytes.Buffer) {
+	formatPathNode(buf, n.input, pathPrecAccessor)
+	buf.WriteString(".*")
+}
+
+func (n *pathArray) format(buf *bytes.Buffer) {
+	formatPathNode(buf, n.input, pathPrecAccessor)
+	if n.subscripts == nil {
+		buf.WriteString("[*]")
+		return
+	}
+	buf.WriteByte('[')
+	for i, s := range n.subscripts {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		s.from.format(buf)
+		if s.to != nil {
+			buf.WriteString(" to ")
+			s.to.format(buf)
+		}
+	}
+	buf.WriteByte(']')
+}
+
+func (n *pathFilter) format(buf *bytes.Buffer) {
+	formatPathNode(buf, n.input, pathPrecAccessor)
+	buf.WriteString("?(")
+	n.pred.format(buf)
+	buf.WriteByte(')')
+}
+
+func (n *pathMethod) format(buf *bytes.Buffer) {
+	formatPathNode(buf, n.input, pathPrecAccessor)
+	buf.WriteByte('.')
+	buf.WriteString(n.name)
+	buf.WriteString("()")
+}
+
+func (n *pathBinary) format(buf *bytes.Buffer) {
+	prec := pathPrecedence(n)
+	formatPathNode(buf, n.left, prec)
+	buf.WriteByte(' ')
+	buf.WriteString(n.op.String())
+	buf.WriteByte(' ')
+	// All binary operators are left-associative, so the right operand needs
+	// parentheses at the same precedence level.
+	formatPathNode(buf, n.right, prec+1)
+}
+
+func (n *pathUnary) format(buf *bytes.Buffer) {
+	buf.WriteString(n.op.String())
+	if n.op == pathOpNot {
+		buf.WriteByte('(')
+		n.operand.format(buf)
+		buf.WriteByte(')')
+		return
+	}
+	formatPathNode(buf, n.operand, pathPrecUnary)
+}
+
+func (n *pathExists) format(buf *bytes.Buffer) {
+	buf.WriteString("exists (")
+	n.expr.format(buf)
+	buf.WriteByte(')')
+}
+
+func (n *pathLikeRegex) format(buf *bytes.Buffer) {
+	formatPathNode(buf, n.input, pathPrecAdditive)
+	buf.WriteString(" like_regex ")
+	encodeJSONString(buf, n.pattern)
+	if n.flags != "" {
+		buf.WriteString(" flag ")
+		encodeJSONString(buf, n.flags)
+	}
+}
+
+func (n *pathStartsWith) format(buf *bytes.Buffer) {
+	formatPathNode(buf, n.input, pathPrecAdditive)
+	buf.WriteString(" starts with ")
+	formatPathNode(buf, n.prefix, pathPrecAdditive)
+}
+
+func (n *pathIsUnknown) format(buf *bytes.Buffer) {
+	buf.WriteByte('(')
+	n.pred.format(buf)
+	buf.WriteString(") is unknown")
+}
+
+// pathMethods is the set of supported item methods.
+var pathMethods = map[string]struct{}{
+	"type":    {},
+	"size":    {},
+	"double":  {},
+	"abs":     {},
+	"floor":   {},
+	"ceiling": {},
+}
+
+type pathTokenKind int
+
+const (
+	pathTokEOF pathTokenKind = iota
+	// pathTokIdent is a bare word: a keyword, key name or method name.
+	pathTokIdent
+	pathTokString
+	pathTokNumber
+	pathTokVariable
+	// pathTokOp is punctuation or an operator.
+	pathTokOp
+)
+
+type pathToken struct {
+	kind pathTokenKind
+	s    string
+	pos  int
+}
+
+func pathSyntaxError(pos int, format string, args ...interface{}) error {
+	return pgerror.NewErrorf(pgerror.CodeSyntaxError,
+		"syntax error in jsonpath at position %d: %s", pos, fmt.Sprintf(format, args...))
+}
+
+var pathTwoCharOps = []string{"==", "!=", "<>", "<=", ">=", "&&", "||"}
+
+const pathOneCharOps = "<>!+-*/%()[].,?@$"
+
+func isPathIdentStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+func isPathIdentChar(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// lexPath splits a path expression into tokens.
+func lexPath(s string) ([]pathToken, error) {
+	var toks []pathToken
+	i := 0
+	for i < len(s) {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		start := i
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+
+		case r == '"':
+			str, n, err := lexPathString(s[i:], i)
+			if err != nil {
+				return nil, err
+			}
+			toks = append(toks, pathToken{kind: pathTokString, s: str, pos: start})
+			i += n
+
+		case r >= '0' && r <= '9':
+			i++
+			for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+				i++
+			}
+			if i+1 < len(s) && s[i] == '.' && s[i+1] >= '0' && s[i+1] <= '9' {
+				i++
+				for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+					i++
+				}
+			}
+			if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+				j := i + 1
+				if j < len(s) && (s[j] == '+' || s[j] == '-') {
+					j++
+				}
+				if j < len(s) && s[j] >= '0' && s[j] <= '9' {
+					for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+						j++
+					}
+					i = j
+				}
+			}
+			if i < len(s) {
+				if next, _ := utf8.DecodeRuneInString(s[i:]); isPathIdentStart(next) {
+					return nil, pathSyntaxError(start, "trailing junk after numeric literal")
+				}
+			}
+			toks = append(toks, pathToken{kind: pathTokNumber, s: s[start:i], pos: start})
+
+		case isPathIdentStart(r):
+			i += size
+			for i < len(s) {
+				r, size = utf8.DecodeRuneInString(s[i:])
+				if !isPathIdentChar(r) {
+					break
+				}
+				i += size
+			}
+			toks = append(toks, pathToken{kind: pathTokIdent, s: s[start:i], pos: start})
+
+		case r == '$' && i+1 < len(s) && s[i+1] == '"':
+			str, n, err := lexPathString(s[i+1:], i+1)
+			if err != nil {
+				return nil, err
+			}
+			toks = append(toks, pathToken{kind: pathTokVariable, s: str, pos: start})
+			i += 1 + n
+
+		case r == '$' && i+1 < len(s):
+			if next, _ := utf8.DecodeRuneInString(s[i+1:]); isPathIdentStart(next) {
+				i++
+				for i < len(s) {
+					r, size = utf8.DecodeRuneInString(s[i:])
+					if !isPathIdentChar(r) {
+						break
+					}
+					i += size
+				}
+				toks = append(toks, pathToken{kind: pathTokVariable, s: s[start+1 : i], pos: start})
+				continue
+			}
+			toks = append(toks, pathToken{kind: pathTokOp, s: "$", pos: start})
+			i++
+
+		default:
+			matched := false
+			for _, op := range pathTwoCharOps {
+				if strings.HasPrefix(s[i:], op) {
+					if op == "<>" {
+						op = "!="
+					}
+					toks = append(toks, pathToken{kind: pathTokOp, s: op, pos: start})
+					i += 2
+					matched = true
+					break
+				}
+			}
+			if matched {
+				continue
+			}
+			if strings.IndexRune(pathOneCharOps, r) < 0 {
+				return nil, pathSyntaxError(start, "unexpected character %q", r)
+			}
+			toks = append(toks, pathToken{kind: pathTokOp, s: string(r), pos: start})
+			i += size
+		}
+	}
+	toks = append(toks, pathToken{kind: pathTokEOF, pos: len(s)})
+	return toks, nil
+}
+
+// lexPathString decodes the double-quoted string at the start of s, which
+// begins at position pos of the full input. It returns the decoded string
+// and the number of bytes consumed.
+func lexPathString(s string, pos int) (string, int, error) {
+	var buf bytes.Buffer
+	i := 1
+	for i < len(s) {
+		c := s[i]
+		switch c {
+		case '"':
+			return buf.String(), i + 1, nil
+		case '\\':
+			if i+1 >= len(s) {
+				return "", 0, pathSyntaxError(pos, "unterminated quoted string")
+			}
+			switch e := s[i+1]; e {
+			case '"', '\\', '/':
+				buf.WriteByte(e)
+			case 'b':
+				buf.WriteByte('\b')
+			case 'f':
+				buf.WriteByte('\f')
+			case 'n':
+				buf.WriteByte('\n')
+			case 'r':
+				buf.WriteByte('\r')
+			case 't':
+				buf.WriteByte('\t')
+			case 'v':
+				buf.WriteByte('\v')
+			case 'u':
+				if i+6 > len(s) {
+					return "", 0, pathSyntaxError(pos+i, "invalid unicode escape sequence")
+				}
+				v, err := strconv.ParseUint(s[i+2:i+6], 16, 16)
+				if err != nil {
+					return "", 0, pathSyntaxError(pos+i, "invalid unicode escape sequence")
+				}
+				buf.WriteRune(rune(v))
+				i += 4
+			default:
+				return "", 0, pathSyntaxError(pos+i, "unsupported escape sequence \\%c", e)
+			}
+			i += 2
+		default:
+			buf.WriteByte(c)
+			i++
+		}
+	}
+	return "", 0, pathSyntaxError(pos, "unterminated quoted string")
+}
+
+// pathParser is a recursive-descent parser for path expressions.
+type pathParser struct {
+	toks []pathToken
+	pos  int
+
+	// filterDepth and subscriptDepth track whether `@` and `last` are
+	// allowed at the current position.
+	filterDepth    int
+	subscriptDepth int
+}
+
+// ParsePath parses a SQL/JSON path expression.
+func ParsePath(s string) (*Path, error) {
+	toks, err := lexPath(s)
+	if err != nil {
+		return nil, err
+	}
+	p := pathParser{toks: toks}
+	path := &Path{}
+	// The mode keywords are only treated as such when followed by the start
+	// of an expression.
+	if t := p.peek(); t.kind == pathTokIdent && (t.s == "lax" || t.s == "strict") &&
+		p.toks[p.pos+1].kind != pathTokEOF {
+		path.strict = t.s == "strict"
+		p.next()
+	}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != pathTokEOF {
+		return nil, p.unexpected(t)
+	}
+	path.expr = expr
+	return path, nil
+}
+
+func (p *pathParser) peek() pathToken {
+	return p.toks[p.pos]
+}
+
+func (p *pathParser) next() pathToken {
+	t := p.toks[p.pos]
+	if t.kind != pathTokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *pathParser) peekOp(op string) bool {
+	t := p.peek()
+	return t.kind == pathTokOp && t.s == op
+}
+
+func (p *pathParser) peekKeyword(kw string) bool {
+	t := p.peek()
+	return t.kind == pathTokIdent && t.s == kw
+}
+
+func (p *pathParser) unexpected(t pathToken) error {
+	if t.kind == pathTokEOF {
+		return pathSyntaxError(t.pos, "unexpected end of input")
+	}
+	return pathSyntaxError(t.pos, "unexpected %q", t.s)
+}
+
+func (p *pathParser) expectOp(op string) error {
+	if !p.peekOp(op) {
+		return p.unexpected(p.peek())
+	}
+	p.next()
+	return nil
+}
+
+func (p *pathParser) expectKeyword(kw string) error {
+	if !p.peekKeyword(kw) {
+		return p.unexpected(p.peek())
+	}
+	p.next()
+	return nil
+}
+
+// parsePredicate parses an expression that must be a predicate, such as
+// the operand of a logical operator or the condition of a filter.
+func (p *pathParser) parsePredicate(parse func() (pathNode, error)) (pathNode, error) {
+	pos := p.peek().pos
+	n, err := parse()
+	if err != nil {
+		return nil, err
+	}
+	if !isPathPredicate(n) {
+		return nil, pathSyntaxError(pos, "expected a boolean predicate")
+	}
+	return n, nil
+}
+
+func (p *pathParser) parseOr() (pathNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekOp("||") {
+		if !isPathPredicate(left) {
+			return nil, p.unexpected(p.peek())
+		}
+		p.next()
+		right, err := p.parsePredicate(p.parseAnd)
+		if err != nil {
+			return nil, err
+		}
+		left = &pathBinary{op: pathOpOr, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *pathParser) parseAnd() (pathNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekOp("&&") {
+		if !isPathPredicate(left) {
+			return nil, p.unexpected(p.peek())
+		}
+		p.next()
+		right, err := p.parsePredicate(p.parseNot)
+		if err != nil {
+			return nil, err
+		}
+		left = &pathBinary{op: pathOpAnd, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *pathParser) parseNot() (pathNode, error) {
+	if p.peekOp("!") {
+		p.next()
+		operand, err := p.parsePredicate(p.parseNot)
+		if err != nil {
+			return nil, err
+		}
+		return &pathUnary{op: pathOpNot, operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+var pathComparisonOps = map[string]pathOp{
+	"==": pathOpEq,
+	"!=": pathOpNe,
+	"<":  pathOpLt,
+	"<=": pathOpLe,
+	">":  pathOpGt,
+	">=": pathOpGe,
+}
+
+func (p *pathParser) parseComparison() (pathNode, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	switch {
+	case t.kind == pathTokOp:
+		op, ok := pathComparisonOps[t.s]
+		if !ok {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		return &pathBinary{op: op, left: left, right: right}, nil
+
+	case t.kind == pathTokIdent && t.s == "like_regex":
+		p.next()
+		pat := p.next()
+		if pat.kind != pathTokString {
+			return nil, p.unexpected(pat)
+		}
+		n := &pathLikeRegex{input: left, pattern: pat.s}
+		if p.peekKeyword("flag") {
+			p.next()
+			flags := p.next()
+			if flags.kind != pathTokString {
+				return nil, p.unexpected(flags)
+			}
+			n.flags = flags.s
+		}
+		if n.re, err = compilePathRegex(n.pattern, n.flags, pat.pos); err != nil {
+			return nil, err
+		}
+		return n, nil
+
+	case t.kind == pathTokIdent && t.s == "starts":
+		p.next()
+		if err := p.expectKeyword("with"); err != nil {
+			return nil, err
+		}
+		prefix, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		return &pathStartsWith{input: left, prefix: prefix}, nil
+
+	case t.kind == pathTokIdent && t.s == "is":
+		if !isPathPredicate(left) {
+			return nil, p.unexpected(t)
+		}
+		p.next()
+		if err := p.expectKeyword("unknown"); err != nil {
+			return nil, err
+		}
+		return &pathIsUnknown{pred: left}, nil
+	}
+	return left, nil
+}
+
+// compilePathRegex compiles a like_regex pattern with the given flags,
+// which are a subset of the XQuery flags: i (case-insensitive), s (dot
+// matches newline), m (multi-line), x (ignore whitespace in the pattern) and
+// q (quote the whole pattern).
+func compilePathRegex(pattern, flags string, pos int) (*regexp.Regexp, error) {
+	var goFlags string
+	for _, f := range flags {
+		switch f {
+		case 'i', 's', 'm':
+			if !strings.ContainsRune(goFlags, f) {
+				goFlags += string(f)
+			}
+		case 'x':
+			pattern = strings.Map(func(r rune) rune {
+				if unicode.IsSpace(r) {
+					return -1
+				}
+				return r
+			}, pattern)
+		case 'q':
+			pattern = regexp.QuoteMeta(pattern)
+		default:
+			return nil, pathSyntaxError(pos, "unrecognized flag character %q in like_regex predicate", f)
+		}
+	}
+	if goFlags != "" {
+		pattern = "(?" + goFlags + ")" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, pgerror.NewErrorf(pgerror.CodeInvalidRegularExpressionError,
+			"invalid regular expression: %v", err)
+	}
+	return re, nil
+}
+
+func (p *pathParser) parseAdditive() (pathNode, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op pathOp
+		switch {
+		case p.peekOp("+"):
+			op = pathOpAdd
+		case p.peekOp("-"):
+			op = pathOpSub
+		default:
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &pathBinary{op: op, left: left, right: right}
+	}
+}
+
+func (p *pathParser) parseMultiplicative() (pathNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op pathOp
+		switch {
+		case p.peekOp("*"):
+			op = pathOpMul
+		case p.peekOp("/"):
+			op = pathOpDiv
+		case p.peekOp("%"):
+			op = pathOpMod
+		default:
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &pathBinary{op: op, left: left, right: right}
+	}
+}
+
+func (p *pathParser) parseUnary() (pathNode, error) {
+	var op pathOp
+	switch {
+	case p.peekOp("+"):
+		op = pathOpPlus
+	case p.peekOp("-"):
+		op = pathOpMinus
+	default:
+		return p.parseAccessorExpr()
+	}
+	p.next()
+	operand, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return &pathUnary{op: op, operand: operand}, nil
+}
+
+func (p *pathParser) parseAccessorExpr() (pathNode, error) {
+	n, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.peekOp("."):
+			p.next()
+			t := p.next()
+			switch {
+			case t.kind == pathTokOp && t.s == "*":
+				n = &pathAnyMember{input: n}
+			case t.kind == pathTokString:
+				n = &pathMember{input: n, key: t.s}
+			case t.kind == pathTokIdent && p.peekOp("("):
+				if _, ok := pathMethods[t.s]; !ok {
+					return nil, pathSyntaxError(t.pos, "unknown item method %q", t.s)
+				}
+				p.next()
+				if err := p.expectOp(")"); err != nil {
+					return nil, err
+				}
+				n = &pathMethod{input: n, name: t.s}
+			case t.kind == pathTokIdent:
+				n = &pathMember{input: n, key: t.s}
+			default:
+				return nil, p.unexpected(t)
+			}
+
+		case p.peekOp("["):
+			p.next()
+			if p.peekOp("*") {
+				p.next()
+				if err := p.expectOp("]"); err != nil {
+					return nil, err
+				}
+				n = &pathArray{input: n}
+				continue
+			}
+			subscripts, err := p.parseSubscripts()
+			if err != nil {
+				return nil, err
+			}
+			n = &pathArray{input: n, subscripts: subscripts}
+
+		case p.peekOp("?"):
+			p.next()
+			if err := p.expectOp("("); err != nil {
+				return nil, err
+			}
+			p.filterDepth++
+			pred, err := p.parsePredicate(p.parseOr)
+			p.filterDepth--
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectOp(")"); err != nil {
+				return nil, err
+			}
+			n = &pathFilter{input: n, pred: pred}
+
+		default:
+			return n, nil
+		}
+	}
+}
+
+func (p *pathParser) parseSubscripts() ([]pathSubscript, error) {
+	p.subscriptDepth++
+	defer func() { p.subscriptDepth-- }()
+	var subscripts []pathSubscript
+	for {
+		from, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		s := pathSubscript{from: from}
+		if p.peekKeyword("to") {
+			p.next()
+			if s.to, err = p.parseAdditive(); err != nil {
+				return nil, err
+			}
+		}
+		subscripts = append(subscripts, s)
+		if p.peekOp(",") {
+			p.next()
+			continue
+		}
+		if err := p.expectOp("]"); err != nil {
+			return nil, err
+		}
+		return subscripts, nil
+	}
+}
+
+func (p *pathParser) parsePrimary() (pathNode, error) {
+	t := p.next()
+	switch t.kind {
+	case pathTokOp:
+		switch t.s {
+		case "$":
+			return pathRoot{}, nil
+		case "@":
+			if p.filterDepth == 0 {
+				return nil, pathSyntaxError(t.pos, "@ is not allowed in root expressions")
+			}
+			return pathCurrent{}, nil
+		case "(":
+			n, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectOp(")"); err != nil {
+				return nil, err
+			}
+			return n, nil
+		}
+
+	case pathTokVariable:
+		return &pathVariable{name: t.s}, nil
+
+	case pathTokString:
+		return &pathLiteral{val: jsonString(t.s)}, nil
+
+	case pathTokNumber:
+		d, _, err := apd.NewFromString(t.s)
+		if err != nil {
+			return nil, pathSyntaxError(t.pos, "invalid numeric literal %q", t.s)
+		}
+		return &pathLiteral{val: jsonNumber(*d)}, nil
+
+	case pathTokIdent:
+		switch t.s {
+		case "true":
+			return &pathLiteral{val: TrueJSONValue}, nil
+		case "false":
+			return &pathLiteral{val: FalseJSONValue}, nil
+		case "null":
+			return &pathLiteral{val: NullJSONValue}, nil
+		case "last":
+			if p.subscriptDepth == 0 {
+				return nil, pathSyntaxError(t.pos, "LAST is allowed only in array subscripts")
+			}
+			return pathLast{}, nil
+		case "exists":
+			if err := p.expectOp("("); err != nil {
+				return nil, err
+			}
+			expr, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectOp(")"); err != nil {
+				return nil, err
+			}
+			return &pathExists{expr: expr}, nil
+		}
+	}
+	return nil, p.unexpected(t)
+}
@@ -0,0 +1,655 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package json
+
+import (
+	"strings"
+
+	"github.com/cockroachdb/apd"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+)
+
+// pathDecimalCtx is the context used for arithmetic in path expressions. It
+// matches the default SQL decimal context.
+var pathDecimalCtx = &apd.Context{
+	Precision:   20,
+	Rounding:    apd.RoundHalfUp,
+	MaxExponent: 2000,
+	MinExponent: -2000,
+	Traps:       apd.DefaultTraps,
+}
+
+// pathExactCtx is used for the rounding item methods.
+var pathExactCtx = pathDecimalCtx.WithPrecision(0)
+
+// pathBool is the result of a predicate, which follows SQL three-valued
+// logic.
+type pathBool int
+
+const (
+	pathFalse pathBool = iota
+	pathTrue
+	pathUnknown
+)
+
+func makePathBool(b bool) pathBool {
+	if b {
+		return pathTrue
+	}
+	return pathFalse
+}
+
+func (b pathBool) toJSON() JSON {
+	switch b {
+	case pathTrue:
+		return TrueJSONValue
+	case pathFalse:
+		return FalseJSONValue
+	}
+	return NullJSONValue
+}
+
+var errSingleBooleanExpected = pgerror.NewError(pgerror.CodeInvalidParameterValueError,
+	"single boolean result is expected")
+
+// Query evaluates the path against target and returns the resulting
+// sequence of items. vars, which may be nil, is an object holding the
+// values of the named variables referenced by the path.
+func (p *Path) Query(target, vars JSON) ([]JSON, error) {
+	if vars != nil && vars.Type() != ObjectJSONType {
+		return nil, pgerror.NewError(pgerror.CodeInvalidParameterValueError,
+			`"vars" argument is not an object`)
+	}
+	e := pathEvaluator{strict: p.strict, root: target, vars: vars}
+	return e.eval(p.expr, target)
+}
+
+// Exists implements the `@?` operator, returning whether the path produces
+// any items for target.
+func (p *Path) Exists(target, vars JSON) (bool, error) {
+	res, err := p.Query(target, vars)
+	if err != nil {
+		return false, err
+	}
+	return len(res) > 0, nil
+}
+
+// Match implements the `@@` operator, returning the result of a predicate
+// check path for target. A nil result means the predicate is unknown.
+func (p *Path) Match(target, vars JSON) (*bool, error) {
+	res, err := p.Query(target, vars)
+	if err != nil {
+		return nil, err
+	}
+	if len(res) == 1 {
+		switch res[0].Type() {
+		case TrueJSONType, FalseJSONType:
+			b := res[0].Type() == TrueJSONType
+			return &b, nil
+		case NullJSONType:
+			return nil, nil
+		}
+	}
+	return nil, errSingleBooleanExpected
+}
+
+// pathEvaluator holds the state needed to evaluate a path expression.
+type pathEvaluator struct {
+	strict bool
+	root   JSON
+	vars   JSON
+	// last is the last index of the innermost array being subscripted. The
+	// parser only allows `last` inside array subscripts.
+	last int
+}
+
+func pathStructuralError(format string, args ...interface{}) error {
+	return pgerror.NewErrorf(pgerror.CodeInvalidParameterValueError, format, args...)
+}
+
+// unwrap returns the items with any arrays replaced by their elements, as
+// done automatically in lax mode.
+func (e *pathEvaluator) unwrap(items []JSON) []JSON {
+	if e.strict {
+		return items
+	}
+	var res []JSON
+	for _, j := range items {
+		if elems, ok := j.AsArray(); ok {
+			res = append(res, elems...)
+		} else {
+			res = append(res, j)
+		}
+	}
+	return res
+}
+
+// eval evaluates n with the given current filter item, returning the
+// resulting sequence of items.
+func (e *pathEvaluator) eval(n pathNode, current JSON) ([]JSON, error) {
+	switch t := n.(type) {
+	case pathRoot:
+		return []JSON{e.root}, nil
+
+	case pathCurrent:
+		return []JSON{current}, nil
+
+	case pathLast:
+		return []JSON{FromInt64(int64(e.last))}, nil
+
+	case *pathVariable:
+		var v JSON
+		if e.vars != nil {
+			v = e.vars.FetchValKey(t.name)
+		}
+		if v == nil {
+			return nil, pgerror.NewErrorf(pgerror.CodeUndefinedObjectError,
+				"could not find jsonpath variable %q", t.name)
+		}
+		return []JSON{v}, nil
+
+	case *pathLiteral:
+		return []JSON{t.val}, nil
+
+	case *pathMember:
+		items, err := e.eval(t.input, current)
+		if err != nil {
+			return nil, err
+		}
+		var res []JSON
+		for _, j := range e.unwrap(items) {
+			if j.Type() != ObjectJSONType {
+				if e.strict {
+					return nil, pathStructuralError("jsonpath member accessor can only be applied to an object")
+				}
+				continue
+			}
+			v := j.FetchValKey(t.key)
+			if v == nil {
+				if e.strict {
+					return nil, pathStructuralError("JSON object does not contain key %q", t.key)
+				}
+				continue
+			}
+			res = append(res, v)
+		}
+		return res, nil
+
+	case *pathAnyMember:
+		items, err := e.eval(t.input, current)
+		if err != nil {
+			return nil, err
+		}
+		var res []JSON
+		for _, j := range e.unwrap(items) {
+			it := j.ObjectIter()
+			if it == nil {
+				if e.strict {
+					return nil, pathStructuralError("jsonpath wildcard member accessor can only be applied to an object")
+				}
+				continue
+			}
+			for it.Next() {
+				res = append(res, it.Value())
+			}
+		}
+		return res, nil
+
+	case *pathArray:
+		items, err := e.eval(t.input, current)
+		if err != nil {
+			return nil, err
+		}
+		var res []JSON
+		for _, j := range items {
+			elems, ok := j.AsArray()
+			if !ok {
+				if e.strict {
+					return nil, pathStructuralError("jsonpath array accessor can only be applied to an array")
+				}
+				// In lax mode, a non-array is treated as a single-element array.
+				elems = []JSON{j}
+			}
+			if t.subscripts == nil {
+				res = append(res, elems...)
+				continue
+			}
+			for _, s := range t.subscripts {
+				from, to, err := e.evalSubscript(s, current, len(elems))
+				if err != nil {
+					return nil, err
+				}
+				if from < 0 {
+					from = 0
+				}
+				if to >= len(elems) {
+					to = len(elems) - 1
+				}
+				if from <= to {
+					res = append(res, elems[from:to+1]...)
+				}
+			}
+		}
+		return res, nil
+
+	case *pathFilter:
+		items, err := e.eval(t.input, current)
+		if err != nil {
+			return nil, err
+		}
+		var res []JSON
+		for _, j := range e.unwrap(items) {
+			if e.evalPredicate(t.pred, j) == pathTrue {
+				res = append(res, j)
+			}
+		}
+		return res, nil
+
+	case *pathMethod:
+		items, err := e.eval(t.input, current)
+		if err != nil {
+			return nil, err
+		}
+		return e.evalMethod(t.name, items)
+
+	case *pathBinary:
+		if isPathPredicate(t) {
+			return []JSON{e.evalPredicate(t, current).toJSON()}, nil
+		}
+		return e.evalArithmetic(t, current)
+
+	case *pathUnary:
+		if t.op == pathOpNot {
+			return []JSON{e.evalPredicate(t, current).toJSON()}, nil
+		}
+		items, err := e.eval(t.operand, current)
+		if err != nil {
+			return nil, err
+		}
+		res := make([]JSON, 0, len(items))
+		for _, j := range e.unwrap(items) {
+			if j.Type() != NumberJSONType {
+				return nil, pathStructuralError(
+					"operand of unary jsonpath operator %s is not a numeric value", t.op)
+			}
+			if t.op == pathOpMinus {
+				var d apd.Decimal
+				num := apd.Decimal(j.(jsonNumber))
+				d.Neg(&num)
+				j = jsonNumber(d)
+			}
+			res = append(res, j)
+		}
+		return res, nil
+
+	case *pathExists, *pathLikeRegex, *pathStartsWith, *pathIsUnknown:
+		return []JSON{e.evalPredicate(n, current).toJSON()}, nil
+	}
+	return nil, pgerror.NewErrorf(pgerror.CodeInternalError, "unknown jsonpath node %T", n)
+}
+
+// evalSubscript evaluates an array subscript against an array of length n,
+// returning the inclusive range of indexes it selects. In strict mode an
+// error is returned if the range is out of bounds.
+func (e *pathEvaluator) evalSubscript(
+	s pathSubscript, current JSON, n int,
+) (from int, to int, err error) {
+	prevLast := e.last
+	e.last = n - 1
+	defer func() { e.last = prevLast }()
+
+	if from, err = e.evalIndex(s.from, current); err != nil {
+		return 0, 0, err
+	}
+	to = from
+	if s.to != nil {
+		if to, err = e.evalIndex(s.to, current); err != nil {
+			return 0, 0, err
+		}
+	}
+	if e.strict && (from < 0 || from > to || to >= n) {
+		return 0, 0, pathStructuralError("jsonpath array subscript is out of bounds")
+	}
+	return from, to, nil
+}
+
+func (e *pathEvaluator) evalIndex(n pathNode, current JSON) (int, error) {
+	items, err := e.eval(n, current)
+	if err != nil {
+		return 0, err
+	}
+	if len(items) != 1 || items[0].Type() != NumberJSONType {
+		return 0, pathStructuralError("jsonpath array subscript is not a single numeric value")
+	}
+	num := apd.Decimal(items[0].(jsonNumber))
+	var d apd.Decimal
+	// Subscripts are truncated towards zero.
+	c := *pathExactCtx
+	c.Rounding = apd.RoundDown
+	if _, err := c.RoundToIntegralValue(&d, &num); err != nil {
+		return 0, err
+	}
+	i, err := d.Int64()
+	if err != nil || i != int64(int32(i)) {
+		return 0, pathStructuralError("jsonpath array subscript is out of integer range")
+	}
+	return int(i), nil
+}
+
+func (e *pathEvaluator) evalMethod(name string, items []JSON) ([]JSON, error) {
+	switch name {
+	case "type":
+		res := make([]JSON, len(items))
+		for i, j := range items {
+			res[i] = jsonString(pathTypeName(j))
+		}
+		return res, nil
+
+	case "size":
+		res := make([]JSON, len(items))
+		for i, j := range items {
+			if j.Type() != ArrayJSONType {
+				if e.strict {
+					return nil, pathStructuralError("jsonpath item method .size() can only be applied to an array")
+				}
+				res[i] = FromInt64(1)
+				continue
+			}
+			res[i] = FromInt64(int64(j.Len()))
+		}
+		return res, nil
+	}
+
+	items = e.unwrap(items)
+	res := make([]JSON, 0, len(items))
+	for _, j := range items {
+		if name == "double" && j.Type() == StringJSONType {
+			d, _, err := apd.NewFromString(string(j.(jsonString)))
+			if err != nil || d.Form != apd.Finite {
+				return nil, pathStructuralError(
+					"string argument of jsonpath item method .double() is not a valid representation of a double precision number")
+			}
+			res = append(res, jsonNumber(*d))
+			continue
+		}
+		if j.Type() != NumberJSONType {
+			if name == "double" {
+				return nil, pathStructuralError(
+					"jsonpath item method .double() can only be applied to a string or numeric value")
+			}
+			return nil, pathStructuralError(
+				"jsonpath item method .%s() can only be applied to a numeric value", name)
+		}
+		num := apd.Decimal(j.(jsonNumber))
+		var d apd.Decimal
+		var err error
+		switch name {
+		case "double":
+			d = num
+		case "abs":
+			d.Abs(&num)
+		case "floor":
+			_, err = pathExactCtx.Floor(&d, &num)
+		case "ceiling":
+			_, err = pathExactCtx.Ceil(&d, &num)
+		}
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, jsonNumber(d))
+	}
+	return res, nil
+}
+
+func pathTypeName(j JSON) string {
+	switch j.Type() {
+	case NullJSONType:
+		return "null"
+	case TrueJSONType, FalseJSONType:
+		return "boolean"
+	case NumberJSONType:
+		return "number"
+	case StringJSONType:
+		return "string"
+	case ArrayJSONType:
+		return "array"
+	}
+	return "object"
+}
+
+var errPathDivisionByZero = pgerror.NewError(pgerror.CodeDivisionByZeroError, "division by zero")
+
+func (e *pathEvaluator) evalArithmetic(n *pathBinary, current JSON) ([]JSON, error) {
+	left, err := e.evalNumericOperand(n.left, current, "left", n.op)
+	if err != nil {
+		return nil, err
+	}
+	right, err := e.evalNumericOperand(n.right, current, "right", n.op)
+	if err != nil {
+		return nil, err
+	}
+	var d apd.Decimal
+	switch n.op {
+	case pathOpAdd:
+		_, err = pathDecimalCtx.Add(&d, &left, &right)
+	case pathOpSub:
+		_, err = pathDecimalCtx.Sub(&d, &left, &right)
+	case pathOpMul:
+		_, err = pathDecimalCtx.Mul(&d, &left, &right)
+	case pathOpDiv:
+		if right.Sign() == 0 {
+			return nil, errPathDivisionByZero
+		}
+		_, err = pathDecimalCtx.Quo(&d, &left, &right)
+	case pathOpMod:
+		if right.Sign() == 0 {
+			return nil, errPathDivisionByZero
+		}
+		_, err = pathExactCtx.Rem(&d, &left, &right)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []JSON{jsonNumber(d)}, nil
+}
+
+// evalNumericOperand evaluates an operand of a binary arithmetic operator,
+// which must produce a single number.
+func (e *pathEvaluator) evalNumericOperand(
+	n pathNode, current JSON, side string, op pathOp,
+) (apd.Decimal, error) {
+	items, err := e.eval(n, current)
+	if err != nil {
+		return apd.Decimal{}, err
+	}
+	items = e.unwrap(items)
+	if len(items) != 1 || items[0].Type() != NumberJSONType {
+		return apd.Decimal{}, pathStructuralError(
+			"%s operand of jsonpath operator %s is not a single numeric value", side, op)
+	}
+	return apd.Decimal(items[0].(jsonNumber)), nil
+}
+
+// evalPredicate evaluates a predicate with the given current filter item.
+// Errors raised while evaluating the operands of a predicate make it
+// unknown rather than failing the whole path.
+func (e *pathEvaluator) evalPredicate(n pathNode, current JSON) pathBool {
+	switch t := n.(type) {
+	case *pathBinary:
+		switch t.op {
+		case pathOpAnd:
+			left := e.evalPredicate(t.left, current)
+			if left == pathFalse {
+				return pathFalse
+			}
+			right := e.evalPredicate(t.right, current)
+			if right == pathTrue {
+				return left
+			}
+			if right == pathFalse {
+				return pathFalse
+			}
+			return pathUnknown
+		case pathOpOr:
+			left := e.evalPredicate(t.left, current)
+			if left == pathTrue {
+				return pathTrue
+			}
+			right := e.evalPredicate(t.right, current)
+			if right == pathFalse {
+				return left
+			}
+			if right == pathTrue {
+				return pathTrue
+			}
+			return pathUnknown
+		}
+		return e.evalComparison(t, current)
+
+	case *pathUnary:
+		switch e.evalPredicate(t.operand, current) {
+		case pathTrue:
+			return pathFalse
+		case pathFalse:
+			return pathTrue
+		}
+		return pathUnknown
+
+	case *pathIsUnknown:
+		return makePathBool(e.evalPredicate(t.pred, current) == pathUnknown)
+
+	case *pathExists:
+		items, err := e.eval(t.expr, current)
+		if err != nil {
+			return pathUnknown
+		}
+		return makePathBool(len(items) > 0)
+
+	case *pathLikeRegex:
+		items, err := e.eval(t.input, current)
+		if err != nil {
+			return pathUnknown
+		}
+		return e.anyItem(e.unwrap(items), func(j JSON) pathBool {
+			if j.Type() != StringJSONType {
+				return pathUnknown
+			}
+			return makePathBool(t.re.MatchString(string(j.(jsonString))))
+		})
+
+	case *pathStartsWith:
+		items, err := e.eval(t.input, current)
+		if err != nil {
+			return pathUnknown
+		}
+		prefixes, err := e.eval(t.prefix, current)
+		if err != nil || len(prefixes) != 1 || prefixes[0].Type() != StringJSONType {
+			return pathUnknown
+		}
+		prefix := string(prefixes[0].(jsonString))
+		return e.anyItem(e.unwrap(items), func(j JSON) pathBool {
+			if j.Type() != StringJSONType {
+				return pathUnknown
+			}
+			return makePathBool(strings.HasPrefix(string(j.(jsonString)), prefix))
+		})
+	}
+	// The parser only allows predicates here.
+	return pathUnknown
+}
+
+// anyItem applies the existential semantics of SQL/JSON predicates: the
+// result is true if fn is true for any item. In lax mode an unknown result
+// for some item is ignored if another item is true, whereas in strict mode
+// any unknown result makes the whole predicate unknown.
+func (e *pathEvaluator) anyItem(items []JSON, fn func(JSON) pathBool) pathBool {
+	found, unknown := false, false
+	for _, j := range items {
+		switch fn(j) {
+		case pathUnknown:
+			if e.strict {
+				return pathUnknown
+			}
+			unknown = true
+		case pathTrue:
+			if !e.strict {
+				return pathTrue
+			}
+			found = true
+		}
+	}
+	if found {
+		return pathTrue
+	}
+	if unknown {
+		return pathUnknown
+	}
+	return pathFalse
+}
+
+func (e *pathEvaluator) evalComparison(n *pathBinary, current JSON) pathBool {
+	left, err := e.eval(n.left, current)
+	if err != nil {
+		return pathUnknown
+	}
+	right, err := e.eval(n.right, current)
+	if err != nil {
+		return pathUnknown
+	}
+	right = e.unwrap(right)
+	return e.anyItem(e.unwrap(left), func(l JSON) pathBool {
+		return e.anyItem(right, func(r JSON) pathBool {
+			return comparePathItems(n.op, l, r)
+		})
+	})
+}
+
+func isPathBool(j JSON) bool {
+	t := j.Type()
+	return t == TrueJSONType || t == FalseJSONType
+}
+
+// comparePathItems compares two items. Only scalars of the same type can be
+// compared; null compares equal to itself and unequal to anything else.
+func comparePathItems(op pathOp, l, r JSON) pathBool {
+	if !l.isScalar() || !r.isScalar() {
+		return pathUnknown
+	}
+	lt, rt := l.Type(), r.Type()
+	if lt != rt && !(isPathBool(l) && isPathBool(r)) {
+		if lt == NullJSONType || rt == NullJSONType {
+			return makePathBool(op == pathOpNe)
+		}
+		return pathUnknown
+	}
+	// Compare orders scalars of the same type as required here, including
+	// false before true.
+	cmp := l.Compare(r)
+	switch op {
+	case pathOpEq:
+		return makePathBool(cmp == 0)
+	case pathOpNe:
+		return makePathBool(cmp != 0)
+	case pathOpLt:
+		return makePathBool(cmp < 0)
+	case pathOpLe:
+		return makePathBool(cmp <= 0)
+	case pathOpGt:
+		return makePathBool(cmp > 0)
+	case pathOpGe:
+		return makePathBool(cmp >= 0)
+	}
+	return pathUnknown
+}
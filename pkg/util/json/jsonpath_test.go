@@ -0,0 +1,262 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package json
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{`$`, `$`},
+		{`lax $`, `$`},
+		{`strict $`, `strict $`},
+		{`$.a`, `$."a"`},
+		{`$."a b"`, `$."a b"`},
+		{`$.a.b[*]`, `$."a"."b"[*]`},
+		{`$.*`, `$.*`},
+		{`$[0, 2 to last]`, `$[0,2 to last]`},
+		{`$[last - 1]`, `$[last - 1]`},
+		{`$.items[*] ? (@.price > 10)`, `$."items"[*]?(@."price" > 10)`},
+		{`$ ? (@ == 1 && (@ == 2 || !(@ == 3)))`, `$?(@ == 1 && (@ == 2 || !(@ == 3)))`},
+		{`$.a + 1 * 2`, `$."a" + 1 * 2`},
+		{`($.a + 1) * 2`, `($."a" + 1) * 2`},
+		{`$.a - ($.b - $.c)`, `$."a" - ($."b" - $."c")`},
+		{`-$.a`, `-$."a"`},
+		{`$.a <> 1`, `$."a" != 1`},
+		{`$.a.size()`, `$."a".size()`},
+		{`$.size`, `$."size"`},
+		{`$.a.type().b`, `$."a".type()."b"`},
+		{`$var`, `$"var"`},
+		{`$ ? (@ like_regex "^a.c$" flag "i")`, `$?(@ like_regex "^a.c$" flag "i")`},
+		{`$ ? (@ starts with "ab")`, `$?(@ starts with "ab")`},
+		{`$ ? ((@ > 1) is unknown)`, `$?((@ > 1) is unknown)`},
+		{`$ ? (exists (@.a))`, `$?(exists (@."a"))`},
+		{`$.a == null`, `$."a" == null`},
+		{`$.a == "x\"y"`, `$."a" == "x\"y"`},
+		{`1.5e2`, `1.5E+2`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			p, err := ParsePath(tc.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := p.String(); s != tc.expected {
+				t.Fatalf("expected %s, got %s", tc.expected, s)
+			}
+			// The formatted path must parse back to the same path.
+			p2, err := ParsePath(p.String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := p2.String(); s != tc.expected {
+				t.Fatalf("expected round trip to produce %s, got %s", tc.expected, s)
+			}
+		})
+	}
+}
+
+func TestParsePathErrors(t *testing.T) {
+	testCases := []struct {
+		input  string
+		errMsg string
+	}{
+		{``, `unexpected end of input`},
+		{`$.`, `unexpected end of input`},
+		{`$[1`, `unexpected end of input`},
+		{`@`, `@ is not allowed in root expressions`},
+		{`$[*] ? (last > 1)`, `LAST is allowed only in array subscripts`},
+		{`$ ? (@.a)`, `expected a boolean predicate`},
+		{`$.a && $.b`, `unexpected "&&"`},
+		{`$.a.foo()`, `unknown item method "foo"`},
+		{`$ ? (@ like_regex "a" flag "z")`, `unrecognized flag character`},
+		{`$ ? (@ like_regex "(")`, `invalid regular expression`},
+		{`$."a`, `unterminated quoted string`},
+		{`$ # 1`, `unexpected character '#'`},
+		{`1a`, `trailing junk after numeric literal`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			_, err := ParsePath(tc.input)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tc.errMsg) {
+				t.Fatalf(`expected error message "%s" to contain "%s"`, err.Error(), tc.errMsg)
+			}
+		})
+	}
+}
+
+func TestPathQuery(t *testing.T) {
+	json := jsonTestShorthand
+	cases := map[string][]struct {
+		path     string
+		vars     string
+		expected []string
+		errMsg   string
+	}{
+		`{"a": 1, "b": [1, 2, 3], "c": {"d": "x"}}`: {
+			{path: `$.a`, expected: []string{`1`}},
+			{path: `$.c.d`, expected: []string{`"x"`}},
+			{path: `$.missing`, expected: nil},
+			{path: `strict $.missing`, errMsg: `JSON object does not contain key "missing"`},
+			{path: `$.a.b`, expected: nil},
+			{path: `strict $.a.b`, errMsg: `jsonpath member accessor can only be applied to an object`},
+			{path: `$.*`, expected: []string{`1`, `[1, 2, 3]`, `{"d": "x"}`}},
+			{path: `$.b[*]`, expected: []string{`1`, `2`, `3`}},
+			{path: `$.b[0]`, expected: []string{`1`}},
+			{path: `$.b[last]`, expected: []string{`3`}},
+			{path: `$.b[0, 1 to last]`, expected: []string{`1`, `2`, `3`}},
+			{path: `$.b[5]`, expected: nil},
+			{path: `strict $.b[5]`, errMsg: `jsonpath array subscript is out of bounds`},
+			{path: `$.a[0]`, expected: []string{`1`}},
+			{path: `strict $.a[0]`, errMsg: `jsonpath array accessor can only be applied to an array`},
+			{path: `$.b ? (@ > 1)`, expected: []string{`2`, `3`}},
+			{path: `strict $.b ? (@ > 1)`, expected: nil},
+			{path: `strict $.b[*] ? (@ > 1)`, expected: []string{`2`, `3`}},
+			{path: `$.b.size()`, expected: []string{`3`}},
+			{path: `$.a.size()`, expected: []string{`1`}},
+			{path: `strict $.a.size()`, errMsg: `.size() can only be applied to an array`},
+			{path: `$.*.type()`, expected: []string{`"number"`, `"array"`, `"object"`}},
+			{path: `$.a + 2 * 3`, expected: []string{`7`}},
+			{path: `$.a / 4`, expected: []string{`0.25`}},
+			{path: `$.a / 0`, errMsg: `division by zero`},
+			{path: `7 % 3`, expected: []string{`1`}},
+			{path: `-$.b`, expected: []string{`-1`, `-2`, `-3`}},
+			{path: `$.b + 1`, errMsg: `left operand of jsonpath operator + is not a single numeric value`},
+			{path: `$.c.d + 1`, errMsg: `left operand of jsonpath operator + is not a single numeric value`},
+			{path: `$.a == 1`, expected: []string{`true`}},
+			{path: `$.b == 2`, expected: []string{`true`}},
+			{path: `$.c.d == 1`, expected: []string{`null`}},
+			{path: `$.a > $x`, vars: `{"x": 0}`, expected: []string{`true`}},
+			{path: `$.a > $x`, errMsg: `could not find jsonpath variable "x"`},
+		},
+		`{"items": [{"name": "a", "price": 5}, {"name": "b", "price": 15}, {"name": "abc", "price": 20}]}`: {
+			{path: `$.items[*] ? (@.price > 10).name`, expected: []string{`"b"`, `"abc"`}},
+			{path: `$.items ? (@.price > 10 && @.name starts with "a").name`, expected: []string{`"abc"`}},
+			{path: `$.items ? (@.name like_regex "^A" flag "i").price`, expected: []string{`5`, `20`}},
+			{path: `$.items ? (exists (@.missing))`, expected: nil},
+			{path: `$.items ? (!(@.price < 10)).name`, expected: []string{`"b"`, `"abc"`}},
+			{path: `$.items ? ((@.missing > 1) is unknown).name`, expected: nil},
+			{path: `strict $.items[*] ? ((@.missing > 1) is unknown).name`, expected: []string{`"a"`, `"b"`, `"abc"`}},
+			{path: `$.items[*].price ? (@ >= $min)`, vars: `{"min": 15}`, expected: []string{`15`, `20`}},
+		},
+		`[1.5, -2.5, "3.5", null, true]`: {
+			{path: `$[0 to 1].abs()`, expected: []string{`1.5`, `2.5`}},
+			{path: `$[0 to 1].floor()`, expected: []string{`1`, `-3`}},
+			{path: `$[0 to 1].ceiling()`, expected: []string{`2`, `-2`}},
+			{path: `$[2].double()`, expected: []string{`3.5`}},
+			{path: `$[3].double()`, errMsg: `.double() can only be applied to a string or numeric value`},
+			{path: `$[*] ? (@ == null)`, expected: []string{`null`}},
+			{path: `$[*] ? (@ != null)`, expected: []string{`1.5`, `-2.5`, `"3.5"`, `true`}},
+			{path: `$[*] ? (@ == true)`, expected: []string{`true`}},
+			{path: `$[*] ? (@ > false)`, expected: []string{`true`}},
+			{path: `$[*].type()`, expected: []string{`"number"`, `"number"`, `"string"`, `"null"`, `"boolean"`}},
+		},
+	}
+
+	for k, tests := range cases {
+		target := json(k)
+		for _, tc := range tests {
+			t.Run(fmt.Sprintf("%s-%s", k, tc.path), func(t *testing.T) {
+				p, err := ParsePath(tc.path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var vars JSON
+				if tc.vars != "" {
+					vars = json(tc.vars)
+				}
+				result, err := p.Query(target, vars)
+				if tc.errMsg != "" {
+					if err == nil {
+						t.Fatal("expected error")
+					} else if !strings.Contains(err.Error(), tc.errMsg) {
+						t.Fatalf(`expected error message "%s" to contain "%s"`, err.Error(), tc.errMsg)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(result) != len(tc.expected) {
+					t.Fatalf("expected %v, got %v", tc.expected, result)
+				}
+				for i := range result {
+					if result[i].Compare(json(tc.expected[i])) != 0 {
+						t.Fatalf("expected %v, got %v", tc.expected, result)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestPathExistsAndMatch(t *testing.T) {
+	target := jsonTestShorthand(`{"a": [1, 2, 3]}`)
+	testCases := []struct {
+		path   string
+		exists bool
+		match  string
+		errMsg string
+	}{
+		{path: `$.a[*] ? (@ > 2)`, exists: true, errMsg: `single boolean result is expected`},
+		{path: `$.a[*] ? (@ > 3)`, exists: false, errMsg: `single boolean result is expected`},
+		{path: `$.a[*] > 2`, exists: true, match: `true`},
+		{path: `$.a[*] > 3`, exists: true, match: `false`},
+		{path: `$.a[*] > "x"`, exists: true, match: `null`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			p, err := ParsePath(tc.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			exists, err := p.Exists(target, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if exists != tc.exists {
+				t.Fatalf("expected exists to be %t, got %t", tc.exists, exists)
+			}
+			match, err := p.Match(target, nil)
+			if tc.errMsg != "" {
+				if err == nil {
+					t.Fatal("expected error")
+				} else if !strings.Contains(err.Error(), tc.errMsg) {
+					t.Fatalf(`expected error message "%s" to contain "%s"`, err.Error(), tc.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			result := "null"
+			if match != nil {
+				result = fmt.Sprint(*match)
+			}
+			if result != tc.match {
+				t.Fatalf("expected match to be %s, got %s", tc.match, result)
+			}
+		})
+	}
+}
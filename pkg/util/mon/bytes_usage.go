@@ -664,6 +664,14 @@ func (mm *BytesMonitor) adjustBudget(ctx context.Context) {
 	}
 }
 
+// MaximumBytes returns the maximum number of bytes that were allocated by this
+// monitor at one time since it was started.
+func (mm *BytesMonitor) MaximumBytes() int64 {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	return mm.mu.maxAllocated
+}
+
 // GetCurrentAllocationForTesting returns the number of bytes that have
 // currently been allocated in the BytesMonitor. Intended for use in testing.
 func (mm *BytesMonitor) GetCurrentAllocationForTesting() int64 {
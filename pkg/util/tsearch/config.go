@@ -0,0 +1,145 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tsearch
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+)
+
+// Config is a text search configuration, which determines how the words of
+// a document or query are turned into lexemes.
+type Config struct {
+	name string
+	// stopWords are dropped from documents and queries. They still count
+	// towards the positions of the following words.
+	stopWords map[string]struct{}
+	// stem, if set, reduces a lowercased word to its stem.
+	stem func(word string) string
+}
+
+// English is the text search configuration for English text: words are
+// lowercased, English stop words are dropped and the remaining words are
+// reduced to their stem using the Porter2 stemming algorithm.
+var English = &Config{
+	name:      "english",
+	stopWords: englishStopWords,
+	stem:      stemEnglish,
+}
+
+// Simple is the text search configuration that only lowercases words.
+var Simple = &Config{name: "simple"}
+
+// DefaultConfig is the configuration used when none is specified.
+var DefaultConfig = English
+
+var configs = map[string]*Config{
+	English.name: English,
+	Simple.name:  Simple,
+}
+
+// GetConfig returns the text search configuration with the given name, which
+// may be qualified with the pg_catalog schema.
+func GetConfig(name string) (*Config, error) {
+	if c, ok := configs[strings.TrimPrefix(strings.ToLower(name), "pg_catalog.")]; ok {
+		return c, nil
+	}
+	return nil, pgerror.NewErrorf(pgerror.CodeUndefinedObjectError,
+		"text search configuration %q does not exist", name)
+}
+
+// Name returns the name of the configuration.
+func (c *Config) Name() string {
+	return c.name
+}
+
+// tokenize splits text into words, which are maximal runs of letters and
+// digits, and calls fn for each of them along with its (1-based) position.
+func tokenize(text string, fn func(word string, pos int)) {
+	pos := 0
+	start := -1
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start == -1 {
+				start = i
+			}
+			continue
+		}
+		if start != -1 {
+			pos++
+			fn(text[start:i], pos)
+			start = -1
+		}
+	}
+	if start != -1 {
+		pos++
+		fn(text[start:], pos)
+	}
+}
+
+// lexeme returns the lexeme for a word, or false if it is a stop word.
+func (c *Config) lexeme(word string) (string, bool) {
+	word = strings.ToLower(word)
+	if _, ok := c.stopWords[word]; ok {
+		return "", false
+	}
+	if c.stem != nil && strings.IndexFunc(word, unicode.IsDigit) == -1 {
+		word = c.stem(word)
+	}
+	return word, true
+}
+
+// lexemes returns the lexemes for all the words of text.
+func (c *Config) lexemes(text string) []string {
+	var res []string
+	tokenize(text, func(word string, _ int) {
+		if l, ok := c.lexeme(word); ok {
+			res = append(res, l)
+		}
+	})
+	return res
+}
+
+// ToVector reduces a document to a Vector.
+func (c *Config) ToVector(doc string) Vector {
+	var lexemes []Lexeme
+	tokenize(doc, func(word string, pos int) {
+		if l, ok := c.lexeme(word); ok {
+			if pos > MaxPosition {
+				pos = MaxPosition
+			}
+			lexemes = append(lexemes, Lexeme{Word: l, Positions: []uint16{uint16(pos)}})
+		}
+	})
+	return makeVector(lexemes)
+}
+
+// ToQuery parses a query written in the tsquery syntax, normalizing its words
+// into lexemes. Stop words are removed from the query.
+func (c *Config) ToQuery(s string) (Query, error) {
+	return parseQuery(s, c.lexemes)
+}
+
+// PlainToQuery turns unformatted text into a query matching documents that
+// contain all of its (non stop) words.
+func (c *Config) PlainToQuery(text string) Query {
+	var root queryNode
+	for _, l := range c.lexemes(text) {
+		root = combineQueryNodes(queryAnd, root, &queryTerm{word: l})
+	}
+	return Query{root: root}
+}
@@ -0,0 +1,116 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tsearch
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStemEnglish(t *testing.T) {
+	testCases := map[string]string{
+		"caresses":      "caress",
+		"ponies":        "poni",
+		"ties":          "tie",
+		"cats":          "cat",
+		"gas":           "gas",
+		"kiwis":         "kiwi",
+		"running":       "run",
+		"hopping":       "hop",
+		"hoping":        "hope",
+		"agreed":        "agre",
+		"sized":         "size",
+		"crying":        "cri",
+		"saying":        "say",
+		"happiness":     "happi",
+		"relational":    "relat",
+		"conditional":   "condit",
+		"quickly":       "quick",
+		"hopefully":     "hope",
+		"nationality":   "nation",
+		"electricity":   "electr",
+		"connection":    "connect",
+		"consignment":   "consign",
+		"generously":    "generous",
+		"communication": "communic",
+		"controllable":  "control",
+		"skies":         "sky",
+		"dying":         "die",
+		"inning":        "inning",
+	}
+	for word, expected := range testCases {
+		if s := stemEnglish(word); s != expected {
+			t.Errorf("expected %s to stem to %s, got %s", word, expected, s)
+		}
+	}
+}
+
+func TestConfig(t *testing.T) {
+	testCases := []struct {
+		config string
+		doc    string
+		vector string
+		query  string
+		plain  string
+	}{
+		{
+			config: "english",
+			doc:    "The quick brown foxes jumped over the lazy dogs",
+			vector: `'brown':3 'dog':9 'fox':4 'jump':5 'lazi':8 'quick':2`,
+			query:  `'fox' & ( 'dog' | !'cat' )`,
+			plain:  `'quick' & 'brown' & 'fox' & 'jump' & 'lazi' & 'dog'`,
+		},
+		{
+			config: "pg_catalog.simple",
+			doc:    "The quick brown foxes jumped over the lazy dogs",
+			vector: `'brown':3 'dogs':9 'foxes':4 'jumped':5 'lazy':8 'over':6 'quick':2 'the':1,7`,
+			query:  `'foxes' & ( 'dogs' | !'cats' ) & 'the'`,
+			plain:  `'the' & 'quick' & 'brown' & 'foxes' & 'jumped' & 'over' & 'the' & 'lazy' & 'dogs'`,
+		},
+		{
+			config: "english",
+			doc:    "SKU-1234: Running shoes, size 10",
+			vector: `'10':6 '1234':2 'run':3 'shoe':4 'size':5 'sku':1`,
+			query:  `'fox' & ( 'dog' | !'cat' )`,
+			plain:  `'sku' & '1234' & 'run' & 'shoe' & 'size' & '10'`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.config+"/"+tc.doc, func(t *testing.T) {
+			c, err := GetConfig(tc.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v := c.ToVector(tc.doc).String(); v != tc.vector {
+				t.Errorf("expected vector %s, got %s", tc.vector, v)
+			}
+			q, err := c.ToQuery("Foxes & (dogs | !cats) & the")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := q.String(); s != tc.query {
+				t.Errorf("expected query %s, got %s", tc.query, s)
+			}
+			if s := c.PlainToQuery(tc.doc).String(); s != tc.plain {
+				t.Errorf("expected plain query %s, got %s", tc.plain, s)
+			}
+		})
+	}
+
+	if _, err := GetConfig("french"); !strings.Contains(err.Error(),
+		`text search configuration "french" does not exist`) {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
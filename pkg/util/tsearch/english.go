@@ -0,0 +1,346 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tsearch
+
+import "bytes"
+
+// englishStopWords is the list of English stop words used by Postgres.
+var englishStopWords = func() map[string]struct{} {
+	words := []string{
+		"i", "me", "my", "myself", "we", "our", "ours", "ourselves", "you", "your",
+		"yours", "yourself", "yourselves", "he", "him", "his", "himself", "she",
+		"her", "hers", "herself", "it", "its", "itself", "they", "them", "their",
+		"theirs", "themselves", "what", "which", "who", "whom", "this", "that",
+		"these", "those", "am", "is", "are", "was", "were", "be", "been", "being",
+		"have", "has", "had", "having", "do", "does", "did", "doing", "a", "an",
+		"the", "and", "but", "if", "or", "because", "as", "until", "while", "of",
+		"at", "by", "for", "with", "about", "against", "between", "into",
+		"through", "during", "before", "after", "above", "below", "to", "from",
+		"up", "down", "in", "out", "on", "off", "over", "under", "again",
+		"further", "then", "once", "here", "there", "when", "where", "why", "how",
+		"all", "any", "both", "each", "few", "more", "most", "other", "some",
+		"such", "no", "nor", "not", "only", "own", "same", "so", "than", "too",
+		"very", "s", "t", "can", "will", "just", "don", "should", "now",
+	}
+	m := make(map[string]struct{}, len(words))
+	for _, w := range words {
+		m[w] = struct{}{}
+	}
+	return m
+}()
+
+// englishExceptions maps words that the Porter2 algorithm would stem
+// incorrectly to their stem.
+var englishExceptions = map[string]string{
+	"skis":   "ski",
+	"skies":  "sky",
+	"dying":  "die",
+	"lying":  "lie",
+	"tying":  "tie",
+	"idly":   "idl",
+	"gently": "gentl",
+	"ugly":   "ugli",
+	"early":  "earli",
+	"only":   "onli",
+	"singly": "singl",
+	"sky":    "sky",
+	"news":   "news",
+	"howe":   "howe",
+	"atlas":  "atlas",
+	"cosmos": "cosmos",
+	"bias":   "bias",
+	"andes":  "andes",
+}
+
+// englishInvariants are left alone once step 1a has been applied.
+var englishInvariants = map[string]struct{}{
+	"inning": {}, "outing": {}, "canning": {}, "herring": {},
+	"earring": {}, "proceed": {}, "exceed": {}, "succeed": {},
+}
+
+// stemEnglish reduces a lowercase English word to its stem using the Porter2
+// (Snowball English) stemming algorithm, e.g. "jumping" and "jumps" both
+// become "jump". See http://snowball.tartarus.org/algorithms/english/stemmer.html.
+func stemEnglish(word string) string {
+	if len(word) <= 2 {
+		return word
+	}
+	if s, ok := englishExceptions[word]; ok {
+		return s
+	}
+	s := &englishStemmer{w: []byte(word)}
+	// A 'y' acting as a consonant is marked as 'Y' for the rest of the
+	// algorithm.
+	if s.w[0] == 'y' {
+		s.w[0] = 'Y'
+	}
+	for i := 1; i < len(s.w); i++ {
+		if s.w[i] == 'y' && isEnglishVowel(s.w[i-1]) {
+			s.w[i] = 'Y'
+		}
+	}
+	s.computeRegions()
+	s.step1a()
+	if _, ok := englishInvariants[string(s.w)]; ok {
+		return string(s.w)
+	}
+	s.step1b()
+	s.step1c()
+	s.step2()
+	s.step3()
+	s.step4()
+	s.step5()
+	return string(bytes.ToLower(s.w))
+}
+
+func isEnglishVowel(c byte) bool {
+	switch c {
+	case 'a', 'e', 'i', 'o', 'u', 'y':
+		return true
+	}
+	return false
+}
+
+type englishStemmer struct {
+	w []byte
+	// r1 and r2 are the offsets at which the R1 and R2 regions of the word
+	// start; suffixes are only removed from within those regions.
+	r1, r2 int
+}
+
+// regionAfter returns the offset after the first non-vowel following a vowel
+// at or after offset i.
+func (s *englishStemmer) regionAfter(i int) int {
+	for ; i+1 < len(s.w); i++ {
+		if isEnglishVowel(s.w[i]) && !isEnglishVowel(s.w[i+1]) {
+			return i + 2
+		}
+	}
+	return len(s.w)
+}
+
+func (s *englishStemmer) computeRegions() {
+	s.r1 = -1
+	for _, p := range []string{"gener", "commun", "arsen"} {
+		if bytes.HasPrefix(s.w, []byte(p)) {
+			s.r1 = len(p)
+		}
+	}
+	if s.r1 == -1 {
+		s.r1 = s.regionAfter(0)
+	}
+	s.r2 = s.regionAfter(s.r1)
+}
+
+func (s *englishStemmer) hasSuffix(suffix string) bool {
+	return bytes.HasSuffix(s.w, []byte(suffix))
+}
+
+// longestSuffix returns the longest of the given suffixes that s ends with.
+func (s *englishStemmer) longestSuffix(suffixes ...string) (string, bool) {
+	var res string
+	for _, suffix := range suffixes {
+		if len(suffix) > len(res) && s.hasSuffix(suffix) {
+			res = suffix
+		}
+	}
+	return res, res != ""
+}
+
+// inRegion returns whether the given suffix of the word lies within the
+// region starting at offset r.
+func (s *englishStemmer) inRegion(suffix string, r int) bool {
+	return len(s.w)-len(suffix) >= r
+}
+
+func (s *englishStemmer) replace(suffix, with string) {
+	s.w = append(s.w[:len(s.w)-len(suffix)], with...)
+}
+
+func (s *englishStemmer) containsVowel(b []byte) bool {
+	for _, c := range b {
+		if isEnglishVowel(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// endsInShortSyllable returns whether w ends with a vowel followed by a
+// non-vowel other than 'w', 'x' or 'Y' and preceded by a non-vowel, or
+// consists of a vowel followed by a non-vowel.
+func endsInShortSyllable(w []byte) bool {
+	n := len(w)
+	if n == 2 {
+		return isEnglishVowel(w[0]) && !isEnglishVowel(w[1])
+	}
+	return n >= 3 && !isEnglishVowel(w[n-3]) && isEnglishVowel(w[n-2]) &&
+		!isEnglishVowel(w[n-1]) && w[n-1] != 'w' && w[n-1] != 'x' && w[n-1] != 'Y'
+}
+
+// isShort returns whether the word ends in a short syllable and has an empty
+// R1 region.
+func (s *englishStemmer) isShort() bool {
+	return s.r1 >= len(s.w) && endsInShortSyllable(s.w)
+}
+
+func (s *englishStemmer) step1a() {
+	suffix, ok := s.longestSuffix("sses", "ied", "ies", "us", "ss", "s")
+	if !ok {
+		return
+	}
+	switch suffix {
+	case "sses":
+		s.replace(suffix, "ss")
+	case "ied", "ies":
+		if len(s.w) > 4 {
+			s.replace(suffix, "i")
+		} else {
+			s.replace(suffix, "ie")
+		}
+	case "s":
+		// Delete the s if the preceding part of the word contains a vowel not
+		// immediately before it.
+		if s.containsVowel(s.w[:len(s.w)-2]) {
+			s.replace(suffix, "")
+		}
+	}
+}
+
+func (s *englishStemmer) step1b() {
+	suffix, ok := s.longestSuffix("eedly", "ingly", "edly", "eed", "ing", "ed")
+	if !ok {
+		return
+	}
+	switch suffix {
+	case "eed", "eedly":
+		if s.inRegion(suffix, s.r1) {
+			s.replace(suffix, "ee")
+		}
+		return
+	}
+	if !s.containsVowel(s.w[:len(s.w)-len(suffix)]) {
+		return
+	}
+	s.replace(suffix, "")
+	if _, ok := s.longestSuffix("at", "bl", "iz"); ok {
+		s.w = append(s.w, 'e')
+	} else if _, ok := s.longestSuffix("bb", "dd", "ff", "gg", "mm", "nn", "pp", "rr", "tt"); ok {
+		s.w = s.w[:len(s.w)-1]
+	} else if s.isShort() {
+		s.w = append(s.w, 'e')
+	}
+}
+
+func (s *englishStemmer) step1c() {
+	n := len(s.w)
+	if n > 2 && (s.w[n-1] == 'y' || s.w[n-1] == 'Y') && !isEnglishVowel(s.w[n-2]) {
+		s.w[n-1] = 'i'
+	}
+}
+
+var englishStep2Suffixes = map[string]string{
+	"tional": "tion", "enci": "ence", "anci": "ance", "abli": "able",
+	"entli": "ent", "izer": "ize", "ization": "ize", "ational": "ate",
+	"ation": "ate", "ator": "ate", "alism": "al", "aliti": "al", "alli": "al",
+	"fulness": "ful", "ousli": "ous", "ousness": "ous", "iveness": "ive",
+	"iviti": "ive", "biliti": "ble", "bli": "ble", "ogi": "og", "fulli": "ful",
+	"lessli": "less", "li": "",
+}
+
+var englishStep2SuffixList = func() []string {
+	var res []string
+	for suffix := range englishStep2Suffixes {
+		res = append(res, suffix)
+	}
+	return res
+}()
+
+func (s *englishStemmer) step2() {
+	suffix, ok := s.longestSuffix(englishStep2SuffixList...)
+	if !ok || !s.inRegion(suffix, s.r1) {
+		return
+	}
+	switch suffix {
+	case "ogi":
+		if len(s.w) < 4 || s.w[len(s.w)-4] != 'l' {
+			return
+		}
+	case "li":
+		// Only delete "li" when preceded by a valid li-ending.
+		if len(s.w) < 3 {
+			return
+		}
+		switch s.w[len(s.w)-3] {
+		case 'c', 'd', 'e', 'g', 'h', 'k', 'm', 'n', 'r', 't':
+		default:
+			return
+		}
+	}
+	s.replace(suffix, englishStep2Suffixes[suffix])
+}
+
+func (s *englishStemmer) step3() {
+	suffix, ok := s.longestSuffix(
+		"tional", "ational", "alize", "icate", "iciti", "ical", "ful", "ness", "ative")
+	if !ok || !s.inRegion(suffix, s.r1) {
+		return
+	}
+	switch suffix {
+	case "tional":
+		s.replace(suffix, "tion")
+	case "ational":
+		s.replace(suffix, "ate")
+	case "alize":
+		s.replace(suffix, "al")
+	case "icate", "iciti", "ical":
+		s.replace(suffix, "ic")
+	case "ful", "ness":
+		s.replace(suffix, "")
+	case "ative":
+		if s.inRegion(suffix, s.r2) {
+			s.replace(suffix, "")
+		}
+	}
+}
+
+func (s *englishStemmer) step4() {
+	suffix, ok := s.longestSuffix(
+		"al", "ance", "ence", "er", "ic", "able", "ible", "ant", "ement", "ment",
+		"ent", "ism", "ate", "iti", "ous", "ive", "ize", "ion")
+	if !ok || !s.inRegion(suffix, s.r2) {
+		return
+	}
+	if suffix == "ion" {
+		if len(s.w) < 4 || (s.w[len(s.w)-4] != 's' && s.w[len(s.w)-4] != 't') {
+			return
+		}
+	}
+	s.replace(suffix, "")
+}
+
+func (s *englishStemmer) step5() {
+	switch {
+	case s.hasSuffix("e"):
+		if s.inRegion("e", s.r2) ||
+			(s.inRegion("e", s.r1) && !endsInShortSyllable(s.w[:len(s.w)-1])) {
+			s.replace("e", "")
+		}
+	case s.hasSuffix("l"):
+		if s.inRegion("l", s.r2) && s.hasSuffix("ll") {
+			s.replace("l", "")
+		}
+	}
+}
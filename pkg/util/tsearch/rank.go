@@ -0,0 +1,135 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tsearch
+
+import "math"
+
+// The constants below mirror the ones used by Postgres' ts_rank, so that
+// rankings match.
+const (
+	// defaultWeight is the weight of a lexeme occurrence. Postgres supports
+	// four weight classes; since weights are not supported, every position
+	// has the default (lowest) weight.
+	defaultWeight = 0.1
+	// sumInverseSquares is the limit of sum(1/i^2), i.e. pi^2/6.
+	sumInverseSquares = 1.64493406685
+	// maxDistance is used as the distance between occurrences of lexemes
+	// that do not have positions.
+	maxDistance = MaxPosition + 1
+)
+
+// Rank computes the relevance of the vector for the query, using the same
+// formula as Postgres' ts_rank without normalization. Conjunctive queries are
+// ranked by how close together the matched lexemes are, while other queries
+// are ranked by how often their lexemes occur.
+func Rank(v Vector, q Query) float32 {
+	terms := q.terms()
+	if len(terms) == 0 {
+		return 0
+	}
+	var res float64
+	if b, ok := q.root.(*queryBinary); ok && b.op == queryAnd && len(terms) > 1 {
+		res = rankAnd(v, terms)
+	} else {
+		res = rankOr(v, terms)
+	}
+	if res < 0 {
+		res = 1e-20
+	}
+	return float32(res)
+}
+
+// positions returns the positions at which the term occurs in v, or nil if
+// it does not occur. Lexemes without positions are assumed to occur once at
+// an unknown position, represented by 0.
+func (t *queryTerm) positions(v Vector) []uint16 {
+	var res []uint16
+	add := func(l Lexeme) {
+		if len(l.Positions) == 0 {
+			res = append(res, 0)
+		}
+		res = append(res, l.Positions...)
+	}
+	if !t.prefix {
+		if i, ok := v.find(t.word); ok {
+			add(v[i])
+		}
+		return res
+	}
+	for i, _ := v.find(t.word); i < len(v) && len(v[i].Word) >= len(t.word) &&
+		v[i].Word[:len(t.word)] == t.word; i++ {
+		add(v[i])
+	}
+	return normalizePositions(res)
+}
+
+func rankOr(v Vector, terms []*queryTerm) float64 {
+	var res float64
+	for _, t := range terms {
+		positions := t.positions(v)
+		if len(positions) == 0 {
+			continue
+		}
+		// Each further occurrence of a lexeme contributes less to the rank.
+		var sum float64
+		for j := range positions {
+			sum += defaultWeight / float64((j+1)*(j+1))
+		}
+		res += sum / sumInverseSquares
+	}
+	return res / float64(len(terms))
+}
+
+func rankAnd(v Vector, terms []*queryTerm) float64 {
+	positions := make([][]uint16, len(terms))
+	for i, t := range terms {
+		positions[i] = t.positions(v)
+	}
+	res := -1.0
+	for i := range terms {
+		for k := 0; k < i; k++ {
+			for _, p := range positions[i] {
+				for _, q := range positions[k] {
+					dist := int(p) - int(q)
+					if dist < 0 {
+						dist = -dist
+					}
+					if dist == 0 {
+						if p != 0 && q != 0 {
+							continue
+						}
+						dist = maxDistance
+					}
+					curw := math.Sqrt(defaultWeight * defaultWeight * wordDistance(dist))
+					if res < 0 {
+						res = curw
+					} else {
+						res = 1.0 - (1.0-res)*(1.0-curw)
+					}
+				}
+			}
+		}
+	}
+	return res
+}
+
+// wordDistance returns the contribution of two lexemes appearing at the given
+// distance from each other; closer lexemes contribute more.
+func wordDistance(dist int) float64 {
+	if dist > 100 {
+		return 1e-30
+	}
+	return 1.0 / (1.005 + 0.05*math.Exp(float64(dist)/1.5-2))
+}
@@ -0,0 +1,386 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tsearch
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+)
+
+// Query is a boolean combination of lexemes that can be matched against a
+// Vector. It is the representation of the SQL TSQUERY type. The zero Query
+// contains no lexemes and matches nothing.
+type Query struct {
+	root queryNode
+}
+
+// queryNode is a node of a Query's expression tree: a *queryTerm, a
+// *queryNot or a *queryBinary.
+type queryNode interface {
+	// match returns whether the node matches the vector.
+	match(v Vector) bool
+}
+
+// queryTerm matches vectors containing word, or any lexeme beginning with
+// word if prefix is set (written word:* in a query).
+type queryTerm struct {
+	word   string
+	prefix bool
+}
+
+type queryNot struct {
+	operand queryNode
+}
+
+type queryOp int
+
+const (
+	queryOr queryOp = iota
+	queryAnd
+)
+
+type queryBinary struct {
+	op          queryOp
+	left, right queryNode
+}
+
+// The priorities of the query operators, used to decide where parentheses
+// are needed when formatting a query.
+const (
+	queryPrioOr = iota + 1
+	queryPrioAnd
+	queryPrioNot
+	queryPrioTerm
+)
+
+func (t *queryTerm) match(v Vector) bool {
+	if t.prefix {
+		return v.hasPrefix(t.word)
+	}
+	_, ok := v.find(t.word)
+	return ok
+}
+
+func (n *queryNot) match(v Vector) bool {
+	return !n.operand.match(v)
+}
+
+func (b *queryBinary) match(v Vector) bool {
+	if b.op == queryAnd {
+		return b.left.match(v) && b.right.match(v)
+	}
+	return b.left.match(v) || b.right.match(v)
+}
+
+// Match returns whether the query matches the vector. An empty query never
+// matches.
+func (q Query) Match(v Vector) bool {
+	if q.root == nil {
+		return false
+	}
+	return q.root.match(v)
+}
+
+// IsEmpty returns whether the query contains no lexemes, which is the case
+// when all its words were stop words.
+func (q Query) IsEmpty() bool {
+	return q.root == nil
+}
+
+// IndexLexeme returns a lexeme which every vector matched by q must contain,
+// making it usable to look up candidate rows in an inverted index. If there
+// are several, the longest (and thus likely the most selective) one is
+// returned. It returns false if there is no such lexeme, e.g. when the query
+// is a disjunction or only has prefix terms.
+func (q Query) IndexLexeme() (string, bool) {
+	var best string
+	for _, w := range requiredWords(q.root) {
+		if len(w) > len(best) {
+			best = w
+		}
+	}
+	return best, best != ""
+}
+
+// requiredWords returns the words that must be present in any vector matched
+// by n.
+func requiredWords(n queryNode) []string {
+	switch t := n.(type) {
+	case *queryTerm:
+		if !t.prefix {
+			return []string{t.word}
+		}
+	case *queryBinary:
+		left, right := requiredWords(t.left), requiredWords(t.right)
+		if t.op == queryAnd {
+			return append(left, right...)
+		}
+		var res []string
+		for _, l := range left {
+			for _, r := range right {
+				if l == r {
+					res = append(res, l)
+					break
+				}
+			}
+		}
+		return res
+	}
+	return nil
+}
+
+// terms returns the distinct terms of the query in the order in which they
+// first appear.
+func (q Query) terms() []*queryTerm {
+	var res []*queryTerm
+	var walk func(n queryNode)
+	walk = func(n queryNode) {
+		switch t := n.(type) {
+		case *queryTerm:
+			for _, r := range res {
+				if *r == *t {
+					return
+				}
+			}
+			res = append(res, t)
+		case *queryNot:
+			walk(t.operand)
+		case *queryBinary:
+			walk(t.left)
+			walk(t.right)
+		}
+	}
+	walk(q.root)
+	return res
+}
+
+// Compare compares the text representations of q and other. It returns -1,
+// 0 or 1.
+func (q Query) Compare(other Query) int {
+	return strings.Compare(q.String(), other.String())
+}
+
+// Size returns the approximate size of q in bytes.
+func (q Query) Size() uintptr {
+	return uintptr(len(q.String()))
+}
+
+// Format writes the Postgres text representation of q to buf, e.g.
+// 'fat' & ( 'rat' | 'cat' ).
+func (q Query) Format(buf *bytes.Buffer) {
+	if q.root != nil {
+		formatQueryNode(buf, q.root, queryPrioOr)
+	}
+}
+
+func (q Query) String() string {
+	var buf bytes.Buffer
+	q.Format(&buf)
+	return buf.String()
+}
+
+func queryNodePriority(n queryNode) int {
+	switch t := n.(type) {
+	case *queryNot:
+		return queryPrioNot
+	case *queryBinary:
+		if t.op == queryAnd {
+			return queryPrioAnd
+		}
+		return queryPrioOr
+	}
+	return queryPrioTerm
+}
+
+// formatQueryNode formats n, surrounding it with parentheses if it binds less
+// tightly than minPrio.
+func formatQueryNode(buf *bytes.Buffer, n queryNode, minPrio int) {
+	parens := queryNodePriority(n) < minPrio
+	if parens {
+		buf.WriteString("( ")
+	}
+	switch t := n.(type) {
+	case *queryTerm:
+		writeQuotedWord(buf, t.word)
+		if t.prefix {
+			buf.WriteString(":*")
+		}
+	case *queryNot:
+		buf.WriteByte('!')
+		formatQueryNode(buf, t.operand, queryPrioNot)
+	case *queryBinary:
+		prio := queryNodePriority(t)
+		formatQueryNode(buf, t.left, prio)
+		if t.op == queryAnd {
+			buf.WriteString(" & ")
+		} else {
+			buf.WriteString(" | ")
+		}
+		formatQueryNode(buf, t.right, prio)
+	}
+	if parens {
+		buf.WriteString(" )")
+	}
+}
+
+// queryParser is a recursive-descent parser for the tsquery syntax:
+//
+//	or    := and ( '|' and )*
+//	and   := unary ( '&' unary )*
+//	unary := '!' unary | '(' or ')' | word [ ':*' ]
+//
+// Each word is passed through normalize, which maps it to zero or more
+// lexemes. Words that map to several lexemes become the conjunction of those
+// lexemes, and words that map to none (stop words) are removed from the query
+// along with the operators applying to them.
+type queryParser struct {
+	input     string
+	rest      string
+	normalize func(word string) []string
+}
+
+func (p *queryParser) syntaxError() error {
+	return pgerror.NewErrorf(pgerror.CodeSyntaxError, "syntax error in tsquery: %q", p.input)
+}
+
+// peek skips whitespace and returns the next byte of the input, or 0 at the
+// end of the input.
+func (p *queryParser) peek() byte {
+	p.rest = strings.TrimLeftFunc(p.rest, func(r rune) bool { return r < 0x80 && isSpace(byte(r)) })
+	if p.rest == "" {
+		return 0
+	}
+	return p.rest[0]
+}
+
+func (p *queryParser) parseOr() (queryNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == '|' {
+		p.rest = p.rest[1:]
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = combineQueryNodes(queryOr, left, right)
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (queryNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == '&' {
+		p.rest = p.rest[1:]
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = combineQueryNodes(queryAnd, left, right)
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseUnary() (queryNode, error) {
+	switch p.peek() {
+	case 0, '&', '|', ')', ':':
+		return nil, p.syntaxError()
+	case '!':
+		p.rest = p.rest[1:]
+		operand, err := p.parseUnary()
+		if err != nil || operand == nil {
+			return nil, err
+		}
+		return &queryNot{operand: operand}, nil
+	case '(':
+		p.rest = p.rest[1:]
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, p.syntaxError()
+		}
+		p.rest = p.rest[1:]
+		return n, nil
+	}
+	word, rest, ok := readWord(p.rest, "&|!():")
+	if !ok {
+		return nil, p.syntaxError()
+	}
+	p.rest = rest
+	prefix := false
+	if strings.HasPrefix(p.rest, ":") {
+		switch {
+		case strings.HasPrefix(p.rest, ":*"):
+			prefix = true
+			p.rest = p.rest[2:]
+		case len(p.rest) > 1 && strings.IndexByte("ABCDabcd", p.rest[1]) != -1:
+			return nil, pgerror.NewErrorf(pgerror.CodeFeatureNotSupportedError,
+				"tsquery weight restrictions are not supported")
+		default:
+			return nil, p.syntaxError()
+		}
+	}
+	words := []string{word}
+	if p.normalize != nil {
+		words = p.normalize(word)
+	}
+	var n queryNode
+	for _, w := range words {
+		n = combineQueryNodes(queryAnd, n, &queryTerm{word: w, prefix: prefix})
+	}
+	return n, nil
+}
+
+// combineQueryNodes joins left and right with op, dropping either side if it
+// is nil (i.e. it only consisted of stop words).
+func combineQueryNodes(op queryOp, left, right queryNode) queryNode {
+	switch {
+	case left == nil:
+		return right
+	case right == nil:
+		return left
+	}
+	return &queryBinary{op: op, left: left, right: right}
+}
+
+func parseQuery(s string, normalize func(string) []string) (Query, error) {
+	p := queryParser{input: s, rest: s, normalize: normalize}
+	if p.peek() == 0 {
+		// Like in Postgres, an empty query is valid and matches nothing.
+		return Query{}, nil
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return Query{}, err
+	}
+	if p.peek() != 0 {
+		return Query{}, p.syntaxError()
+	}
+	return Query{root: root}, nil
+}
+
+// ParseQuery parses the Postgres text representation of a tsquery. The words
+// are taken as given, without any normalization.
+func ParseQuery(s string) (Query, error) {
+	return parseQuery(s, nil)
+}
@@ -0,0 +1,171 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tsearch
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{``, ``},
+		{`a`, `'a'`},
+		{`a & b | c`, `'a' & 'b' | 'c'`},
+		{`a & (b | c)`, `'a' & ( 'b' | 'c' )`},
+		{`(a & b) & c`, `'a' & 'b' & 'c'`},
+		{`!a & !(b | c)`, `!'a' & !( 'b' | 'c' )`},
+		{`!!a`, `!!'a'`},
+		{`super:*`, `'super':*`},
+		{`'a b' | 'it''s'`, `'a b' | 'it''s'`},
+		{`Fat`, `'Fat'`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			q, err := ParseQuery(tc.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := q.String(); s != tc.expected {
+				t.Fatalf("expected %s, got %s", tc.expected, s)
+			}
+			// The formatted query must parse back to the same query.
+			q2, err := ParseQuery(q.String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if q2.Compare(q) != 0 {
+				t.Fatalf("expected round trip to produce %s, got %s", q, q2)
+			}
+		})
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	testCases := []struct {
+		input  string
+		errMsg string
+	}{
+		{`&`, `syntax error in tsquery`},
+		{`a &`, `syntax error in tsquery`},
+		{`a b`, `syntax error in tsquery`},
+		{`(a`, `syntax error in tsquery`},
+		{`a)`, `syntax error in tsquery`},
+		{`a:`, `syntax error in tsquery`},
+		{`a:B`, `tsquery weight restrictions are not supported`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			_, err := ParseQuery(tc.input)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tc.errMsg) {
+				t.Fatalf(`expected error message "%s" to contain "%s"`, err.Error(), tc.errMsg)
+			}
+		})
+	}
+}
+
+func TestQueryMatch(t *testing.T) {
+	v, err := ParseVector(`brown:3 dog:9 fox:4 jump:5 lazi:8 quick:2`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		query    string
+		expected bool
+	}{
+		{``, false},
+		{`fox`, true},
+		{`cat`, false},
+		{`fox & dog`, true},
+		{`fox & cat`, false},
+		{`cat | dog`, true},
+		{`!cat`, true},
+		{`fox & !dog`, false},
+		{`qui:*`, true},
+		{`qua:*`, false},
+		{`(cat | fox) & !(cat & dog)`, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			q, err := ParseQuery(tc.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m := q.Match(v); m != tc.expected {
+				t.Fatalf("expected %s @@ %s to be %t", v, q, tc.expected)
+			}
+		})
+	}
+}
+
+func TestQueryIndexLexeme(t *testing.T) {
+	testCases := []struct {
+		query    string
+		expected string
+	}{
+		{``, ``},
+		{`a`, `a`},
+		{`a:*`, ``},
+		{`!a`, ``},
+		{`a | b`, ``},
+		{`ab & abc & !abcd`, `abc`},
+		{`(a & b) | (a & c)`, `a`},
+		{`a & (b | c)`, `a`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			q, err := ParseQuery(tc.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			l, ok := q.IndexLexeme()
+			if ok != (tc.expected != "") || l != tc.expected {
+				t.Fatalf("expected %q, got %q (%t)", tc.expected, l, ok)
+			}
+		})
+	}
+}
+
+func TestRank(t *testing.T) {
+	v := English.ToVector("The quick brown foxes jumped over the lazy dogs")
+	testCases := []struct {
+		query    string
+		expected string
+	}{
+		{`cat`, `0`},
+		{`fox`, `0.0607927`},
+		{`fox | cat`, `0.0303964`},
+		{`quick & fox`, `0.0985009`},
+		{`quick & dog`, `0.0761476`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			q, err := English.ToQuery(tc.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r := fmt.Sprintf("%.6g", Rank(v, q)); r != tc.expected {
+				t.Fatalf("expected rank %s, got %s", tc.expected, r)
+			}
+		})
+	}
+}
@@ -0,0 +1,277 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tsearch
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+)
+
+// MaxPosition is the largest word position that can be stored in a Vector.
+// Like in Postgres, larger positions are silently clamped to it.
+const MaxPosition = 16383
+
+// Lexeme is a single normalized word of a Vector, along with the (1-based)
+// positions at which it appeared in the original document. A lexeme parsed
+// from text that did not specify positions has none.
+type Lexeme struct {
+	Word      string
+	Positions []uint16
+}
+
+// Vector is a document reduced to a sorted list of distinct lexemes. It is
+// the representation of the SQL TSVECTOR type.
+type Vector []Lexeme
+
+// makeVector sorts the given lexemes, merging duplicates and their positions.
+func makeVector(lexemes []Lexeme) Vector {
+	if len(lexemes) == 0 {
+		return nil
+	}
+	sort.SliceStable(lexemes, func(i, j int) bool { return lexemes[i].Word < lexemes[j].Word })
+	v := lexemes[:1]
+	for _, l := range lexemes[1:] {
+		if last := &v[len(v)-1]; last.Word == l.Word {
+			last.Positions = append(last.Positions, l.Positions...)
+		} else {
+			v = append(v, l)
+		}
+	}
+	for i := range v {
+		v[i].Positions = normalizePositions(v[i].Positions)
+	}
+	return Vector(v)
+}
+
+func normalizePositions(positions []uint16) []uint16 {
+	if len(positions) < 2 {
+		return positions
+	}
+	sort.Slice(positions, func(i, j int) bool { return positions[i] < positions[j] })
+	res := positions[:1]
+	for _, p := range positions[1:] {
+		if p != res[len(res)-1] {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+// find returns the index of the lexeme with the given word, or the index at
+// which it would be inserted and false if there is none.
+func (v Vector) find(word string) (int, bool) {
+	i := sort.Search(len(v), func(i int) bool { return v[i].Word >= word })
+	return i, i < len(v) && v[i].Word == word
+}
+
+// hasPrefix returns whether any lexeme in v starts with prefix.
+func (v Vector) hasPrefix(prefix string) bool {
+	i, _ := v.find(prefix)
+	return i < len(v) && strings.HasPrefix(v[i].Word, prefix)
+}
+
+// Words returns the distinct words of v in sorted order.
+func (v Vector) Words() []string {
+	words := make([]string, len(v))
+	for i := range v {
+		words[i] = v[i].Word
+	}
+	return words
+}
+
+// Compare compares v to other lexicographically, first by words and then by
+// positions. It returns -1, 0 or 1.
+func (v Vector) Compare(other Vector) int {
+	for i := 0; i < len(v) && i < len(other); i++ {
+		if c := strings.Compare(v[i].Word, other[i].Word); c != 0 {
+			return c
+		}
+		a, b := v[i].Positions, other[i].Positions
+		for j := 0; j < len(a) && j < len(b); j++ {
+			if a[j] != b[j] {
+				if a[j] < b[j] {
+					return -1
+				}
+				return 1
+			}
+		}
+		if len(a) != len(b) {
+			if len(a) < len(b) {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case len(v) < len(other):
+		return -1
+	case len(v) > len(other):
+		return 1
+	}
+	return 0
+}
+
+// Size returns the approximate size of v in bytes.
+func (v Vector) Size() uintptr {
+	sz := uintptr(len(v)) * unsafe.Sizeof(Lexeme{})
+	for i := range v {
+		sz += uintptr(len(v[i].Word)) + uintptr(len(v[i].Positions))*unsafe.Sizeof(uint16(0))
+	}
+	return sz
+}
+
+// Format writes the Postgres text representation of v to buf, e.g.
+// 'brown':3 'fox':4 'quick':2.
+func (v Vector) Format(buf *bytes.Buffer) {
+	for i := range v {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		writeQuotedWord(buf, v[i].Word)
+		for j, p := range v[i].Positions {
+			if j == 0 {
+				buf.WriteByte(':')
+			} else {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(strconv.Itoa(int(p)))
+		}
+	}
+}
+
+func (v Vector) String() string {
+	var buf bytes.Buffer
+	v.Format(&buf)
+	return buf.String()
+}
+
+// writeQuotedWord writes word surrounded by single quotes, doubling any
+// quotes and backslashes it contains.
+func writeQuotedWord(buf *bytes.Buffer, word string) {
+	buf.WriteByte('\'')
+	for i := 0; i < len(word); i++ {
+		switch c := word[i]; c {
+		case '\'', '\\':
+			buf.WriteByte(c)
+			buf.WriteByte(c)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	buf.WriteByte('\'')
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+// readWord reads a possibly quoted word from the start of s, stopping at an
+// unquoted whitespace character or one of the bytes in stop. It returns the
+// unescaped word and the remainder of s.
+func readWord(s string, stop string) (word string, rest string, ok bool) {
+	var buf bytes.Buffer
+	i := 0
+	if i < len(s) && s[i] == '\'' {
+		i++
+		for {
+			if i >= len(s) {
+				return "", "", false
+			}
+			c := s[i]
+			switch {
+			case c == '\\' && i+1 < len(s):
+				buf.WriteByte(s[i+1])
+				i += 2
+			case c == '\'' && i+1 < len(s) && s[i+1] == '\'':
+				buf.WriteByte('\'')
+				i += 2
+			case c == '\'':
+				return buf.String(), s[i+1:], buf.Len() > 0
+			default:
+				buf.WriteByte(c)
+				i++
+			}
+		}
+	}
+	for i < len(s) && !isSpace(s[i]) && strings.IndexByte(stop, s[i]) == -1 {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		buf.WriteByte(s[i])
+		i++
+	}
+	return buf.String(), s[i:], buf.Len() > 0
+}
+
+// ParseVector parses the Postgres text representation of a tsvector: a list
+// of whitespace-separated, optionally quoted words, each optionally followed
+// by a colon and a comma-separated list of positions. The words are taken as
+// given, without any normalization. Position weights are not supported.
+func ParseVector(s string) (Vector, error) {
+	var lexemes []Lexeme
+	rest := s
+	for {
+		rest = strings.TrimLeftFunc(rest, func(r rune) bool { return r < 0x80 && isSpace(byte(r)) })
+		if rest == "" {
+			break
+		}
+		var l Lexeme
+		var ok bool
+		l.Word, rest, ok = readWord(rest, ":")
+		if !ok {
+			return nil, pgerror.NewErrorf(pgerror.CodeSyntaxError, "syntax error in tsvector: %q", s)
+		}
+		if rest != "" && rest[0] == ':' {
+			for {
+				rest = rest[1:]
+				n := 0
+				for n < len(rest) && rest[n] >= '0' && rest[n] <= '9' {
+					n++
+				}
+				if n == 0 {
+					return nil, pgerror.NewErrorf(pgerror.CodeSyntaxError,
+						"syntax error in tsvector: %q", s)
+				}
+				pos, err := strconv.Atoi(rest[:n])
+				if err != nil || pos > MaxPosition {
+					pos = MaxPosition
+				}
+				if pos == 0 {
+					return nil, pgerror.NewErrorf(pgerror.CodeSyntaxError,
+						"wrong position info in tsvector: %q", s)
+				}
+				l.Positions = append(l.Positions, uint16(pos))
+				rest = rest[n:]
+				if rest != "" && strings.IndexByte("ABCDabcd", rest[0]) != -1 {
+					return nil, pgerror.NewErrorf(pgerror.CodeFeatureNotSupportedError,
+						"tsvector position weights are not supported")
+				}
+				if rest == "" || rest[0] != ',' {
+					break
+				}
+			}
+			if rest != "" && !isSpace(rest[0]) {
+				return nil, pgerror.NewErrorf(pgerror.CodeSyntaxError, "syntax error in tsvector: %q", s)
+			}
+		}
+		lexemes = append(lexemes, l)
+	}
+	return makeVector(lexemes), nil
+}
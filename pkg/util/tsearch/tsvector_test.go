@@ -0,0 +1,106 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tsearch
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseVector(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{``, ``},
+		{`a`, `'a'`},
+		{`b a`, `'a' 'b'`},
+		{`a:1 b:2,3`, `'a':1 'b':2,3`},
+		{`a:3,1 a:2 a`, `'a':1,2,3`},
+		{`'b c':2 'it''s' x\ y`, `'b c':2 'it''s' 'x y'`},
+		{`'a\\b'`, `'a\\b'`},
+		{`a:99999`, `'a':16383`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			v, err := ParseVector(tc.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := v.String(); s != tc.expected {
+				t.Fatalf("expected %s, got %s", tc.expected, s)
+			}
+			// The formatted vector must parse back to the same vector.
+			v2, err := ParseVector(v.String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v2.Compare(v) != 0 {
+				t.Fatalf("expected round trip to produce %s, got %s", v, v2)
+			}
+		})
+	}
+}
+
+func TestParseVectorErrors(t *testing.T) {
+	testCases := []struct {
+		input  string
+		errMsg string
+	}{
+		{`'`, `syntax error in tsvector`},
+		{`''`, `syntax error in tsvector`},
+		{`a:`, `syntax error in tsvector`},
+		{`a:x`, `syntax error in tsvector`},
+		{`a:1x`, `syntax error in tsvector`},
+		{`a:0`, `wrong position info in tsvector`},
+		{`a:1A`, `tsvector position weights are not supported`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			_, err := ParseVector(tc.input)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tc.errMsg) {
+				t.Fatalf(`expected error message "%s" to contain "%s"`, err.Error(), tc.errMsg)
+			}
+		})
+	}
+}
+
+func TestVectorCompare(t *testing.T) {
+	vectors := []string{``, `a`, `a b`, `a:1`, `a:1,2`, `a:2`, `b`}
+	for i := range vectors {
+		for j := range vectors {
+			a, err := ParseVector(vectors[i])
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := ParseVector(vectors[j])
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := 0
+			if i < j {
+				expected = -1
+			} else if i > j {
+				expected = 1
+			}
+			if c := a.Compare(b); c != expected {
+				t.Errorf("expected %s compared to %s to be %d, got %d", a, b, expected, c)
+			}
+		}
+	}
+}